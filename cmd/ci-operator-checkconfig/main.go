@@ -9,6 +9,8 @@ import (
 
 	"github.com/sirupsen/logrus"
 
+	"k8s.io/apimachinery/pkg/util/sets"
+
 	"github.com/openshift/ci-tools/pkg/api"
 	"github.com/openshift/ci-tools/pkg/config"
 	"github.com/openshift/ci-tools/pkg/defaults"
@@ -33,26 +35,39 @@ type options struct {
 	resolver        registry.Resolver
 	ciOPConfigAgent agents.ConfigAgent
 	clusterProfiles api.ClusterProfilesList
+	shellChecker    *validation.ShellChecker
 }
 
 func (o *options) parse() error {
-	var registryDir string
+	var registryDir, approvalsPath string
 	var profilesConfigPath string
+	var shellCheck, shellCheckSeverity string
 
 	fs := flag.NewFlagSet("", flag.ExitOnError)
 
 	fs.StringVar(&registryDir, "registry", "", "Path to the step registry directory")
+	fs.StringVar(&approvalsPath, "privileged-approvals", "", "Path to the allowlist of the steps which may use privileged capabilities. Steps which use them without an approval are rejected. Not enforced if empty.")
 	fs.StringVar(&profilesConfigPath, "cluster-profiles-config", "", "Path to the cluster profile config file")
+	fs.StringVar(&shellCheck, "shellcheck", "", "Path to the shellcheck binary with which the commands of steps are analyzed. Disabled if empty. Findings can be suppressed with `# shellcheck disable=SCXXXX` directives in the commands.")
+	fs.StringVar(&shellCheckSeverity, "shellcheck-severity", "error", fmt.Sprintf("Minimum severity of the shellcheck findings reported, one of %v", sets.List(validation.ShellCheckSeverities)))
 	o.Options.Bind(fs)
 
 	if err := fs.Parse(os.Args[1:]); err != nil {
 		return fmt.Errorf("failed to parse flags: %w", err)
 	}
 
-	if err := o.loadResolver(registryDir); err != nil {
+	if err := o.loadResolver(registryDir, approvalsPath); err != nil {
 		return fmt.Errorf("failed to load registry: %w", err)
 	}
 
+	if shellCheck != "" {
+		checker, err := validation.NewShellChecker(shellCheck, shellCheckSeverity)
+		if err != nil {
+			return err
+		}
+		o.shellChecker = checker
+	}
+
 	profiles, err := load.ClusterProfilesConfig(profilesConfigPath)
 	if err != nil {
 		return fmt.Errorf("failed to load cluster profile config: %w", err)
@@ -91,6 +106,9 @@ func (o *options) validate() (ret []error) {
 	errCh := make(chan error)
 	map_ := func() error {
 		validator := validation.NewValidator(o.clusterProfiles)
+		if o.shellChecker != nil {
+			validator.UseShellChecker(o.shellChecker)
+		}
 		for c := range inputCh {
 			if err := o.validateConfiguration(&validator, outputCh, c); err != nil {
 				errCh <- fmt.Errorf("failed to validate configuration %s: %w", c.Metadata.RelativePath(), err)
@@ -112,7 +130,7 @@ func (o *options) validate() (ret []error) {
 	return append(ret, validateTags(seen)...)
 }
 
-func (o *options) loadResolver(path string) error {
+func (o *options) loadResolver(path, approvalsPath string) error {
 	if path == "" {
 		return nil
 	}
@@ -120,7 +138,15 @@ func (o *options) loadResolver(path string) error {
 	if err != nil {
 		return err
 	}
-	o.resolver = registry.NewResolver(refs, chains, workflows, observers)
+	var opts []registry.ResolverOption
+	if approvalsPath != "" {
+		approvals, err := registry.LoadPrivilegedApprovals(approvalsPath)
+		if err != nil {
+			return err
+		}
+		opts = append(opts, registry.WithPrivilegedApprovals(approvals))
+	}
+	o.resolver = registry.NewResolver(refs, chains, workflows, observers, opts...)
 	return nil
 }
 
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	coreclientset "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/rest"
+
+	"github.com/openshift/ci-tools/pkg/steps/multi_stage"
+	"github.com/openshift/ci-tools/pkg/util"
+)
+
+const debugUsage = `Debug the running pods of multi-stage steps
+
+Usage: ci-operator debug attach --namespace namespace --pod pod [--image image]
+
+The attach command adds an ephemeral debug container to a running step pod,
+with the environment and the volumes of the step, e.g. the cluster profile
+and the cli, and prints the command to attach to it.  The step is not
+modified.  Debugging must have been enabled for the job with
+--enable-debug-containers.
+`
+
+type debugOptions struct {
+	namespace  string
+	pod        string
+	image      string
+	kubeconfig string
+}
+
+func bindDebugOptions(fs *flag.FlagSet) *debugOptions {
+	o := &debugOptions{}
+	fs.StringVar(&o.namespace, "namespace", "", "The namespace of the test.")
+	fs.StringVar(&o.pod, "pod", "", "The name of the step pod.")
+	fs.StringVar(&o.image, "image", "", "The image of the debug container, the image of the step by default.")
+	fs.StringVar(&o.kubeconfig, "kubeconfig", "", "The kubeconfig of the build cluster, the in-cluster configuration or $KUBECONFIG by default.")
+	return o
+}
+
+// runDebug implements the `debug` mode, returning the exit code of the
+// process.
+func runDebug(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("debug", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprint(stderr, debugUsage)
+		fs.PrintDefaults()
+	}
+	if len(args) == 0 || args[0] != "attach" {
+		fs.Usage()
+		return 2
+	}
+	o := bindDebugOptions(fs)
+	if err := fs.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	if o.namespace == "" || o.pod == "" {
+		fmt.Fprintln(stderr, "--namespace and --pod are required")
+		return 2
+	}
+	loadConfig := util.LoadClusterConfig
+	if o.kubeconfig != "" {
+		loadConfig = func() (*rest.Config, error) { return util.LoadKubeConfig(o.kubeconfig) }
+	}
+	clusterConfig, err := loadConfig()
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to load cluster config: %v\n", err)
+		return 2
+	}
+	client, err := kubernetes.NewForConfig(clusterConfig)
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to create client: %v\n", err)
+		return 2
+	}
+	container, err := o.attach(context.Background(), client.CoreV1().Pods(o.namespace))
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	fmt.Fprintf(stdout, "Added debug container %s, attach to it with:\n  oc attach --namespace %s -it %s -c %s\n", container, o.namespace, o.pod, container)
+	return 0
+}
+
+// attach adds a debug container to the step pod, returning its name.
+func (o *debugOptions) attach(ctx context.Context, client coreclientset.PodInterface) (string, error) {
+	pod, err := client.Get(ctx, o.pod, meta.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to get pod %s: %w", o.pod, err)
+	}
+	container, err := multi_stage.DebugContainer(pod, o.image)
+	if err != nil {
+		return "", err
+	}
+	pod.Spec.EphemeralContainers = append(pod.Spec.EphemeralContainers, *container)
+	if _, err := client.UpdateEphemeralContainers(ctx, pod.Name, pod, meta.UpdateOptions{}); err != nil {
+		return "", fmt.Errorf("failed to add debug container to pod %s: %w", o.pod, err)
+	}
+	return container.Name, nil
+}
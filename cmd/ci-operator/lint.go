@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+
+	"sigs.k8s.io/yaml"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/lint"
+	"github.com/openshift/ci-tools/pkg/util/gzip"
+)
+
+const lintUsage = `Check the multi-stage tests of a configuration for common problems
+
+Usage: ci-operator lint --config path [--fixes]
+
+Problems are printed one per line.  With --fixes, a JSON patch (RFC 6902)
+which addresses the problems is printed instead, and can be applied to the
+configuration file with any JSON patch implementation.  The command exits
+with a non-zero status when problems are found.
+`
+
+type lintOptions struct {
+	configPath string
+	fixes      bool
+}
+
+func bindLintOptions(fs *flag.FlagSet) *lintOptions {
+	o := &lintOptions{}
+	fs.StringVar(&o.configPath, "config", "", "The configuration file to check.")
+	fs.BoolVar(&o.fixes, "fixes", false, "Print a JSON patch which fixes the problems instead of the problems.")
+	return o
+}
+
+// runLint implements the `lint` mode, returning the exit code of the process.
+func runLint(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("lint", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprint(stderr, lintUsage)
+		fs.PrintDefaults()
+	}
+	o := bindLintOptions(fs)
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	if o.configPath == "" {
+		fmt.Fprintln(stderr, "--config is required")
+		return 2
+	}
+	problems, err := o.lint()
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	if o.fixes {
+		fixes := lint.Fixes(problems)
+		if fixes == nil {
+			fixes = []lint.Operation{}
+		}
+		data, err := json.MarshalIndent(fixes, "", "  ")
+		if err != nil {
+			fmt.Fprintf(stderr, "failed to marshal fixes: %v\n", err)
+			return 2
+		}
+		fmt.Fprintln(stdout, string(data))
+	} else {
+		for _, p := range problems {
+			fmt.Fprintln(stdout, p)
+		}
+	}
+	if len(problems) != 0 {
+		return 1
+	}
+	return 0
+}
+
+func (o *lintOptions) lint() ([]lint.Problem, error) {
+	data, err := gzip.ReadFileMaybeGZIP(o.configPath)
+	if err != nil {
+		return nil, fmt.Errorf("--config error: %w", err)
+	}
+	var config api.ReleaseBuildConfiguration
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to load configuration: %w", err)
+	}
+	return lint.Lint(&config), nil
+}
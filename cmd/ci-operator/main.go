@@ -14,11 +14,13 @@ import (
 	"io"
 	"io/fs"
 	"math/rand"
+	"net/http"
 	"os"
 	"os/exec"
 	"path"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
@@ -39,6 +41,7 @@ import (
 	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/scheme"
 	authclientset "k8s.io/client-go/kubernetes/typed/authorization/v1"
 	coreclientset "k8s.io/client-go/kubernetes/typed/core/v1"
@@ -71,6 +74,8 @@ import (
 
 	"github.com/openshift/ci-tools/pkg/api"
 	"github.com/openshift/ci-tools/pkg/api/nsttl"
+	"github.com/openshift/ci-tools/pkg/artifactindex"
+	"github.com/openshift/ci-tools/pkg/audit"
 	"github.com/openshift/ci-tools/pkg/defaults"
 	"github.com/openshift/ci-tools/pkg/interrupt"
 	"github.com/openshift/ci-tools/pkg/junit"
@@ -81,6 +86,20 @@ import (
 	"github.com/openshift/ci-tools/pkg/results"
 	"github.com/openshift/ci-tools/pkg/secrets"
 	"github.com/openshift/ci-tools/pkg/steps"
+	"github.com/openshift/ci-tools/pkg/steps/artifactsync"
+	"github.com/openshift/ci-tools/pkg/steps/compression"
+	"github.com/openshift/ci-tools/pkg/steps/costattribution"
+	"github.com/openshift/ci-tools/pkg/steps/identity"
+	"github.com/openshift/ci-tools/pkg/steps/leakcheck"
+	"github.com/openshift/ci-tools/pkg/steps/logs"
+	"github.com/openshift/ci-tools/pkg/steps/multi_stage"
+	"github.com/openshift/ci-tools/pkg/steps/nodecapabilities"
+	"github.com/openshift/ci-tools/pkg/steps/orgquota"
+	"github.com/openshift/ci-tools/pkg/steps/podpolicy"
+	"github.com/openshift/ci-tools/pkg/steps/previousjob"
+	"github.com/openshift/ci-tools/pkg/steps/profileschema"
+	"github.com/openshift/ci-tools/pkg/steps/progress"
+	"github.com/openshift/ci-tools/pkg/steps/riskanalysis"
 	"github.com/openshift/ci-tools/pkg/util"
 	"github.com/openshift/ci-tools/pkg/util/gzip"
 	"github.com/openshift/ci-tools/pkg/validation"
@@ -167,6 +186,8 @@ After a successful build the --promote will tag each built image (in "images")
 to the image stream(s) identified by the "promotion" config. You may add
 additional images to promote and their target names via the "additional_images"
 map.
+
+Run "ci-operator lint --help" to check a configuration for common problems.
 `
 
 const (
@@ -184,6 +205,12 @@ var (
 const CustomProwMetadata = "custom-prow-metadata.json"
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "lint" {
+		os.Exit(runLint(os.Args[2:], os.Stdout, os.Stderr))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "debug" {
+		os.Exit(runDebug(os.Args[2:], os.Stdout, os.Stderr))
+	}
 	censor, closer, err := setupLogger()
 	if err != nil {
 		logrus.WithError(err).Fatal("Could not set up logging.")
@@ -344,6 +371,7 @@ func (s *stringSlice) Set(value string) error {
 type options struct {
 	configSpecPath       string
 	unresolvedConfigPath string
+	bundlePath           string
 	templatePaths        stringSlice
 	secretDirectories    stringSlice
 	sshKeyPath           string
@@ -352,9 +380,11 @@ type options struct {
 	targets stringSlice
 	promote bool
 
-	verbose    bool
-	help       bool
-	printGraph bool
+	verbose           bool
+	help              bool
+	printGraph        bool
+	printResolvedTest string
+	renderObjects     string
 
 	writeParams string
 	artifactDir string
@@ -367,6 +397,9 @@ type options struct {
 	idleCleanupDurationSet bool
 	cleanupDuration        time.Duration
 	cleanupDurationSet     bool
+	heartbeatInterval      time.Duration
+	artifactsSyncInterval  time.Duration
+	gcsCredentialsFile     string
 
 	inputHash                  string
 	secrets                    []*coreapi.Secret
@@ -424,12 +457,55 @@ type options struct {
 	targetAdditionalSuffix string
 	manifestToolDockerCfg  string
 	localRegistryDNS       string
+
+	multiStageOptions  multi_stage.Options
+	failureHistory     string
+	podPolicy          string
+	costAttribution    string
+	nodeCapabilities   string
+	orgQuota           string
+	leakCheck          string
+	profileSchemas     string
+	podMutationHooks   stringSlice
+	podScalerURL       string
+	debugServiceURL    string
+	debugServiceKey    string
+	scrubSecretsOnExit bool
+	captureAuditLog    bool
+
+	costAttributionConfig *costattribution.Config
+	// maxConcurrentStepPods caps the number of step pods which run at the
+	// same time
+	maxConcurrentStepPods int
+	// namespaceQuota is the quota of the namespaces of the build farm, which
+	// the resources requested by the graph are checked against
+	namespaceQuota resourceListFlag
+	// burstLimitCaps are the maximum limits of steps which burst to their
+	// limits
+	burstLimitCaps resourceListFlag
+	// podQuotaTimeout and podQuotaMaxBackoff control how the creation of
+	// step pods is retried while the namespace is out of quota
+	podQuotaTimeout    time.Duration
+	podQuotaMaxBackoff time.Duration
+	// stepLogSink and stepLogSinkType locate the store to which the output
+	// of steps is forwarded
+	stepLogSink     string
+	stepLogSinkType string
+	// stepIdentityKey, stepIdentityIssuer and stepIdentityAudience
+	// configure the tokens which identify steps to external services
+	stepIdentityKey      string
+	stepIdentityIssuer   string
+	stepIdentityAudience stringSlice
+	// namespaceResourceQuota is created in the test namespace, sized to the
+	// resources requested by the graph
+	namespaceResourceQuota *coreapi.ResourceQuota
 }
 
 func bindOptions(flag *flag.FlagSet) *options {
 	opt := &options{
 		idleCleanupDuration: 1 * time.Hour,
 		cleanupDuration:     24 * time.Hour,
+		heartbeatInterval:   10 * time.Minute,
 	}
 
 	// command specific options
@@ -446,8 +522,11 @@ func bindOptions(flag *flag.FlagSet) *options {
 	flag.StringVar(&opt.registryPath, "registry", "", "Path to the step registry directory")
 	flag.StringVar(&opt.configSpecPath, "config", "", "The configuration file. If not specified the CONFIG_SPEC environment variable or the configresolver will be used.")
 	flag.StringVar(&opt.unresolvedConfigPath, "unresolved-config", "", "The configuration file, before resolution. If not specified the UNRESOLVED_CONFIG environment variable will be used, if set.")
+	flag.StringVar(&opt.bundlePath, "bundle", "", fmt.Sprintf("Path of a tarball, optionally compressed, holding the configuration in %s and, if the configuration is not resolved, the step registry in %s/. The configuration resolver is not used, so jobs can run without access to it and past executions can be replayed with the bundle ci-operator saves in $ARTIFACTS/%s.", load.BundleConfigFile, load.BundleRegistryDir, bundleArtifact))
 	flag.Var(&opt.targets, "target", "One or more targets in the configuration to build. Only steps that are required for this target will be run.")
 	flag.BoolVar(&opt.printGraph, "print-graph", opt.printGraph, "Print a directed graph of the build steps and exit. Intended for use with the golang digraph utility.")
+	flag.StringVar(&opt.printResolvedTest, "print-resolved-test", "", "Print the fully-resolved literal configuration of the named multi-stage test and exit.")
+	flag.StringVar(&opt.renderObjects, "render-objects", "", "Write the objects the multi-stage tests would create (limited to the targets, if set) to this directory, grouped by step, and exit.")
 
 	// add to the graph of things we run or create
 	flag.Var(&opt.templatePaths, "template", "A set of paths to optional templates to add as stages to this job. Each template is expected to contain at least one restart=Never pod. Parameters are filled from environment or from the automatic parameters generated by the operator.")
@@ -461,6 +540,9 @@ func bindOptions(flag *flag.FlagSet) *options {
 	flag.StringVar(&opt.baseNamespace, "base-namespace", "stable", "Namespace to read builds from, defaults to stable.")
 	flag.DurationVar(&opt.idleCleanupDuration, "delete-when-idle", opt.idleCleanupDuration, "If no pod is running for longer than this interval, delete the namespace. Set to zero to retain the contents. Requires the namespace TTL controller to be deployed.")
 	flag.DurationVar(&opt.cleanupDuration, "delete-after", opt.cleanupDuration, "If namespace exists for longer than this interval, delete the namespace. Set to zero to retain the contents. Requires the namespace TTL controller to be deployed.")
+	flag.DurationVar(&opt.heartbeatInterval, "heartbeat-interval", opt.heartbeatInterval, "Interval at which the namespace and the pods running in it are annotated as active, so that tooling which prunes namespaces does not delete them while the job runs.")
+	flag.DurationVar(&opt.artifactsSyncInterval, "artifacts-sync-interval", 0, "Interval at which the artifacts created or changed since the previous upload are uploaded to the bucket of the job while it runs, so that the artifacts of completed steps can be inspected before the job ends. Zero uploads them only when the job ends.")
+	flag.StringVar(&opt.gcsCredentialsFile, "gcs-credentials-file", "", "Path of the credentials used to upload artifacts to the bucket of the job with --artifacts-sync-interval. The default credentials of the environment are used if not set.")
 
 	// actions to add to the graph
 	flag.BoolVar(&opt.promote, "promote", false, "When all other targets complete, publish the set of images built by this job into the release configuration.")
@@ -495,6 +577,37 @@ func bindOptions(flag *flag.FlagSet) *options {
 	flag.StringVar(&opt.targetAdditionalSuffix, "target-additional-suffix", "", "Inject an additional suffix onto the targeted test's 'as' name. Used for adding an aggregate index")
 
 	flag.StringVar(&opt.manifestToolDockerCfg, "manifest-tool-dockercfg", "/secrets/manifest-tool/.dockerconfigjson", "The dockercfg file path to be used to push the manifest listed image after build. This is being used by the manifest-tool binary.")
+	flag.StringVar(&opt.multiStageOptions.MetricsSink, "step-metrics-sink", "", "URL to which metrics published by multi-stage test steps in $ARTIFACT_DIR/metrics/*.prom are forwarded. Disabled if empty.")
+	flag.StringVar(&opt.stepLogSink, "step-log-sink", "", "URL of the log store to which the output of multi-stage test steps is forwarded while they run: the push endpoint of Loki or the index of Elasticsearch. Disabled if empty.")
+	flag.StringVar(&opt.stepLogSinkType, "step-log-sink-type", string(logs.SinkLoki), fmt.Sprintf("The type of the store of --step-log-sink, one of: %s, %s.", logs.SinkLoki, logs.SinkElasticsearch))
+	flag.StringVar((*string)(&opt.multiStageOptions.ArtifactCompression), "step-artifact-compression", "", fmt.Sprintf("Compress the artifacts of multi-stage test steps and write a manifest of their checksums. Allowed values are: %v. Disabled if empty.", compression.Algorithms))
+	flag.Int64Var(&opt.multiStageOptions.ArtifactCompressionThreshold, "step-artifact-compression-threshold", 1024*1024, "Size in bytes above which artifacts of multi-stage test steps are compressed")
+	flag.StringVar(&opt.failureHistory, "failure-history", "", fmt.Sprintf("Path or HTTP(S) URL of a JSON file with the historical results of test cases. If set, failures of multi-stage tests are classified as new or previously failing and summarized in $ARTIFACTS/<test>/%s.", riskanalysis.Artifact))
+	flag.Var(&opt.podMutationHooks, "pod-mutation-webhook", "URL of a service which mutates the pods of multi-stage tests before they are created. The pod is sent as the JSON body of a POST request and the mutated pod is expected as the response. May be passed multiple times, the services are called in order.")
+	flag.StringVar(&opt.podScalerURL, "pod-scaler-url", "", "URL of the pod-scaler frontend. If set, the 95th percentile of the historical memory usage of step containers which are OOM killed is reported with the failure.")
+	flag.StringVar(&opt.podPolicy, "pod-policy", "", "Path of a YAML file with the policy enforced for the pods of multi-stage tests before they are created.")
+	flag.StringVar(&opt.costAttribution, "cost-attribution", "", fmt.Sprintf("Path of a YAML file which attributes repositories to teams, products or cost centers. The labels of the repository are set on the pods and builds of the job and exposed to multi-stage test steps in $%s.", costattribution.TagsEnv))
+	flag.StringVar(&opt.orgQuota, "org-quota", "", "Path of a YAML file with the share of the build farm each organization may use. The step pods of an organization which exceeds its share are queued or rejected, and the time they were queued is reported with the metrics of the steps. Requires listing pods in all namespaces.")
+	flag.StringVar(&opt.leakCheck, "leak-check", "", fmt.Sprintf("Path of a YAML file listing the cluster profiles whose tests are checked for leaked cloud resources. A step which lists the resources still tagged with the cluster after it was deprovisioned runs last in the tests of these profiles, and the resources are reported as a failed test case and in $ARTIFACTS/<test>/%s.", multi_stage.LeakedResourcesArtifact))
+	flag.StringVar(&opt.profileSchemas, "cluster-profile-schemas", "", "Path of a YAML file with the schemas of the secrets of cluster profiles, by cluster type or profile. Tests fail before they run if the secret of their profile lacks required keys or has malformed values, with a list of all the problems.")
+	flag.StringVar(&opt.nodeCapabilities, "node-capabilities", "", "Path of a YAML file with the node capabilities of the build farm. Tests with steps requiring capabilities the farm does not provide fail before they run, and steps are scheduled on the nodes which provide them.")
+	flag.BoolVar(&opt.multiStageOptions.EncryptSharedDir, "encrypt-shared-dir", false, "Encrypt the contents of the shared directory of multi-stage tests with a key generated for the job. The key is stored in the test namespace while the test runs and the contents are only protected once it is deleted when the test finishes.")
+	flag.IntVar(&opt.maxConcurrentStepPods, "max-concurrent-step-pods", 0, "The maximum number of pods of multi-stage test steps which run at the same time, across all the tests of the job. Unlimited if zero.")
+	flag.Var(&opt.namespaceQuota, "namespace-quota", "The resource quota of the namespaces of the build farm, e.g. cpu=64,memory=256Gi. Tests whose steps request more resources at the same time fail before they run, and a ResourceQuota sized to the peak demand of the graph is created in the test namespace.")
+	flag.BoolVar(&opt.multiStageOptions.CheckStepImages, "check-step-images", true, "Verify that the images of all steps of a multi-stage test exist before its first step runs, failing the test with a report of the missing images otherwise.")
+	flag.BoolVar(&opt.multiStageOptions.StepProgress, "step-progress", true, fmt.Sprintf("Follow the output of running multi-stage steps and report the progress they print with lines like `%s <message> <percent>`.", progress.Marker))
+	flag.DurationVar(&opt.multiStageOptions.TimeoutSnapshotLeadTime, "timeout-snapshot-lead-time", 2*time.Minute, fmt.Sprintf("How long before a multi-stage test step is killed for exceeding its timeout the processes of its container, the files of its shared directory and the last lines of its output are captured in $ARTIFACTS/<test>/<step>/%s. Disabled if zero.", multi_stage.TimeoutSnapshotArtifact))
+	flag.BoolVar(&opt.multiStageOptions.Debug, "enable-debug-containers", false, "Allow debug containers to be attached to the running pods of multi-stage steps with `ci-operator debug attach`.")
+	flag.Var(&opt.burstLimitCaps, "burst-limit-caps", "The maximum limits of the multi-stage test steps which set `burst`, e.g. cpu=16,memory=64Gi. Higher limits are lowered to these.")
+	flag.DurationVar(&opt.podQuotaTimeout, "pod-quota-timeout", util.DefaultPodQuotaBackoff.Timeout, "How long the creation of the pods of multi-stage test steps waits for the quota of the namespace before failing. Zero fails immediately.")
+	flag.DurationVar(&opt.podQuotaMaxBackoff, "pod-quota-max-backoff", util.DefaultPodQuotaBackoff.Max, "The maximum interval between attempts to create the pods of multi-stage test steps while the namespace is out of quota.")
+	flag.StringVar(&opt.debugServiceURL, "debug-service-url", "", "URL of the break-glass service to which the kubeconfig of the cluster of failed multi-stage tests is uploaded when --enable-debug-containers is set, for the author of the pull request to retrieve it. Requires --debug-service-public-key.")
+	flag.StringVar(&opt.debugServiceKey, "debug-service-public-key", "", "Path of the PEM-encoded RSA public key of the service set with --debug-service-url, with which the uploaded kubeconfig is encrypted.")
+	flag.StringVar(&opt.stepIdentityKey, "step-identity-key", "", fmt.Sprintf("Path of the PEM-encoded PKCS#8 private key with which tokens identifying the job, build and step are signed. Each multi-stage test step is given a token in the file at $%s, which it can exchange with external services for authenticated access. Disabled if empty.", multi_stage.StepIdentityTokenEnv))
+	flag.StringVar(&opt.stepIdentityIssuer, "step-identity-issuer", "ci-operator", "The issuer of the tokens signed with --step-identity-key.")
+	flag.Var(&opt.stepIdentityAudience, "step-identity-audience", "The audience of the tokens signed with --step-identity-key, i.e. a service which accepts them. May be passed multiple times.")
+	flag.BoolVar(&opt.scrubSecretsOnExit, "scrub-secrets-on-exit", false, "Zero the data of secrets holding credentials of the test, such as cluster profiles and the shared directories of multi-stage tests, and delete them before exiting. Secrets which other jobs in the namespace still use are kept.")
+	flag.BoolVar(&opt.captureAuditLog, "capture-audit-log", false, "Collect the records of the audit log of the build cluster for the test namespace and its service accounts as artifacts. Requires access to the logs of the control plane nodes.")
 	flag.StringVar(&opt.localRegistryDNS, "local-registry-dns", "image-registry.openshift-image-registry.svc:5000", "Defines the target image registry.")
 
 	opt.resultsOptions.Bind(flag)
@@ -502,6 +615,117 @@ func bindOptions(flag *flag.FlagSet) *options {
 }
 
 func (o *options) Complete() error {
+	if o.heartbeatInterval <= 0 {
+		return fmt.Errorf("--heartbeat-interval must be positive, got %s", o.heartbeatInterval)
+	}
+	if o.artifactsSyncInterval < 0 {
+		return fmt.Errorf("--artifacts-sync-interval cannot be negative, got %s", o.artifactsSyncInterval)
+	}
+	if a := o.multiStageOptions.ArtifactCompression; a != "" && !a.Valid() {
+		return fmt.Errorf("invalid --step-artifact-compression: %q", a)
+	}
+	o.multiStageOptions.ClusterShares = multi_stage.NewClusterShares()
+	if o.maxConcurrentStepPods < 0 {
+		return fmt.Errorf("--max-concurrent-step-pods must not be negative, got %d", o.maxConcurrentStepPods)
+	} else if o.maxConcurrentStepPods > 0 {
+		o.multiStageOptions.StepPodLimiter = multi_stage.NewStepPodLimiter(o.maxConcurrentStepPods)
+	}
+	if o.failureHistory != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		history, err := riskanalysis.Load(ctx, http.DefaultClient, o.failureHistory)
+		if err != nil {
+			return fmt.Errorf("failed to load --failure-history: %w", err)
+		}
+		o.multiStageOptions.FailureHistory = history
+	}
+	if o.podScalerURL != "" {
+		o.multiStageOptions.MemoryHistory = &multi_stage.PodScalerMemoryHistory{URL: o.podScalerURL, Client: &http.Client{Timeout: time.Minute}}
+	}
+	for _, hook := range o.podMutationHooks.values {
+		o.multiStageOptions.PodMutators = append(o.multiStageOptions.PodMutators, &multi_stage.WebhookPodMutator{URL: hook, Client: &http.Client{Timeout: time.Minute}})
+	}
+	o.multiStageOptions.BurstLimitCaps = o.burstLimitCaps.values
+	if o.podQuotaTimeout < 0 {
+		return fmt.Errorf("--pod-quota-timeout must not be negative, got %s", o.podQuotaTimeout)
+	}
+	if o.podQuotaMaxBackoff <= 0 {
+		return fmt.Errorf("--pod-quota-max-backoff must be positive, got %s", o.podQuotaMaxBackoff)
+	}
+	o.multiStageOptions.PodQuotaBackoff = &util.PodQuotaBackoff{
+		Initial: min(util.DefaultPodQuotaBackoff.Initial, o.podQuotaMaxBackoff),
+		Max:     o.podQuotaMaxBackoff,
+		Timeout: o.podQuotaTimeout,
+	}
+	if o.stepLogSink != "" {
+		forwarder, err := logs.NewForwarder(&http.Client{Timeout: time.Minute}, logs.SinkType(o.stepLogSinkType), o.stepLogSink)
+		if err != nil {
+			return fmt.Errorf("invalid --step-log-sink: %w", err)
+		}
+		o.multiStageOptions.LogForwarder = forwarder
+	}
+	if (o.debugServiceURL == "") != (o.debugServiceKey == "") {
+		return errors.New("--debug-service-url and --debug-service-public-key must be set together")
+	} else if o.debugServiceURL != "" {
+		service, err := multi_stage.LoadDebugService(o.debugServiceURL, o.debugServiceKey)
+		if err != nil {
+			return fmt.Errorf("failed to load --debug-service-public-key: %w", err)
+		}
+		o.multiStageOptions.DebugService = service
+	}
+	if o.stepIdentityKey != "" {
+		key, err := identity.LoadSigningKey(o.stepIdentityKey)
+		if err != nil {
+			return fmt.Errorf("failed to load --step-identity-key: %w", err)
+		}
+		issuer, err := identity.NewIssuer(key, o.stepIdentityIssuer, o.stepIdentityAudience.values)
+		if err != nil {
+			return fmt.Errorf("invalid --step-identity-key: %w", err)
+		}
+		o.multiStageOptions.StepIdentity = issuer
+	}
+	if o.orgQuota != "" {
+		config, err := orgquota.Load(o.orgQuota)
+		if err != nil {
+			return fmt.Errorf("failed to load --org-quota: %w", err)
+		}
+		o.multiStageOptions.OrgQuota = config
+	}
+	if o.leakCheck != "" {
+		config, err := leakcheck.Load(o.leakCheck)
+		if err != nil {
+			return fmt.Errorf("failed to load --leak-check: %w", err)
+		}
+		o.multiStageOptions.LeakCheck = config
+	}
+	if o.profileSchemas != "" {
+		registry, err := profileschema.Load(o.profileSchemas)
+		if err != nil {
+			return fmt.Errorf("failed to load --cluster-profile-schemas: %w", err)
+		}
+		o.multiStageOptions.ProfileSchemas = registry
+	}
+	if o.podPolicy != "" {
+		policy, err := podpolicy.Load(o.podPolicy)
+		if err != nil {
+			return fmt.Errorf("failed to load --pod-policy: %w", err)
+		}
+		o.multiStageOptions.PodPolicy = policy
+	}
+	if o.costAttribution != "" {
+		config, err := costattribution.Load(o.costAttribution)
+		if err != nil {
+			return fmt.Errorf("failed to load --cost-attribution: %w", err)
+		}
+		o.costAttributionConfig = config
+	}
+	if o.nodeCapabilities != "" {
+		capabilities, err := nodecapabilities.Load(o.nodeCapabilities)
+		if err != nil {
+			return fmt.Errorf("failed to load --node-capabilities: %w", err)
+		}
+		o.multiStageOptions.NodeCapabilities = capabilities
+	}
 	jobSpec, err := api.ResolveSpecFromEnv()
 	if err != nil {
 		if len(o.gitRef) == 0 {
@@ -532,6 +756,11 @@ func (o *options) Complete() error {
 	info := o.getResolverInfo(jobSpec)
 	o.resolverClient = server.NewResolverClient(o.resolverAddress)
 
+	if o.bundlePath != "" {
+		if err := o.extractBundle(); err != nil {
+			return results.ForReason("loading_config").ForError(err)
+		}
+	}
 	if o.unresolvedConfigPath != "" && o.configSpecPath != "" {
 		return errors.New("cannot set --config and --unresolved-config at the same time")
 	}
@@ -573,6 +802,9 @@ func (o *options) Complete() error {
 	if err := validation.IsValidGraphConfiguration(o.graphConfig.Steps); err != nil {
 		return results.ForReason("validating_config").ForError(err)
 	}
+	if err := o.saveBundle(); err != nil {
+		logrus.WithError(err).Warn("Failed to save the bundle of the configuration.")
+	}
 
 	if o.verbose {
 		config, _ := yaml.Marshal(o.configSpec)
@@ -629,6 +861,7 @@ func (o *options) Complete() error {
 			return fmt.Errorf("failed to generate secret %s: %w", name, err)
 		}
 		secret.Name = name
+		secret.Labels = map[string]string{api.ScrubOnExitLabel: "true", api.ScrubOwnerLabel(o.jobSpec.ScrubOwner()): "true"}
 		if len(secret.Data) == 1 {
 			if _, ok := secret.Data[coreapi.DockerConfigJsonKey]; ok {
 				secret.Type = coreapi.SecretTypeDockerConfigJson
@@ -706,11 +939,73 @@ func (o *options) Complete() error {
 		return err
 	}
 
+	addAggregatedTargets(o)
+	addReusedClusterTargets(o)
 	handleTargetAdditionalSuffix(o)
 
 	return overrideTestStepDependencyParams(o)
 }
 
+// addAggregatedTargets adds the tests aggregated by the targeted aggregate
+// tests to the targets, for their results to be aggregated.
+func addAggregatedTargets(o *options) {
+	targets := sets.New[string](o.targets.values...)
+	for _, test := range o.configSpec.Tests {
+		c := test.AggregateTestConfiguration
+		if c == nil || !targets.Has(test.As) {
+			continue
+		}
+		for _, name := range c.Tests {
+			if targets.Has(name) {
+				continue
+			}
+			logrus.Infof("Adding test %s to the targets, test %s aggregates its result.", name, test.As)
+			o.targets.values = append(o.targets.values, name)
+			targets.Insert(name)
+		}
+	}
+}
+
+// addReusedClusterTargets adds the tests whose cluster is reused by the
+// targeted tests to the targets, for them to provision it.
+func addReusedClusterTargets(o *options) {
+	targets := sets.New[string](o.targets.values...)
+	for _, test := range o.configSpec.Tests {
+		c := test.MultiStageTestConfigurationLiteral
+		if c == nil || c.ReuseClusterFrom == "" || !targets.Has(test.As) || targets.Has(c.ReuseClusterFrom) {
+			continue
+		}
+		logrus.Infof("Adding test %s to the targets, test %s reuses its cluster.", c.ReuseClusterFrom, test.As)
+		o.targets.values = append(o.targets.values, c.ReuseClusterFrom)
+		targets.Insert(c.ReuseClusterFrom)
+	}
+}
+
+// expectClusterReuse registers the tests of the graph which reuse the cluster
+// of another test, so that it is not torn down before they have run.
+func expectClusterReuse(shares *multi_stage.ClusterShares, config *api.ReleaseBuildConfiguration, nodes api.OrderedStepList) {
+	names := sets.New[string](nodeNames(nodes)...)
+	for _, test := range config.Tests {
+		if c := test.MultiStageTestConfigurationLiteral; c != nil && c.ReuseClusterFrom != "" && names.Has(test.As) {
+			shares.Expect(c.ReuseClusterFrom)
+		}
+	}
+}
+
+// expectSharedLeases registers the tests of the graph which use the leases
+// shared by the job, so that they are not released before they have run.
+func expectSharedLeases(shared *steps.SharedLeases, config *api.ReleaseBuildConfiguration, nodes api.OrderedStepList) {
+	if shared == nil {
+		return
+	}
+	names := sets.New[string](nodeNames(nodes)...)
+	for _, test := range config.Tests {
+		if c := test.MultiStageTestConfigurationLiteral; c != nil && names.Has(test.As) {
+			shared.Expect(api.LeasesForTest(c))
+		}
+	}
+}
+
 func parseKeyValParams(input []string, paramType string) (map[string]string, error) {
 	var validationErrors []error
 	params := make(map[string]string)
@@ -855,19 +1150,41 @@ func (o *options) Report(errs ...error) {
 }
 
 func (o *options) Run() []error {
+	if o.printResolvedTest != "" {
+		if err := printResolvedTest(os.Stdout, o.configSpec, o.printResolvedTest); err != nil {
+			return []error{fmt.Errorf("could not print resolved test: %w", err)}
+		}
+		return nil
+	}
+	if o.renderObjects != "" {
+		if o.jobSpec.Namespace() == "" {
+			o.jobSpec.SetNamespace(renderNamespace)
+		}
+		if err := renderObjects(o.renderObjects, o.configSpec, o.jobSpec, o.multiStageOptions, o.targets.values); err != nil {
+			return []error{fmt.Errorf("could not render objects: %w", err)}
+		}
+		return nil
+	}
 	start := time.Now()
 	defer func() {
 		logrus.Infof("Ran for %s", time.Since(start).Truncate(time.Second))
 	}()
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := context.WithCancelCause(context.Background())
 	handler := func(s os.Signal) {
 		logrus.Infof("error: Process interrupted with signal %s, cancelling execution...", s)
-		cancel()
+		cancel(nil)
 	}
 	var leaseClient *lease.Client
 	if o.leaseServer != "" && o.leaseServerCredentialsFile != "" {
 		leaseClient = &o.leaseClient
 	}
+	o.multiStageOptions.LeaseClient = leaseClient
+	o.multiStageOptions.SharedLeases = steps.NewSharedLeases(ctx, o.configSpec.SharedLeases)
+	costLabels := o.costAttributionConfig.For(o.configSpec.Metadata.Org, o.configSpec.Metadata.Repo)
+	o.multiStageOptions.CostAttribution = costLabels
+	if finder := previousjob.NewFinder(&o.jobSpec.JobSpec, &http.Client{Timeout: time.Minute}); finder != nil {
+		o.multiStageOptions.PreviousJob = finder
+	}
 
 	o.resolveConsoleHost()
 
@@ -884,7 +1201,7 @@ func (o *options) Run() []error {
 	// load the graph from the configuration
 	buildSteps, postSteps, err := defaults.FromConfig(ctx, o.configSpec, &o.graphConfig, o.jobSpec, o.templates, o.writeParams, o.promote, o.clusterConfig,
 		o.podPendingTimeout, leaseClient, o.targets.values, o.cloneAuthConfig, o.pullSecret, o.pushSecret, o.censor, o.hiveKubeconfig,
-		o.consoleHost, o.nodeName, nodeArchitectures, o.targetAdditionalSuffix, o.manifestToolDockerCfg, o.localRegistryDNS)
+		o.consoleHost, o.nodeName, nodeArchitectures, o.targetAdditionalSuffix, o.manifestToolDockerCfg, o.localRegistryDNS, costLabels, o.multiStageOptions)
 	if err != nil {
 		return []error{results.ForReason("defaulting_config").WithError(err).Errorf("failed to generate steps from config: %v", err)}
 	}
@@ -909,6 +1226,8 @@ func (o *options) Run() []error {
 		return append([]error{results.ForReason("building_graph").ForError(errors.New("could not sort nodes"))}, errs...)
 	}
 	logrus.Infof("Running %s", strings.Join(nodeNames(stepList), ", "))
+	expectClusterReuse(o.multiStageOptions.ClusterShares, o.configSpec, stepList)
+	expectSharedLeases(o.multiStageOptions.SharedLeases, o.configSpec, stepList)
 	if o.printGraph {
 		if err := printDigraph(os.Stdout, stepList); err != nil {
 			return []error{fmt.Errorf("could not print graph: %w", err)}
@@ -919,7 +1238,16 @@ func (o *options) Run() []error {
 	if errs != nil {
 		return errs
 	}
+	if quota := o.namespaceQuota.values; len(quota) != 0 {
+		demand := resourceDemand(stepList)
+		if err := checkNamespaceQuota(demand, quota); err != nil {
+			return []error{results.ForReason("namespace_quota").ForError(err)}
+		}
+		o.namespaceResourceQuota = namespaceResourceQuota(o.namespace, demand, o.configSpec.Resources["*"].Requests, quota)
+	}
+	var suites *junit.TestSuites
 	defer func() {
+		o.saveArtifactIndex(*graph, suites)
 		serializedGraph, err := json.Marshal(graph)
 		if err != nil {
 			logrus.WithError(err).Error("Failed to marshal graph")
@@ -934,6 +1262,10 @@ func (o *options) Run() []error {
 		return []error{results.ForReason("initializing_namespace").WithError(err).Errorf("could not initialize namespace: %v", err)}
 	}
 
+	if o.scrubSecretsOnExit {
+		defer o.scrubSecrets()
+	}
+
 	return interrupt.New(handler, o.saveNamespaceArtifacts).Run(func() []error {
 		if leaseClient != nil {
 			if err := o.initializeLeaseClient(); err != nil {
@@ -951,12 +1283,28 @@ func (o *options) Run() []error {
 		}
 		runtimeObject := &coreapi.ObjectReference{Namespace: o.namespace}
 		eventRecorder.Event(runtimeObject, coreapi.EventTypeNormal, "CiJobStarted", eventJobDescription(o.jobSpec, o.namespace))
+		if o.captureAuditLog {
+			defer o.saveAuditLog(time.Now())
+		}
+		if o.artifactsSyncInterval > 0 {
+			if artifactDir, set := api.Artifacts(); set {
+				if syncer := artifactsync.NewSyncer(&o.jobSpec.JobSpec, artifactDir, o.gcsCredentialsFile, o.censor); syncer != nil {
+					syncCtx, stopSync := context.WithCancel(ctx)
+					defer stopSync()
+					go syncer.Run(syncCtx, o.artifactsSyncInterval)
+				}
+			}
+		}
 		// execute the graph
-		suites, graphDetails, errs := steps.Run(ctx, nodes)
+		var graphDetails []api.CIOperatorStepDetails
+		suites, graphDetails, errs = steps.Run(ctx, nodes)
 		if err := o.writeJUnit(suites, "operator"); err != nil {
 			logrus.WithError(err).Warn("Unable to write JUnit result.")
 		}
 		graph.MergeFrom(graphDetails...)
+		if cause := context.Cause(ctx); errors.Is(cause, errNamespaceDeleted) {
+			errs = append([]error{results.ForReason("namespace_deleted").ForError(cause)}, errs...)
+		}
 		// Rewrite the Metadata JSON to catch custom metadata if it has been generated by the job
 		if err := o.writeMetadataJSON(); err != nil {
 			logrus.WithError(err).Warn("Unable to update metadata.json for build")
@@ -1196,6 +1544,7 @@ func (o *options) initializeNamespace() error {
 	// This label makes sure that the namespace is active, and the value will be updated
 	// if the namespace will be reused.
 	annotationUpdates[nsttl.AnnotationNamespaceLastActive] = time.Now().Format(time.RFC3339)
+	annotationUpdates[nsttl.AnnotationHeartbeatInterval] = o.heartbeatInterval.String()
 
 	if err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
 		ns := &coreapi.Namespace{}
@@ -1281,30 +1630,13 @@ func (o *options) initializeNamespace() error {
 		}
 	}
 
-	go func() {
-		ticker := time.NewTicker(10 * time.Minute)
-		defer ticker.Stop()
-		for {
-			select {
-			case <-ctx.Done():
-				return
-			case <-ticker.C:
-				ns := &coreapi.Namespace{}
-				if err := client.Get(ctx, ctrlruntimeclient.ObjectKey{Name: o.namespace}, ns); err != nil {
-					logrus.WithError(err).Warnf("Failed to get namespace %s for heartbeating", o.namespace)
-					continue
-				}
-				originalNS := ns.DeepCopy()
-				if ns.Annotations == nil {
-					ns.Annotations = map[string]string{}
-				}
-				ns.Annotations[nsttl.AnnotationNamespaceLastActive] = time.Now().Format(time.RFC3339)
-				if err := client.Patch(ctx, ns, ctrlruntimeclient.MergeFrom(originalNS)); err != nil {
-					logrus.WithError(err).Warnf("Failed to patch the %s namespace to update the %s annotation.", o.namespace, nsttl.AnnotationNamespaceLastActive)
-				}
-			}
+	if o.namespaceResourceQuota != nil {
+		if err := ensureNamespaceQuota(ctx, client, o.namespaceResourceQuota); err != nil {
+			return err
 		}
-	}()
+	}
+
+	go heartbeat(ctx, client, o.namespace, o.heartbeatInterval)
 
 	logrus.Debugf("Setting up pipeline ImageStream for the test")
 
@@ -1342,6 +1674,7 @@ func (o *options) initializeNamespace() error {
 	}
 
 	for _, secret := range o.secrets {
+		secret.OwnerReferences = []meta.OwnerReference{*o.jobSpec.Owner()}
 		created, err := util.UpsertImmutableSecret(ctx, client, secret)
 		if err != nil {
 			return fmt.Errorf("could not update secret %s: %w", secret.Name, err)
@@ -1350,6 +1683,10 @@ func (o *options) initializeNamespace() error {
 			logrus.Debugf("Created secret %s", secret.Name)
 		} else {
 			logrus.Debugf("Updated secret %s", secret.Name)
+			// an identical secret of another job in the namespace is kept
+			if err := util.AddScrubOwner(ctx, client, ctrlruntimeclient.ObjectKeyFromObject(secret), o.jobSpec.ScrubOwner()); err != nil {
+				return fmt.Errorf("could not claim secret %s: %w", secret.Name, err)
+			}
 		}
 	}
 	pdb, mutateFn := pdb(steps.CreatedByCILabel, o.namespace)
@@ -1598,12 +1935,16 @@ func (o *options) writeFailingJUnit(errs []error) {
 		if errors.Is(err, &errWroteJUnit{}) {
 			continue
 		}
-		testCases = append(testCases, &junit.TestCase{
+		testCase := &junit.TestCase{
 			Name: "initialize",
 			FailureOutput: &junit.FailureOutput{
 				Output: err.Error(),
 			},
-		})
+		}
+		if kind := results.KindString(err); kind != "" {
+			testCase.Properties = []*junit.TestSuiteProperty{{Name: steps.ErrorKindProperty, Value: kind}}
+		}
+		testCases = append(testCases, testCase)
 	}
 	if len(testCases) == 0 {
 		return
@@ -1623,6 +1964,25 @@ func (o *options) writeFailingJUnit(errs []error) {
 	}
 }
 
+// saveArtifactIndex writes the index of the artifacts of the job, which
+// summarizes its steps and links to their artifacts.
+func (o *options) saveArtifactIndex(graph api.CIOperatorStepGraph, suites *junit.TestSuites) {
+	title := fmt.Sprintf("Artifacts of job %s #%s", o.jobSpec.Job, o.jobSpec.BuildID)
+	index := artifactindex.New(title, graph, suites)
+	markdown, err := index.Markdown()
+	if err != nil {
+		logrus.WithError(err).Warn("Failed to render the artifact index.")
+		return
+	}
+	html, err := index.HTML()
+	if err != nil {
+		logrus.WithError(err).Warn("Failed to render the artifact index.")
+		return
+	}
+	_ = api.SaveArtifact(o.censor, artifactindex.MarkdownFilename, []byte(markdown))
+	_ = api.SaveArtifact(o.censor, artifactindex.HTMLFilename, []byte(html))
+}
+
 func (o *options) writeJUnit(suites *junit.TestSuites, name string) error {
 	if suites == nil {
 		return nil
@@ -1665,6 +2025,52 @@ func inputHash(inputs api.InputDefinition) string {
 
 // saveNamespaceArtifacts is a best effort attempt to save ci-operator namespace artifacts to disk
 // for review later.
+// scrubSecrets removes the secrets holding credentials of the test from the
+// namespace, so they do not outlive the job if the namespace is not deleted.
+// The namespace is shared by the jobs with the same inputs: secrets which
+// other jobs still use are only released by this one.
+func (o *options) scrubSecrets() {
+	client, err := ctrlruntimeclient.New(o.clusterConfig, ctrlruntimeclient.Options{})
+	if err != nil {
+		logrus.WithError(err).Warn("Failed to create client to scrub secrets.")
+		return
+	}
+	logrus.Debugf("Scrubbing secrets in namespace %s", o.namespace)
+	if err := util.ScrubSecrets(context.Background(), client, o.namespace, o.jobSpec.ScrubOwner()); err != nil {
+		logrus.WithError(err).Warn("Failed to scrub secrets.")
+	}
+}
+
+// saveAuditLog is a best effort attempt to save the audit records of the
+// namespace since the test started.
+func (o *options) saveAuditLog(since time.Time) {
+	client, err := kubernetes.NewForConfig(o.clusterConfig)
+	if err != nil {
+		logrus.WithError(err).Warn("Failed to create client to collect the audit log.")
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	events, err := audit.Collect(ctx, client, o.namespace, since)
+	if err != nil {
+		logrus.WithError(err).Warn("Failed to collect the audit log.")
+		return
+	}
+	logrus.Debugf("Collected %d audit records for namespace %s.", len(events), o.namespace)
+	var records bytes.Buffer
+	encoder := json.NewEncoder(&records)
+	for _, e := range events {
+		if err := encoder.Encode(e); err != nil {
+			logrus.WithError(err).Warn("Failed to marshal audit record.")
+			return
+		}
+	}
+	_ = api.SaveArtifact(o.censor, audit.EventsArtifact, records.Bytes())
+	if data, err := json.MarshalIndent(audit.Summarize(events), "", "  "); err == nil {
+		_ = api.SaveArtifact(o.censor, audit.SummaryArtifact, data)
+	}
+}
+
 func (o *options) saveNamespaceArtifacts() {
 	namespaceDir := api.NamespaceDir
 	if kubeClient, err := coreclientset.NewForConfig(o.clusterConfig); err == nil {
@@ -1848,6 +2254,72 @@ func printDigraph(w io.Writer, steps api.OrderedStepList) error {
 	return nil
 }
 
+// printResolvedTest writes the literal configuration of a multi-stage test, as
+// executed after registry resolution and parameter overrides, to `w`.
+func printResolvedTest(w io.Writer, config *api.ReleaseBuildConfiguration, name string) error {
+	for _, test := range config.Tests {
+		if test.As != name {
+			continue
+		}
+		if test.MultiStageTestConfigurationLiteral == nil {
+			return fmt.Errorf("test %q is not a resolved multi-stage test", name)
+		}
+		data, err := yaml.Marshal(test.MultiStageTestConfigurationLiteral)
+		if err != nil {
+			return fmt.Errorf("failed to marshal test %q: %w", name, err)
+		}
+		_, err = w.Write(data)
+		return err
+	}
+	return fmt.Errorf("test %q not found in configuration", name)
+}
+
+// renderNamespace is the namespace of rendered objects, as the namespace of
+// the test is only known once its inputs are resolved.
+const renderNamespace = "ci-op-{id}"
+
+// renderObjects writes the objects the multi-stage tests would create to a
+// directory: those of each test to `<test>.yaml` and those of each of its
+// steps to `<test>/<step>.yaml`.
+func renderObjects(dir string, config *api.ReleaseBuildConfiguration, jobSpec *api.JobSpec, options multi_stage.Options, targets []string) error {
+	for _, test := range config.Tests {
+		if test.MultiStageTestConfigurationLiteral == nil || (len(targets) != 0 && !slices.Contains(targets, test.As)) {
+			continue
+		}
+		rendered, err := multi_stage.RenderObjects(test, config, jobSpec, options)
+		if err != nil {
+			return err
+		}
+		if err := writeObjects(filepath.Join(dir, test.As+".yaml"), rendered.Test); err != nil {
+			return err
+		}
+		for step, objects := range rendered.Steps {
+			if err := writeObjects(filepath.Join(dir, test.As, step+".yaml"), objects); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func writeObjects(path string, objects []ctrlruntimeclient.Object) error {
+	var docs [][]byte
+	for _, obj := range objects {
+		data, err := yaml.Marshal(obj)
+		if err != nil {
+			return fmt.Errorf("failed to marshal %s: %w", obj.GetName(), err)
+		}
+		docs = append(docs, data)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", path, err)
+	}
+	if err := os.WriteFile(path, bytes.Join(docs, []byte("---\n")), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
 func calculateGraph(nodes api.OrderedStepList) (*api.CIOperatorStepGraph, []error) {
 	if err := validateSteps(nodes); err != nil {
 		return nil, err
@@ -2055,6 +2527,41 @@ func (o *options) getInjectTest() (*api.MetadataWithTest, error) {
 	return api.MetadataTestFromString(o.injectTest)
 }
 
+// bundleArtifact is the bundle with which the execution of the job can be
+// replayed using --bundle.
+const bundleArtifact = "ci-operator-bundle.tar"
+
+// extractBundle extracts the bundle given with --bundle and loads the
+// configuration and the step registry from it.
+func (o *options) extractBundle() error {
+	if o.configSpecPath != "" || o.unresolvedConfigPath != "" || o.registryPath != "" || o.injectTest != "" {
+		return errors.New("--bundle cannot be used with --config, --unresolved-config, --registry or --with-test-from")
+	}
+	dir, err := os.MkdirTemp("", "ci-operator-bundle-")
+	if err != nil {
+		return fmt.Errorf("failed to create the directory for the bundle: %w", err)
+	}
+	bundle, err := load.ExtractBundle(o.bundlePath, dir)
+	if err != nil {
+		return fmt.Errorf("--bundle error: %w", err)
+	}
+	o.configSpecPath, o.registryPath = bundle.ConfigPath, bundle.RegistryPath
+	return nil
+}
+
+// saveBundle saves the resolved configuration as a bundle, with which the
+// execution of the job can be replayed.
+func (o *options) saveBundle() error {
+	if _, set := api.Artifacts(); !set {
+		return nil
+	}
+	data, err := load.WriteBundle(o.configSpec)
+	if err != nil {
+		return err
+	}
+	return api.SaveArtifact(o.censor, bundleArtifact, data)
+}
+
 // loadConfig loads the standard configuration path, env, or configresolver (in that order of priority)
 func (o *options) loadConfig(info *api.Metadata) (*api.ReleaseBuildConfiguration, error) {
 	var raw string
@@ -2107,6 +2614,13 @@ func (o *options) loadConfig(info *api.Metadata) (*api.ReleaseBuildConfiguration
 		}
 		return nil, fmt.Errorf("invalid configuration: %w\nvalue:\n%s", err, raw)
 	}
+	var configDir string
+	if len(o.configSpecPath) > 0 {
+		configDir = filepath.Dir(o.configSpecPath)
+	}
+	if err := load.EnvironmentFromFiles(&configSpec, configDir); err != nil {
+		return nil, fmt.Errorf("failed to load parameters from files: %w", err)
+	}
 	if o.registryPath != "" {
 		refs, chains, workflows, _, _, observers, err := load.Registry(o.registryPath, load.RegistryFlag(0))
 		if err != nil {
@@ -2134,7 +2648,66 @@ func resolveNodeArchitectures(ctx context.Context, client coreclientset.NodeInte
 	return sets.List(ret), nil
 }
 
-func monitorNamespace(ctx context.Context, cancel func(), namespace string, client coreclientset.NamespaceInterface) {
+// errNamespaceDeleted is the cause of the cancellation of the test when its
+// namespace is deleted while the test runs.
+var errNamespaceDeleted = errors.New("namespace was deleted")
+
+// heartbeat marks the namespace and the pods ci-operator runs in it as active
+// at each interval, so that tooling which prunes namespaces does not delete
+// them while the job runs.
+func heartbeat(ctx context.Context, client ctrlruntimeclient.Client, namespace string, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			markActive(ctx, client, namespace, time.Now())
+		}
+	}
+}
+
+func markActive(ctx context.Context, client ctrlruntimeclient.Client, namespace string, now time.Time) {
+	active := now.Format(time.RFC3339)
+	ns := &coreapi.Namespace{}
+	if err := client.Get(ctx, ctrlruntimeclient.ObjectKey{Name: namespace}, ns); err != nil {
+		logrus.WithError(err).Warnf("Failed to get namespace %s for heartbeating", namespace)
+		return
+	}
+	if err := annotateActive(ctx, client, ns, active); err != nil {
+		logrus.WithError(err).Warnf("Failed to patch the %s namespace to update the %s annotation.", namespace, nsttl.AnnotationNamespaceLastActive)
+	}
+	// only the pods created by ci-operator are patched, others in the
+	// namespace are managed by the steps themselves
+	pods := &coreapi.PodList{}
+	if err := client.List(ctx, pods, ctrlruntimeclient.InNamespace(namespace), ctrlruntimeclient.MatchingLabels{steps.CreatedByCILabel: "true"}); err != nil {
+		logrus.WithError(err).Warnf("Failed to list the pods in namespace %s for heartbeating", namespace)
+		return
+	}
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if pod.Status.Phase != coreapi.PodRunning {
+			continue
+		}
+		if err := annotateActive(ctx, client, pod, active); err != nil && !kerrors.IsNotFound(err) {
+			logrus.WithError(err).Warnf("Failed to patch the %s pod to update the %s annotation.", pod.Name, nsttl.AnnotationNamespaceLastActive)
+		}
+	}
+}
+
+func annotateActive(ctx context.Context, client ctrlruntimeclient.Client, obj ctrlruntimeclient.Object, active string) error {
+	original := obj.DeepCopyObject().(ctrlruntimeclient.Object)
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[nsttl.AnnotationNamespaceLastActive] = active
+	obj.SetAnnotations(annotations)
+	return client.Patch(ctx, obj, ctrlruntimeclient.MergeFrom(original))
+}
+
+func monitorNamespace(ctx context.Context, cancel context.CancelCauseFunc, namespace string, client coreclientset.NamespaceInterface) {
 reset:
 	for {
 		watcher, err := client.Watch(context.Background(), meta.ListOptions{
@@ -2144,7 +2717,7 @@ reset:
 		})
 		if err != nil {
 			logrus.WithError(err).Warn("Could not start a watch on our test namespace.")
-			cancel()
+			cancel(fmt.Errorf("could not watch namespace %s: %w", namespace, err))
 			return
 		}
 		for {
@@ -2165,7 +2738,7 @@ reset:
 				}
 				if ns.DeletionTimestamp != nil {
 					logrus.Info("The namespace in which this test is executing has been deleted, cancelling the test...")
-					cancel()
+					cancel(fmt.Errorf("%s: %w while the test was running, most likely by a namespace TTL controller; it was last marked active at %s", namespace, errNamespaceDeleted, ns.Annotations[nsttl.AnnotationNamespaceLastActive]))
 					return
 				}
 			}
@@ -1,21 +1,27 @@
 package main
 
 import (
+	"bytes"
 	"compress/gzip"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 
+	coreapi "k8s.io/api/core/v1"
 	rbacapi "k8s.io/api/rbac/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	fakekubernetes "k8s.io/client-go/kubernetes/fake"
 	"k8s.io/client-go/kubernetes/scheme"
 	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
 	"k8s.io/test-infra/prow/pod-utils/downwardapi"
@@ -27,10 +33,12 @@ import (
 	imagev1 "github.com/openshift/api/image/v1"
 
 	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/api/nsttl"
 	"github.com/openshift/ci-tools/pkg/results"
 	"github.com/openshift/ci-tools/pkg/secrets"
 	"github.com/openshift/ci-tools/pkg/steps"
 	"github.com/openshift/ci-tools/pkg/steps/loggingclient"
+	"github.com/openshift/ci-tools/pkg/steps/multi_stage"
 	"github.com/openshift/ci-tools/pkg/testhelper"
 	utilgzip "github.com/openshift/ci-tools/pkg/util/gzip"
 )
@@ -1609,3 +1617,232 @@ func TestHandleTargetAdditionalSuffix(t *testing.T) {
 		})
 	}
 }
+
+func TestAddReusedClusterTargets(t *testing.T) {
+	tests := []api.TestStepConfiguration{
+		{As: "e2e", MultiStageTestConfigurationLiteral: &api.MultiStageTestConfigurationLiteral{ClusterProfile: api.ClusterProfileAWS}},
+		{As: "e2e-serial", MultiStageTestConfigurationLiteral: &api.MultiStageTestConfigurationLiteral{ReuseClusterFrom: "e2e"}},
+		{As: "e2e-disruptive", MultiStageTestConfigurationLiteral: &api.MultiStageTestConfigurationLiteral{ReuseClusterFrom: "e2e"}},
+		{As: "unit"},
+	}
+	for _, tc := range []struct {
+		name     string
+		targets  []string
+		expected []string
+	}{{
+		name:     "no test reusing a cluster",
+		targets:  []string{"unit"},
+		expected: []string{"unit"},
+	}, {
+		name:     "tests reusing a cluster",
+		targets:  []string{"e2e-serial", "e2e-disruptive"},
+		expected: []string{"e2e-serial", "e2e-disruptive", "e2e"},
+	}, {
+		name:     "test whose cluster is reused already targeted",
+		targets:  []string{"e2e", "e2e-serial"},
+		expected: []string{"e2e", "e2e-serial"},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			o := &options{configSpec: &api.ReleaseBuildConfiguration{Tests: tests}, targets: stringSlice{tc.targets}}
+			addReusedClusterTargets(o)
+			if diff := cmp.Diff(tc.expected, o.targets.values); diff != "" {
+				t.Errorf("unexpected targets: %s", diff)
+			}
+		})
+	}
+}
+
+func TestAddAggregatedTargets(t *testing.T) {
+	tests := []api.TestStepConfiguration{
+		{As: "e2e-aws"},
+		{As: "e2e-gcp"},
+		{As: "gate", AggregateTestConfiguration: &api.AggregateTestConfiguration{Tests: []string{"e2e-aws", "e2e-gcp"}}},
+		{As: "unit"},
+	}
+	for _, tc := range []struct {
+		name     string
+		targets  []string
+		expected []string
+	}{{
+		name:     "no aggregate test",
+		targets:  []string{"unit"},
+		expected: []string{"unit"},
+	}, {
+		name:     "aggregate test",
+		targets:  []string{"gate"},
+		expected: []string{"gate", "e2e-aws", "e2e-gcp"},
+	}, {
+		name:     "aggregated test already targeted",
+		targets:  []string{"e2e-gcp", "gate"},
+		expected: []string{"e2e-gcp", "gate", "e2e-aws"},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			o := &options{configSpec: &api.ReleaseBuildConfiguration{Tests: tests}, targets: stringSlice{tc.targets}}
+			addAggregatedTargets(o)
+			if diff := cmp.Diff(tc.expected, o.targets.values); diff != "" {
+				t.Errorf("unexpected targets: %s", diff)
+			}
+		})
+	}
+}
+
+func TestPrintResolvedTest(t *testing.T) {
+	config := &api.ReleaseBuildConfiguration{
+		Tests: []api.TestStepConfiguration{
+			{As: "unit", ContainerTestConfiguration: &api.ContainerTestConfiguration{From: "src"}},
+			{As: "e2e", MultiStageTestConfigurationLiteral: &api.MultiStageTestConfigurationLiteral{
+				Test:        []api.LiteralTestStep{{As: "test", From: "src", Commands: "make e2e"}},
+				Environment: api.TestEnvironment{"PARAM": "value"},
+			}},
+		},
+	}
+	testCases := []struct {
+		name        string
+		test        string
+		expected    string
+		expectedErr string
+	}{
+		{
+			name: "multi-stage test is printed",
+			test: "e2e",
+			expected: `cluster_profile: ""
+env:
+  PARAM: value
+test:
+- as: test
+  commands: make e2e
+  from: src
+  resources: {}
+`,
+		},
+		{
+			name:        "container test is rejected",
+			test:        "unit",
+			expectedErr: `test "unit" is not a resolved multi-stage test`,
+		},
+		{
+			name:        "unknown test is rejected",
+			test:        "missing",
+			expectedErr: `test "missing" not found in configuration`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var out bytes.Buffer
+			err := printResolvedTest(&out, config, tc.test)
+			var errMsg string
+			if err != nil {
+				errMsg = err.Error()
+			}
+			if diff := cmp.Diff(tc.expectedErr, errMsg); diff != "" {
+				t.Fatalf("unexpected error, diff: %s", diff)
+			}
+			if diff := cmp.Diff(tc.expected, out.String()); diff != "" {
+				t.Errorf("unexpected output, diff: %s", diff)
+			}
+		})
+	}
+}
+
+func TestMarkActive(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	ciLabels := map[string]string{steps.CreatedByCILabel: "true"}
+	client := fakectrlruntimeclient.NewClientBuilder().WithObjects(
+		&coreapi.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "ns", Annotations: map[string]string{nsttl.AnnotationNamespaceLastActive: "old"}}},
+		&coreapi.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "running", Labels: ciLabels}, Status: coreapi.PodStatus{Phase: coreapi.PodRunning}},
+		&coreapi.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "succeeded", Labels: ciLabels}, Status: coreapi.PodStatus{Phase: coreapi.PodSucceeded}},
+		&coreapi.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "created-by-step"}, Status: coreapi.PodStatus{Phase: coreapi.PodRunning}},
+	).Build()
+	markActive(ctx, client, "ns", now)
+	active := map[string]string{nsttl.AnnotationNamespaceLastActive: "2023-01-01T00:00:00Z"}
+	ns := &coreapi.Namespace{}
+	if err := client.Get(ctx, ctrlruntimeclient.ObjectKey{Name: "ns"}, ns); err != nil {
+		t.Fatal(err)
+	}
+	testhelper.Diff(t, "namespace annotations", ns.Annotations, active)
+	for name, expected := range map[string]map[string]string{"running": active, "succeeded": nil, "created-by-step": nil} {
+		pod := &coreapi.Pod{}
+		if err := client.Get(ctx, ctrlruntimeclient.ObjectKey{Namespace: "ns", Name: name}, pod); err != nil {
+			t.Fatal(err)
+		}
+		testhelper.Diff(t, name+" pod annotations", pod.Annotations, expected)
+	}
+}
+
+func TestRenderObjects(t *testing.T) {
+	config := &api.ReleaseBuildConfiguration{
+		Tests: []api.TestStepConfiguration{
+			{As: "unit", ContainerTestConfiguration: &api.ContainerTestConfiguration{From: "src"}},
+			{As: "e2e", MultiStageTestConfigurationLiteral: &api.MultiStageTestConfigurationLiteral{
+				Test: []api.LiteralTestStep{{As: "test", From: "src", Commands: "make e2e"}},
+			}},
+			{As: "other", MultiStageTestConfigurationLiteral: &api.MultiStageTestConfigurationLiteral{
+				Test: []api.LiteralTestStep{{As: "test", From: "src", Commands: "make other"}},
+			}},
+		},
+	}
+	jobSpec := &api.JobSpec{JobSpec: downwardapi.JobSpec{
+		Job:  "job",
+		Type: prowapi.PeriodicJob,
+		DecorationConfig: &prowapi.DecorationConfig{
+			Timeout:       &prowapi.Duration{Duration: time.Minute},
+			GracePeriod:   &prowapi.Duration{Duration: time.Second},
+			UtilityImages: &prowapi.UtilityImages{Sidecar: "sidecar", Entrypoint: "entrypoint"},
+		},
+	}}
+	jobSpec.SetNamespace(renderNamespace)
+	dir := t.TempDir()
+	if err := renderObjects(dir, config, jobSpec, multi_stage.Options{}, []string{"e2e"}); err != nil {
+		t.Fatal(err)
+	}
+	var files []string
+	if err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			rel, _ := filepath.Rel(dir, path)
+			files = append(files, rel)
+		}
+		return err
+	}); err != nil {
+		t.Fatal(err)
+	}
+	testhelper.Diff(t, "files", files, []string{"e2e/test.yaml", "e2e.yaml"})
+	raw, err := os.ReadFile(filepath.Join(dir, "e2e", "test.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	docs := strings.Split(string(raw), "---\n")
+	if len(docs) != 2 || !strings.Contains(docs[0], "kind: ConfigMap") || !strings.Contains(docs[1], "kind: Pod") {
+		t.Errorf("expected the commands and pod of the step, got:\n%s", raw)
+	}
+}
+
+func TestDebugAttach(t *testing.T) {
+	pod := &coreapi.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ci-op-1234", Name: "e2e-test", Annotations: map[string]string{multi_stage.DebugAnnotation: "true"}},
+		Spec:       coreapi.PodSpec{Containers: []coreapi.Container{{Name: "test", Image: "pipeline:tests"}}},
+		Status:     coreapi.PodStatus{Phase: coreapi.PodRunning},
+	}
+	client := fakekubernetes.NewSimpleClientset(pod)
+	o := debugOptions{namespace: "ci-op-1234", pod: "e2e-test"}
+	name, err := o.attach(context.Background(), client.CoreV1().Pods("ci-op-1234"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	testhelper.Diff(t, "container name", name, "debug-0")
+	updated, err := client.CoreV1().Pods("ci-op-1234").Get(context.Background(), "e2e-test", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(updated.Spec.EphemeralContainers); n != 1 {
+		t.Fatalf("expected one ephemeral container, got %d", n)
+	}
+	if name, err = o.attach(context.Background(), client.CoreV1().Pods("ci-op-1234")); err != nil {
+		t.Fatal(err)
+	}
+	testhelper.Diff(t, "second container name", name, "debug-1")
+	o.pod = "missing"
+	if _, err := o.attach(context.Background(), client.CoreV1().Pods("ci-op-1234")); err == nil {
+		t.Error("expected an error for a missing pod")
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	coreapi "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/openshift/ci-tools/pkg/api"
+)
+
+// namespaceQuotaName is the name of the ResourceQuota created in the test
+// namespace.
+const namespaceQuotaName = "ci-operator"
+
+// resourceListFlag parses a list of resources, e.g. `cpu=64,memory=256Gi`.
+type resourceListFlag struct {
+	values coreapi.ResourceList
+}
+
+func (f *resourceListFlag) String() string {
+	var ret []string
+	for name, q := range f.values {
+		ret = append(ret, fmt.Sprintf("%s=%s", name, q.String()))
+	}
+	sort.Strings(ret)
+	return strings.Join(ret, ",")
+}
+
+func (f *resourceListFlag) Set(value string) error {
+	values := coreapi.ResourceList{}
+	for _, item := range strings.Split(value, ",") {
+		name, quantity, ok := strings.Cut(item, "=")
+		if !ok || name == "" {
+			return fmt.Errorf("invalid resource %q, expected name=quantity", item)
+		}
+		q, err := resource.ParseQuantity(quantity)
+		if err != nil {
+			return fmt.Errorf("invalid quantity for resource %s: %w", name, err)
+		}
+		values[coreapi.ResourceName(name)] = q
+	}
+	f.values = values
+	return nil
+}
+
+// peakResourcer is implemented by steps which can compute the resources
+// requested by their pods which run at the same time.
+type peakResourcer interface {
+	PeakResources() coreapi.ResourceList
+}
+
+// resourceDemand returns the peak resources requested by the steps of the
+// graph.  Steps which do not depend on each other may run at the same time,
+// so their peaks are added.
+func resourceDemand(nodes api.OrderedStepList) coreapi.ResourceList {
+	demand := coreapi.ResourceList{}
+	for _, node := range nodes {
+		step, ok := node.Step.(peakResourcer)
+		if !ok {
+			continue
+		}
+		for name, q := range step.PeakResources() {
+			sum := demand[name]
+			sum.Add(q)
+			demand[name] = sum
+		}
+	}
+	return demand
+}
+
+// checkNamespaceQuota fails if the demand of the graph exceeds the quota of
+// the namespaces of the build farm, in which case the test could never run
+// to completion.
+func checkNamespaceQuota(demand, quota coreapi.ResourceList) error {
+	var exceeded []string
+	for name, limit := range quota {
+		if q, ok := demand[name]; ok && q.Cmp(limit) > 0 {
+			exceeded = append(exceeded, fmt.Sprintf("%s: %s requested, %s allowed", name, q.String(), limit.String()))
+		}
+	}
+	if len(exceeded) == 0 {
+		return nil
+	}
+	sort.Strings(exceeded)
+	return fmt.Errorf("the test cannot fit in the namespace quota of the build farm, its steps request more resources at the same time than the quota allows: %s", strings.Join(exceeded, ", "))
+}
+
+// namespaceResourceQuota sizes the quota of the test namespace to the demand
+// of the graph.  Room for one more pod with the default resources of the
+// configuration is added, for the builds and other pods which are not part of
+// the demand, and which usually run before the tests.  The quota never exceeds
+// the quota of the build farm.
+func namespaceResourceQuota(namespace string, demand coreapi.ResourceList, defaults api.ResourceList, quota coreapi.ResourceList) *coreapi.ResourceQuota {
+	hard := coreapi.ResourceList{}
+	for name, limit := range quota {
+		q := demand[name].DeepCopy()
+		if value, ok := defaults[string(name)]; ok {
+			if d, err := resource.ParseQuantity(value); err == nil {
+				q.Add(d)
+			}
+		}
+		if q.Cmp(limit) > 0 {
+			q = limit.DeepCopy()
+		}
+		hard[coreapi.ResourceName("requests."+string(name))] = q
+	}
+	return &coreapi.ResourceQuota{
+		ObjectMeta: meta.ObjectMeta{Namespace: namespace, Name: namespaceQuotaName},
+		Spec:       coreapi.ResourceQuotaSpec{Hard: hard},
+	}
+}
+
+// ensureNamespaceQuota creates the quota of the test namespace.  The quota of
+// a namespace which is reused is only ever raised, so that it fits the demand
+// of all the jobs using it.
+func ensureNamespaceQuota(ctx context.Context, client ctrlruntimeclient.Client, quota *coreapi.ResourceQuota) error {
+	logrus.Debugf("Creating resource quota %s: %v", quota.Name, quota.Spec.Hard)
+	err := client.Create(ctx, quota)
+	if kerrors.IsAlreadyExists(err) {
+		existing := &coreapi.ResourceQuota{}
+		if err := client.Get(ctx, ctrlruntimeclient.ObjectKeyFromObject(quota), existing); err != nil {
+			return fmt.Errorf("failed to get resource quota %s: %w", quota.Name, err)
+		}
+		if existing.Spec.Hard == nil {
+			existing.Spec.Hard = coreapi.ResourceList{}
+		}
+		for name, q := range quota.Spec.Hard {
+			if current, ok := existing.Spec.Hard[name]; !ok || q.Cmp(current) > 0 {
+				existing.Spec.Hard[name] = q
+			}
+		}
+		err = client.Update(ctx, existing)
+	}
+	if kerrors.IsForbidden(err) {
+		logrus.WithError(err).Warn("Could not create the resource quota of the namespace because you do not have permission to manage resource quotas.")
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create resource quota %s: %w", quota.Name, err)
+	}
+	return nil
+}
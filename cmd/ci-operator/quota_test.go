@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	coreapi "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	fakectrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/testhelper"
+)
+
+var equateQuantities = cmp.Comparer(func(a, b resource.Quantity) bool { return a.Cmp(b) == 0 })
+
+func TestResourceListFlag(t *testing.T) {
+	for _, tc := range []struct {
+		value    string
+		expected coreapi.ResourceList
+		err      error
+	}{{
+		value:    "cpu=64,memory=256Gi",
+		expected: coreapi.ResourceList{coreapi.ResourceCPU: resource.MustParse("64"), coreapi.ResourceMemory: resource.MustParse("256Gi")},
+	}, {
+		value: "cpu",
+		err:   errors.New(`invalid resource "cpu", expected name=quantity`),
+	}, {
+		value: "cpu=lots",
+		err:   errors.New("invalid quantity for resource cpu: quantities must match the regular expression '^([+-]?[0-9.]+)([eEinumkKMGTP]*[-+]?[0-9]*)$'"),
+	}} {
+		t.Run(tc.value, func(t *testing.T) {
+			var f resourceListFlag
+			err := f.Set(tc.value)
+			if diff := cmp.Diff(tc.err, err, testhelper.EquateErrorMessage); diff != "" {
+				t.Fatalf("unexpected error: %s", diff)
+			}
+			if diff := cmp.Diff(tc.expected, f.values, equateQuantities); diff != "" {
+				t.Errorf("unexpected resources: %s", diff)
+			}
+		})
+	}
+}
+
+func TestCheckNamespaceQuota(t *testing.T) {
+	quota := coreapi.ResourceList{coreapi.ResourceCPU: resource.MustParse("16"), coreapi.ResourceMemory: resource.MustParse("64Gi")}
+	for _, tc := range []struct {
+		name   string
+		demand coreapi.ResourceList
+		err    error
+	}{{
+		name:   "demand fits",
+		demand: coreapi.ResourceList{coreapi.ResourceCPU: resource.MustParse("16"), coreapi.ResourceMemory: resource.MustParse("8Gi"), "nvidia.com/gpu": resource.MustParse("1")},
+	}, {
+		name:   "demand exceeds the quota",
+		demand: coreapi.ResourceList{coreapi.ResourceCPU: resource.MustParse("24"), coreapi.ResourceMemory: resource.MustParse("128Gi")},
+		err:    errors.New("the test cannot fit in the namespace quota of the build farm, its steps request more resources at the same time than the quota allows: cpu: 24 requested, 16 allowed, memory: 128Gi requested, 64Gi allowed"),
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.err, checkNamespaceQuota(tc.demand, quota), testhelper.EquateErrorMessage); diff != "" {
+				t.Errorf("unexpected error: %s", diff)
+			}
+		})
+	}
+}
+
+func TestNamespaceResourceQuota(t *testing.T) {
+	demand := coreapi.ResourceList{coreapi.ResourceCPU: resource.MustParse("15"), coreapi.ResourceMemory: resource.MustParse("8Gi")}
+	defaults := api.ResourceList{"cpu": "2", "memory": "200Mi"}
+	quota := coreapi.ResourceList{coreapi.ResourceCPU: resource.MustParse("16"), coreapi.ResourceMemory: resource.MustParse("64Gi")}
+	expected := coreapi.ResourceList{"requests.cpu": resource.MustParse("16"), "requests.memory": resource.MustParse("8392Mi")}
+	actual := namespaceResourceQuota("ci-op-test", demand, defaults, quota)
+	if diff := cmp.Diff(expected, actual.Spec.Hard, equateQuantities); diff != "" {
+		t.Errorf("unexpected quota: %s", diff)
+	}
+
+	existing := &coreapi.ResourceQuota{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ci-op-test", Name: namespaceQuotaName},
+		Spec:       coreapi.ResourceQuotaSpec{Hard: coreapi.ResourceList{"requests.cpu": resource.MustParse("8"), "requests.memory": resource.MustParse("32Gi")}},
+	}
+	client := fakectrlruntimeclient.NewClientBuilder().WithObjects(existing).Build()
+	if err := ensureNamespaceQuota(context.Background(), client, actual); err != nil {
+		t.Fatal(err)
+	}
+	updated := &coreapi.ResourceQuota{}
+	if err := client.Get(context.Background(), ctrlruntimeclient.ObjectKeyFromObject(existing), updated); err != nil {
+		t.Fatal(err)
+	}
+	expected = coreapi.ResourceList{"requests.cpu": resource.MustParse("16"), "requests.memory": resource.MustParse("32Gi")}
+	if diff := cmp.Diff(expected, updated.Spec.Hard, equateQuantities); diff != "" {
+		t.Errorf("the quota of a reused namespace was not raised: %s", diff)
+	}
+}
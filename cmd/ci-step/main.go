@@ -0,0 +1,50 @@
+// ci-step is the helper with which the commands of multi-stage test steps
+// communicate with ci-operator.  It is copied into the pods of steps with the
+// entrypoint wrapper, which adds it to the $PATH of the command.
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/openshift/ci-tools/pkg/steps/stepruntime"
+)
+
+const usage = `Usage:
+  ci-step output set KEY VALUE   publish a value of the step
+  ci-step artifact add FILE      add a file to the artifacts of the step
+  ci-step mark-flaky REASON      report that the step observed a flake
+`
+
+func main() {
+	if err := run(os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string) error {
+	if len(args) == 1 && (args[0] == "-h" || args[0] == "--help" || args[0] == "help") {
+		fmt.Print(usage)
+		return nil
+	}
+	dir, set := os.LookupEnv(stepruntime.DirEnv)
+	if !set {
+		return fmt.Errorf("$%s is not set, ci-step must be run by the command of a multi-stage test step", stepruntime.DirEnv)
+	}
+	switch {
+	case len(args) == 4 && args[0] == "output" && args[1] == "set":
+		return stepruntime.SetOutput(dir, args[2], args[3])
+	case len(args) == 3 && args[0] == "artifact" && args[1] == "add":
+		artifactDir, set := os.LookupEnv("ARTIFACT_DIR")
+		if !set {
+			return errors.New("$ARTIFACT_DIR is not set")
+		}
+		return stepruntime.AddArtifact(dir, artifactDir, args[2])
+	case len(args) >= 2 && args[0] == "mark-flaky":
+		return stepruntime.MarkFlaky(dir, strings.Join(args[1:], " "))
+	}
+	return fmt.Errorf("invalid arguments: %s\n%s", strings.Join(args, " "), usage)
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/openshift/ci-tools/pkg/steps/stepruntime"
+	"github.com/openshift/ci-tools/pkg/testhelper"
+)
+
+func TestRun(t *testing.T) {
+	for _, tc := range []struct {
+		name          string
+		args          [][]string
+		noRuntimeDir  bool
+		noArtifactDir bool
+		expectedErr   string
+		expected      *stepruntime.Record
+	}{{
+		name:     "help",
+		args:     [][]string{{"--help"}},
+		expected: &stepruntime.Record{},
+	}, {
+		name:         "not run by a step",
+		args:         [][]string{{"output", "set", "key", "value"}},
+		noRuntimeDir: true,
+		expectedErr:  "$CI_STEP_RUNTIME_DIR is not set, ci-step must be run by the command of a multi-stage test step",
+	}, {
+		name: "outputs",
+		args: [][]string{
+			{"output", "set", "cluster_id", "1234"},
+			{"output", "set", "region", "us-east-1"},
+			{"output", "set", "cluster_id", "5678"},
+		},
+		expected: &stepruntime.Record{Outputs: map[string]string{"cluster_id": "5678", "region": "us-east-1"}},
+	}, {
+		name:        "invalid output key",
+		args:        [][]string{{"output", "set", "cluster id", "1234"}},
+		expectedErr: `invalid output key "cluster id": must match ^[a-zA-Z_][a-zA-Z0-9_.-]*$`,
+		expected:    &stepruntime.Record{},
+	}, {
+		name:     "artifacts",
+		args:     [][]string{{"artifact", "add", "report.txt"}, {"artifact", "add", "report.txt"}},
+		expected: &stepruntime.Record{Artifacts: []string{"ci-step/report.txt"}},
+	}, {
+		name:          "artifact without an artifact directory",
+		args:          [][]string{{"artifact", "add", "report.txt"}},
+		noArtifactDir: true,
+		expectedErr:   "$ARTIFACT_DIR is not set",
+		expected:      &stepruntime.Record{},
+	}, {
+		name:     "flakes",
+		args:     [][]string{{"mark-flaky", "registry", "timed", "out"}, {"mark-flaky", "DNS"}},
+		expected: &stepruntime.Record{Flaky: []string{"registry timed out", "DNS"}},
+	}, {
+		name:        "flake without a reason",
+		args:        [][]string{{"mark-flaky"}},
+		expectedErr: "invalid arguments: mark-flaky\n" + usage,
+		expected:    &stepruntime.Record{},
+	}, {
+		name:        "unknown command",
+		args:        [][]string{{"output", "get", "key"}},
+		expectedErr: "invalid arguments: output get key\n" + usage,
+		expected:    &stepruntime.Record{},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			dir, artifactDir := t.TempDir(), t.TempDir()
+			t.Setenv(stepruntime.DirEnv, dir)
+			t.Setenv("ARTIFACT_DIR", artifactDir)
+			if tc.noRuntimeDir {
+				os.Unsetenv(stepruntime.DirEnv)
+			}
+			if tc.noArtifactDir {
+				os.Unsetenv("ARTIFACT_DIR")
+			}
+			source := filepath.Join(t.TempDir(), "report.txt")
+			if err := os.WriteFile(source, []byte("passed"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			var err error
+			for _, args := range tc.args {
+				if args[0] == "artifact" {
+					args = append(args[:2:2], source)
+				}
+				if err = run(args); err != nil {
+					break
+				}
+			}
+			var actualErr string
+			if err != nil {
+				actualErr = err.Error()
+			}
+			testhelper.Diff(t, "error", actualErr, tc.expectedErr)
+			if tc.expected == nil {
+				return
+			}
+			record, err := stepruntime.Load(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			testhelper.Diff(t, "record", record, tc.expected)
+		})
+	}
+}
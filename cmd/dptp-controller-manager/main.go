@@ -33,6 +33,7 @@ import (
 
 	"github.com/openshift/ci-tools/pkg/api"
 	"github.com/openshift/ci-tools/pkg/config"
+	"github.com/openshift/ci-tools/pkg/controller/postphasefinalizercleaner"
 	"github.com/openshift/ci-tools/pkg/controller/promotionreconciler"
 	serviceaccountsecretrefresher "github.com/openshift/ci-tools/pkg/controller/serviceaccount_secret_refresher"
 	testimagesdistributor "github.com/openshift/ci-tools/pkg/controller/test-images-distributor"
@@ -51,6 +52,7 @@ var allControllers = sets.New[string](
 	testimagesdistributor.ControllerName,
 	serviceaccountsecretrefresher.ControllerName,
 	testimagestreamimportcleaner.ControllerName,
+	postphasefinalizercleaner.ControllerName,
 )
 
 type options struct {
@@ -506,6 +508,12 @@ func main() {
 		}
 	}
 
+	if opts.enabledControllersSet.Has(postphasefinalizercleaner.ControllerName) {
+		if err := postphasefinalizercleaner.AddToManager(mgr, allManagers); err != nil {
+			logrus.WithError(err).Fatal("Failed to construct the postphasefinalizercleaner controller")
+		}
+	}
+
 	if err := mgr.Start(ctx); err != nil {
 		logrus.WithError(err).Fatal("Manager ended with error")
 	}
@@ -1,19 +1,23 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"io/fs"
 	"log"
+	"net/http"
 	"os"
 	"os/exec"
 	"os/signal"
 	"path"
 	"path/filepath"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
@@ -29,8 +33,16 @@ import (
 	"k8s.io/apimachinery/pkg/util/wait"
 	coreclientset "k8s.io/client-go/kubernetes/typed/core/v1"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/test-infra/prow/flagutil"
 
 	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/steps/compression"
+	"github.com/openshift/ci-tools/pkg/steps/logs"
+	"github.com/openshift/ci-tools/pkg/steps/metrics"
+	"github.com/openshift/ci-tools/pkg/steps/shareddir"
+	"github.com/openshift/ci-tools/pkg/steps/stepruntime"
+	"github.com/openshift/ci-tools/pkg/steps/testresults"
 	"github.com/openshift/ci-tools/pkg/util"
 )
 
@@ -75,13 +87,29 @@ type options struct {
 	name             string
 	srcPath          string
 	dstPath          string
-	waitPath         string
+	waitPaths        flagutil.Strings
 	waitTimeoutStr   string
 	waitTimeout      time.Duration
 	mode             string
 	rwKubeconfig     bool
 	uploadKubeconfig bool
 	updateSharedDir  bool
+	metricsSink      string
+	recordThrottling bool
+	metricsStep      string
+	logsMaxSize      int64
+	logsBackups      int
+	compression      string
+	compressionMin   int64
+	junitStep        string
+	runtimeDir       string
+	sharedDirKeyPath string
+	sharedDirKey     []byte
+	sharedDirStep    string
+	sharedDir        *sharedDirState
+	sharedDirOutputs string
+	stdinEnv         string
+	stdinSharedFile  string
 	cmd              []string
 	client           coreclientset.SecretInterface
 }
@@ -89,9 +117,22 @@ type options struct {
 func bindOptions(flag *flag.FlagSet) *options {
 	opt := &options{}
 	flag.BoolVar(&opt.dry, "dry-run", false, "Print the secret instead of creating it")
-	flag.StringVar(&opt.waitPath, "wait-for-file", "", "Wait for a file to appear at this path before starting the program")
-	flag.StringVar(&opt.waitTimeoutStr, "wait-timeout", "", "Used with --wait-for-file, maximum wait time before starting the program")
+	flag.Var(&opt.waitPaths, "wait-for-file", "Wait for a file to appear at this path before starting the program, may be passed multiple times")
+	flag.StringVar(&opt.waitTimeoutStr, "wait-timeout", "", "Used with --wait-for-file, maximum wait time for each file before starting the program")
 	flag.StringVar(&opt.mode, "mode", manageKubeconfigMode, fmt.Sprintf("Set how kubeconfig should be managed. Allowed values are: %s, %s or %s", manageKubeconfigMode, skipKubeconfigMode, observerMode))
+	flag.BoolVar(&opt.recordThrottling, "record-cpu-throttling", false, "If set, record the CPU throttling of the step in $ARTIFACT_DIR/metrics once the command exits")
+	flag.StringVar(&opt.metricsSink, "metrics-sink", "", "If set, forward metrics published by the step in $ARTIFACT_DIR/metrics to this URL")
+	flag.StringVar(&opt.metricsStep, "metrics-step", "", "Name of the step, used to label forwarded metrics")
+	flag.Int64Var(&opt.logsMaxSize, "logs-max-size", 0, "Maximum size in bytes of the files in $ARTIFACT_DIR/logs holding the standard output and error of the command, they are only written when set")
+	flag.IntVar(&opt.logsBackups, "logs-max-backups", 2, "Number of rotated files kept in $ARTIFACT_DIR/logs for each output stream")
+	flag.StringVar(&opt.compression, "artifact-compression", "", fmt.Sprintf("If set, compress files in $ARTIFACT_DIR and write a manifest of their checksums. Allowed values are: %v", compression.Algorithms))
+	flag.Int64Var(&opt.compressionMin, "artifact-compression-threshold", 1024*1024, "Size in bytes above which artifacts are compressed")
+	flag.StringVar(&opt.sharedDirKeyPath, "shared-dir-key", "", "If set, decrypt the contents of $SHARED_DIR with the key in this file and encrypt them when they are updated")
+	flag.StringVar(&opt.sharedDirStep, "shared-dir-step", "", "Name of the step, recorded in the signed provenance of $SHARED_DIR when --shared-dir-key is set")
+	flag.StringVar(&opt.sharedDirOutputs, "provides-shared-files", "", "Comma-separated list of files the command must write to $SHARED_DIR, the step fails if any of them is missing after the command succeeds")
+	flag.StringVar(&opt.stdinEnv, "stdin-from-env", "", "If set, write the value of this environment variable, followed by a newline, to the standard input of the command")
+	flag.StringVar(&opt.stdinSharedFile, "stdin-from-shared-file", "", "If set, write the contents of this file in $SHARED_DIR to the standard input of the command")
+	flag.StringVar(&opt.junitStep, "junit-step", "", fmt.Sprintf("If set, store the jUnit results in $ARTIFACT_DIR/**/%s under this key in the results secret of the test", testresults.Pattern))
 	return opt
 }
 
@@ -99,8 +140,11 @@ func (o *options) complete() error {
 	if len(o.cmd) == 0 {
 		return fmt.Errorf("a command is required")
 	}
+	if o.stdinEnv != "" && o.stdinSharedFile != "" {
+		return fmt.Errorf("--stdin-from-env and --stdin-from-shared-file are mutually exclusive")
+	}
 	if w := o.waitTimeoutStr; w != "" {
-		if o.waitPath == "" {
+		if len(o.waitPaths.Strings()) == 0 {
 			return fmt.Errorf("--wait-timeout requires --wait-for-file")
 		}
 		if d, err := time.ParseDuration(w); err != nil {
@@ -125,6 +169,15 @@ func (o *options) complete() error {
 	if err := o.validateMode(); err != nil {
 		return err
 	}
+	if o.compression != "" && !compression.Algorithm(o.compression).Valid() {
+		return fmt.Errorf("invalid --artifact-compression: %q", o.compression)
+	}
+	if o.sharedDirKeyPath != "" {
+		var err error
+		if o.sharedDirKey, err = os.ReadFile(o.sharedDirKeyPath); err != nil {
+			return fmt.Errorf("failed to read shared directory key: %w", err)
+		}
+	}
 
 	if !o.dry && o.mode != skipKubeconfigMode {
 		var err error
@@ -156,28 +209,77 @@ func (o *options) validateMode() error {
 }
 
 func (o *options) run() (exitCode int, err error) {
-	if err := copyDir(o.dstPath, o.srcPath); err != nil {
+	if err := copyDir(o.dstPath, o.srcPath, o.sharedDirKey); err != nil {
 		return errorCode, fmt.Errorf("failed to copy secret mount: %w", err)
 	}
-	if o.waitPath != "" {
-		if err := waitForFile(o.waitPath, o.waitTimeout); err != nil {
+	o.sharedDir = &sharedDirState{}
+	if o.sharedDirKey != nil {
+		if o.sharedDir.provenance, err = loadProvenance(o.dstPath, o.sharedDirStep, o.sharedDirKey); err != nil {
+			return errorCode, err
+		}
+	}
+	base, err := util.SecretFromDir(o.dstPath)
+	if err != nil {
+		return errorCode, fmt.Errorf("failed to read shared directory: %w", err)
+	}
+	o.sharedDir.base = base.Data
+	for _, path := range o.waitPaths.Strings() {
+		if err := waitForFile(path, o.waitTimeout); err != nil {
 			return errorCode, fmt.Errorf("failed to wait for file: %w", err)
 		}
 	}
+	if dir, err := os.MkdirTemp("", "ci-step"); err != nil {
+		logrus.WithError(err).Warn("Failed to create the step runtime directory, ci-step will not be available.")
+	} else {
+		o.runtimeDir = dir
+		defer os.RemoveAll(dir)
+	}
 	var errs []error
 	ctx, cancel := context.WithCancel(context.Background())
 	if o.uploadKubeconfig {
-		go uploadKubeconfig(ctx, o.client, o.name, o.dstPath, o.dry)
+		go uploadKubeconfig(ctx, o.client, o.name, o.dstPath, o.sharedDirKey, o.sharedDir, o.dry)
 	}
 	if exitCode, err = o.execCmd(); err != nil {
 		errs = append(errs, fmt.Errorf("failed to execute wrapped command: %w", err))
 	}
+	if missing := o.missingOutputs(); len(missing) != 0 {
+		err := fmt.Errorf("the step did not provide the files it declares in $SHARED_DIR: %s", strings.Join(missing, ", "))
+		if exitCode == 0 {
+			exitCode = errorCode
+			errs = append(errs, err)
+		} else {
+			logrus.WithError(err).Warn("Missing declared outputs.")
+		}
+	}
 	// we will upload the secret from the post-execution state, so we know
 	// that the best-effort upload of the kubeconfig can exit now and so as
 	// not to race with the post-execution one
 	cancel()
+	if o.recordThrottling {
+		if err := recordCPUThrottling(); err != nil {
+			logrus.WithError(err).Warn("Failed to record CPU throttling.")
+		}
+	}
+	if o.metricsSink != "" {
+		if err := o.forwardMetrics(); err != nil {
+			logrus.WithError(err).Warn("Failed to forward step metrics.")
+		}
+	}
+	if o.junitStep != "" {
+		if err := o.uploadJUnit(); err != nil {
+			logrus.WithError(err).Warn("Failed to upload jUnit results.")
+		}
+		if err := o.uploadRuntimeRecord(); err != nil {
+			logrus.WithError(err).Warn("Failed to upload the requests made with ci-step.")
+		}
+	}
+	if o.compression != "" {
+		if err := processArtifacts(compression.Algorithm(o.compression), o.compressionMin); err != nil {
+			logrus.WithError(err).Warn("Failed to compress artifacts.")
+		}
+	}
 	if o.updateSharedDir {
-		if err := createSecret(o.client, o.name, o.dstPath, o.dry); err != nil {
+		if err := createSecret(o.client, o.name, o.dstPath, o.sharedDirKey, o.sharedDir, o.dry); err != nil {
 			errs = append(errs, fmt.Errorf("failed to create/update secret: %w", err))
 			return errorCode, utilerrors.NewAggregate(errs)
 		}
@@ -185,6 +287,138 @@ func (o *options) run() (exitCode int, err error) {
 	return exitCode, utilerrors.NewAggregate(errs)
 }
 
+// missingOutputs returns the files declared as provided by the step which
+// are not in the shared directory.
+func (o *options) missingOutputs() []string {
+	var missing []string
+	for _, file := range strings.Split(o.sharedDirOutputs, ",") {
+		if file == "" {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(o.dstPath, file)); err != nil {
+			missing = append(missing, file)
+		}
+	}
+	return missing
+}
+
+// recordCPUThrottling writes the CPU throttling of the container as metrics
+// of the step.
+func recordCPUThrottling() error {
+	dir, set := os.LookupEnv("ARTIFACT_DIR")
+	if !set {
+		return nil
+	}
+	throttling, err := metrics.ReadCPUThrottling("/")
+	if err != nil {
+		return err
+	}
+	return throttling.Write(filepath.Join(dir, metrics.Dir))
+}
+
+// forwardMetrics sends the metrics published by the step to the sink,
+// labeled with the identity of the job.
+func (o *options) forwardMetrics() error {
+	dir, set := os.LookupEnv("ARTIFACT_DIR")
+	if !set {
+		return nil
+	}
+	labels := map[string]string{
+		"job":      os.Getenv("JOB_NAME"),
+		"build_id": os.Getenv("BUILD_ID"),
+		"test":     o.name,
+		"step":     o.metricsStep,
+	}
+	families, err := metrics.Collect(filepath.Join(dir, metrics.Dir), labels)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	return metrics.Forward(ctx, http.DefaultClient, o.metricsSink, families)
+}
+
+// uploadJUnit merges the jUnit results written by the step and stores them in
+// the results secret of the test.
+func (o *options) uploadJUnit() error {
+	dir, set := os.LookupEnv("ARTIFACT_DIR")
+	if !set {
+		return nil
+	}
+	suites, collectErr := testresults.Collect(dir)
+	if suites == nil || len(suites.Suites) == 0 {
+		return collectErr
+	}
+	data, err := testresults.Encode(suites)
+	if err != nil {
+		return err
+	}
+	if o.dry {
+		logrus.Infof("Collected %d jUnit suites for step %s", len(suites.Suites), o.junitStep)
+		return collectErr
+	}
+	if err := o.updateResults(o.junitStep, data); err != nil {
+		return err
+	}
+	return collectErr
+}
+
+// uploadRuntimeRecord stores the requests made by the command with ci-step in
+// the results secret of the test.
+func (o *options) uploadRuntimeRecord() error {
+	if o.runtimeDir == "" {
+		return nil
+	}
+	record, err := stepruntime.Load(o.runtimeDir)
+	if err != nil || record.Empty() {
+		return err
+	}
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal step runtime record: %w", err)
+	}
+	if o.dry {
+		logrus.Infof("Collected step runtime record for step %s: %s", o.junitStep, string(data))
+		return nil
+	}
+	return o.updateResults(stepruntime.SecretKey(o.junitStep), data)
+}
+
+// updateResults sets a key of the results secret of the test.
+func (o *options) updateResults(key string, data []byte) error {
+	if o.client == nil {
+		return errors.New("no client is available to upload results")
+	}
+	name := testresults.SecretName(o.name)
+	err := retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+		secret, err := o.client.Get(context.TODO(), name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		if secret.Data == nil {
+			secret.Data = map[string][]byte{}
+		}
+		secret.Data[key] = data
+		_, err = o.client.Update(context.TODO(), secret, metav1.UpdateOptions{})
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update secret %s: %w", name, err)
+	}
+	return nil
+}
+
+// processArtifacts compresses the artifacts of the step and writes their
+// manifest.
+func processArtifacts(algorithm compression.Algorithm, threshold int64) error {
+	dir, set := os.LookupEnv("ARTIFACT_DIR")
+	if !set {
+		return nil
+	}
+	_, err := compression.Process(dir, algorithm, threshold)
+	return err
+}
+
 func loadClient(namespace string) (coreclientset.SecretInterface, error) {
 	config, err := rest.InClusterConfig()
 	if err != nil {
@@ -197,7 +431,9 @@ func loadClient(namespace string) (coreclientset.SecretInterface, error) {
 	return client.Secrets(namespace), nil
 }
 
-func copyDir(dst, src string) error {
+// copyDir copies the files in `src` to `dst`, decrypting them if a key is
+// provided.
+func copyDir(dst, src string, key []byte) error {
 	if err := os.MkdirAll(dst, 0770); err != nil {
 		return err
 	}
@@ -216,6 +452,12 @@ func copyDir(dst, src string) error {
 		} else if stat.IsDir() {
 			continue
 		}
+		if key != nil {
+			if err := decryptFile(filepath.Join(dst, f), srcPath, key); err != nil {
+				return err
+			}
+			continue
+		}
 		srcFD, err := os.Open(srcPath)
 		if err != nil {
 			return err
@@ -234,6 +476,18 @@ func copyDir(dst, src string) error {
 	return nil
 }
 
+func decryptFile(dst, src string, key []byte) error {
+	raw, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	data, err := shareddir.Decrypt(key, raw)
+	if err != nil {
+		return fmt.Errorf("%s: %w", src, err)
+	}
+	return os.WriteFile(dst, data, 0640)
+}
+
 func waitForFile(path string, timeout time.Duration) error {
 	dir := filepath.Dir(path)
 	watcher, err := fsnotify.NewWatcher()
@@ -273,6 +527,9 @@ func (o *options) execCmd() (exitCode int, err error) {
 	proc := exec.Command(argv[0], argv[1:]...)
 	proc.Stdout = os.Stdout
 	proc.Stderr = os.Stderr
+	if closeLogs := o.splitLogs(proc); closeLogs != nil {
+		defer closeLogs()
+	}
 	if proc.Env == nil {
 		// the command inherits the environment if it's nil,
 		// explicitly set it so when we change it, we add to
@@ -284,6 +541,12 @@ func (o *options) execCmd() (exitCode int, err error) {
 		return errorCode, fmt.Errorf("failed to create Git configuration: %w", err)
 	}
 	manageCLI(proc)
+	o.manageStepRuntime(proc)
+	if closeStdin, err := o.manageStdin(proc); err != nil {
+		return errorCode, err
+	} else if closeStdin != nil {
+		defer closeStdin()
+	}
 	if o.rwKubeconfig {
 		if err := manageKubeconfig(proc); err != nil {
 			return errorCode, err
@@ -311,17 +574,118 @@ func (o *options) execCmd() (exitCode int, err error) {
 	}()
 	// we have to Wait() for the process before we can call ExitCode()
 	err = proc.Wait()
+	if errors.Is(err, exec.ErrWaitDelay) {
+		logrus.Warn("Processes started by the command still hold its output open, output written after it exited is not captured.")
+		err = nil
+	}
 	return proc.ProcessState.ExitCode(), err
 }
 
+// manageStdin feeds the standard input of the command from an environment
+// variable or a file in the shared directory, if requested.
+func (o *options) manageStdin(proc *exec.Cmd) (func(), error) {
+	switch {
+	case o.stdinEnv != "":
+		value := os.Getenv(o.stdinEnv)
+		if !strings.HasSuffix(value, "\n") {
+			value += "\n"
+		}
+		proc.Stdin = strings.NewReader(value)
+	case o.stdinSharedFile != "":
+		f, err := os.Open(filepath.Join(o.dstPath, o.stdinSharedFile))
+		if err != nil {
+			return nil, fmt.Errorf("failed to open the standard input of the command: %w", err)
+		}
+		proc.Stdin = f
+		return func() { _ = f.Close() }, nil
+	}
+	return nil, nil
+}
+
+// splitLogs additionally writes the standard output and error of the command
+// to separate, size-capped files in the artifact directory.  Failing to do so
+// is not fatal, the combined output is always available in the container log.
+func (o *options) splitLogs(proc *exec.Cmd) func() {
+	dir, set := os.LookupEnv("ARTIFACT_DIR")
+	if !set || o.logsMaxSize <= 0 {
+		return nil
+	}
+	stdout, err := logs.NewRotatingFile(filepath.Join(dir, logs.Dir, logs.Stdout), o.logsMaxSize, o.logsBackups)
+	if err != nil {
+		logrus.WithError(err).Warn("Failed to create the standard output log file.")
+		return nil
+	}
+	stderr, err := logs.NewRotatingFile(filepath.Join(dir, logs.Dir, logs.Stderr), o.logsMaxSize, o.logsBackups)
+	if err != nil {
+		logrus.WithError(err).Warn("Failed to create the standard error log file.")
+		_ = stdout.Close()
+		return nil
+	}
+	proc.Stdout = io.MultiWriter(os.Stdout, &bestEffortWriter{name: logs.Stdout, w: stdout})
+	proc.Stderr = io.MultiWriter(os.Stderr, &bestEffortWriter{name: logs.Stderr, w: stderr})
+	// the output is now copied from pipes, which background processes started
+	// by the command may keep open after it exits: do not wait for them forever
+	proc.WaitDelay = 10 * time.Second
+	return func() {
+		for _, f := range []*logs.RotatingFile{stdout, stderr} {
+			if err := f.Close(); err != nil {
+				logrus.WithError(err).Warn("Failed to close log file.")
+			}
+		}
+	}
+}
+
+// bestEffortWriter ignores errors from the underlying writer, so that a
+// failure to write the log files does not interrupt the output of the
+// command.  Only the first error is reported.
+type bestEffortWriter struct {
+	name   string
+	w      io.Writer
+	failed bool
+}
+
+func (w *bestEffortWriter) Write(p []byte) (int, error) {
+	if !w.failed {
+		if _, err := w.w.Write(p); err != nil {
+			logrus.WithError(err).Warnf("Failed to write to %s, it will be incomplete.", w.name)
+			w.failed = true
+		}
+	}
+	return len(p), nil
+}
+
 // manageCLI configures the PATH to include a CLI_DIR if one was provided
 func manageCLI(proc *exec.Cmd) {
 	cliDir, set := os.LookupEnv(api.CliEnv)
 	if set {
-		proc.Env = append(proc.Env, fmt.Sprintf("PATH=%s:%s", os.Getenv("PATH"), cliDir))
+		proc.Env = append(proc.Env, fmt.Sprintf("PATH=%s:%s", envValue(proc.Env, "PATH"), cliDir))
 	}
 }
 
+// manageStepRuntime exposes the ci-step helper, which is copied next to the
+// wrapper, and the directory in which it records the requests of the command.
+func (o *options) manageStepRuntime(proc *exec.Cmd) {
+	if o.runtimeDir == "" {
+		return
+	}
+	proc.Env = append(proc.Env, fmt.Sprintf("%s=%s", stepruntime.DirEnv, o.runtimeDir))
+	if exe, err := os.Executable(); err == nil {
+		proc.Env = append(proc.Env, fmt.Sprintf("PATH=%s:%s", envValue(proc.Env, "PATH"), filepath.Dir(exe)))
+	}
+}
+
+// envValue returns the value of a variable in an environment, which is the
+// last of any duplicate keys.
+func envValue(env []string, key string) string {
+	var ret string
+	for _, kv := range env {
+		if k, v, ok := strings.Cut(kv, "="); ok && k == key {
+			ret = v
+		}
+	}
+	return ret
+}
+
 // manageHome provides a writeable home so kubectl discovery can be cached
 func manageHome(proc *exec.Cmd) string {
 	home, set := os.LookupEnv("HOME")
@@ -416,33 +780,152 @@ func manageKubeconfig(proc *exec.Cmd) error {
 	return nil
 }
 
-func createSecret(client coreclientset.SecretInterface, name, dir string, dry bool) error {
+// createSecret stores the changes made to the shared directory since this step
+// last stored it.  Steps of shards and gangs update the secret in parallel, so
+// the changes are applied to its current contents and retried on conflicts
+// instead of replacing them.  When two steps change the same file, the last
+// update wins.
+func createSecret(client coreclientset.SecretInterface, name, dir string, key []byte, state *sharedDirState, dry bool) error {
 	if _, err := os.Stat(dir); err != nil {
 		if os.IsNotExist(err) {
 			return nil
 		}
 		return fmt.Errorf("failed to stat directory %q: %w", dir, err)
 	}
-	secret, err := util.SecretFromDir(dir)
+	local, err := util.SecretFromDir(dir)
 	if err != nil {
 		return fmt.Errorf("failed to generate secret: %w", err)
 	}
-	secret.Name = name
-	if secret.Labels == nil {
-		secret.Labels = map[string]string{}
-	}
-	secret.Labels[api.SkipCensoringLabel] = "true"
+	state.lock.Lock()
+	defer state.lock.Unlock()
+	var chain shareddir.Chain
 	if dry {
-		err := encoder.Encode(secret, os.Stdout)
-		if err != nil {
+		if state.provenance != nil {
+			chain = state.provenance.chain
+		}
+		secret := &coreapi.Secret{Type: coreapi.SecretTypeOpaque}
+		if secret.Data, chain, err = state.merge(key, state.base, chain, local.Data); err != nil {
+			return err
+		}
+		secret.Name = name
+		secret.Labels = map[string]string{api.SkipCensoringLabel: "true"}
+		if err := encoder.Encode(secret, os.Stdout); err != nil {
 			return fmt.Errorf("failed to log secret: %w", err)
 		}
-	} else if _, err := client.Update(context.TODO(), secret, metav1.UpdateOptions{}); err != nil {
+	} else if err := retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+		secret, err := client.Get(context.TODO(), name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		current := secret.Data
+		if key != nil {
+			if current, err = shareddir.DecryptData(key, current); err != nil {
+				return fmt.Errorf("failed to decrypt secret: %w", err)
+			}
+		}
+		chain = nil
+		if state.provenance != nil {
+			if current, chain, err = shareddir.VerifiedData(key, current); err != nil {
+				return err
+			}
+		}
+		if secret.Data, chain, err = state.merge(key, current, chain, local.Data); err != nil {
+			return err
+		}
+		if secret.Labels == nil {
+			secret.Labels = map[string]string{}
+		}
+		secret.Labels[api.SkipCensoringLabel] = "true"
+		_, err = client.Update(context.TODO(), secret, metav1.UpdateOptions{})
+		return err
+	}); err != nil {
 		return fmt.Errorf("failed to update secret: %w", err)
 	}
+	state.base = local.Data
+	if state.provenance != nil {
+		state.provenance.chain = chain
+	}
 	return nil
 }
 
+// sharedDirState holds the contents of the shared directory as this step last
+// stored them, which its changes are computed from.
+type sharedDirState struct {
+	lock sync.Mutex
+	base map[string][]byte
+	// provenance is only set when the shared directory is encrypted
+	provenance *provenance
+}
+
+// merge applies the changes between the base and the local contents of the
+// shared directory to the current contents of the secret and returns the data
+// to store, signed and encrypted if a key is set.
+func (s *sharedDirState) merge(key []byte, current map[string][]byte, chain shareddir.Chain, local map[string][]byte) (map[string][]byte, shareddir.Chain, error) {
+	data := make(map[string][]byte, len(current)+len(local))
+	for k, v := range current {
+		data[k] = v
+	}
+	for k := range s.base {
+		if _, ok := local[k]; !ok {
+			delete(data, k)
+		}
+	}
+	for k, v := range local {
+		if base, ok := s.base[k]; !ok || !bytes.Equal(base, v) {
+			data[k] = v
+		}
+	}
+	if s.provenance != nil {
+		chain = chain.Append(key, s.provenance.step, data)
+		raw, err := chain.Marshal()
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to marshal provenance: %w", err)
+		}
+		data[shareddir.ProvenanceKey] = raw
+	}
+	if key != nil {
+		var err error
+		if data, err = shareddir.EncryptData(key, data); err != nil {
+			return nil, nil, fmt.Errorf("failed to encrypt secret: %w", err)
+		}
+	}
+	return data, chain, nil
+}
+
+// provenance holds the signed records of the updates of the shared directory
+// up to the contents this step last stored.
+type provenance struct {
+	step  string
+	chain shareddir.Chain
+}
+
+// loadProvenance removes the provenance records from the decrypted copy of
+// the shared directory and verifies them against its contents.
+func loadProvenance(dir, step string, key []byte) (*provenance, error) {
+	path := filepath.Join(dir, shareddir.ProvenanceKey)
+	raw, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read provenance: %w", err)
+	}
+	if err == nil {
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("failed to remove provenance: %w", err)
+		}
+	}
+	secret, err := util.SecretFromDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read shared directory: %w", err)
+	}
+	if raw != nil {
+		secret.Data[shareddir.ProvenanceKey] = raw
+	}
+	_, chain, err := shareddir.VerifiedData(key, secret.Data)
+	if err != nil {
+		return nil, err
+	}
+	return &provenance{step: step, chain: chain}, nil
+}
+
 // uploadKubeconfig will do a best-effort attempt at uploading a kubeconfig
 // file if one does not exist at the time we start running but one does get
 // created while executing the command
@@ -451,7 +934,7 @@ func createSecret(client coreclientset.SecretInterface, name, dir string, dry bo
 // make a minimally functional kubeconfig available for tasks that need to run
 // before the final complete kubeconfig is available for general usage. An example
 // use case is for observers to start observing while install is still in progress.
-func uploadKubeconfig(ctx context.Context, client coreclientset.SecretInterface, name, dir string, dry bool) {
+func uploadKubeconfig(ctx context.Context, client coreclientset.SecretInterface, name, dir string, key []byte, state *sharedDirState, dry bool) {
 	if _, err := os.Stat(path.Join(dir, "kubeconfig")); err == nil {
 		// kubeconfig already exists, no need to do anything
 		return
@@ -461,7 +944,7 @@ func uploadKubeconfig(ctx context.Context, client coreclientset.SecretInterface,
 	if err := wait.PollUntil(time.Second, func() (done bool, err error) {
 		if !minimalUploaded {
 			if _, uploadErr = os.Stat(path.Join(dir, "kubeconfig-minimal")); uploadErr == nil {
-				uploadErr = createSecret(client, name, dir, dry)
+				uploadErr = createSecret(client, name, dir, key, state, dry)
 				if uploadErr == nil {
 					minimalUploaded = true
 				}
@@ -472,7 +955,7 @@ func uploadKubeconfig(ctx context.Context, client coreclientset.SecretInterface,
 			return false, nil
 		}
 		// kubeconfig exists, we can upload it
-		uploadErr = createSecret(client, name, dir, dry)
+		uploadErr = createSecret(client, name, dir, key, state, dry)
 		return uploadErr == nil, nil // retry errors
 	}, ctx.Done()); err != nil && !errors.Is(err, wait.ErrWaitTimeout) {
 		log.Printf("Failed to upload $KUBECONFIG: %v: %v\n", err, uploadErr)
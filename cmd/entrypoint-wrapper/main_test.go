@@ -0,0 +1,330 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	coreapi "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/openshift/ci-tools/pkg/steps/logs"
+	"github.com/openshift/ci-tools/pkg/steps/shareddir"
+	"github.com/openshift/ci-tools/pkg/testhelper"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func readFiles(t *testing.T, dir string) map[string]string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ret := map[string]string{}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		ret[entry.Name()] = string(data)
+	}
+	return ret
+}
+
+func TestSharedDirRoundTrip(t *testing.T) {
+	key, err := shareddir.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, err := shareddir.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{"kubeconfig": "apiVersion: v1\nkind: Config\n", "cluster-id": "1234"}
+	for _, tc := range []struct {
+		name        string
+		key         []byte
+		decryptKey  []byte
+		expectedErr bool
+	}{{
+		name: "plain shared directory",
+	}, {
+		name:       "encrypted shared directory",
+		key:        key,
+		decryptKey: key,
+	}, {
+		name:        "decrypted with another key",
+		key:         key,
+		decryptKey:  otherKey,
+		expectedErr: true,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			updated, mount, copied := t.TempDir(), t.TempDir(), filepath.Join(t.TempDir(), "secret")
+			// the mount of a secret holds directories of its own, which are
+			// not copied
+			if err := os.Mkdir(filepath.Join(mount, "..data"), 0755); err != nil {
+				t.Fatal(err)
+			}
+			writeFiles(t, updated, files)
+			client := fake.NewSimpleClientset(&coreapi.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "test"}}).CoreV1().Secrets("ns")
+			if err := createSecret(client, "test", updated, tc.key, &sharedDirState{}, false); err != nil {
+				t.Fatalf("failed to update the secret: %v", err)
+			}
+			secret, err := client.Get(context.Background(), "test", metav1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			stored := map[string]string{}
+			for name, value := range secret.Data {
+				stored[name] = string(value)
+			}
+			if tc.key == nil {
+				testhelper.Diff(t, "stored files", stored, files)
+			} else if stored["kubeconfig"] == files["kubeconfig"] || stored["cluster-id"] == files["cluster-id"] {
+				t.Errorf("the files were stored unencrypted: %v", stored)
+			}
+			writeFiles(t, mount, stored)
+			err = copyDir(copied, mount, tc.decryptKey)
+			if (err != nil) != tc.expectedErr {
+				t.Fatalf("expected error %t, got: %v", tc.expectedErr, err)
+			}
+			if tc.expectedErr {
+				return
+			}
+			testhelper.Diff(t, "copied files", readFiles(t, copied), files)
+		})
+	}
+}
+
+func TestSharedDirMerge(t *testing.T) {
+	key, err := shareddir.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	toBytes := func(files map[string]string) map[string][]byte {
+		ret := map[string][]byte{}
+		for name, content := range files {
+			ret[name] = []byte(content)
+		}
+		return ret
+	}
+	initial := map[string]string{"kubeconfig": "original", "proxy-conf.sh": "export HTTP_PROXY=proxy"}
+	for _, tc := range []struct {
+		name      string
+		encrypted bool
+	}{{
+		name: "plain shared directory",
+	}, {
+		name:      "encrypted shared directory",
+		encrypted: true,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			var stepKey []byte
+			data := toBytes(initial)
+			var chain shareddir.Chain
+			if tc.encrypted {
+				stepKey = key
+				chain = chain.Append(key, "install", data)
+				raw, err := chain.Marshal()
+				if err != nil {
+					t.Fatal(err)
+				}
+				data[shareddir.ProvenanceKey] = raw
+				if data, err = shareddir.EncryptData(key, data); err != nil {
+					t.Fatal(err)
+				}
+			}
+			client := fake.NewSimpleClientset(&coreapi.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "test"}, Data: data}).CoreV1().Secrets("ns")
+			// both shards start from the same contents and change different files
+			for _, shard := range []struct {
+				name  string
+				files map[string]string
+			}{{
+				name:  "shard-1",
+				files: map[string]string{"kubeconfig": "original", "proxy-conf.sh": "export HTTP_PROXY=proxy", "cluster-id": "1234"},
+			}, {
+				name:  "shard-2",
+				files: map[string]string{"kubeconfig": "updated"},
+			}} {
+				state := &sharedDirState{base: toBytes(initial)}
+				if tc.encrypted {
+					state.provenance = &provenance{step: shard.name, chain: chain}
+				}
+				dir := t.TempDir()
+				writeFiles(t, dir, shard.files)
+				if err := createSecret(client, "test", dir, stepKey, state, false); err != nil {
+					t.Fatalf("%s failed to update the secret: %v", shard.name, err)
+				}
+			}
+			secret, err := client.Get(context.Background(), "test", metav1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			stored := secret.Data
+			if tc.encrypted {
+				decrypted, err := shareddir.DecryptData(key, stored)
+				if err != nil {
+					t.Fatal(err)
+				}
+				var chain shareddir.Chain
+				if stored, chain, err = shareddir.VerifiedData(key, decrypted); err != nil {
+					t.Fatal(err)
+				}
+				var steps []string
+				for _, r := range chain {
+					steps = append(steps, r.Step)
+				}
+				testhelper.Diff(t, "provenance", steps, []string{"install", "shard-1", "shard-2"})
+			}
+			testhelper.Diff(t, "stored files", stored, toBytes(map[string]string{"kubeconfig": "updated", "cluster-id": "1234"}))
+		})
+	}
+}
+
+func TestMissingOutputs(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		outputs  string
+		files    map[string]string
+		expected []string
+	}{{
+		name: "no declared outputs",
+	}, {
+		name:    "all outputs provided",
+		outputs: "kubeconfig,metadata.json",
+		files:   map[string]string{"kubeconfig": "", "metadata.json": "{}", "extra": ""},
+	}, {
+		name:     "outputs missing",
+		outputs:  "kubeconfig,metadata.json,proxy-conf.sh",
+		files:    map[string]string{"metadata.json": "{}"},
+		expected: []string{"kubeconfig", "proxy-conf.sh"},
+	}, {
+		name:     "empty entries are ignored",
+		outputs:  ",kubeconfig,,",
+		expected: []string{"kubeconfig"},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFiles(t, dir, tc.files)
+			o := options{dstPath: dir, sharedDirOutputs: tc.outputs}
+			testhelper.Diff(t, "missing outputs", o.missingOutputs(), tc.expected)
+		})
+	}
+}
+
+func TestManageStdin(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		env         string
+		sharedFile  string
+		files       map[string]string
+		expected    string
+		expectedErr bool
+	}{{
+		name: "no standard input",
+	}, {
+		name:     "parameter",
+		env:      "a token",
+		expected: "a token\n",
+	}, {
+		name:     "parameter ending with a newline",
+		env:      "line 1\nline 2\n",
+		expected: "line 1\nline 2\n",
+	}, {
+		name:       "shared file",
+		sharedFile: "install-config.yaml",
+		files:      map[string]string{"install-config.yaml": "apiVersion: v1\nbaseDomain: example.com"},
+		expected:   "apiVersion: v1\nbaseDomain: example.com",
+	}, {
+		name:        "missing shared file",
+		sharedFile:  "install-config.yaml",
+		expectedErr: true,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFiles(t, dir, tc.files)
+			o := options{dstPath: dir, stdinSharedFile: tc.sharedFile}
+			if tc.env != "" {
+				o.stdinEnv = "STEP_STDIN"
+				t.Setenv(o.stdinEnv, tc.env)
+			}
+			proc := exec.Command("cat")
+			var out bytes.Buffer
+			proc.Stdout = &out
+			closeStdin, err := o.manageStdin(proc)
+			if (err != nil) != tc.expectedErr {
+				t.Fatalf("expected error %t, got: %v", tc.expectedErr, err)
+			}
+			if tc.expectedErr {
+				return
+			}
+			if closeStdin != nil {
+				defer closeStdin()
+			}
+			if err := proc.Run(); err != nil {
+				t.Fatal(err)
+			}
+			testhelper.Diff(t, "standard input", out.String(), tc.expected)
+		})
+	}
+}
+
+func TestSplitLogs(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		maxSize  int64
+		backups  int
+		expected map[string]string
+	}{{
+		name: "logs disabled",
+	}, {
+		name:     "output below the maximum size",
+		maxSize:  1024,
+		backups:  2,
+		expected: map[string]string{logs.Stdout: "abcdefgh", logs.Stderr: "warning"},
+	}, {
+		name:    "output rotated",
+		maxSize: 3,
+		backups: 1,
+		expected: map[string]string{
+			logs.Stdout:        "gh",
+			logs.Stdout + ".1": "def",
+			logs.Stderr:        "g",
+			logs.Stderr + ".1": "nin",
+		},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			t.Setenv("ARTIFACT_DIR", dir)
+			o := options{logsMaxSize: tc.maxSize, logsBackups: tc.backups}
+			proc := exec.Command("/bin/sh", "-c", "printf abcdefgh; printf warning >&2")
+			closeLogs := o.splitLogs(proc)
+			if (closeLogs != nil) != (tc.expected != nil) {
+				t.Fatalf("expected logs %t, got %t", tc.expected != nil, closeLogs != nil)
+			}
+			if closeLogs == nil {
+				return
+			}
+			if err := proc.Run(); err != nil {
+				t.Fatal(err)
+			}
+			closeLogs()
+			testhelper.Diff(t, "logs", readFiles(t, filepath.Join(dir, logs.Dir)), tc.expected)
+		})
+	}
+}
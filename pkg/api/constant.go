@@ -61,8 +61,24 @@ const (
 	// CliEnv if the env we use to expose the path to the cli
 	CliEnv          = "CLI_DIR"
 	DefaultLeaseEnv = "LEASED_RESOURCE"
+	// LeasedRegionParam is the parameter which holds the region named by the
+	// resource leased for the cluster profile, if any.  It is not exposed
+	// to steps, which receive the region of the cluster in $REGION.
+	LeasedRegionParam = "LEASED_RESOURCE_REGION"
 	// SkipCensoringLabel is the label we use to mark a secret as not needing to be censored
 	SkipCensoringLabel = "ci.openshift.io/skip-censoring"
+	// ScrubOnExitLabel is the label we use to mark a secret which holds
+	// credentials or other data of the test which must not outlive the job
+	ScrubOnExitLabel = "ci.openshift.io/scrub-on-exit"
+	// ScrubOwnerLabelPrefix prefixes the labels with which each job using a
+	// secret marked with ScrubOnExitLabel claims it.  Namespaces are shared
+	// by the jobs with the same inputs, so a secret is only scrubbed by the
+	// last of its owners to exit.
+	ScrubOwnerLabelPrefix = "scrub-owner.ci.openshift.io/"
+	// PostPhaseFinalizerSuffix is the suffix of the finalizers with which
+	// each multi-stage test keeps the objects its steps depend on until its
+	// post phase has completed, e.g. `e2e.tests.ci.openshift.io/post-phase`.
+	PostPhaseFinalizerSuffix = ".tests.ci.openshift.io/post-phase"
 
 	OauthTokenSecretKey  = "oauth"
 	OauthTokenSecretName = "github-credentials-openshift-ci-robot-private-git-cloner"
@@ -70,6 +86,10 @@ const (
 	CIAdminsGroupName = "test-platform-ci-admins"
 
 	ShmResource = "ci-operator.openshift.io/shm"
+
+	// MaxStepCommandsSize is the size in bytes of the largest script which can
+	// be stored in the ConfigMap used to mount step commands.
+	MaxStepCommandsSize = 1024 * 1024
 )
 
 var (
@@ -83,6 +103,44 @@ var (
 	)
 )
 
+// ClusterCapability is a feature which only some clusters of the build farm
+// support.
+type ClusterCapability string
+
+const (
+	// ClusterCapabilityHostNetwork allows pods to use the network namespace of
+	// the node.
+	ClusterCapabilityHostNetwork ClusterCapability = "host-network"
+	// ClusterCapabilityUnsafeSysctls allows pods to set sysctls which the
+	// kubelet does not consider safe.
+	ClusterCapabilityUnsafeSysctls ClusterCapability = "unsafe-sysctls"
+)
+
+// clusterCapabilities lists the capabilities of the clusters of the build
+// farm, which must be kept in sync with their configuration.
+var clusterCapabilities = map[Cluster]sets.Set[ClusterCapability]{
+	ClusterVSphere:   sets.New(ClusterCapabilityHostNetwork, ClusterCapabilityUnsafeSysctls),
+	ClusterVSphere02: sets.New(ClusterCapabilityHostNetwork, ClusterCapabilityUnsafeSysctls),
+}
+
+// HasCapability determines whether a cluster of the build farm supports a
+// feature.
+func (c Cluster) HasCapability(capability ClusterCapability) bool {
+	return clusterCapabilities[c].Has(capability)
+}
+
+// ClustersWithCapability returns the sorted names of the clusters which
+// support a feature.
+func ClustersWithCapability(capability ClusterCapability) []string {
+	ret := sets.New[string]()
+	for cluster, capabilities := range clusterCapabilities {
+		if capabilities.Has(capability) {
+			ret.Insert(string(cluster))
+		}
+	}
+	return sets.List(ret)
+}
+
 // GitHubUserGroup returns the group name for a GitHub user
 func GitHubUserGroup(username string) string {
 	return fmt.Sprintf("%s-group", username)
@@ -0,0 +1,48 @@
+package api
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// parameterReference matches the references to the parameters of a step in
+// the fields which support expansion, using the syntax of Kubernetes: `$(NAME)`
+// is replaced by the value of the parameter and `$$` by a literal `$`.
+var parameterReference = regexp.MustCompile(`\$\$|\$\(([A-Za-z_][A-Za-z0-9_]*)\)`)
+
+// ParameterReferences returns the names of the parameters referenced in a
+// value.
+func ParameterReferences(value string) []string {
+	var ret []string
+	for _, match := range parameterReference.FindAllStringSubmatch(value, -1) {
+		if match[1] != "" {
+			ret = append(ret, match[1])
+		}
+	}
+	return ret
+}
+
+// ExpandParameters replaces the references to parameters in a value with
+// their values.  References to unknown parameters are an error.
+func ExpandParameters(value string, params map[string]string) (string, error) {
+	missing := sets.New[string]()
+	ret := parameterReference.ReplaceAllStringFunc(value, func(match string) string {
+		if match == "$$" {
+			return "$"
+		}
+		name := match[2 : len(match)-1]
+		v, ok := params[name]
+		if !ok {
+			missing.Insert(name)
+			return match
+		}
+		return v
+	})
+	if missing.Len() != 0 {
+		return "", fmt.Errorf("unknown parameter(s) referenced in %q: %s", value, strings.Join(sets.List(missing), ", "))
+	}
+	return ret, nil
+}
@@ -0,0 +1,52 @@
+package api
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/openshift/ci-tools/pkg/testhelper"
+)
+
+func TestExpandParameters(t *testing.T) {
+	params := map[string]string{"PLATFORM": "aws", "DIR": "/var/run/secrets"}
+	for _, tc := range []struct {
+		name     string
+		value    string
+		expected string
+		err      error
+	}{{
+		name:     "no references",
+		value:    "/var/run/aws",
+		expected: "/var/run/aws",
+	}, {
+		name:     "references",
+		value:    "$(DIR)/$(PLATFORM)",
+		expected: "/var/run/secrets/aws",
+	}, {
+		name:     "escaped reference",
+		value:    "/var/run/$$(PLATFORM)",
+		expected: "/var/run/$(PLATFORM)",
+	}, {
+		name:     "shell syntax is not expanded",
+		value:    "/var/run/$PLATFORM/${DIR}",
+		expected: "/var/run/$PLATFORM/${DIR}",
+	}, {
+		name:  "unknown references",
+		value: "$(ZONE)/$(REGION)/$(ZONE)",
+		err:   errors.New(`unknown parameter(s) referenced in "$(ZONE)/$(REGION)/$(ZONE)": REGION, ZONE`),
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, err := ExpandParameters(tc.value, params)
+			if diff := cmp.Diff(tc.err, err, testhelper.EquateErrorMessage); diff != "" {
+				t.Fatalf("unexpected error: %s", diff)
+			}
+			testhelper.Diff(t, "value", actual, tc.expected)
+		})
+	}
+}
+
+func TestParameterReferences(t *testing.T) {
+	testhelper.Diff(t, "references", ParameterReferences("$(DIR)/$$(ESCAPED)/$(PLATFORM)"), []string{"DIR", "PLATFORM"})
+}
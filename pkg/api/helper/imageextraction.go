@@ -114,7 +114,7 @@ func imageStreamTagReferenceToString(istr api.ImageStreamTagReference) string {
 }
 
 func insertTagReferencesFromSteps(config api.MultiStageTestConfigurationLiteral, m map[string]types.NamespacedName) {
-	for _, subStep := range append(append(config.Pre, config.Test...), config.Post...) {
+	for _, subStep := range append(append(append(config.Pre, config.Test...), config.Post...), config.Rollbacks...) {
 		if subStep.FromImage != nil {
 			insert(*subStep.FromImage, m)
 		}
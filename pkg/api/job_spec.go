@@ -85,6 +85,21 @@ func (s JobSpec) UniqueHash() string {
 	return fmt.Sprintf("%x", sha256.Sum256([]byte(job)))[:5]
 }
 
+// ScrubOwner identifies the job in the labels of the secrets it scrubs when
+// it exits.
+func (s JobSpec) ScrubOwner() string {
+	if s.BuildID == "" {
+		return s.UniqueHash()
+	}
+	return fmt.Sprintf("%s-%s", s.UniqueHash(), s.BuildID)
+}
+
+// ScrubOwnerLabel is the label with which a job claims a secret it scrubs
+// when it exits.
+func ScrubOwnerLabel(owner string) string {
+	return ScrubOwnerLabelPrefix + owner
+}
+
 // ResolveSpecFromEnv will determine the Refs being
 // tested in by parsing Prow environment variable contents
 func ResolveSpecFromEnv() (*JobSpec, error) {
@@ -1,5 +1,7 @@
 package api
 
+import "strings"
+
 // LeasesForTest aggregates all the lease configurations in a test.
 // It is assumed that they have been validated and contain only valid and
 // unique values.
@@ -11,9 +13,31 @@ func LeasesForTest(s *MultiStageTestConfigurationLiteral) (ret []StepLease) {
 			Count:        1,
 		})
 	}
-	for _, step := range append(s.Pre, append(s.Test, s.Post...)...) {
+	for _, step := range append(s.Pre, append(s.Test, append(s.Post, s.Rollbacks...)...)...) {
 		ret = append(ret, step.Leases...)
 	}
 	ret = append(ret, s.Leases...)
 	return
 }
+
+// ConcurrencyGroupLeaseType is the type of the resources leased by steps of a
+// concurrency group.
+func ConcurrencyGroupLeaseType(group string) string {
+	return group + "-concurrency"
+}
+
+// MutexLeaseType is the type of the resource leased by steps holding a mutex.
+func MutexLeaseType(mutex string) string {
+	return mutex + "-mutex"
+}
+
+// LeaseRegion returns the region named by a leased resource.  Resources of
+// cloud accounts are named `<region>--<type>-<index>`, other resources do
+// not name a region.
+func LeaseRegion(name string) (string, bool) {
+	region, _, found := strings.Cut(name, "--")
+	if !found || region == "" {
+		return "", false
+	}
+	return region, true
+}
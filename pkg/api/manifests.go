@@ -0,0 +1,41 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// DecodeManifests decodes the objects of the manifests of a step.  Empty
+// documents are skipped and every object must have an API version, a kind and
+// a name.
+func DecodeManifests(manifests string) ([]*unstructured.Unstructured, error) {
+	decoder := yaml.NewYAMLOrJSONDecoder(strings.NewReader(manifests), 4096)
+	var ret []*unstructured.Unstructured
+	for i := 0; ; i++ {
+		var object map[string]interface{}
+		if err := decoder.Decode(&object); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("document %d: %w", i, err)
+		}
+		if len(object) == 0 {
+			continue
+		}
+		obj := &unstructured.Unstructured{Object: object}
+		switch {
+		case obj.GetAPIVersion() == "":
+			return nil, fmt.Errorf("document %d: apiVersion is not set", i)
+		case obj.GetKind() == "":
+			return nil, fmt.Errorf("document %d: kind is not set", i)
+		case obj.GetName() == "":
+			return nil, fmt.Errorf("document %d: metadata.name is not set", i)
+		}
+		ret = append(ret, obj)
+	}
+	return ret, nil
+}
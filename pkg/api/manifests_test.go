@@ -0,0 +1,55 @@
+package api
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/openshift/ci-tools/pkg/testhelper"
+)
+
+func TestDecodeManifests(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		manifests string
+		expected  []string
+		err       error
+	}{{
+		name: "objects",
+		manifests: `---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: e2e
+rules: []
+---
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRoleBinding
+metadata:
+  name: e2e
+`,
+		expected: []string{"ClusterRole/e2e", "ClusterRoleBinding/e2e"},
+	}, {
+		name:      "object without a name",
+		manifests: "apiVersion: v1\nkind: ConfigMap\n",
+		err:       errors.New("document 0: metadata.name is not set"),
+	}, {
+		name:      "object without a kind",
+		manifests: "apiVersion: v1\nmetadata:\n  name: config\n---\napiVersion: v1\nmetadata:\n  name: config\n",
+		err:       errors.New("document 0: kind is not set"),
+	}, {
+		name:      "invalid YAML",
+		manifests: "kind: [",
+		err:       errors.New("document 0: error converting YAML to JSON: yaml: line 1: did not find expected node content"),
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			objects, err := DecodeManifests(tc.manifests)
+			testhelper.Diff(t, "error", err, tc.err, testhelper.EquateErrorMessage)
+			var actual []string
+			for _, obj := range objects {
+				actual = append(actual, obj.GetKind()+"/"+obj.GetName())
+			}
+			testhelper.Diff(t, "objects", actual, tc.expected)
+		})
+	}
+}
@@ -9,6 +9,9 @@ const (
 	// AnnotationCleanupDurationTTL is the annotation for requesting namespace cleanup after the namespace has been active
 	AnnotationCleanupDurationTTL = "ci.openshift.io/ttl.hard"
 	// AnnotationNamespaceLastActive contains time.RFC3339 timestamp at which the namespace was last in active use. We
-	// update this every ten minutes.
+	// update this at the heartbeat interval, on the namespace and on the pods running in it.
 	AnnotationNamespaceLastActive = "ci.openshift.io/active"
+	// AnnotationHeartbeatInterval contains the interval at which AnnotationNamespaceLastActive is updated. Namespaces
+	// and pods should not be pruned while their last heartbeat is more recent than a few intervals.
+	AnnotationHeartbeatInterval = "ci.openshift.io/heartbeat-interval"
 )
@@ -3,6 +3,7 @@ package api
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"k8s.io/apimachinery/pkg/util/sets"
 	prowv1 "k8s.io/test-infra/prow/apis/prowjobs/v1"
@@ -129,6 +130,51 @@ type ReleaseBuildConfiguration struct {
 	// input types. The special name '*' may be used to set default
 	// requests and limits.
 	Resources ResourceConfiguration `json:"resources,omitempty"`
+
+	// Quarantine lists known-flaky jUnit test cases reported by multi-stage
+	// test steps. Until their waiver expires, failures of these test cases
+	// are reported as skipped and do not fail the step.
+	Quarantine []QuarantinedTestCase `json:"quarantine,omitempty"`
+
+	// SharedLeases are leases acquired once for the job and shared by all
+	// the multi-stage tests which lease resources of the same type, instead
+	// of each test acquiring and releasing its own.  The lease is released
+	// once the last test using it has finished.
+	SharedLeases []SharedLease `json:"shared_leases,omitempty"`
+}
+
+// SharedLease is a lease held by the job for the tests which need resources
+// of its type.
+type SharedLease struct {
+	// ResourceType is the type of resource that will be leased.
+	ResourceType string `json:"resource_type"`
+	// Count is the number of resources to acquire (optional, defaults to 1).
+	// It bounds the number of resources a single test can use.
+	Count uint `json:"count,omitempty"`
+}
+
+// QuarantineExpiryFormat is the format of the expiry date of waivers.
+const QuarantineExpiryFormat = "2006-01-02"
+
+// QuarantinedTestCase is a waiver for the failures of a jUnit test case.
+type QuarantinedTestCase struct {
+	// Name is the name of the test case.
+	Name string `json:"name"`
+	// Reason explains why the test case is quarantined, usually with a link
+	// to the issue tracking the flake.
+	Reason string `json:"reason"`
+	// Expires is the last day, in YYYY-MM-DD format, on which the waiver
+	// applies.
+	Expires string `json:"expires"`
+}
+
+// Active determines whether the waiver applies at the given time.
+func (q QuarantinedTestCase) Active(now time.Time) bool {
+	expires, err := time.Parse(QuarantineExpiryFormat, q.Expires)
+	if err != nil {
+		return false
+	}
+	return now.Before(expires.AddDate(0, 0, 1))
 }
 
 // RefCommands pairs a ref (in org/repo format) with commands
@@ -802,6 +848,14 @@ type TestStepConfiguration struct {
 	// Timeout overrides maximum prowjob duration
 	Timeout *prowv1.Duration `json:"timeout,omitempty"`
 
+	// Labels are added to every object created for a multi-stage test, in
+	// addition to those set by ci-operator.
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// Annotations are added to every object created for a multi-stage test,
+	// in addition to those set by ci-operator.
+	Annotations map[string]string `json:"annotations,omitempty"`
+
 	// Only one of the following can be not-null.
 	ContainerTestConfiguration                                *ContainerTestConfiguration                                `json:"container,omitempty"`
 	MultiStageTestConfiguration                               *MultiStageTestConfiguration                               `json:"steps,omitempty"`
@@ -813,6 +867,7 @@ type TestStepConfiguration struct {
 	OpenshiftInstallerUPIClusterTestConfiguration             *OpenshiftInstallerUPIClusterTestConfiguration             `json:"openshift_installer_upi,omitempty"`
 	OpenshiftInstallerUPISrcClusterTestConfiguration          *OpenshiftInstallerUPISrcClusterTestConfiguration          `json:"openshift_installer_upi_src,omitempty"`
 	OpenshiftInstallerCustomTestImageClusterTestConfiguration *OpenshiftInstallerCustomTestImageClusterTestConfiguration `json:"openshift_installer_custom_test_image,omitempty"`
+	AggregateTestConfiguration                                *AggregateTestConfiguration                                `json:"aggregate,omitempty"`
 }
 
 func (config TestStepConfiguration) TargetName() string {
@@ -995,6 +1050,12 @@ type LiteralTestStep struct {
 	FromImage *ImageStreamTagReference `json:"from_image,omitempty"`
 	// Commands is the command(s) that will be run inside the image.
 	Commands string `json:"commands,omitempty"`
+	// Command is the executable run inside the image, as an alternative to
+	// `commands` for images without bash, e.g. distroless or scratch-based
+	// tool images.  It is executed directly with Args, without a shell.
+	Command []string `json:"command,omitempty"`
+	// Args are the arguments passed to Command.
+	Args []string `json:"args,omitempty"`
 	// Resources defines the resource requirements for the step.
 	Resources ResourceRequirements `json:"resources"`
 	// Timeout is how long the we will wait before aborting a job with SIGINT.
@@ -1023,18 +1084,275 @@ type LiteralTestStep struct {
 	// to true in MultiStageTestConfiguration. This option is applicable to
 	// `post` steps.
 	BestEffort *bool `json:"best_effort,omitempty"`
+	// Gather marks a `post` step which collects information about the test.
+	// Gather steps run after all other `post` steps, even if the test failed
+	// or was cancelled, each with a timeout of its own (30 minutes unless
+	// `timeout` is set). Their results are reported in a dedicated suite.
+	Gather *bool `json:"gather,omitempty"`
 	// NoKubeconfig determines that no $KUBECONFIG will exist in $SHARED_DIR,
 	// so no local copy of it will be created for the step and if the step
 	// creates one, it will not be propagated.
 	NoKubeconfig *bool `json:"no_kubeconfig,omitempty"`
 	// Cli is the (optional) name of the release from which the `oc` binary
-	// will be injected into this step.
+	// will be injected into this step.  If the step has a `KUBECTL_RETRY`
+	// parameter, the binary is wrapped to retry calls which fail with a
+	// refused connection or a 429 response, at most that many times.
 	Cli string `json:"cli,omitempty"`
 	// Observers are the observers that should be running
 	Observers []string `json:"observers,omitempty"`
-	// RunAsScript defines if this step should be executed as a script mounted
-	// in the test container instead of being executed directly via bash
+	// RunAsScript defines if the commands of this step should be executed
+	// verbatim, using their own interpreter line, instead of being prefixed
+	// with the default bash preamble. The commands of every step are mounted
+	// as an executable script in the test container.
 	RunAsScript *bool `json:"run_as_script,omitempty"`
+	// Toolkit sources the shell function library of steps before their
+	// commands, which provides `log`, `retry`, `wait_for` and `collect`.  The
+	// library is mounted in the step and its path is exposed as $CI_TOOLKIT,
+	// for commands run as scripts to source it themselves.
+	Toolkit *bool `json:"toolkit,omitempty"`
+	// Burst lets the step use resources up to its limits, which are kept as
+	// configured instead of being derived from its requests, so that bursty
+	// steps can request what they use on average.  Limits are capped by the
+	// policy of the build farm and the CPU throttling of the step is recorded
+	// in its metrics.
+	Burst *bool `json:"burst,omitempty"`
+	// ShardCount is the number of pods the step is split into.  All shards
+	// run in parallel and receive the $SHARD_INDEX (starting from zero) and
+	// $SHARD_TOTAL environment variables, which the step uses to select its
+	// part of the work.  The step fails if any of its shards fail.  The
+	// changes the shards make to $SHARED_DIR are merged, when several shards
+	// write the same file the last one to finish wins.
+	ShardCount *int `json:"shard_count,omitempty"`
+	// RequiresSharedFiles lists the files which previous steps must have
+	// written to $SHARED_DIR for this step to run.  Their presence is
+	// verified before the step is executed.
+	RequiresSharedFiles []string `json:"requires_shared_files,omitempty"`
+	// ProvidesSharedFiles lists the files this step writes to $SHARED_DIR.
+	// The step fails if any of them is missing when its commands succeed.
+	// Steps requiring a file must run after a step providing it.
+	ProvidesSharedFiles []string `json:"provides_shared_files,omitempty"`
+	// Sidecars are helper containers which run alongside the commands of
+	// the step, e.g. local registries, tunnels or log forwarders.  They are
+	// terminated when the commands exit.
+	Sidecars []StepSidecar `json:"sidecars,omitempty"`
+	// HostNetwork runs the step in the network namespace of the node, e.g.
+	// for dual-stack tests which need the addresses of the node.  Tests with
+	// such steps must run on a cluster with the `host-network` capability.
+	HostNetwork *bool `json:"host_network,omitempty"`
+	// Sysctls are set for the pod of the step.  Sysctls which the kubelet does
+	// not consider safe require a cluster with the `unsafe-sysctls`
+	// capability.
+	Sysctls []StepSysctl `json:"sysctls,omitempty"`
+	// RunOnEphemeralCluster runs the step as a pod on the cluster under test
+	// instead of the build farm, e.g. for tests which must run inside the
+	// network of the cluster.  The step is executed with the `kubeconfig` in
+	// the shared directory, which must be provided by a previous step.  The
+	// shared directory is available to the step, but changes to it are not
+	// propagated.  The artifacts of the step are copied back once it exits.
+	RunOnEphemeralCluster *bool `json:"run_on_ephemeral_cluster,omitempty"`
+	// Manifests are Kubernetes objects, as a stream of YAML documents, which
+	// are created on the cluster under test before the step runs and deleted
+	// once it completes, e.g. the RBAC objects or CRDs the step requires.
+	// Objects which exist are replaced.  The cluster is accessed with the
+	// `kubeconfig` in the shared directory.  In the registry, this is the name
+	// of a file next to the reference, e.g. `ipi-conf-manifests.yaml`.
+	Manifests string `json:"manifests,omitempty"`
+	// WaitFor lists conditions on objects of the cluster under test which
+	// ci-operator waits for, in order, before the step starts, instead of the
+	// step polling the cluster itself.  The step fails if a condition is not
+	// met in time.  The cluster is accessed with the `kubeconfig` in the
+	// shared directory.
+	WaitFor []WaitCondition `json:"wait_for,omitempty"`
+	// ConcurrencyGroup limits the number of instances of the step which run
+	// concurrently across all jobs, e.g. for heavyweight steps which would
+	// overload a shared service.  A lease is acquired from the lease server
+	// for the duration of the step, so the limit is the number of resources
+	// of type `<group>-concurrency` configured in the server.
+	ConcurrencyGroup string `json:"concurrency_group,omitempty"`
+	// Mutex is the name of a lock held for the duration of the step, so that
+	// no two steps with the same mutex run at the same time across all jobs,
+	// e.g. for steps which modify a shared DNS zone.  The lock is a lease of
+	// type `<mutex>-mutex`, of which the lease server must hold exactly one
+	// resource.
+	Mutex string `json:"mutex,omitempty"`
+	// PreviousJobArtifacts exposes the artifacts of the last successful run
+	// of the same job to the step, e.g. for performance or compatibility
+	// tests which compare their results with a baseline.
+	PreviousJobArtifacts *PreviousJobArtifacts `json:"previous_job_artifacts,omitempty"`
+	// Upgrade makes this a built-in step which upgrades the cluster under
+	// test, instead of running `commands` in a container.  The upgrade is
+	// driven by ci-operator through the ClusterVersion of the cluster, using
+	// the `kubeconfig` in the shared directory.
+	Upgrade *UpgradeStep `json:"upgrade,omitempty"`
+	// Rollback is the name of a step which is executed immediately if this
+	// step fails, before the test proceeds, to restore external state the
+	// step modified, e.g. DNS records or image tags.  It references a step
+	// in the registry, which is resolved into the `rollbacks` of the test.
+	Rollback string `json:"rollback,omitempty"`
+	// Gang is the name of a group of consecutive steps of a phase which run
+	// in parallel and must be scheduled together, e.g. the client and server
+	// of a performance test.  The pods of a gang are held back from the
+	// scheduler until all of them were created.  If they are not all created
+	// in time, or a pod of the gang cannot be scheduled while its peers run,
+	// all pods of the gang are stopped and the gang fails.
+	// The changes the steps of a gang make to $SHARED_DIR are merged like
+	// those of shards.
+	Gang string `json:"gang,omitempty"`
+	// FailOnRestart fails the step if a container of its pod is restarted
+	// while it runs, e.g. by the kubelet, which violates the restart policy
+	// of the pod.  Restarts and warning events of the pod are reported in the
+	// results of the step either way.
+	FailOnRestart *bool `json:"fail_on_restart,omitempty"`
+	// Workdir is the path at which a writable scratch directory is mounted
+	// for the step, which is also the working directory of its commands,
+	// `/scratch` by default.  The path is exposed as $SCRATCH_DIR.  The
+	// scratch directory is only added if this or `scratch_size` is set.
+	// References to the parameters of the step, e.g. `$(PLATFORM)`, are
+	// expanded.
+	Workdir string `json:"workdir,omitempty"`
+	// ScratchSize is the size of the scratch directory, e.g. `20Gi`.  That
+	// much ephemeral storage is requested for the step, so the directory is
+	// guaranteed to have room for it instead of relying on the image having
+	// a writable `/tmp` big enough.
+	ScratchSize string `json:"scratch_size,omitempty"`
+	// NodeCapabilities lists the capabilities the nodes running the step
+	// must have, e.g. `kvm`, `sse4`, `large-disk` or `arm64`.  They are
+	// checked against the capabilities of the build farm before the test
+	// runs, and the step is scheduled on the nodes which provide them.
+	NodeCapabilities []string `json:"node_capabilities,omitempty"`
+	// Critical protects the pods of the step from being evicted while they
+	// run, e.g. when the build farm scales down, for steps which cannot be
+	// interrupted without leaking resources, like cluster installation and
+	// teardown.
+	Critical *bool `json:"critical,omitempty"`
+	// Job runs the step as a Kubernetes Job instead of a bare pod, for steps
+	// which can safely be retried.  The cluster replaces the pod of the step
+	// when it fails or is lost, e.g. with its node, up to `backoff_limit`
+	// times, and the step fails once the Job does.
+	Job *StepJob `json:"job,omitempty"`
+	// PrivilegedApproval is the name of the entry of the central allowlist
+	// which approves the privileged capabilities the step uses, like
+	// `host_network` or `sysctls`.  Where the allowlist is enforced, steps
+	// which use such capabilities cannot be resolved without an approval
+	// which lists the step and its capabilities.
+	PrivilegedApproval string `json:"privileged_approval,omitempty"`
+	// StdinFrom feeds the standard input of the commands of the step from a
+	// parameter of the step or a file in the shared directory, e.g. to drive
+	// installers which prompt for a confirmation.  The standard input of the
+	// commands is empty if not set.
+	StdinFrom *StepStdin `json:"stdin_from,omitempty"`
+	// SplitLogs additionally writes the standard output and error of the
+	// commands of the step to separate files in `$ARTIFACT_DIR/logs`, which
+	// are rotated once they reach 10MiB.  The output is then copied from
+	// pipes: processes the commands start in the background and which keep
+	// them open are not waited for longer than ten seconds after the commands
+	// exit, and what they write afterwards is only in the container log.
+	SplitLogs *bool `json:"split_logs,omitempty"`
+}
+
+// StepStdin is the source of the standard input of the commands of a step.
+// Exactly one of the fields must be set.
+type StepStdin struct {
+	// Parameter is the name of a parameter of the step, the value of which is
+	// written to the standard input followed by a newline, unless it already
+	// ends with one.
+	Parameter string `json:"parameter,omitempty"`
+	// SharedFile is the name of a file in the shared directory, the contents
+	// of which are written to the standard input.  The step fails if the file
+	// does not exist when it starts.
+	SharedFile string `json:"shared_file,omitempty"`
+}
+
+// StepJob configures the Job a step runs as.
+type StepJob struct {
+	// BackoffLimit is the number of times the pod of the step is retried
+	// before the step fails.
+	BackoffLimit int32 `json:"backoff_limit,omitempty"`
+	// TTLSecondsAfterFinished is the time after which the Job and its pods
+	// are removed once the step finished.  They are kept with the namespace
+	// of the test if not set.
+	TTLSecondsAfterFinished *int32 `json:"ttl_seconds_after_finished,omitempty"`
+}
+
+// UpgradeStep configures the upgrade of the cluster under test to a release.
+// Each phase of the upgrade is reported as a test case.  The upgrade must
+// complete within the `timeout` of the step.
+type UpgradeStep struct {
+	// Release is the name of the release to upgrade to, e.g. `latest`.
+	Release string `json:"release"`
+	// Channel is set on the ClusterVersion before the upgrade, if not empty.
+	Channel string `json:"channel,omitempty"`
+	// Force upgrades to the release even if its signature cannot be verified
+	// or the cluster is not upgradeable.
+	Force bool `json:"force,omitempty"`
+	// Rollback returns the cluster to the release it was running if the
+	// upgrade fails.  The step still fails.
+	Rollback bool `json:"rollback,omitempty"`
+}
+
+// PreviousJobArtifacts configures the access of a step to the artifacts of
+// the last successful run of the job.  The URL of the artifact directory of
+// that run is exposed as `$PREVIOUS_JOB_ARTIFACTS_URL`, which is not set if
+// there is no such run.
+type PreviousJobArtifacts struct {
+	// Files are paths relative to the artifact directory of the previous run
+	// which are fetched before the step starts and mounted in the directory
+	// at `$PREVIOUS_JOB_ARTIFACTS_DIR`.  Files which do not exist in the
+	// previous run are skipped.  Not available to steps which run on the
+	// ephemeral cluster.
+	Files []string `json:"files,omitempty"`
+}
+
+// WaitCondition is a condition on an object of the cluster under test which
+// must be met before a step starts.
+type WaitCondition struct {
+	// APIVersion of the object, e.g. `apps/v1`.
+	APIVersion string `json:"api_version"`
+	// Kind of the object, e.g. `Deployment`.
+	Kind string `json:"kind"`
+	// Name of the object.
+	Name string `json:"name"`
+	// Namespace of the object, empty for cluster-scoped objects.
+	Namespace string `json:"namespace,omitempty"`
+	// JSONPath is a template evaluated against the object, in the syntax of
+	// `oc get -o jsonpath`, e.g.
+	// `{.status.conditions[?(@.type=="Available")].status}`.
+	JSONPath string `json:"jsonpath"`
+	// Value is the output of the template which meets the condition.  If
+	// empty, any non-empty output meets it.
+	Value string `json:"value,omitempty"`
+	// Timeout is how long to wait for the condition, 10 minutes by default.
+	Timeout *prowv1.Duration `json:"timeout,omitempty"`
+}
+
+// StepSysctl is a kernel parameter set for the pod of a step.
+type StepSysctl struct {
+	// Name of the parameter, e.g. `net.ipv6.conf.all.forwarding`.
+	Name string `json:"name"`
+	// Value of the parameter.
+	Value string `json:"value"`
+}
+
+// SidecarContainerPrefix is prepended to the names of sidecars to form the
+// names of their containers.
+const SidecarContainerPrefix = "sidecar-"
+
+// StepSidecar is a helper container running alongside the commands of a step.
+// The sidecars and the commands of the step share the $SIDECAR_DIR directory.
+type StepSidecar struct {
+	// Name is the name of the container, unique within the step.
+	Name string `json:"name"`
+	// From is the container image tag used for the sidecar, resolved like
+	// the `from` field of the step.
+	From string `json:"from"`
+	// Commands is the command(s) that will be run inside the sidecar.
+	Commands string `json:"commands"`
+	// Readiness is an optional command executed repeatedly in the sidecar
+	// until it succeeds.  The commands of the step are only started once the
+	// sidecars are ready.
+	Readiness string `json:"readiness,omitempty"`
+	// Resources defines the resource requirements for the sidecar.
+	Resources ResourceRequirements `json:"resources"`
 }
 
 // StepParameter is a variable set by the test, with an optional default.
@@ -1052,9 +1370,27 @@ type CredentialReference struct {
 	// Namespace is where the source secret exists.
 	Namespace string `json:"namespace"`
 	// Names is which source secret to mount.
-	Name string `json:"name"`
-	// MountPath is where the secret should be mounted.
+	Name string `json:"name,omitempty"`
+	// Selector mounts a collection of secrets instead of a single one: every
+	// secret in the namespace which has the labels is mounted in a directory
+	// named after it under the mount path.  The collection is resolved when
+	// the test starts, so secrets can be added to or removed from it without
+	// changing the configuration of the step.
+	Selector *CredentialSelector `json:"selector,omitempty"`
+	// MountPath is where the secret should be mounted.  References to the
+	// parameters of the step, e.g. `$(PLATFORM)`, are expanded.
 	MountPath string `json:"mount_path"`
+	// Refresh propagates updates of the source secret to its copy in the
+	// test namespace while the test runs, for credentials which are rotated
+	// during long tests.  Mounted files are updated by the kubelet shortly
+	// after, so steps must read them again instead of caching their content.
+	Refresh bool `json:"refresh,omitempty"`
+}
+
+// CredentialSelector selects the secrets of a credential collection.
+type CredentialSelector struct {
+	// MatchLabels are the labels the secrets must have.
+	MatchLabels map[string]string `json:"matchLabels"`
 }
 
 // StepDependency defines a dependency on an image and the environment variable
@@ -1062,7 +1398,8 @@ type CredentialReference struct {
 type StepDependency struct {
 	// Name is the tag or stream:tag that this dependency references
 	Name string `json:"name"`
-	// Env is the environment variable that the image's pull spec is exposed with
+	// Env is the environment variable that the image's pull spec is exposed with.
+	// References to the parameters of the step, e.g. `$(PLATFORM)`, are expanded.
 	Env string `json:"env"`
 	// PullSpec allows the ci-operator user to pass in an external pull-spec that should be used when resolving the dependency
 	PullSpec string `json:"-"`
@@ -1108,12 +1445,26 @@ type TestStep struct {
 	Reference *string `json:"ref,omitempty"`
 	// Chain is the name of a step chain reference.
 	Chain *string `json:"chain,omitempty"`
+	// Alias disambiguates steps whose names would otherwise conflict, e.g.
+	// when two chains contribute steps with the same name.  For a reference,
+	// it replaces the name of the step.  For a chain, it is prepended to the
+	// names of all the steps of the chain, e.g. `upgrade-gather-must-gather`
+	// for the step `gather-must-gather` of a chain with the alias `upgrade`.
+	Alias string `json:"alias,omitempty"`
 }
 
 // MultiStageTestConfiguration is a flexible configuration mode that allows tighter control over
 // the multiple stages of end to end tests.
 type MultiStageTestConfiguration struct {
 	// ClusterProfile defines the profile/cloud provider for end-to-end test steps.
+	// Steps receive a $CLUSTER_NAME unique to the test and short enough for
+	// the platform and, when it is known, the $BASE_DOMAIN of the profile.
+	// Once the `pre` steps have completed, the following steps receive the
+	// $CONSOLE_URL, $API_URL and $OCP_VERSION of the cluster which was
+	// installed, also as the values of the step parameters of these names.
+	// Steps mount a copy of the profile owned by the test, in which the keys
+	// of the `<test>-cluster-profile-overrides` secret, if it exists, replace
+	// those of the profile.
 	ClusterProfile ClusterProfile `json:"cluster_profile,omitempty"`
 	// Pre is the array of test steps run to set up the environment for the test.
 	Pre []TestStep `json:"pre,omitempty"`
@@ -1128,6 +1479,11 @@ type MultiStageTestConfiguration struct {
 	Workflow *string `json:"workflow,omitempty"`
 	// Environment has the values of parameters for the steps.
 	Environment TestEnvironment `json:"env,omitempty"`
+	// EnvironmentFromFile has the values of parameters for the steps which are
+	// read from files, relative to the directory of the configuration file.
+	// The files are read when the configuration is loaded and their contents
+	// are added to Environment.
+	EnvironmentFromFile TestEnvironment `json:"env_from_file,omitempty"`
 	// Dependencies holds override values for dependency parameters.
 	Dependencies TestDependencies `json:"dependencies,omitempty"`
 	// DnsConfig for step's Pod.
@@ -1147,9 +1503,128 @@ type MultiStageTestConfiguration struct {
 	// DependencyOverrides allows a step to override a dependency with a fully-qualified pullspec. This will probably only ever
 	// be used with rehearsals. Otherwise, the overrides should be passed in as parameters to ci-operator.
 	DependencyOverrides DependencyOverrides `json:"dependency_overrides,omitempty"`
+	// StepReleases maps the names of steps to the release they use in place
+	// of `latest`: their dependencies on `release:latest` and on images of the
+	// `stable` stream, and the $RELEASE_IMAGE_LATEST given to them with a
+	// cluster profile, refer to the named release instead. This allows the
+	// steps of one test to use different payloads, e.g. for the management
+	// and the hosted clusters of a HyperShift test.
+	StepReleases StepReleases `json:"step_releases,omitempty"`
+	// DisruptionMonitor configures an availability monitor which runs in
+	// ci-operator during the `test` phase.
+	DisruptionMonitor *DisruptionMonitor `json:"disruption_monitor,omitempty"`
+	// ClusterHealthGate configures checks of the health of the cluster under
+	// test which must pass after the `pre` phase before `test` steps are run.
+	ClusterHealthGate *ClusterHealthGate `json:"cluster_health_gate,omitempty"`
+	// ContainerOnly declares that the steps of the test do not interact with
+	// any cluster. Steps are not given a kubeconfig, run with reduced
+	// permissions in the test namespace and have a default timeout of one
+	// hour. Cluster profiles, claims, observers and leases cannot be used.
+	ContainerOnly *bool `json:"container_only,omitempty"`
+	// FeatureSet is the OpenShift feature set the cluster under test is
+	// installed with, e.g. TechPreviewNoUpgrade. It is exposed to the steps
+	// as $FEATURE_SET and as the value of their parameter of that name, and
+	// is validated against the version of the `latest` release.
+	FeatureSet FeatureSet `json:"feature_set,omitempty"`
+	// ReuseClusterFrom is the name of another test of the configuration whose
+	// cluster this test runs against instead of provisioning one.  The test
+	// starts with a snapshot of the shared directory of the other test, taken
+	// once its `pre` steps have installed the cluster, and the other test only
+	// runs its `post` steps once this test has finished.  Cluster profiles and
+	// claims cannot be used.
+	ReuseClusterFrom string `json:"reuse_cluster_from,omitempty"`
 }
 type DependencyOverrides map[string]string
 
+// FeatureSet is an OpenShift feature set, which enables groups of features
+// that are not enabled by default.
+type FeatureSet string
+
+const (
+	FeatureSetTechPreviewNoUpgrade FeatureSet = "TechPreviewNoUpgrade"
+	FeatureSetDevPreviewNoUpgrade  FeatureSet = "DevPreviewNoUpgrade"
+	FeatureSetCustomNoUpgrade      FeatureSet = "CustomNoUpgrade"
+	FeatureSetLatencySensitive     FeatureSet = "LatencySensitive"
+)
+
+// FeatureSetEnv is the parameter which holds the feature set of a test.
+const FeatureSetEnv = "FEATURE_SET"
+
+// FeatureSetMinimumVersions are the first minor versions of OpenShift which
+// support each feature set.
+var FeatureSetMinimumVersions = map[FeatureSet]string{
+	FeatureSetTechPreviewNoUpgrade: "4.1",
+	FeatureSetCustomNoUpgrade:      "4.1",
+	FeatureSetLatencySensitive:     "4.6",
+	FeatureSetDevPreviewNoUpgrade:  "4.15",
+}
+
+// AllowsUpgrades determines whether clusters with the feature set can be
+// upgraded.
+func (f FeatureSet) AllowsUpgrades() bool {
+	return !strings.HasSuffix(string(f), "NoUpgrade")
+}
+
+// StepReleases maps the names of steps to the names of releases.
+type StepReleases map[string]string
+
+// DisruptionMonitor configures the endpoints polled by ci-operator while the
+// `test` phase of a multi-stage test runs. Periods of unavailability are
+// recorded in a report and fail the test when they exceed the allowed total.
+type DisruptionMonitor struct {
+	// Targets are the endpoints which are polled.
+	Targets []DisruptionTarget `json:"targets"`
+	// Interval is the time between two requests to a target, defaults to 1s.
+	Interval *prowv1.Duration `json:"interval,omitempty"`
+}
+
+// DisruptionTarget is an endpoint polled by the disruption monitor.
+type DisruptionTarget struct {
+	// Name identifies the target in the report.
+	Name string `json:"name"`
+	// URL is requested with GET on every poll. The target is considered
+	// available when the request succeeds with a status code below 500.
+	URL string `json:"url"`
+	// InsecureSkipTLSVerify disables the verification of the certificate
+	// served by the target, which is necessary when polling endpoints of
+	// ephemeral clusters with self-signed certificates.
+	InsecureSkipTLSVerify bool `json:"insecure_skip_tls_verify,omitempty"`
+	// MaxDisruption is the total unavailability tolerated during the `test`
+	// phase. When unset, disruption is only reported.
+	MaxDisruption *prowv1.Duration `json:"max_disruption,omitempty"`
+}
+
+// ClusterHealthGate configures the checks run by ci-operator against the
+// cluster installed in the `pre` phase, using the `kubeconfig` file placed in
+// the shared directory. The `test` phase only starts once all checks pass.
+type ClusterHealthGate struct {
+	// Checks are the checks which must pass. All checks are run when empty.
+	Checks []ClusterHealthCheck `json:"checks,omitempty"`
+	// Timeout is how long to wait for the checks to pass, defaults to 10m.
+	Timeout *prowv1.Duration `json:"timeout,omitempty"`
+}
+
+// ClusterHealthCheck is a check run by the cluster health gate.
+type ClusterHealthCheck string
+
+const (
+	// ClusterHealthCheckClusterVersion requires the ClusterVersion to be
+	// Available.
+	ClusterHealthCheckClusterVersion ClusterHealthCheck = "cluster_version"
+	// ClusterHealthCheckClusterOperators requires all ClusterOperators to be
+	// Available and not Degraded.
+	ClusterHealthCheckClusterOperators ClusterHealthCheck = "cluster_operators"
+	// ClusterHealthCheckNodes requires all Nodes to be Ready.
+	ClusterHealthCheckNodes ClusterHealthCheck = "nodes"
+)
+
+// ClusterHealthChecks are all valid cluster health checks.
+var ClusterHealthChecks = []ClusterHealthCheck{
+	ClusterHealthCheckClusterVersion,
+	ClusterHealthCheckClusterOperators,
+	ClusterHealthCheckNodes,
+}
+
 // MultiStageTestConfigurationLiteral is a form of the MultiStageTestConfiguration that does not include
 // references. It is the type that MultiStageTestConfigurations are converted to when parsed by the
 // ci-operator-configresolver.
@@ -1184,6 +1659,39 @@ type MultiStageTestConfigurationLiteral struct {
 	// DependencyOverrides allows a step to override a dependency with a fully-qualified pullspec. This will probably only ever
 	// be used with rehearsals. Otherwise, the overrides should be passed in as parameters to ci-operator.
 	DependencyOverrides DependencyOverrides `json:"dependency_overrides,omitempty"`
+	// StepReleases maps the names of steps to the release they use in place
+	// of `latest`: their dependencies on `release:latest` and on images of the
+	// `stable` stream, and the $RELEASE_IMAGE_LATEST given to them with a
+	// cluster profile, refer to the named release instead. This allows the
+	// steps of one test to use different payloads, e.g. for the management
+	// and the hosted clusters of a HyperShift test.
+	StepReleases StepReleases `json:"step_releases,omitempty"`
+	// DisruptionMonitor configures an availability monitor which runs in
+	// ci-operator during the `test` phase.
+	DisruptionMonitor *DisruptionMonitor `json:"disruption_monitor,omitempty"`
+	// ClusterHealthGate configures checks of the health of the cluster under
+	// test which must pass after the `pre` phase before `test` steps are run.
+	ClusterHealthGate *ClusterHealthGate `json:"cluster_health_gate,omitempty"`
+	// ContainerOnly declares that the steps of the test do not interact with
+	// any cluster. Steps are not given a kubeconfig, run with reduced
+	// permissions in the test namespace and have a default timeout of one
+	// hour. Cluster profiles, claims, observers and leases cannot be used.
+	ContainerOnly *bool `json:"container_only,omitempty"`
+	// FeatureSet is the OpenShift feature set the cluster under test is
+	// installed with, e.g. TechPreviewNoUpgrade. It is exposed to the steps
+	// as $FEATURE_SET and as the value of their parameter of that name, and
+	// is validated against the version of the `latest` release.
+	FeatureSet FeatureSet `json:"feature_set,omitempty"`
+	// ReuseClusterFrom is the name of another test of the configuration whose
+	// cluster this test runs against instead of provisioning one.  The test
+	// starts with a snapshot of the shared directory of the other test, taken
+	// once its `pre` steps have installed the cluster, and the other test only
+	// runs its `post` steps once this test has finished.  Cluster profiles and
+	// claims cannot be used.
+	ReuseClusterFrom string `json:"reuse_cluster_from,omitempty"`
+	// Rollbacks are the steps referenced by the `rollback` of other steps,
+	// executed only when those steps fail.
+	Rollbacks []LiteralTestStep `json:"rollbacks,omitempty"`
 
 	// Override job timeout
 	Timeout *prowv1.Duration `json:"timeout,omitempty"`
@@ -1344,6 +1852,7 @@ const (
 	ClusterProfileGCPVirtualization     ClusterProfile = "gcp-virtualization"
 	ClusterProfileAWSVirtualization     ClusterProfile = "aws-virtualization"
 	ClusterProfileAzureVirtualization   ClusterProfile = "azure-virtualization"
+	ClusterProfileOCI                   ClusterProfile = "oci"
 	ClusterProfileOCIAssisted           ClusterProfile = "oci-assisted"
 	ClusterProfileHypershiftPowerVS     ClusterProfile = "hypershift-powervs"
 	ClusterProfileHypershiftPowerVSCB   ClusterProfile = "hypershift-powervs-cb"
@@ -1463,6 +1972,7 @@ func ClusterProfiles() []ClusterProfile {
 		ClusterProfileVSphereMultizone,
 		ClusterProfileVSphereConnected,
 		ClusterProfileVSpherePlatformNone,
+		ClusterProfileOCI,
 		ClusterProfileOCIAssisted,
 		ClusterProfileHypershiftPowerVS,
 		ClusterProfileHypershiftPowerVSCB,
@@ -1641,6 +2151,8 @@ func (p ClusterProfile) ClusterType() string {
 		return "osd-ephemeral"
 	case ClusterProfileHyperShift:
 		return "hypershift"
+	case ClusterProfileOCI:
+		return "oci"
 	case ClusterProfileOCIAssisted:
 		return "oci-edge"
 	case ClusterProfileHypershiftPowerVS:
@@ -1862,6 +2374,8 @@ func (p ClusterProfile) LeaseType() string {
 		return "aws-3-quota-slice"
 	case ClusterProfileHyperShift:
 		return "hypershift-quota-slice"
+	case ClusterProfileOCI:
+		return "oci-quota-slice"
 	case ClusterProfileOCIAssisted:
 		return "oci-edge-quota-slice"
 	case ClusterProfileHypershiftPowerVS:
@@ -1945,7 +2459,7 @@ func (p ClusterProfile) Secret() string {
 // LeaseTypeFromClusterType maps cluster types to lease types
 func LeaseTypeFromClusterType(t string) (string, error) {
 	switch t {
-	case "aws", "aws-arm64", "aws-c2s", "aws-china", "aws-usgov", "aws-sc2s", "aws-osd-msp", "aws-outpost", "aws-local-zones", "aws-opendatahub", "alibaba", "azure-2", "azure4", "azure-arc", "azure-arm64", "azurestack", "azuremag", "equinix-ocp-metal", "gcp", "gcp-arm64", "gcp-opendatahub", "libvirt-ppc64le", "libvirt-s390x", "ibmcloud-multi-ppc64le", "ibmcloud-multi-s390x", "nutanix", "nutanix-qe", "nutanix-qe-dis", "openstack", "openstack-osuosl", "openstack-vexxhost", "openstack-ppc64le", "vsphere", "ovirt", "packet", "packet-edge", "powervs-1", "powervs-2", "powervs-3", "kubevirt", "aws-cpaas", "osd-ephemeral", "gcp-virtualization", "aws-virtualization", "azure-virtualization", "hypershift-powervs", "hypershift-powervs-cb":
+	case "aws", "aws-arm64", "aws-c2s", "aws-china", "aws-usgov", "aws-sc2s", "aws-osd-msp", "aws-outpost", "aws-local-zones", "aws-opendatahub", "alibaba", "azure-2", "azure4", "azure-arc", "azure-arm64", "azurestack", "azuremag", "equinix-ocp-metal", "gcp", "gcp-arm64", "gcp-opendatahub", "libvirt-ppc64le", "libvirt-s390x", "ibmcloud", "ibmcloud-multi-ppc64le", "ibmcloud-multi-s390x", "nutanix", "nutanix-qe", "nutanix-qe-dis", "oci", "oci-edge", "openstack", "openstack-osuosl", "openstack-vexxhost", "openstack-ppc64le", "vsphere", "ovirt", "packet", "packet-edge", "powervs-1", "powervs-2", "powervs-3", "kubevirt", "aws-cpaas", "osd-ephemeral", "gcp-virtualization", "aws-virtualization", "azure-virtualization", "hypershift-powervs", "hypershift-powervs-cb":
 		return t + "-quota-slice", nil
 	default:
 		return "", fmt.Errorf("invalid cluster type %q", t)
@@ -2051,6 +2565,26 @@ type OpenshiftInstallerGCPNestedVirtCustomTestImageClusterTestConfiguration stru
 	From string `json:"from"`
 }
 
+// AggregateTestConfiguration describes a test which runs no pods itself,
+// but gates on the results of other tests of the configuration.  The tests
+// are run when the aggregate test is, and their failures only fail the job
+// through the verdict of the aggregate test.
+type AggregateTestConfiguration struct {
+	// Tests are the names of the tests whose results are aggregated.
+	Tests []string `json:"tests"`
+	// MinimumPassing is the number of tests which must pass for the
+	// aggregate test to pass, e.g. 4 of 5.  All tests must pass by default.
+	MinimumPassing *int `json:"minimum_passing,omitempty"`
+}
+
+// Minimum returns the number of tests which must pass.
+func (config AggregateTestConfiguration) Minimum() int {
+	if config.MinimumPassing != nil {
+		return *config.MinimumPassing
+	}
+	return len(config.Tests)
+}
+
 // PipelineImageStreamTagReference is a tag on the
 // ImageStream corresponding to the code under test.
 // This tag will identify an image but not use any
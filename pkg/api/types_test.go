@@ -3,6 +3,7 @@ package api
 import (
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/ghodss/yaml"
 	"github.com/google/go-cmp/cmp"
@@ -345,3 +346,63 @@ func TestInputImageTagStepConfiguration(t *testing.T) {
 		})
 	}
 }
+
+func TestQuarantinedTestCaseActive(t *testing.T) {
+	q := QuarantinedTestCase{Name: "flaky", Reason: "flakes", Expires: "2024-01-31"}
+	for _, tc := range []struct {
+		now      string
+		expected bool
+	}{
+		{now: "2024-01-30T12:00:00Z", expected: true},
+		{now: "2024-01-31T23:59:59Z", expected: true},
+		{now: "2024-02-01T00:00:00Z"},
+	} {
+		now, err := time.Parse(time.RFC3339, tc.now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if active := q.Active(now); active != tc.expected {
+			t.Errorf("%s: expected active to be %t, got %t", tc.now, tc.expected, active)
+		}
+	}
+	if (QuarantinedTestCase{Expires: "soon"}).Active(time.Now()) {
+		t.Error("expected a waiver with an invalid expiry to be inactive")
+	}
+}
+
+func TestAlternativeCloudProfiles(t *testing.T) {
+	for _, tc := range []struct {
+		profile     ClusterProfile
+		clusterType string
+		leaseType   string
+	}{
+		{profile: ClusterProfileIBMCloud, clusterType: "ibmcloud", leaseType: "ibmcloud-quota-slice"},
+		{profile: ClusterProfilePOWERVS1, clusterType: "powervs-1", leaseType: "powervs-1-quota-slice"},
+		{profile: ClusterProfileNutanix, clusterType: "nutanix", leaseType: "nutanix-quota-slice"},
+		{profile: ClusterProfileOCI, clusterType: "oci", leaseType: "oci-quota-slice"},
+		{profile: ClusterProfileOCIAssisted, clusterType: "oci-edge", leaseType: "oci-edge-quota-slice"},
+	} {
+		t.Run(string(tc.profile), func(t *testing.T) {
+			var valid bool
+			for _, p := range ClusterProfiles() {
+				valid = valid || p == tc.profile
+			}
+			if !valid {
+				t.Error("expected the profile to be valid")
+			}
+			if diff := cmp.Diff(tc.clusterType, tc.profile.ClusterType()); diff != "" {
+				t.Errorf("unexpected cluster type: %s", diff)
+			}
+			if diff := cmp.Diff(tc.leaseType, tc.profile.LeaseType()); diff != "" {
+				t.Errorf("unexpected lease type: %s", diff)
+			}
+			leaseType, err := LeaseTypeFromClusterType(tc.clusterType)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.leaseType, leaseType); diff != "" {
+				t.Errorf("unexpected lease type of the cluster type: %s", diff)
+			}
+		})
+	}
+}
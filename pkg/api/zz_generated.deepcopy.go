@@ -9,6 +9,31 @@ import (
 	"k8s.io/test-infra/prow/apis/prowjobs/v1"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AggregateTestConfiguration) DeepCopyInto(out *AggregateTestConfiguration) {
+	*out = *in
+	if in.Tests != nil {
+		in, out := &in.Tests, &out.Tests
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.MinimumPassing != nil {
+		in, out := &in.MinimumPassing, &out.MinimumPassing
+		*out = new(int)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AggregateTestConfiguration.
+func (in *AggregateTestConfiguration) DeepCopy() *AggregateTestConfiguration {
+	if in == nil {
+		return nil
+	}
+	out := new(AggregateTestConfiguration)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *BuildArg) DeepCopyInto(out *BuildArg) {
 	*out = *in
@@ -158,6 +183,31 @@ func (in *ClusterClaim) DeepCopy() *ClusterClaim {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterHealthGate) DeepCopyInto(out *ClusterHealthGate) {
+	*out = *in
+	if in.Checks != nil {
+		in, out := &in.Checks, &out.Checks
+		*out = make([]ClusterHealthCheck, len(*in))
+		copy(*out, *in)
+	}
+	if in.Timeout != nil {
+		in, out := &in.Timeout, &out.Timeout
+		*out = new(v1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterHealthGate.
+func (in *ClusterHealthGate) DeepCopy() *ClusterHealthGate {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterHealthGate)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ClusterProfileDetails) DeepCopyInto(out *ClusterProfileDetails) {
 	*out = *in
@@ -264,6 +314,11 @@ func (in *ContainerTestConfiguration) DeepCopy() *ContainerTestConfiguration {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CredentialReference) DeepCopyInto(out *CredentialReference) {
 	*out = *in
+	if in.Selector != nil {
+		in, out := &in.Selector, &out.Selector
+		*out = new(CredentialSelector)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CredentialReference.
@@ -276,6 +331,28 @@ func (in *CredentialReference) DeepCopy() *CredentialReference {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CredentialSelector) DeepCopyInto(out *CredentialSelector) {
+	*out = *in
+	if in.MatchLabels != nil {
+		in, out := &in.MatchLabels, &out.MatchLabels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CredentialSelector.
+func (in *CredentialSelector) DeepCopy() *CredentialSelector {
+	if in == nil {
+		return nil
+	}
+	out := new(CredentialSelector)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in DependencyOverrides) DeepCopyInto(out *DependencyOverrides) {
 	{
@@ -297,6 +374,53 @@ func (in DependencyOverrides) DeepCopy() DependencyOverrides {
 	return *out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DisruptionMonitor) DeepCopyInto(out *DisruptionMonitor) {
+	*out = *in
+	if in.Targets != nil {
+		in, out := &in.Targets, &out.Targets
+		*out = make([]DisruptionTarget, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Interval != nil {
+		in, out := &in.Interval, &out.Interval
+		*out = new(v1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DisruptionMonitor.
+func (in *DisruptionMonitor) DeepCopy() *DisruptionMonitor {
+	if in == nil {
+		return nil
+	}
+	out := new(DisruptionMonitor)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DisruptionTarget) DeepCopyInto(out *DisruptionTarget) {
+	*out = *in
+	if in.MaxDisruption != nil {
+		in, out := &in.MaxDisruption, &out.MaxDisruption
+		*out = new(v1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DisruptionTarget.
+func (in *DisruptionTarget) DeepCopy() *DisruptionTarget {
+	if in == nil {
+		return nil
+	}
+	out := new(DisruptionTarget)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *GraphConfiguration) DeepCopyInto(out *GraphConfiguration) {
 	*out = *in
@@ -541,6 +665,16 @@ func (in *LiteralTestStep) DeepCopyInto(out *LiteralTestStep) {
 		*out = new(ImageStreamTagReference)
 		**out = **in
 	}
+	if in.Command != nil {
+		in, out := &in.Command, &out.Command
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Args != nil {
+		in, out := &in.Args, &out.Args
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	in.Resources.DeepCopyInto(&out.Resources)
 	if in.Timeout != nil {
 		in, out := &in.Timeout, &out.Timeout
@@ -555,7 +689,9 @@ func (in *LiteralTestStep) DeepCopyInto(out *LiteralTestStep) {
 	if in.Credentials != nil {
 		in, out := &in.Credentials, &out.Credentials
 		*out = make([]CredentialReference, len(*in))
-		copy(*out, *in)
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
 	}
 	if in.Environment != nil {
 		in, out := &in.Environment, &out.Environment
@@ -589,6 +725,11 @@ func (in *LiteralTestStep) DeepCopyInto(out *LiteralTestStep) {
 		*out = new(bool)
 		**out = **in
 	}
+	if in.Gather != nil {
+		in, out := &in.Gather, &out.Gather
+		*out = new(bool)
+		**out = **in
+	}
 	if in.NoKubeconfig != nil {
 		in, out := &in.NoKubeconfig, &out.NoKubeconfig
 		*out = new(bool)
@@ -604,6 +745,100 @@ func (in *LiteralTestStep) DeepCopyInto(out *LiteralTestStep) {
 		*out = new(bool)
 		**out = **in
 	}
+	if in.Toolkit != nil {
+		in, out := &in.Toolkit, &out.Toolkit
+		*out = new(bool)
+		**out = **in
+	}
+	if in.Burst != nil {
+		in, out := &in.Burst, &out.Burst
+		*out = new(bool)
+		**out = **in
+	}
+	if in.ShardCount != nil {
+		in, out := &in.ShardCount, &out.ShardCount
+		*out = new(int)
+		**out = **in
+	}
+	if in.RequiresSharedFiles != nil {
+		in, out := &in.RequiresSharedFiles, &out.RequiresSharedFiles
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ProvidesSharedFiles != nil {
+		in, out := &in.ProvidesSharedFiles, &out.ProvidesSharedFiles
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Sidecars != nil {
+		in, out := &in.Sidecars, &out.Sidecars
+		*out = make([]StepSidecar, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.HostNetwork != nil {
+		in, out := &in.HostNetwork, &out.HostNetwork
+		*out = new(bool)
+		**out = **in
+	}
+	if in.Sysctls != nil {
+		in, out := &in.Sysctls, &out.Sysctls
+		*out = make([]StepSysctl, len(*in))
+		copy(*out, *in)
+	}
+	if in.RunOnEphemeralCluster != nil {
+		in, out := &in.RunOnEphemeralCluster, &out.RunOnEphemeralCluster
+		*out = new(bool)
+		**out = **in
+	}
+	if in.WaitFor != nil {
+		in, out := &in.WaitFor, &out.WaitFor
+		*out = make([]WaitCondition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.PreviousJobArtifacts != nil {
+		in, out := &in.PreviousJobArtifacts, &out.PreviousJobArtifacts
+		*out = new(PreviousJobArtifacts)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Upgrade != nil {
+		in, out := &in.Upgrade, &out.Upgrade
+		*out = new(UpgradeStep)
+		**out = **in
+	}
+	if in.FailOnRestart != nil {
+		in, out := &in.FailOnRestart, &out.FailOnRestart
+		*out = new(bool)
+		**out = **in
+	}
+	if in.NodeCapabilities != nil {
+		in, out := &in.NodeCapabilities, &out.NodeCapabilities
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Critical != nil {
+		in, out := &in.Critical, &out.Critical
+		*out = new(bool)
+		**out = **in
+	}
+	if in.Job != nil {
+		in, out := &in.Job, &out.Job
+		*out = new(StepJob)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.StdinFrom != nil {
+		in, out := &in.StdinFrom, &out.StdinFrom
+		*out = new(StepStdin)
+		**out = **in
+	}
+	if in.SplitLogs != nil {
+		in, out := &in.SplitLogs, &out.SplitLogs
+		*out = new(bool)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LiteralTestStep.
@@ -698,6 +933,13 @@ func (in *MultiStageTestConfiguration) DeepCopyInto(out *MultiStageTestConfigura
 			(*out)[key] = val
 		}
 	}
+	if in.EnvironmentFromFile != nil {
+		in, out := &in.EnvironmentFromFile, &out.EnvironmentFromFile
+		*out = make(TestEnvironment, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 	if in.Dependencies != nil {
 		in, out := &in.Dependencies, &out.Dependencies
 		*out = make(TestDependencies, len(*in))
@@ -737,6 +979,28 @@ func (in *MultiStageTestConfiguration) DeepCopyInto(out *MultiStageTestConfigura
 			(*out)[key] = val
 		}
 	}
+	if in.StepReleases != nil {
+		in, out := &in.StepReleases, &out.StepReleases
+		*out = make(StepReleases, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.DisruptionMonitor != nil {
+		in, out := &in.DisruptionMonitor, &out.DisruptionMonitor
+		*out = new(DisruptionMonitor)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ClusterHealthGate != nil {
+		in, out := &in.ClusterHealthGate, &out.ClusterHealthGate
+		*out = new(ClusterHealthGate)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ContainerOnly != nil {
+		in, out := &in.ContainerOnly, &out.ContainerOnly
+		*out = new(bool)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MultiStageTestConfiguration.
@@ -821,6 +1085,35 @@ func (in *MultiStageTestConfigurationLiteral) DeepCopyInto(out *MultiStageTestCo
 			(*out)[key] = val
 		}
 	}
+	if in.StepReleases != nil {
+		in, out := &in.StepReleases, &out.StepReleases
+		*out = make(StepReleases, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.DisruptionMonitor != nil {
+		in, out := &in.DisruptionMonitor, &out.DisruptionMonitor
+		*out = new(DisruptionMonitor)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ClusterHealthGate != nil {
+		in, out := &in.ClusterHealthGate, &out.ClusterHealthGate
+		*out = new(ClusterHealthGate)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ContainerOnly != nil {
+		in, out := &in.ContainerOnly, &out.ContainerOnly
+		*out = new(bool)
+		**out = **in
+	}
+	if in.Rollbacks != nil {
+		in, out := &in.Rollbacks, &out.Rollbacks
+		*out = make([]LiteralTestStep, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	if in.Timeout != nil {
 		in, out := &in.Timeout, &out.Timeout
 		*out = new(v1.Duration)
@@ -1165,6 +1458,26 @@ func (in *Prerelease) DeepCopy() *Prerelease {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PreviousJobArtifacts) DeepCopyInto(out *PreviousJobArtifacts) {
+	*out = *in
+	if in.Files != nil {
+		in, out := &in.Files, &out.Files
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PreviousJobArtifacts.
+func (in *PreviousJobArtifacts) DeepCopy() *PreviousJobArtifacts {
+	if in == nil {
+		return nil
+	}
+	out := new(PreviousJobArtifacts)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ProjectDirectoryImageBuildInputs) DeepCopyInto(out *ProjectDirectoryImageBuildInputs) {
 	*out = *in
@@ -1289,6 +1602,21 @@ func (in *PullSpecSubstitution) DeepCopy() *PullSpecSubstitution {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *QuarantinedTestCase) DeepCopyInto(out *QuarantinedTestCase) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new QuarantinedTestCase.
+func (in *QuarantinedTestCase) DeepCopy() *QuarantinedTestCase {
+	if in == nil {
+		return nil
+	}
+	out := new(QuarantinedTestCase)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RPMImageInjectionStepConfiguration) DeepCopyInto(out *RPMImageInjectionStepConfiguration) {
 	*out = *in
@@ -1598,6 +1926,16 @@ func (in *ReleaseBuildConfiguration) DeepCopyInto(out *ReleaseBuildConfiguration
 			(*out)[key] = *val.DeepCopy()
 		}
 	}
+	if in.Quarantine != nil {
+		in, out := &in.Quarantine, &out.Quarantine
+		*out = make([]QuarantinedTestCase, len(*in))
+		copy(*out, *in)
+	}
+	if in.SharedLeases != nil {
+		in, out := &in.SharedLeases, &out.SharedLeases
+		*out = make([]SharedLease, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReleaseBuildConfiguration.
@@ -1742,6 +2080,21 @@ func (in *Secret) DeepCopy() *Secret {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SharedLease) DeepCopyInto(out *SharedLease) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SharedLease.
+func (in *SharedLease) DeepCopy() *SharedLease {
+	if in == nil {
+		return nil
+	}
+	out := new(SharedLease)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SourceStepConfiguration) DeepCopyInto(out *SourceStepConfiguration) {
 	*out = *in
@@ -1878,6 +2231,26 @@ func (in *StepDependency) DeepCopy() *StepDependency {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StepJob) DeepCopyInto(out *StepJob) {
+	*out = *in
+	if in.TTLSecondsAfterFinished != nil {
+		in, out := &in.TTLSecondsAfterFinished, &out.TTLSecondsAfterFinished
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StepJob.
+func (in *StepJob) DeepCopy() *StepJob {
+	if in == nil {
+		return nil
+	}
+	out := new(StepJob)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *StepLease) DeepCopyInto(out *StepLease) {
 	*out = *in
@@ -1928,6 +2301,74 @@ func (in *StepParameter) DeepCopy() *StepParameter {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in StepReleases) DeepCopyInto(out *StepReleases) {
+	{
+		in := &in
+		*out = make(StepReleases, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StepReleases.
+func (in StepReleases) DeepCopy() StepReleases {
+	if in == nil {
+		return nil
+	}
+	out := new(StepReleases)
+	in.DeepCopyInto(out)
+	return *out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StepSidecar) DeepCopyInto(out *StepSidecar) {
+	*out = *in
+	in.Resources.DeepCopyInto(&out.Resources)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StepSidecar.
+func (in *StepSidecar) DeepCopy() *StepSidecar {
+	if in == nil {
+		return nil
+	}
+	out := new(StepSidecar)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StepStdin) DeepCopyInto(out *StepStdin) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StepStdin.
+func (in *StepStdin) DeepCopy() *StepStdin {
+	if in == nil {
+		return nil
+	}
+	out := new(StepStdin)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StepSysctl) DeepCopyInto(out *StepSysctl) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StepSysctl.
+func (in *StepSysctl) DeepCopy() *StepSysctl {
+	if in == nil {
+		return nil
+	}
+	out := new(StepSysctl)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in TestDependencies) DeepCopyInto(out *TestDependencies) {
 	{
@@ -2049,6 +2490,20 @@ func (in *TestStepConfiguration) DeepCopyInto(out *TestStepConfiguration) {
 		*out = new(v1.Duration)
 		**out = **in
 	}
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Annotations != nil {
+		in, out := &in.Annotations, &out.Annotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 	if in.ContainerTestConfiguration != nil {
 		in, out := &in.ContainerTestConfiguration, &out.ContainerTestConfiguration
 		*out = new(ContainerTestConfiguration)
@@ -2099,6 +2554,11 @@ func (in *TestStepConfiguration) DeepCopyInto(out *TestStepConfiguration) {
 		*out = new(OpenshiftInstallerCustomTestImageClusterTestConfiguration)
 		**out = **in
 	}
+	if in.AggregateTestConfiguration != nil {
+		in, out := &in.AggregateTestConfiguration, &out.AggregateTestConfiguration
+		*out = new(AggregateTestConfiguration)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TestStepConfiguration.
@@ -2146,6 +2606,21 @@ func (in *UnresolvedRelease) DeepCopy() *UnresolvedRelease {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UpgradeStep) DeepCopyInto(out *UpgradeStep) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UpgradeStep.
+func (in *UpgradeStep) DeepCopy() *UpgradeStep {
+	if in == nil {
+		return nil
+	}
+	out := new(UpgradeStep)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *VersionBounds) DeepCopyInto(out *VersionBounds) {
 	*out = *in
@@ -2160,3 +2635,24 @@ func (in *VersionBounds) DeepCopy() *VersionBounds {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WaitCondition) DeepCopyInto(out *WaitCondition) {
+	*out = *in
+	if in.Timeout != nil {
+		in, out := &in.Timeout, &out.Timeout
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WaitCondition.
+func (in *WaitCondition) DeepCopy() *WaitCondition {
+	if in == nil {
+		return nil
+	}
+	out := new(WaitCondition)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -0,0 +1,268 @@
+// Package artifactindex generates the index of the artifacts of a ci-operator
+// job, which summarizes the steps of the job and links to their artifacts for
+// those who land in the artifact directory without context.
+package artifactindex
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/junit"
+)
+
+const (
+	// MarkdownFilename is the name of the index in the artifact root, which
+	// GCS browsers render when listing the directory.
+	MarkdownFilename = "README.md"
+	// HTMLFilename is the name of the index in the artifact root, for
+	// browsers which serve the directory as a website.
+	HTMLFilename = "index.html"
+
+	// snippetLines is the number of trailing lines of the output of a failure
+	// included in the index.
+	snippetLines = 20
+)
+
+const (
+	resultSucceeded = "succeeded"
+	resultFailed    = "failed"
+	resultNotRun    = "not run"
+)
+
+// Index summarizes the steps of a job.
+type Index struct {
+	Title    string
+	Steps    []Step
+	Failures []Failure
+	// Artifacts are the files written by ci-operator for the whole job.
+	Artifacts []Link
+}
+
+// Step is a step of the job, or a pod of a multi-stage test.
+type Step struct {
+	Name        string
+	Description string
+	Result      string
+	Duration    string
+	Links       []Link
+	Substeps    []Step
+}
+
+// Link points to an artifact, relative to the artifact root.
+type Link struct {
+	Name, Path string
+}
+
+// Failure is a failed jUnit test case, with the end of its output.
+type Failure struct {
+	Name, Message, Snippet string
+}
+
+// New builds the index of the job from the graph of its steps and the jUnit
+// results of their execution.
+func New(title string, graph api.CIOperatorStepGraph, suites *junit.TestSuites) *Index {
+	index := &Index{
+		Title: title,
+		Artifacts: []Link{
+			{Name: "ci-operator log", Path: "../build-log.txt"},
+			{Name: "step graph", Path: api.CIOperatorStepGraphJSONFilename},
+			{Name: "jUnit results", Path: "junit_operator.xml"},
+		},
+	}
+	for _, details := range graph {
+		step := newStep(details.CIOperatorStepDetailInfo)
+		for _, sub := range details.Substeps {
+			// only the pods of multi-stage tests report their result, other
+			// sub-steps are entries of the timeline such as image pulls
+			if sub.Failed == nil {
+				continue
+			}
+			substep := newStep(sub)
+			dir := fmt.Sprintf("%s/%s", details.StepName, strings.TrimPrefix(sub.StepName, details.StepName+"-"))
+			substep.Links = []Link{
+				{Name: "log", Path: dir + "/build-log.txt"},
+				{Name: "artifacts", Path: dir + "/artifacts/"},
+			}
+			step.Substeps = append(step.Substeps, substep)
+		}
+		if len(step.Substeps) != 0 {
+			step.Links = []Link{{Name: "artifacts", Path: details.StepName + "/"}}
+		}
+		index.Steps = append(index.Steps, step)
+	}
+	if suites != nil {
+		for _, suite := range suites.Suites {
+			index.Failures = append(index.Failures, failures(suite)...)
+		}
+	}
+	return index
+}
+
+func newStep(info api.CIOperatorStepDetailInfo) Step {
+	step := Step{Name: info.StepName, Description: info.Description, Result: resultNotRun}
+	switch {
+	case info.Failed != nil && *info.Failed:
+		step.Result = resultFailed
+	case info.FinishedAt != nil:
+		step.Result = resultSucceeded
+	}
+	if info.Duration != nil {
+		step.Duration = info.Duration.Truncate(time.Second).String()
+	}
+	return step
+}
+
+func failures(suite *junit.TestSuite) []Failure {
+	var ret []Failure
+	for _, test := range suite.TestCases {
+		if test.FailureOutput == nil {
+			continue
+		}
+		ret = append(ret, Failure{
+			Name:    test.Name,
+			Message: test.FailureOutput.Message,
+			Snippet: snippet(test.FailureOutput.Output),
+		})
+	}
+	for _, child := range suite.Children {
+		ret = append(ret, failures(child)...)
+	}
+	return ret
+}
+
+// snippet returns the last lines of the output.
+func snippet(output string) string {
+	lines := strings.Split(strings.TrimRight(output, "\n"), "\n")
+	if len(lines) > snippetLines {
+		lines = append([]string{"..."}, lines[len(lines)-snippetLines:]...)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Failed returns the number of steps which failed.
+func (i *Index) Failed() int {
+	var n int
+	for _, step := range i.Steps {
+		if step.Result == resultFailed {
+			n++
+		}
+	}
+	return n
+}
+
+// fence returns a code fence longer than any sequence of backticks in the
+// text, so that it cannot be closed early.
+func fence(text string) string {
+	longest, current := 0, 0
+	for _, c := range text {
+		if c == '`' {
+			current++
+			longest = max(longest, current)
+		} else {
+			current = 0
+		}
+	}
+	return strings.Repeat("`", max(3, longest+1))
+}
+
+var funcs = map[string]any{
+	"fence": fence,
+	"cell":  func(s string) string { return strings.ReplaceAll(s, "|", `\|`) },
+}
+
+var markdownTemplate = template.Must(template.New("README.md").Funcs(funcs).Parse(`# {{ .Title }}
+
+{{ len .Steps }} steps, {{ .Failed }} failed.
+{{- range $i, $link := .Artifacts }}{{ if $i }} ·{{ else }} Start with:{{ end }} [{{ $link.Name }}]({{ $link.Path }}){{ end }}
+
+| Step | Result | Duration | Artifacts |
+| ---- | ------ | -------- | --------- |
+{{- range .Steps }}
+| **{{ cell .Name }}**<br>{{ cell .Description }} | {{ .Result }} | {{ .Duration }} | {{ range $i, $link := .Links }}{{ if $i }} · {{ end }}[{{ $link.Name }}]({{ $link.Path }}){{ end }} |
+{{- range .Substeps }}
+| ↳ {{ cell .Name }} | {{ .Result }} | {{ .Duration }} | {{ range $i, $link := .Links }}{{ if $i }} · {{ end }}[{{ $link.Name }}]({{ $link.Path }}){{ end }} |
+{{- end }}
+{{- end }}
+{{- if .Failures }}
+
+## Failures
+{{- range .Failures }}
+
+### {{ .Name }}
+{{ if .Message }}
+{{ .Message }}
+{{ end }}
+{{- if .Snippet }}
+{{ fence .Snippet }}
+{{ .Snippet }}
+{{ fence .Snippet }}
+{{- end }}
+{{- end }}
+{{- end }}
+`))
+
+var htmlTemplate = htmltemplate.Must(htmltemplate.New("index.html").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="UTF-8">
+<title>{{ .Title }}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; }
+td, th { border: 1px solid #ccc; padding: 0.3em 0.6em; text-align: left; vertical-align: top; }
+tr.failed td { background: #fdd; }
+tr.substep td:first-child { padding-left: 2em; }
+pre { background: #f6f6f6; padding: 0.6em; overflow-x: auto; }
+</style>
+</head>
+<body>
+<h1>{{ .Title }}</h1>
+<p>{{ len .Steps }} steps, {{ .Failed }} failed. Start with:
+{{- range $i, $link := .Artifacts }}{{ if $i }} ·{{ end }} <a href="{{ $link.Path }}">{{ $link.Name }}</a>{{ end }}</p>
+<table>
+<tr><th>Step</th><th>Result</th><th>Duration</th><th>Artifacts</th></tr>
+{{- range .Steps }}
+<tr class="{{ if eq .Result "failed" }}failed{{ end }}"><td><b>{{ .Name }}</b><br>{{ .Description }}</td><td>{{ .Result }}</td><td>{{ .Duration }}</td><td>{{ range $i, $link := .Links }}{{ if $i }} · {{ end }}<a href="{{ $link.Path }}">{{ $link.Name }}</a>{{ end }}</td></tr>
+{{- range .Substeps }}
+<tr class="substep{{ if eq .Result "failed" }} failed{{ end }}"><td>{{ .Name }}</td><td>{{ .Result }}</td><td>{{ .Duration }}</td><td>{{ range $i, $link := .Links }}{{ if $i }} · {{ end }}<a href="{{ $link.Path }}">{{ $link.Name }}</a>{{ end }}</td></tr>
+{{- end }}
+{{- end }}
+</table>
+{{- if .Failures }}
+<h2>Failures</h2>
+{{- range .Failures }}
+<h3>{{ .Name }}</h3>
+{{- if .Message }}
+<p>{{ .Message }}</p>
+{{- end }}
+{{- if .Snippet }}
+<pre>{{ .Snippet }}</pre>
+{{- end }}
+{{- end }}
+{{- end }}
+</body>
+</html>
+`))
+
+// Markdown renders the index as a markdown document.
+func (i *Index) Markdown() (string, error) {
+	var b bytes.Buffer
+	if err := markdownTemplate.Execute(&b, i); err != nil {
+		return "", fmt.Errorf("failed to render the markdown index: %w", err)
+	}
+	return b.String(), nil
+}
+
+// HTML renders the index as an HTML page.
+func (i *Index) HTML() (string, error) {
+	var b bytes.Buffer
+	if err := htmlTemplate.Execute(&b, i); err != nil {
+		return "", fmt.Errorf("failed to render the HTML index: %w", err)
+	}
+	return b.String(), nil
+}
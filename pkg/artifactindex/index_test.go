@@ -0,0 +1,88 @@
+package artifactindex
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	utilpointer "k8s.io/utils/pointer"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/junit"
+	"github.com/openshift/ci-tools/pkg/testhelper"
+)
+
+func testIndex() *Index {
+	started := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	info := func(name, description string, duration time.Duration, failed *bool) api.CIOperatorStepDetailInfo {
+		finished := started.Add(duration)
+		return api.CIOperatorStepDetailInfo{
+			StepName:    name,
+			Description: description,
+			StartedAt:   &started,
+			FinishedAt:  &finished,
+			Duration:    &duration,
+			Failed:      failed,
+		}
+	}
+	var log []string
+	for i := 0; i < 30; i++ {
+		log = append(log, fmt.Sprintf("line %d", i))
+	}
+	log = append(log, "```", "error: the | cluster is unreachable")
+	graph := api.CIOperatorStepGraph{
+		{CIOperatorStepDetailInfo: info("src", "Build image src from the repository", 2*time.Minute+500*time.Millisecond, utilpointer.Bool(false))},
+		{
+			CIOperatorStepDetailInfo: info("e2e", "Run multi-stage test e2e", time.Hour, utilpointer.Bool(true)),
+			Substeps: []api.CIOperatorStepDetailInfo{
+				info("e2e-ipi-install", "Run pod e2e-ipi-install", 40*time.Minute, utilpointer.Bool(false)),
+				info("e2e-ipi-install-pull-test", "Pull image quay.io/org/installer:latest", time.Minute, nil),
+				info("e2e-test", "Run pod e2e-test", 20*time.Minute, utilpointer.Bool(true)),
+			},
+		},
+		{CIOperatorStepDetailInfo: api.CIOperatorStepDetailInfo{StepName: "[images]", Description: "All images are built and tagged into stable"}},
+	}
+	suites := &junit.TestSuites{Suites: []*junit.TestSuite{{
+		Name: "operator",
+		TestCases: []*junit.TestCase{
+			{Name: "Build image src from the repository"},
+			{Name: "Run multi-stage test e2e - e2e-test container test", FailureOutput: &junit.FailureOutput{Message: "the step failed", Output: strings.Join(log, "\n")}},
+		},
+		Children: []*junit.TestSuite{{
+			Name:      "e2e",
+			TestCases: []*junit.TestCase{{Name: "[sig-network] pods can reach each other", FailureOutput: &junit.FailureOutput{Output: "timed out"}}},
+		}},
+	}}}
+	return New("Artifacts of job pull-ci-org-repo-master-e2e #1", graph, suites)
+}
+
+func TestIndex(t *testing.T) {
+	index := testIndex()
+	testhelper.Diff(t, "failed", index.Failed(), 1)
+	t.Run("markdown", func(t *testing.T) {
+		markdown, err := index.Markdown()
+		if err != nil {
+			t.Fatal(err)
+		}
+		testhelper.CompareWithFixture(t, markdown, testhelper.WithExtension(".md"))
+	})
+	t.Run("html", func(t *testing.T) {
+		html, err := index.HTML()
+		if err != nil {
+			t.Fatal(err)
+		}
+		testhelper.CompareWithFixture(t, html, testhelper.WithExtension(".html"))
+	})
+}
+
+func TestFence(t *testing.T) {
+	for text, expected := range map[string]string{
+		"no code":          "```",
+		"some `code`":      "```",
+		"a ```fence```":    "````",
+		"a ``````long one": "```````",
+	} {
+		testhelper.Diff(t, text, fence(text), expected)
+	}
+}
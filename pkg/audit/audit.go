@@ -0,0 +1,195 @@
+// Package audit collects the records of the API audit log of the build
+// cluster which concern the namespace of a test, so the requests made by the
+// steps of the test can be reviewed after it ran.
+package audit
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	coreapi "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// EventsArtifact is the name of the artifact file, relative to the
+	// artifact directory, which holds the audit records of the namespace.
+	EventsArtifact = "audit/audit.jsonl"
+	// SummaryArtifact is the name of the artifact file, relative to the
+	// artifact directory, which holds the summary of the audit records.
+	SummaryArtifact = "audit/summary.json"
+
+	// stageResponseComplete is the stage of the records which are collected,
+	// the only one with the status of the response.
+	stageResponseComplete = "ResponseComplete"
+	// controlPlaneLabel marks the nodes which run the API servers.
+	controlPlaneLabel = "node-role.kubernetes.io/master"
+)
+
+// logPaths are the audit logs of the API servers, relative to the log
+// directory of the control plane nodes.
+var logPaths = []string{"kube-apiserver/audit.log", "openshift-apiserver/audit.log"}
+
+// Event holds the fields of an audit record (audit.k8s.io/v1 Event) which are
+// collected.
+type Event struct {
+	AuditID                  string          `json:"auditID"`
+	Stage                    string          `json:"stage"`
+	RequestURI               string          `json:"requestURI"`
+	Verb                     string          `json:"verb"`
+	User                     User            `json:"user"`
+	ObjectRef                *ObjectRef      `json:"objectRef,omitempty"`
+	ResponseStatus           *ResponseStatus `json:"responseStatus,omitempty"`
+	RequestReceivedTimestamp meta.MicroTime  `json:"requestReceivedTimestamp"`
+}
+
+// User is the user which made the request.
+type User struct {
+	Username string `json:"username"`
+}
+
+// ObjectRef is the object the request concerns.
+type ObjectRef struct {
+	Resource    string `json:"resource,omitempty"`
+	Namespace   string `json:"namespace,omitempty"`
+	Name        string `json:"name,omitempty"`
+	APIGroup    string `json:"apiGroup,omitempty"`
+	Subresource string `json:"subresource,omitempty"`
+}
+
+// ResponseStatus is the status of the response to the request.
+type ResponseStatus struct {
+	Code int32 `json:"code,omitempty"`
+}
+
+// resource returns the resource of the request, qualified by its group and
+// subresource, e.g. `pods/log` or `deployments.apps`.
+func (e *Event) resource() string {
+	if e.ObjectRef == nil {
+		return ""
+	}
+	ret := e.ObjectRef.Resource
+	if e.ObjectRef.APIGroup != "" {
+		ret += "." + e.ObjectRef.APIGroup
+	}
+	if e.ObjectRef.Subresource != "" {
+		ret += "/" + e.ObjectRef.Subresource
+	}
+	return ret
+}
+
+// denied determines whether the request was rejected by the authorizer.
+func (e *Event) denied() bool {
+	return e.ResponseStatus != nil && e.ResponseStatus.Code == 403
+}
+
+// Filter reads an audit log and returns the completed requests made since a
+// time which concern a namespace: those for objects in the namespace and
+// those made by its service accounts.  Malformed records are skipped.
+func Filter(r io.Reader, namespace string, since time.Time) ([]Event, error) {
+	serviceAccounts := fmt.Sprintf("system:serviceaccount:%s:", namespace)
+	var ret []Event
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var e Event
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			continue
+		}
+		if e.Stage != stageResponseComplete || e.RequestReceivedTimestamp.Time.Before(since) {
+			continue
+		}
+		if (e.ObjectRef != nil && e.ObjectRef.Namespace == namespace) || strings.HasPrefix(e.User.Username, serviceAccounts) {
+			ret = append(ret, e)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return ret, fmt.Errorf("failed to read audit log: %w", err)
+	}
+	return ret, nil
+}
+
+// Collect reads the audit logs of the control plane nodes through the API
+// server and returns the records which concern a namespace, sorted by time.
+// It requires access to the logs of the nodes, which is usually restricted
+// to cluster administrators.  Logs which cannot be read are skipped.
+func Collect(ctx context.Context, client kubernetes.Interface, namespace string, since time.Time) ([]Event, error) {
+	nodes, err := client.CoreV1().Nodes().List(ctx, meta.ListOptions{LabelSelector: controlPlaneLabel})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list control plane nodes: %w", err)
+	}
+	var ret []Event
+	for _, node := range nodes.Items {
+		for _, path := range logPaths {
+			events, err := collectFrom(ctx, client, &node, path, namespace, since)
+			if err != nil {
+				logrus.WithError(err).Debugf("Failed to read audit log %s of node %s.", path, node.Name)
+				continue
+			}
+			ret = append(ret, events...)
+		}
+	}
+	sort.SliceStable(ret, func(i, j int) bool {
+		return ret[i].RequestReceivedTimestamp.Before(&ret[j].RequestReceivedTimestamp)
+	})
+	return ret, nil
+}
+
+func collectFrom(ctx context.Context, client kubernetes.Interface, node *coreapi.Node, path, namespace string, since time.Time) ([]Event, error) {
+	stream, err := client.CoreV1().RESTClient().Get().AbsPath("/api/v1/nodes", node.Name, "proxy", "logs", path).Stream(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer stream.Close()
+	return Filter(stream, namespace, since)
+}
+
+// Summary counts the requests made by a user with a verb for a resource,
+// e.g. as a starting point for the RBAC rules of a service account.
+type Summary struct {
+	User     string `json:"user"`
+	Verb     string `json:"verb"`
+	Resource string `json:"resource,omitempty"`
+	Count    int    `json:"count"`
+	// Denied is the number of requests rejected by the authorizer.
+	Denied int `json:"denied,omitempty"`
+}
+
+// Summarize groups the records by user, verb and resource, sorted.
+func Summarize(events []Event) []Summary {
+	index := map[Summary]int{}
+	var ret []Summary
+	for i := range events {
+		e := &events[i]
+		key := Summary{User: e.User.Username, Verb: e.Verb, Resource: e.resource()}
+		idx, ok := index[key]
+		if !ok {
+			idx = len(ret)
+			index[key] = idx
+			ret = append(ret, key)
+		}
+		ret[idx].Count++
+		if e.denied() {
+			ret[idx].Denied++
+		}
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].User != ret[j].User {
+			return ret[i].User < ret[j].User
+		}
+		if ret[i].Resource != ret[j].Resource {
+			return ret[i].Resource < ret[j].Resource
+		}
+		return ret[i].Verb < ret[j].Verb
+	})
+	return ret
+}
@@ -0,0 +1,46 @@
+package audit
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/openshift/ci-tools/pkg/testhelper"
+)
+
+const log = `{"auditID":"1","stage":"ResponseStarted","verb":"watch","user":{"username":"system:serviceaccount:ci-op-1234:e2e"},"objectRef":{"resource":"pods","namespace":"ci-op-1234"},"requestReceivedTimestamp":"2023-01-01T10:00:00.000000Z"}
+{"auditID":"2","stage":"ResponseComplete","verb":"create","user":{"username":"system:serviceaccount:ci-op-1234:e2e"},"objectRef":{"resource":"configmaps","namespace":"ci-op-1234","name":"data"},"responseStatus":{"code":201},"requestReceivedTimestamp":"2023-01-01T10:00:01.000000Z"}
+not a record
+{"auditID":"3","stage":"ResponseComplete","verb":"list","user":{"username":"system:serviceaccount:ci-op-1234:e2e"},"objectRef":{"resource":"nodes"},"responseStatus":{"code":403},"requestReceivedTimestamp":"2023-01-01T10:00:02.000000Z"}
+{"auditID":"4","stage":"ResponseComplete","verb":"get","user":{"username":"system:admin"},"objectRef":{"resource":"deployments","apiGroup":"apps","namespace":"other"},"responseStatus":{"code":200},"requestReceivedTimestamp":"2023-01-01T10:00:03.000000Z"}
+{"auditID":"5","stage":"ResponseComplete","verb":"get","user":{"username":"system:admin"},"objectRef":{"resource":"pods","subresource":"log","namespace":"ci-op-1234","name":"e2e-test"},"responseStatus":{"code":200},"requestReceivedTimestamp":"2023-01-01T10:00:04.000000Z"}
+{"auditID":"6","stage":"ResponseComplete","verb":"create","user":{"username":"system:serviceaccount:ci-op-1234:e2e"},"objectRef":{"resource":"configmaps","namespace":"ci-op-1234","name":"data"},"responseStatus":{"code":201},"requestReceivedTimestamp":"2023-01-01T09:00:00.000000Z"}
+`
+
+func TestFilter(t *testing.T) {
+	since := time.Date(2023, 1, 1, 10, 0, 0, 0, time.UTC)
+	events, err := Filter(strings.NewReader(log), "ci-op-1234", since)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, e := range events {
+		ids = append(ids, e.AuditID)
+	}
+	testhelper.Diff(t, "records", ids, []string{"2", "3", "5"})
+	testhelper.Diff(t, "timestamp", events[0].RequestReceivedTimestamp, meta.NewMicroTime(since.Add(time.Second)))
+}
+
+func TestSummarize(t *testing.T) {
+	events, err := Filter(strings.NewReader(log), "ci-op-1234", time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testhelper.Diff(t, "summary", Summarize(events), []Summary{
+		{User: "system:admin", Verb: "get", Resource: "pods/log", Count: 1},
+		{User: "system:serviceaccount:ci-op-1234:e2e", Verb: "create", Resource: "configmaps", Count: 2},
+		{User: "system:serviceaccount:ci-op-1234:e2e", Verb: "list", Resource: "nodes", Count: 1, Denied: 1},
+	})
+}
@@ -14,6 +14,7 @@ import (
 	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 
 	cioperatorapi "github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/load"
 	"github.com/openshift/ci-tools/pkg/util"
 	"github.com/openshift/ci-tools/pkg/util/gzip"
 	"github.com/openshift/ci-tools/pkg/validation"
@@ -94,6 +95,9 @@ func readCiOperatorConfig(configFilePath string, info Info) (*cioperatorapi.Rele
 	if err := yaml.Unmarshal(data, &configSpec); err != nil {
 		return nil, fmt.Errorf("failed to load ci-operator config (%w)", err)
 	}
+	if err := load.EnvironmentFromFiles(&configSpec, filepath.Dir(configFilePath)); err != nil {
+		return nil, fmt.Errorf("failed to load ci-operator config (%w)", err)
+	}
 
 	if err := validation.IsValidConfiguration(&configSpec, info.Org, info.Repo); err != nil {
 		return nil, fmt.Errorf("invalid ci-operator config: %w", err)
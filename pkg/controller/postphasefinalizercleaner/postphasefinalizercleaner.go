@@ -0,0 +1,109 @@
+package postphasefinalizercleaner
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	"github.com/openshift/ci-tools/pkg/api"
+)
+
+const ControllerName = "postphasefinalizercleaner"
+
+func AddToManager(
+	mgr manager.Manager,
+	allManagers map[string]manager.Manager,
+) error {
+	for clusterName, clusterManager := range allManagers {
+		c, err := controller.New(ControllerName+"_"+clusterName, mgr, controller.Options{
+			// the objects of a namespace are only read once it has been
+			// terminating for long enough, they are not cached
+			Reconciler:              &reconciler{client: clusterManager.GetClient(), reader: clusterManager.GetAPIReader(), now: time.Now},
+			MaxConcurrentReconciles: 10,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to construct controller for cluster %s: %w", clusterName, err)
+		}
+		if err := c.Watch(source.Kind(clusterManager.GetCache(), &corev1.Namespace{}), &handler.EnqueueRequestForObject{}); err != nil {
+			return fmt.Errorf("failed to watch namespaces in cluster %s: %w", clusterName, err)
+		}
+	}
+
+	return nil
+}
+
+type reconciler struct {
+	client ctrlruntimeclient.Client
+	reader ctrlruntimeclient.Reader
+	now    func() time.Time
+}
+
+// gracePeriod is the time a namespace has to be terminating before the
+// finalizers are removed.  It is longer than the timeout of any job, so the
+// post phase of the tests which ran in the namespace has ended.
+const gracePeriod = 8 * time.Hour
+
+func (r *reconciler) Reconcile(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+	var ns corev1.Namespace
+	if err := r.client.Get(ctx, req.NamespacedName, &ns); err != nil {
+		if apierrors.IsNotFound(err) {
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, fmt.Errorf("failed to get %s: %w", req, err)
+	}
+	if ns.DeletionTimestamp == nil {
+		return reconcile.Result{}, nil
+	}
+
+	if age := r.now().Sub(ns.DeletionTimestamp.Time); age < gracePeriod {
+		return reconcile.Result{RequeueAfter: gracePeriod - age}, nil
+	}
+
+	var errs []error
+	for _, list := range []ctrlruntimeclient.ObjectList{&corev1.SecretList{}, &corev1.ServiceAccountList{}, &rbacv1.RoleList{}, &rbacv1.RoleBindingList{}} {
+		if err := r.reader.List(ctx, list, ctrlruntimeclient.InNamespace(ns.Name)); err != nil {
+			errs = append(errs, fmt.Errorf("failed to list %T in namespace %s: %w", list, ns.Name, err))
+			continue
+		}
+		items, err := meta.ExtractList(list)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to extract %T: %w", list, err))
+			continue
+		}
+		for _, item := range items {
+			obj := item.(ctrlruntimeclient.Object)
+			if err := r.release(ctx, obj); err != nil && !apierrors.IsNotFound(err) {
+				errs = append(errs, fmt.Errorf("failed to release %T %s/%s: %w", obj, obj.GetNamespace(), obj.GetName(), err))
+			}
+		}
+	}
+	return reconcile.Result{}, utilerrors.NewAggregate(errs)
+}
+
+// release removes the post-phase finalizers of all tests from an object.
+func (r *reconciler) release(ctx context.Context, obj ctrlruntimeclient.Object) error {
+	var kept []string
+	for _, finalizer := range obj.GetFinalizers() {
+		if !strings.HasSuffix(finalizer, api.PostPhaseFinalizerSuffix) {
+			kept = append(kept, finalizer)
+		}
+	}
+	if len(kept) == len(obj.GetFinalizers()) {
+		return nil
+	}
+	obj.SetFinalizers(kept)
+	return r.client.Update(ctx, obj)
+}
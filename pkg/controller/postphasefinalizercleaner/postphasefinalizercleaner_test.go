@@ -0,0 +1,117 @@
+package postphasefinalizercleaner
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	fakectrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestReconcile(t *testing.T) {
+	t.Parallel()
+
+	now := time.Time{}.Add(2 * gracePeriod)
+	namespace := func(deleted *time.Time) *corev1.Namespace {
+		ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "namespace"}}
+		if deleted != nil {
+			ns.DeletionTimestamp = &metav1.Time{Time: *deleted}
+			ns.Finalizers = []string{"kubernetes"}
+		}
+		return ns
+	}
+	deletedRecently, deletedLongAgo := now.Add(-time.Hour), now.Add(-gracePeriod)
+	objects := func() []ctrlruntimeclient.Object {
+		return []ctrlruntimeclient.Object{
+			&corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+				Namespace:  "namespace",
+				Name:       "e2e",
+				Finalizers: []string{"e2e.tests.ci.openshift.io/post-phase", "example.com/other"},
+			}},
+			&rbacv1.Role{ObjectMeta: metav1.ObjectMeta{
+				Namespace:  "namespace",
+				Name:       "e2e",
+				Finalizers: []string{"e2e.tests.ci.openshift.io/post-phase", "other.tests.ci.openshift.io/post-phase"},
+			}},
+		}
+	}
+	testCases := []struct {
+		name      string
+		namespace *corev1.Namespace
+
+		expectReconcileResult reconcile.Result
+		expectSecret          []string
+		expectRole            []string
+	}{
+		{
+			name: "Not found is swallowed",
+		},
+		{
+			name:         "Finalizers are kept in a namespace which is not deleted",
+			namespace:    namespace(nil),
+			expectSecret: []string{"e2e.tests.ci.openshift.io/post-phase", "example.com/other"},
+			expectRole:   []string{"e2e.tests.ci.openshift.io/post-phase", "other.tests.ci.openshift.io/post-phase"},
+		},
+		{
+			name:                  "ReconcileAfter is returned for a namespace deleted within the grace period",
+			namespace:             namespace(&deletedRecently),
+			expectReconcileResult: reconcile.Result{RequeueAfter: gracePeriod - time.Hour},
+			expectSecret:          []string{"e2e.tests.ci.openshift.io/post-phase", "example.com/other"},
+			expectRole:            []string{"e2e.tests.ci.openshift.io/post-phase", "other.tests.ci.openshift.io/post-phase"},
+		},
+		{
+			name:         "Post-phase finalizers are removed once the grace period has passed",
+			namespace:    namespace(&deletedLongAgo),
+			expectSecret: []string{"example.com/other"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			builder := fakectrlruntimeclient.NewClientBuilder().WithObjects(objects()...)
+			if tc.namespace != nil {
+				builder = builder.WithObjects(tc.namespace)
+			}
+			client := builder.Build()
+			r := &reconciler{
+				client: client,
+				reader: client,
+				now:    func() time.Time { return now },
+			}
+
+			result, err := r.Reconcile(context.Background(), reconcile.Request{NamespacedName: types.NamespacedName{Name: "namespace"}})
+			if err != nil {
+				t.Fatalf("reconcile failed: %v", err)
+			}
+			if diff := cmp.Diff(result, tc.expectReconcileResult); diff != "" {
+				t.Errorf("reconcile result differs from expected: %s", diff)
+			}
+			if tc.namespace == nil {
+				return
+			}
+
+			var secret corev1.Secret
+			if err := client.Get(context.Background(), types.NamespacedName{Namespace: "namespace", Name: "e2e"}, &secret); err != nil {
+				t.Fatalf("failed to get secret: %v", err)
+			}
+			if diff := cmp.Diff(secret.Finalizers, tc.expectSecret); diff != "" {
+				t.Errorf("secret finalizers differ from expected: %s", diff)
+			}
+			var role rbacv1.Role
+			if err := client.Get(context.Background(), types.NamespacedName{Namespace: "namespace", Name: "e2e"}, &role); err != nil {
+				t.Fatalf("failed to get role: %v", err)
+			}
+			if diff := cmp.Diff(role.Finalizers, tc.expectRole); diff != "" {
+				t.Errorf("role finalizers differ from expected: %s", diff)
+			}
+		})
+	}
+}
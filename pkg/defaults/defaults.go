@@ -42,6 +42,7 @@ import (
 	"github.com/openshift/ci-tools/pkg/secrets"
 	"github.com/openshift/ci-tools/pkg/steps"
 	"github.com/openshift/ci-tools/pkg/steps/clusterinstall"
+	"github.com/openshift/ci-tools/pkg/steps/costattribution"
 	"github.com/openshift/ci-tools/pkg/steps/loggingclient"
 	"github.com/openshift/ci-tools/pkg/steps/multi_stage"
 	releasesteps "github.com/openshift/ci-tools/pkg/steps/release"
@@ -78,12 +79,17 @@ func FromConfig(
 	targetAdditionalSuffix string,
 	manifestToolDockerCfg string,
 	localRegistryDNS string,
+	costLabels costattribution.Labels,
+	multiStageOptions multi_stage.Options,
 ) ([]api.Step, []api.Step, error) {
 	crclient, err := ctrlruntimeclient.NewWithWatch(clusterConfig, ctrlruntimeclient.Options{})
 	crclient = secretrecordingclient.Wrap(crclient, censor)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to construct client: %w", err)
 	}
+	if len(costLabels) != 0 {
+		crclient = costattribution.Wrap(crclient, costLabels)
+	}
 	client := loggingclient.New(crclient)
 	buildGetter, err := buildclientset.NewForConfig(clusterConfig)
 	if err != nil {
@@ -114,7 +120,7 @@ func FromConfig(
 	httpClient := retryablehttp.NewClient()
 	httpClient.Logger = nil
 
-	return fromConfig(ctx, config, graphConf, jobSpec, templates, paramFile, promote, client, buildClient, templateClient, podClient, leaseClient, hiveClient, httpClient.StandardClient(), requiredTargets, cloneAuthConfig, pullSecret, pushSecret, api.NewDeferredParameters(nil), censor, consoleHost, nodeName, targetAdditionalSuffix, nodeArchitectures)
+	return fromConfig(ctx, config, graphConf, jobSpec, templates, paramFile, promote, client, buildClient, templateClient, podClient, leaseClient, hiveClient, httpClient.StandardClient(), requiredTargets, cloneAuthConfig, pullSecret, pushSecret, api.NewDeferredParameters(nil), censor, consoleHost, nodeName, targetAdditionalSuffix, nodeArchitectures, multiStageOptions)
 }
 
 func fromConfig(
@@ -141,6 +147,7 @@ func fromConfig(
 	nodeName string,
 	targetAdditionalSuffix string,
 	nodeArchitectures []string,
+	multiStageOptions multi_stage.Options,
 ) ([]api.Step, []api.Step, error) {
 	requiredNames := sets.New[string]()
 	for _, target := range requiredTargets {
@@ -165,7 +172,7 @@ func fromConfig(
 
 	for _, rawStep := range rawSteps {
 		if testStep := rawStep.TestStepConfiguration; testStep != nil {
-			steps, err := stepForTest(config, params, podClient, leaseClient, templateClient, client, hiveClient, jobSpec, inputImages, testStep, &imageConfigs, pullSecret, censor, nodeName, targetAdditionalSuffix)
+			steps, err := stepForTest(config, params, podClient, leaseClient, templateClient, client, hiveClient, jobSpec, inputImages, testStep, &imageConfigs, pullSecret, censor, nodeName, targetAdditionalSuffix, multiStageOptions)
 			if err != nil {
 				return nil, nil, err
 			}
@@ -334,6 +341,8 @@ func fromConfig(
 		addProvidesForStep(step, params)
 	}
 
+	buildSteps = append(buildSteps, aggregateTestSteps(config, buildSteps, requiredNames, targetAdditionalSuffix)...)
+
 	if len(paramFile) > 0 {
 		step := steps.WriteParametersStep(params, paramFile)
 		buildSteps = append(buildSteps, step)
@@ -369,6 +378,43 @@ func fromConfig(
 	return append(overridableSteps, buildSteps...), postSteps, nil
 }
 
+// aggregateTestSteps creates the steps of the targeted aggregate tests.  The
+// steps of the tests they aggregate are replaced in place with steps which
+// record their results for the aggregate tests.
+func aggregateTestSteps(config *api.ReleaseBuildConfiguration, buildSteps []api.Step, requiredNames sets.Set[string], targetAdditionalSuffix string) []api.Step {
+	indices := map[string]int{}
+	for i, step := range buildSteps {
+		indices[step.Name()] = i
+	}
+	var ret []api.Step
+	for _, test := range config.Tests {
+		c := test.AggregateTestConfiguration
+		if c == nil || !requiredNames.Has(test.As) {
+			continue
+		}
+		var members []api.Step
+		var positions []int
+		for _, name := range c.Tests {
+			i, ok := indices[name]
+			if !ok && targetAdditionalSuffix != "" {
+				i, ok = indices[fmt.Sprintf("%s-%s", name, targetAdditionalSuffix)]
+			}
+			if !ok {
+				logrus.Warnf("Test %s aggregated by test %s has no step, it is not run.", name, test.As)
+				continue
+			}
+			members = append(members, buildSteps[i])
+			positions = append(positions, i)
+		}
+		step, wrapped := steps.AggregateTestStep(test.As, c.Minimum(), members)
+		for j, i := range positions {
+			buildSteps[i] = wrapped[j]
+		}
+		ret = append(ret, step)
+	}
+	return ret
+}
+
 // registryDomain determines the domain of the registry we promote to
 func registryDomain(configuration *api.PromotionConfiguration) string {
 	registry := api.DomainForService(api.ServiceRegistry)
@@ -398,6 +444,7 @@ func stepForTest(
 	censor *secrets.DynamicCensor,
 	nodeName string,
 	targetAdditionalSuffix string,
+	multiStageOptions multi_stage.Options,
 ) ([]api.Step, error) {
 	if test := c.MultiStageTestConfigurationLiteral; test != nil {
 		leases := api.LeasesForTest(test)
@@ -405,9 +452,9 @@ func stepForTest(
 			params = api.NewDeferredParameters(params)
 		}
 		var ret []api.Step
-		step := multi_stage.MultiStageTestStep(*c, config, params, podClient, jobSpec, leases, nodeName, targetAdditionalSuffix)
+		step := multi_stage.MultiStageTestStep(*c, config, params, podClient, jobSpec, leases, nodeName, targetAdditionalSuffix, censor, multiStageOptions)
 		if len(leases) != 0 {
-			step = steps.LeaseStep(leaseClient, leases, step, jobSpec.Namespace)
+			step = steps.SharedLeaseStep(leaseClient, multiStageOptions.SharedLeases, leases, step, jobSpec.Namespace)
 		}
 		if c.ClusterClaim != nil {
 			step = steps.ClusterClaimStep(c.As, c.ClusterClaim, hiveClient, client, jobSpec, step, censor)
@@ -453,7 +500,7 @@ func stepsForStepImages(
 	test *api.MultiStageTestConfigurationLiteral,
 	imageConfigs *[]*api.InputImageTagStepConfiguration,
 ) (ret []api.Step) {
-	for _, subStep := range append(append(test.Pre, test.Test...), test.Post...) {
+	for _, subStep := range append(append(append(test.Pre, test.Test...), test.Post...), test.Rollbacks...) {
 		if link, ok := subStep.FromImageTag(); ok {
 			source := api.ImageStreamSource{SourceType: api.ImageStreamSourceTest, Name: subStep.As}
 
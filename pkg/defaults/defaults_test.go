@@ -32,6 +32,7 @@ import (
 	"github.com/openshift/ci-tools/pkg/secrets"
 	"github.com/openshift/ci-tools/pkg/steps"
 	"github.com/openshift/ci-tools/pkg/steps/loggingclient"
+	"github.com/openshift/ci-tools/pkg/steps/multi_stage"
 	"github.com/openshift/ci-tools/pkg/steps/utils"
 	"github.com/openshift/ci-tools/pkg/testhelper"
 )
@@ -1209,7 +1210,6 @@ func TestFromConfig(t *testing.T) {
 	hiveClient := fakectrlruntimeclient.NewClientBuilder().WithScheme(scheme).WithObjects(&clusterPool, &imageset).Build()
 
 	var leaseClient *lease.Client
-	var requiredTargets []string
 	var cloneAuthConfig *steps.CloneAuthConfig
 	pullSecret, pushSecret := &coreapi.Secret{}, &coreapi.Secret{}
 	for _, tc := range []struct {
@@ -1221,6 +1221,7 @@ func TestFromConfig(t *testing.T) {
 		templates      []*templateapi.Template
 		env            api.Parameters
 		params         map[string]string
+		targets        []string
 		expectedSteps  []string
 		expectedPost   []string
 		expectedParams map[string]string
@@ -1430,9 +1431,11 @@ func TestFromConfig(t *testing.T) {
 			"[images]",
 		},
 		expectedParams: map[string]string{
-			"IMAGE_FORMAT":          "public_docker_image_repository/ns/stable:${component}",
-			"RELEASE_IMAGE_INITIAL": "public_docker_image_repository:initial",
-			"RELEASE_IMAGE_LATEST":  "public_docker_image_repository:latest",
+			"IMAGE_FORMAT":               "public_docker_image_repository/ns/stable:${component}",
+			"RELEASE_IMAGE_INITIAL":      "public_docker_image_repository:initial",
+			"RELEASE_IMAGE_LATEST":       "public_docker_image_repository:latest",
+			"RELEASE_PROVENANCE_INITIAL": "",
+			"RELEASE_PROVENANCE_LATEST":  "",
 		},
 	}, {
 		name: "tag specification with input",
@@ -1456,9 +1459,11 @@ func TestFromConfig(t *testing.T) {
 			"[images]",
 		},
 		expectedParams: map[string]string{
-			"IMAGE_FORMAT":          "public_docker_image_repository/ns/stable:${component}",
-			"RELEASE_IMAGE_INITIAL": "public_docker_image_repository:initial",
-			"RELEASE_IMAGE_LATEST":  "public_docker_image_repository:latest",
+			"IMAGE_FORMAT":               "public_docker_image_repository/ns/stable:${component}",
+			"RELEASE_IMAGE_INITIAL":      "public_docker_image_repository:initial",
+			"RELEASE_IMAGE_LATEST":       "public_docker_image_repository:latest",
+			"RELEASE_PROVENANCE_INITIAL": "",
+			"RELEASE_PROVENANCE_LATEST":  "",
 		},
 	}, {
 		name: "resolve release",
@@ -1471,7 +1476,8 @@ func TestFromConfig(t *testing.T) {
 		},
 		expectedSteps: []string{"[release:release]", "[images]"},
 		expectedParams: map[string]string{
-			utils.ReleaseImageEnv("release"): "public_docker_image_repository:release",
+			utils.ReleaseImageEnv("release"):      "public_docker_image_repository:release",
+			utils.ReleaseProvenanceEnv("release"): "",
 		},
 	}, {
 		name: "resolve release with input",
@@ -1489,7 +1495,8 @@ func TestFromConfig(t *testing.T) {
 		},
 		expectedSteps: []string{"[release:release]", "[images]"},
 		expectedParams: map[string]string{
-			utils.ReleaseImageEnv("release"): "public_docker_image_repository:release",
+			utils.ReleaseImageEnv("release"):      "public_docker_image_repository:release",
+			utils.ReleaseProvenanceEnv("release"): "",
 		},
 	}, {
 		name: "container test",
@@ -1500,6 +1507,26 @@ func TestFromConfig(t *testing.T) {
 			}},
 		},
 		expectedSteps: []string{"test", "[output-images]", "[images]"},
+	}, {
+		name: "aggregate test",
+		config: api.ReleaseBuildConfiguration{
+			Tests: []api.TestStepConfiguration{
+				{As: "unit", ContainerTestConfiguration: &api.ContainerTestConfiguration{}},
+				{As: "e2e", MultiStageTestConfigurationLiteral: &api.MultiStageTestConfigurationLiteral{}},
+				{As: "gate", AggregateTestConfiguration: &api.AggregateTestConfiguration{Tests: []string{"unit", "e2e"}}},
+			},
+		},
+		targets:       []string{"gate"},
+		expectedSteps: []string{"unit", "e2e", "gate", "[output-images]", "[images]"},
+	}, {
+		name: "aggregate test not targeted",
+		config: api.ReleaseBuildConfiguration{
+			Tests: []api.TestStepConfiguration{
+				{As: "unit", ContainerTestConfiguration: &api.ContainerTestConfiguration{}},
+				{As: "gate", AggregateTestConfiguration: &api.AggregateTestConfiguration{Tests: []string{"unit"}}},
+			},
+		},
+		expectedSteps: []string{"unit", "[output-images]", "[images]"},
 	}, {
 		name: "openshift-installer test",
 		config: api.ReleaseBuildConfiguration{
@@ -1589,8 +1616,9 @@ func TestFromConfig(t *testing.T) {
 		params:        map[string]string{"CLUSTER_TYPE": "aws"},
 		expectedSteps: []string{"template", "[output-images]", "[images]"},
 		expectedParams: map[string]string{
-			"CLUSTER_TYPE":      "aws",
-			api.DefaultLeaseEnv: "",
+			"CLUSTER_TYPE":        "aws",
+			api.DefaultLeaseEnv:   "",
+			api.LeasedRegionParam: "",
 		},
 	}, {
 		name:       "param files",
@@ -1693,7 +1721,7 @@ func TestFromConfig(t *testing.T) {
 				params.Add(k, func() (string, error) { return v, nil })
 			}
 			graphConf := FromConfigStatic(&tc.config)
-			configSteps, post, err := fromConfig(context.Background(), &tc.config, &graphConf, &jobSpec, tc.templates, tc.paramFiles, tc.promote, client, buildClient, templateClient, podClient, leaseClient, hiveClient, httpClient, requiredTargets, cloneAuthConfig, pullSecret, pushSecret, params, &secrets.DynamicCensor{}, "", "", "", nil)
+			configSteps, post, err := fromConfig(context.Background(), &tc.config, &graphConf, &jobSpec, tc.templates, tc.paramFiles, tc.promote, client, buildClient, templateClient, podClient, leaseClient, hiveClient, httpClient, tc.targets, cloneAuthConfig, pullSecret, pushSecret, params, &secrets.DynamicCensor{}, "", "", "", nil, multi_stage.Options{})
 			if diff := cmp.Diff(tc.expectedErr, err); diff != "" {
 				t.Errorf("unexpected error: %v", diff)
 			}
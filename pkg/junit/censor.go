@@ -14,6 +14,10 @@ func CensorTestSuite(censor secretutil.Censorer, testSuite *TestSuite) {
 	}
 	for i := range testSuite.TestCases {
 		testSuite.TestCases[i].Name = censored(censor, testSuite.TestCases[i].Name)
+		for j := range testSuite.TestCases[i].Properties {
+			testSuite.TestCases[i].Properties[j].Name = censored(censor, testSuite.TestCases[i].Properties[j].Name)
+			testSuite.TestCases[i].Properties[j].Value = censored(censor, testSuite.TestCases[i].Properties[j].Value)
+		}
 		if testSuite.TestCases[i].SkipMessage != nil {
 			testSuite.TestCases[i].SkipMessage.Message = censored(censor, testSuite.TestCases[i].SkipMessage.Message)
 		}
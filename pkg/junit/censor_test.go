@@ -19,7 +19,10 @@ func TestCensorTestSuite(t *testing.T) {
 		},
 		TestCases: []*TestCase{
 			{
-				Name:        "somehow secret",
+				Name: "somehow secret",
+				Properties: []*TestSuiteProperty{
+					{Name: "secret property", Value: "secret property value"},
+				},
 				SkipMessage: &SkipMessage{Message: "skipped due to secret"},
 				FailureOutput: &FailureOutput{
 					Message: "failed due to secret",
@@ -68,6 +68,9 @@ type TestCase struct {
 	// Duration is the time taken in seconds to run the test
 	Duration float64 `xml:"time,attr"`
 
+	// Properties holds other properties of the test case as a mapping of name to value
+	Properties []*TestSuiteProperty `xml:"properties>property,omitempty"`
+
 	// SkipMessage holds the reason why the test was skipped
 	SkipMessage *SkipMessage `xml:"skipped"`
 
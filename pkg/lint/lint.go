@@ -0,0 +1,239 @@
+// Package lint checks the multi-stage tests of ci-operator configurations for
+// common problems which are not invalid but usually not intended.  Where
+// possible, problems carry a fix in the form of JSON patch (RFC 6902)
+// operations which can be applied to the configuration file.
+package lint
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/openshift/ci-tools/pkg/api"
+)
+
+const (
+	// RuleUnsetDefault reports step parameters which have neither a default
+	// nor a value in the environment of the test.
+	RuleUnsetDefault = "unset-default"
+	// RuleDeprecatedField reports fields which were superseded by others.
+	RuleDeprecatedField = "deprecated-field"
+	// RuleGatherBestEffort reports post steps gathering artifacts which can
+	// fail the test.
+	RuleGatherBestEffort = "gather-best-effort"
+	// RuleResources reports resource requests which are larger than what a
+	// step is reasonably expected to need.
+	RuleResources = "resources"
+)
+
+// gatherTimeout is the timeout suggested for gather steps, which are required
+// to have one when they are best-effort.
+const gatherTimeout = "10m0s"
+
+// maxRequests are the largest resource requests accepted for a step.
+var maxRequests = map[string]resource.Quantity{
+	"cpu":    resource.MustParse("4"),
+	"memory": resource.MustParse("16Gi"),
+}
+
+// Operation is a JSON patch operation.
+type Operation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// Problem is an issue found in a configuration.
+type Problem struct {
+	// Rule is the name of the check which found the problem.
+	Rule string `json:"rule"`
+	// Field is the path of the field with the problem.
+	Field string `json:"field"`
+	// Message describes the problem.
+	Message string `json:"message"`
+	// Fix are the operations which address the problem, if any.
+	Fix []Operation `json:"fix,omitempty"`
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("%s: %s (%s)", p.Field, p.Message, p.Rule)
+}
+
+// Fixes returns all operations which fix the problems, in order.
+func Fixes(problems []Problem) []Operation {
+	var ret []Operation
+	for _, p := range problems {
+		ret = append(ret, p.Fix...)
+	}
+	return ret
+}
+
+// location is the path of a field, both as displayed to users and as a JSON
+// pointer.
+type location struct {
+	field, pointer string
+}
+
+func (l location) addField(name string) location {
+	return location{field: l.field + "." + name, pointer: l.pointer + "/" + escape(name)}
+}
+
+func (l location) addIndex(i int) location {
+	return location{field: fmt.Sprintf("%s[%d]", l.field, i), pointer: fmt.Sprintf("%s/%d", l.pointer, i)}
+}
+
+// escape encodes a JSON pointer token.
+func escape(token string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(token)
+}
+
+// Lint checks the tests of a configuration.  Only steps which are defined
+// literally in the configuration are considered, since fixes can only be
+// applied to the configuration itself.
+func Lint(config *api.ReleaseBuildConfiguration) []Problem {
+	var ret []Problem
+	for i := range config.Tests {
+		test := &config.Tests[i]
+		l := location{field: "tests", pointer: "/tests"}.addIndex(i)
+		ret = append(ret, lintDeprecated(l, test)...)
+		if c := test.MultiStageTestConfiguration; c != nil {
+			l := l.addField("steps")
+			var pre, t, post []api.LiteralTestStep
+			for _, phase := range []struct {
+				steps []api.TestStep
+				dst   *[]api.LiteralTestStep
+			}{{c.Pre, &pre}, {c.Test, &t}, {c.Post, &post}} {
+				for _, s := range phase.steps {
+					if s.LiteralTestStep == nil {
+						// keep the indices of literal steps intact
+						*phase.dst = append(*phase.dst, api.LiteralTestStep{})
+						continue
+					}
+					*phase.dst = append(*phase.dst, *s.LiteralTestStep)
+				}
+			}
+			// a workflow may provide the parameters, which is verified by the
+			// resolver instead
+			ret = append(ret, lintSteps(l, c.Workflow == nil, c.Environment, c.AllowBestEffortPostSteps, pre, t, post)...)
+		}
+		if c := test.MultiStageTestConfigurationLiteral; c != nil {
+			ret = append(ret, lintSteps(l.addField("literal_steps"), true, c.Environment, c.AllowBestEffortPostSteps, c.Pre, c.Test, c.Post)...)
+		}
+	}
+	return ret
+}
+
+func lintDeprecated(l location, test *api.TestStepConfiguration) []Problem {
+	if test.Secret == nil {
+		return nil
+	}
+	p := Problem{
+		Rule:    RuleDeprecatedField,
+		Field:   l.addField("secret").field,
+		Message: "`secret` is superseded by `secrets`",
+	}
+	if test.Secrets == nil {
+		p.Fix = []Operation{
+			{Op: "remove", Path: l.addField("secret").pointer},
+			{Op: "add", Path: l.addField("secrets").pointer, Value: []*api.Secret{test.Secret}},
+		}
+	}
+	return []Problem{p}
+}
+
+func lintSteps(l location, checkEnv bool, env api.TestEnvironment, allowBestEffort *bool, pre, test, post []api.LiteralTestStep) []Problem {
+	var ret []Problem
+	unset := map[string]location{}
+	for _, phase := range []struct {
+		name  string
+		steps []api.LiteralTestStep
+	}{{"pre", pre}, {"test", test}, {"post", post}} {
+		for i, step := range phase.steps {
+			if step.As == "" {
+				continue
+			}
+			sl := l.addField(phase.name).addIndex(i)
+			if checkEnv {
+				for j, param := range step.Environment {
+					if _, ok := env[param.Name]; !ok && param.Default == nil {
+						if _, seen := unset[param.Name]; !seen {
+							unset[param.Name] = sl.addField("env").addIndex(j)
+						}
+					}
+				}
+			}
+			if phase.name == "post" && strings.Contains(step.As, "gather") && (step.BestEffort == nil || !*step.BestEffort) {
+				ret = append(ret, lintGather(sl, step))
+			}
+			ret = append(ret, lintResources(sl.addField("resources").addField("requests"), step.Resources.Requests)...)
+		}
+	}
+	for _, name := range sortedKeys(unset) {
+		var op Operation
+		if env == nil {
+			op = Operation{Op: "add", Path: l.addField("env").pointer, Value: map[string]string{name: ""}}
+			env = api.TestEnvironment{name: ""}
+		} else {
+			op = Operation{Op: "add", Path: l.addField("env").addField(name).pointer, Value: ""}
+		}
+		ret = append(ret, Problem{
+			Rule:    RuleUnsetDefault,
+			Field:   unset[name].field,
+			Message: fmt.Sprintf("parameter %s has no default and is not set in the environment of the test", name),
+			Fix:     []Operation{op},
+		})
+	}
+	if allowBestEffort == nil || !*allowBestEffort {
+		for i := range ret {
+			if ret[i].Rule == RuleGatherBestEffort {
+				ret[i].Fix = append(ret[i].Fix, Operation{Op: "add", Path: l.addField("allow_best_effort_post_steps").pointer, Value: true})
+				break
+			}
+		}
+	}
+	return ret
+}
+
+func lintGather(l location, step api.LiteralTestStep) Problem {
+	fix := []Operation{{Op: "add", Path: l.addField("best_effort").pointer, Value: true}}
+	if step.Timeout == nil {
+		fix = append(fix, Operation{Op: "add", Path: l.addField("timeout").pointer, Value: gatherTimeout})
+	}
+	return Problem{
+		Rule:    RuleGatherBestEffort,
+		Field:   l.field,
+		Message: fmt.Sprintf("step %s gathers artifacts but is not best-effort, so its failures fail the test", step.As),
+		Fix:     fix,
+	}
+}
+
+func lintResources(l location, requests api.ResourceList) []Problem {
+	var ret []Problem
+	for _, name := range sortedKeys(maxRequests) {
+		value, ok := requests[name]
+		if !ok {
+			continue
+		}
+		quantity, err := resource.ParseQuantity(value)
+		if limit := maxRequests[name]; err == nil && quantity.Cmp(limit) > 0 {
+			ret = append(ret, Problem{
+				Rule:    RuleResources,
+				Field:   l.addField(name).field,
+				Message: fmt.Sprintf("request of %s is larger than the maximum of %s", value, limit.String()),
+				Fix:     []Operation{{Op: "replace", Path: l.addField(name).pointer, Value: limit.String()}},
+			})
+		}
+	}
+	return ret
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	ret := make([]string, 0, len(m))
+	for k := range m {
+		ret = append(ret, k)
+	}
+	sort.Strings(ret)
+	return ret
+}
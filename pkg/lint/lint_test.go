@@ -0,0 +1,135 @@
+package lint
+
+import (
+	"testing"
+
+	prowv1 "k8s.io/test-infra/prow/apis/prowjobs/v1"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/testhelper"
+)
+
+func TestLint(t *testing.T) {
+	yes, value := true, "value"
+	ref := "ipi-install"
+	workflow := "ipi-aws"
+	for _, tc := range []struct {
+		name     string
+		tests    []api.TestStepConfiguration
+		expected []Problem
+	}{{
+		name: "no problems",
+		tests: []api.TestStepConfiguration{{
+			As: "e2e",
+			MultiStageTestConfigurationLiteral: &api.MultiStageTestConfigurationLiteral{
+				Environment:              api.TestEnvironment{"PARAM": "value"},
+				AllowBestEffortPostSteps: &yes,
+				Test: []api.LiteralTestStep{{
+					As:          "e2e",
+					Environment: []api.StepParameter{{Name: "PARAM"}, {Name: "DEFAULT", Default: &value}},
+					Resources:   api.ResourceRequirements{Requests: api.ResourceList{"cpu": "100m", "memory": "1Gi"}},
+				}},
+				Post: []api.LiteralTestStep{{As: "gather-must-gather", BestEffort: &yes, Timeout: &prowv1.Duration{}}},
+			},
+		}},
+	}, {
+		name: "deprecated secret",
+		tests: []api.TestStepConfiguration{{
+			As:     "unit",
+			Secret: &api.Secret{Name: "secret", MountPath: "/secret"},
+		}},
+		expected: []Problem{{
+			Rule:    RuleDeprecatedField,
+			Field:   "tests[0].secret",
+			Message: "`secret` is superseded by `secrets`",
+			Fix: []Operation{
+				{Op: "remove", Path: "/tests/0/secret"},
+				{Op: "add", Path: "/tests/0/secrets", Value: []*api.Secret{{Name: "secret", MountPath: "/secret"}}},
+			},
+		}},
+	}, {
+		name: "unset parameters",
+		tests: []api.TestStepConfiguration{{
+			As: "e2e",
+			MultiStageTestConfiguration: &api.MultiStageTestConfiguration{
+				Pre: []api.TestStep{{Reference: &ref}},
+				Test: []api.TestStep{{LiteralTestStep: &api.LiteralTestStep{
+					As:          "e2e",
+					Environment: []api.StepParameter{{Name: "B"}, {Name: "A"}},
+				}}},
+			},
+		}},
+		expected: []Problem{{
+			Rule:    RuleUnsetDefault,
+			Field:   "tests[0].steps.test[0].env[1]",
+			Message: "parameter A has no default and is not set in the environment of the test",
+			Fix:     []Operation{{Op: "add", Path: "/tests/0/steps/env", Value: map[string]string{"A": ""}}},
+		}, {
+			Rule:    RuleUnsetDefault,
+			Field:   "tests[0].steps.test[0].env[0]",
+			Message: "parameter B has no default and is not set in the environment of the test",
+			Fix:     []Operation{{Op: "add", Path: "/tests/0/steps/env/B", Value: ""}},
+		}},
+	}, {
+		name: "parameters may be provided by the workflow",
+		tests: []api.TestStepConfiguration{{
+			As: "e2e",
+			MultiStageTestConfiguration: &api.MultiStageTestConfiguration{
+				Workflow: &workflow,
+				Test: []api.TestStep{{LiteralTestStep: &api.LiteralTestStep{
+					As:          "e2e",
+					Environment: []api.StepParameter{{Name: "A"}},
+				}}},
+			},
+		}},
+	}, {
+		name: "gather steps which are not best-effort",
+		tests: []api.TestStepConfiguration{{
+			As: "e2e",
+			MultiStageTestConfigurationLiteral: &api.MultiStageTestConfigurationLiteral{
+				Post: []api.LiteralTestStep{
+					{As: "gather-extra"},
+					{As: "gather-audit-logs", Timeout: &prowv1.Duration{}},
+					{As: "deprovision"},
+				},
+			},
+		}},
+		expected: []Problem{{
+			Rule:    RuleGatherBestEffort,
+			Field:   "tests[0].literal_steps.post[0]",
+			Message: "step gather-extra gathers artifacts but is not best-effort, so its failures fail the test",
+			Fix: []Operation{
+				{Op: "add", Path: "/tests/0/literal_steps/post/0/best_effort", Value: true},
+				{Op: "add", Path: "/tests/0/literal_steps/post/0/timeout", Value: "10m0s"},
+				{Op: "add", Path: "/tests/0/literal_steps/allow_best_effort_post_steps", Value: true},
+			},
+		}, {
+			Rule:    RuleGatherBestEffort,
+			Field:   "tests[0].literal_steps.post[1]",
+			Message: "step gather-audit-logs gathers artifacts but is not best-effort, so its failures fail the test",
+			Fix:     []Operation{{Op: "add", Path: "/tests/0/literal_steps/post/1/best_effort", Value: true}},
+		}},
+	}, {
+		name: "large resource requests",
+		tests: []api.TestStepConfiguration{{
+			As: "e2e",
+			MultiStageTestConfigurationLiteral: &api.MultiStageTestConfigurationLiteral{
+				Test: []api.LiteralTestStep{{
+					As:        "e2e",
+					Resources: api.ResourceRequirements{Requests: api.ResourceList{"cpu": "16", "memory": "16Gi"}},
+				}},
+			},
+		}},
+		expected: []Problem{{
+			Rule:    RuleResources,
+			Field:   "tests[0].literal_steps.test[0].resources.requests.cpu",
+			Message: "request of 16 is larger than the maximum of 4",
+			Fix:     []Operation{{Op: "replace", Path: "/tests/0/literal_steps/test/0/resources/requests/cpu", Value: "4"}},
+		}},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			actual := Lint(&api.ReleaseBuildConfiguration{Tests: tc.tests})
+			testhelper.Diff(t, "problems", actual, tc.expected)
+		})
+	}
+}
@@ -36,6 +36,7 @@ type registryAgent struct {
 	workflows     registry.WorkflowByName
 	documentation map[string]string
 	metadata      api.RegistryMetadata
+	approvalsPath string
 }
 
 var registryReloadTimeMetric = prometheus.NewHistogram(
@@ -58,6 +59,10 @@ type RegistryAgentOptions struct {
 	// from the filepath. Defaults to true.
 	FlatRegistry            *bool
 	UniversalSymlinkWatcher *UniversalSymlinkWatcher
+	// PrivilegedApprovalsPath is the allowlist of the steps which may use
+	// privileged capabilities.  It is read each time the registry is
+	// reloaded.  Approvals are not enforced if empty.
+	PrivilegedApprovalsPath string
 }
 
 type RegistryAgentOption func(*RegistryAgentOptions)
@@ -74,6 +79,12 @@ func WithRegistryFlat(v bool) RegistryAgentOption {
 	}
 }
 
+func WithPrivilegedApprovals(path string) RegistryAgentOption {
+	return func(o *RegistryAgentOptions) {
+		o.PrivilegedApprovalsPath = path
+	}
+}
+
 // NewRegistryAgent returns a RegistryAgent interface that automatically reloads when
 // the registry is changed on disk.
 func NewRegistryAgent(registryPath string, errCh chan error, opts ...RegistryAgentOption) (RegistryAgent, error) {
@@ -92,10 +103,11 @@ func NewRegistryAgent(registryPath string, errCh chan error, opts ...RegistryAge
 		flags |= load.RegistryFlat
 	}
 	a := &registryAgent{
-		registryPath: registryPath,
-		lock:         &sync.RWMutex{},
-		errorMetrics: opt.ErrorMetric,
-		flags:        flags,
+		registryPath:  registryPath,
+		lock:          &sync.RWMutex{},
+		errorMetrics:  opt.ErrorMetric,
+		flags:         flags,
+		approvalsPath: opt.PrivilegedApprovalsPath,
 	}
 	// Load config once so we fail early if that doesn't work and are ready as soon as we return
 	if err := a.loadRegistry(); err != nil {
@@ -149,12 +161,21 @@ func (a *registryAgent) loadRegistry() error {
 			recordErrorForMetric(a.errorMetrics, "failed to load ci-operator registry")
 			return time.Duration(0), fmt.Errorf("failed to load ci-operator registry (%w)", err)
 		}
+		var opts []registry.ResolverOption
+		if a.approvalsPath != "" {
+			approvals, err := registry.LoadPrivilegedApprovals(a.approvalsPath)
+			if err != nil {
+				recordErrorForMetric(a.errorMetrics, "failed to load privileged approvals")
+				return time.Duration(0), err
+			}
+			opts = append(opts, registry.WithPrivilegedApprovals(approvals))
+		}
 		a.references = references
 		a.chains = chains
 		a.workflows = workflows
 		a.documentation = documentation
 		a.metadata = metadata
-		a.resolver = registry.NewResolver(references, chains, workflows, observers)
+		a.resolver = registry.NewResolver(references, chains, workflows, observers, opts...)
 		a.generation++
 		return time.Since(startTime), nil
 	}()
@@ -0,0 +1,110 @@
+package load
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/util/gzip"
+)
+
+const (
+	// BundleConfigFile is the configuration in a bundle.
+	BundleConfigFile = "config.yaml"
+	// BundleRegistryDir is the step registry in a bundle, with which the
+	// configuration is resolved if it is present.
+	BundleRegistryDir = "registry"
+)
+
+// Bundle holds the paths of the contents of an extracted bundle, a tarball
+// with everything ci-operator needs to execute a configuration without the
+// configuration resolver.  Other files of the bundle are available next to the
+// configuration, e.g. the files parameters are loaded from.
+type Bundle struct {
+	// ConfigPath is the path of the configuration.
+	ConfigPath string
+	// RegistryPath is the path of the step registry, empty if the
+	// configuration is already resolved.
+	RegistryPath string
+}
+
+// ExtractBundle extracts a bundle, optionally compressed with gzip, into a
+// directory.
+func ExtractBundle(bundlePath, dir string) (*Bundle, error) {
+	raw, err := gzip.ReadFileMaybeGZIP(bundlePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bundle: %w", err)
+	}
+	reader := tar.NewReader(bytes.NewReader(raw))
+	for {
+		header, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read bundle: %w", err)
+		}
+		name := path.Clean(header.Name)
+		if path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+			return nil, fmt.Errorf("invalid path in bundle: %s", header.Name)
+		}
+		target := filepath.Join(dir, filepath.FromSlash(name))
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return nil, err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return nil, err
+			}
+			data, err := io.ReadAll(reader)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read %s from bundle: %w", header.Name, err)
+			}
+			if err := os.WriteFile(target, data, 0644); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("unsupported file type in bundle: %s", header.Name)
+		}
+	}
+	ret := &Bundle{ConfigPath: filepath.Join(dir, BundleConfigFile)}
+	if _, err := os.Stat(ret.ConfigPath); err != nil {
+		return nil, fmt.Errorf("bundle does not contain %s", BundleConfigFile)
+	}
+	if info, err := os.Stat(filepath.Join(dir, BundleRegistryDir)); err == nil && info.IsDir() {
+		ret.RegistryPath = filepath.Join(dir, BundleRegistryDir)
+	}
+	return ret, nil
+}
+
+// WriteBundle returns a bundle holding a resolved configuration, with which
+// the execution of a job can be replayed.
+func WriteBundle(config *api.ReleaseBuildConfiguration) ([]byte, error) {
+	raw, err := yaml.Marshal(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal configuration: %w", err)
+	}
+	var buf bytes.Buffer
+	writer := tar.NewWriter(&buf)
+	if err := writer.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: BundleConfigFile, Mode: 0644, Size: int64(len(raw))}); err != nil {
+		return nil, err
+	}
+	if _, err := writer.Write(raw); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,125 @@
+package load
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"sigs.k8s.io/yaml"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/testhelper"
+)
+
+func TestBundle(t *testing.T) {
+	config := &api.ReleaseBuildConfiguration{
+		Metadata: api.Metadata{Org: "org", Repo: "repo", Branch: "main"},
+		Tests:    []api.TestStepConfiguration{{As: "unit", ContainerTestConfiguration: &api.ContainerTestConfiguration{From: "src"}, Commands: "make test"}},
+	}
+	raw, err := WriteBundle(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "bundle.tar")
+	if err := os.WriteFile(path, raw, 0644); err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	bundle, err := ExtractBundle(path, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testhelper.Diff(t, "bundle", bundle, &Bundle{ConfigPath: filepath.Join(dir, BundleConfigFile)})
+	data, err := os.ReadFile(bundle.ConfigPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var actual api.ReleaseBuildConfiguration
+	if err := yaml.UnmarshalStrict(data, &actual); err != nil {
+		t.Fatal(err)
+	}
+	testhelper.Diff(t, "configuration", &actual, config)
+}
+
+func TestExtractBundle(t *testing.T) {
+	type file struct {
+		name     string
+		typeflag byte
+		content  string
+		link     string
+	}
+	for _, tc := range []struct {
+		name     string
+		files    []file
+		compress bool
+		expected *Bundle
+		err      error
+	}{{
+		name: "configuration and registry",
+		files: []file{
+			{name: "config.yaml", content: "tests: []"},
+			{name: "registry/", typeflag: tar.TypeDir},
+			{name: "registry/ipi/ipi-workflow.yaml", content: "workflow: {}"},
+		},
+		compress: true,
+		expected: &Bundle{ConfigPath: "config.yaml", RegistryPath: "registry"},
+	}, {
+		name:  "no configuration",
+		files: []file{{name: "registry/ipi/ipi-workflow.yaml", content: "workflow: {}"}},
+		err:   errors.New("bundle does not contain config.yaml"),
+	}, {
+		name:  "path outside of the bundle",
+		files: []file{{name: "../config.yaml", content: "tests: []"}},
+		err:   errors.New("invalid path in bundle: ../config.yaml"),
+	}, {
+		name:  "link",
+		files: []file{{name: "config.yaml", typeflag: tar.TypeSymlink, link: "/etc/passwd"}},
+		err:   errors.New("unsupported file type in bundle: config.yaml"),
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			var gz *gzip.Writer
+			writer := tar.NewWriter(&buf)
+			if tc.compress {
+				gz = gzip.NewWriter(&buf)
+				writer = tar.NewWriter(gz)
+			}
+			for _, f := range tc.files {
+				typeflag := f.typeflag
+				if typeflag == 0 {
+					typeflag = tar.TypeReg
+				}
+				if err := writer.WriteHeader(&tar.Header{Typeflag: typeflag, Name: f.name, Mode: 0644, Size: int64(len(f.content)), Linkname: f.link}); err != nil {
+					t.Fatal(err)
+				}
+				if _, err := writer.Write([]byte(f.content)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := writer.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if gz != nil {
+				if err := gz.Close(); err != nil {
+					t.Fatal(err)
+				}
+			}
+			path := filepath.Join(t.TempDir(), "bundle.tar.gz")
+			if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+				t.Fatal(err)
+			}
+			dir := t.TempDir()
+			bundle, err := ExtractBundle(path, dir)
+			testhelper.Diff(t, "error", err, tc.err, testhelper.EquateErrorMessage)
+			if tc.expected != nil {
+				tc.expected.ConfigPath = filepath.Join(dir, tc.expected.ConfigPath)
+				tc.expected.RegistryPath = filepath.Join(dir, tc.expected.RegistryPath)
+			}
+			testhelper.Diff(t, "bundle", bundle, tc.expected)
+		})
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/sirupsen/logrus"
 
 	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/test-infra/prow/config"
 	"sigs.k8s.io/yaml"
 
@@ -24,12 +25,13 @@ import (
 type RegistryFlag uint8
 
 const (
-	RefSuffix      = "-ref.yaml"
-	ChainSuffix    = "-chain.yaml"
-	WorkflowSuffix = "-workflow.yaml"
-	ObserverSuffix = "-observer.yaml"
-	CommandsSuffix = "-commands" // excluding the file extension
-	MetadataSuffix = ".metadata.json"
+	RefSuffix       = "-ref.yaml"
+	ChainSuffix     = "-chain.yaml"
+	WorkflowSuffix  = "-workflow.yaml"
+	ObserverSuffix  = "-observer.yaml"
+	CommandsSuffix  = "-commands" // excluding the file extension
+	ManifestsSuffix = "-manifests.yaml"
+	MetadataSuffix  = ".metadata.json"
 )
 
 const (
@@ -169,7 +171,7 @@ func Registry(root string, flags RegistryFlag) (registry.ReferenceByName, regist
 			}
 			observer.Observer.Documentation = ""
 			observers[observer.Observer.Name] = observer.Observer.Observer
-		} else if strings.HasSuffix(path, fmt.Sprintf("%s%s", CommandsSuffix, filepath.Ext(path))) {
+		} else if strings.HasSuffix(path, fmt.Sprintf("%s%s", CommandsSuffix, filepath.Ext(path))) || strings.HasSuffix(path, ManifestsSuffix) {
 			// ignore
 		} else if filepath.Base(path) == config.ConfigVersionFileName {
 			if version, err := gzip.ReadFileMaybeGZIP(path); err == nil {
@@ -211,6 +213,20 @@ func loadReference(bytes []byte, baseDir, prefix string, flat bool) (string, str
 	if err != nil {
 		return "", "", api.LiteralTestStep{}, err
 	}
+	if step.Reference.Manifests != "" {
+		if !flat && step.Reference.Manifests != prefix+ManifestsSuffix {
+			return "", "", api.LiteralTestStep{}, fmt.Errorf("reference %s has invalid manifests file path; manifests should be set to %s", step.Reference.As, prefix+ManifestsSuffix)
+		}
+		manifests, err := gzip.ReadFileMaybeGZIP(filepath.Join(baseDir, step.Reference.Manifests))
+		if err != nil {
+			return "", "", api.LiteralTestStep{}, err
+		}
+		step.Reference.Manifests = string(manifests)
+	}
+	if step.Reference.Commands == "" && len(step.Reference.Command) != 0 {
+		// steps in exec form have no script
+		return step.Reference.As, step.Reference.Documentation, step.Reference.LiteralTestStep, nil
+	}
 	if !flat && step.Reference.Commands != fmt.Sprintf("%s%s%s", prefix, CommandsSuffix, filepath.Ext(step.Reference.Commands)) {
 		return "", "", api.LiteralTestStep{}, fmt.Errorf("reference %s has invalid command file path; command should be set to %s (with an optional extension like .sh)", step.Reference.As, fmt.Sprintf("%s%s", prefix, CommandsSuffix))
 	}
@@ -231,6 +247,9 @@ func loadWorkflow(bytes []byte) (string, string, api.MultiStageTestConfiguration
 	if workflow.Workflow.Steps.Workflow != nil {
 		return "", "", api.MultiStageTestConfiguration{}, errors.New("workflows cannot contain other workflows")
 	}
+	if len(workflow.Workflow.Steps.EnvironmentFromFile) != 0 {
+		return "", "", api.MultiStageTestConfiguration{}, errors.New("workflows cannot contain env_from_file")
+	}
 	return workflow.Workflow.As, workflow.Workflow.Documentation, workflow.Workflow.Steps, nil
 }
 
@@ -264,3 +283,45 @@ func ClusterProfilesConfig(configPath string) (api.ClusterProfilesList, error) {
 
 	return mergedList, nil
 }
+
+// EnvironmentFromFiles reads the files referenced by the `env_from_file`
+// fields of the multi-stage tests in a configuration, relative to the
+// directory which holds the configuration file, and moves their contents into
+// the `env` fields.  Configurations which are not read from a file, for which
+// the directory is empty, cannot reference files.
+func EnvironmentFromFiles(config *api.ReleaseBuildConfiguration, dir string) error {
+	var errs []error
+	for i := range config.Tests {
+		test := &config.Tests[i]
+		ms := test.MultiStageTestConfiguration
+		if ms == nil || len(ms.EnvironmentFromFile) == 0 {
+			continue
+		}
+		if dir == "" {
+			errs = append(errs, fmt.Errorf("test %s: env_from_file can only be used in configurations read from a file", test.As))
+			continue
+		}
+		if ms.Environment == nil {
+			ms.Environment = api.TestEnvironment{}
+		}
+		for _, name := range sets.List(sets.KeySet(ms.EnvironmentFromFile)) {
+			path := ms.EnvironmentFromFile[name]
+			if _, ok := ms.Environment[name]; ok {
+				errs = append(errs, fmt.Errorf("test %s: parameter %s is set in both env and env_from_file", test.As, name))
+				continue
+			}
+			if filepath.IsAbs(path) {
+				errs = append(errs, fmt.Errorf("test %s: path of parameter %s must be relative to the configuration file, got %s", test.As, name, path))
+				continue
+			}
+			raw, err := gzip.ReadFileMaybeGZIP(filepath.Join(dir, path))
+			if err != nil {
+				errs = append(errs, fmt.Errorf("test %s: failed to read parameter %s: %w", test.As, name, err))
+				continue
+			}
+			ms.Environment[name] = string(raw)
+		}
+		ms.EnvironmentFromFile = nil
+	}
+	return utilerrors.NewAggregate(errs)
+}
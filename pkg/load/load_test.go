@@ -4,9 +4,11 @@ import (
 	"os"
 	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/ghodss/yaml"
+	"github.com/google/go-cmp/cmp"
 
 	"k8s.io/apimachinery/pkg/util/diff"
 
@@ -344,3 +346,122 @@ func TestClusterProfilesConfig(t *testing.T) {
 		})
 	}
 }
+
+func TestEnvironmentFromFiles(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "patches"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "patches", "install-config.yaml"), []byte("networking:\n  networkType: OVNKubernetes\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	config := func(env, fromFile api.TestEnvironment) *api.ReleaseBuildConfiguration {
+		return &api.ReleaseBuildConfiguration{Tests: []api.TestStepConfiguration{{
+			As:                          "e2e",
+			MultiStageTestConfiguration: &api.MultiStageTestConfiguration{Environment: env, EnvironmentFromFile: fromFile},
+		}, {
+			As:       "unit",
+			Commands: "make test",
+		}}}
+	}
+	for _, tc := range []struct {
+		name        string
+		config      *api.ReleaseBuildConfiguration
+		dir         string
+		expected    *api.ReleaseBuildConfiguration
+		expectedErr string
+	}{{
+		name:     "no files",
+		config:   config(api.TestEnvironment{"A": "a"}, nil),
+		dir:      dir,
+		expected: config(api.TestEnvironment{"A": "a"}, nil),
+	}, {
+		name:   "files are read into the environment",
+		config: config(api.TestEnvironment{"A": "a"}, api.TestEnvironment{"INSTALL_CONFIG_PATCH": "patches/install-config.yaml"}),
+		dir:    dir,
+		expected: config(api.TestEnvironment{
+			"A":                    "a",
+			"INSTALL_CONFIG_PATCH": "networking:\n  networkType: OVNKubernetes\n",
+		}, nil),
+	}, {
+		name:        "configuration not read from a file",
+		config:      config(nil, api.TestEnvironment{"INSTALL_CONFIG_PATCH": "patches/install-config.yaml"}),
+		expectedErr: "test e2e: env_from_file can only be used in configurations read from a file",
+	}, {
+		name:        "parameter set in both fields",
+		config:      config(api.TestEnvironment{"INSTALL_CONFIG_PATCH": "a"}, api.TestEnvironment{"INSTALL_CONFIG_PATCH": "patches/install-config.yaml"}),
+		dir:         dir,
+		expectedErr: "test e2e: parameter INSTALL_CONFIG_PATCH is set in both env and env_from_file",
+	}, {
+		name:        "missing file",
+		config:      config(nil, api.TestEnvironment{"INSTALL_CONFIG_PATCH": "missing.yaml"}),
+		dir:         dir,
+		expectedErr: "test e2e: failed to read parameter INSTALL_CONFIG_PATCH: open " + filepath.Join(dir, "missing.yaml") + ": no such file or directory",
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := EnvironmentFromFiles(tc.config, tc.dir)
+			var actualErr string
+			if err != nil {
+				actualErr = err.Error()
+			}
+			if actualErr != tc.expectedErr {
+				t.Fatalf("expected error %q, got %q", tc.expectedErr, actualErr)
+			}
+			if tc.expected != nil && !reflect.DeepEqual(tc.config, tc.expected) {
+				t.Errorf("unexpected configuration: %s", diff.ObjectReflectDiff(tc.expected, tc.config))
+			}
+		})
+	}
+}
+
+func TestRegistryManifests(t *testing.T) {
+	const manifests = "apiVersion: v1\nkind: ServiceAccount\nmetadata:\n  name: e2e\n  namespace: e2e\n"
+	for _, tc := range []struct {
+		name      string
+		reference string
+		expected  string
+		err       string
+	}{{
+		name:      "manifests are read from the file",
+		reference: "ref:\n  as: rbac\n  from: cli\n  commands: rbac-commands.sh\n  manifests: rbac-manifests.yaml\n  resources:\n    requests:\n      cpu: 10m\n",
+		expected:  manifests,
+	}, {
+		name:      "manifests file with an invalid name",
+		reference: "ref:\n  as: rbac\n  from: cli\n  commands: rbac-commands.sh\n  manifests: manifests.yaml\n  resources:\n    requests:\n      cpu: 10m\n",
+		err:       "reference rbac has invalid manifests file path; manifests should be set to rbac-manifests.yaml",
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			root := t.TempDir()
+			dir := filepath.Join(root, "rbac")
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				t.Fatal(err)
+			}
+			for name, content := range map[string]string{
+				"rbac-ref.yaml":       tc.reference,
+				"rbac-commands.sh":    "oc whoami\n",
+				"rbac-manifests.yaml": manifests,
+			} {
+				if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			references, _, _, _, _, _, err := Registry(root, RegistryFlag(0))
+			var actualErr string
+			if err != nil {
+				actualErr = err.Error()
+			}
+			if tc.err != "" {
+				if !strings.Contains(actualErr, tc.err) {
+					t.Fatalf("expected error containing %q, got %q", tc.err, actualErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.expected, references["rbac"].Manifests); diff != "" {
+				t.Errorf("unexpected manifests: %s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package registry
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/yaml"
+
+	"github.com/openshift/ci-tools/pkg/api"
+)
+
+const (
+	// CapabilityHostNetwork is requested by steps with `host_network`.
+	CapabilityHostNetwork = "host_network"
+	// CapabilitySysctls is requested by steps with `sysctls`.
+	CapabilitySysctls = "sysctls"
+)
+
+var privilegedCapabilities = sets.New[string](CapabilityHostNetwork, CapabilitySysctls)
+
+// PrivilegedApprovals is the central allowlist of the steps which may use
+// privileged capabilities.  The file is owned by those who review the use of
+// such capabilities, so that they cannot be added in the configuration of a
+// repository without their approval.
+type PrivilegedApprovals struct {
+	Approvals []PrivilegedApproval `json:"approvals"`
+}
+
+// PrivilegedApproval allows steps to use privileged capabilities.  Steps
+// reference the approval by its name in `privileged_approval`.
+type PrivilegedApproval struct {
+	// Name identifies the approval.
+	Name string `json:"name"`
+	// Steps are the names of the steps which may reference the approval.
+	Steps []string `json:"steps"`
+	// Capabilities are the privileged capabilities the steps may use.
+	Capabilities []string `json:"capabilities"`
+	// Reason records why the capabilities were approved.
+	Reason string `json:"reason,omitempty"`
+}
+
+// LoadPrivilegedApprovals reads the allowlist of privileged capabilities.
+func LoadPrivilegedApprovals(path string) (*PrivilegedApprovals, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read privileged approvals: %w", err)
+	}
+	var ret PrivilegedApprovals
+	if err := yaml.UnmarshalStrict(raw, &ret); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal privileged approvals: %w", err)
+	}
+	if err := ret.validate(); err != nil {
+		return nil, fmt.Errorf("invalid privileged approvals: %w", err)
+	}
+	return &ret, nil
+}
+
+func (a *PrivilegedApprovals) validate() error {
+	names := sets.New[string]()
+	for i, approval := range a.Approvals {
+		if approval.Name == "" {
+			return fmt.Errorf("approvals[%d]: `name` is required", i)
+		}
+		if names.Has(approval.Name) {
+			return fmt.Errorf("approvals[%d]: duplicate name %q", i, approval.Name)
+		}
+		names.Insert(approval.Name)
+		if len(approval.Steps) == 0 {
+			return fmt.Errorf("approvals[%d]: `steps` is required", i)
+		}
+		for _, capability := range approval.Capabilities {
+			if !privilegedCapabilities.Has(capability) {
+				return fmt.Errorf("approvals[%d]: unknown capability %q, must be one of: %s", i, capability, strings.Join(sets.List(privilegedCapabilities), ", "))
+			}
+		}
+	}
+	return nil
+}
+
+// requestedCapabilities returns the privileged capabilities a step uses.
+func requestedCapabilities(step api.LiteralTestStep) []string {
+	var ret []string
+	if step.HostNetwork != nil && *step.HostNetwork {
+		ret = append(ret, CapabilityHostNetwork)
+	}
+	if len(step.Sysctls) != 0 {
+		ret = append(ret, CapabilitySysctls)
+	}
+	return ret
+}
+
+// check verifies that the privileged capabilities of a step are approved.
+func (a *PrivilegedApprovals) check(step api.LiteralTestStep) error {
+	requested := requestedCapabilities(step)
+	if len(requested) == 0 {
+		return nil
+	}
+	if step.PrivilegedApproval == "" {
+		return fmt.Errorf("step/%s: uses privileged capabilities (%s) without a `privileged_approval`", step.As, strings.Join(requested, ", "))
+	}
+	for _, approval := range a.Approvals {
+		if approval.Name != step.PrivilegedApproval {
+			continue
+		}
+		if !sets.New[string](approval.Steps...).Has(step.As) {
+			return fmt.Errorf("step/%s: privileged approval %q does not allow this step", step.As, approval.Name)
+		}
+		if missing := sets.List(sets.New[string](requested...).Difference(sets.New[string](approval.Capabilities...))); len(missing) != 0 {
+			return fmt.Errorf("step/%s: privileged approval %q does not allow capabilities: %s", step.As, approval.Name, strings.Join(missing, ", "))
+		}
+		return nil
+	}
+	return fmt.Errorf("step/%s: unknown privileged approval %q", step.As, step.PrivilegedApproval)
+}
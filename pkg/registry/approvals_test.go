@@ -0,0 +1,108 @@
+package registry
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/testhelper"
+)
+
+func TestLoadPrivilegedApprovals(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		raw      string
+		expected *PrivilegedApprovals
+		err      error
+	}{{
+		name: "valid approvals",
+		raw: `approvals:
+- name: ipi-conf-network
+  steps: [ipi-conf-network]
+  capabilities: [host_network, sysctls]
+  reason: configures the network of the nodes
+`,
+		expected: &PrivilegedApprovals{Approvals: []PrivilegedApproval{{
+			Name:         "ipi-conf-network",
+			Steps:        []string{"ipi-conf-network"},
+			Capabilities: []string{CapabilityHostNetwork, CapabilitySysctls},
+			Reason:       "configures the network of the nodes",
+		}}},
+	}, {
+		name: "duplicate name",
+		raw: `approvals:
+- name: network
+  steps: [a]
+- name: network
+  steps: [b]
+`,
+		err: errors.New(`invalid privileged approvals: approvals[1]: duplicate name "network"`),
+	}, {
+		name: "unknown capability",
+		raw: `approvals:
+- name: network
+  steps: [a]
+  capabilities: [privileged]
+`,
+		err: errors.New(`invalid privileged approvals: approvals[0]: unknown capability "privileged", must be one of: host_network, sysctls`),
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "approvals.yaml")
+			if err := os.WriteFile(path, []byte(tc.raw), 0644); err != nil {
+				t.Fatal(err)
+			}
+			approvals, err := LoadPrivilegedApprovals(path)
+			testhelper.Diff(t, "error", err, tc.err, testhelper.EquateErrorMessage)
+			testhelper.Diff(t, "approvals", approvals, tc.expected)
+		})
+	}
+}
+
+func TestResolvePrivilegedApprovals(t *testing.T) {
+	yes := true
+	approvals := &PrivilegedApprovals{Approvals: []PrivilegedApproval{{
+		Name:         "network",
+		Steps:        []string{"conf-network"},
+		Capabilities: []string{CapabilityHostNetwork},
+	}}}
+	sysctls := []api.StepSysctl{{Name: "kernel.shm_rmid_forced", Value: "1"}}
+	for _, tc := range []struct {
+		name string
+		step api.LiteralTestStep
+		err  error
+	}{{
+		name: "unprivileged step",
+		step: api.LiteralTestStep{As: "e2e"},
+	}, {
+		name: "approved step",
+		step: api.LiteralTestStep{As: "conf-network", HostNetwork: &yes, PrivilegedApproval: "network"},
+	}, {
+		name: "missing approval",
+		step: api.LiteralTestStep{As: "conf-network", HostNetwork: &yes},
+		err:  errors.New("test/test: step/conf-network: uses privileged capabilities (host_network) without a `privileged_approval`"),
+	}, {
+		name: "unknown approval",
+		step: api.LiteralTestStep{As: "conf-network", HostNetwork: &yes, PrivilegedApproval: "everything"},
+		err:  errors.New(`test/test: step/conf-network: unknown privileged approval "everything"`),
+	}, {
+		name: "approval of another step",
+		step: api.LiteralTestStep{As: "e2e", HostNetwork: &yes, PrivilegedApproval: "network"},
+		err:  errors.New(`test/test: step/e2e: privileged approval "network" does not allow this step`),
+	}, {
+		name: "capability which was not approved",
+		step: api.LiteralTestStep{As: "conf-network", HostNetwork: &yes, Sysctls: sysctls, PrivilegedApproval: "network"},
+		err:  errors.New(`test/test: step/conf-network: privileged approval "network" does not allow capabilities: sysctls`),
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			step := tc.step
+			test := api.MultiStageTestConfiguration{Test: []api.TestStep{{LiteralTestStep: &step}}}
+			_, err := NewResolver(nil, nil, nil, nil, WithPrivilegedApprovals(approvals)).Resolve("test", test)
+			testhelper.Diff(t, "error", err, tc.err, testhelper.EquateErrorMessage)
+			if _, err := NewResolver(nil, nil, nil, nil).Resolve("test", test); err != nil {
+				t.Errorf("expected approvals not to be enforced without an allowlist, got %v", err)
+			}
+		})
+	}
+}
@@ -2,6 +2,9 @@ package registry
 
 import (
 	"fmt"
+	"strings"
+
+	"github.com/sirupsen/logrus"
 
 	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/apimachinery/pkg/util/sets"
@@ -25,20 +28,24 @@ type ObserverByName map[string]api.Observer
 // A superset of this validation is performed later when actual test
 // configurations are resolved.
 func Validate(stepsByName ReferenceByName, chainsByName ChainByName, workflowsByName WorkflowByName, observersByName ObserverByName) error {
-	reg := registry{stepsByName, chainsByName, workflowsByName, observersByName}
+	reg := registry{stepsByName: stepsByName, chainsByName: chainsByName, workflowsByName: workflowsByName, observersByName: observersByName}
 	var ret []error
 	for k := range chainsByName {
-		if _, err := reg.process([]api.TestStep{{Chain: &k}}, sets.New[string](), stackForChain()); err != nil {
+		if _, err := reg.process([]api.TestStep{{Chain: &k}}, stepNames{}, stackForChain()); err != nil {
 			ret = append(ret, err...)
 		}
 	}
 	for k, v := range workflowsByName {
 		stack := stackForWorkflow(k, v.Environment, v.Dependencies)
+		var phases []stepNames
 		for _, s := range [][]api.TestStep{v.Pre, v.Test, v.Post} {
-			if _, err := reg.process(s, sets.New[string](), stack); err != nil {
+			seen := stepNames{}
+			if _, err := reg.process(s, seen, stack); err != nil {
 				ret = append(ret, err...)
 			}
+			phases = append(phases, seen)
 		}
+		warnCrossPhaseNames(&stack, phases...)
 		ret = append(ret, stack.checkUnused(&stack.records[0], nil, &reg)...)
 	}
 	for _, v := range observersByName {
@@ -55,18 +62,39 @@ type registry struct {
 	chainsByName    ChainByName
 	workflowsByName WorkflowByName
 	observersByName ObserverByName
+	// approvals are enforced for the privileged capabilities of steps, if
+	// set.
+	approvals *PrivilegedApprovals
 }
 
-func NewResolver(stepsByName ReferenceByName, chainsByName ChainByName, workflowsByName WorkflowByName, observersByName ObserverByName) Resolver {
-	return &registry{
+// ResolverOption configures a Resolver.
+type ResolverOption func(*registry)
+
+// WithPrivilegedApprovals makes the resolver reject steps which use
+// privileged capabilities without an approval in the allowlist.
+func WithPrivilegedApprovals(approvals *PrivilegedApprovals) ResolverOption {
+	return func(r *registry) {
+		r.approvals = approvals
+	}
+}
+
+func NewResolver(stepsByName ReferenceByName, chainsByName ChainByName, workflowsByName WorkflowByName, observersByName ObserverByName, opts ...ResolverOption) Resolver {
+	r := &registry{
 		stepsByName:     stepsByName,
 		chainsByName:    chainsByName,
 		workflowsByName: workflowsByName,
 		observersByName: observersByName,
 	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
 }
 
 func (r *registry) Resolve(name string, config api.MultiStageTestConfiguration) (api.MultiStageTestConfigurationLiteral, error) {
+	// the overrides inherited from the workflow may target steps which the
+	// test replaced, only those of the test itself are checked
+	overrides := config.DependencyOverrides
 	var overridden [][]api.TestStep
 	if config.Workflow != nil {
 		var errs []error
@@ -75,7 +103,34 @@ func (r *registry) Resolve(name string, config api.MultiStageTestConfiguration)
 			return api.MultiStageTestConfigurationLiteral{}, utilerrors.NewAggregate(errs)
 		}
 	}
-	return r.resolveTest(config, stackForTest(name, config.Environment, config.Dependencies), overridden)
+	ret, err := r.resolveTest(config, stackForTest(name, config.Environment, config.Dependencies), overridden)
+	if err != nil {
+		return ret, err
+	}
+	if errs := checkDependencyOverrides(overrides, ret.Pre, ret.Test, ret.Post); errs != nil {
+		return api.MultiStageTestConfigurationLiteral{}, utilerrors.NewAggregate(errs)
+	}
+	return ret, nil
+}
+
+// checkDependencyOverrides verifies that each dependency override targets
+// the environment variable of a dependency of a step, as overrides which do
+// not match any are silently ignored.
+func checkDependencyOverrides(overrides api.DependencyOverrides, phases ...[]api.LiteralTestStep) (errs []error) {
+	env := sets.New[string]()
+	for _, steps := range phases {
+		for _, step := range steps {
+			for _, dependency := range step.Dependencies {
+				env.Insert(dependency.Env)
+			}
+		}
+	}
+	for _, name := range sets.List(sets.KeySet(overrides)) {
+		if !env.Has(name) {
+			errs = append(errs, fmt.Errorf("dependency override %q does not match the environment variable of any step dependency", name))
+		}
+	}
+	return errs
 }
 
 func (r *registry) mergeWorkflow(config *api.MultiStageTestConfiguration) ([][]api.TestStep, []error) {
@@ -106,6 +161,7 @@ func (r *registry) mergeWorkflow(config *api.MultiStageTestConfiguration) ([][]a
 	config.Environment = mergeEnvironments(workflow.Environment, config.Environment)
 	config.Dependencies = mergeDependencies(workflow.Dependencies, config.Dependencies)
 	config.DependencyOverrides = mergeDependencyOverrides(workflow.DependencyOverrides, config.DependencyOverrides)
+	config.StepReleases = mergeStepReleases(workflow.StepReleases, config.StepReleases)
 	if l, err := mergeLeases(workflow.Leases, config.Leases); err != nil {
 		errs = append(errs, err)
 	} else {
@@ -117,6 +173,18 @@ func (r *registry) mergeWorkflow(config *api.MultiStageTestConfiguration) ([][]a
 	if config.AllowBestEffortPostSteps == nil {
 		config.AllowBestEffortPostSteps = workflow.AllowBestEffortPostSteps
 	}
+	if config.DisruptionMonitor == nil {
+		config.DisruptionMonitor = workflow.DisruptionMonitor
+	}
+	if config.ClusterHealthGate == nil {
+		config.ClusterHealthGate = workflow.ClusterHealthGate
+	}
+	if config.ContainerOnly == nil {
+		config.ContainerOnly = workflow.ContainerOnly
+	}
+	if config.FeatureSet == "" {
+		config.FeatureSet = workflow.FeatureSet
+	}
 	return overridden, errs
 }
 
@@ -132,21 +200,29 @@ func (r *registry) resolveTest(
 		AllowBestEffortPostSteps: config.AllowBestEffortPostSteps,
 		Leases:                   config.Leases,
 		DependencyOverrides:      config.DependencyOverrides,
+		StepReleases:             config.StepReleases,
+		DisruptionMonitor:        config.DisruptionMonitor,
+		ClusterHealthGate:        config.ClusterHealthGate,
+		ContainerOnly:            config.ContainerOnly,
+		FeatureSet:               config.FeatureSet,
+		ReuseClusterFrom:         config.ReuseClusterFrom,
 	}
 	if config.Workflow != nil {
 		stack.push(stackRecordForTest("workflow/"+*config.Workflow, nil, nil))
 	}
-	pre, errs := r.process(config.Pre, sets.New[string](), stack)
+	preNames, testNames, postNames := stepNames{}, stepNames{}, stepNames{}
+	pre, errs := r.process(config.Pre, preNames, stack)
 	expandedFlow.Pre = append(expandedFlow.Pre, pre...)
 	resolveErrors = append(resolveErrors, errs...)
 
-	test, errs := r.process(config.Test, sets.New[string](), stack)
+	test, errs := r.process(config.Test, testNames, stack)
 	expandedFlow.Test = append(expandedFlow.Test, test...)
 	resolveErrors = append(resolveErrors, errs...)
 
-	post, errs := r.process(config.Post, sets.New[string](), stack)
+	post, errs := r.process(config.Post, postNames, stack)
 	expandedFlow.Post = append(expandedFlow.Post, post...)
 	resolveErrors = append(resolveErrors, errs...)
+	seen := warnCrossPhaseNames(&stack, preNames, testNames, postNames)
 
 	observerNames := sets.New[string]()
 	for _, step := range append(pre, append(test, post...)...) {
@@ -160,6 +236,10 @@ func (r *registry) resolveTest(
 	resolveErrors = append(resolveErrors, errs...)
 	expandedFlow.Observers = observers
 
+	rollbacks, errs := r.processRollbacks(append(pre, append(test, post...)...), seen, stack)
+	resolveErrors = append(resolveErrors, errs...)
+	expandedFlow.Rollbacks = rollbacks
+
 	resolveErrors = append(resolveErrors, stack.checkUnused(&stack.records[0], overridden, r)...)
 
 	if resolveErrors != nil {
@@ -179,7 +259,7 @@ func (r *registry) ResolveWorkflow(name string) (api.MultiStageTestConfiguration
 }
 
 func (r *registry) ResolveChain(name string) (api.RegistryChain, error) {
-	steps, err := r.processChain(name, sets.New[string](), stack{})
+	steps, err := r.processChain(name, "", stepNames{}, stack{})
 	if err != nil {
 		return api.RegistryChain{}, utilerrors.NewAggregate(err)
 	}
@@ -212,6 +292,13 @@ func mergeDependencyOverrides(dst api.DependencyOverrides, src api.DependencyOve
 	return mergeMaps(dst, src)
 }
 
+// mergeStepReleases joins two step_releases maps.
+// A copy of `dst` is returned with elements overwritten by those in `src` if
+// they target the same step.
+func mergeStepReleases(dst api.StepReleases, src api.StepReleases) api.StepReleases {
+	return mergeMaps(dst, src)
+}
+
 func mergeMaps(dst map[string]string, src map[string]string) map[string]string {
 	if dst == nil && src == nil {
 		return nil
@@ -251,10 +338,32 @@ func mergeLeases(dst, src []api.StepLease) ([]api.StepLease, error) {
 	return ret, nil
 }
 
-func (r *registry) process(steps []api.TestStep, seen sets.Set[string], stack stack) (ret []api.LiteralTestStep, errs []error) {
+// stepNames records where each step name of a phase was declared, so that
+// conflicting names can be reported with both of their sources.
+type stepNames map[string]string
+
+// warnCrossPhaseNames warns about the step names which are used in more than
+// one phase and returns the names of all phases.  The steps run in the same
+// namespace, so the pod of the later step replaces that of the earlier one.
+// Tests have long reused names across phases, so this is not an error.
+func warnCrossPhaseNames(stack *stack, phases ...stepNames) stepNames {
+	ret := stepNames{}
+	for _, phase := range phases {
+		for _, name := range sets.List(sets.KeySet(phase)) {
+			if other, ok := ret[name]; ok {
+				logrus.Warn(stack.errorf("step name %s is used in several phases, by %s and %s, the pod of the later step replaces that of the earlier one; set `alias` on one of them to rename it", name, other, phase[name]))
+				continue
+			}
+			ret[name] = phase[name]
+		}
+	}
+	return ret
+}
+
+func (r *registry) process(steps []api.TestStep, seen stepNames, stack stack) (ret []api.LiteralTestStep, errs []error) {
 	for _, step := range steps {
 		if step.Chain != nil {
-			steps, err := r.processChain(*step.Chain, seen, stack)
+			steps, err := r.processChain(*step.Chain, step.Alias, seen, stack)
 			errs = append(errs, err...)
 			ret = append(ret, steps...)
 		} else {
@@ -268,12 +377,15 @@ func (r *registry) process(steps []api.TestStep, seen sets.Set[string], stack st
 	return
 }
 
-func (r *registry) processChain(name string, seen sets.Set[string], stack stack) ([]api.LiteralTestStep, []error) {
+// processChain resolves the steps of a chain.  If the chain is aliased, the
+// alias is prepended to the names of its steps.
+func (r *registry) processChain(name, alias string, seen stepNames, stack stack) ([]api.LiteralTestStep, []error) {
 	chain, ok := r.chainsByName[name]
 	if !ok {
 		return nil, []error{stack.errorf("unknown step chain: %s", name)}
 	}
 	rec := stackRecordForStep("chain/"+name, chain.Environment, nil)
+	rec.alias = alias
 	stack.push(rec)
 	defer stack.pop()
 	ret, err := r.process(chain.Steps, seen, stack)
@@ -281,22 +393,28 @@ func (r *registry) processChain(name string, seen sets.Set[string], stack stack)
 	return ret, err
 }
 
-func (r *registry) processStep(step *api.TestStep, seen sets.Set[string], stack stack) (ret api.LiteralTestStep, err []error) {
+func (r *registry) processStep(step *api.TestStep, seen stepNames, stack stack) (ret api.LiteralTestStep, err []error) {
+	source := stack.location()
 	if ref := step.Reference; ref != nil {
 		var ok bool
 		ret, ok = r.stepsByName[*ref]
 		if !ok {
 			return api.LiteralTestStep{}, []error{stack.errorf("invalid step reference: %s", *ref)}
 		}
+		if step.Alias != "" {
+			ret.As = step.Alias
+		}
+		source = strings.TrimPrefix(source+": ref/"+*ref, ": ")
 	} else if step.LiteralTestStep != nil {
 		ret = *step.LiteralTestStep
 	} else {
 		return api.LiteralTestStep{}, []error{stack.errorf("encountered TestStep where both `Reference` and `LiteralTestStep` are nil")}
 	}
-	if seen.Has(ret.As) {
-		return api.LiteralTestStep{}, []error{stack.errorf("duplicate name: %s", ret.As)}
+	ret.As = stack.aliased(ret.As)
+	if other, ok := seen[ret.As]; ok {
+		return api.LiteralTestStep{}, []error{stack.errorf("duplicate name: %s, also declared by %s; set `alias` on one of them to rename it", ret.As, other)}
 	}
-	seen.Insert(ret.As)
+	seen[ret.As] = source
 	var errs []error
 	if ret.Leases != nil {
 		ret.Leases = append([]api.StepLease(nil), ret.Leases...)
@@ -323,6 +441,42 @@ func (r *registry) processStep(step *api.TestStep, seen sets.Set[string], stack
 		}
 		ret.Dependencies = deps
 	}
+	if ret.Rollback != "" {
+		if _, ok := r.stepsByName[ret.Rollback]; !ok {
+			errs = append(errs, stack.errorf("step/%s: invalid rollback step reference: %s", ret.As, ret.Rollback))
+		}
+	}
+	if r.approvals != nil {
+		if err := r.approvals.check(ret); err != nil {
+			errs = append(errs, stack.errorf("%w", err))
+		}
+	}
+	return ret, errs
+}
+
+// processRollbacks resolves the steps referenced as the rollback of other
+// steps.  Rollback steps are executed alongside the others, so their names
+// must not conflict with any step of the test.
+func (r *registry) processRollbacks(steps []api.LiteralTestStep, seen stepNames, stack stack) (ret []api.LiteralTestStep, errs []error) {
+	names := sets.New[string]()
+	for _, step := range steps {
+		if step.Rollback != "" {
+			names.Insert(step.Rollback)
+		}
+	}
+	for _, name := range sets.List(names) {
+		name := name
+		step, err := r.processStep(&api.TestStep{Reference: &name}, seen, stack)
+		errs = append(errs, err...)
+		if err != nil {
+			continue
+		}
+		if step.Rollback != "" {
+			errs = append(errs, stack.errorf("step/%s: rollback steps cannot declare a rollback", step.As))
+			continue
+		}
+		ret = append(ret, step)
+	}
 	return ret, errs
 }
 
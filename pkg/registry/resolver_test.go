@@ -284,6 +284,68 @@ func TestResolve(t *testing.T) {
 		observerMap: map[string]api.Observer{},
 		expectedRes: api.MultiStageTestConfigurationLiteral{},
 		expectedErr: errors.New("observer \"yes\" is referenced but no such observer is configured"),
+	}, {
+		name: "Test with rollback",
+		config: api.MultiStageTestConfiguration{
+			ClusterProfile: api.ClusterProfileAWS,
+			Test: []api.TestStep{{
+				LiteralTestStep: &api.LiteralTestStep{As: "dns", From: "my-image", Commands: "make dns", Rollback: "dns-restore"},
+			}},
+		},
+		stepMap: ReferenceByName{
+			"dns-restore": {
+				As:          "dns-restore",
+				From:        "my-image",
+				Commands:    "make dns-restore",
+				Environment: []api.StepParameter{{Name: "ZONE", Default: strPtr("ci")}},
+			},
+		},
+		expectedRes: api.MultiStageTestConfigurationLiteral{
+			ClusterProfile: api.ClusterProfileAWS,
+			Test:           []api.LiteralTestStep{{As: "dns", From: "my-image", Commands: "make dns", Rollback: "dns-restore"}},
+			Rollbacks: []api.LiteralTestStep{{
+				As:          "dns-restore",
+				From:        "my-image",
+				Commands:    "make dns-restore",
+				Environment: []api.StepParameter{{Name: "ZONE", Default: strPtr("ci")}},
+			}},
+		},
+	}, {
+		name: "Test with unknown rollback",
+		config: api.MultiStageTestConfiguration{
+			ClusterProfile: api.ClusterProfileAWS,
+			Test: []api.TestStep{{
+				LiteralTestStep: &api.LiteralTestStep{As: "dns", From: "my-image", Commands: "make dns", Rollback: "dns-restore"},
+			}},
+		},
+		expectedErr: errors.New("test/test: step/dns: invalid rollback step reference: dns-restore"),
+	}, {
+		name: "Test with rollback which is also a step",
+		config: api.MultiStageTestConfiguration{
+			ClusterProfile: api.ClusterProfileAWS,
+			Test: []api.TestStep{{
+				LiteralTestStep: &api.LiteralTestStep{As: "dns", From: "my-image", Commands: "make dns", Rollback: "dns-restore"},
+			}, {
+				Reference: strPtr("dns-restore"),
+			}},
+		},
+		stepMap: ReferenceByName{
+			"dns-restore": {As: "dns-restore", From: "my-image", Commands: "make dns-restore"},
+		},
+		expectedErr: errors.New("test/test: duplicate name: dns-restore, also declared by test/test: ref/dns-restore; set `alias` on one of them to rename it"),
+	}, {
+		name: "Test with rollback which declares a rollback",
+		config: api.MultiStageTestConfiguration{
+			ClusterProfile: api.ClusterProfileAWS,
+			Test: []api.TestStep{{
+				LiteralTestStep: &api.LiteralTestStep{As: "dns", From: "my-image", Commands: "make dns", Rollback: "dns-restore"},
+			}},
+		},
+		stepMap: ReferenceByName{
+			"dns-restore":       {As: "dns-restore", From: "my-image", Commands: "make dns-restore", Rollback: "dns-restore-again"},
+			"dns-restore-again": {As: "dns-restore-again", From: "my-image", Commands: "make dns-restore"},
+		},
+		expectedErr: errors.New("test/test: step/dns-restore: rollback steps cannot declare a rollback"),
 	}, {
 		name: "Test with reference",
 		config: api.MultiStageTestConfiguration{
@@ -598,8 +660,51 @@ func TestResolve(t *testing.T) {
 			},
 		},
 		expectedRes:           api.MultiStageTestConfigurationLiteral{},
-		expectedErr:           errors.New("test/test: chain/nested-chains: duplicate name: ipi-setup"),
-		expectedValidationErr: errors.New("chain/nested-chains: duplicate name: ipi-setup"),
+		expectedErr:           errors.New("test/test: chain/nested-chains: duplicate name: ipi-setup, also declared by test/test: chain/nested-chains: chain/install-chain; set `alias` on one of them to rename it"),
+		expectedValidationErr: errors.New("chain/nested-chains: duplicate name: ipi-setup, also declared by chain/nested-chains: chain/install-chain; set `alias` on one of them to rename it"),
+	}, {
+		name: "Test with duplicate names across phases is only warned about",
+		config: api.MultiStageTestConfiguration{
+			ClusterProfile: api.ClusterProfileAWS,
+			Pre:            []api.TestStep{{Chain: &chainInstall}},
+			Post:           []api.TestStep{{Reference: strPtr("ipi-setup")}},
+		},
+		chainMap: ChainByName{
+			chainInstall: {Steps: []api.TestStep{{Reference: strPtr("ipi-setup")}}},
+		},
+		stepMap: ReferenceByName{
+			"ipi-setup": {As: "ipi-setup", From: "installer", Commands: "openshift-cluster install"},
+		},
+		expectedRes: api.MultiStageTestConfigurationLiteral{
+			ClusterProfile: api.ClusterProfileAWS,
+			Pre:            []api.LiteralTestStep{{As: "ipi-setup", From: "installer", Commands: "openshift-cluster install"}},
+			Post:           []api.LiteralTestStep{{As: "ipi-setup", From: "installer", Commands: "openshift-cluster install"}},
+		},
+	}, {
+		name: "Test with aliased steps",
+		config: api.MultiStageTestConfiguration{
+			ClusterProfile: api.ClusterProfileAWS,
+			Pre: []api.TestStep{
+				{Chain: &chainInstall},
+				{Chain: &nestedChains, Alias: "second"},
+			},
+			Post: []api.TestStep{{Reference: strPtr("ipi-setup"), Alias: "ipi-setup-again"}},
+		},
+		chainMap: ChainByName{
+			chainInstall: {Steps: []api.TestStep{{Reference: strPtr("ipi-setup")}}},
+			nestedChains: {Steps: []api.TestStep{{Chain: &chainInstall, Alias: "nested"}}},
+		},
+		stepMap: ReferenceByName{
+			"ipi-setup": {As: "ipi-setup", From: "installer", Commands: "openshift-cluster install"},
+		},
+		expectedRes: api.MultiStageTestConfigurationLiteral{
+			ClusterProfile: api.ClusterProfileAWS,
+			Pre: []api.LiteralTestStep{
+				{As: "ipi-setup", From: "installer", Commands: "openshift-cluster install"},
+				{As: "second-nested-ipi-setup", From: "installer", Commands: "openshift-cluster install"},
+			},
+			Post: []api.LiteralTestStep{{As: "ipi-setup-again", From: "installer", Commands: "openshift-cluster install"}},
+		},
 	}, {
 		name: "Full AWS Workflow",
 		config: api.MultiStageTestConfiguration{
@@ -1028,7 +1133,7 @@ func TestResolveParameters(t *testing.T) {
 			Workflow:            &testMergeWorkflow,
 			Environment:         api.TestEnvironment{"FROM_TEST": defaultTest},
 			Dependencies:        api.TestDependencies{"FROM_TEST": defaultTest},
-			DependencyOverrides: api.DependencyOverrides{"ADDED": defaultTest},
+			DependencyOverrides: api.DependencyOverrides{"FROM_TEST": defaultTest},
 		},
 		expectedParams: [][]api.StepParameter{
 			{{Name: "NOT_CHANGED", Default: &defaultNotChanged}},
@@ -1042,7 +1147,7 @@ func TestResolveParameters(t *testing.T) {
 		},
 		expectedDepOverrides: api.DependencyOverrides{
 			"FROM_WORKFLOW": defaultWorkflow,
-			"ADDED":         defaultTest,
+			"FROM_TEST":     defaultTest,
 		},
 	}, {
 		name: "invalid chain parameter",
@@ -1195,6 +1300,55 @@ func TestResolveLeases(t *testing.T) {
 	}
 }
 
+func TestResolveDependencyOverrides(t *testing.T) {
+	install, e2e, workflow := "install", "e2e", "workflow"
+	refs := ReferenceByName{
+		install: {As: install, Dependencies: []api.StepDependency{{Name: "release:latest", Env: "RELEASE_IMAGE_LATEST"}}},
+		e2e:     {As: e2e, Dependencies: []api.StepDependency{{Name: "ci-index", Env: "OO_INDEX"}}},
+	}
+	workflows := WorkflowByName{
+		workflow: {
+			Pre:                 []api.TestStep{{Reference: &install}},
+			Test:                []api.TestStep{{Reference: &e2e}},
+			DependencyOverrides: api.DependencyOverrides{"OO_INDEX": "quay.io/org/index:workflow"},
+		},
+	}
+	for _, tc := range []struct {
+		name        string
+		test        api.MultiStageTestConfiguration
+		expectedErr error
+	}{{
+		name: "overrides target step dependencies",
+		test: api.MultiStageTestConfiguration{
+			Workflow:            &workflow,
+			DependencyOverrides: api.DependencyOverrides{"RELEASE_IMAGE_LATEST": "quay.io/org/release:tag"},
+		},
+	}, {
+		name: "override inherited from the workflow for a replaced step",
+		test: api.MultiStageTestConfiguration{
+			Workflow: &workflow,
+			Test:     []api.TestStep{{LiteralTestStep: &api.LiteralTestStep{As: "custom", From: "src", Commands: "make test"}}},
+		},
+	}, {
+		name: "unknown environment variables",
+		test: api.MultiStageTestConfiguration{
+			Pre:                 []api.TestStep{{Reference: &install}},
+			DependencyOverrides: api.DependencyOverrides{"RELEASE_IMAGE_LATEST": "quay.io/org/release:tag", "OO_BUNDLE": "quay.io/org/bundle:tag", "INDEX": "quay.io/org/index:tag"},
+		},
+		expectedErr: utilerrors.NewAggregate([]error{
+			errors.New(`dependency override "INDEX" does not match the environment variable of any step dependency`),
+			errors.New(`dependency override "OO_BUNDLE" does not match the environment variable of any step dependency`),
+		}),
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewResolver(refs, ChainByName{}, workflows, ObserverByName{}).Resolve("test", tc.test)
+			if diff := cmp.Diff(tc.expectedErr, err, testhelper.EquateErrorMessage); diff != "" {
+				t.Errorf("unexpected error: %v", diff)
+			}
+		})
+	}
+}
+
 func TestResolveLeasesCopy(t *testing.T) {
 	ref := "ref"
 	refs := ReferenceByName{
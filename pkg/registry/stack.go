@@ -47,6 +47,27 @@ func (s *stack) errorf(format string, args ...interface{}) error {
 	return fmt.Errorf("%s"+format, args...)
 }
 
+// location describes the position of the top of the stack, e.g.
+// `test/e2e: chain/ipi-install`.
+func (s *stack) location() string {
+	names := make([]string, 0, len(s.records))
+	for _, r := range s.records {
+		names = append(names, r.name)
+	}
+	return strings.Join(names, ": ")
+}
+
+// aliased returns the name of a step after the aliases of the chains in the
+// stack are prepended, the outermost first.
+func (s *stack) aliased(name string) string {
+	for i := len(s.records) - 1; i >= 0; i-- {
+		if alias := s.records[i].alias; alias != "" {
+			name = alias + "-" + name
+		}
+	}
+	return name
+}
+
 func (s *stack) resolve(name string) *string {
 	for _, r := range s.records {
 		for j, e := range r.env {
@@ -135,7 +156,10 @@ func (s *stack) checkUnused(r *stackRecord, overridden [][]api.TestStep, registr
 }
 
 type stackRecord struct {
-	name       string
+	name string
+	// alias is prepended to the names of the steps of the chain of the
+	// record, if set
+	alias      string
 	env        []api.StepParameter
 	unusedEnv  sets.Set[string]
 	deps       []api.StepDependency
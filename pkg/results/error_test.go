@@ -170,3 +170,19 @@ func TestReasons(t *testing.T) {
 		})
 	}
 }
+
+func TestKinds(t *testing.T) {
+	base := errors.New("failure")
+	testhelper.Diff(t, "kinds for base error", Kinds(base), []Kind(nil))
+	user := &UserError{Err: base}
+	testhelper.Diff(t, "kinds for user error", Kinds(user), []Kind{KindUser})
+	wrapped := ForReason("step_failed").WithError(fmt.Errorf("step failed: %w", &TimeoutError{Err: user})).Errorf("step failed")
+	testhelper.Diff(t, "outermost kind is used", Kinds(wrapped), []Kind{KindTimeout})
+	if !errors.Is(wrapped, base) {
+		t.Errorf("expected the typed error to wrap the base error")
+	}
+	aggregate := utilerrors.NewAggregate([]error{wrapped, &InfrastructureError{Err: base}, &DependencyError{Err: base}, &InfrastructureError{Err: base}, base})
+	testhelper.Diff(t, "kinds for aggregate error", Kinds(aggregate), []Kind{KindDependency, KindInfrastructure, KindTimeout})
+	testhelper.Diff(t, "kind string for aggregate error", KindString(aggregate), "dependency,infrastructure,timeout")
+	testhelper.Diff(t, "kind string without kinds", KindString(base, nil), "")
+}
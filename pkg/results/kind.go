@@ -0,0 +1,107 @@
+package results
+
+import (
+	"sort"
+	"strings"
+)
+
+// Kind classifies the cause of an error, so consumers of the results can
+// tell who needs to act on a failure without matching error messages.
+type Kind string
+
+const (
+	// KindUser is an error caused by the configuration or the code under
+	// test, e.g. a test which failed.
+	KindUser Kind = "user"
+	// KindInfrastructure is an error caused by the systems running the
+	// test, e.g. a pod which could not be scheduled.
+	KindInfrastructure Kind = "infrastructure"
+	// KindTimeout is an error caused by an operation exceeding its time
+	// limit.
+	KindTimeout Kind = "timeout"
+	// KindDependency is an error caused by something the failed operation
+	// required not being provided, e.g. by a previous step.
+	KindDependency Kind = "dependency"
+)
+
+// UserError is an error of kind KindUser.
+type UserError struct{ Err error }
+
+func (e *UserError) Error() string { return e.Err.Error() }
+func (e *UserError) Unwrap() error { return e.Err }
+func (e *UserError) Kind() Kind    { return KindUser }
+
+// InfrastructureError is an error of kind KindInfrastructure.
+type InfrastructureError struct{ Err error }
+
+func (e *InfrastructureError) Error() string { return e.Err.Error() }
+func (e *InfrastructureError) Unwrap() error { return e.Err }
+func (e *InfrastructureError) Kind() Kind    { return KindInfrastructure }
+
+// TimeoutError is an error of kind KindTimeout.
+type TimeoutError struct{ Err error }
+
+func (e *TimeoutError) Error() string { return e.Err.Error() }
+func (e *TimeoutError) Unwrap() error { return e.Err }
+func (e *TimeoutError) Kind() Kind    { return KindTimeout }
+
+// DependencyError is an error of kind KindDependency.
+type DependencyError struct{ Err error }
+
+func (e *DependencyError) Error() string { return e.Err.Error() }
+func (e *DependencyError) Unwrap() error { return e.Err }
+func (e *DependencyError) Kind() Kind    { return KindDependency }
+
+// WithKind wraps an error in the typed error of a kind.  Errors of unknown
+// kinds are returned unchanged.
+func WithKind(kind Kind, err error) error {
+	if err == nil {
+		return nil
+	}
+	switch kind {
+	case KindUser:
+		return &UserError{Err: err}
+	case KindInfrastructure:
+		return &InfrastructureError{Err: err}
+	case KindTimeout:
+		return &TimeoutError{Err: err}
+	case KindDependency:
+		return &DependencyError{Err: err}
+	}
+	return err
+}
+
+// Kinds returns the distinct kinds of the errors, sorted.  The outermost kind
+// of each chain is used and aggregate errors are expanded, as in Reasons.
+func Kinds(errs ...error) []Kind {
+	seen := map[Kind]bool{}
+	var walk func(errs ...error)
+	walk = func(errs ...error) {
+		for _, err := range errs {
+			switch err := err.(type) {
+			case interface{ Kind() Kind }:
+				seen[err.Kind()] = true
+			case interface{ Errors() []error }:
+				walk(err.Errors()...)
+			case interface{ Unwrap() error }:
+				walk(err.Unwrap())
+			}
+		}
+	}
+	walk(errs...)
+	var ret []Kind
+	for kind := range seen {
+		ret = append(ret, kind)
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i] < ret[j] })
+	return ret
+}
+
+// KindString returns the kinds of the errors as a comma-delimited list.
+func KindString(errs ...error) string {
+	var kinds []string
+	for _, kind := range Kinds(errs...) {
+		kinds = append(kinds, string(kind))
+	}
+	return strings.Join(kinds, ",")
+}
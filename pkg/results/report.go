@@ -94,6 +94,8 @@ type Request struct {
 	State string `json:"state"`
 	// Reason is a colon-delimited list of reasons for failure
 	Reason string `json:"reason"`
+	// Kind is a comma-delimited list of the kinds of the failure
+	Kind string `json:"kind,omitempty"`
 }
 
 // PodScalerRequest holds the data from pod-scaler used to report a result to an aggregation server
@@ -139,6 +141,7 @@ func (r *reporter) Report(err error) {
 	if len(reasons) == 0 {
 		reasons = []string{string(ReasonUnknown)}
 	}
+	kind := KindString(err)
 	for _, reason := range reasons {
 		r.report(Request{
 			JobName: r.spec.Job,
@@ -146,6 +149,7 @@ func (r *reporter) Report(err error) {
 			Cluster: r.consoleHost,
 			State:   state,
 			Reason:  reason,
+			Kind:    kind,
 		})
 	}
 }
@@ -0,0 +1,164 @@
+package steps
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/junit"
+	"github.com/openshift/ci-tools/pkg/results"
+)
+
+// testResult is the outcome of a test aggregated by an aggregate test.
+type testResult struct {
+	name string
+	// done is closed once the test has run.
+	done chan struct{}
+	err  error
+}
+
+// aggregatedTestStep wraps a test whose result is aggregated by one or more
+// aggregate tests.  Its failure is recorded instead of failing the job, the
+// aggregate tests decide whether it does.
+type aggregatedTestStep struct {
+	wrapped api.Step
+	results []*testResult
+	err     error
+}
+
+func (s *aggregatedTestStep) Inputs() (api.InputDefinition, error) { return s.wrapped.Inputs() }
+func (s *aggregatedTestStep) Validate() error                      { return s.wrapped.Validate() }
+func (s *aggregatedTestStep) Name() string                         { return s.wrapped.Name() }
+func (s *aggregatedTestStep) Description() string                  { return s.wrapped.Description() }
+func (s *aggregatedTestStep) Requires() []api.StepLink             { return s.wrapped.Requires() }
+func (s *aggregatedTestStep) Creates() []api.StepLink              { return s.wrapped.Creates() }
+func (s *aggregatedTestStep) Provides() api.ParameterMap           { return s.wrapped.Provides() }
+func (s *aggregatedTestStep) Objects() []ctrlruntimeclient.Object  { return s.wrapped.Objects() }
+
+// SubTests reports the test cases of the test, which fail if the test
+// failed, even though the step itself succeeds.
+func (s *aggregatedTestStep) SubTests() []*junit.TestCase {
+	if subTests, ok := s.wrapped.(SubtestReporter); ok {
+		if ret := subTests.SubTests(); len(ret) != 0 {
+			return ret
+		}
+	}
+	if s.err == nil {
+		return nil
+	}
+	return []*junit.TestCase{{Name: s.Description(), FailureOutput: &junit.FailureOutput{Output: s.err.Error()}}}
+}
+
+func (s *aggregatedTestStep) SubSuites() []*junit.TestSuite {
+	if subSuites, ok := s.wrapped.(SubSuiteReporter); ok {
+		return subSuites.SubSuites()
+	}
+	return nil
+}
+
+func (s *aggregatedTestStep) SubSteps() []api.CIOperatorStepDetailInfo {
+	if subSteps, ok := s.wrapped.(SubStepReporter); ok {
+		return subSteps.SubSteps()
+	}
+	return nil
+}
+
+func (s *aggregatedTestStep) Run(ctx context.Context) error {
+	s.err = s.wrapped.Run(ctx)
+	if s.err != nil {
+		logrus.Infof("Test %s failed, its result is aggregated: %v", s.Name(), s.err)
+	}
+	for _, result := range s.results {
+		result.err = s.err
+		close(result.done)
+	}
+	return nil
+}
+
+// aggregateTestStep waits for the tests it aggregates to run and passes if
+// enough of them passed.
+type aggregateTestStep struct {
+	name    string
+	minimum int
+	results []*testResult
+	// requires are the links required by all the aggregated tests, so that
+	// the step only starts once all of them can run.
+	requires []api.StepLink
+	subTests []*junit.TestCase
+}
+
+// AggregateTestStep creates the step of an aggregate test, which passes if at
+// least `minimum` of the tests pass.  The steps of the tests are returned
+// wrapped, so that their results are recorded for the aggregate test, and
+// must replace the original steps in the graph.
+func AggregateTestStep(name string, minimum int, tests []api.Step) (api.Step, []api.Step) {
+	step := &aggregateTestStep{name: name, minimum: minimum}
+	var wrapped []api.Step
+	for _, test := range tests {
+		result := &testResult{name: test.Name(), done: make(chan struct{})}
+		step.results = append(step.results, result)
+		step.requires = append(step.requires, test.Requires()...)
+		aggregated, ok := test.(*aggregatedTestStep)
+		if !ok {
+			aggregated = &aggregatedTestStep{wrapped: test}
+		}
+		aggregated.results = append(aggregated.results, result)
+		wrapped = append(wrapped, aggregated)
+	}
+	return step, wrapped
+}
+
+func (s *aggregateTestStep) Inputs() (api.InputDefinition, error) { return nil, nil }
+func (s *aggregateTestStep) Validate() error                      { return nil }
+func (s *aggregateTestStep) Name() string                         { return s.name }
+func (s *aggregateTestStep) Requires() []api.StepLink             { return s.requires }
+func (s *aggregateTestStep) Creates() []api.StepLink              { return nil }
+func (s *aggregateTestStep) Provides() api.ParameterMap           { return nil }
+func (s *aggregateTestStep) Objects() []ctrlruntimeclient.Object  { return nil }
+func (s *aggregateTestStep) SubTests() []*junit.TestCase          { return s.subTests }
+
+func (s *aggregateTestStep) Description() string {
+	names := make([]string, 0, len(s.results))
+	for _, result := range s.results {
+		names = append(names, result.name)
+	}
+	return fmt.Sprintf("Aggregate the results of tests %s", strings.Join(names, ", "))
+}
+
+func (s *aggregateTestStep) Run(ctx context.Context) error {
+	return results.ForReason("aggregating_tests").ForError(s.run(ctx))
+}
+
+func (s *aggregateTestStep) run(ctx context.Context) error {
+	logrus.Infof("Waiting for %d tests to finish, %d of them must pass.", len(s.results), s.minimum)
+	var failed, lines []string
+	for _, result := range s.results {
+		select {
+		case <-result.done:
+		case <-ctx.Done():
+			return fmt.Errorf("interrupted while waiting for test %s: %w", result.name, ctx.Err())
+		}
+		if result.err != nil {
+			failed = append(failed, result.name)
+			lines = append(lines, fmt.Sprintf("test %s failed: %v", result.name, result.err))
+		} else {
+			lines = append(lines, fmt.Sprintf("test %s passed", result.name))
+		}
+	}
+	passed := len(s.results) - len(failed)
+	summary := fmt.Sprintf("%d of %d tests passed, %d required", passed, len(s.results), s.minimum)
+	gate := &junit.TestCase{Name: s.Description(), SystemOut: strings.Join(append(lines, summary), "\n")}
+	var err error
+	if passed < s.minimum {
+		err = fmt.Errorf("only %d of %d tests passed, %d required, failed: %s", passed, len(s.results), s.minimum, strings.Join(failed, ", "))
+		gate.FailureOutput = &junit.FailureOutput{Output: err.Error()}
+	}
+	s.subTests = []*junit.TestCase{gate}
+	logrus.Infof("Aggregate test %s: %s.", s.name, summary)
+	return err
+}
@@ -0,0 +1,90 @@
+package steps
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/junit"
+	"github.com/openshift/ci-tools/pkg/testhelper"
+)
+
+func TestAggregateTestStep(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		minimum  int
+		errs     []error
+		expected []*junit.TestCase
+		err      error
+	}{{
+		name:    "all tests pass",
+		minimum: 3,
+		errs:    []error{nil, nil, nil},
+		expected: []*junit.TestCase{{
+			Name:      "Aggregate the results of tests a, b, c",
+			SystemOut: "test a passed\ntest b passed\ntest c passed\n3 of 3 tests passed, 3 required",
+		}},
+	}, {
+		name:    "enough tests pass",
+		minimum: 2,
+		errs:    []error{nil, errors.New("oops"), nil},
+		expected: []*junit.TestCase{{
+			Name:      "Aggregate the results of tests a, b, c",
+			SystemOut: "test a passed\ntest b failed: oops\ntest c passed\n2 of 3 tests passed, 2 required",
+		}},
+	}, {
+		name:    "too many tests fail",
+		minimum: 2,
+		errs:    []error{errors.New("oops"), errors.New("oh no"), nil},
+		expected: []*junit.TestCase{{
+			Name:          "Aggregate the results of tests a, b, c",
+			SystemOut:     "test a failed: oops\ntest b failed: oh no\ntest c passed\n1 of 3 tests passed, 2 required",
+			FailureOutput: &junit.FailureOutput{Output: "only 1 of 3 tests passed, 2 required, failed: a, b"},
+		}},
+		err: errors.New("only 1 of 3 tests passed, 2 required, failed: a, b"),
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			var tests []api.Step
+			for i, name := range []string{"a", "b", "c"} {
+				tests = append(tests, &fakeStep{name: name, runErr: tc.errs[i], requires: []api.StepLink{api.ImagesReadyLink()}})
+			}
+			step, wrapped := AggregateTestStep("gate", tc.minimum, tests)
+			if diff := cmp.Diff([]api.StepLink{api.ImagesReadyLink(), api.ImagesReadyLink(), api.ImagesReadyLink()}, step.Requires()); diff != "" {
+				t.Errorf("unexpected requirements: %s", diff)
+			}
+			for _, test := range wrapped {
+				if err := test.Run(context.Background()); err != nil {
+					t.Errorf("aggregated test %s failed: %v", test.Name(), err)
+				}
+			}
+			err := step.Run(context.Background())
+			if diff := cmp.Diff(tc.err, err, testhelper.EquateErrorMessage); diff != "" {
+				t.Errorf("unexpected error: %s", diff)
+			}
+			testhelper.Diff(t, "test cases", step.(SubtestReporter).SubTests(), tc.expected)
+		})
+	}
+}
+
+func TestAggregateTestStepSharedTest(t *testing.T) {
+	test := &fakeStep{name: "a", runErr: errors.New("oops")}
+	first, wrapped := AggregateTestStep("first", 1, []api.Step{test})
+	second, rewrapped := AggregateTestStep("second", 1, wrapped)
+	if rewrapped[0] != wrapped[0] {
+		t.Fatalf("test aggregated twice was wrapped twice")
+	}
+	if err := rewrapped[0].Run(context.Background()); err != nil {
+		t.Fatalf("aggregated test failed: %v", err)
+	}
+	if got := rewrapped[0].(SubtestReporter).SubTests(); len(got) != 1 || got[0].FailureOutput == nil {
+		t.Errorf("expected the failure of the aggregated test to be reported, got %v", got)
+	}
+	for _, step := range []api.Step{first, second} {
+		if err := step.Run(context.Background()); err == nil {
+			t.Errorf("expected aggregate test %s to fail", step.Name())
+		}
+	}
+}
@@ -565,6 +565,14 @@ func (w *ArtifactWorker) Done(podName string) <-chan struct{} {
 	return w.remaining[podName].done
 }
 
+// AddPod adds the container from which the contents of the `artifacts`
+// volume of a pod are copied and registers the pod with the worker.  It must
+// be called before the pod is created.
+func (w *ArtifactWorker) AddPod(pod *coreapi.Pod) {
+	addArtifactsToPod(pod)
+	addArtifactContainersFromPod(pod, w)
+}
+
 func addArtifactContainersFromPod(pod *coreapi.Pod, worker *ArtifactWorker) {
 	var containers []string
 	for _, container := range append(append([]coreapi.Container{}, pod.Spec.InitContainers...), pod.Spec.Containers...) {
@@ -0,0 +1,146 @@
+// Package artifactsync uploads the artifacts of a job to the bucket of the job
+// while it runs, so that the artifacts of the steps which completed, e.g. the
+// logs of the installation, can be inspected in Spyglass while later steps
+// still execute.  Prow uploads all the artifacts again when the job ends, so
+// files uploaded here are eventually replaced by their final version.
+package artifactsync
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/pod-utils/downwardapi"
+	"k8s.io/test-infra/prow/pod-utils/gcs"
+	"k8s.io/test-infra/prow/secretutil"
+)
+
+// maxFileSize is the size above which files are only uploaded when the job
+// ends, as they are censored in memory before they are uploaded.
+const maxFileSize = 64 * 1024 * 1024
+
+// UploadFunc uploads files to the bucket, keyed by their path in the bucket.
+type UploadFunc func(ctx context.Context, targets map[string]gcs.UploadFunc) error
+
+// Syncer uploads the files of the artifact directory which were created or
+// changed since the previous synchronization.
+type Syncer struct {
+	dir    string
+	dest   string
+	censor secretutil.Censorer
+	upload UploadFunc
+	synced map[string]fileState
+}
+
+type fileState struct {
+	size    int64
+	modTime time.Time
+}
+
+// NewSyncer creates a syncer for the artifacts of the job of a spec.  It
+// returns nil when the artifacts of the job are not uploaded to a bucket.
+func NewSyncer(spec *downwardapi.JobSpec, dir, credentialsFile string, censor secretutil.Censorer) *Syncer {
+	if spec.DecorationConfig == nil || spec.DecorationConfig.GCSConfiguration == nil {
+		return nil
+	}
+	config := spec.DecorationConfig.GCSConfiguration
+	return &Syncer{
+		dir:    dir,
+		dest:   path.Join(config.PathPrefix, gcs.PathForSpec(spec, pathBuilder(config)), "artifacts"),
+		censor: censor,
+		upload: func(ctx context.Context, targets map[string]gcs.UploadFunc) error {
+			return gcs.Upload(ctx, config.Bucket, credentialsFile, "", targets)
+		},
+		synced: map[string]fileState{},
+	}
+}
+
+// pathBuilder returns the builder of the paths of the jobs of a repository,
+// which Prow uses when uploading the artifacts of a job.
+func pathBuilder(config *prowapi.GCSConfiguration) gcs.RepoPathBuilder {
+	switch config.PathStrategy {
+	case prowapi.PathStrategyLegacy:
+		return gcs.NewLegacyRepoPathBuilder(config.DefaultOrg, config.DefaultRepo)
+	case prowapi.PathStrategySingle:
+		return gcs.NewSingleDefaultRepoPathBuilder(config.DefaultOrg, config.DefaultRepo)
+	default:
+		return gcs.NewExplicitRepoPathBuilder()
+	}
+}
+
+// Run synchronizes the artifacts periodically until the context is done.
+func (s *Syncer) Run(ctx context.Context, interval time.Duration) {
+	_ = wait.PollUntilContextCancel(ctx, interval, false, func(ctx context.Context) (bool, error) {
+		if err := s.Sync(ctx); err != nil {
+			logrus.WithError(err).Warn("Failed to upload the artifacts of the job.")
+		}
+		return false, nil
+	})
+}
+
+// Sync uploads the files which were created or changed since the previous
+// synchronization.  Files which fail to upload are uploaded again the next
+// time.
+func (s *Syncer) Sync(ctx context.Context) error {
+	targets := map[string]gcs.UploadFunc{}
+	changed := map[string]fileState{}
+	err := filepath.WalkDir(s.dir, func(file string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(s.dir, file)
+		if err != nil {
+			return err
+		}
+		state := fileState{size: info.Size(), modTime: info.ModTime()}
+		if state.size > maxFileSize || s.synced[rel] == state {
+			return nil
+		}
+		changed[rel] = state
+		targets[path.Join(s.dest, filepath.ToSlash(rel))] = gcs.DataUpload(s.censored(file))
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list the artifacts: %w", err)
+	}
+	if len(targets) == 0 {
+		return nil
+	}
+	if err := s.upload(ctx, targets); err != nil {
+		return fmt.Errorf("failed to upload %d artifacts: %w", len(targets), err)
+	}
+	for rel, state := range changed {
+		s.synced[rel] = state
+	}
+	logrus.Debugf("Uploaded %d artifacts.", len(targets))
+	return nil
+}
+
+// censored reads a file and censors its content.
+func (s *Syncer) censored(file string) gcs.ReaderFunc {
+	return func() (io.ReadCloser, error) {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		s.censor.Censor(&data)
+		return io.NopCloser(bytes.NewReader(data)), nil
+	}
+}
@@ -0,0 +1,130 @@
+package artifactsync
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/pod-utils/downwardapi"
+	"k8s.io/test-infra/prow/pod-utils/gcs"
+
+	"github.com/openshift/ci-tools/pkg/secrets"
+	"github.com/openshift/ci-tools/pkg/testhelper"
+)
+
+func TestNewSyncer(t *testing.T) {
+	if s := NewSyncer(&downwardapi.JobSpec{}, "", "", nil); s != nil {
+		t.Errorf("expected no syncer for a job which is not uploaded, got %v", s)
+	}
+	for _, tc := range []struct {
+		name     string
+		spec     downwardapi.JobSpec
+		expected string
+	}{{
+		name:     "periodic",
+		spec:     downwardapi.JobSpec{Type: prowapi.PeriodicJob, Job: "periodic", BuildID: "1"},
+		expected: "pr-logs/logs/periodic/1/artifacts",
+	}, {
+		name: "presubmit",
+		spec: downwardapi.JobSpec{
+			Type:    prowapi.PresubmitJob,
+			Job:     "pull-e2e",
+			BuildID: "2",
+			Refs:    &prowapi.Refs{Org: "org", Repo: "repo", Pulls: []prowapi.Pull{{Number: 3}}},
+		},
+		expected: "pr-logs/pr-logs/pull/org_repo/3/pull-e2e/2/artifacts",
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.spec.DecorationConfig = &prowapi.DecorationConfig{GCSConfiguration: &prowapi.GCSConfiguration{
+				Bucket:       "bucket",
+				PathPrefix:   "pr-logs",
+				PathStrategy: prowapi.PathStrategyExplicit,
+			}}
+			s := NewSyncer(&tc.spec, "", "", nil)
+			if s == nil {
+				t.Fatal("expected a syncer")
+			}
+			testhelper.Diff(t, "destination", s.dest, tc.expected)
+		})
+	}
+}
+
+func TestSync(t *testing.T) {
+	dir, export := t.TempDir(), t.TempDir()
+	write := func(name, content string) {
+		file := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	censor := secrets.NewDynamicCensor()
+	censor.AddSecrets("secret")
+	var uploaded []string
+	s := &Syncer{
+		dir:    dir,
+		dest:   "artifacts",
+		censor: &censor,
+		upload: func(ctx context.Context, targets map[string]gcs.UploadFunc) error {
+			for target := range targets {
+				uploaded = append(uploaded, target)
+			}
+			return gcs.LocalExport(ctx, export, targets)
+		},
+		synced: map[string]fileState{},
+	}
+	read := func() map[string]string {
+		ret := map[string]string{}
+		if err := filepath.WalkDir(export, func(file string, entry fs.DirEntry, err error) error {
+			if err != nil || entry.IsDir() {
+				return err
+			}
+			content, err := os.ReadFile(file)
+			if err != nil {
+				return err
+			}
+			rel, _ := filepath.Rel(export, file)
+			ret[rel] = string(content)
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+		return ret
+	}
+	ctx := context.Background()
+
+	write("ci-operator.log", "running")
+	write("e2e/ipi-install/build-log.txt", "using the secret token")
+	if err := s.Sync(ctx); err != nil {
+		t.Fatal(err)
+	}
+	testhelper.Diff(t, "first synchronization", read(), map[string]string{
+		"artifacts/ci-operator.log":               "running",
+		"artifacts/e2e/ipi-install/build-log.txt": "using the XXXXXX token",
+	})
+
+	uploaded = nil
+	write("ci-operator.log", "running the tests")
+	write("e2e/e2e-test/build-log.txt", "testing")
+	if err := s.Sync(ctx); err != nil {
+		t.Fatal(err)
+	}
+	testhelper.Diff(t, "changed files", sets.List(sets.New(uploaded...)), []string{"artifacts/ci-operator.log", "artifacts/e2e/e2e-test/build-log.txt"})
+	testhelper.Diff(t, "second synchronization", read(), map[string]string{
+		"artifacts/ci-operator.log":               "running the tests",
+		"artifacts/e2e/ipi-install/build-log.txt": "using the XXXXXX token",
+		"artifacts/e2e/e2e-test/build-log.txt":    "testing",
+	})
+
+	uploaded = nil
+	if err := s.Sync(ctx); err != nil {
+		t.Fatal(err)
+	}
+	testhelper.Diff(t, "unchanged files", uploaded, []string(nil))
+}
@@ -0,0 +1,221 @@
+// Package compression implements the post-processing of the artifacts of
+// multi-stage test steps.  When the step finishes, files in the artifact
+// directory larger than a threshold are compressed and a manifest with the
+// checksums of all files is written, so downstream consumers can verify the
+// integrity of what they download.
+package compression
+
+import (
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Algorithm is a compression algorithm for artifacts.
+type Algorithm string
+
+const (
+	// None does not compress artifacts, only the manifest is written.
+	None Algorithm = "none"
+	Gzip Algorithm = "gzip"
+	Zstd Algorithm = "zstd"
+
+	// ManifestName is the name of the manifest in the artifact directory.
+	ManifestName = "artifacts-manifest.json"
+)
+
+// Algorithms are all supported algorithms.
+var Algorithms = []Algorithm{None, Gzip, Zstd}
+
+// Valid determines whether the algorithm is supported.
+func (a Algorithm) Valid() bool {
+	for _, v := range Algorithms {
+		if a == v {
+			return true
+		}
+	}
+	return false
+}
+
+func (a Algorithm) extension() string {
+	switch a {
+	case Gzip:
+		return ".gz"
+	case Zstd:
+		return ".zst"
+	}
+	return ""
+}
+
+// skipCompression lists patterns of files which are never compressed, either
+// because they already are or because tools read them from storage directly.
+var skipCompression = []string{
+	"*.gz", "*.tgz", "*.zst", "*.xz", "*.bz2", "*.zip",
+	"*.png", "*.jpg", "*.jpeg",
+	"junit*.xml",
+}
+
+// Entry describes a file in the artifact directory.
+type Entry struct {
+	// Path is relative to the artifact directory.
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+	// Compression is set when the file was compressed, in which case the
+	// original size is also recorded.
+	Compression  Algorithm `json:"compression,omitempty"`
+	OriginalSize int64     `json:"original_size,omitempty"`
+}
+
+// Manifest lists all files in the artifact directory.
+type Manifest struct {
+	Files []Entry `json:"files"`
+}
+
+// Process compresses files in `dir` larger than `threshold` bytes and writes
+// the manifest of the directory.  Files are processed one at a time, while
+// they are streamed to the compressed file.
+func Process(dir string, algorithm Algorithm, threshold int64) (*Manifest, error) {
+	if !algorithm.Valid() {
+		return nil, fmt.Errorf("invalid compression algorithm: %q", algorithm)
+	}
+	manifest := &Manifest{Files: []Entry{}}
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		if rel == ManifestName {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		var entry *Entry
+		if algorithm != None && info.Size() > threshold && shouldCompress(d.Name()) {
+			entry, err = compress(path, algorithm)
+		} else {
+			entry, err = checksum(path)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", rel, err)
+		}
+		entry.Path, err = filepath.Rel(dir, entry.Path)
+		if err != nil {
+			return err
+		}
+		manifest.Files = append(manifest.Files, *entry)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	raw, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, ManifestName), raw, 0644); err != nil {
+		return nil, fmt.Errorf("failed to write manifest: %w", err)
+	}
+	return manifest, nil
+}
+
+func shouldCompress(name string) bool {
+	for _, pattern := range skipCompression {
+		if match, _ := filepath.Match(pattern, strings.ToLower(name)); match {
+			return false
+		}
+	}
+	return true
+}
+
+func checksum(path string) (*Entry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	hash := sha256.New()
+	n, err := io.Copy(hash, f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	return &Entry{Path: path, Size: n, SHA256: hex.EncodeToString(hash.Sum(nil))}, nil
+}
+
+// compress replaces the file with its compressed version.
+func compress(path string, algorithm Algorithm) (ret *Entry, err error) {
+	src, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+	dstPath := path + algorithm.extension()
+	dst, err := os.Create(dstPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create compressed file: %w", err)
+	}
+	defer func() {
+		if closeErr := dst.Close(); err == nil && closeErr != nil {
+			err = fmt.Errorf("failed to close compressed file: %w", closeErr)
+		}
+		if err != nil {
+			_ = os.Remove(dstPath)
+		}
+	}()
+	hash := sha256.New()
+	counter := &countingWriter{w: io.MultiWriter(dst, hash)}
+	var w io.WriteCloser
+	switch algorithm {
+	case Gzip:
+		w = gzip.NewWriter(counter)
+	case Zstd:
+		if w, err = zstd.NewWriter(counter); err != nil {
+			return nil, fmt.Errorf("failed to create encoder: %w", err)
+		}
+	}
+	n, err := io.Copy(w, src)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compress file: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress file: %w", err)
+	}
+	if err := os.Remove(path); err != nil {
+		return nil, fmt.Errorf("failed to remove original file: %w", err)
+	}
+	return &Entry{
+		Path:         dstPath,
+		Size:         counter.n,
+		SHA256:       hex.EncodeToString(hash.Sum(nil)),
+		Compression:  algorithm,
+		OriginalSize: n,
+	}, nil
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
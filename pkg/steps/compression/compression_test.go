@@ -0,0 +1,125 @@
+package compression
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/klauspost/compress/zstd"
+)
+
+func sum(data []byte) string {
+	s := sha256.Sum256(data)
+	return hex.EncodeToString(s[:])
+}
+
+func TestProcess(t *testing.T) {
+	small := []byte("small")
+	large := bytes.Repeat([]byte("large file content\n"), 100)
+	for _, tc := range []struct {
+		name      string
+		algorithm Algorithm
+		decode    func(io.Reader) ([]byte, error)
+	}{{
+		name:      "gzip",
+		algorithm: Gzip,
+		decode: func(r io.Reader) ([]byte, error) {
+			gz, err := gzip.NewReader(r)
+			if err != nil {
+				return nil, err
+			}
+			return io.ReadAll(gz)
+		},
+	}, {
+		name:      "zstd",
+		algorithm: Zstd,
+		decode: func(r io.Reader) ([]byte, error) {
+			d, err := zstd.NewReader(r)
+			if err != nil {
+				return nil, err
+			}
+			defer d.Close()
+			return io.ReadAll(d)
+		},
+	}, {
+		name:      "none",
+		algorithm: None,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for path, data := range map[string][]byte{
+				"small.txt":            small,
+				"nested/large.log":     large,
+				"nested/junit_e2e.xml": large,
+			} {
+				path = filepath.Join(dir, path)
+				if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, data, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			manifest, err := Process(dir, tc.algorithm, 100)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			largeEntry := Entry{Path: "nested/large.log", Size: int64(len(large)), SHA256: sum(large)}
+			if tc.algorithm != None {
+				compressed, err := os.ReadFile(filepath.Join(dir, "nested", "large.log"+tc.algorithm.extension()))
+				if err != nil {
+					t.Fatalf("failed to read compressed file: %v", err)
+				}
+				decompressed, err := tc.decode(bytes.NewReader(compressed))
+				if err != nil {
+					t.Fatalf("failed to decompress file: %v", err)
+				}
+				if !bytes.Equal(large, decompressed) {
+					t.Errorf("decompressed content differs")
+				}
+				if _, err := os.Stat(filepath.Join(dir, "nested", "large.log")); !os.IsNotExist(err) {
+					t.Errorf("expected the original file to be removed, got %v", err)
+				}
+				largeEntry = Entry{
+					Path:         "nested/large.log" + tc.algorithm.extension(),
+					Size:         int64(len(compressed)),
+					SHA256:       sum(compressed),
+					Compression:  tc.algorithm,
+					OriginalSize: int64(len(large)),
+				}
+			}
+			expected := &Manifest{Files: []Entry{
+				{Path: "nested/junit_e2e.xml", Size: int64(len(large)), SHA256: sum(large)},
+				largeEntry,
+				{Path: "small.txt", Size: int64(len(small)), SHA256: sum(small)},
+			}}
+			if diff := cmp.Diff(expected, manifest); diff != "" {
+				t.Errorf("unexpected manifest: %s", diff)
+			}
+			raw, err := os.ReadFile(filepath.Join(dir, ManifestName))
+			if err != nil {
+				t.Fatalf("failed to read manifest: %v", err)
+			}
+			var written Manifest
+			if err := json.Unmarshal(raw, &written); err != nil {
+				t.Fatalf("failed to unmarshal manifest: %v", err)
+			}
+			if diff := cmp.Diff(expected, &written); diff != "" {
+				t.Errorf("unexpected manifest file: %s", diff)
+			}
+		})
+	}
+}
+
+func TestProcessInvalidAlgorithm(t *testing.T) {
+	if _, err := Process(t.TempDir(), "lz4", 0); err == nil {
+		t.Error("expected an error")
+	}
+}
@@ -0,0 +1,162 @@
+// Package costattribution labels the objects created for jobs with the team,
+// product or cost center they are attributed to, so that the spend on build
+// farms and cloud accounts can be split between them.  The attribution is
+// configured centrally, by organization and repository.
+package costattribution
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	buildapi "github.com/openshift/api/build/v1"
+
+	coreapi "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// LabelPrefix is prepended to the names of the labels set on objects.
+	LabelPrefix = "cost.ci.openshift.io/"
+	// TagsEnv exposes the labels to multi-stage steps as comma-separated
+	// `name=value` pairs, so that they can tag the cloud resources they
+	// create.
+	TagsEnv = "CI_COST_TAGS"
+)
+
+// Labels maps label names, without the prefix, to values.
+type Labels map[string]string
+
+// Config attributes the jobs of repositories.  Labels for a repository take
+// precedence over those of its organization, which take precedence over the
+// defaults.
+type Config struct {
+	// Default labels are set for all jobs.
+	Default Labels `json:"default,omitempty"`
+	// Orgs holds labels by organization.
+	Orgs map[string]Labels `json:"orgs,omitempty"`
+	// Repos holds labels by repository, in `org/repo` form.
+	Repos map[string]Labels `json:"repos,omitempty"`
+}
+
+// Load reads a configuration from a YAML file.
+func Load(path string) (*Config, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read configuration: %w", err)
+	}
+	config := &Config{}
+	if err := yaml.UnmarshalStrict(raw, config); err != nil {
+		return nil, fmt.Errorf("failed to parse configuration: %w", err)
+	}
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+func (c *Config) validate() error {
+	check := func(where string, labels Labels) error {
+		for name, value := range labels {
+			if msgs := validation.IsQualifiedName(LabelPrefix + name); len(msgs) != 0 {
+				return fmt.Errorf("%s: invalid label name %q: %s", where, name, strings.Join(msgs, ", "))
+			}
+			if msgs := validation.IsValidLabelValue(value); len(msgs) != 0 {
+				return fmt.Errorf("%s: invalid value %q for label %q: %s", where, value, name, strings.Join(msgs, ", "))
+			}
+		}
+		return nil
+	}
+	if err := check("default", c.Default); err != nil {
+		return err
+	}
+	for _, org := range sortedKeys(c.Orgs) {
+		if err := check("orgs."+org, c.Orgs[org]); err != nil {
+			return err
+		}
+	}
+	for _, repo := range sortedKeys(c.Repos) {
+		if strings.Count(repo, "/") != 1 {
+			return fmt.Errorf("repos.%s: repositories must be in org/repo form", repo)
+		}
+		if err := check("repos."+repo, c.Repos[repo]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// For returns the labels of a repository.  A nil configuration has none.
+func (c *Config) For(org, repo string) Labels {
+	if c == nil {
+		return nil
+	}
+	ret := Labels{}
+	for _, labels := range []Labels{c.Default, c.Orgs[org], c.Repos[org+"/"+repo]} {
+		for name, value := range labels {
+			ret[name] = value
+		}
+	}
+	if len(ret) == 0 {
+		return nil
+	}
+	return ret
+}
+
+// Tags returns the value of the environment variable exposing the labels.
+func (l Labels) Tags() string {
+	var tags []string
+	for _, name := range sortedKeys(l) {
+		tags = append(tags, name+"="+l[name])
+	}
+	return strings.Join(tags, ",")
+}
+
+// Apply sets the labels on an object, without overwriting existing values.
+func (l Labels) Apply(obj ctrlruntimeclient.Object) {
+	if len(l) == 0 {
+		return
+	}
+	labels := obj.GetLabels()
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	for name, value := range l {
+		if _, ok := labels[LabelPrefix+name]; !ok {
+			labels[LabelPrefix+name] = value
+		}
+	}
+	obj.SetLabels(labels)
+}
+
+// Wrap returns a client which sets the labels on the pods and builds it
+// creates.
+func Wrap(upstream ctrlruntimeclient.WithWatch, labels Labels) ctrlruntimeclient.WithWatch {
+	return &client{WithWatch: upstream, labels: labels}
+}
+
+type client struct {
+	ctrlruntimeclient.WithWatch
+	labels Labels
+}
+
+func (c *client) Create(ctx context.Context, obj ctrlruntimeclient.Object, opts ...ctrlruntimeclient.CreateOption) error {
+	switch obj.(type) {
+	case *coreapi.Pod, *buildapi.Build:
+		c.labels.Apply(obj)
+	}
+	return c.WithWatch.Create(ctx, obj, opts...)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	ret := make([]string, 0, len(m))
+	for k := range m {
+		ret = append(ret, k)
+	}
+	sort.Strings(ret)
+	return ret
+}
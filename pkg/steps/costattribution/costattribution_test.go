@@ -0,0 +1,124 @@
+package costattribution
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	coreapi "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	fakectrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/openshift/ci-tools/pkg/testhelper"
+)
+
+func TestLoad(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		raw         string
+		expectedErr string
+	}{{
+		name: "valid configuration",
+		raw:  "default:\n  cost-center: \"700\"\norgs:\n  openshift:\n    product: ocp\nrepos:\n  openshift/installer:\n    team: installer\n",
+	}, {
+		name:        "invalid label name",
+		raw:         "orgs:\n  openshift:\n    cost center: \"700\"\n",
+		expectedErr: `orgs.openshift: invalid label name "cost center": name part must consist of alphanumeric characters, '-', '_' or '.', and must start and end with an alphanumeric character (e.g. 'MyName',  or 'my.name',  or '123-abc', regex used for validation is '([A-Za-z0-9][-A-Za-z0-9_.]*)?[A-Za-z0-9]')`,
+	}, {
+		name:        "invalid label value",
+		raw:         "default:\n  team: \"a team\"\n",
+		expectedErr: `default: invalid value "a team" for label "team": a valid label must be an empty string or consist of alphanumeric characters, '-', '_' or '.', and must start and end with an alphanumeric character (e.g. 'MyValue',  or 'my_value',  or '12345', regex used for validation is '(([A-Za-z0-9][-A-Za-z0-9_.]*)?[A-Za-z0-9])?')`,
+	}, {
+		name:        "invalid repository",
+		raw:         "repos:\n  installer:\n    team: installer\n",
+		expectedErr: "repos.installer: repositories must be in org/repo form",
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(path, []byte(tc.raw), 0644); err != nil {
+				t.Fatal(err)
+			}
+			_, err := Load(path)
+			var actualErr string
+			if err != nil {
+				actualErr = err.Error()
+			}
+			testhelper.Diff(t, "error", actualErr, tc.expectedErr)
+		})
+	}
+}
+
+func TestFor(t *testing.T) {
+	config := &Config{
+		Default: Labels{"cost-center": "700", "team": "unknown"},
+		Orgs:    map[string]Labels{"openshift": {"product": "ocp", "team": "openshift"}},
+		Repos:   map[string]Labels{"openshift/installer": {"team": "installer"}},
+	}
+	for _, tc := range []struct {
+		name       string
+		config     *Config
+		org, repo  string
+		expected   Labels
+		expectTags string
+	}{{
+		name: "no configuration",
+		org:  "openshift",
+		repo: "installer",
+	}, {
+		name:       "defaults",
+		config:     config,
+		org:        "org",
+		repo:       "repo",
+		expected:   Labels{"cost-center": "700", "team": "unknown"},
+		expectTags: "cost-center=700,team=unknown",
+	}, {
+		name:       "organization",
+		config:     config,
+		org:        "openshift",
+		repo:       "origin",
+		expected:   Labels{"cost-center": "700", "product": "ocp", "team": "openshift"},
+		expectTags: "cost-center=700,product=ocp,team=openshift",
+	}, {
+		name:       "repository",
+		config:     config,
+		org:        "openshift",
+		repo:       "installer",
+		expected:   Labels{"cost-center": "700", "product": "ocp", "team": "installer"},
+		expectTags: "cost-center=700,product=ocp,team=installer",
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			labels := tc.config.For(tc.org, tc.repo)
+			testhelper.Diff(t, "labels", labels, tc.expected)
+			testhelper.Diff(t, "tags", labels.Tags(), tc.expectTags)
+		})
+	}
+}
+
+func TestWrap(t *testing.T) {
+	ctx := context.Background()
+	upstream := fakectrlruntimeclient.NewClientBuilder().Build()
+	client := Wrap(upstream, Labels{"team": "installer", "product": "ocp"})
+	pod := &coreapi.Pod{ObjectMeta: meta.ObjectMeta{
+		Namespace: "ns",
+		Name:      "pod",
+		Labels:    map[string]string{"app": "test", LabelPrefix + "product": "okd"},
+	}}
+	secret := &coreapi.Secret{ObjectMeta: meta.ObjectMeta{Namespace: "ns", Name: "secret"}}
+	for _, obj := range []ctrlruntimeclient.Object{pod, secret} {
+		if err := client.Create(ctx, obj); err != nil {
+			t.Fatal(err)
+		}
+	}
+	created := &coreapi.Pod{}
+	if err := upstream.Get(ctx, ctrlruntimeclient.ObjectKeyFromObject(pod), created); err != nil {
+		t.Fatal(err)
+	}
+	testhelper.Diff(t, "pod labels", created.Labels, map[string]string{
+		"app":                   "test",
+		LabelPrefix + "product": "okd",
+		LabelPrefix + "team":    "installer",
+	})
+	testhelper.Diff(t, "secret labels", secret.Labels, map[string]string(nil))
+}
@@ -0,0 +1,142 @@
+// Package identity issues the tokens which identify the steps of a job to
+// external services.  A token is a JWT signed by the key of ci-operator which
+// states the job, build and step it was issued for, so that services like
+// artifact caches or result stores can authorize writes from a step without
+// static credentials being handed to it.  The services verify the token with
+// the public key of the issuer.
+package identity
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	jose "github.com/go-jose/go-jose/v3"
+	"github.com/go-jose/go-jose/v3/jwt"
+)
+
+// Identity is what a token states about its bearer.
+type Identity struct {
+	// Job is the name of the Prow job.
+	Job string `json:"job"`
+	// BuildID identifies the run of the job.
+	BuildID string `json:"build_id"`
+	// ProwJobID is the name of the ProwJob of the run.
+	ProwJobID string `json:"prow_job_id,omitempty"`
+	// Namespace is the namespace the job runs in.
+	Namespace string `json:"namespace"`
+	// Test is the name of the multi-stage test.
+	Test string `json:"test"`
+	// Step is the name of the step in the test.
+	Step string `json:"step"`
+}
+
+// Subject is the subject of a token for the identity, which is unique for
+// each step of each run of a job.
+func (i Identity) Subject() string {
+	return strings.Join([]string{i.Job, i.BuildID, i.Test, i.Step}, "/")
+}
+
+// Claims are the claims of a token.
+type Claims struct {
+	jwt.Claims
+	Identity
+}
+
+// Issuer signs tokens.
+type Issuer struct {
+	signer   jose.Signer
+	issuer   string
+	audience []string
+	now      func() time.Time
+}
+
+// NewIssuer returns an issuer which signs with the key.  The key can be a
+// jose.OpaqueSigner, for the private key to be held by a key management
+// service instead of by ci-operator.
+func NewIssuer(key jose.SigningKey, issuer string, audience []string) (*Issuer, error) {
+	signer, err := jose.NewSigner(key, (&jose.SignerOptions{}).WithType("JWT"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create signer: %w", err)
+	}
+	return &Issuer{signer: signer, issuer: issuer, audience: audience, now: time.Now}, nil
+}
+
+// LoadSigningKey reads a PEM-encoded PKCS#8 private key.  The algorithm of
+// the signatures is determined by the type of the key: ES256, ES384 or ES512
+// for ECDSA, EdDSA for Ed25519 and RS256 for RSA.
+func LoadSigningKey(path string) (jose.SigningKey, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return jose.SigningKey{}, fmt.Errorf("failed to read signing key: %w", err)
+	}
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return jose.SigningKey{}, fmt.Errorf("no PEM block found in %s", path)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return jose.SigningKey{}, fmt.Errorf("failed to parse signing key: %w", err)
+	}
+	return signingKey(key)
+}
+
+func signingKey(key crypto.PrivateKey) (jose.SigningKey, error) {
+	var alg jose.SignatureAlgorithm
+	switch k := key.(type) {
+	case *ecdsa.PrivateKey:
+		switch k.Curve.Params().BitSize {
+		case 256:
+			alg = jose.ES256
+		case 384:
+			alg = jose.ES384
+		case 521:
+			alg = jose.ES512
+		default:
+			return jose.SigningKey{}, fmt.Errorf("unsupported ECDSA curve %s", k.Curve.Params().Name)
+		}
+	case ed25519.PrivateKey:
+		alg = jose.EdDSA
+	case *rsa.PrivateKey:
+		alg = jose.RS256
+	default:
+		return jose.SigningKey{}, fmt.Errorf("unsupported signing key type %T", key)
+	}
+	return jose.SigningKey{Algorithm: alg, Key: key}, nil
+}
+
+// Issue returns a token for the identity which is valid for the duration.
+func (i *Issuer) Issue(identity Identity, validity time.Duration) (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", fmt.Errorf("failed to generate token ID: %w", err)
+	}
+	now := i.now()
+	claims := Claims{
+		Claims: jwt.Claims{
+			Issuer:   i.issuer,
+			Subject:  identity.Subject(),
+			Audience: i.audience,
+			IssuedAt: jwt.NewNumericDate(now),
+			// allow for the clocks of services to be slightly behind
+			NotBefore: jwt.NewNumericDate(now.Add(-time.Minute)),
+			Expiry:    jwt.NewNumericDate(now.Add(validity)),
+			ID:        hex.EncodeToString(id),
+		},
+		Identity: identity,
+	}
+	token, err := jwt.Signed(i.signer).Claims(claims).CompactSerialize()
+	if err != nil {
+		return "", fmt.Errorf("failed to sign token: %w", err)
+	}
+	return token, nil
+}
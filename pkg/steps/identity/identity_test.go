@@ -0,0 +1,112 @@
+package identity
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	jose "github.com/go-jose/go-jose/v3"
+	"github.com/go-jose/go-jose/v3/jwt"
+
+	"github.com/openshift/ci-tools/pkg/testhelper"
+)
+
+func writeKey(t *testing.T, key interface{}) string {
+	raw, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "key.pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: raw}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestIssue(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	id := Identity{Job: "pull-ci-org-repo-master-e2e", BuildID: "1234", Namespace: "ci-op-abcd", Test: "e2e", Step: "upload"}
+	for _, tc := range []struct {
+		name      string
+		key       interface{}
+		public    interface{}
+		algorithm jose.SignatureAlgorithm
+	}{{
+		name:      "ECDSA",
+		key:       ecKey,
+		public:    &ecKey.PublicKey,
+		algorithm: jose.ES256,
+	}, {
+		name:      "Ed25519",
+		key:       edKey,
+		public:    edKey.Public(),
+		algorithm: jose.EdDSA,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			key, err := LoadSigningKey(writeKey(t, tc.key))
+			if err != nil {
+				t.Fatal(err)
+			}
+			testhelper.Diff(t, "algorithm", key.Algorithm, tc.algorithm)
+			issuer, err := NewIssuer(key, "ci-operator", []string{"artifact-cache"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			issuer.now = func() time.Time { return now }
+			raw, err := issuer.Issue(id, time.Hour)
+			if err != nil {
+				t.Fatal(err)
+			}
+			token, err := jwt.ParseSigned(raw)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var claims Claims
+			if err := token.Claims(tc.public, &claims); err != nil {
+				t.Fatalf("failed to verify token: %v", err)
+			}
+			testhelper.Diff(t, "identity", claims.Identity, id)
+			if claims.ID == "" {
+				t.Error("expected the token to have an ID")
+			}
+			claims.ID = ""
+			expected := jwt.Claims{
+				Issuer:    "ci-operator",
+				Subject:   "pull-ci-org-repo-master-e2e/1234/e2e/upload",
+				Audience:  jwt.Audience{"artifact-cache"},
+				IssuedAt:  jwt.NewNumericDate(now),
+				NotBefore: jwt.NewNumericDate(now.Add(-time.Minute)),
+				Expiry:    jwt.NewNumericDate(now.Add(time.Hour)),
+			}
+			testhelper.Diff(t, "claims", claims.Claims, expected)
+			if err := claims.Validate(jwt.Expected{Issuer: "ci-operator", Audience: jwt.Audience{"artifact-cache"}, Time: now.Add(2 * time.Hour)}); !errors.Is(err, jwt.ErrExpired) {
+				t.Errorf("expected the token to expire, got %v", err)
+			}
+		})
+	}
+}
+
+func TestLoadSigningKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "key.pem")
+	if err := os.WriteFile(path, []byte("not a key"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	_, err := LoadSigningKey(path)
+	testhelper.Diff(t, "error", err, errors.New("no PEM block found in "+path), testhelper.EquateErrorMessage)
+}
@@ -0,0 +1,182 @@
+// Package leakcheck verifies that the cloud resources of the clusters of tests
+// are removed when the clusters are deprovisioned.  For the cluster profiles
+// which opt in, a built-in step runs after all other post steps and lists the
+// resources of the cloud account of the profile which carry the tag of the
+// cluster, using the credentials of the profile.  The resources which remain
+// are published by the step as an output, from which ci-operator reports them
+// in the results of the test and in an artifact read by the pruners of the
+// accounts.
+package leakcheck
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	prowv1 "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"sigs.k8s.io/yaml"
+
+	"github.com/openshift/ci-tools/pkg/api"
+)
+
+const (
+	// StepName is the name of the built-in step.
+	StepName = "leak-check"
+	// OutputKey is the key of the output of the step which holds the
+	// identifiers of the leaked resources, one per line.
+	OutputKey = "leaked_resources"
+	// DefaultFrom is the image of the step, which has the CLIs of the
+	// supported clouds.
+	DefaultFrom = "upi-installer"
+	// timeout is the maximum duration of the step.
+	timeout = 15 * time.Minute
+)
+
+// listCommands list the identifiers of the resources of the cloud account of
+// the profile tagged with the cluster with the infrastructure ID in
+// $INFRA_ID, by cluster type.
+var listCommands = map[string]string{
+	string(api.CloudAWS): `export AWS_SHARED_CREDENTIALS_FILE="${CLUSTER_PROFILE_DIR}/.awscred"
+  aws resourcegroupstaggingapi get-resources --region "${LEASED_RESOURCE}" \
+    --tag-filters "Key=kubernetes.io/cluster/${INFRA_ID},Values=owned" \
+    --query 'ResourceTagMappingList[].ResourceARN' --output text | tr '\t' '\n'`,
+	string(api.CloudGCP): `gcloud auth activate-service-account --quiet --key-file="${CLUSTER_PROFILE_DIR}/gce.json" >&2
+  gcloud asset search-all-resources --project "$(jq -r .project_id "${CLUSTER_PROFILE_DIR}/gce.json")" \
+    --query "labels.kubernetes-io-cluster-${INFRA_ID}:owned" --format 'value(name)'`,
+	string(api.CloudAzure4): `local credentials="${CLUSTER_PROFILE_DIR}/osServicePrincipal.json"
+  az login --service-principal --output none \
+    --username "$(jq -r .clientId "${credentials}")" --password "$(jq -r .clientSecret "${credentials}")" \
+    --tenant "$(jq -r .tenantId "${credentials}")"
+  az account set --subscription "$(jq -r .subscriptionId "${credentials}")"
+  az resource list --tag "kubernetes.io_cluster.${INFRA_ID}=owned" --query '[].id' --output tsv`,
+}
+
+// script is the command of the step.  The infrastructure ID of the cluster is
+// read from the metadata written to the shared directory by the installer, so
+// tests which did not install a cluster are not checked.
+const script = `#!/bin/bash
+set -o nounset
+set -o pipefail
+
+if [[ ! -f "${SHARED_DIR}/metadata.json" ]]; then
+  echo "No cluster metadata in the shared directory, skipping the check for leaked resources."
+  exit 0
+fi
+INFRA_ID="$(jq -r '.infraID // empty' "${SHARED_DIR}/metadata.json")"
+if [[ -z "${INFRA_ID}" ]]; then
+  echo "No infrastructure ID in the cluster metadata, skipping the check for leaked resources."
+  exit 0
+fi
+
+list_resources() {
+  %s
+}
+
+if ! resources="$(list_resources | sed '/^[[:space:]]*$/d' | sort -u)"; then
+  echo "Failed to list the resources of cluster ${INFRA_ID}."
+  exit %[2]d
+fi
+if [[ -z "${resources}" ]]; then
+  echo "No resources of cluster ${INFRA_ID} remain."
+  exit 0
+fi
+echo "Resources of cluster ${INFRA_ID} remain after it was deprovisioned:"
+echo "${resources}"
+ci-step output set %[3]s "${resources}"
+exit %[2]d
+`
+
+// Config lists the cluster profiles for which leaked resources are reported.
+type Config struct {
+	// Profiles are the settings of the check, by cluster profile.  Tests with
+	// other profiles are not checked.
+	Profiles map[api.ClusterProfile]Profile `json:"profiles"`
+}
+
+// Profile configures the check for a cluster profile.
+type Profile struct {
+	// From is the image of the step, DefaultFrom if empty.  It must have the
+	// CLI of the cloud of the profile and `jq`.
+	From string `json:"from,omitempty"`
+	// Enforce fails the tests of the profile which leak resources, instead
+	// of only reporting them.
+	Enforce bool `json:"enforce,omitempty"`
+}
+
+// Load reads the profiles which are checked from a YAML file.
+func Load(path string) (*Config, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read leak check configuration: %w", err)
+	}
+	config := &Config{}
+	if err := yaml.UnmarshalStrict(raw, config); err != nil {
+		return nil, fmt.Errorf("failed to parse leak check configuration: %w", err)
+	}
+	if err := config.validate(); err != nil {
+		return nil, fmt.Errorf("invalid leak check configuration: %w", err)
+	}
+	return config, nil
+}
+
+func (c *Config) validate() error {
+	if len(c.Profiles) == 0 {
+		return errors.New("`profiles` is required")
+	}
+	var errs []string
+	for profile := range c.Profiles {
+		if _, ok := listCommands[profile.ClusterType()]; !ok {
+			errs = append(errs, fmt.Sprintf("profiles.%s: cluster type %q is not supported", profile, profile.ClusterType()))
+		}
+	}
+	if errs != nil {
+		sort.Strings(errs)
+		return errors.New(strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+// Step returns the step which checks the resources of a test with a cluster
+// profile, if the profile is checked.
+func (c *Config) Step(profile api.ClusterProfile) (api.LiteralTestStep, bool) {
+	settings, ok := c.Profiles[profile]
+	if !ok {
+		return api.LiteralTestStep{}, false
+	}
+	list, ok := listCommands[profile.ClusterType()]
+	if !ok {
+		return api.LiteralTestStep{}, false
+	}
+	from := settings.From
+	if from == "" {
+		from = DefaultFrom
+	}
+	var exitCode int
+	if settings.Enforce {
+		exitCode = 1
+	}
+	return api.LiteralTestStep{
+		As:       StepName,
+		From:     from,
+		Commands: fmt.Sprintf(script, list, exitCode, OutputKey),
+		Resources: api.ResourceRequirements{
+			Requests: api.ResourceList{"cpu": "100m", "memory": "200Mi"},
+		},
+		Timeout: &prowv1.Duration{Duration: timeout},
+	}, true
+}
+
+// Resources returns the identifiers of the leaked resources in the output of
+// the step.
+func Resources(output string) []string {
+	var ret []string
+	for _, line := range strings.Split(output, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			ret = append(ret, line)
+		}
+	}
+	return ret
+}
@@ -0,0 +1,100 @@
+package leakcheck
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/testhelper"
+)
+
+func TestLoad(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		raw      string
+		expected *Config
+		err      error
+	}{{
+		name: "valid config",
+		raw: `profiles:
+  aws:
+    enforce: true
+  gcp:
+    from: cli-gcloud
+`,
+		expected: &Config{Profiles: map[api.ClusterProfile]Profile{
+			api.ClusterProfileAWS: {Enforce: true},
+			api.ClusterProfileGCP: {From: "cli-gcloud"},
+		}},
+	}, {
+		name: "no profiles",
+		raw:  "profiles: {}\n",
+		err:  errors.New("invalid leak check configuration: `profiles` is required"),
+	}, {
+		name: "unsupported cloud",
+		raw: `profiles:
+  openstack: {}
+`,
+		err: errors.New(`invalid leak check configuration: profiles.openstack: cluster type "openstack" is not supported`),
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "leak-check.yaml")
+			if err := os.WriteFile(path, []byte(tc.raw), 0644); err != nil {
+				t.Fatal(err)
+			}
+			config, err := Load(path)
+			testhelper.Diff(t, "error", err, tc.err, testhelper.EquateErrorMessage)
+			testhelper.Diff(t, "config", config, tc.expected)
+		})
+	}
+}
+
+func TestStep(t *testing.T) {
+	config := Config{Profiles: map[api.ClusterProfile]Profile{
+		api.ClusterProfileAWS:    {Enforce: true},
+		api.ClusterProfileAzure4: {From: "cli-azure"},
+	}}
+	if _, ok := config.Step(api.ClusterProfileGCP); ok {
+		t.Error("expected no step for a profile which is not checked")
+	}
+	for _, tc := range []struct {
+		profile  api.ClusterProfile
+		from     string
+		list     string
+		exitCode string
+	}{{
+		profile:  api.ClusterProfileAWS,
+		from:     DefaultFrom,
+		list:     "aws resourcegroupstaggingapi get-resources",
+		exitCode: "exit 1\n",
+	}, {
+		profile:  api.ClusterProfileAzure4,
+		from:     "cli-azure",
+		list:     "az resource list",
+		exitCode: "exit 0\n",
+	}} {
+		t.Run(string(tc.profile), func(t *testing.T) {
+			step, ok := config.Step(tc.profile)
+			if !ok {
+				t.Fatal("expected a step")
+			}
+			testhelper.Diff(t, "name", step.As, StepName)
+			testhelper.Diff(t, "image", step.From, tc.from)
+			if !strings.Contains(step.Commands, tc.list) {
+				t.Errorf("expected the commands to list resources with %q:\n%s", tc.list, step.Commands)
+			}
+			if !strings.HasSuffix(step.Commands, tc.exitCode) {
+				t.Errorf("expected the commands to end with %q:\n%s", tc.exitCode, step.Commands)
+			}
+		})
+	}
+}
+
+func TestResources(t *testing.T) {
+	output := "arn:aws:ec2:us-east-1:123:volume/vol-1\n\n  arn:aws:s3:::bucket  \n"
+	testhelper.Diff(t, "resources", Resources(output), []string{"arn:aws:ec2:us-east-1:123:volume/vol-1", "arn:aws:s3:::bucket"})
+	testhelper.Diff(t, "no resources", Resources(""), []string(nil))
+}
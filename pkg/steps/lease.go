@@ -23,6 +23,10 @@ var NoLeaseClientErr = errors.New("step needs a lease but no lease client provid
 type stepLease struct {
 	api.StepLease
 	resources []string
+	// shared is the lease held by the job for resources of this type, if any.
+	shared *sharedLease
+	// release detaches the step from the shared lease once acquired.
+	release func() error
 }
 
 // leaseStep wraps another step and acquires/releases one or more leases.
@@ -36,13 +40,20 @@ type leaseStep struct {
 }
 
 func LeaseStep(client *lease.Client, leases []api.StepLease, wrapped api.Step, namespace func() string) api.Step {
+	return SharedLeaseStep(client, nil, leases, wrapped, namespace)
+}
+
+// SharedLeaseStep is LeaseStep, except that leases of the types shared by
+// the job are taken from the shared leases instead of being acquired for the
+// step alone.
+func SharedLeaseStep(client *lease.Client, shared *SharedLeases, leases []api.StepLease, wrapped api.Step, namespace func() string) api.Step {
 	ret := leaseStep{
 		client:    client,
 		wrapped:   wrapped,
 		namespace: namespace,
 	}
 	for _, l := range leases {
-		ret.leases = append(ret.leases, stepLease{StepLease: l})
+		ret.leases = append(ret.leases, stepLease{StepLease: l, shared: shared.lease(l.ResourceType)})
 	}
 	return &ret
 }
@@ -90,6 +101,15 @@ func (s *leaseStep) Provides() api.ParameterMap {
 			}
 			return builder.String(), nil
 		}
+		if l.Env == api.DefaultLeaseEnv {
+			parameters[api.LeasedRegionParam] = func() (string, error) {
+				if len(l.resources) == 0 {
+					return "", nil
+				}
+				region, _ := api.LeaseRegion(l.resources[0])
+				return region, nil
+			}
+		}
 	}
 	return parameters
 }
@@ -101,6 +121,13 @@ func (s *leaseStep) SubTests() []*junit.TestCase {
 	return nil
 }
 
+func (s *leaseStep) SubSuites() []*junit.TestSuite {
+	if subSuites, ok := s.wrapped.(SubSuiteReporter); ok {
+		return subSuites.SubSuites()
+	}
+	return nil
+}
+
 func (s *leaseStep) Run(ctx context.Context) error {
 	return results.ForReason("utilizing_lease").ForError(s.run(ctx))
 }
@@ -151,8 +178,14 @@ func acquireLeases(
 	var errs []error
 	for _, i := range sorted {
 		l := &leases[i]
-		logrus.Debugf("Acquiring %d lease(s) for %s", l.Count, l.ResourceType)
-		names, err := client.Acquire(l.ResourceType, l.Count, ctx, cancel)
+		var names []string
+		var err error
+		if l.shared != nil {
+			names, l.release, err = l.shared.acquire(client, cancel, l.Count)
+		} else {
+			logrus.Debugf("Acquiring %d lease(s) for %s", l.Count, l.ResourceType)
+			names, err = client.Acquire(l.ResourceType, l.Count, ctx, cancel)
+		}
 		if err != nil {
 			if err == lease.ErrNotFound {
 				printResourceMetrics(client, l.ResourceType)
@@ -173,7 +206,17 @@ func acquireLeases(
 
 func releaseLeases(client lease.Client, leases []stepLease) error {
 	var errs []error
-	for _, l := range leases {
+	for i := range leases {
+		l := &leases[i]
+		if l.shared != nil {
+			if l.release != nil {
+				if err := l.release(); err != nil {
+					errs = append(errs, err)
+				}
+				l.release = nil
+			}
+			continue
+		}
 		for _, r := range l.resources {
 			if r == "" {
 				continue
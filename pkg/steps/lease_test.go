@@ -134,6 +134,36 @@ func TestProvidesStripsSuffix(t *testing.T) {
 	}
 }
 
+func TestProvidesLeasedRegion(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		resources []string
+		expected  string
+	}{{
+		name:      "cloud account",
+		resources: []string{"us-east-1--aws-quota-slice-03"},
+		expected:  "us-east-1",
+	}, {
+		name:      "resource without a region",
+		resources: []string{"ci-segment-151"},
+	}, {
+		name: "no resource",
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			leases := []api.StepLease{{Env: api.DefaultLeaseEnv, ResourceType: "rtype"}}
+			withLease := LeaseStep(nil, leases, &stepNeedsLease{}, emptyNamespace)
+			withLease.(*leaseStep).leases[0].resources = tc.resources
+			actual, err := withLease.Provides()[api.LeasedRegionParam]()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if actual != tc.expected {
+				t.Errorf("got %q for %s, expected %q", actual, api.LeasedRegionParam, tc.expected)
+			}
+		})
+	}
+}
+
 func TestError(t *testing.T) {
 	leases := []api.StepLease{
 		{ResourceType: "rtype0", Count: 1},
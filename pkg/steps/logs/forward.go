@@ -0,0 +1,227 @@
+// Package logs implements the forwarding of the output of multi-stage test
+// steps to a central log store.  The output of the containers of the steps is
+// followed while they run, labeled with the identity of the job and shipped
+// in batches, so that it remains searchable after the artifacts of the job
+// have expired.
+package logs
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"k8s.io/test-infra/prow/secretutil"
+)
+
+// SinkType is the kind of log store the output is forwarded to.
+type SinkType string
+
+const (
+	// SinkLoki accepts requests of the Loki push API, at /loki/api/v1/push.
+	SinkLoki SinkType = "loki"
+	// SinkElasticsearch accepts requests of the Elasticsearch bulk API, at
+	// <index>/_bulk.
+	SinkElasticsearch SinkType = "elasticsearch"
+)
+
+const (
+	// batchLines is the largest number of lines sent in one request.
+	batchLines = 1000
+	// batchInterval is how long lines are held before they are sent.
+	batchInterval = 5 * time.Second
+)
+
+// Valid determines whether the sink type is supported.
+func (t SinkType) Valid() bool {
+	return t == SinkLoki || t == SinkElasticsearch
+}
+
+// Line is a line of output and the time at which it was written.
+type Line struct {
+	Time time.Time
+	Text string
+}
+
+// Forwarder ships lines of output to a log store.
+type Forwarder struct {
+	client   *http.Client
+	url      string
+	sinkType SinkType
+}
+
+// NewForwarder returns a forwarder to the log store of the type at the URL.
+// For Loki, the URL is that of the push endpoint; for Elasticsearch, that of
+// the index or data stream.
+func NewForwarder(client *http.Client, sinkType SinkType, url string) (*Forwarder, error) {
+	if !sinkType.Valid() {
+		return nil, fmt.Errorf("invalid log sink type %q, must be one of: %s, %s", sinkType, SinkLoki, SinkElasticsearch)
+	}
+	if url == "" {
+		return nil, fmt.Errorf("the URL of the log sink is required")
+	}
+	return &Forwarder{client: client, url: url, sinkType: sinkType}, nil
+}
+
+// Follow reads the output of a container, with the timestamps prefixed by the
+// kubelet, and forwards it with the labels until the output ends.  Secrets
+// are removed from the lines before they are sent.
+func (f *Forwarder) Follow(ctx context.Context, labels map[string]string, output io.Reader, censor secretutil.Censorer) error {
+	lines := make(chan Line)
+	scanErr := make(chan error, 1)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(output)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			select {
+			case lines <- parseLine(scanner.Text(), censor):
+			case <-ctx.Done():
+				scanErr <- ctx.Err()
+				return
+			}
+		}
+		scanErr <- scanner.Err()
+	}()
+	ticker := time.NewTicker(batchInterval)
+	defer ticker.Stop()
+	var batch []Line
+	var sendErr error
+	send := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := f.Send(ctx, labels, batch); err != nil && sendErr == nil {
+			// keep following the output, in case the store recovers
+			sendErr = err
+		}
+		batch = nil
+	}
+	for {
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				send()
+				if err := <-scanErr; err != nil {
+					return fmt.Errorf("failed to read the output: %w", err)
+				}
+				return sendErr
+			}
+			batch = append(batch, line)
+			if len(batch) >= batchLines {
+				send()
+			}
+		case <-ticker.C:
+			send()
+		}
+	}
+}
+
+// parseLine splits the timestamp added by the kubelet from a line.
+func parseLine(raw string, censor secretutil.Censorer) Line {
+	line := Line{Time: time.Now(), Text: raw}
+	if i := strings.IndexByte(raw, ' '); i != -1 {
+		if t, err := time.Parse(time.RFC3339Nano, raw[:i]); err == nil {
+			line = Line{Time: t, Text: raw[i+1:]}
+		}
+	}
+	text := []byte(line.Text)
+	censor.Censor(&text)
+	line.Text = string(text)
+	return line
+}
+
+// Send forwards the lines with the labels in a single request.
+func (f *Forwarder) Send(ctx context.Context, labels map[string]string, lines []Line) error {
+	if len(lines) == 0 {
+		return nil
+	}
+	var body []byte
+	var contentType string
+	var err error
+	switch f.sinkType {
+	case SinkLoki:
+		body, err = lokiRequest(labels, lines)
+		contentType = "application/json"
+	case SinkElasticsearch:
+		body, err = elasticsearchRequest(labels, lines)
+		contentType = "application/x-ndjson"
+	}
+	if err != nil {
+		return fmt.Errorf("failed to encode the log lines: %w", err)
+	}
+	url := f.url
+	if f.sinkType == SinkElasticsearch {
+		url = strings.TrimSuffix(url, "/") + "/_bulk"
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", contentType)
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send the log lines: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected response from log sink: %s", resp.Status)
+	}
+	if f.sinkType == SinkElasticsearch {
+		// the bulk API reports the failure of individual documents in the body
+		var result struct {
+			Errors bool `json:"errors"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+			return fmt.Errorf("failed to decode the response of the log sink: %w", err)
+		}
+		if result.Errors {
+			return fmt.Errorf("the log sink failed to index some of the lines")
+		}
+	}
+	return nil
+}
+
+type lokiPush struct {
+	Streams []lokiStream `json:"streams"`
+}
+
+type lokiStream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+func lokiRequest(labels map[string]string, lines []Line) ([]byte, error) {
+	stream := lokiStream{Stream: labels}
+	for _, line := range lines {
+		stream.Values = append(stream.Values, [2]string{strconv.FormatInt(line.Time.UnixNano(), 10), line.Text})
+	}
+	return json.Marshal(lokiPush{Streams: []lokiStream{stream}})
+}
+
+type elasticsearchDocument struct {
+	Timestamp time.Time         `json:"@timestamp"`
+	Message   string            `json:"message"`
+	Labels    map[string]string `json:"labels"`
+}
+
+func elasticsearchRequest(labels map[string]string, lines []Line) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	for _, line := range lines {
+		// `create` is accepted by both indices and data streams
+		if err := encoder.Encode(map[string]any{"create": map[string]any{}}); err != nil {
+			return nil, err
+		}
+		if err := encoder.Encode(elasticsearchDocument{Timestamp: line.Time, Message: line.Text, Labels: labels}); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
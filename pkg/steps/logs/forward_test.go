@@ -0,0 +1,108 @@
+package logs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/openshift/ci-tools/pkg/secrets"
+	"github.com/openshift/ci-tools/pkg/testhelper"
+)
+
+const output = `2024-05-01T12:00:00.000000001Z Installing the cluster
+2024-05-01T12:00:01Z using token s3cr3t
+no timestamp
+`
+
+// timestampRE matches the time of the line without a timestamp, which is the
+// time at which it was read.
+var timestampRE = regexp.MustCompile(`(\["|"@timestamp":")[^"]+(","(?:message":")?no timestamp")`)
+
+func TestFollow(t *testing.T) {
+	labels := map[string]string{"job": "job", "step": "install"}
+	for _, tc := range []struct {
+		name     string
+		sinkType SinkType
+		path     string
+		response string
+		expected string
+		err      error
+	}{{
+		name:     "loki",
+		sinkType: SinkLoki,
+		path:     "/loki/api/v1/push",
+		expected: `{"streams":[{"stream":{"job":"job","step":"install"},"values":[["1714564800000000001","Installing the cluster"],["1714564801000000000","using token XXXXXX"],["TIME","no timestamp"]]}]}`,
+	}, {
+		name:     "elasticsearch",
+		sinkType: SinkElasticsearch,
+		path:     "/ci-logs/_bulk",
+		response: `{"errors":false}`,
+		expected: `{"create":{}}
+{"@timestamp":"2024-05-01T12:00:00.000000001Z","message":"Installing the cluster","labels":{"job":"job","step":"install"}}
+{"create":{}}
+{"@timestamp":"2024-05-01T12:00:01Z","message":"using token XXXXXX","labels":{"job":"job","step":"install"}}
+{"create":{}}
+{"@timestamp":"TIME","message":"no timestamp","labels":{"job":"job","step":"install"}}
+`,
+	}, {
+		name:     "elasticsearch fails to index",
+		sinkType: SinkElasticsearch,
+		path:     "/ci-logs/_bulk",
+		response: `{"errors":true}`,
+		err:      errors.New("the log sink failed to index some of the lines"),
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			var lock sync.Mutex
+			var received []string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != tc.path {
+					t.Errorf("unexpected path %s", r.URL.Path)
+				}
+				raw, err := io.ReadAll(r.Body)
+				if err != nil {
+					t.Error(err)
+				}
+				lock.Lock()
+				received = append(received, string(raw))
+				lock.Unlock()
+				_, _ = w.Write([]byte(tc.response))
+			}))
+			defer server.Close()
+			url := server.URL + "/loki/api/v1/push"
+			if tc.sinkType == SinkElasticsearch {
+				url = server.URL + "/ci-logs/"
+			}
+			forwarder, err := NewForwarder(server.Client(), tc.sinkType, url)
+			if err != nil {
+				t.Fatal(err)
+			}
+			censor := secrets.NewDynamicCensor()
+			censor.AddSecrets("s3cr3t")
+			err = forwarder.Follow(context.Background(), labels, strings.NewReader(output), &censor)
+			testhelper.Diff(t, "error", err, tc.err, testhelper.EquateErrorMessage)
+			lock.Lock()
+			defer lock.Unlock()
+			if len(received) != 1 {
+				t.Fatalf("expected the lines to be sent in a single request, got %d", len(received))
+			}
+			if tc.expected != "" {
+				testhelper.Diff(t, "request", timestampRE.ReplaceAllString(received[0], "${1}TIME${2}"), tc.expected)
+			}
+		})
+	}
+}
+
+func TestNewForwarder(t *testing.T) {
+	if _, err := NewForwarder(http.DefaultClient, "splunk", "https://splunk"); err == nil {
+		t.Error("expected an unsupported sink type to be rejected")
+	}
+	if _, err := NewForwarder(http.DefaultClient, SinkLoki, ""); err == nil {
+		t.Error("expected a missing URL to be rejected")
+	}
+}
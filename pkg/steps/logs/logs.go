@@ -0,0 +1,119 @@
+// Package logs implements the capture of the output streams of multi-stage
+// test steps.  For the steps which request it, the standard output and error
+// of the step command are written, in addition to the combined container log,
+// to separate files in
+// `$ARTIFACT_DIR/logs`, rotated once they reach a maximum size so verbose steps
+// cannot fill the artifact volume.
+package logs
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+const (
+	// Dir is the directory, relative to the artifact directory, where the
+	// output of the step is written.
+	Dir = "logs"
+	// Stdout is the name of the file holding the standard output.
+	Stdout = "stdout.log"
+	// Stderr is the name of the file holding the standard error.
+	Stderr = "stderr.log"
+	// DefaultMaxSize is the size at which the files of the steps which
+	// request split logs are rotated.
+	DefaultMaxSize = 10 * 1024 * 1024
+)
+
+// RotatingFile is a writer to a file which is rotated once it reaches a
+// maximum size.  Rotated files are suffixed with `.1`, `.2`, etc., from the
+// most to the least recent, and only a fixed number of them is kept.
+type RotatingFile struct {
+	path    string
+	maxSize int64
+	backups int
+
+	lock sync.Mutex
+	file *os.File
+	size int64
+}
+
+// NewRotatingFile creates the file at `path`, truncating it if it exists.
+func NewRotatingFile(path string, maxSize int64, backups int) (*RotatingFile, error) {
+	if maxSize <= 0 {
+		return nil, fmt.Errorf("invalid maximum size: %d", maxSize)
+	}
+	if backups < 0 {
+		return nil, fmt.Errorf("invalid number of backups: %d", backups)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %w", err)
+	}
+	f := &RotatingFile{path: path, maxSize: maxSize, backups: backups}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *RotatingFile) open() error {
+	file, err := os.Create(f.path)
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	f.file, f.size = file, 0
+	return nil
+}
+
+// Write writes to the current file, rotating it as many times as necessary.
+func (f *RotatingFile) Write(p []byte) (int, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	var written int
+	for len(p) != 0 {
+		if f.size >= f.maxSize {
+			if err := f.rotate(); err != nil {
+				return written, err
+			}
+		}
+		n := int64(len(p))
+		if remaining := f.maxSize - f.size; n > remaining {
+			n = remaining
+		}
+		w, err := f.file.Write(p[:n])
+		written += w
+		f.size += int64(w)
+		if err != nil {
+			return written, err
+		}
+		p = p[n:]
+	}
+	return written, nil
+}
+
+func (f *RotatingFile) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return fmt.Errorf("failed to close file: %w", err)
+	}
+	if f.backups == 0 {
+		return f.open()
+	}
+	for i := f.backups - 1; i > 0; i-- {
+		src := fmt.Sprintf("%s.%d", f.path, i)
+		if err := os.Rename(src, fmt.Sprintf("%s.%d", f.path, i+1)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to rotate file: %w", err)
+		}
+	}
+	if err := os.Rename(f.path, f.path+".1"); err != nil {
+		return fmt.Errorf("failed to rotate file: %w", err)
+	}
+	return f.open()
+}
+
+// Close closes the current file.
+func (f *RotatingFile) Close() error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.file.Close()
+}
@@ -0,0 +1,83 @@
+package logs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRotatingFile(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		maxSize  int64
+		backups  int
+		writes   []string
+		expected map[string]string
+	}{{
+		name:     "no rotation",
+		maxSize:  10,
+		backups:  2,
+		writes:   []string{"abc", "def"},
+		expected: map[string]string{"stdout.log": "abcdef"},
+	}, {
+		name:    "writes are split across files",
+		maxSize: 4,
+		backups: 2,
+		writes:  []string{"abc", "defghi"},
+		expected: map[string]string{
+			"stdout.log":   "i",
+			"stdout.log.1": "efgh",
+			"stdout.log.2": "abcd",
+		},
+	}, {
+		name:    "oldest files are removed",
+		maxSize: 2,
+		backups: 2,
+		writes:  []string{"ab", "cd", "ef", "gh"},
+		expected: map[string]string{
+			"stdout.log":   "gh",
+			"stdout.log.1": "ef",
+			"stdout.log.2": "cd",
+		},
+	}, {
+		name:     "no backups",
+		maxSize:  2,
+		writes:   []string{"abcde"},
+		expected: map[string]string{"stdout.log": "e"},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			f, err := NewRotatingFile(filepath.Join(dir, Stdout), tc.maxSize, tc.backups)
+			if err != nil {
+				t.Fatalf("failed to create file: %v", err)
+			}
+			for _, w := range tc.writes {
+				if n, err := f.Write([]byte(w)); err != nil {
+					t.Fatalf("failed to write: %v", err)
+				} else if n != len(w) {
+					t.Fatalf("expected to write %d bytes, wrote %d", len(w), n)
+				}
+			}
+			if err := f.Close(); err != nil {
+				t.Fatalf("failed to close file: %v", err)
+			}
+			entries, err := os.ReadDir(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			actual := map[string]string{}
+			for _, e := range entries {
+				raw, err := os.ReadFile(filepath.Join(dir, e.Name()))
+				if err != nil {
+					t.Fatal(err)
+				}
+				actual[e.Name()] = string(raw)
+			}
+			if diff := cmp.Diff(tc.expected, actual); diff != "" {
+				t.Errorf("unexpected files: %s", diff)
+			}
+		})
+	}
+}
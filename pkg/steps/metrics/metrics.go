@@ -0,0 +1,143 @@
+// Package metrics implements the collection of metrics published by
+// multi-stage test steps.  Steps write files in the Prometheus text exposition
+// format to `$ARTIFACT_DIR/metrics/*.prom`; when the step finishes, those are
+// validated, labeled with the identity of the job and forwarded to a sink.
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"github.com/prometheus/common/model"
+
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+)
+
+const (
+	// Dir is the directory, relative to the artifact directory, where steps
+	// write their metrics.
+	Dir = "metrics"
+	// Extension is the extension of files containing metrics.
+	Extension = ".prom"
+)
+
+// Collect reads and validates all metric files in `dir`, adding `labels` to
+// every sample.  A missing directory is not an error: most steps do not
+// publish any metrics.
+func Collect(dir string, labels map[string]string) ([]*dto.MetricFamily, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*"+Extension))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list metric files: %w", err)
+	}
+	sort.Strings(files)
+	families := map[string]*dto.MetricFamily{}
+	var errs []error
+	for _, file := range files {
+		if err := collectFile(file, labels, families); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", filepath.Base(file), err))
+		}
+	}
+	if len(errs) != 0 {
+		return nil, utilerrors.NewAggregate(errs)
+	}
+	names := make([]string, 0, len(families))
+	for name := range families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	ret := make([]*dto.MetricFamily, 0, len(names))
+	for _, name := range names {
+		ret = append(ret, families[name])
+	}
+	return ret, nil
+}
+
+func collectFile(path string, labels map[string]string, families map[string]*dto.MetricFamily) error {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+	var parser expfmt.TextParser
+	parsed, err := parser.TextToMetricFamilies(bytes.NewReader(raw))
+	if err != nil {
+		return fmt.Errorf("invalid metrics: %w", err)
+	}
+	var errs []error
+	for name, family := range parsed {
+		if _, ok := families[name]; ok {
+			errs = append(errs, fmt.Errorf("metric %s is defined in more than one file", name))
+			continue
+		}
+		if err := addLabels(family, labels); err != nil {
+			errs = append(errs, fmt.Errorf("metric %s: %w", name, err))
+			continue
+		}
+		families[name] = family
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// addLabels adds the job labels to every sample in a family.  Steps may not
+// set those labels themselves, so they cannot impersonate other jobs.
+func addLabels(family *dto.MetricFamily, labels map[string]string) error {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		if !model.LabelName(k).IsValid() {
+			return fmt.Errorf("invalid label name %q", k)
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, metric := range family.Metric {
+		for _, pair := range metric.Label {
+			if _, ok := labels[pair.GetName()]; ok {
+				return fmt.Errorf("label %q is reserved", pair.GetName())
+			}
+		}
+		for _, k := range keys {
+			name, value := k, labels[k]
+			metric.Label = append(metric.Label, &dto.LabelPair{Name: &name, Value: &value})
+		}
+		sort.Slice(metric.Label, func(i, j int) bool {
+			return metric.Label[i].GetName() < metric.Label[j].GetName()
+		})
+	}
+	return nil
+}
+
+// Forward sends the metric families to the sink in the text exposition
+// format.  The sink is expected to accept the same requests as a Prometheus
+// push gateway.
+func Forward(ctx context.Context, client *http.Client, sink string, families []*dto.MetricFamily) error {
+	if len(families) == 0 {
+		return nil
+	}
+	var buf bytes.Buffer
+	encoder := expfmt.NewEncoder(&buf, expfmt.FmtText)
+	for _, family := range families {
+		if err := encoder.Encode(family); err != nil {
+			return fmt.Errorf("failed to encode metric %s: %w", family.GetName(), err)
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sink, &buf)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", string(expfmt.FmtText))
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send metrics: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected response from metrics sink: %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package metrics
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/common/expfmt"
+
+	"github.com/openshift/ci-tools/pkg/testhelper"
+)
+
+func TestCollect(t *testing.T) {
+	labels := map[string]string{"job": "job", "step": "step"}
+	for _, tc := range []struct {
+		name     string
+		files    map[string]string
+		expected string
+		err      string
+	}{{
+		name: "no metrics",
+	}, {
+		name: "metrics are labeled",
+		files: map[string]string{
+			"perf.prom": `# TYPE latency_seconds gauge
+latency_seconds{quantile="0.99"} 1.5
+`,
+			"ignored.txt": "not metrics",
+		},
+		expected: `# TYPE latency_seconds gauge
+latency_seconds{job="job",quantile="0.99",step="step"} 1.5
+`,
+	}, {
+		name:  "invalid file",
+		files: map[string]string{"bad.prom": "not a metric line at all\n"},
+		err:   "bad.prom: invalid metrics: text format parsing error in line 1: expected float as value, got \"a\"",
+	}, {
+		name:  "reserved label",
+		files: map[string]string{"perf.prom": "value{job=\"other\"} 1\n"},
+		err:   `perf.prom: metric value: label "job" is reserved`,
+	}, {
+		name: "duplicated metric",
+		files: map[string]string{
+			"a.prom": "value 1\n",
+			"b.prom": "value 2\n",
+		},
+		err: "b.prom: metric value is defined in more than one file",
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range tc.files {
+				if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			families, err := Collect(dir, labels)
+			var errStr string
+			if err != nil {
+				errStr = err.Error()
+			}
+			testhelper.Diff(t, "error", errStr, tc.err)
+			var out strings.Builder
+			for _, f := range families {
+				if _, err := expfmt.MetricFamilyToText(&out, f); err != nil {
+					t.Fatal(err)
+				}
+			}
+			testhelper.Diff(t, "metrics", out.String(), tc.expected)
+		})
+	}
+}
+
+func TestForward(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "perf.prom"), []byte("value 1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	families, err := Collect(dir, map[string]string{"job": "job"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var received string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+		}
+		received = string(body)
+	}))
+	defer server.Close()
+	if err := Forward(context.Background(), server.Client(), server.URL, families); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	testhelper.Diff(t, "body", received, "# TYPE value untyped\nvalue{job=\"job\"} 1\n")
+}
@@ -0,0 +1,98 @@
+package metrics
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CPUThrottlingFile is the name of the file, in the metrics directory, where
+// the CPU throttling of a step is recorded.
+const CPUThrottlingFile = "cpu-throttling" + Extension
+
+// cpuStatPaths are the paths, relative to the root of the filesystem, of the
+// CPU statistics of the cgroup of a container with cgroups v2 and v1.
+var cpuStatPaths = []string{
+	"sys/fs/cgroup/cpu.stat",
+	"sys/fs/cgroup/cpu,cpuacct/cpu.stat",
+	"sys/fs/cgroup/cpu/cpu.stat",
+}
+
+// CPUThrottling is how much a container was throttled by its CPU limit.
+type CPUThrottling struct {
+	// Periods is the number of enforcement periods in which the container
+	// was runnable.
+	Periods uint64
+	// ThrottledPeriods is the number of periods in which the container was
+	// throttled.
+	ThrottledPeriods uint64
+	// Throttled is the total time for which the container was throttled.
+	Throttled time.Duration
+}
+
+// ReadCPUThrottling reads the CPU throttling of the cgroup of the current
+// container from the filesystem under `root`.
+func ReadCPUThrottling(root string) (CPUThrottling, error) {
+	for _, path := range cpuStatPaths {
+		f, err := os.Open(filepath.Join(root, path))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return CPUThrottling{}, err
+		}
+		defer f.Close()
+		return parseCPUStat(f.Name(), bufio.NewScanner(f))
+	}
+	return CPUThrottling{}, errors.New("no CPU statistics were found for the cgroup")
+}
+
+func parseCPUStat(path string, scanner *bufio.Scanner) (CPUThrottling, error) {
+	var ret CPUThrottling
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), " ")
+		if !ok {
+			continue
+		}
+		n, err := strconv.ParseUint(strings.TrimSpace(value), 10, 64)
+		if err != nil {
+			return CPUThrottling{}, fmt.Errorf("%s: invalid value for %s: %w", path, key, err)
+		}
+		switch key {
+		case "nr_periods":
+			ret.Periods = n
+		case "nr_throttled":
+			ret.ThrottledPeriods = n
+		case "throttled_usec":
+			// cgroups v2
+			ret.Throttled = time.Duration(n) * time.Microsecond
+		case "throttled_time":
+			// cgroups v1
+			ret.Throttled = time.Duration(n)
+		}
+	}
+	return ret, scanner.Err()
+}
+
+// Write records the throttling as metrics in `dir`, from where they are
+// collected with those published by the step.
+func (t CPUThrottling) Write(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	var b strings.Builder
+	for _, metric := range []struct {
+		name, help, value string
+	}{
+		{name: "step_cpu_periods_total", help: "Number of CPU enforcement periods in which the step was runnable.", value: strconv.FormatUint(t.Periods, 10)},
+		{name: "step_cpu_throttled_periods_total", help: "Number of CPU enforcement periods in which the step was throttled.", value: strconv.FormatUint(t.ThrottledPeriods, 10)},
+		{name: "step_cpu_throttled_seconds_total", help: "Total time for which the step was throttled by its CPU limit.", value: strconv.FormatFloat(t.Throttled.Seconds(), 'f', -1, 64)},
+	} {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s counter\n%s %s\n", metric.name, metric.help, metric.name, metric.name, metric.value)
+	}
+	return os.WriteFile(filepath.Join(dir, CPUThrottlingFile), []byte(b.String()), 0644)
+}
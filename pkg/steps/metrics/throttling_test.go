@@ -0,0 +1,69 @@
+package metrics
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/openshift/ci-tools/pkg/testhelper"
+)
+
+func TestReadCPUThrottling(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		path     string
+		stat     string
+		expected CPUThrottling
+	}{{
+		name:     "cgroups v2",
+		path:     "sys/fs/cgroup/cpu.stat",
+		stat:     "usage_usec 9000000\nuser_usec 6000000\nsystem_usec 3000000\nnr_periods 120\nnr_throttled 30\nthrottled_usec 1500000\n",
+		expected: CPUThrottling{Periods: 120, ThrottledPeriods: 30, Throttled: 1500 * time.Millisecond},
+	}, {
+		name:     "cgroups v1",
+		path:     "sys/fs/cgroup/cpu,cpuacct/cpu.stat",
+		stat:     "nr_periods 120\nnr_throttled 30\nthrottled_time 1500000000\n",
+		expected: CPUThrottling{Periods: 120, ThrottledPeriods: 30, Throttled: 1500 * time.Millisecond},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			root := t.TempDir()
+			path := filepath.Join(root, tc.path)
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(path, []byte(tc.stat), 0644); err != nil {
+				t.Fatal(err)
+			}
+			actual, err := ReadCPUThrottling(root)
+			if err != nil {
+				t.Fatal(err)
+			}
+			testhelper.Diff(t, "throttling", actual, tc.expected)
+		})
+	}
+	if _, err := ReadCPUThrottling(t.TempDir()); err == nil {
+		t.Error("expected an error without CPU statistics")
+	}
+}
+
+func TestCPUThrottlingWrite(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), Dir)
+	throttling := CPUThrottling{Periods: 120, ThrottledPeriods: 30, Throttled: 1500 * time.Millisecond}
+	if err := throttling.Write(dir); err != nil {
+		t.Fatal(err)
+	}
+	families, err := Collect(dir, map[string]string{"step": "step"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	values := map[string]float64{}
+	for _, family := range families {
+		values[family.GetName()] = family.GetMetric()[0].GetCounter().GetValue()
+	}
+	testhelper.Diff(t, "metrics", values, map[string]float64{
+		"step_cpu_periods_total":           120,
+		"step_cpu_throttled_periods_total": 30,
+		"step_cpu_throttled_seconds_total": 1.5,
+	})
+}
@@ -0,0 +1,158 @@
+package multi_stage
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	coreapi "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/util/wait"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/openshift/ci-tools/pkg/junit"
+	"github.com/openshift/ci-tools/pkg/kubernetes"
+	"github.com/openshift/ci-tools/pkg/results"
+)
+
+const anomalyPollInterval = 30 * time.Second
+
+// podAnomalies records the restarts of the containers of a step pod and the
+// warning events emitted for it while it runs.  Restarts of a pod which must
+// never be restarted otherwise only show up as partial logs.
+type podAnomalies struct {
+	lock     sync.Mutex
+	pod      string
+	restarts map[string]int32
+	events   []*anomalyEvent
+}
+
+type anomalyEvent struct {
+	reason, message string
+	count           int32
+}
+
+func newPodAnomalies(pod string) *podAnomalies {
+	return &podAnomalies{pod: pod, restarts: map[string]int32{}}
+}
+
+// observe records the restarts and warning events in the current state of
+// the pod, logging those which were not seen before.
+func (a *podAnomalies) observe(pod *coreapi.Pod, events []coreapi.Event) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if pod != nil {
+		for _, status := range append(pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses...) {
+			if status.RestartCount > a.restarts[status.Name] {
+				logrus.Warnf("Container %s of step %s was restarted (%d restarts).", status.Name, a.pod, status.RestartCount)
+				a.restarts[status.Name] = status.RestartCount
+			}
+		}
+	}
+	for _, event := range events {
+		if event.Type != coreapi.EventTypeWarning {
+			continue
+		}
+		count := event.Count
+		if count == 0 {
+			count = 1
+		}
+		var seen *anomalyEvent
+		for _, e := range a.events {
+			if e.reason == event.Reason && e.message == event.Message {
+				seen = e
+				break
+			}
+		}
+		if seen == nil {
+			logrus.Warnf("Warning event for step %s: %s: %s", a.pod, event.Reason, event.Message)
+			a.events = append(a.events, &anomalyEvent{reason: event.Reason, message: event.Message, count: count})
+		} else if count > seen.count {
+			seen.count = count
+		}
+	}
+}
+
+// restarted returns the containers which were restarted, sorted by name.
+func (a *podAnomalies) restarted() []string {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	var ret []string
+	for name, count := range a.restarts {
+		if count != 0 {
+			ret = append(ret, name)
+		}
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+func (a *podAnomalies) String() string {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	var names []string
+	for name := range a.restarts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var lines []string
+	for _, name := range names {
+		lines = append(lines, fmt.Sprintf("container %s has %d restarts", name, a.restarts[name]))
+	}
+	for _, e := range a.events {
+		lines = append(lines, fmt.Sprintf("warning event %dx %s: %s", e.count, e.reason, e.message))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// testCase returns a jUnit test case listing the anomalies of the pod, which
+// fails if the step does not tolerate restarts, or nil if there were none.
+func (a *podAnomalies) testCase(name string, failOnRestart bool) (*junit.TestCase, error) {
+	output := a.String()
+	if output == "" {
+		return nil, nil
+	}
+	testCase := &junit.TestCase{Name: name, SystemOut: output}
+	var err error
+	if restarted := a.restarted(); failOnRestart && len(restarted) != 0 {
+		err = &results.InfrastructureError{Err: results.ForReason("step_restarted").ForError(fmt.Errorf("containers of step %s were restarted: %s", a.pod, strings.Join(restarted, ", ")))}
+		testCase.FailureOutput = &junit.FailureOutput{Output: err.Error()}
+	}
+	return testCase, err
+}
+
+// watchPodAnomalies polls a running pod and its events until the context is
+// done, recording any anomalies.
+func watchPodAnomalies(ctx context.Context, client kubernetes.PodClient, namespace, name string, interval time.Duration, anomalies *podAnomalies) {
+	_ = wait.PollUntilContextCancel(ctx, interval, false, func(ctx context.Context) (bool, error) {
+		pod := &coreapi.Pod{}
+		if err := client.Get(ctx, ctrlruntimeclient.ObjectKey{Namespace: namespace, Name: name}, pod); err != nil {
+			logrus.WithError(err).Debugf("Failed to get pod %s to watch for anomalies", name)
+			return false, nil
+		}
+		anomalies.observe(pod, podEvents(ctx, client, pod))
+		return false, nil
+	})
+}
+
+// podEvents lists the events of a pod, tolerating failures as the events are
+// only informational.
+func podEvents(ctx context.Context, client ctrlruntimeclient.Client, pod *coreapi.Pod) []coreapi.Event {
+	if pod.UID == "" {
+		return nil
+	}
+	events := &coreapi.EventList{}
+	if err := client.List(ctx, events, &ctrlruntimeclient.ListOptions{
+		Namespace:     pod.Namespace,
+		FieldSelector: fields.OneTermEqualSelector("involvedObject.uid", string(pod.UID)),
+	}); err != nil {
+		logrus.WithError(err).Debugf("Failed to list the events of pod %s", pod.Name)
+		return nil
+	}
+	return events.Items
+}
@@ -0,0 +1,77 @@
+package multi_stage
+
+import (
+	"testing"
+
+	coreapi "k8s.io/api/core/v1"
+
+	"github.com/openshift/ci-tools/pkg/junit"
+	"github.com/openshift/ci-tools/pkg/testhelper"
+)
+
+func TestPodAnomalies(t *testing.T) {
+	restarted := func(test, sidecar int32) *coreapi.Pod {
+		return &coreapi.Pod{Status: coreapi.PodStatus{
+			InitContainerStatuses: []coreapi.ContainerStatus{{Name: "cp-secret-wrapper"}},
+			ContainerStatuses: []coreapi.ContainerStatus{
+				{Name: "test", RestartCount: test},
+				{Name: "sidecar", RestartCount: sidecar},
+			},
+		}}
+	}
+	events := []coreapi.Event{
+		{Type: coreapi.EventTypeNormal, Reason: "Pulled", Message: "image pulled"},
+		{Type: coreapi.EventTypeWarning, Reason: "BackOff", Message: "back-off restarting failed container", Count: 2},
+		{Type: coreapi.EventTypeWarning, Reason: "FailedMount", Message: "secret not found"},
+	}
+	for _, tc := range []struct {
+		name          string
+		observe       func(*podAnomalies)
+		failOnRestart bool
+		expected      *junit.TestCase
+		expectedErr   string
+	}{{
+		name:    "no anomalies",
+		observe: func(a *podAnomalies) { a.observe(restarted(0, 0), events[:1]) },
+	}, {
+		name: "restarts and warning events are reported",
+		observe: func(a *podAnomalies) {
+			a.observe(restarted(1, 0), events)
+			a.observe(restarted(2, 0), append(events, coreapi.Event{Type: coreapi.EventTypeWarning, Reason: "BackOff", Message: "back-off restarting failed container", Count: 5}))
+		},
+		expected: &junit.TestCase{
+			Name:      "anomalies",
+			SystemOut: "container test has 2 restarts\nwarning event 5x BackOff: back-off restarting failed container\nwarning event 1x FailedMount: secret not found",
+		},
+	}, {
+		name:          "warning events do not fail the step",
+		observe:       func(a *podAnomalies) { a.observe(restarted(0, 0), events) },
+		failOnRestart: true,
+		expected: &junit.TestCase{
+			Name:      "anomalies",
+			SystemOut: "warning event 2x BackOff: back-off restarting failed container\nwarning event 1x FailedMount: secret not found",
+		},
+	}, {
+		name:          "restarts fail the step",
+		observe:       func(a *podAnomalies) { a.observe(restarted(1, 3), nil) },
+		failOnRestart: true,
+		expected: &junit.TestCase{
+			Name:          "anomalies",
+			SystemOut:     "container sidecar has 3 restarts\ncontainer test has 1 restarts",
+			FailureOutput: &junit.FailureOutput{Output: "containers of step test-e2e were restarted: sidecar, test"},
+		},
+		expectedErr: "containers of step test-e2e were restarted: sidecar, test",
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			a := newPodAnomalies("test-e2e")
+			tc.observe(a)
+			testCase, err := a.testCase("anomalies", tc.failOnRestart)
+			var actualErr string
+			if err != nil {
+				actualErr = err.Error()
+			}
+			testhelper.Diff(t, "test case", testCase, tc.expected)
+			testhelper.Diff(t, "error", actualErr, tc.expectedErr)
+		})
+	}
+}
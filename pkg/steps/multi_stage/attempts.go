@@ -0,0 +1,77 @@
+package multi_stage
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/openshift/ci-tools/pkg/junit"
+)
+
+const (
+	// AttemptProperty is the name of the jUnit property added to the test
+	// cases of steps which were retried, with the index of the attempt which
+	// produced them, starting at 1.
+	AttemptProperty = "step_attempt"
+	// RetryVerdictProperty is the name of the jUnit property of the test case
+	// which consolidates the attempts of a retried step, with its final
+	// result as the value.
+	RetryVerdictProperty = "step_retry_verdict"
+)
+
+const (
+	// verdictPassedOnRetry is the verdict of steps which failed at first
+	// and passed when executed again, i.e. flakes.
+	verdictPassedOnRetry = "passed_on_retry"
+	// verdictFailed is the verdict of steps which failed in all attempts.
+	verdictFailed = "failed"
+)
+
+// stepAttempt is one execution of a step pod.
+type stepAttempt struct {
+	started  time.Time
+	duration time.Duration
+	err      error
+	// tests are the test cases reported for the pod by the attempt.
+	tests []*junit.TestCase
+}
+
+// recordAttempts reports each attempt of a step which was retried as its own
+// jUnit test case, tags the test cases of the step with the attempt which
+// produced them and adds a test case with the final verdict, so that failures
+// which passed when retried can be distinguished from persistent ones.
+func (s *multiStageTestStep) recordAttempts(podName string, attempts []stepAttempt) {
+	if len(attempts) < 2 {
+		return
+	}
+	prefix := fmt.Sprintf("%s - %s ", s.Description(), podName)
+	s.subLock.Lock()
+	defer s.subLock.Unlock()
+	for i, attempt := range attempts {
+		index := strconv.Itoa(i + 1)
+		for _, test := range attempt.tests {
+			test.Properties = append(test.Properties, &junit.TestSuiteProperty{Name: AttemptProperty, Value: index})
+		}
+	}
+	for i, attempt := range attempts {
+		test := &junit.TestCase{
+			Name:       fmt.Sprintf("%sattempt %d", prefix, i+1),
+			Duration:   attempt.duration.Seconds(),
+			Properties: []*junit.TestSuiteProperty{{Name: AttemptProperty, Value: strconv.Itoa(i + 1)}},
+		}
+		if attempt.err != nil {
+			test.FailureOutput = &junit.FailureOutput{Output: attempt.err.Error()}
+		}
+		s.subTests = append(s.subTests, test)
+	}
+	final := attempts[len(attempts)-1]
+	verdict := &junit.TestCase{Name: prefix + "retries"}
+	if final.err == nil {
+		verdict.Properties = []*junit.TestSuiteProperty{{Name: RetryVerdictProperty, Value: verdictPassedOnRetry}}
+		verdict.SystemOut = fmt.Sprintf("step %s passed on attempt %d of %d", podName, len(attempts), len(attempts))
+	} else {
+		verdict.Properties = []*junit.TestSuiteProperty{{Name: RetryVerdictProperty, Value: verdictFailed}}
+		verdict.FailureOutput = &junit.FailureOutput{Output: fmt.Sprintf("step %s failed in all %d attempts", podName, len(attempts))}
+	}
+	s.subTests = append(s.subTests, verdict)
+}
@@ -0,0 +1,72 @@
+package multi_stage
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/openshift/ci-tools/pkg/junit"
+	"github.com/openshift/ci-tools/pkg/testhelper"
+)
+
+func TestRecordAttempts(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		errs     []error
+		expected []*junit.TestCase
+	}{{
+		name: "step which was not retried",
+		errs: []error{errors.New("oops")},
+		expected: []*junit.TestCase{
+			{Name: "Run multi-stage test e2e - e2e-test container test", FailureOutput: &junit.FailureOutput{Output: "oops"}},
+		},
+	}, {
+		name: "step which passed when retried",
+		errs: []error{errors.New("flake"), nil},
+		expected: []*junit.TestCase{
+			{Name: "Run multi-stage test e2e - e2e-test container test", FailureOutput: &junit.FailureOutput{Output: "flake"}, Properties: []*junit.TestSuiteProperty{{Name: AttemptProperty, Value: "1"}}},
+			{Name: "Run multi-stage test e2e - e2e-other container test"},
+			{Name: "Run multi-stage test e2e - e2e-test container test", Properties: []*junit.TestSuiteProperty{{Name: AttemptProperty, Value: "2"}}},
+			{Name: "Run multi-stage test e2e - e2e-other container test"},
+			{Name: "Run multi-stage test e2e - e2e-test attempt 1", Duration: 60, FailureOutput: &junit.FailureOutput{Output: "flake"}, Properties: []*junit.TestSuiteProperty{{Name: AttemptProperty, Value: "1"}}},
+			{Name: "Run multi-stage test e2e - e2e-test attempt 2", Duration: 60, Properties: []*junit.TestSuiteProperty{{Name: AttemptProperty, Value: "2"}}},
+			{Name: "Run multi-stage test e2e - e2e-test retries", SystemOut: "step e2e-test passed on attempt 2 of 2", Properties: []*junit.TestSuiteProperty{{Name: RetryVerdictProperty, Value: "passed_on_retry"}}},
+		},
+	}, {
+		name: "step which failed in all attempts",
+		errs: []error{errors.New("flake"), errors.New("failure")},
+		expected: []*junit.TestCase{
+			{Name: "Run multi-stage test e2e - e2e-test container test", FailureOutput: &junit.FailureOutput{Output: "flake"}, Properties: []*junit.TestSuiteProperty{{Name: AttemptProperty, Value: "1"}}},
+			{Name: "Run multi-stage test e2e - e2e-other container test"},
+			{Name: "Run multi-stage test e2e - e2e-test container test", FailureOutput: &junit.FailureOutput{Output: "failure"}, Properties: []*junit.TestSuiteProperty{{Name: AttemptProperty, Value: "2"}}},
+			{Name: "Run multi-stage test e2e - e2e-other container test"},
+			{Name: "Run multi-stage test e2e - e2e-test attempt 1", Duration: 60, FailureOutput: &junit.FailureOutput{Output: "flake"}, Properties: []*junit.TestSuiteProperty{{Name: AttemptProperty, Value: "1"}}},
+			{Name: "Run multi-stage test e2e - e2e-test attempt 2", Duration: 60, FailureOutput: &junit.FailureOutput{Output: "failure"}, Properties: []*junit.TestSuiteProperty{{Name: AttemptProperty, Value: "2"}}},
+			{Name: "Run multi-stage test e2e - e2e-test retries", FailureOutput: &junit.FailureOutput{Output: "step e2e-test failed in all 2 attempts"}, Properties: []*junit.TestSuiteProperty{{Name: RetryVerdictProperty, Value: "failed"}}},
+		},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			s := multiStageTestStep{name: "e2e", subLock: &sync.Mutex{}}
+			var attempts []stepAttempt
+			for _, err := range tc.errs {
+				attempt := stepAttempt{started: time.Now()}
+				test := &junit.TestCase{Name: "Run multi-stage test e2e - e2e-test container test"}
+				if err != nil {
+					test.FailureOutput = &junit.FailureOutput{Output: err.Error()}
+				}
+				// the test cases of other steps which run at the same time
+				// are not attributed to the attempt
+				other := &junit.TestCase{Name: "Run multi-stage test e2e - e2e-other container test"}
+				s.subTests = append(s.subTests, test)
+				if len(tc.errs) > 1 {
+					s.subTests = append(s.subTests, other)
+				}
+				attempt.duration, attempt.err, attempt.tests = time.Minute, err, []*junit.TestCase{test}
+				attempts = append(attempts, attempt)
+			}
+			s.recordAttempts("e2e-test", attempts)
+			testhelper.Diff(t, "test cases", s.subTests, tc.expected)
+		})
+	}
+}
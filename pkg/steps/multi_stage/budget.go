@@ -0,0 +1,90 @@
+package multi_stage
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	coreapi "k8s.io/api/core/v1"
+
+	"github.com/openshift/ci-tools/pkg/api"
+)
+
+// BudgetArtifact is the name of the artifact file, relative to the test's
+// artifact directory, which compares the duration of each step to its
+// timeout.
+const BudgetArtifact = "budget.json"
+
+// budgetWarningThreshold is the fraction of its timeout above which a step is
+// considered at risk of timing out.
+const budgetWarningThreshold = 0.9
+
+// budgetUse records how much of its timeout a step used.
+type budgetUse struct {
+	Step     string `json:"step"`
+	Timeout  string `json:"timeout"`
+	Duration string `json:"duration"`
+	// Utilization is the percentage of the timeout used by the step.
+	Utilization float64 `json:"utilization"`
+	// AtRisk is set when the step used more than 90% of its timeout.
+	AtRisk bool `json:"at_risk,omitempty"`
+}
+
+// recordBudget records the duration of the step of a pod relative to its
+// timeout.  The duration of the test container is used when it is known,
+// since the timeout does not include the time spent scheduling the pod.
+func (s *multiStageTestStep) recordBudget(pod *coreapi.Pod, duration time.Duration) {
+	step, ok := s.stepFor(pod)
+	if !ok {
+		return
+	}
+	if d := testContainerDuration(pod); d > 0 {
+		duration = d
+	}
+	timeout := s.stepTimeout(&step)
+	utilization := float64(duration) / float64(timeout)
+	use := budgetUse{
+		Step:        strings.TrimPrefix(pod.Name, s.name+"-"),
+		Timeout:     timeout.String(),
+		Duration:    duration.Truncate(time.Second).String(),
+		Utilization: float64(int(utilization*1000)) / 10,
+		AtRisk:      utilization > budgetWarningThreshold,
+	}
+	if use.AtRisk {
+		logrus.Warnf("Step %s used %.1f%% of its %s timeout, consider increasing the timeout before it is exceeded.", pod.Name, use.Utilization, use.Timeout)
+	}
+	s.subLock.Lock()
+	s.budget = append(s.budget, use)
+	s.subLock.Unlock()
+}
+
+func testContainerDuration(pod *coreapi.Pod) time.Duration {
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.Name != containerName {
+			continue
+		}
+		if t := status.State.Terminated; t != nil && !t.StartedAt.IsZero() && !t.FinishedAt.IsZero() {
+			return t.FinishedAt.Sub(t.StartedAt.Time)
+		}
+	}
+	return 0
+}
+
+// saveBudgetReport writes the budget utilization of the steps of the test to
+// its artifact directory.
+func (s *multiStageTestStep) saveBudgetReport() error {
+	s.subLock.Lock()
+	defer s.subLock.Unlock()
+	if len(s.budget) == 0 {
+		return nil
+	}
+	data, err := json.MarshalIndent(s.budget, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal budget report: %w", err)
+	}
+	return api.SaveArtifact(s.censor, path.Join(s.name, BudgetArtifact), data)
+}
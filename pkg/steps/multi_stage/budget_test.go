@@ -0,0 +1,66 @@
+package multi_stage
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	coreapi "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/secrets"
+	base_steps "github.com/openshift/ci-tools/pkg/steps"
+	"github.com/openshift/ci-tools/pkg/testhelper"
+)
+
+func TestRecordBudget(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("ARTIFACTS", dir)
+	censor := secrets.NewDynamicCensor()
+	s := multiStageTestStep{
+		name:    "test",
+		subLock: &sync.Mutex{},
+		pre:     []api.LiteralTestStep{{As: "install", Timeout: &prowapi.Duration{Duration: time.Hour}}},
+		test:    []api.LiteralTestStep{{As: "e2e"}},
+		flags:   containerOnly,
+		censor:  &censor,
+	}
+	podFor := func(step string) *coreapi.Pod {
+		return &coreapi.Pod{ObjectMeta: meta.ObjectMeta{
+			Name:   "test-" + step,
+			Labels: map[string]string{base_steps.LabelMetadataStep: step},
+		}}
+	}
+	install := podFor("install")
+	start := meta.NewTime(time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC))
+	install.Status.ContainerStatuses = []coreapi.ContainerStatus{{
+		Name: containerName,
+		State: coreapi.ContainerState{Terminated: &coreapi.ContainerStateTerminated{
+			StartedAt:  start,
+			FinishedAt: meta.NewTime(start.Add(57 * time.Minute)),
+		}},
+	}}
+	s.recordBudget(install, 2*time.Hour)
+	s.recordBudget(podFor("e2e"), 15*time.Minute)
+	s.recordBudget(podFor("observer"), time.Minute)
+	if err := s.saveBudgetReport(); err != nil {
+		t.Fatal(err)
+	}
+	report, err := os.ReadFile(filepath.Join(dir, "test", BudgetArtifact))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var actual []budgetUse
+	if err := json.Unmarshal(report, &actual); err != nil {
+		t.Fatal(err)
+	}
+	testhelper.Diff(t, "budget report", actual, []budgetUse{
+		{Step: "install", Timeout: "1h0m0s", Duration: "57m0s", Utilization: 95, AtRisk: true},
+		{Step: "e2e", Timeout: "1h0m0s", Duration: "15m0s", Utilization: 25},
+	})
+}
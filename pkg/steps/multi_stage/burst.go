@@ -0,0 +1,37 @@
+package multi_stage
+
+import (
+	"github.com/sirupsen/logrus"
+
+	coreapi "k8s.io/api/core/v1"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	base_steps "github.com/openshift/ci-tools/pkg/steps"
+)
+
+func isBurst(step *api.LiteralTestStep) bool {
+	return step.Burst != nil && *step.Burst
+}
+
+// capBurstLimits lowers the limits of a step which bursts to the caps of the
+// build farm.  Requests are lowered as well if they would exceed the limits.
+func capBurstLimits(step string, resources *coreapi.ResourceRequirements, caps coreapi.ResourceList) {
+	for name, limit := range resources.Limits {
+		max, ok := caps[name]
+		if !ok || limit.Cmp(max) <= 0 {
+			continue
+		}
+		logrus.Warnf("Capping the %s limit of step %s from %s to %s, the maximum allowed for steps with `burst`.", name, step, limit.String(), max.String())
+		resources.Limits[name] = max.DeepCopy()
+		if request, ok := resources.Requests[name]; ok && request.Cmp(max) > 0 {
+			resources.Requests[name] = max.DeepCopy()
+		}
+	}
+}
+
+// markBurst keeps the resources of the pod of a step which bursts as
+// generated: the pod scaler would otherwise raise its requests and remove its
+// CPU limit.
+func markBurst(pod *coreapi.Pod) {
+	pod.Annotations[base_steps.AnnotationPodScalerScale] = "false"
+}
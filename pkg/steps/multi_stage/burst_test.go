@@ -0,0 +1,100 @@
+package multi_stage
+
+import (
+	"strings"
+	"testing"
+
+	coreapi "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	prowdapi "k8s.io/test-infra/prow/pod-utils/downwardapi"
+	utilpointer "k8s.io/utils/pointer"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	base_steps "github.com/openshift/ci-tools/pkg/steps"
+	"github.com/openshift/ci-tools/pkg/testhelper"
+)
+
+func TestGeneratePodsBurst(t *testing.T) {
+	test := api.TestStepConfiguration{
+		As: "test",
+		MultiStageTestConfigurationLiteral: &api.MultiStageTestConfigurationLiteral{
+			Test: []api.LiteralTestStep{{
+				As:       "compile",
+				From:     "src",
+				Commands: "make",
+				Resources: api.ResourceRequirements{
+					Requests: api.ResourceList{"cpu": "500m", "memory": "12Gi"},
+					Limits:   api.ResourceList{"cpu": "16", "memory": "16Gi"},
+				},
+				Burst: utilpointer.Bool(true),
+			}, {
+				As:        "steady",
+				From:      "src",
+				Commands:  "make",
+				Resources: api.ResourceRequirements{Requests: api.ResourceList{"cpu": "1"}},
+			}},
+		},
+	}
+	config := api.ReleaseBuildConfiguration{Tests: []api.TestStepConfiguration{test}}
+	jobSpec := api.JobSpec{JobSpec: prowdapi.JobSpec{
+		Job:     "job",
+		BuildID: "build id",
+		Refs:    &prowapi.Refs{Org: "org", Repo: "repo", BaseRef: "base ref", BaseSHA: "base sha"},
+		Type:    "postsubmit",
+		DecorationConfig: &prowapi.DecorationConfig{
+			UtilityImages: &prowapi.UtilityImages{Sidecar: "sidecar", Entrypoint: "entrypoint"},
+		},
+	}}
+	jobSpec.SetNamespace("namespace")
+	caps := coreapi.ResourceList{
+		coreapi.ResourceCPU:    resource.MustParse("8"),
+		coreapi.ResourceMemory: resource.MustParse("8Gi"),
+	}
+	step := newMultiStageTestStep(test, &config, nil, nil, &jobSpec, nil, "node-name", "", nil, Options{BurstLimitCaps: caps})
+	pods, _, err := step.generatePods(step.test, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pods) != 2 {
+		t.Fatalf("expected two pods, got %d", len(pods))
+	}
+	burst, steady := pods[0], pods[1]
+	testhelper.Diff(t, "burst annotation", burst.Annotations[base_steps.AnnotationPodScalerScale], "false")
+	if _, ok := steady.Annotations[base_steps.AnnotationPodScalerScale]; ok {
+		t.Errorf("unexpected pod scaler annotation on a step which does not burst")
+	}
+	expected := coreapi.ResourceRequirements{
+		Requests: coreapi.ResourceList{
+			coreapi.ResourceCPU:    resource.MustParse("500m"),
+			coreapi.ResourceMemory: resource.MustParse("8Gi"),
+		},
+		Limits: coreapi.ResourceList{
+			coreapi.ResourceCPU:    resource.MustParse("8"),
+			coreapi.ResourceMemory: resource.MustParse("8Gi"),
+		},
+	}
+	resources := burst.Spec.Containers[0].Resources
+	for _, list := range []struct {
+		name             string
+		actual, expected coreapi.ResourceList
+	}{
+		{name: "requests", actual: resources.Requests, expected: expected.Requests},
+		{name: "limits", actual: resources.Limits, expected: expected.Limits},
+	} {
+		for name, quantity := range list.expected {
+			if actual := list.actual[name]; actual.Cmp(quantity) != 0 {
+				t.Errorf("expected %s %s to be %s, got %s", list.name, name, quantity.String(), actual.String())
+			}
+		}
+	}
+	args := func(pod coreapi.Pod) string {
+		return strings.Join(pod.Spec.Containers[0].Args, " ")
+	}
+	if !strings.Contains(args(burst), "--record-cpu-throttling") {
+		t.Errorf("expected the CPU throttling of a step which bursts to be recorded, got arguments %s", args(burst))
+	}
+	if strings.Contains(args(steady), "--record-cpu-throttling") {
+		t.Errorf("unexpected recording of the CPU throttling of a step which does not burst")
+	}
+}
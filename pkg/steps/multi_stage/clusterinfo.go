@@ -0,0 +1,65 @@
+package multi_stage
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+
+	configv1 "github.com/openshift/api/config/v1"
+	coreapi "k8s.io/api/core/v1"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Variables with details of the cluster under test, resolved once the `pre`
+// phase of a test with a cluster profile has completed.
+const (
+	ConsoleURLEnv = "CONSOLE_URL"
+	APIURLEnv     = "API_URL"
+	OCPVersionEnv = "OCP_VERSION"
+)
+
+// resolveClusterInfo reads the details of the cluster installed in the `pre`
+// phase, which are then exposed to the following steps, so that they do not
+// each have to query them.  It is best effort: details which cannot be
+// resolved are not set.
+func (s *multiStageTestStep) resolveClusterInfo(ctx context.Context) {
+	if s.profile == "" || s.rendering {
+		return
+	}
+	client, err := s.clusterClient(ctx)
+	if err != nil {
+		logrus.WithError(err).Debugf("Not resolving the cluster details of test %s.", s.name)
+		return
+	}
+	s.clusterInfo = clusterInfo(ctx, client)
+}
+
+// clusterInfo returns the details of a cluster as variables, skipping those
+// which cannot be read, e.g. the console URL when the console is disabled.
+func clusterInfo(ctx context.Context, client ctrlruntimeclient.Client) []coreapi.EnvVar {
+	var ret []coreapi.EnvVar
+	add := func(name, value string) {
+		if value != "" {
+			ret = append(ret, coreapi.EnvVar{Name: name, Value: value})
+		}
+	}
+	console := &configv1.Console{}
+	if err := client.Get(ctx, ctrlruntimeclient.ObjectKey{Name: "cluster"}, console); err != nil {
+		logrus.WithError(err).Debug("Failed to get the console configuration of the cluster.")
+	} else {
+		add(ConsoleURLEnv, console.Status.ConsoleURL)
+	}
+	infra := &configv1.Infrastructure{}
+	if err := client.Get(ctx, ctrlruntimeclient.ObjectKey{Name: "cluster"}, infra); err != nil {
+		logrus.WithError(err).Debug("Failed to get the infrastructure configuration of the cluster.")
+	} else {
+		add(APIURLEnv, infra.Status.APIServerURL)
+	}
+	cv := &configv1.ClusterVersion{}
+	if err := client.Get(ctx, ctrlruntimeclient.ObjectKey{Name: "version"}, cv); err != nil {
+		logrus.WithError(err).Debug("Failed to get the version of the cluster.")
+	} else {
+		add(OCPVersionEnv, cv.Status.Desired.Version)
+	}
+	return ret
+}
@@ -0,0 +1,76 @@
+package multi_stage
+
+import (
+	"context"
+	"testing"
+
+	configv1 "github.com/openshift/api/config/v1"
+	coreapi "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilpointer "k8s.io/utils/pointer"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	fakectrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/testhelper"
+)
+
+func TestClusterInfo(t *testing.T) {
+	console := &configv1.Console{
+		ObjectMeta: meta.ObjectMeta{Name: "cluster"},
+		Status:     configv1.ConsoleStatus{ConsoleURL: "https://console-openshift-console.apps.ci.example.com"},
+	}
+	infra := &configv1.Infrastructure{
+		ObjectMeta: meta.ObjectMeta{Name: "cluster"},
+		Status:     configv1.InfrastructureStatus{APIServerURL: "https://api.ci.example.com:6443"},
+	}
+	version := &configv1.ClusterVersion{
+		ObjectMeta: meta.ObjectMeta{Name: "version"},
+		Status:     configv1.ClusterVersionStatus{Desired: configv1.Release{Version: "4.15.0"}},
+	}
+	for _, tc := range []struct {
+		name     string
+		objects  []ctrlruntimeclient.Object
+		expected []coreapi.EnvVar
+	}{{
+		name:    "all details are resolved",
+		objects: []ctrlruntimeclient.Object{console, infra, version},
+		expected: []coreapi.EnvVar{
+			{Name: ConsoleURLEnv, Value: "https://console-openshift-console.apps.ci.example.com"},
+			{Name: APIURLEnv, Value: "https://api.ci.example.com:6443"},
+			{Name: OCPVersionEnv, Value: "4.15.0"},
+		},
+	}, {
+		name:    "console disabled",
+		objects: []ctrlruntimeclient.Object{infra, version},
+		expected: []coreapi.EnvVar{
+			{Name: APIURLEnv, Value: "https://api.ci.example.com:6443"},
+			{Name: OCPVersionEnv, Value: "4.15.0"},
+		},
+	}, {
+		name: "nothing is resolved",
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			client := fakectrlruntimeclient.NewClientBuilder().WithScheme(healthScheme(t)).WithObjects(tc.objects...).Build()
+			testhelper.Diff(t, "variables", clusterInfo(context.Background(), client), tc.expected)
+		})
+	}
+}
+
+func TestGenerateParamsClusterInfo(t *testing.T) {
+	s := multiStageTestStep{
+		env:         api.TestEnvironment{OCPVersionEnv: "4.14"},
+		clusterInfo: []coreapi.EnvVar{{Name: ConsoleURLEnv, Value: "https://console"}, {Name: OCPVersionEnv, Value: "4.15.0"}},
+	}
+	params := []api.StepParameter{
+		{Name: ConsoleURLEnv, Default: utilpointer.String("")},
+		{Name: OCPVersionEnv},
+		{Name: "OTHER", Default: utilpointer.String("value")},
+	}
+	expected := []coreapi.EnvVar{
+		{Name: ConsoleURLEnv, Value: "https://console"},
+		{Name: OCPVersionEnv, Value: "4.14"},
+		{Name: "OTHER", Value: "value"},
+	}
+	testhelper.Diff(t, "parameters", s.generateParams(params), expected)
+}
@@ -0,0 +1,115 @@
+package multi_stage
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"strconv"
+	"strings"
+
+	coreapi "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	"github.com/openshift/ci-tools/pkg/api"
+)
+
+const (
+	// ClusterNameEnv is the env we use to expose the name of the cluster
+	// created with the cluster profile.
+	ClusterNameEnv = "CLUSTER_NAME"
+	// BaseDomainEnv is the env we use to expose the base domain of the
+	// cluster created with the cluster profile.
+	BaseDomainEnv = "BASE_DOMAIN"
+	// baseDomainPath is the path of the file in the cluster profile which
+	// holds the base domain of the clusters created with it.
+	baseDomainPath = "base-domain"
+	// clusterNameMaxLengthPath is the path of the file in the cluster profile
+	// which holds the maximum length of the names of the clusters created
+	// with it, for platforms with stricter limits than the default.
+	clusterNameMaxLengthPath = "cluster-name-max-length"
+	// defaultClusterNameMaxLength is the length to which the installer
+	// truncates cluster names when it derives the infrastructure ID, which
+	// names most cloud resources, so names which fit are never ambiguous.
+	defaultClusterNameMaxLength = 21
+	// minClusterNameLength is the shortest limit for which names with enough
+	// entropy can be generated.
+	minClusterNameLength = 8
+	// clusterNameHashLength is the length of the suffix which makes names
+	// unique within a namespace.
+	clusterNameHashLength = 5
+)
+
+// defaultBaseDomains are the base domains used in CI for cluster types whose
+// profiles do not declare one.
+var defaultBaseDomains = map[string]string{
+	api.ClusterProfileAWS.ClusterType():    "origin-ci-int-aws.dev.rhcloud.com",
+	api.ClusterProfileAzure4.ClusterType(): "ci.azure.devcluster.openshift.com",
+	api.ClusterProfileGCP.ClusterType():    "origin-ci-int-gce.dev.openshift.com",
+}
+
+// clusterNaming holds how the cluster created with a profile is named.
+type clusterNaming struct {
+	baseDomain string
+	maxLength  int
+}
+
+// readClusterNaming reads the base domain and the limit of the length of
+// cluster names from the profile, defaulting them for the cluster type.
+func (s *multiStageTestStep) readClusterNaming(secret *coreapi.Secret) error {
+	naming := clusterNaming{
+		baseDomain: defaultBaseDomains[s.profile.ClusterType()],
+		maxLength:  defaultClusterNameMaxLength,
+	}
+	if bytes, ok := secret.Data[baseDomainPath]; ok {
+		naming.baseDomain = strings.TrimSpace(string(bytes))
+		if errs := validation.IsDNS1123Subdomain(naming.baseDomain); len(errs) != 0 {
+			return fmt.Errorf("invalid base domain %q: %s", naming.baseDomain, strings.Join(errs, ", "))
+		}
+	}
+	if bytes, ok := secret.Data[clusterNameMaxLengthPath]; ok {
+		value := strings.TrimSpace(string(bytes))
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid maximum cluster name length %q: %w", value, err)
+		}
+		if n < minClusterNameLength || n > validation.DNS1035LabelMaxLength {
+			return fmt.Errorf("maximum cluster name length must be between %d and %d, got %d", minClusterNameLength, validation.DNS1035LabelMaxLength, n)
+		}
+		naming.maxLength = n
+	}
+	s.clusterNaming = &naming
+	return nil
+}
+
+// clusterName returns the name of the cluster of a test.  Names are made of
+// the namespace, which is unique to the inputs of the job, and a hash of the
+// job and the test, so that tests which share a namespace do not collide.
+// Names which would be longer than the limit, or which are not valid DNS
+// labels, are replaced with a hash of all of them.
+func clusterName(namespace, job, test string, maxLength int) string {
+	hash := fmt.Sprintf("%x", sha256.Sum256([]byte(job+"\x00"+test)))
+	if name := namespace + "-" + hash[:clusterNameHashLength]; len(name) <= maxLength && len(validation.IsDNS1035Label(name)) == 0 {
+		return name
+	}
+	hash = fmt.Sprintf("%x", sha256.Sum256([]byte(namespace+"\x00"+job+"\x00"+test)))
+	return "ci-" + hash[:maxLength-len("ci-")]
+}
+
+// clusterEnv returns the name and base domain of the cluster of a test,
+// verifying that the host names of the cluster fit in DNS names.
+func (s *multiStageTestStep) clusterEnv() ([]coreapi.EnvVar, error) {
+	naming := clusterNaming{maxLength: defaultClusterNameMaxLength, baseDomain: defaultBaseDomains[s.profile.ClusterType()]}
+	if s.clusterNaming != nil {
+		naming = *s.clusterNaming
+	}
+	name := clusterName(s.jobSpec.Namespace(), s.jobSpec.Job+s.jobSpec.TargetAdditionalSuffix, s.name, naming.maxLength)
+	ret := []coreapi.EnvVar{{Name: ClusterNameEnv, Value: name}}
+	if naming.baseDomain == "" {
+		return ret, nil
+	}
+	// the longest name under the base domain is that of the routes of
+	// applications, e.g. console-openshift-console.apps.<name>.<base domain>
+	if host := fmt.Sprintf("*.apps.%s.%s", name, naming.baseDomain); len(host) > validation.DNS1123SubdomainMaxLength {
+		return nil, fmt.Errorf("base domain %s is too long: host names of cluster %s would be longer than %d characters", naming.baseDomain, name, validation.DNS1123SubdomainMaxLength)
+	}
+	return append(ret, coreapi.EnvVar{Name: BaseDomainEnv, Value: naming.baseDomain}), nil
+}
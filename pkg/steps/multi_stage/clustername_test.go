@@ -0,0 +1,138 @@
+package multi_stage
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	coreapi "k8s.io/api/core/v1"
+	prowdapi "k8s.io/test-infra/prow/pod-utils/downwardapi"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/testhelper"
+)
+
+func TestClusterName(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		namespace string
+		maxLength int
+		expected  string
+	}{{
+		name:      "namespace and hash",
+		namespace: "ci-op-abcdefgh",
+		maxLength: defaultClusterNameMaxLength,
+		expected:  "ci-op-abcdefgh-e4843",
+	}, {
+		name:      "too long for the platform",
+		namespace: "ci-op-abcdefgh",
+		maxLength: 14,
+		expected:  "ci-c7628f0505c",
+	}, {
+		name:      "namespace is not a DNS label",
+		namespace: "1-namespace",
+		maxLength: defaultClusterNameMaxLength,
+		expected:  "ci-b90db75ae2d0ae8006",
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			actual := clusterName(tc.namespace, "pull-ci-org-repo-master-e2e", "e2e", tc.maxLength)
+			testhelper.Diff(t, "name", actual, tc.expected)
+			if len(actual) > tc.maxLength {
+				t.Errorf("name %s is longer than %d characters", actual, tc.maxLength)
+			}
+		})
+	}
+	if clusterName("ci-op-abcdefgh", "job", "e2e", defaultClusterNameMaxLength) == clusterName("ci-op-abcdefgh", "job", "e2e-upgrade", defaultClusterNameMaxLength) {
+		t.Error("tests in the same namespace must have different cluster names")
+	}
+}
+
+func TestReadClusterNaming(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		profile     api.ClusterProfile
+		data        map[string][]byte
+		expected    *clusterNaming
+		expectedErr string
+	}{{
+		name:     "defaults for the cluster type",
+		profile:  api.ClusterProfileGCP,
+		expected: &clusterNaming{baseDomain: "origin-ci-int-gce.dev.openshift.com", maxLength: defaultClusterNameMaxLength},
+	}, {
+		name:     "no default base domain",
+		profile:  api.ClusterProfileVSphere,
+		expected: &clusterNaming{maxLength: defaultClusterNameMaxLength},
+	}, {
+		name:    "profile overrides",
+		profile: api.ClusterProfileAWS,
+		data: map[string][]byte{
+			baseDomainPath:           []byte("ci.example.com\n"),
+			clusterNameMaxLengthPath: []byte("14\n"),
+		},
+		expected: &clusterNaming{baseDomain: "ci.example.com", maxLength: 14},
+	}, {
+		name:        "invalid base domain",
+		profile:     api.ClusterProfileAWS,
+		data:        map[string][]byte{baseDomainPath: []byte("CI_Example")},
+		expectedErr: `invalid base domain "CI_Example"`,
+	}, {
+		name:        "limit too short",
+		profile:     api.ClusterProfileAWS,
+		data:        map[string][]byte{clusterNameMaxLengthPath: []byte("4")},
+		expectedErr: "maximum cluster name length must be between 8 and 63, got 4",
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			s := multiStageTestStep{profile: tc.profile}
+			err := s.readClusterNaming(&coreapi.Secret{Data: tc.data})
+			var actualErr string
+			if err != nil {
+				actualErr = err.Error()
+				if strings.HasPrefix(actualErr, tc.expectedErr) {
+					actualErr = tc.expectedErr
+				}
+			}
+			testhelper.Diff(t, "error", actualErr, tc.expectedErr)
+			testhelper.Diff(t, "naming", s.clusterNaming, tc.expected, cmp.AllowUnexported(clusterNaming{}))
+		})
+	}
+}
+
+func TestClusterEnv(t *testing.T) {
+	jobSpec := &api.JobSpec{JobSpec: prowdapi.JobSpec{Job: "pull-ci-org-repo-master-e2e"}}
+	jobSpec.SetNamespace("ci-op-abcdefgh")
+	for _, tc := range []struct {
+		name        string
+		naming      *clusterNaming
+		expected    []coreapi.EnvVar
+		expectedErr string
+	}{{
+		name: "name and base domain",
+		expected: []coreapi.EnvVar{
+			{Name: ClusterNameEnv, Value: "ci-op-abcdefgh-e4843"},
+			{Name: BaseDomainEnv, Value: "origin-ci-int-aws.dev.rhcloud.com"},
+		},
+	}, {
+		name:     "no base domain",
+		naming:   &clusterNaming{maxLength: defaultClusterNameMaxLength},
+		expected: []coreapi.EnvVar{{Name: ClusterNameEnv, Value: "ci-op-abcdefgh-e4843"}},
+	}, {
+		name:        "base domain too long",
+		naming:      &clusterNaming{baseDomain: strings.Repeat("a", 60) + "." + strings.Repeat("b", 60) + "." + strings.Repeat("c", 60) + "." + strings.Repeat("d", 50), maxLength: defaultClusterNameMaxLength},
+		expectedErr: "is too long: host names of cluster ci-op-abcdefgh-e4843 would be longer than 253 characters",
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			s := multiStageTestStep{name: "e2e", profile: api.ClusterProfileAWS, jobSpec: jobSpec, clusterNaming: tc.naming}
+			actual, err := s.clusterEnv()
+			var actualErr string
+			if err != nil {
+				actualErr = err.Error()
+				if strings.HasSuffix(actualErr, tc.expectedErr) {
+					actualErr = tc.expectedErr
+				}
+			}
+			testhelper.Diff(t, "error", actualErr, tc.expectedErr)
+			testhelper.Diff(t, "env", actual, tc.expected)
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package multi_stage
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sync/semaphore"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/junit"
+	"github.com/openshift/ci-tools/pkg/results"
+)
+
+// stepLeaseTypes returns the types of the leases held while a step runs, to
+// limit its concurrency across jobs.  They are sorted so that all jobs
+// acquire them in the same order.
+func stepLeaseTypes(step api.LiteralTestStep) []string {
+	var ret []string
+	if step.ConcurrencyGroup != "" {
+		ret = append(ret, api.ConcurrencyGroupLeaseType(step.ConcurrencyGroup))
+	}
+	if step.Mutex != "" {
+		ret = append(ret, api.MutexLeaseType(step.Mutex))
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// acquireStepLeases blocks until the step of a pod may run within the limit
+// of its concurrency group and holds its mutex.  The time spent waiting for
+// each lease is reported as a test case.  The returned context is cancelled
+// if a lease is lost and the returned function releases the leases.
+func (s *multiStageTestStep) acquireStepLeases(ctx context.Context, podName string, step api.LiteralTestStep) (context.Context, func(), error) {
+	types := stepLeaseTypes(step)
+	if len(types) == 0 {
+		return ctx, func() {}, nil
+	}
+	client := *s.options.LeaseClient
+	ctx, cancel := context.WithCancel(ctx)
+	var names []string
+	release := func() {
+		cancel()
+		for _, name := range names {
+			if err := client.Release(name); err != nil {
+				logrus.WithError(err).Warnf("Failed to release lease %s of step %s", name, podName)
+			}
+		}
+	}
+	for _, rtype := range types {
+		logrus.Infof("Acquiring lease %s for step %s.", rtype, podName)
+		start := time.Now()
+		acquired, err := client.Acquire(rtype, 1, ctx, cancel)
+		wait := time.Since(start)
+		testCase := &junit.TestCase{
+			Name:      fmt.Sprintf("%s - %s lease %s", s.Description(), podName, rtype),
+			Duration:  wait.Seconds(),
+			SystemOut: fmt.Sprintf("Waited %s for lease %s.", wait.Truncate(time.Second), rtype),
+		}
+		if err != nil {
+			testCase.FailureOutput = &junit.FailureOutput{Output: err.Error()}
+		}
+		s.subLock.Lock()
+		s.subTests = append(s.subTests, testCase)
+		s.subLock.Unlock()
+		if err != nil {
+			release()
+			return nil, nil, &results.InfrastructureError{Err: results.ForReason("acquiring_lease").WithError(err).Errorf("failed to acquire lease for %q: %v", rtype, err)}
+		}
+		logrus.Infof("Acquired lease %s for step %s after %s.", rtype, podName, wait.Truncate(time.Second))
+		names = append(names, acquired...)
+	}
+	return ctx, release, nil
+}
+
+// needsLeaseClient determines whether any of the steps of the test limit
+// their concurrency.
+func (s *multiStageTestStep) needsLeaseClient() bool {
+	for _, step := range s.allSteps() {
+		if len(stepLeaseTypes(step)) != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// StepPodLimiter caps the number of step pods which run at the same time.
+// A single limiter is shared by all the tests executed by ci-operator, which
+// run in the same namespace, to protect build farms from workflows which fan
+// out to many parallel steps.
+type StepPodLimiter struct {
+	max int64
+	sem *semaphore.Weighted
+}
+
+// NewStepPodLimiter returns a limiter which allows at most max pods to run.
+func NewStepPodLimiter(max int) *StepPodLimiter {
+	return &StepPodLimiter{max: int64(max), sem: semaphore.NewWeighted(int64(max))}
+}
+
+// acquire blocks until n more pods may run and returns the function which
+// releases them.  Pods which must run together, such as the steps of a gang,
+// are acquired at once, so they never wait for each other.
+func (l *StepPodLimiter) acquire(ctx context.Context, name string, n int) (func(), error) {
+	if l == nil {
+		return func() {}, nil
+	}
+	if int64(n) > l.max {
+		return nil, fmt.Errorf("%s needs %d pods to run at the same time, but at most %d step pods may run concurrently", name, n, l.max)
+	}
+	if !l.sem.TryAcquire(int64(n)) {
+		logrus.Infof("Waiting for other step pods to finish before running %s, at most %d may run concurrently.", name, l.max)
+		if err := l.sem.Acquire(ctx, int64(n)); err != nil {
+			return nil, err
+		}
+	}
+	return func() { l.sem.Release(int64(n)) }, nil
+}
@@ -0,0 +1,141 @@
+package multi_stage
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/lease"
+	base_steps "github.com/openshift/ci-tools/pkg/steps"
+	"github.com/openshift/ci-tools/pkg/testhelper"
+)
+
+func TestAcquireStepLeases(t *testing.T) {
+	for _, tc := range []struct {
+		name          string
+		step          api.LiteralTestStep
+		failures      sets.Set[string]
+		expected      []string
+		expectedCases []string
+		expectedErr   string
+	}{{
+		name: "no concurrency group or mutex",
+		step: api.LiteralTestStep{As: "e2e"},
+	}, {
+		name: "concurrency group lease is acquired and released",
+		step: api.LiteralTestStep{As: "e2e", ConcurrencyGroup: "loki-ingest"},
+		expected: []string{
+			"acquire owner loki-ingest-concurrency free leased random",
+			"releaseone owner loki-ingest-concurrency_0 free",
+		},
+		expectedCases: []string{"Run multi-stage test test - test-e2e lease loki-ingest-concurrency"},
+	}, {
+		name: "mutex and concurrency group are acquired in order",
+		step: api.LiteralTestStep{As: "e2e", ConcurrencyGroup: "loki-ingest", Mutex: "dns-zone"},
+		expected: []string{
+			"acquire owner dns-zone-mutex free leased random",
+			"acquire owner loki-ingest-concurrency free leased random",
+			"releaseone owner dns-zone-mutex_0 free",
+			"releaseone owner loki-ingest-concurrency_1 free",
+		},
+		expectedCases: []string{
+			"Run multi-stage test test - test-e2e lease dns-zone-mutex",
+			"Run multi-stage test test - test-e2e lease loki-ingest-concurrency",
+		},
+	}, {
+		name:     "acquisition fails",
+		step:     api.LiteralTestStep{As: "e2e", ConcurrencyGroup: "loki-ingest", Mutex: "dns-zone"},
+		failures: sets.New[string]("acquire owner loki-ingest-concurrency free leased random"),
+		expected: []string{
+			"acquire owner dns-zone-mutex free leased random",
+			"acquire owner loki-ingest-concurrency free leased random",
+			"releaseone owner dns-zone-mutex_0 free",
+		},
+		expectedCases: []string{
+			"Run multi-stage test test - test-e2e lease dns-zone-mutex",
+			"Run multi-stage test test - test-e2e lease loki-ingest-concurrency",
+		},
+		expectedErr: `failed to acquire lease for "loki-ingest-concurrency": injected failure "acquire owner loki-ingest-concurrency free leased random"`,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			var calls []string
+			client := lease.NewFakeClient("owner", "url", 0, tc.failures, &calls)
+			s := multiStageTestStep{name: "test", subLock: &sync.Mutex{}, options: Options{LeaseClient: &client}}
+			ctx, release, err := s.acquireStepLeases(context.Background(), "test-e2e", tc.step)
+			var actualErr string
+			if err != nil {
+				actualErr = err.Error()
+			} else {
+				if ctx.Err() != nil {
+					t.Errorf("unexpected cancellation: %v", ctx.Err())
+				}
+				release()
+			}
+			testhelper.Diff(t, "error", actualErr, tc.expectedErr)
+			testhelper.Diff(t, "calls", calls, tc.expected)
+			var cases []string
+			for _, test := range s.subTests {
+				cases = append(cases, test.Name)
+			}
+			testhelper.Diff(t, "test cases", cases, tc.expectedCases)
+		})
+	}
+}
+
+func TestValidateStepLeases(t *testing.T) {
+	for _, step := range []api.LiteralTestStep{
+		{As: "e2e", ConcurrencyGroup: "loki-ingest"},
+		{As: "e2e", Mutex: "dns-zone"},
+	} {
+		s := multiStageTestStep{test: []api.LiteralTestStep{step}}
+		if err := s.Validate(); !errors.Is(err, base_steps.NoLeaseClientErr) {
+			t.Errorf("expected an error for the missing lease client, got %v", err)
+		}
+		client := lease.NewFakeClient("owner", "url", 0, nil, nil)
+		s.options.LeaseClient = &client
+		if err := s.Validate(); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+}
+
+func TestStepPodLimiter(t *testing.T) {
+	var unlimited *StepPodLimiter
+	if _, err := unlimited.acquire(context.Background(), "step a", 100); err != nil {
+		t.Fatalf("unexpected error without a limit: %v", err)
+	}
+	l := NewStepPodLimiter(2)
+	if _, err := l.acquire(context.Background(), "gang g", 3); err == nil || err.Error() != "gang g needs 3 pods to run at the same time, but at most 2 step pods may run concurrently" {
+		t.Errorf("unexpected error for a gang larger than the limit: %v", err)
+	}
+	releaseA, err := l.acquire(context.Background(), "step a", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := l.acquire(context.Background(), "step b", 1); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := l.acquire(ctx, "step c", 1); err == nil {
+		t.Fatal("expected step c to wait for the other steps")
+	}
+	acquired := make(chan struct{})
+	go func() {
+		if _, err := l.acquire(context.Background(), "step c", 1); err != nil {
+			t.Error(err)
+		}
+		close(acquired)
+	}()
+	releaseA()
+	select {
+	case <-acquired:
+	case <-time.After(time.Minute):
+		t.Fatal("step c did not run after step a finished")
+	}
+}
@@ -0,0 +1,154 @@
+package multi_stage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+
+	"github.com/sirupsen/logrus"
+
+	coreapi "k8s.io/api/core/v1"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/openshift/ci-tools/pkg/api"
+)
+
+// ContractArtifact is the name of the artifact file, relative to the test's
+// artifact directory, which records the contract observed for each executed
+// step: the parameters it received and the files of the shared directory it
+// could read and wrote.  Comparing it with the documentation of the steps in
+// the registry shows where the documentation is out of date.
+const ContractArtifact = "contract.json"
+
+// stepContract is the contract observed for a step.
+type stepContract struct {
+	Step         string               `json:"step"`
+	Parameters   []parameterUse       `json:"parameters,omitempty"`
+	Dependencies []api.StepDependency `json:"dependencies,omitempty"`
+	// AvailableFiles are the files in the shared directory when the step
+	// started, which it could read.
+	AvailableFiles []string `json:"available_files,omitempty"`
+	// WrittenFiles are the files the step created or modified.
+	WrittenFiles []string `json:"written_files,omitempty"`
+	// RemovedFiles are the files the step removed.
+	RemovedFiles []string `json:"removed_files,omitempty"`
+	// UndeclaredFiles are the written files the step does not declare in
+	// `provides_shared_files`.
+	UndeclaredFiles []string `json:"undeclared_files,omitempty"`
+}
+
+// parameterUse records the value a step received for a parameter.
+type parameterUse struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+	// Default is set when the value is the default of the step.
+	Default bool `json:"default,omitempty"`
+}
+
+// sharedDirSnapshot returns the digests of the files in the shared directory.
+func (s *multiStageTestStep) sharedDirSnapshot(ctx context.Context) (map[string][sha256.Size]byte, error) {
+	secret := &coreapi.Secret{}
+	if err := s.client.Get(ctx, ctrlruntimeclient.ObjectKey{Namespace: s.jobSpec.Namespace(), Name: s.name}, secret); err != nil {
+		return nil, fmt.Errorf("failed to get the shared directory: %w", err)
+	}
+	data, err := s.sharedDirData(secret)
+	if err != nil {
+		return nil, err
+	}
+	ret := make(map[string][sha256.Size]byte, len(data))
+	for k, v := range data {
+		ret[k] = sha256.Sum256(v)
+	}
+	return ret, nil
+}
+
+// recordContract records the contract of the step of a pod, given the state
+// of the shared directory before the pod was executed.  Shards of a step are
+// recorded together.
+func (s *multiStageTestStep) recordContract(ctx context.Context, pod *coreapi.Pod, before map[string][sha256.Size]byte) {
+	step, ok := s.stepFor(pod)
+	if !ok {
+		return
+	}
+	after, err := s.sharedDirSnapshot(ctx)
+	if err != nil {
+		logrus.WithError(err).Debugf("Failed to determine the files written by step %s", pod.Name)
+	}
+	s.subLock.Lock()
+	defer s.subLock.Unlock()
+	if s.contracts == nil {
+		s.contracts = map[string]*stepContract{}
+	}
+	contract, ok := s.contracts[step.As]
+	if !ok {
+		contract = &stepContract{Step: step.As, Dependencies: step.Dependencies}
+		for _, param := range step.Environment {
+			use := parameterUse{Name: param.Name}
+			if v, ok := s.env[param.Name]; ok {
+				use.Value = v
+			} else if param.Default != nil {
+				use.Value, use.Default = *param.Default, true
+			}
+			contract.Parameters = append(contract.Parameters, use)
+		}
+		for file := range before {
+			contract.AvailableFiles = append(contract.AvailableFiles, file)
+		}
+		sort.Strings(contract.AvailableFiles)
+		s.contracts[step.As] = contract
+	}
+	if before == nil || after == nil {
+		return
+	}
+	declared := map[string]bool{}
+	for _, file := range step.ProvidesSharedFiles {
+		declared[file] = true
+	}
+	for file, digest := range after {
+		if previous, ok := before[file]; ok && previous == digest {
+			continue
+		}
+		contract.WrittenFiles = insertSorted(contract.WrittenFiles, file)
+		if !declared[file] {
+			contract.UndeclaredFiles = insertSorted(contract.UndeclaredFiles, file)
+		}
+	}
+	for file := range before {
+		if _, ok := after[file]; !ok {
+			contract.RemovedFiles = insertSorted(contract.RemovedFiles, file)
+		}
+	}
+}
+
+// insertSorted adds a string to a sorted list if it is not already present.
+func insertSorted(l []string, s string) []string {
+	i := sort.SearchStrings(l, s)
+	if i < len(l) && l[i] == s {
+		return l
+	}
+	return append(l[:i], append([]string{s}, l[i:]...)...)
+}
+
+// saveContract writes the contracts of the executed steps to the artifact
+// directory of the test, in the order in which the steps are defined.
+func (s *multiStageTestStep) saveContract() error {
+	s.subLock.Lock()
+	defer s.subLock.Unlock()
+	contracts := []*stepContract{}
+	for _, step := range s.allSteps() {
+		if c, ok := s.contracts[step.As]; ok {
+			contracts = append(contracts, c)
+		}
+	}
+	if len(contracts) == 0 {
+		return nil
+	}
+	data, err := json.MarshalIndent(contracts, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal step contracts: %w", err)
+	}
+	return api.SaveArtifact(s.censor, path.Join(s.name, ContractArtifact), data)
+}
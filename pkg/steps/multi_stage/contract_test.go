@@ -0,0 +1,94 @@
+package multi_stage
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	coreapi "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakectrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/kubernetes"
+	"github.com/openshift/ci-tools/pkg/secrets"
+	base_steps "github.com/openshift/ci-tools/pkg/steps"
+	"github.com/openshift/ci-tools/pkg/steps/loggingclient"
+)
+
+func TestRecordContract(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("ARTIFACTS", dir)
+	censor := secrets.NewDynamicCensor()
+	jobSpec := api.JobSpec{}
+	jobSpec.SetNamespace("ns")
+	client := kubernetes.NewPodClient(loggingclient.New(fakectrlruntimeclient.NewClientBuilder().WithObjects(
+		&coreapi.Secret{ObjectMeta: meta.ObjectMeta{Namespace: "ns", Name: "test"}, Data: map[string][]byte{
+			"kubeconfig": []byte("kubeconfig"),
+			"proxy":      []byte("proxy"),
+			"old":        []byte("old"),
+		}},
+	).Build()), nil, nil, 0)
+	defaultValue := "default"
+	s := multiStageTestStep{
+		name:    "test",
+		jobSpec: &jobSpec,
+		client:  client,
+		subLock: &sync.Mutex{},
+		env:     api.TestEnvironment{"SET": "from-test"},
+		pre:     []api.LiteralTestStep{{As: "install"}},
+		test: []api.LiteralTestStep{{
+			As:                  "e2e",
+			Environment:         []api.StepParameter{{Name: "SET", Default: &defaultValue}, {Name: "DEFAULTED", Default: &defaultValue}, {Name: "EMPTY"}},
+			Dependencies:        []api.StepDependency{{Name: "tests", Env: "TESTS_IMAGE"}},
+			ProvidesSharedFiles: []string{"results"},
+		}},
+		censor: &censor,
+	}
+	ctx := context.Background()
+	pod := &coreapi.Pod{ObjectMeta: meta.ObjectMeta{Name: "test-e2e", Labels: map[string]string{base_steps.LabelMetadataStep: "e2e"}}}
+	before, err := s.sharedDirSnapshot(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := client.Update(ctx, &coreapi.Secret{ObjectMeta: meta.ObjectMeta{Namespace: "ns", Name: "test"}, Data: map[string][]byte{
+		"kubeconfig": []byte("kubeconfig"),
+		"proxy":      []byte("modified"),
+		"results":    []byte("results"),
+	}}); err != nil {
+		t.Fatalf("failed to update the shared directory: %v", err)
+	}
+	s.recordContract(ctx, pod, before)
+	if err := s.saveContract(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	raw, err := os.ReadFile(filepath.Join(dir, "test", ContractArtifact))
+	if err != nil {
+		t.Fatalf("failed to read artifact: %v", err)
+	}
+	var got []stepContract
+	if err := json.Unmarshal(raw, &got); err != nil {
+		t.Fatalf("failed to unmarshal artifact: %v", err)
+	}
+	expected := []stepContract{{
+		Step: "e2e",
+		Parameters: []parameterUse{
+			{Name: "SET", Value: "from-test"},
+			{Name: "DEFAULTED", Value: "default", Default: true},
+			{Name: "EMPTY"},
+		},
+		Dependencies:    []api.StepDependency{{Name: "tests", Env: "TESTS_IMAGE"}},
+		AvailableFiles:  []string{"kubeconfig", "old", "proxy"},
+		WrittenFiles:    []string{"proxy", "results"},
+		RemovedFiles:    []string{"old"},
+		UndeclaredFiles: []string{"proxy"},
+	}}
+	if diff := cmp.Diff(expected, got); diff != "" {
+		t.Errorf("unexpected contract: %s", diff)
+	}
+}
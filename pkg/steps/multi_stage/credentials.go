@@ -0,0 +1,134 @@
+package multi_stage
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	coreapi "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/wait"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/openshift/ci-tools/pkg/api"
+)
+
+// credentialRefreshInterval is how often the sources of the credentials which
+// are refreshed are checked for updates.
+const credentialRefreshInterval = time.Minute
+
+// credentialCollectionKey identifies a collection of credentials by its
+// namespace and selector, e.g. `test-credentials/partner=true`.
+func credentialCollectionKey(credential api.CredentialReference) string {
+	return fmt.Sprintf("%s/%s", credential.Namespace, labels.SelectorFromSet(credential.Selector.MatchLabels))
+}
+
+// resolveCredentialCollection lists the secrets of a collection of
+// credentials and records their names, so that the steps which use it mount
+// the same secrets for the whole test.  A collection which matches no secrets
+// is an error.
+func (s *multiStageTestStep) resolveCredentialCollection(ctx context.Context, credential api.CredentialReference) ([]coreapi.Secret, error) {
+	key := credentialCollectionKey(credential)
+	list := coreapi.SecretList{}
+	if err := s.client.List(ctx, &list, ctrlruntimeclient.InNamespace(credential.Namespace), ctrlruntimeclient.MatchingLabels(credential.Selector.MatchLabels)); err != nil {
+		return nil, fmt.Errorf("could not list the secrets of credential collection %s: %w", key, err)
+	}
+	if len(list.Items) == 0 {
+		return nil, fmt.Errorf("no secrets match credential collection %s", key)
+	}
+	names := make([]string, 0, len(list.Items))
+	for _, secret := range list.Items {
+		names = append(names, secret.Name)
+	}
+	sort.Strings(names)
+	if s.credentialCollections == nil {
+		s.credentialCollections = map[string][]string{}
+	}
+	s.credentialCollections[key] = names
+	return list.Items, nil
+}
+
+// stepCredentials returns the secrets mounted by a step: the collections of
+// credentials are replaced by their secrets, each mounted in a directory named
+// after it.  Collections which have not been resolved mount nothing.
+func (s *multiStageTestStep) stepCredentials(credentials []api.CredentialReference) []api.CredentialReference {
+	var ret []api.CredentialReference
+	for _, credential := range credentials {
+		if credential.Selector == nil {
+			ret = append(ret, credential)
+			continue
+		}
+		for _, name := range s.credentialCollections[credentialCollectionKey(credential)] {
+			ret = append(ret, api.CredentialReference{
+				Namespace: credential.Namespace,
+				Name:      name,
+				MountPath: path.Join(credential.MountPath, name),
+				Refresh:   credential.Refresh,
+			})
+		}
+	}
+	return ret
+}
+
+// refreshedCredentials returns the credentials whose updates are propagated
+// while the test runs, once for each source secret.
+func (s *multiStageTestStep) refreshedCredentials() []api.CredentialReference {
+	seen := map[string]bool{}
+	var ret []api.CredentialReference
+	for _, step := range s.allSteps() {
+		for _, credential := range s.stepCredentials(step.Credentials) {
+			if name := credentialSecretName(credential); credential.Refresh && !seen[name] {
+				seen[name] = true
+				ret = append(ret, credential)
+			}
+		}
+	}
+	return ret
+}
+
+// watchCredentials propagates the updates of the sources of the credentials
+// to their copies until the context is done.
+func (s *multiStageTestStep) watchCredentials(ctx context.Context, credentials []api.CredentialReference, interval time.Duration) {
+	_ = wait.PollUntilContextCancel(ctx, interval, false, func(ctx context.Context) (bool, error) {
+		for _, credential := range credentials {
+			if err := s.refreshCredential(ctx, credential); err != nil {
+				logrus.WithError(err).Warnf("Failed to refresh credential %s/%s", credential.Namespace, credential.Name)
+			}
+		}
+		return false, nil
+	})
+}
+
+// refreshCredential replaces the content of the copy of a credential in the
+// test namespace with that of its source, if it was updated.  The new content
+// is censored from the artifacts.
+func (s *multiStageTestStep) refreshCredential(ctx context.Context, credential api.CredentialReference) error {
+	source := &coreapi.Secret{}
+	if err := s.client.Get(ctx, ctrlruntimeclient.ObjectKey{Namespace: credential.Namespace, Name: credential.Name}, source); err != nil {
+		return fmt.Errorf("could not read source credential: %w", err)
+	}
+	name := credentialSecretName(credential)
+	secret := &coreapi.Secret{}
+	if err := s.client.Get(ctx, ctrlruntimeclient.ObjectKey{Namespace: s.jobSpec.Namespace(), Name: name}, secret); err != nil {
+		return fmt.Errorf("could not read the copy of the credential: %w", err)
+	}
+	if equality.Semantic.DeepEqual(source.Data, secret.Data) {
+		return nil
+	}
+	if s.censor != nil {
+		for _, value := range source.Data {
+			s.censor.AddSecrets(string(value))
+		}
+	}
+	secret.Data = source.Data
+	if err := s.client.Update(ctx, secret); err != nil {
+		return fmt.Errorf("could not update the copy of the credential: %w", err)
+	}
+	logrus.Infof("Propagated the update of credential %s/%s to %s.", credential.Namespace, credential.Name, name)
+	return nil
+}
@@ -0,0 +1,123 @@
+package multi_stage
+
+import (
+	"context"
+	"testing"
+
+	coreapi "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	fakectrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/kubernetes"
+	"github.com/openshift/ci-tools/pkg/secrets"
+	"github.com/openshift/ci-tools/pkg/steps/loggingclient"
+	"github.com/openshift/ci-tools/pkg/testhelper"
+)
+
+func TestCredentialCollections(t *testing.T) {
+	partner := func(name, value string) *coreapi.Secret {
+		return &coreapi.Secret{
+			ObjectMeta: meta.ObjectMeta{Namespace: "creds", Name: name, Labels: map[string]string{"partner": "true"}},
+			Data:       map[string][]byte{"token": []byte(value)},
+		}
+	}
+	fakeClient := fakectrlruntimeclient.NewClientBuilder().WithObjects(
+		partner("partner-b", "b"),
+		partner("partner-a", "a"),
+		&coreapi.Secret{ObjectMeta: meta.ObjectMeta{Namespace: "creds", Name: "other"}},
+		&coreapi.Secret{ObjectMeta: meta.ObjectMeta{Namespace: "creds", Name: "single"}},
+	).Build()
+	client := kubernetes.NewPodClient(loggingclient.New(fakeClient), nil, nil, 0)
+	jobSpec := api.JobSpec{}
+	jobSpec.SetNamespace("ns")
+	collection := api.CredentialReference{Namespace: "creds", Selector: &api.CredentialSelector{MatchLabels: map[string]string{"partner": "true"}}, MountPath: "/var/run/partners"}
+	single := api.CredentialReference{Namespace: "creds", Name: "single", MountPath: "/var/run/single"}
+	s := multiStageTestStep{
+		name:    "e2e",
+		jobSpec: &jobSpec,
+		client:  client,
+		pre:     []api.LiteralTestStep{{As: "install", Credentials: []api.CredentialReference{collection, single}}},
+		post:    []api.LiteralTestStep{{As: "deprovision", Credentials: []api.CredentialReference{collection}}},
+	}
+	testhelper.Diff(t, "unresolved credentials", s.stepCredentials(s.pre[0].Credentials), []api.CredentialReference{single})
+	ctx := context.Background()
+	if err := s.createCredentials(ctx); err != nil {
+		t.Fatal(err)
+	}
+	testhelper.Diff(t, "credentials", s.stepCredentials(s.pre[0].Credentials), []api.CredentialReference{
+		{Namespace: "creds", Name: "partner-a", MountPath: "/var/run/partners/partner-a"},
+		{Namespace: "creds", Name: "partner-b", MountPath: "/var/run/partners/partner-b"},
+		single,
+	})
+	for name, value := range map[string]string{"creds-partner-a": "a", "creds-partner-b": "b"} {
+		var secret coreapi.Secret
+		if err := fakeClient.Get(ctx, ctrlruntimeclient.ObjectKey{Namespace: "ns", Name: name}, &secret); err != nil {
+			t.Fatalf("the secret of the collection was not copied: %v", err)
+		}
+		testhelper.Diff(t, name, string(secret.Data["token"]), value)
+	}
+}
+
+func TestCredentialCollectionWithoutSecrets(t *testing.T) {
+	client := kubernetes.NewPodClient(loggingclient.New(fakectrlruntimeclient.NewClientBuilder().Build()), nil, nil, 0)
+	jobSpec := api.JobSpec{}
+	jobSpec.SetNamespace("ns")
+	s := multiStageTestStep{
+		name:    "e2e",
+		jobSpec: &jobSpec,
+		client:  client,
+		pre: []api.LiteralTestStep{{As: "install", Credentials: []api.CredentialReference{
+			{Namespace: "creds", Selector: &api.CredentialSelector{MatchLabels: map[string]string{"partner": "true"}}, MountPath: "/var/run/partners"},
+		}}},
+	}
+	err := s.createCredentials(context.Background())
+	var actual string
+	if err != nil {
+		actual = err.Error()
+	}
+	testhelper.Diff(t, "error", actual, "no secrets match credential collection creds/partner=true")
+}
+
+func TestRefreshCredentials(t *testing.T) {
+	source := &coreapi.Secret{
+		ObjectMeta: meta.ObjectMeta{Namespace: "creds", Name: "token"},
+		Data:       map[string][]byte{"token": []byte("rotated")},
+	}
+	secret := &coreapi.Secret{
+		ObjectMeta: meta.ObjectMeta{Namespace: "ns", Name: "creds-token", Labels: map[string]string{api.ScrubOnExitLabel: "true"}},
+		Data:       map[string][]byte{"token": []byte("expired")},
+	}
+	fakeClient := fakectrlruntimeclient.NewClientBuilder().WithObjects(source, secret).Build()
+	client := kubernetes.NewPodClient(loggingclient.New(fakeClient), nil, nil, 0)
+	jobSpec := api.JobSpec{}
+	jobSpec.SetNamespace("ns")
+	censor := secrets.NewDynamicCensor()
+	refreshed := api.CredentialReference{Namespace: "creds", Name: "token", MountPath: "/var/run/token", Refresh: true}
+	s := multiStageTestStep{
+		name:    "e2e",
+		jobSpec: &jobSpec,
+		client:  client,
+		censor:  &censor,
+		pre: []api.LiteralTestStep{{As: "install", Credentials: []api.CredentialReference{
+			refreshed,
+			{Namespace: "creds", Name: "other", MountPath: "/var/run/other"},
+		}}},
+		post: []api.LiteralTestStep{{As: "deprovision", Credentials: []api.CredentialReference{refreshed}}},
+	}
+	credentials := s.refreshedCredentials()
+	testhelper.Diff(t, "refreshed credentials", credentials, []api.CredentialReference{refreshed})
+	ctx := context.Background()
+	if err := s.refreshCredential(ctx, refreshed); err != nil {
+		t.Fatal(err)
+	}
+	if err := fakeClient.Get(ctx, ctrlruntimeclient.ObjectKeyFromObject(secret), secret); err != nil {
+		t.Fatal(err)
+	}
+	testhelper.Diff(t, "content", string(secret.Data["token"]), "rotated")
+	testhelper.Diff(t, "labels", secret.Labels, map[string]string{api.ScrubOnExitLabel: "true"})
+	data := []byte("token: rotated")
+	censor.Censor(&data)
+	testhelper.Diff(t, "censored", string(data), "token: XXXXXXX")
+}
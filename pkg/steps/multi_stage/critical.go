@@ -0,0 +1,85 @@
+package multi_stage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+
+	coreapi "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	base_steps "github.com/openshift/ci-tools/pkg/steps"
+)
+
+const (
+	// SafeToEvictAnnotation is set to "false" on the pods of critical steps
+	// for the cluster autoscaler not to remove their nodes.
+	SafeToEvictAnnotation = "cluster-autoscaler.kubernetes.io/safe-to-evict"
+	// CriticalStepLabel identifies the pod of a critical step, selected by
+	// the disruption budget which protects it from eviction.
+	CriticalStepLabel = "ci.openshift.io/critical-step"
+)
+
+func isCritical(step api.LiteralTestStep) bool {
+	return step.Critical != nil && *step.Critical
+}
+
+// criticalStepLabelValue returns the value of the label of a pod, which is
+// its name unless it is too long for a label value.
+func criticalStepLabelValue(name string) string {
+	if len(name) <= validation.LabelValueMaxLength {
+		return name
+	}
+	hash := sha256.Sum256([]byte(name))
+	return hex.EncodeToString(hash[:])[:validation.LabelValueMaxLength-1]
+}
+
+// markCritical marks the pod of a critical step so that it is not evicted.
+func markCritical(pod *coreapi.Pod) {
+	pod.Annotations[SafeToEvictAnnotation] = "false"
+	pod.Labels[CriticalStepLabel] = criticalStepLabelValue(pod.Name)
+}
+
+// protectCriticalPod creates the disruption budget which forbids voluntary
+// disruptions of the pod of a critical step, e.g. when nodes are drained.  It
+// returns the function which deletes the budget once the pod has finished.
+func (s *multiStageTestStep) protectCriticalPod(ctx context.Context, pod *coreapi.Pod) (func(), error) {
+	value, ok := pod.Labels[CriticalStepLabel]
+	if !ok {
+		return func() {}, nil
+	}
+	zero := intstr.FromInt(0)
+	pdb := &policyv1.PodDisruptionBudget{
+		ObjectMeta: meta.ObjectMeta{
+			Namespace:       pod.Namespace,
+			Name:            pod.Name,
+			OwnerReferences: s.ownerReferences(),
+		},
+		Spec: policyv1.PodDisruptionBudgetSpec{
+			MaxUnavailable: &zero,
+			Selector:       &meta.LabelSelector{MatchLabels: map[string]string{CriticalStepLabel: value}},
+		},
+	}
+	s.addMetadata(pdb)
+	logrus.Debugf("Creating pod disruption budget for critical step %s.", pod.Name)
+	if err := s.client.Create(ctx, pdb); err != nil && !kerrors.IsAlreadyExists(err) {
+		if kerrors.IsForbidden(err) {
+			logrus.WithError(err).Warnf("Could not protect critical step %s from eviction because you do not have permission to manage pod disruption budgets.", pod.Name)
+			return func() {}, nil
+		}
+		return nil, fmt.Errorf("failed to create pod disruption budget for step %s: %w", pod.Name, err)
+	}
+	return func() {
+		if err := s.client.Delete(base_steps.CleanupCtx, pdb); err != nil && !kerrors.IsNotFound(err) {
+			logrus.WithError(err).Warnf("Failed to delete pod disruption budget of step %s.", pod.Name)
+		}
+	}, nil
+}
@@ -0,0 +1,71 @@
+package multi_stage
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	coreapi "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/validation"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	fakectrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/kubernetes"
+	"github.com/openshift/ci-tools/pkg/steps/loggingclient"
+	"github.com/openshift/ci-tools/pkg/testhelper"
+)
+
+func TestCriticalStepLabelValue(t *testing.T) {
+	testhelper.Diff(t, "short name", criticalStepLabelValue("e2e-ipi-install"), "e2e-ipi-install")
+	long := criticalStepLabelValue(strings.Repeat("e2e-", 20))
+	if errs := validation.IsValidLabelValue(long); len(errs) != 0 {
+		t.Errorf("invalid label value for long name %q: %v", long, errs)
+	}
+}
+
+func TestProtectCriticalPod(t *testing.T) {
+	ctx := context.Background()
+	jobSpec := api.JobSpec{}
+	jobSpec.SetNamespace("ns")
+	client := kubernetes.NewPodClient(loggingclient.New(fakectrlruntimeclient.NewClientBuilder().Build()), nil, nil, 0)
+	s := multiStageTestStep{name: "e2e", jobSpec: &jobSpec, client: client}
+	pod := &coreapi.Pod{ObjectMeta: meta.ObjectMeta{Namespace: "ns", Name: "e2e-ipi-install", Labels: map[string]string{}, Annotations: map[string]string{}}}
+
+	release, err := s.protectCriticalPod(ctx, pod.DeepCopy())
+	if err != nil {
+		t.Fatal(err)
+	}
+	release()
+	pdbs := &policyv1.PodDisruptionBudgetList{}
+	if err := client.List(ctx, pdbs); err != nil {
+		t.Fatal(err)
+	}
+	if len(pdbs.Items) != 0 {
+		t.Errorf("expected no disruption budget for a step which is not critical, got %d", len(pdbs.Items))
+	}
+
+	markCritical(pod)
+	testhelper.Diff(t, "annotation", pod.Annotations[SafeToEvictAnnotation], "false")
+	release, err = s.protectCriticalPod(ctx, pod)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pdb := &policyv1.PodDisruptionBudget{}
+	if err := client.Get(ctx, ctrlruntimeclient.ObjectKey{Namespace: "ns", Name: "e2e-ipi-install"}, pdb); err != nil {
+		t.Fatalf("failed to get the disruption budget: %v", err)
+	}
+	zero := intstr.FromInt(0)
+	testhelper.Diff(t, "spec", pdb.Spec, policyv1.PodDisruptionBudgetSpec{
+		MaxUnavailable: &zero,
+		Selector:       &meta.LabelSelector{MatchLabels: map[string]string{CriticalStepLabel: "e2e-ipi-install"}},
+	})
+	release()
+	if err := client.Get(ctx, ctrlruntimeclient.ObjectKey{Namespace: "ns", Name: "e2e-ipi-install"}, pdb); !kerrors.IsNotFound(err) {
+		t.Errorf("expected the disruption budget to be deleted, got %v", err)
+	}
+}
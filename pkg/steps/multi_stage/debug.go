@@ -0,0 +1,65 @@
+package multi_stage
+
+import (
+	"fmt"
+	"strings"
+
+	coreapi "k8s.io/api/core/v1"
+)
+
+const (
+	// DebugAnnotation marks step pods to which debug containers can be
+	// attached, set when debugging is enabled for the job.
+	DebugAnnotation = "ci-operator.openshift.io/debug"
+	// debugContainerPrefix is the prefix of the names of debug containers,
+	// which are suffixed with a sequence number as ephemeral containers
+	// cannot be removed from a pod.
+	debugContainerPrefix = "debug-"
+)
+
+// DebugContainer returns an ephemeral container which can be added to a
+// running step pod to inspect it.  The container has the environment and the
+// volumes of the test container, e.g. the cluster profile, the cli and the
+// shared directory, and shares its process namespace.  The image of the test
+// container is used unless another one is given.
+func DebugContainer(pod *coreapi.Pod, image string) (*coreapi.EphemeralContainer, error) {
+	if pod.Annotations[DebugAnnotation] != "true" {
+		return nil, fmt.Errorf("debugging is not enabled for pod %s", pod.Name)
+	}
+	if pod.Status.Phase != coreapi.PodRunning {
+		return nil, fmt.Errorf("pod %s is not running: %s", pod.Name, pod.Status.Phase)
+	}
+	var test *coreapi.Container
+	for i := range pod.Spec.Containers {
+		if pod.Spec.Containers[i].Name == containerName {
+			test = &pod.Spec.Containers[i]
+			break
+		}
+	}
+	if test == nil {
+		return nil, fmt.Errorf("pod %s has no %s container", pod.Name, containerName)
+	}
+	if image == "" {
+		image = test.Image
+	}
+	var n int
+	for _, c := range pod.Spec.EphemeralContainers {
+		if strings.HasPrefix(c.Name, debugContainerPrefix) {
+			n++
+		}
+	}
+	return &coreapi.EphemeralContainer{
+		EphemeralContainerCommon: coreapi.EphemeralContainerCommon{
+			Name:            fmt.Sprintf("%s%d", debugContainerPrefix, n),
+			Image:           image,
+			Command:         []string{"/bin/bash"},
+			Env:             append([]coreapi.EnvVar(nil), test.Env...),
+			VolumeMounts:    append([]coreapi.VolumeMount(nil), test.VolumeMounts...),
+			WorkingDir:      test.WorkingDir,
+			SecurityContext: test.SecurityContext.DeepCopy(),
+			Stdin:           true,
+			TTY:             true,
+		},
+		TargetContainerName: containerName,
+	}, nil
+}
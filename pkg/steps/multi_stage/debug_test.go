@@ -0,0 +1,78 @@
+package multi_stage
+
+import (
+	"testing"
+
+	coreapi "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/openshift/ci-tools/pkg/testhelper"
+)
+
+func TestDebugContainer(t *testing.T) {
+	pod := func(annotations map[string]string, phase coreapi.PodPhase, ephemeral ...string) *coreapi.Pod {
+		ret := &coreapi.Pod{
+			ObjectMeta: meta.ObjectMeta{Name: "e2e-test", Annotations: annotations},
+			Spec: coreapi.PodSpec{Containers: []coreapi.Container{{
+				Name:         "test",
+				Image:        "pipeline:tests",
+				Env:          []coreapi.EnvVar{{Name: "CLUSTER_PROFILE_DIR", Value: ClusterProfileMountPath}},
+				VolumeMounts: []coreapi.VolumeMount{{Name: "cluster-profile", MountPath: ClusterProfileMountPath}, {Name: "cli", MountPath: CliMountPath}},
+			}}},
+			Status: coreapi.PodStatus{Phase: phase},
+		}
+		for _, name := range ephemeral {
+			ret.Spec.EphemeralContainers = append(ret.Spec.EphemeralContainers, coreapi.EphemeralContainer{EphemeralContainerCommon: coreapi.EphemeralContainerCommon{Name: name}})
+		}
+		return ret
+	}
+	debug := map[string]string{DebugAnnotation: "true"}
+	expected := func(name, image string) *coreapi.EphemeralContainer {
+		return &coreapi.EphemeralContainer{
+			EphemeralContainerCommon: coreapi.EphemeralContainerCommon{
+				Name:         name,
+				Image:        image,
+				Command:      []string{"/bin/bash"},
+				Env:          []coreapi.EnvVar{{Name: "CLUSTER_PROFILE_DIR", Value: ClusterProfileMountPath}},
+				VolumeMounts: []coreapi.VolumeMount{{Name: "cluster-profile", MountPath: ClusterProfileMountPath}, {Name: "cli", MountPath: CliMountPath}},
+				Stdin:        true,
+				TTY:          true,
+			},
+			TargetContainerName: "test",
+		}
+	}
+	for _, tc := range []struct {
+		name        string
+		pod         *coreapi.Pod
+		image       string
+		expected    *coreapi.EphemeralContainer
+		expectedErr string
+	}{{
+		name:     "container of the step",
+		pod:      pod(debug, coreapi.PodRunning),
+		expected: expected("debug-0", "pipeline:tests"),
+	}, {
+		name:     "image is overridden and names are not reused",
+		pod:      pod(debug, coreapi.PodRunning, "debug-0", "debug-1"),
+		image:    "registry.ci.openshift.org/ci/debug:latest",
+		expected: expected("debug-2", "registry.ci.openshift.org/ci/debug:latest"),
+	}, {
+		name:        "debugging not enabled",
+		pod:         pod(nil, coreapi.PodRunning),
+		expectedErr: "debugging is not enabled for pod e2e-test",
+	}, {
+		name:        "pod not running",
+		pod:         pod(debug, coreapi.PodSucceeded),
+		expectedErr: "pod e2e-test is not running: Succeeded",
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, err := DebugContainer(tc.pod, tc.image)
+			var actualErr string
+			if err != nil {
+				actualErr = err.Error()
+			}
+			testhelper.Diff(t, "error", actualErr, tc.expectedErr)
+			testhelper.Diff(t, "container", actual, tc.expected)
+		})
+	}
+}
@@ -0,0 +1,168 @@
+package multi_stage
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/openshift/ci-tools/pkg/steps/shareddir"
+)
+
+// DebugService is the break-glass service to which the kubeconfig of the
+// cluster of a failed test is uploaded when debugging is enabled, from where
+// the author of the job can retrieve it for a short time.
+//
+// The kubeconfig is encrypted with a key generated for the upload, which is
+// itself encrypted with the public key of the service.
+type DebugService struct {
+	URL       string
+	PublicKey *rsa.PublicKey
+	Client    *http.Client
+}
+
+// LoadDebugService creates the client of a debug service from the path of its
+// PEM-encoded RSA public key.
+func LoadDebugService(url, publicKeyPath string) (*DebugService, error) {
+	raw, err := os.ReadFile(publicKeyPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the public key: %w", err)
+	}
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return nil, errors.New("no PEM data was found in the public key")
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the public key: %w", err)
+	}
+	rsaKey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("the public key must be an RSA key, got %T", key)
+	}
+	return &DebugService{URL: url, PublicKey: rsaKey, Client: &http.Client{Timeout: time.Minute}}, nil
+}
+
+// DebugUpload is the body of the request which uploads a kubeconfig.
+type DebugUpload struct {
+	// GitHubUser is the only principal allowed to retrieve the kubeconfig.
+	GitHubUser string `json:"github_user"`
+	Job        string `json:"job"`
+	BuildID    string `json:"build_id"`
+	Test       string `json:"test"`
+	Namespace  string `json:"namespace"`
+	// Key is the key of the kubeconfig, encrypted with RSA-OAEP and SHA-256
+	// using the public key of the service.
+	Key []byte `json:"key"`
+	// Kubeconfig is encrypted with AES-GCM using the key.
+	Kubeconfig []byte `json:"kubeconfig"`
+}
+
+// DebugUploadResponse is where the uploaded kubeconfig can be retrieved.
+type DebugUploadResponse struct {
+	URL     string    `json:"url"`
+	Expires time.Time `json:"expires"`
+}
+
+// Upload encrypts and uploads a kubeconfig.
+func (d *DebugService) Upload(ctx context.Context, upload DebugUpload, kubeconfig []byte) (*DebugUploadResponse, error) {
+	key, err := shareddir.GenerateKey()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate the key: %w", err)
+	}
+	if upload.Kubeconfig, err = shareddir.Encrypt(key, kubeconfig); err != nil {
+		return nil, fmt.Errorf("failed to encrypt the kubeconfig: %w", err)
+	}
+	if upload.Key, err = rsa.EncryptOAEP(sha256.New(), rand.Reader, d.PublicKey, key, nil); err != nil {
+		return nil, fmt.Errorf("failed to encrypt the key: %w", err)
+	}
+	body, err := json.Marshal(upload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal upload: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	client := d.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call the debug service: %w", err)
+	}
+	defer resp.Body.Close()
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the debug service response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return nil, fmt.Errorf("debug service responded with status code %d: %s", resp.StatusCode, string(raw))
+	}
+	ret := &DebugUploadResponse{}
+	if err := json.Unmarshal(raw, ret); err != nil {
+		return nil, fmt.Errorf("failed to parse the debug service response: %w", err)
+	}
+	if ret.URL == "" {
+		return nil, errors.New("debug service did not respond with the URL of the kubeconfig")
+	}
+	return ret, nil
+}
+
+// jobAuthor returns the GitHub login of the author of the pull request tested
+// by the job, if any.
+func (s *multiStageTestStep) jobAuthor() string {
+	if refs := s.jobSpec.Refs; refs != nil && len(refs.Pulls) != 0 {
+		return refs.Pulls[0].Author
+	}
+	return ""
+}
+
+// uploadKubeconfig uploads the kubeconfig of the cluster of a failed test to
+// the debug service and prints how its author can retrieve it.  It is called
+// before the post steps, while the cluster still exists.
+func (s *multiStageTestStep) uploadKubeconfig(ctx context.Context) {
+	if !s.options.Debug || s.options.DebugService == nil || s.rendering {
+		return
+	}
+	author := s.jobAuthor()
+	if author == "" {
+		logrus.Infof("Not uploading the kubeconfig of test %s: the job does not test a pull request, so there is nobody to grant access to.", s.name)
+		return
+	}
+	kubeconfig, err := s.sharedKubeconfig(ctx)
+	if err != nil {
+		logrus.WithError(err).Warnf("Not uploading the kubeconfig of test %s.", s.name)
+		return
+	}
+	resp, err := s.options.DebugService.Upload(ctx, DebugUpload{
+		GitHubUser: author,
+		Job:        s.jobSpec.Job,
+		BuildID:    s.jobSpec.BuildID,
+		Test:       s.name,
+		Namespace:  s.jobSpec.Namespace(),
+	}, kubeconfig)
+	if err != nil {
+		logrus.WithError(err).Warnf("Failed to upload the kubeconfig of test %s to the debug service.", s.name)
+		return
+	}
+	var until string
+	if !resp.Expires.IsZero() {
+		until = fmt.Sprintf(" until %s", resp.Expires.UTC().Format(time.RFC3339))
+	}
+	logrus.Infof("The kubeconfig of the cluster of test %s was uploaded to the debug service. @%s can retrieve it%s by logging in with GitHub at %s", s.name, author, until, resp.URL)
+}
@@ -0,0 +1,75 @@
+package multi_stage
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/openshift/ci-tools/pkg/steps/shareddir"
+	"github.com/openshift/ci-tools/pkg/testhelper"
+)
+
+func TestDebugServiceUpload(t *testing.T) {
+	private, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&private.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyPath := filepath.Join(t.TempDir(), "key.pem")
+	if err := os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	expires := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	var received DebugUpload
+	var kubeconfig []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			t.Errorf("failed to decode upload: %v", err)
+		}
+		key, err := rsa.DecryptOAEP(sha256.New(), rand.Reader, private, received.Key, nil)
+		if err != nil {
+			t.Errorf("failed to decrypt key: %v", err)
+		}
+		if kubeconfig, err = shareddir.Decrypt(key, received.Kubeconfig); err != nil {
+			t.Errorf("failed to decrypt kubeconfig: %v", err)
+		}
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(DebugUploadResponse{URL: "https://debug.example.com/kubeconfig/1234", Expires: expires})
+	}))
+	defer server.Close()
+
+	service, err := LoadDebugService(server.URL, keyPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := service.Upload(context.Background(), DebugUpload{GitHubUser: "author", Test: "e2e"}, []byte("kubeconfig"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	testhelper.Diff(t, "response", resp, &DebugUploadResponse{URL: "https://debug.example.com/kubeconfig/1234", Expires: expires})
+	testhelper.Diff(t, "principal", received.GitHubUser, "author")
+	testhelper.Diff(t, "kubeconfig", string(kubeconfig), "kubeconfig")
+}
+
+func TestLoadDebugServiceInvalidKey(t *testing.T) {
+	keyPath := filepath.Join(t.TempDir(), "key.pem")
+	if err := os.WriteFile(keyPath, []byte("not a key"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadDebugService("https://debug.example.com", keyPath); err == nil {
+		t.Error("expected an error for an invalid key")
+	}
+}
@@ -0,0 +1,196 @@
+package multi_stage
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/junit"
+)
+
+const (
+	// DisruptionReportArtifact is the name of the file, in the artifact
+	// directory of the test, where the disruption report is written.
+	DisruptionReportArtifact  = "disruption.json"
+	defaultDisruptionInterval = time.Second
+)
+
+// disruptionWindow is a period during which a target was unavailable.
+type disruptionWindow struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// disruptionTargetReport holds the availability observed for a target.
+type disruptionTargetReport struct {
+	Name          string             `json:"name"`
+	URL           string             `json:"url"`
+	Requests      int                `json:"requests"`
+	Failures      int                `json:"failures"`
+	Disruption    string             `json:"disruption"`
+	MaxDisruption string             `json:"max_disruption,omitempty"`
+	Windows       []disruptionWindow `json:"windows,omitempty"`
+
+	total time.Duration
+	max   *time.Duration
+}
+
+// disruptionReport is the result of monitoring all targets during the `test`
+// phase.
+type disruptionReport struct {
+	Start   time.Time                 `json:"start"`
+	End     time.Time                 `json:"end"`
+	Targets []*disruptionTargetReport `json:"targets"`
+}
+
+// disruptionMonitor polls the configured targets until it is stopped.
+type disruptionMonitor struct {
+	config   api.DisruptionMonitor
+	interval time.Duration
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+	report   disruptionReport
+}
+
+func newDisruptionMonitor(config api.DisruptionMonitor) *disruptionMonitor {
+	interval := defaultDisruptionInterval
+	if config.Interval != nil {
+		interval = config.Interval.Duration
+	}
+	return &disruptionMonitor{config: config, interval: interval}
+}
+
+func (m *disruptionMonitor) start(ctx context.Context) {
+	ctx, m.cancel = context.WithCancel(ctx)
+	m.report.Start = time.Now()
+	for _, target := range m.config.Targets {
+		r := &disruptionTargetReport{Name: target.Name, URL: target.URL}
+		if target.MaxDisruption != nil {
+			r.max = &target.MaxDisruption.Duration
+			r.MaxDisruption = target.MaxDisruption.Duration.String()
+		}
+		m.report.Targets = append(m.report.Targets, r)
+		m.wg.Add(1)
+		go func(target api.DisruptionTarget) {
+			defer m.wg.Done()
+			m.poll(ctx, target, r)
+		}(target)
+	}
+}
+
+// stop terminates the polling and returns the report.  Targets unavailable
+// when the monitor is stopped have their last window closed at that time.
+func (m *disruptionMonitor) stop() disruptionReport {
+	m.cancel()
+	m.wg.Wait()
+	m.report.End = time.Now()
+	return m.report
+}
+
+func (m *disruptionMonitor) poll(ctx context.Context, target api.DisruptionTarget, r *disruptionTargetReport) {
+	client := &http.Client{Timeout: m.interval}
+	if target.InsecureSkipTLSVerify {
+		// #nosec G402 -- explicitly requested for endpoints with self-signed certificates
+		client.Transport = &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}
+	}
+	var down *time.Time
+	closeWindow := func(end time.Time) {
+		r.Windows = append(r.Windows, disruptionWindow{Start: *down, End: end})
+		r.total += end.Sub(*down)
+		down = nil
+	}
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		now := time.Now()
+		available := targetAvailable(ctx, client, target.URL)
+		if ctx.Err() != nil {
+			// the request was interrupted by the monitor stopping
+			return
+		}
+		r.Requests++
+		if !available {
+			r.Failures++
+			if down == nil {
+				down = &now
+			}
+		} else if down != nil {
+			closeWindow(now)
+		}
+	}, m.interval)
+	if down != nil {
+		closeWindow(time.Now())
+	}
+	r.Disruption = r.total.String()
+}
+
+func targetAvailable(ctx context.Context, client *http.Client, url string) bool {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return false
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+	return resp.StatusCode < http.StatusInternalServerError
+}
+
+// startDisruptionMonitor starts monitoring the targets configured for the
+// test, if any.  The returned function stops the monitor, records the report
+// and returns an error if the disruption of any target exceeded its limit.
+func (s *multiStageTestStep) startDisruptionMonitor(ctx context.Context) func() error {
+	if s.resolved == nil || s.resolved.DisruptionMonitor == nil {
+		return func() error { return nil }
+	}
+	logrus.Infof("Starting disruption monitor for test %s", s.name)
+	m := newDisruptionMonitor(*s.resolved.DisruptionMonitor)
+	m.start(ctx)
+	return func() error {
+		report := m.stop()
+		if data, err := json.MarshalIndent(report, "", "  "); err != nil {
+			logrus.WithError(err).Warn("Failed to marshal disruption report")
+		} else if err := api.SaveArtifact(s.censor, path.Join(s.name, DisruptionReportArtifact), data); err != nil {
+			logrus.WithError(err).Warn("Failed to save disruption report")
+		}
+		return s.checkDisruption(report)
+	}
+}
+
+// checkDisruption records a jUnit test case for each target with a maximum
+// allowed disruption.
+func (s *multiStageTestStep) checkDisruption(report disruptionReport) error {
+	var breached []string
+	for _, t := range report.Targets {
+		logrus.Infof("Target %s of test %s was unavailable for %s (%d/%d requests failed)", t.Name, s.name, t.Disruption, t.Failures, t.Requests)
+		if t.max == nil {
+			continue
+		}
+		testCase := &junit.TestCase{
+			Name:      fmt.Sprintf("Disruption of %s in multi-stage test %s should not exceed %s", t.Name, s.name, t.MaxDisruption),
+			Duration:  report.End.Sub(report.Start).Seconds(),
+			SystemOut: fmt.Sprintf("%s was unavailable for %s in %d windows.", t.URL, t.Disruption, len(t.Windows)),
+		}
+		if t.total > *t.max {
+			msg := fmt.Sprintf("%s was unavailable for %s, more than the allowed %s", t.Name, t.Disruption, t.MaxDisruption)
+			testCase.FailureOutput = &junit.FailureOutput{Output: msg}
+			breached = append(breached, msg)
+		}
+		s.subLock.Lock()
+		s.subTests = append(s.subTests, testCase)
+		s.subLock.Unlock()
+	}
+	if len(breached) != 0 {
+		return fmt.Errorf("disruption exceeded the allowed maximum: %v", breached)
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package multi_stage
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	prowv1 "k8s.io/test-infra/prow/apis/prowjobs/v1"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/junit"
+)
+
+func TestDisruptionMonitor(t *testing.T) {
+	var down atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if down.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+	m := newDisruptionMonitor(api.DisruptionMonitor{
+		Targets:  []api.DisruptionTarget{{Name: "api", URL: server.URL}},
+		Interval: &prowv1.Duration{Duration: 5 * time.Millisecond},
+	})
+	m.start(context.Background())
+	time.Sleep(20 * time.Millisecond)
+	down.Store(true)
+	time.Sleep(50 * time.Millisecond)
+	down.Store(false)
+	time.Sleep(20 * time.Millisecond)
+	down.Store(true)
+	time.Sleep(20 * time.Millisecond)
+	report := m.stop()
+	if len(report.Targets) != 1 {
+		t.Fatalf("expected one target, got %d", len(report.Targets))
+	}
+	r := report.Targets[0]
+	if len(r.Windows) != 2 {
+		t.Fatalf("expected two disruption windows, got %d: %v", len(r.Windows), r.Windows)
+	}
+	if r.Failures == 0 || r.Failures >= r.Requests {
+		t.Errorf("expected some requests to fail, got %d/%d", r.Failures, r.Requests)
+	}
+	if r.total < 40*time.Millisecond || r.total > report.End.Sub(report.Start) {
+		t.Errorf("unexpected total disruption: %s", r.total)
+	}
+	if last := r.Windows[1]; last.End.After(report.End) {
+		t.Errorf("last window should be closed when the monitor stops, got %v", last)
+	}
+}
+
+func TestCheckDisruption(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	max := time.Minute
+	report := disruptionReport{
+		Start: start,
+		End:   start.Add(10 * time.Minute),
+		Targets: []*disruptionTargetReport{{
+			Name:       "ignored",
+			URL:        "https://ignored.example.com",
+			Disruption: "5m0s",
+			total:      5 * time.Minute,
+		}, {
+			Name:          "api",
+			URL:           "https://api.example.com",
+			Disruption:    "30s",
+			MaxDisruption: "1m0s",
+			Windows:       []disruptionWindow{{Start: start, End: start.Add(30 * time.Second)}},
+			total:         30 * time.Second,
+			max:           &max,
+		}, {
+			Name:          "ingress",
+			URL:           "https://ingress.example.com",
+			Disruption:    "2m0s",
+			MaxDisruption: "1m0s",
+			Windows:       []disruptionWindow{{Start: start, End: start.Add(2 * time.Minute)}},
+			total:         2 * time.Minute,
+			max:           &max,
+		}},
+	}
+	s := multiStageTestStep{name: "test", subLock: &sync.Mutex{}}
+	err := s.checkDisruption(report)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	expectedErr := "disruption exceeded the allowed maximum: [ingress was unavailable for 2m0s, more than the allowed 1m0s]"
+	if diff := cmp.Diff(expectedErr, err.Error()); diff != "" {
+		t.Errorf("unexpected error: %s", diff)
+	}
+	expected := []*junit.TestCase{{
+		Name:      "Disruption of api in multi-stage test test should not exceed 1m0s",
+		Duration:  600,
+		SystemOut: "https://api.example.com was unavailable for 30s in 1 windows.",
+	}, {
+		Name:          "Disruption of ingress in multi-stage test test should not exceed 1m0s",
+		Duration:      600,
+		SystemOut:     "https://ingress.example.com was unavailable for 2m0s in 1 windows.",
+		FailureOutput: &junit.FailureOutput{Output: "ingress was unavailable for 2m0s, more than the allowed 1m0s"},
+	}}
+	if diff := cmp.Diff(expected, s.subTests); diff != "" {
+		t.Errorf("unexpected test cases: %s", diff)
+	}
+}
@@ -0,0 +1,133 @@
+package multi_stage
+
+import (
+	"encoding/json"
+	"path"
+
+	"github.com/sirupsen/logrus"
+
+	coreapi "k8s.io/api/core/v1"
+
+	"github.com/openshift/ci-tools/pkg/api"
+)
+
+// EnvReportArtifact is the name of the artifact which records the environment
+// of a step and the source of each of its variables.
+const EnvReportArtifact = "env-report.json"
+
+// Sources of the variables in the environment of a step.
+const (
+	envSourceJob             = "job"
+	envSourceShard           = "shard"
+	envSourceLease           = "lease"
+	envSourceRelease         = "release"
+	envSourceCostAttribution = "cost_attribution"
+	envSourceFeatureSet      = "feature_set"
+	envSourceClusterProfile  = "cluster_profile"
+	envSourceClusterInfo     = "cluster_info"
+	envSourceParameter       = "parameter"
+	envSourceDependency      = "dependency"
+	envSourceClusterClaim    = "cluster_claim"
+	envSourceKubeconfig      = "kubeconfig"
+	envSourceStepResults     = "step_results"
+)
+
+// envEntry is a variable in the environment of a step, with its source and
+// the sources of the values it replaced.
+type envEntry struct {
+	Name      string   `json:"name"`
+	Value     string   `json:"value"`
+	Source    string   `json:"source"`
+	Overrides []string `json:"overrides,omitempty"`
+}
+
+// envBuilder composes the environment of a step from its sources, in order.
+// A variable set by more than one source keeps the position at which it was
+// first set and the value of the last source, which is recorded as a
+// conflict.
+type envBuilder struct {
+	entries []envEntry
+	index   map[string]int
+}
+
+func newEnvBuilder() *envBuilder {
+	return &envBuilder{index: map[string]int{}}
+}
+
+// add sets variables from a source.
+func (b *envBuilder) add(source string, vars ...coreapi.EnvVar) *envBuilder {
+	for _, v := range vars {
+		b.set(envEntry{Name: v.Name, Value: v.Value, Source: source})
+	}
+	return b
+}
+
+// merge sets the variables of another builder, keeping their sources.
+func (b *envBuilder) merge(other *envBuilder) *envBuilder {
+	if other == nil {
+		return b
+	}
+	for _, e := range other.entries {
+		e.Overrides = append([]string(nil), e.Overrides...)
+		b.set(e)
+	}
+	return b
+}
+
+func (b *envBuilder) set(e envEntry) {
+	i, ok := b.index[e.Name]
+	if !ok {
+		b.index[e.Name] = len(b.entries)
+		b.entries = append(b.entries, e)
+		return
+	}
+	prev := b.entries[i]
+	e.Overrides = append(append(append([]string(nil), prev.Overrides...), prev.Source), e.Overrides...)
+	b.entries[i] = e
+}
+
+// env returns the variables in the order in which they were first set.
+func (b *envBuilder) env() []coreapi.EnvVar {
+	if b == nil {
+		return nil
+	}
+	var ret []coreapi.EnvVar
+	for _, e := range b.entries {
+		ret = append(ret, coreapi.EnvVar{Name: e.Name, Value: e.Value})
+	}
+	return ret
+}
+
+// conflicts returns the names of the variables set by more than one source.
+func (b *envBuilder) conflicts() []string {
+	var ret []string
+	for _, e := range b.entries {
+		if len(e.Overrides) != 0 {
+			ret = append(ret, e.Name)
+		}
+	}
+	return ret
+}
+
+// saveEnvReport writes the environment of the pod of a step to its artifact
+// directory, with the source of each variable.
+func (s *multiStageTestStep) saveEnvReport(name string, env *envBuilder) {
+	if s.rendering {
+		return
+	}
+	if conflicts := env.conflicts(); len(conflicts) != 0 {
+		logrus.Debugf("Variables set by more than one source in the environment of step %s-%s: %v", s.name, name, conflicts)
+	}
+	entries := env.entries
+	if entries == nil {
+		entries = []envEntry{}
+	}
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		logrus.WithError(err).Warnf("Failed to marshal the environment report of step %s-%s", s.name, name)
+		return
+	}
+	if err := api.SaveArtifact(s.censor, path.Join(s.name, name, EnvReportArtifact), data); err != nil {
+		logrus.WithError(err).Warnf("Failed to save the environment report of step %s-%s", s.name, name)
+	}
+}
@@ -0,0 +1,99 @@
+package multi_stage
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	coreapi "k8s.io/api/core/v1"
+
+	"github.com/openshift/ci-tools/pkg/secrets"
+	"github.com/openshift/ci-tools/pkg/testhelper"
+)
+
+func TestEnvBuilder(t *testing.T) {
+	for _, tc := range []struct {
+		name              string
+		build             func() *envBuilder
+		expected          []coreapi.EnvVar
+		expectedEntries   []envEntry
+		expectedConflicts []string
+	}{{
+		name:  "empty",
+		build: newEnvBuilder,
+	}, {
+		name: "variables keep the order of their sources",
+		build: func() *envBuilder {
+			return newEnvBuilder().
+				add(envSourceJob, coreapi.EnvVar{Name: "NAMESPACE", Value: "ns"}).
+				add(envSourceParameter, coreapi.EnvVar{Name: "A", Value: "a"}, coreapi.EnvVar{Name: "B", Value: "b"})
+		},
+		expected: []coreapi.EnvVar{{Name: "NAMESPACE", Value: "ns"}, {Name: "A", Value: "a"}, {Name: "B", Value: "b"}},
+		expectedEntries: []envEntry{
+			{Name: "NAMESPACE", Value: "ns", Source: envSourceJob},
+			{Name: "A", Value: "a", Source: envSourceParameter},
+			{Name: "B", Value: "b", Source: envSourceParameter},
+		},
+	}, {
+		name: "later sources override earlier ones in place",
+		build: func() *envBuilder {
+			return newEnvBuilder().
+				add(envSourceLease, coreapi.EnvVar{Name: "LEASED_RESOURCE", Value: "uuid"}, coreapi.EnvVar{Name: "A", Value: "a"}).
+				add(envSourceParameter, coreapi.EnvVar{Name: "LEASED_RESOURCE", Value: "other"}).
+				add(envSourceDependency, coreapi.EnvVar{Name: "LEASED_RESOURCE", Value: "last"})
+		},
+		expected: []coreapi.EnvVar{{Name: "LEASED_RESOURCE", Value: "last"}, {Name: "A", Value: "a"}},
+		expectedEntries: []envEntry{
+			{Name: "LEASED_RESOURCE", Value: "last", Source: envSourceDependency, Overrides: []string{envSourceLease, envSourceParameter}},
+			{Name: "A", Value: "a", Source: envSourceLease},
+		},
+		expectedConflicts: []string{"LEASED_RESOURCE"},
+	}, {
+		name: "merged variables keep their sources",
+		build: func() *envBuilder {
+			test := newEnvBuilder().
+				add(envSourceRelease, coreapi.EnvVar{Name: "RELEASE_IMAGE_LATEST", Value: "latest"}).
+				add(envSourceClusterProfile, coreapi.EnvVar{Name: "CLUSTER_TYPE", Value: "aws"})
+			return newEnvBuilder().
+				add(envSourceJob, coreapi.EnvVar{Name: "CLUSTER_TYPE", Value: "gcp"}).
+				merge(test).
+				merge(nil)
+		},
+		expected: []coreapi.EnvVar{{Name: "CLUSTER_TYPE", Value: "aws"}, {Name: "RELEASE_IMAGE_LATEST", Value: "latest"}},
+		expectedEntries: []envEntry{
+			{Name: "CLUSTER_TYPE", Value: "aws", Source: envSourceClusterProfile, Overrides: []string{envSourceJob}},
+			{Name: "RELEASE_IMAGE_LATEST", Value: "latest", Source: envSourceRelease},
+		},
+		expectedConflicts: []string{"CLUSTER_TYPE"},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			b := tc.build()
+			testhelper.Diff(t, "env", b.env(), tc.expected)
+			testhelper.Diff(t, "entries", b.entries, tc.expectedEntries)
+			testhelper.Diff(t, "conflicts", b.conflicts(), tc.expectedConflicts)
+		})
+	}
+}
+
+func TestSaveEnvReport(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("ARTIFACTS", dir)
+	censor := secrets.NewDynamicCensor()
+	s := multiStageTestStep{name: "test", censor: &censor}
+	env := newEnvBuilder().
+		add(envSourceJob, coreapi.EnvVar{Name: "NAMESPACE", Value: "ns"}).
+		add(envSourceParameter, coreapi.EnvVar{Name: "NAMESPACE", Value: "other"})
+	s.saveEnvReport("test-step", env)
+	raw, err := os.ReadFile(filepath.Join(dir, "test", "test-step", EnvReportArtifact))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entries []envEntry
+	if err := json.Unmarshal(raw, &entries); err != nil {
+		t.Fatal(err)
+	}
+	testhelper.Diff(t, "report", entries, []envEntry{
+		{Name: "NAMESPACE", Value: "other", Source: envSourceParameter, Overrides: []string{envSourceJob}},
+	})
+}
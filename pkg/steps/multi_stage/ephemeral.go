@@ -0,0 +1,221 @@
+package multi_stage
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	coreapi "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	coreclientset "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/clientcmd"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/junit"
+	"github.com/openshift/ci-tools/pkg/kubernetes"
+	"github.com/openshift/ci-tools/pkg/results"
+	base_steps "github.com/openshift/ci-tools/pkg/steps"
+	"github.com/openshift/ci-tools/pkg/steps/loggingclient"
+	"github.com/openshift/ci-tools/pkg/steps/utils"
+	"github.com/openshift/ci-tools/pkg/util"
+)
+
+const (
+	// ephemeralKubeconfig is the file in the shared directory which holds the
+	// kubeconfig of the cluster under test.
+	ephemeralKubeconfig = "kubeconfig"
+	// ephemeralArtifactDir is where steps executed on the cluster under test
+	// write their artifacts.
+	ephemeralArtifactDir = "/tmp/artifacts"
+)
+
+// ephemeralClientFunc creates a client for the cluster under test from its
+// kubeconfig.
+type ephemeralClientFunc func(kubeconfig []byte, pendingTimeout time.Duration) (kubernetes.PodClient, error)
+
+func newEphemeralClient(kubeconfig []byte, pendingTimeout time.Duration) (kubernetes.PodClient, error) {
+	config, err := clientcmd.RESTConfigFromKubeConfig(kubeconfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load kubeconfig: %w", err)
+	}
+	client, err := ctrlruntimeclient.NewWithWatch(config, ctrlruntimeclient.Options{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to construct client: %w", err)
+	}
+	core, err := coreclientset.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to construct core client: %w", err)
+	}
+	return kubernetes.NewPodClient(loggingclient.New(client), config, core.RESTClient(), pendingTimeout), nil
+}
+
+func runsOnEphemeralCluster(step api.LiteralTestStep) bool {
+	return step.RunOnEphemeralCluster != nil && *step.RunOnEphemeralCluster
+}
+
+// runOnEphemeralCluster executes the step of a pod on the cluster under test.
+// A simplified version of the pod is created in a namespace with the same
+// name as the test namespace, along with copies of the shared directory, the
+// commands of the step and the credentials used to pull its image.  The
+// artifacts of the step are copied back once it exits.
+func (s *multiStageTestStep) runOnEphemeralCluster(ctx context.Context, pod *coreapi.Pod, notifier *base_steps.TestCaseNotifier, flags util.WaitForPodFlag) ([]*junit.TestCase, error) {
+	step, _ := s.stepFor(pod)
+	client, data, err := s.ephemeralClusterClient(ctx, fmt.Sprintf("step %s runs on the ephemeral cluster", step.As))
+	if err != nil {
+		return nil, err
+	}
+	stream, tag, _ := strings.Cut(pod.Spec.Containers[0].Image, ":")
+	image, err := utils.ImageDigestFor(s.client, s.jobSpec.Namespace, stream, tag)()
+	if err != nil {
+		return nil, fmt.Errorf("could not determine the pull spec of image %s: %w", pod.Spec.Containers[0].Image, err)
+	}
+	remote := ephemeralPod(s.name, pod, step, image)
+	if err := s.prepareEphemeralCluster(ctx, client, step, data, remote); err != nil {
+		return nil, results.ForReason("ephemeral_cluster").WithError(err).Errorf("failed to prepare the ephemeral cluster for step %s: %v", step.As, err)
+	}
+	if dir, ok := api.Artifacts(); ok {
+		worker := base_steps.NewArtifactWorker(client, filepath.Join(dir, s.name, strings.TrimPrefix(pod.Name, s.name+"-")), remote.Namespace)
+		worker.AddPod(remote)
+		notifier = base_steps.NewTestCaseNotifier(worker)
+	}
+	logrus.Infof("Running step %s on the ephemeral cluster.", pod.Name)
+	return s.runPodOn(ctx, client, remote, notifier, flags)
+}
+
+// ephemeralClusterClient creates a client for the cluster under test from the
+// kubeconfig in the shared directory and returns it with the contents of the
+// shared directory.  The use of the cluster is described in the error
+// returned when there is no kubeconfig.
+func (s *multiStageTestStep) ephemeralClusterClient(ctx context.Context, use string) (kubernetes.PodClient, map[string][]byte, error) {
+	secret := &coreapi.Secret{}
+	if err := s.client.Get(ctx, ctrlruntimeclient.ObjectKey{Namespace: s.jobSpec.Namespace(), Name: s.name}, secret); err != nil {
+		return nil, nil, fmt.Errorf("failed to get the shared directory: %w", err)
+	}
+	data, err := s.sharedDirData(secret)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read the shared directory: %w", err)
+	}
+	kubeconfig, ok := data[ephemeralKubeconfig]
+	if !ok {
+		return nil, nil, results.ForReason("ephemeral_cluster").ForError(fmt.Errorf("%s, but there is no %s in the shared directory", use, ephemeralKubeconfig))
+	}
+	newClient := s.ephemeralClient
+	if newClient == nil {
+		newClient = newEphemeralClient
+	}
+	client, err := newClient(kubeconfig, s.client.GetPendingTimeout())
+	if err != nil {
+		return nil, nil, results.ForReason("ephemeral_cluster").WithError(err).Errorf("failed to create a client for the ephemeral cluster: %v", err)
+	}
+	return client, data, nil
+}
+
+// ephemeralPod derives the pod executed on the cluster under test from the
+// one generated for the build farm.  Only the image, commands, environment
+// and shared directory of the step are kept.
+func ephemeralPod(testName string, pod *coreapi.Pod, step api.LiteralTestStep, image string) *coreapi.Pod {
+	test := pod.Spec.Containers[0]
+	var env []coreapi.EnvVar
+	for _, e := range test.Env {
+		switch e.Name {
+		case "ARTIFACT_DIR", "ENTRYPOINT_OPTIONS", SecretMountEnv, ClusterProfileMountEnv, api.CliEnv:
+			continue
+		}
+		if e.ValueFrom == nil {
+			env = append(env, e)
+		}
+	}
+	env = append(env, []coreapi.EnvVar{
+		{Name: "ARTIFACT_DIR", Value: ephemeralArtifactDir},
+		{Name: SecretMountEnv, Value: SecretMountPath},
+	}...)
+	command := execCommand(&step)
+	if command == nil {
+		command = []string{filepath.Join(CommandScriptMountPath, step.As)}
+	}
+	ret := &coreapi.Pod{
+		ObjectMeta: meta.ObjectMeta{
+			Name:      pod.Name,
+			Namespace: pod.Namespace,
+			Labels:    pod.Labels,
+		},
+		Spec: coreapi.PodSpec{
+			RestartPolicy:                 coreapi.RestartPolicyNever,
+			ActiveDeadlineSeconds:         pod.Spec.ActiveDeadlineSeconds,
+			TerminationGracePeriodSeconds: pod.Spec.TerminationGracePeriodSeconds,
+			ImagePullSecrets:              []coreapi.LocalObjectReference{{Name: api.RegistryPullCredentialsSecret}},
+			Containers: []coreapi.Container{{
+				Name:      containerName,
+				Image:     image,
+				Command:   command,
+				Env:       env,
+				Resources: test.Resources,
+				VolumeMounts: []coreapi.VolumeMount{
+					{Name: "artifacts", MountPath: ephemeralArtifactDir},
+					{Name: "shared-dir", MountPath: SecretMountPath},
+				},
+				TerminationMessagePolicy: coreapi.TerminationMessageFallbackToLogsOnError,
+			}},
+			Volumes: []coreapi.Volume{{
+				Name:         "artifacts",
+				VolumeSource: coreapi.VolumeSource{EmptyDir: &coreapi.EmptyDirVolumeSource{}},
+			}, {
+				Name:         "shared-dir",
+				VolumeSource: coreapi.VolumeSource{Secret: &coreapi.SecretVolumeSource{SecretName: testName}},
+			}},
+		},
+	}
+	if execCommand(&step) == nil {
+		addCommandScript(commandConfigMapForStep(testName, step.As), ret)
+	}
+	return ret
+}
+
+// prepareEphemeralCluster creates the objects required by the pod of a step
+// on the cluster under test.  Objects left by previous steps are replaced.
+func (s *multiStageTestStep) prepareEphemeralCluster(ctx context.Context, client ctrlruntimeclient.Client, step api.LiteralTestStep, sharedDir map[string][]byte, pod *coreapi.Pod) error {
+	ns := &coreapi.Namespace{ObjectMeta: meta.ObjectMeta{Name: pod.Namespace}}
+	if err := client.Create(ctx, ns); err != nil && !kerrors.IsAlreadyExists(err) {
+		return fmt.Errorf("could not create namespace %s: %w", ns.Name, err)
+	}
+	labels := map[string]string{MultiStageTestLabel: s.name}
+	objects := []ctrlruntimeclient.Object{
+		&coreapi.Secret{
+			ObjectMeta: meta.ObjectMeta{Namespace: ns.Name, Name: s.name, Labels: labels},
+			Data:       sharedDir,
+		},
+	}
+	if execCommand(&step) == nil {
+		objects = append(objects, &coreapi.ConfigMap{
+			ObjectMeta: meta.ObjectMeta{Namespace: ns.Name, Name: commandConfigMapForStep(s.name, step.As), Labels: labels},
+			Data:       map[string]string{step.As: commandScript(&step)},
+		})
+	}
+	pullSecret := &coreapi.Secret{}
+	if err := s.client.Get(ctx, ctrlruntimeclient.ObjectKey{Namespace: s.jobSpec.Namespace(), Name: api.RegistryPullCredentialsSecret}, pullSecret); err != nil {
+		if !kerrors.IsNotFound(err) {
+			return fmt.Errorf("could not get secret %s: %w", api.RegistryPullCredentialsSecret, err)
+		}
+	} else {
+		objects = append(objects, &coreapi.Secret{
+			ObjectMeta: meta.ObjectMeta{Namespace: ns.Name, Name: api.RegistryPullCredentialsSecret, Labels: labels},
+			Type:       pullSecret.Type,
+			Data:       pullSecret.Data,
+		})
+	}
+	for _, obj := range objects {
+		if err := client.Delete(ctx, obj); err != nil && !kerrors.IsNotFound(err) {
+			return fmt.Errorf("could not delete %s: %w", obj.GetName(), err)
+		}
+		if err := client.Create(ctx, obj); err != nil {
+			return fmt.Errorf("could not create %s: %w", obj.GetName(), err)
+		}
+	}
+	return nil
+}
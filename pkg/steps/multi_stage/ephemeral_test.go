@@ -0,0 +1,151 @@
+package multi_stage
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	imagev1 "github.com/openshift/api/image/v1"
+
+	coreapi "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	fakectrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/kubernetes"
+	base_steps "github.com/openshift/ci-tools/pkg/steps"
+	"github.com/openshift/ci-tools/pkg/steps/loggingclient"
+	"github.com/openshift/ci-tools/pkg/testhelper"
+	testhelper_kube "github.com/openshift/ci-tools/pkg/testhelper/kubernetes"
+	"github.com/openshift/ci-tools/pkg/util"
+)
+
+func TestRunOnEphemeralCluster(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := coreapi.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := imagev1.Install(scheme); err != nil {
+		t.Fatal(err)
+	}
+	yes := true
+	step := api.LiteralTestStep{As: "e2e", From: "src", Commands: "make e2e", RunOnEphemeralCluster: &yes}
+	jobSpec := api.JobSpec{}
+	jobSpec.SetNamespace("ns")
+	local := &testhelper_kube.FakePodClient{FakePodExecutor: &testhelper_kube.FakePodExecutor{
+		LoggingClient: loggingclient.New(fakectrlruntimeclient.NewClientBuilder().WithScheme(scheme).WithObjects(
+			&coreapi.Secret{
+				ObjectMeta: meta.ObjectMeta{Namespace: "ns", Name: "test"},
+				Data:       map[string][]byte{ephemeralKubeconfig: []byte("kubeconfig"), "file": []byte("content")},
+			},
+			&coreapi.Secret{
+				ObjectMeta: meta.ObjectMeta{Namespace: "ns", Name: api.RegistryPullCredentialsSecret},
+				Type:       coreapi.SecretTypeDockerConfigJson,
+				Data:       map[string][]byte{coreapi.DockerConfigJsonKey: []byte("{}")},
+			},
+			&imagev1.ImageStream{
+				ObjectMeta: meta.ObjectMeta{Namespace: "ns", Name: api.PipelineImageStream},
+				Status: imagev1.ImageStreamStatus{
+					PublicDockerImageRepository: "registry.ci.example.com/ns/pipeline",
+					Tags: []imagev1.NamedTagEventList{{
+						Tag:   "src",
+						Items: []imagev1.TagEvent{{Image: "sha256:abc"}},
+					}},
+				},
+			},
+		).Build()),
+	}}
+	remoteExecutor := &testhelper_kube.FakePodExecutor{
+		LoggingClient: loggingclient.New(fakectrlruntimeclient.NewClientBuilder().
+			WithIndex(&coreapi.Pod{}, "metadata.name", fakePodNameIndexer).
+			WithIndex(&coreapi.Event{}, "involvedObject.uid", func(o ctrlruntimeclient.Object) []string {
+				return []string{string(o.(*coreapi.Event).InvolvedObject.UID)}
+			}).
+			Build()),
+	}
+	remote := &testhelper_kube.FakePodClient{FakePodExecutor: remoteExecutor}
+	var kubeconfig string
+	s := multiStageTestStep{
+		name:    "test",
+		test:    []api.LiteralTestStep{step},
+		jobSpec: &jobSpec,
+		client:  local,
+		subLock: &sync.Mutex{},
+		ephemeralClient: func(data []byte, _ time.Duration) (kubernetes.PodClient, error) {
+			kubeconfig = string(data)
+			return remote, nil
+		},
+	}
+	pod := &coreapi.Pod{
+		ObjectMeta: meta.ObjectMeta{
+			Namespace: "ns",
+			Name:      "test-e2e",
+			Labels:    map[string]string{MultiStageTestLabel: "test", base_steps.LabelMetadataStep: "e2e"},
+		},
+		Spec: coreapi.PodSpec{
+			Containers: []coreapi.Container{{
+				Name:  containerName,
+				Image: "pipeline:src",
+				Env: []coreapi.EnvVar{
+					{Name: "NAMESPACE", Value: "ns"},
+					{Name: "ARTIFACT_DIR", Value: "/logs/artifacts"},
+					{Name: SecretMountEnv, Value: SecretMountPath},
+					{Name: "SECRET", ValueFrom: &coreapi.EnvVarSource{}},
+				},
+			}},
+		},
+	}
+	if _, err := s.runOnEphemeralCluster(context.Background(), pod, base_steps.NewTestCaseNotifier(util.NopNotifier), util.WaitForPodFlag(0)); err != nil {
+		t.Fatal(err)
+	}
+	testhelper.Diff(t, "kubeconfig", kubeconfig, "kubeconfig")
+	if len(remoteExecutor.CreatedPods) != 1 {
+		t.Fatalf("expected one pod on the ephemeral cluster, got %d", len(remoteExecutor.CreatedPods))
+	}
+	created := remoteExecutor.CreatedPods[0]
+	testhelper.Diff(t, "image", created.Spec.Containers[0].Image, "registry.ci.example.com/ns/pipeline@sha256:abc")
+	testhelper.Diff(t, "environment", created.Spec.Containers[0].Env, []coreapi.EnvVar{
+		{Name: "NAMESPACE", Value: "ns"},
+		{Name: "ARTIFACT_DIR", Value: ephemeralArtifactDir},
+		{Name: SecretMountEnv, Value: SecretMountPath},
+	})
+	for _, obj := range []ctrlruntimeclient.Object{
+		&coreapi.Namespace{ObjectMeta: meta.ObjectMeta{Name: "ns"}},
+		&coreapi.Secret{ObjectMeta: meta.ObjectMeta{Namespace: "ns", Name: "test"}},
+		&coreapi.Secret{ObjectMeta: meta.ObjectMeta{Namespace: "ns", Name: api.RegistryPullCredentialsSecret}},
+		&coreapi.ConfigMap{ObjectMeta: meta.ObjectMeta{Namespace: "ns", Name: commandConfigMapForStep("test", "e2e")}},
+	} {
+		if err := remoteExecutor.Get(context.Background(), ctrlruntimeclient.ObjectKeyFromObject(obj), obj); err != nil {
+			t.Errorf("expected %s to be created on the ephemeral cluster: %v", obj.GetName(), err)
+		}
+	}
+}
+
+func TestRunOnEphemeralClusterWithoutKubeconfig(t *testing.T) {
+	yes := true
+	jobSpec := api.JobSpec{}
+	jobSpec.SetNamespace("ns")
+	s := multiStageTestStep{
+		name:    "test",
+		test:    []api.LiteralTestStep{{As: "e2e", RunOnEphemeralCluster: &yes}},
+		jobSpec: &jobSpec,
+		client: &testhelper_kube.FakePodClient{FakePodExecutor: &testhelper_kube.FakePodExecutor{
+			LoggingClient: loggingclient.New(fakectrlruntimeclient.NewClientBuilder().WithObjects(
+				&coreapi.Secret{ObjectMeta: meta.ObjectMeta{Namespace: "ns", Name: "test"}},
+			).Build()),
+		}},
+	}
+	pod := &coreapi.Pod{ObjectMeta: meta.ObjectMeta{
+		Namespace: "ns",
+		Name:      "test-e2e",
+		Labels:    map[string]string{base_steps.LabelMetadataStep: "e2e"},
+	}}
+	_, err := s.runOnEphemeralCluster(context.Background(), pod, base_steps.NewTestCaseNotifier(util.NopNotifier), util.WaitForPodFlag(0))
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	testhelper.Diff(t, "error", err.Error(), "step e2e runs on the ephemeral cluster, but there is no kubeconfig in the shared directory")
+}
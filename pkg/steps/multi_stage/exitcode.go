@@ -0,0 +1,104 @@
+package multi_stage
+
+import (
+	"fmt"
+
+	coreapi "k8s.io/api/core/v1"
+
+	"github.com/openshift/ci-tools/pkg/junit"
+	"github.com/openshift/ci-tools/pkg/results"
+)
+
+// Exit codes with which step commands communicate intent to ci-operator
+// beyond a plain failure.
+const (
+	// ExitCodeSkip reports that the step decided it does not apply, e.g.
+	// because of the contents of the change being tested.  The step is
+	// reported as skipped and does not fail the test.
+	ExitCodeSkip = 80
+	// ExitCodeRetry reports a failure which the step knows to be a flake.
+	// The step is executed once more and its second result is used.
+	ExitCodeRetry = 81
+	// ExitCodeInfrastructureFailure reports a failure which is not caused by
+	// the code being tested.  The step still fails, but with a distinct
+	// reason.
+	ExitCodeInfrastructureFailure = 82
+)
+
+// ExitCodeProperty is the name of the jUnit property added to the test cases
+// of steps which exited with a special code, with its meaning as the value.
+const ExitCodeProperty = "step_exit_code"
+
+// stepIntent is the meaning of a special exit code.
+type stepIntent string
+
+const (
+	intentSkip                  stepIntent = "skip"
+	intentRetry                 stepIntent = "retry"
+	intentInfrastructureFailure stepIntent = "infrastructure_failure"
+)
+
+var exitCodeIntents = map[int32]stepIntent{
+	ExitCodeSkip:                  intentSkip,
+	ExitCodeRetry:                 intentRetry,
+	ExitCodeInfrastructureFailure: intentInfrastructureFailure,
+}
+
+// maxRetryRequests is the number of times a step which requested to be
+// retried is executed again.
+const maxRetryRequests = 1
+
+// failedStepIntent returns the intent communicated by the command of a failed
+// step pod through its exit code, if any.  Pods which were terminated by
+// their deadline are not considered, as the command did not exit by itself.
+func failedStepIntent(pod *coreapi.Pod) (stepIntent, bool) {
+	if pod.Status.Phase != coreapi.PodFailed || pod.Status.Reason == "DeadlineExceeded" {
+		return "", false
+	}
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.Name != containerName {
+			continue
+		}
+		if t := status.State.Terminated; t != nil {
+			intent, ok := exitCodeIntents[t.ExitCode]
+			return intent, ok
+		}
+	}
+	return "", false
+}
+
+// retryRequestedError is returned for steps which failed and requested to
+// be executed again.
+type retryRequestedError struct {
+	error
+}
+
+func (e *retryRequestedError) Unwrap() error {
+	return e.error
+}
+
+// intentTestCase records the intent of the exit code of a step in a jUnit
+// test case, which is skipped if the step requested it and failed otherwise.
+func intentTestCase(name string, intent stepIntent, output string) *junit.TestCase {
+	test := &junit.TestCase{
+		Name:          name,
+		Properties:    []*junit.TestSuiteProperty{{Name: ExitCodeProperty, Value: string(intent)}},
+		FailureOutput: &junit.FailureOutput{Output: output},
+	}
+	if intent == intentSkip {
+		skip(test, fmt.Sprintf("the step requested to be skipped with exit code %d", ExitCodeSkip))
+	}
+	return test
+}
+
+// wrapStepError annotates the error of a failed step with the intent of its
+// exit code.
+func wrapStepError(intent stepIntent, err error) error {
+	switch intent {
+	case intentRetry:
+		return &retryRequestedError{error: err}
+	case intentInfrastructureFailure:
+		return results.ForReason("step_infrastructure_failure").ForError(err)
+	}
+	return err
+}
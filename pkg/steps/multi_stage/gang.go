@@ -0,0 +1,177 @@
+package multi_stage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	coreapi "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/retry"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/openshift/ci-tools/pkg/junit"
+	"github.com/openshift/ci-tools/pkg/results"
+	base_steps "github.com/openshift/ci-tools/pkg/steps"
+)
+
+const (
+	gangPollInterval = 15 * time.Second
+	// gangScheduleTimeout bounds the time a pod of a gang can wait to be
+	// created or scheduled while its peers exist or are running.
+	gangScheduleTimeout = 10 * time.Minute
+)
+
+var gangGatePollInterval = time.Second
+
+// runGang executes the pods of the steps of a gang in parallel.  The pods are
+// created with a scheduling gate, which is only removed once all of them
+// exist, so that none of them starts before its peers can be scheduled.  The
+// steps of a gang depend on each other, so if one of the pods cannot be
+// created or scheduled, the whole gang is stopped instead of letting its peers
+// wait until they time out.
+func (s *multiStageTestStep) runGang(ctx context.Context, pods []coreapi.Pod, bestEffortSteps sets.Set[string]) []error {
+	gang := pods[0].Labels[GangLabel]
+	release, err := s.options.StepPodLimiter.acquire(ctx, "gang "+gang, len(pods))
+	if err != nil {
+		return []error{err}
+	}
+	defer release()
+	start := time.Now()
+	ctx, stop := context.WithCancelCause(ctx)
+	var unschedulable error
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if unschedulable = s.releaseGang(ctx, pods, gangGatePollInterval, gangScheduleTimeout); unschedulable != nil {
+			stop(unschedulable)
+			return
+		}
+		if unschedulable = s.watchGang(ctx, pods, gangPollInterval, gangScheduleTimeout); unschedulable != nil {
+			stop(unschedulable)
+		}
+	}()
+	errs := s.runShards(ctx, pods, bestEffortSteps)
+	stop(nil)
+	<-done
+	testCase := &junit.TestCase{
+		Name:     fmt.Sprintf("%s - %s gang scheduling", s.Description(), gang),
+		Duration: time.Since(start).Seconds(),
+	}
+	if unschedulable != nil {
+		logrus.Warnf("Stopping the steps of gang %s: %v", gang, unschedulable)
+		for i := range pods {
+			if err := s.client.Delete(base_steps.CleanupCtx, &pods[i]); err != nil && !kerrors.IsNotFound(err) {
+				logrus.WithError(err).Warnf("Failed to delete pod %s of gang %s", pods[i].Name, gang)
+			}
+		}
+		testCase.FailureOutput = &junit.FailureOutput{Output: unschedulable.Error()}
+		errs = append(errs, &results.InfrastructureError{Err: results.ForReason("scheduling_gang").WithError(unschedulable).Errorf("gang %s failed: %v", gang, unschedulable)})
+	}
+	s.subLock.Lock()
+	s.subTests = append(s.subTests, testCase)
+	s.subLock.Unlock()
+	return errs
+}
+
+// releaseGang waits until all pods of a gang were created and removes their
+// scheduling gate, returning an error if they were not created in time.
+func (s *multiStageTestStep) releaseGang(ctx context.Context, pods []coreapi.Pod, interval, timeout time.Duration) error {
+	err := wait.PollUntilContextTimeout(ctx, interval, timeout, true, func(ctx context.Context) (bool, error) {
+		for i := range pods {
+			pod := &coreapi.Pod{}
+			if err := s.client.Get(ctx, ctrlruntimeclient.ObjectKeyFromObject(&pods[i]), pod); err != nil {
+				return false, nil
+			}
+			// a pod left over from a previous execution is replaced
+			if pod.DeletionTimestamp != nil || (!hasGangSchedulingGate(pod) && (pod.Status.Phase == coreapi.PodSucceeded || pod.Status.Phase == coreapi.PodFailed)) {
+				return false, nil
+			}
+		}
+		return true, nil
+	})
+	if err != nil {
+		if ctx.Err() != nil {
+			// the gang finished or the test was cancelled
+			return nil
+		}
+		return fmt.Errorf("the pods were not all created within %s", timeout)
+	}
+	for i := range pods {
+		if err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+			pod := &coreapi.Pod{}
+			if err := s.client.Get(ctx, ctrlruntimeclient.ObjectKeyFromObject(&pods[i]), pod); err != nil {
+				return err
+			}
+			if !hasGangSchedulingGate(pod) {
+				return nil
+			}
+			var gates []coreapi.PodSchedulingGate
+			for _, gate := range pod.Spec.SchedulingGates {
+				if gate.Name != GangSchedulingGate {
+					gates = append(gates, gate)
+				}
+			}
+			pod.Spec.SchedulingGates = gates
+			return s.client.Update(ctx, pod)
+		}); err != nil {
+			return fmt.Errorf("failed to remove the scheduling gate of pod %s: %w", pods[i].Name, err)
+		}
+	}
+	return nil
+}
+
+func hasGangSchedulingGate(pod *coreapi.Pod) bool {
+	for _, gate := range pod.Spec.SchedulingGates {
+		if gate.Name == GangSchedulingGate {
+			return true
+		}
+	}
+	return false
+}
+
+// watchGang polls the pods of a gang until the context is done and returns an
+// error if one of them cannot be scheduled while its peers are running.
+func (s *multiStageTestStep) watchGang(ctx context.Context, pods []coreapi.Pod, interval, timeout time.Duration) error {
+	var ret error
+	_ = wait.PollUntilContextCancel(ctx, interval, false, func(ctx context.Context) (bool, error) {
+		var current []coreapi.Pod
+		for i := range pods {
+			pod := &coreapi.Pod{}
+			// pods which were not created yet are not considered
+			if err := s.client.Get(ctx, ctrlruntimeclient.ObjectKeyFromObject(&pods[i]), pod); err == nil {
+				current = append(current, *pod)
+			}
+		}
+		ret = unschedulableGangPod(current, time.Now(), timeout)
+		return ret != nil, nil
+	})
+	return ret
+}
+
+// unschedulableGangPod returns an error if a pod of a gang has been
+// unschedulable for longer than the timeout while one of its peers runs.
+func unschedulableGangPod(pods []coreapi.Pod, now time.Time, timeout time.Duration) error {
+	var running bool
+	for _, pod := range pods {
+		running = running || pod.Status.Phase == coreapi.PodRunning
+	}
+	if !running {
+		return nil
+	}
+	for _, pod := range pods {
+		for _, c := range pod.Status.Conditions {
+			if c.Type != coreapi.PodScheduled || c.Status != coreapi.ConditionFalse || c.Reason != coreapi.PodReasonUnschedulable {
+				continue
+			}
+			if now.Sub(c.LastTransitionTime.Time) > timeout {
+				return fmt.Errorf("pod %s could not be scheduled for %s while its peers were running: %s", pod.Name, timeout, c.Message)
+			}
+		}
+	}
+	return nil
+}
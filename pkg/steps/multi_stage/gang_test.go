@@ -0,0 +1,173 @@
+package multi_stage
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	coreapi "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	fakectrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/openshift/ci-tools/pkg/kubernetes"
+	"github.com/openshift/ci-tools/pkg/steps/loggingclient"
+	"github.com/openshift/ci-tools/pkg/testhelper"
+)
+
+func TestGroupShardsGangs(t *testing.T) {
+	podFor := func(name string, labels map[string]string) coreapi.Pod {
+		return coreapi.Pod{ObjectMeta: meta.ObjectMeta{Name: name, Labels: labels}}
+	}
+	pods := []coreapi.Pod{
+		podFor("install", nil),
+		podFor("server", map[string]string{GangLabel: "perf"}),
+		podFor("client", map[string]string{GangLabel: "perf"}),
+		podFor("e2e-0", map[string]string{ShardLabel: "e2e"}),
+		podFor("e2e-1", map[string]string{ShardLabel: "e2e"}),
+		podFor("other-server", map[string]string{GangLabel: "other"}),
+		podFor("other-client", map[string]string{GangLabel: "other"}),
+	}
+	var actual [][]string
+	for _, group := range groupShards(pods) {
+		var names []string
+		for _, pod := range group {
+			names = append(names, pod.Name)
+		}
+		actual = append(actual, names)
+	}
+	testhelper.Diff(t, "groups", actual, [][]string{
+		{"install"},
+		{"server", "client"},
+		{"e2e-0", "e2e-1"},
+		{"other-server", "other-client"},
+	})
+}
+
+func TestUnschedulableGangPod(t *testing.T) {
+	now := time.Date(2023, 1, 1, 1, 0, 0, 0, time.UTC)
+	running := coreapi.Pod{
+		ObjectMeta: meta.ObjectMeta{Name: "server"},
+		Status:     coreapi.PodStatus{Phase: coreapi.PodRunning},
+	}
+	pending := func(since time.Duration) coreapi.Pod {
+		return coreapi.Pod{
+			ObjectMeta: meta.ObjectMeta{Name: "client"},
+			Status: coreapi.PodStatus{
+				Phase: coreapi.PodPending,
+				Conditions: []coreapi.PodCondition{{
+					Type:               coreapi.PodScheduled,
+					Status:             coreapi.ConditionFalse,
+					Reason:             coreapi.PodReasonUnschedulable,
+					Message:            "0/3 nodes are available: 3 Insufficient cpu.",
+					LastTransitionTime: meta.NewTime(now.Add(-since)),
+				}},
+			},
+		}
+	}
+	for _, tc := range []struct {
+		name     string
+		pods     []coreapi.Pod
+		expected error
+	}{{
+		name: "all pods are running",
+		pods: []coreapi.Pod{running, running},
+	}, {
+		name: "pod was unschedulable for a short time",
+		pods: []coreapi.Pod{running, pending(time.Minute)},
+	}, {
+		name: "no peer is running",
+		pods: []coreapi.Pod{pending(time.Hour), pending(time.Hour)},
+	}, {
+		name:     "pod is unschedulable while its peer is running",
+		pods:     []coreapi.Pod{running, pending(time.Hour)},
+		expected: errors.New("pod client could not be scheduled for 10m0s while its peers were running: 0/3 nodes are available: 3 Insufficient cpu."),
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := unschedulableGangPod(tc.pods, now, 10*time.Minute)
+			testhelper.Diff(t, "error", err, tc.expected, testhelper.EquateErrorMessage)
+		})
+	}
+}
+
+func TestReleaseGang(t *testing.T) {
+	gated := func(name string, phase coreapi.PodPhase) *coreapi.Pod {
+		return &coreapi.Pod{
+			ObjectMeta: meta.ObjectMeta{Namespace: "ns", Name: name, Labels: map[string]string{GangLabel: "perf"}},
+			Spec: coreapi.PodSpec{SchedulingGates: []coreapi.PodSchedulingGate{
+				{Name: "other"},
+				{Name: GangSchedulingGate},
+			}},
+			Status: coreapi.PodStatus{Phase: phase},
+		}
+	}
+	released := func(name string, phase coreapi.PodPhase) *coreapi.Pod {
+		pod := gated(name, phase)
+		pod.Spec.SchedulingGates = pod.Spec.SchedulingGates[:1]
+		return pod
+	}
+	for _, tc := range []struct {
+		name        string
+		objects     []ctrlruntimeclient.Object
+		expected    map[string][]coreapi.PodSchedulingGate
+		expectedErr error
+	}{{
+		name:    "all pods exist",
+		objects: []ctrlruntimeclient.Object{gated("server", coreapi.PodPending), gated("client", coreapi.PodPending)},
+		expected: map[string][]coreapi.PodSchedulingGate{
+			"server": {{Name: "other"}},
+			"client": {{Name: "other"}},
+		},
+	}, {
+		name:    "pod already released",
+		objects: []ctrlruntimeclient.Object{released("server", coreapi.PodRunning), gated("client", coreapi.PodPending)},
+		expected: map[string][]coreapi.PodSchedulingGate{
+			"server": {{Name: "other"}},
+			"client": {{Name: "other"}},
+		},
+	}, {
+		name:        "pod not created",
+		objects:     []ctrlruntimeclient.Object{gated("server", coreapi.PodPending)},
+		expected:    map[string][]coreapi.PodSchedulingGate{"server": {{Name: "other"}, {Name: GangSchedulingGate}}},
+		expectedErr: errors.New("the pods were not all created within 10ms"),
+	}, {
+		name:    "pod left over from a previous execution",
+		objects: []ctrlruntimeclient.Object{gated("server", coreapi.PodPending), released("client", coreapi.PodSucceeded)},
+		expected: map[string][]coreapi.PodSchedulingGate{
+			"server": {{Name: "other"}, {Name: GangSchedulingGate}},
+			"client": {{Name: "other"}},
+		},
+		expectedErr: errors.New("the pods were not all created within 10ms"),
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			client := fakectrlruntimeclient.NewClientBuilder().WithObjects(tc.objects...).Build()
+			s := &multiStageTestStep{client: kubernetes.NewPodClient(loggingclient.New(client), nil, nil, 0)}
+			pods := []coreapi.Pod{
+				{ObjectMeta: meta.ObjectMeta{Namespace: "ns", Name: "server"}},
+				{ObjectMeta: meta.ObjectMeta{Namespace: "ns", Name: "client"}},
+			}
+			err := s.releaseGang(context.Background(), pods, time.Millisecond, 10*time.Millisecond)
+			testhelper.Diff(t, "error", err, tc.expectedErr, testhelper.EquateErrorMessage)
+			actual := map[string][]coreapi.PodSchedulingGate{}
+			for _, pod := range pods {
+				current := &coreapi.Pod{}
+				if err := client.Get(context.Background(), ctrlruntimeclient.ObjectKeyFromObject(&pod), current); err == nil {
+					actual[pod.Name] = current.Spec.SchedulingGates
+				}
+			}
+			testhelper.Diff(t, "scheduling gates", actual, tc.expected)
+		})
+	}
+}
+
+func TestReleaseGangCancelled(t *testing.T) {
+	client := fakectrlruntimeclient.NewClientBuilder().Build()
+	s := &multiStageTestStep{client: kubernetes.NewPodClient(loggingclient.New(client), nil, nil, 0)}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	pods := []coreapi.Pod{{ObjectMeta: meta.ObjectMeta{Namespace: "ns", Name: "server"}}}
+	if err := s.releaseGang(ctx, pods, time.Millisecond, time.Minute); err != nil {
+		t.Errorf("expected no error when the gang is stopped, got: %v", err)
+	}
+}
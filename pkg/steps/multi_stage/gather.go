@@ -0,0 +1,65 @@
+package multi_stage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	coreapi "k8s.io/api/core/v1"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/junit"
+)
+
+// gatherDefaultTimeout is the default timeout of gather steps, so that a
+// stuck gather step does not consume the time left for those after it.
+const gatherDefaultTimeout = 30 * time.Minute
+
+func isGatherStep(step *api.LiteralTestStep) bool {
+	return step.Gather != nil && *step.Gather
+}
+
+// splitGatherSteps separates the gather steps from the other post steps,
+// preserving their order.
+func splitGatherSteps(steps []api.LiteralTestStep) (post, gather []api.LiteralTestStep) {
+	for _, step := range steps {
+		if isGatherStep(&step) {
+			gather = append(gather, step)
+		} else {
+			post = append(post, step)
+		}
+	}
+	return post, gather
+}
+
+// runGatherSteps executes the gather steps after all other steps.  They are
+// not affected by the cancellation of the test and their test cases are
+// reported in a dedicated suite.
+func (s *multiStageTestStep) runGatherSteps(
+	steps []api.LiteralTestStep,
+	env *envBuilder,
+	secretVolumes []coreapi.Volume,
+	secretVolumeMounts []coreapi.VolumeMount,
+) error {
+	s.subLock.Lock()
+	n := len(s.subTests)
+	s.subLock.Unlock()
+	err := s.runSteps(context.Background(), "gather", steps, env, secretVolumes, secretVolumeMounts)
+	s.subLock.Lock()
+	defer s.subLock.Unlock()
+	suite := &junit.TestSuite{Name: fmt.Sprintf("%s - gather", s.Description())}
+	for _, test := range s.subTests[n:] {
+		suite.NumTests++
+		if test.FailureOutput != nil {
+			suite.NumFailed++
+		}
+		if test.SkipMessage != nil {
+			suite.NumSkipped++
+		}
+		suite.Duration += test.Duration
+		suite.TestCases = append(suite.TestCases, test)
+	}
+	s.subTests = s.subTests[:n:n]
+	s.subSuites = append(s.subSuites, suite)
+	return err
+}
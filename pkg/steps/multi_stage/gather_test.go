@@ -0,0 +1,111 @@
+package multi_stage
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	coreapi "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	prowdapi "k8s.io/test-infra/prow/pod-utils/downwardapi"
+	fakectrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/junit"
+	"github.com/openshift/ci-tools/pkg/secrets"
+	"github.com/openshift/ci-tools/pkg/steps"
+	"github.com/openshift/ci-tools/pkg/steps/loggingclient"
+	testhelper_kube "github.com/openshift/ci-tools/pkg/testhelper/kubernetes"
+)
+
+func TestRunGatherSteps(t *testing.T) {
+	sa := &coreapi.ServiceAccount{ObjectMeta: meta.ObjectMeta{Name: "test", Namespace: "test-namespace", Labels: map[string]string{MultiStageTestLabel: "test"}}}
+	crclient := &testhelper_kube.FakePodExecutor{
+		Lock: sync.RWMutex{},
+		LoggingClient: loggingclient.New(
+			fakectrlruntimeclient.NewClientBuilder().
+				WithIndex(&coreapi.Pod{}, "metadata.name", fakePodNameIndexer).
+				WithObjects(sa).
+				Build()),
+		Failures: sets.New[string]("test-e2e"),
+	}
+	jobSpec := api.JobSpec{
+		JobSpec: prowdapi.JobSpec{
+			Job:       "job",
+			BuildID:   "build_id",
+			ProwJobID: "prow_job_id",
+			Type:      prowapi.PeriodicJob,
+			DecorationConfig: &prowapi.DecorationConfig{
+				Timeout:     &prowapi.Duration{Duration: time.Minute},
+				GracePeriod: &prowapi.Duration{Duration: time.Second},
+				UtilityImages: &prowapi.UtilityImages{
+					Sidecar:    "sidecar",
+					Entrypoint: "entrypoint",
+				},
+			},
+		},
+	}
+	jobSpec.SetNamespace("test-namespace")
+	client := &testhelper_kube.FakePodClient{FakePodExecutor: crclient}
+	censor := secrets.NewDynamicCensor()
+	yes := true
+	step := MultiStageTestStep(api.TestStepConfiguration{
+		As: "test",
+		MultiStageTestConfigurationLiteral: &api.MultiStageTestConfigurationLiteral{
+			Test: []api.LiteralTestStep{{As: "e2e"}},
+			Post: []api.LiteralTestStep{{As: "gather", Gather: &yes}, {As: "post0"}, {As: "post1"}},
+		},
+	}, &api.ReleaseBuildConfiguration{}, nil, client, &jobSpec, nil, "node-name", "", &censor, Options{})
+	if err := step.Run(context.Background()); err == nil {
+		t.Fatal("expected an error")
+	}
+	var names []string
+	for _, pod := range crclient.CreatedPods {
+		names = append(names, pod.Name)
+	}
+	if diff := cmp.Diff([]string{"test-e2e", "test-post0", "test-post1", "test-gather"}, names); diff != "" {
+		t.Errorf("did not execute pods in the correct order: %s", diff)
+	}
+	phase := "Run multi-stage test gather phase"
+	for _, tc := range step.(steps.SubtestReporter).SubTests() {
+		if tc.Name == phase {
+			t.Errorf("gather test case was not moved to its suite")
+		}
+	}
+	var suite *junit.TestSuite
+	for _, s := range step.(steps.SubSuiteReporter).SubSuites() {
+		if s.Name == "Run multi-stage test test - gather" {
+			suite = s
+		}
+	}
+	if suite == nil {
+		t.Fatal("no suite was created for the gather steps")
+	}
+	var found bool
+	for _, tc := range suite.TestCases {
+		found = found || tc.Name == phase
+	}
+	if !found {
+		t.Errorf("gather suite does not contain the test case of the phase: %v", suite.TestCases)
+	}
+	if suite.NumTests != uint(len(suite.TestCases)) || suite.NumFailed != 0 {
+		t.Errorf("unexpected counts in the gather suite: %d tests, %d failed", suite.NumTests, suite.NumFailed)
+	}
+}
+
+func TestStepTimeoutGather(t *testing.T) {
+	yes := true
+	var s multiStageTestStep
+	if timeout := s.stepTimeout(&api.LiteralTestStep{Gather: &yes}); timeout != gatherDefaultTimeout {
+		t.Errorf("expected the default timeout of gather steps, got %s", timeout)
+	}
+	explicit := &prowapi.Duration{Duration: time.Hour}
+	if timeout := s.stepTimeout(&api.LiteralTestStep{Gather: &yes, Timeout: explicit}); timeout != time.Hour {
+		t.Errorf("expected the timeout of the step, got %s", timeout)
+	}
+}
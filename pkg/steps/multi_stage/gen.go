@@ -1,9 +1,12 @@
 package multi_stage
 
 import (
+	"crypto/sha256"
 	"fmt"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/sirupsen/logrus"
 
@@ -16,6 +19,8 @@ import (
 
 	"github.com/openshift/ci-tools/pkg/api"
 	base_steps "github.com/openshift/ci-tools/pkg/steps"
+	"github.com/openshift/ci-tools/pkg/steps/logs"
+	"github.com/openshift/ci-tools/pkg/steps/shareddir"
 	"github.com/openshift/ci-tools/pkg/steps/utils"
 )
 
@@ -23,8 +28,46 @@ const (
 	containerName     = "test"
 	profileVolumeName = "cluster-profile"
 	vpnContainerName  = "vpn-client"
+	sidecarVolumeName = "sidecars"
+	scratchVolumeName = "scratch"
+
+	// ScratchDirPath is where the scratch directory of a step is mounted
+	// when it does not declare a `workdir`.
+	ScratchDirPath = "/scratch"
+	// ScratchDirEnv holds the path of the scratch directory of a step.
+	ScratchDirEnv = "SCRATCH_DIR"
+
+	// SidecarDirPath is where the directory shared by a step and its sidecars
+	// is mounted.
+	SidecarDirPath = "/var/run/ci.openshift.io/sidecars"
+	// SidecarDirEnv holds the path of the directory shared with sidecars.
+	SidecarDirEnv = "SIDECAR_DIR"
+	// sidecarReadinessTimeout is the maximum time the test container waits
+	// for each sidecar to become ready.
+	sidecarReadinessTimeout = 10 * time.Minute
 )
 
+// sidecarScript runs the commands of a sidecar in the background.  When a
+// readiness command is set, it is executed until it succeeds and the sidecar
+// is then marked as ready in the shared directory.  The commands are stopped
+// once the test container exits, signaled by the marker file written by the
+// Prow entrypoint.  A sidecar which exits on its own propagates its exit code.
+const sidecarScript = `bash -c "$1" &
+pid=$!
+if [[ -n "$2" ]]; then
+  until bash -c "$2"; do
+    if ! kill -0 "${pid}" 2>/dev/null; then wait "${pid}"; exit; fi
+    sleep 1
+  done
+  touch "$3"
+fi
+while [[ ! -f /logs/marker-file.txt ]]; do
+  if ! kill -0 "${pid}" 2>/dev/null; then wait "${pid}"; exit; fi
+  sleep 1
+done
+kill "${pid}" 2>/dev/null || true
+`
+
 func (s *multiStageTestStep) generateObservers(
 	observers []api.Observer,
 	secretVolumes []coreapi.Volume,
@@ -59,9 +102,38 @@ func defaultGeneratePodOptions() *generatePodOptions {
 	}
 }
 
+// stepTimeout returns the time the commands of a step are allowed to run.
+func (s *multiStageTestStep) stepTimeout(step *api.LiteralTestStep) time.Duration {
+	if step.Timeout != nil {
+		return step.Timeout.Duration
+	}
+	if isGatherStep(step) {
+		return gatherDefaultTimeout
+	}
+	if s.flags&containerOnly != 0 {
+		return containerOnlyDefaultTimeout
+	}
+	return entrypoint.DefaultTimeout
+}
+
+// stepImage returns the image stream tag the container of a step runs.
+func (s *multiStageTestStep) stepImage(step api.LiteralTestStep, claimRelease *api.ClaimRelease) string {
+	if link, ok := step.FromImageTag(); ok {
+		return fmt.Sprintf("%s:%s", api.PipelineImageStream, link)
+	}
+	return s.dependencyImage(step.From, claimRelease)
+}
+
+// dependencyImage returns the image stream tag of an image referenced by a
+// step, e.g. `src` or `stable:installer`.
+func (s *multiStageTestStep) dependencyImage(name string, claimRelease *api.ClaimRelease) string {
+	stream, tag, _ := s.config.DependencyParts(api.StepDependency{Name: name}, claimRelease)
+	return fmt.Sprintf("%s:%s", stream, tag)
+}
+
 func (s *multiStageTestStep) generatePods(
 	steps []api.LiteralTestStep,
-	env []coreapi.EnvVar,
+	env *envBuilder,
 	secretVolumes []coreapi.Volume,
 	secretVolumeMounts []coreapi.VolumeMount,
 	genPodOpts *generatePodOptions,
@@ -75,24 +147,15 @@ func (s *multiStageTestStep) generatePods(
 	}
 	var ret []coreapi.Pod
 	var errs []error
-	var claimRelease *api.ClaimRelease
-	if s.clusterClaim != nil {
-		claimRelease = s.clusterClaim.ClaimRelease(s.name)
-	}
-	for _, step := range steps {
-		name := fmt.Sprintf("%s-%s", s.name, step.As)
+	for _, shard := range shardSteps(steps) {
+		step := shard.LiteralTestStep
+		claimRelease := s.claimReleaseFor(step.As)
+		name := fmt.Sprintf("%s-%s", s.name, shard.name())
 		if o := step.OptionalOnSuccess; o != nil && *o && s.flags&allowSkipOnSuccess != 0 && s.flags&hasPrevErrs == 0 {
 			logrus.Infof(fmt.Sprintf("Skipping optional step %s", name))
 			continue
 		}
-		image := step.From
-		if link, ok := step.FromImageTag(); ok {
-			image = fmt.Sprintf("%s:%s", api.PipelineImageStream, link)
-		} else {
-			dep := api.StepDependency{Name: image}
-			stream, tag, _ := s.config.DependencyParts(dep, claimRelease)
-			image = fmt.Sprintf("%s:%s", stream, tag)
-		}
+		image := s.stepImage(step, claimRelease)
 		resources, err := base_steps.ResourcesFor(step.Resources)
 		if err != nil {
 			errs = append(errs, err)
@@ -106,18 +169,17 @@ func (s *multiStageTestStep) generatePods(
 			delete(resources.Requests, api.ShmResource)
 			delete(resources.Limits, api.ShmResource)
 		}
+		if isBurst(&step) {
+			capBurstLimits(step.As, &resources, s.options.BurstLimitCaps)
+		}
 		if bestEffortSteps != nil && step.BestEffort != nil && *step.BestEffort {
 			bestEffortSteps.Insert(name)
 		}
 		p := func(i int64) *int64 {
 			return &i
 		}
-		artifactDir := fmt.Sprintf("%s/%s", s.name, step.As)
-		timeout := entrypoint.DefaultTimeout
-		if step.Timeout != nil {
-			timeout = step.Timeout.Duration
-		}
-		s.jobSpec.DecorationConfig.Timeout = &prowapi.Duration{Duration: timeout}
+		artifactDir := fmt.Sprintf("%s/%s", s.name, shard.name())
+		s.jobSpec.DecorationConfig.Timeout = &prowapi.Duration{Duration: s.stepTimeout(&step)}
 		gracePeriod := entrypoint.DefaultGracePeriod
 		if step.GracePeriod != nil {
 			gracePeriod = step.GracePeriod.Duration
@@ -127,11 +189,9 @@ func (s *multiStageTestStep) generatePods(
 		// the grace period for the Pod to be just larger than the grace period
 		// for the process, assuming an 80/20 distribution of work.
 		terminationGracePeriodSeconds := p(int64(gracePeriod.Seconds() * 5 / 4))
-		var commands []string
-		if step.RunAsScript != nil && *step.RunAsScript {
-			commands = []string{fmt.Sprintf("%s/%s", CommandScriptMountPath, step.As)}
-		} else {
-			commands = []string{"/bin/bash", "-c", CommandPrefix + step.Commands}
+		commands := []string{fmt.Sprintf("%s/%s", CommandScriptMountPath, step.As)}
+		if command := execCommand(&step); command != nil {
+			commands = command
 		}
 		labels := map[string]string{base_steps.LabelMetadataStep: step.As}
 		pod, err := base_steps.GenerateBasePod(s.jobSpec, labels, name, s.nodeName,
@@ -143,7 +203,23 @@ func (s *multiStageTestStep) generatePods(
 		}
 		delete(pod.Labels, base_steps.ProwJobIdLabel)
 		pod.Annotations[base_steps.AnnotationSaveContainerLogs] = "true"
+		if s.options.Debug {
+			pod.Annotations[DebugAnnotation] = "true"
+		}
 		pod.Labels[MultiStageTestLabel] = s.name
+		if shard.total > 1 {
+			pod.Labels[ShardLabel] = step.As
+		}
+		if step.Gang != "" {
+			pod.Labels[GangLabel] = step.Gang
+			pod.Spec.SchedulingGates = []coreapi.PodSchedulingGate{{Name: GangSchedulingGate}}
+		}
+		if isCritical(step) {
+			markCritical(pod)
+		}
+		if isBurst(&step) {
+			markBurst(pod)
+		}
 		needsKubeConfig := isKubeconfigNeeded(&step, genPodOpts)
 		if needsKubeConfig {
 			pod.Spec.ServiceAccountName = s.name
@@ -163,6 +239,25 @@ func (s *multiStageTestStep) generatePods(
 				pod.Spec.DNSPolicy = coreapi.DNSNone
 			}
 		}
+		if step.HostNetwork != nil && *step.HostNetwork {
+			pod.Spec.HostNetwork = true
+			if pod.Spec.DNSPolicy == "" {
+				// keep resolving the services of the build cluster
+				pod.Spec.DNSPolicy = coreapi.DNSClusterFirstWithHostNet
+			}
+		}
+		if len(step.Sysctls) != 0 {
+			if pod.Spec.SecurityContext == nil {
+				pod.Spec.SecurityContext = &coreapi.PodSecurityContext{}
+			}
+			for _, sysctl := range step.Sysctls {
+				pod.Spec.SecurityContext.Sysctls = append(pod.Spec.SecurityContext.Sysctls, coreapi.Sysctl{Name: sysctl.Name, Value: sysctl.Value})
+			}
+		}
+		if err := s.applyNodeCapabilities(pod, step); err != nil {
+			errs = append(errs, err)
+			continue
+		}
 		pod.Spec.Volumes = append(pod.Spec.Volumes, coreapi.Volume{Name: homeVolumeName, VolumeSource: coreapi.VolumeSource{EmptyDir: &coreapi.EmptyDirVolumeSource{}}})
 		pod.Spec.Volumes = append(pod.Spec.Volumes, secretVolumes...)
 		for idx := range pod.Spec.Containers {
@@ -175,28 +270,64 @@ func (s *multiStageTestStep) generatePods(
 			}
 		}
 
-		addSecretWrapper(pod, s.vpnConf, !needsKubeConfig, genPodOpts)
+		wrapperArgs := s.wrapperArgs(&step)
+		if needsKubeConfig {
+			// results are uploaded with the service account of the test
+			wrapperArgs = append(wrapperArgs, "--junit-step", shard.name())
+		}
+		addSecretWrapper(pod, s.vpnConf, !needsKubeConfig, genPodOpts, wrapperArgs)
 		if s.vpnConf != nil {
 			s.addVPNClient(pod)
 		}
+		if s.tunnelConf != nil {
+			s.addTunnel(pod)
+		}
+		if err := s.addSidecars(pod, step.Sidecars, claimRelease); err != nil {
+			errs = append(errs, err)
+			continue
+		}
 		container := &pod.Spec.Containers[0]
-		container.Env = append(container.Env, []coreapi.EnvVar{
+		stepEnv := newEnvBuilder().add(envSourceJob, []coreapi.EnvVar{
 			{Name: "NAMESPACE", Value: s.jobSpec.Namespace()},
 			{Name: "JOB_NAME_SAFE", Value: strings.Replace(s.name, "_", "-", -1)},
 			{Name: "JOB_NAME_HASH", Value: s.jobSpec.JobNameHash()},
 			{Name: "UNIQUE_HASH", Value: s.jobSpec.UniqueHash()},
 		}...)
-		container.Env = append(container.Env, env...)
-		container.Env = append(container.Env, s.generateParams(step.Environment)...)
+		if step.ShardCount != nil {
+			stepEnv.add(envSourceShard, []coreapi.EnvVar{
+				{Name: "SHARD_INDEX", Value: strconv.Itoa(shard.index)},
+				{Name: "SHARD_TOTAL", Value: strconv.Itoa(shard.total)},
+			}...)
+		}
+		stepEnv.merge(env)
+		if release, ok := s.stepReleases[step.As]; ok && s.profile != "" {
+			value, err := s.params.Get(utils.ReleaseImageEnv(release))
+			if err != nil {
+				errs = append(errs, fmt.Errorf("failed to get the pull spec of release %s for step %s: %w", release, step.As, err))
+				continue
+			}
+			stepEnv.add(envSourceRelease, coreapi.EnvVar{Name: utils.ReleaseImageEnv(api.LatestReleaseName), Value: value})
+		}
+		stepEnv.add(envSourceClusterInfo, s.clusterInfo...)
+		if s.podOverrides != nil {
+			stepEnv.add(envSourceClusterProfile, s.podOverrides.Env...)
+		}
+		params := s.generateParams(step.Environment)
+		stepEnv.add(envSourceParameter, params...)
+		if err := expandParameters(&step, params); err != nil {
+			errs = append(errs, fmt.Errorf("failed to expand the parameters of step %s: %w", step.As, err))
+			continue
+		}
 		depEnv, depErrs := s.envForDependencies(step)
 		if len(depErrs) != 0 {
 			errs = append(errs, depErrs...)
 			continue
 		}
-		container.Env = append(container.Env, depEnv...)
+		stepEnv.add(envSourceDependency, depEnv...)
 		if owner := s.jobSpec.Owner(); owner != nil {
 			pod.OwnerReferences = append(pod.OwnerReferences, *owner)
 		}
+		s.addMetadata(pod)
 		if s.profile != "" && s.clusterClaim != nil {
 			// should never happen
 			errs = append(errs, fmt.Errorf("cannot set both cluster_profile and cluster_claim in a test"))
@@ -206,34 +337,66 @@ func (s *multiStageTestStep) generatePods(
 			if err != nil {
 				errs = append(errs, fmt.Errorf("failed to get cluster claim pod params: %w", err))
 			} else {
-				container.Env = append(container.Env, clusterClaimEnv...)
+				stepEnv.add(envSourceClusterClaim, clusterClaimEnv...)
 				// The volumes are there already because sidecar container uses them.
 				// We mount them here to the test container.
 				container.VolumeMounts = append(container.VolumeMounts, clusterClaimMount...)
 			}
-		} else if needsKubeConfig {
-			container.Env = append(container.Env, []coreapi.EnvVar{
+		} else if needsKubeConfig && s.flags&containerOnly == 0 {
+			stepEnv.add(envSourceKubeconfig, []coreapi.EnvVar{
 				{Name: "KUBECONFIG", Value: filepath.Join(SecretMountPath, "kubeconfig")},
 				{Name: "KUBECONFIGMINIMAL", Value: filepath.Join(SecretMountPath, "kubeconfig-minimal")},
 				{Name: "KUBEADMIN_PASSWORD_FILE", Value: filepath.Join(SecretMountPath, "kubeadmin-password")},
 			}...)
 		}
+		if s.profile != "" && s.ipFamily != "" {
+			stepEnv.add(envSourceClusterProfile, coreapi.EnvVar{Name: IPFamilyEnv, Value: s.ipFamily})
+		}
+		container.Env = append(container.Env, stepEnv.env()...)
+		s.saveEnvReport(shard.name(), stepEnv)
+		s.addPreviousJobArtifacts(&step, pod)
 		shmSize := allResources.Requests.Name(api.ShmResource, resource.BinarySI)
 		if !shmSize.IsZero() {
 			addDshmVolume(shmSize, pod, container)
 		}
+		if step.Workdir != "" || step.ScratchSize != "" {
+			if err := addScratchDir(&step, pod, container); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+		}
 		if s.profile != "" {
-			addProfile(s.profileSecretName(), s.profile, pod)
+			addProfile(s.mountedProfileSecretName(), s.profile, pod)
+			if s.podOverrides != nil {
+				if err := s.addPodOverrides(pod); err != nil {
+					errs = append(errs, err)
+					continue
+				}
+			}
 		}
 		if step.Cli != "" {
 			dependency := api.StepDependency{Name: fmt.Sprintf("%s:cli", api.ReleaseStreamFor(step.Cli))}
 			imagestream, _, _ := s.config.DependencyParts(dependency, claimRelease)
 			addCliInjector(imagestream, pod)
+			retries, err := kubectlRetries(params)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("step %s: %w", step.As, err))
+				continue
+			}
+			if retries != 0 {
+				addKubectlRetryShim(pod)
+			}
 		}
 		addSharedDirSecret(s.name, pod)
-		addCredentials(step.Credentials, pod)
-		if step.RunAsScript != nil && *step.RunAsScript {
-			addCommandScript(commandConfigMapForTest(s.name), pod)
+		if s.sharedDirKey != nil {
+			addSharedDirKey(s.name, pod)
+		}
+		addCredentials(s.stepCredentials(step.Credentials), pod)
+		if execCommand(&step) == nil {
+			addCommandScript(commandConfigMapForStep(s.name, step.As), pod)
+		}
+		if usesToolkit(&step) {
+			addToolkit(pod)
 		}
 		if s.vpnConf != nil {
 			caps := coreapi.Capabilities{
@@ -257,12 +420,94 @@ func (s *multiStageTestStep) generatePods(
 	return ret, bestEffortSteps, utilerrors.NewAggregate(errs)
 }
 
+// stepShard is one of the pods a step is split into.
+type stepShard struct {
+	api.LiteralTestStep
+	index, total int
+}
+
+// name is the name of the step, suffixed with the index of the shard when the
+// step is split into multiple pods.
+func (s stepShard) name() string {
+	if s.total > 1 {
+		return fmt.Sprintf("%s-%d", s.As, s.index)
+	}
+	return s.As
+}
+
+// shardSteps expands the steps into one entry per pod, according to their
+// shard count.
+func shardSteps(steps []api.LiteralTestStep) []stepShard {
+	var ret []stepShard
+	for _, step := range steps {
+		total := 1
+		if step.ShardCount != nil && *step.ShardCount > 1 {
+			total = *step.ShardCount
+		}
+		for i := 0; i < total; i++ {
+			ret = append(ret, stepShard{LiteralTestStep: step, index: i, total: total})
+		}
+	}
+	return ret
+}
+
 func isKubeconfigNeeded(step *api.LiteralTestStep, opts *generatePodOptions) bool {
 	needsKubeconfig := step.NoKubeconfig == nil || !*step.NoKubeconfig
 	return needsKubeconfig || opts.IsObserver
 }
 
-func addSecretWrapper(pod *coreapi.Pod, vpnConf *vpnConf, skipKubeconfig bool, genPodOpts *generatePodOptions) {
+// wrapperArgs returns the entrypoint wrapper arguments required by settings
+// which apply to all steps.
+func (s *multiStageTestStep) wrapperArgs(step *api.LiteralTestStep) []string {
+	var ret []string
+	if sink := s.options.MetricsSink; sink != "" {
+		ret = append(ret, "--metrics-sink", sink, "--metrics-step", step.As)
+	}
+	if algorithm := s.options.ArtifactCompression; algorithm != "" {
+		ret = append(ret, "--artifact-compression", string(algorithm), "--artifact-compression-threshold", strconv.FormatInt(s.options.ArtifactCompressionThreshold, 10))
+	}
+	if isBurst(step) {
+		ret = append(ret, "--record-cpu-throttling")
+	}
+	if files := step.ProvidesSharedFiles; len(files) != 0 {
+		ret = append(ret, "--provides-shared-files", strings.Join(files, ","))
+	}
+	if stdin := step.StdinFrom; stdin != nil {
+		if stdin.Parameter != "" {
+			ret = append(ret, "--stdin-from-env", stdin.Parameter)
+		} else {
+			ret = append(ret, "--stdin-from-shared-file", stdin.SharedFile)
+		}
+	}
+	if step.SplitLogs != nil && *step.SplitLogs {
+		ret = append(ret, "--logs-max-size", strconv.Itoa(logs.DefaultMaxSize))
+	}
+	if s.sharedDirKey != nil {
+		ret = append(ret, "--shared-dir-key", filepath.Join(SharedDirKeyMountPath, shareddir.KeySecretKey), "--shared-dir-step", step.As)
+	}
+	var waitForSidecars bool
+	if s.tunnelConf != nil {
+		ret = append(ret, "--wait-for-file", tunnelReadyFile)
+		waitForSidecars = true
+	}
+	for _, sidecar := range step.Sidecars {
+		if sidecar.Readiness != "" {
+			ret = append(ret, "--wait-for-file", sidecarReadyFile(sidecar.Name))
+			waitForSidecars = true
+		}
+	}
+	// the timeout of the VPN client, if any, applies to all files
+	if waitForSidecars && (s.vpnConf == nil || s.vpnConf.WaitTimeout == nil) {
+		ret = append(ret, "--wait-timeout", sidecarReadinessTimeout.String())
+	}
+	return ret
+}
+
+func sidecarReadyFile(name string) string {
+	return filepath.Join(SidecarDirPath, name+".ready")
+}
+
+func addSecretWrapper(pod *coreapi.Pod, vpnConf *vpnConf, skipKubeconfig bool, genPodOpts *generatePodOptions, extraArgs []string) {
 	volume := "entrypoint-wrapper"
 	dir := "/tmp/entrypoint-wrapper"
 	bin := filepath.Join(dir, "entrypoint-wrapper")
@@ -273,11 +518,13 @@ func addSecretWrapper(pod *coreapi.Pod, vpnConf *vpnConf, skipKubeconfig bool, g
 		},
 	})
 	mount := coreapi.VolumeMount{Name: volume, MountPath: dir}
+	// the ci-step helper is copied next to the wrapper, which exposes it to
+	// the command
 	pod.Spec.InitContainers = append(pod.Spec.InitContainers, coreapi.Container{
 		Image:                    fmt.Sprintf("%s/%s/entrypoint-wrapper:latest", api.DomainForService(api.ServiceRegistry), "ci"),
 		Name:                     "cp-entrypoint-wrapper",
 		Command:                  []string{"cp"},
-		Args:                     []string{"/bin/entrypoint-wrapper", bin},
+		Args:                     []string{"/bin/entrypoint-wrapper", "/bin/ci-step", dir},
 		VolumeMounts:             []coreapi.VolumeMount{mount},
 		TerminationMessagePolicy: coreapi.TerminationMessageFallbackToLogsOnError,
 	})
@@ -295,6 +542,7 @@ func addSecretWrapper(pod *coreapi.Pod, vpnConf *vpnConf, skipKubeconfig bool, g
 	if genPodOpts.IsObserver {
 		container.Args = append(container.Args, "--mode=observer")
 	}
+	container.Args = append(container.Args, extraArgs...)
 	container.Args = append(container.Args, container.Command...)
 	container.Args = append(container.Args, args...)
 	container.Command = []string{bin}
@@ -336,6 +584,59 @@ func (s *multiStageTestStep) addVPNClient(pod *coreapi.Pod) {
 	pod.Spec.Containers[0].VolumeMounts = append(pod.Spec.Containers[0].VolumeMounts, vpnVolMount)
 }
 
+// addSidecars adds the helper containers declared by a step to its pod.  The
+// test container and the sidecars share a directory, which is also where
+// sidecars signal their readiness.
+func (s *multiStageTestStep) addSidecars(pod *coreapi.Pod, sidecars []api.StepSidecar, claimRelease *api.ClaimRelease) error {
+	for _, sidecar := range sidecars {
+		resources, err := base_steps.ResourcesFor(sidecar.Resources)
+		if err != nil {
+			return fmt.Errorf("invalid resources for sidecar %s: %w", sidecar.Name, err)
+		}
+		addSidecarContainer(pod, api.SidecarContainerPrefix+sidecar.Name, s.dependencyImage(sidecar.From, claimRelease), sidecar.Commands, sidecar.Readiness, sidecarReadyFile(sidecar.Name), resources)
+	}
+	return nil
+}
+
+// addSidecarContainer adds a container which runs alongside the test
+// container until it exits, sharing the sidecar directory with it.
+func addSidecarContainer(pod *coreapi.Pod, name, image, commands, readiness, readyFile string, resources coreapi.ResourceRequirements) {
+	mount := coreapi.VolumeMount{Name: sidecarVolumeName, MountPath: SidecarDirPath}
+	env := coreapi.EnvVar{Name: SidecarDirEnv, Value: SidecarDirPath}
+	if !hasVolume(pod, sidecarVolumeName) {
+		pod.Spec.Volumes = append(pod.Spec.Volumes, coreapi.Volume{
+			Name: sidecarVolumeName,
+			VolumeSource: coreapi.VolumeSource{
+				EmptyDir: &coreapi.EmptyDirVolumeSource{},
+			},
+		})
+		test := &pod.Spec.Containers[0]
+		test.VolumeMounts = append(test.VolumeMounts, mount)
+		test.Env = append(test.Env, env)
+	}
+	pod.Spec.Containers = append(pod.Spec.Containers, coreapi.Container{
+		Name:      name,
+		Image:     image,
+		Command:   []string{"bash", "-c", sidecarScript, "sidecar", commands, readiness, readyFile},
+		Env:       []coreapi.EnvVar{env},
+		Resources: resources,
+		VolumeMounts: []coreapi.VolumeMount{
+			mount,
+			{Name: "logs", MountPath: "/logs"},
+		},
+		TerminationMessagePolicy: coreapi.TerminationMessageFallbackToLogsOnError,
+	})
+}
+
+func hasVolume(pod *coreapi.Pod, name string) bool {
+	for _, v := range pod.Spec.Volumes {
+		if v.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
 // setSecurityContexts configures the context of all containers in a pod
 // `root` specifies a container (or init container) which should be run as UID 0
 // and with `capabilities` and `seLinuxOpts`.  All others are explicitly set to
@@ -377,6 +678,14 @@ func (s *multiStageTestStep) generateParams(env []api.StepParameter) []coreapi.E
 		if env.Default != nil {
 			value = *env.Default
 		}
+		for _, info := range s.clusterInfo {
+			if info.Name == env.Name {
+				value = info.Value
+			}
+		}
+		if env.Name == api.FeatureSetEnv && s.featureSet != "" {
+			value = string(s.featureSet)
+		}
 		if v, ok := s.env[env.Name]; ok {
 			value = v
 		}
@@ -385,19 +694,52 @@ func (s *multiStageTestStep) generateParams(env []api.StepParameter) []coreapi.E
 	return ret
 }
 
+// expandParameters expands the `$(NAME)` references to the parameters of a
+// step in the fields which configure its layout: the mount paths of its
+// credentials, the variables of its dependencies and its working directory.
+func expandParameters(step *api.LiteralTestStep, params []coreapi.EnvVar) error {
+	values := make(map[string]string, len(params))
+	for _, param := range params {
+		values[param.Name] = param.Value
+	}
+	var errs []error
+	expand := func(field string, value *string, path bool) {
+		expanded, err := api.ExpandParameters(*value, values)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", field, err))
+			return
+		}
+		if path && expanded != *value && !filepath.IsAbs(expanded) {
+			errs = append(errs, fmt.Errorf("%s: %q expands to %q, which is not an absolute path", field, *value, expanded))
+			return
+		}
+		*value = expanded
+	}
+	// the slices are shared with the configuration of the step
+	step.Credentials = append([]api.CredentialReference(nil), step.Credentials...)
+	for i := range step.Credentials {
+		expand(fmt.Sprintf("credentials[%d].mount_path", i), &step.Credentials[i].MountPath, true)
+	}
+	step.Dependencies = append([]api.StepDependency(nil), step.Dependencies...)
+	for i := range step.Dependencies {
+		expand(fmt.Sprintf("dependencies[%d].env", i), &step.Dependencies[i].Env, false)
+	}
+	expand("workdir", &step.Workdir, true)
+	return utilerrors.NewAggregate(errs)
+}
+
 func (s *multiStageTestStep) envForDependencies(step api.LiteralTestStep) ([]coreapi.EnvVar, []error) {
 	var env []coreapi.EnvVar
 	var errs []error
-	var claimRelease *api.ClaimRelease
-	if s.clusterClaim != nil {
-		claimRelease = s.clusterClaim.ClaimRelease(s.name)
-	}
+	claimRelease := s.claimReleaseFor(step.As)
 	for _, dependency := range step.Dependencies {
 		var ref string
 		// if a fully-qualified pull spec was provided, then just use that. It'll be up to the job to use that pull spec
 		// correctly as it could possibly point to an external registry that ci-operator will itself not have access to.
 		if dependency.PullSpec != "" {
 			ref = dependency.PullSpec
+		} else if s.rendering {
+			ref = renderPlaceholder(dependency.Env)
 		} else {
 			imageStream, name, _ := s.config.DependencyParts(dependency, claimRelease)
 			depRef, err := utils.ImageDigestFor(s.client, s.jobSpec.Namespace, imageStream, name)()
@@ -447,6 +789,44 @@ func getClusterClaimPodParams(secretVolumeMounts []coreapi.VolumeMount, testName
 	return retEnv, retMount, nil
 }
 
+// addScratchDir adds the writable scratch directory of a step to its pod and
+// makes it the working directory of the commands.  Its declared size is
+// requested as ephemeral storage, so the node is guaranteed to provide it.
+func addScratchDir(step *api.LiteralTestStep, pod *coreapi.Pod, container *coreapi.Container) error {
+	path := step.Workdir
+	if path == "" {
+		path = ScratchDirPath
+	}
+	emptyDir := &coreapi.EmptyDirVolumeSource{}
+	if step.ScratchSize != "" {
+		size, err := resource.ParseQuantity(step.ScratchSize)
+		if err != nil {
+			return fmt.Errorf("invalid scratch size %q for step %s: %w", step.ScratchSize, step.As, err)
+		}
+		emptyDir.SizeLimit = &size
+		if container.Resources.Requests == nil {
+			container.Resources.Requests = coreapi.ResourceList{}
+		}
+		request := container.Resources.Requests[coreapi.ResourceEphemeralStorage]
+		request.Add(size)
+		container.Resources.Requests[coreapi.ResourceEphemeralStorage] = request
+		if limit, ok := container.Resources.Limits[coreapi.ResourceEphemeralStorage]; ok && limit.Cmp(request) < 0 {
+			container.Resources.Limits[coreapi.ResourceEphemeralStorage] = request
+		}
+	}
+	pod.Spec.Volumes = append(pod.Spec.Volumes, coreapi.Volume{
+		Name:         scratchVolumeName,
+		VolumeSource: coreapi.VolumeSource{EmptyDir: emptyDir},
+	})
+	container.VolumeMounts = append(container.VolumeMounts, coreapi.VolumeMount{
+		Name:      scratchVolumeName,
+		MountPath: path,
+	})
+	container.WorkingDir = path
+	container.Env = append(container.Env, coreapi.EnvVar{Name: ScratchDirEnv, Value: path})
+	return nil
+}
+
 func addDshmVolume(shmSize *resource.Quantity, pod *coreapi.Pod, container *coreapi.Container) {
 	logrus.Infof("Adding Dshm Volume to pod: %s", pod.Name)
 	pod.Spec.Volumes = append(pod.Spec.Volumes, coreapi.Volume{
@@ -488,6 +868,9 @@ func addProfile(name string, profile api.ClusterProfile, pod *coreapi.Pod) {
 	}}...)
 }
 
+// cliInjectorName is the name of the init container which injects the cli.
+const cliInjectorName = "inject-cli"
+
 func addCliInjector(imagestream string, pod *coreapi.Pod) {
 	volumeName := "cli"
 	pod.Spec.Volumes = append(pod.Spec.Volumes, coreapi.Volume{
@@ -497,7 +880,7 @@ func addCliInjector(imagestream string, pod *coreapi.Pod) {
 		},
 	})
 	pod.Spec.InitContainers = append(pod.Spec.InitContainers, coreapi.Container{
-		Name:    "inject-cli",
+		Name:    cliInjectorName,
 		Image:   fmt.Sprintf("%s:cli", imagestream),
 		Command: []string{"/bin/cp"},
 		Args:    []string{"/usr/bin/oc", CliMountPath},
@@ -534,6 +917,32 @@ func addSharedDirSecret(secret string, pod *coreapi.Pod) {
 	})
 }
 
+// addSharedDirKey mounts the key of the shared dir and the memory-backed
+// volume its contents are decrypted to.  The key is mounted into the step
+// container, so the command of the step can read it as well.
+func addSharedDirKey(test string, pod *coreapi.Pod) {
+	keyVolume, dirVolume := "shared-dir-key", "shared-dir"
+	pod.Spec.Volumes = append(pod.Spec.Volumes, coreapi.Volume{
+		Name: keyVolume,
+		VolumeSource: coreapi.VolumeSource{
+			Secret: &coreapi.SecretVolumeSource{SecretName: shareddir.KeySecretName(test)},
+		},
+	}, coreapi.Volume{
+		Name: dirVolume,
+		VolumeSource: coreapi.VolumeSource{
+			EmptyDir: &coreapi.EmptyDirVolumeSource{Medium: coreapi.StorageMediumMemory},
+		},
+	})
+	pod.Spec.Containers[0].VolumeMounts = append(pod.Spec.Containers[0].VolumeMounts, coreapi.VolumeMount{
+		Name:      keyVolume,
+		MountPath: SharedDirKeyMountPath,
+		ReadOnly:  true,
+	}, coreapi.VolumeMount{
+		Name:      dirVolume,
+		MountPath: decryptedSharedDirPath,
+	})
+}
+
 func addCredentials(credentials []api.CredentialReference, pod *coreapi.Pod) {
 	for _, credential := range credentials {
 		name := fmt.Sprintf("%s-%s", credential.Namespace, credential.Name)
@@ -547,12 +956,41 @@ func addCredentials(credentials []api.CredentialReference, pod *coreapi.Pod) {
 		pod.Spec.Containers[0].VolumeMounts = append(pod.Spec.Containers[0].VolumeMounts, coreapi.VolumeMount{
 			Name:      volumeName,
 			MountPath: credential.MountPath,
+			ReadOnly:  true,
 		})
 	}
 }
 
-func commandConfigMapForTest(testName string) string {
-	return fmt.Sprintf("%s-commands", testName)
+// commandConfigMapForStep names the ConfigMap holding the script of a step.
+// Names of tests and steps may contain dashes, so a hash of both is added for
+// test `e2e` with step `aws-install` and test `e2e-aws` with step `install`
+// not to share a ConfigMap.
+func commandConfigMapForStep(testName, stepName string) string {
+	hash := fmt.Sprintf("%x", sha256.Sum256([]byte(testName+"\x00"+stepName)))[:8]
+	return fmt.Sprintf("%s-%s-commands-%s", testName, stepName, hash)
+}
+
+// commandScript is the content of the executable file mounted for a step.
+// Scripts explicitly configured to run as-is are expected to contain their
+// own interpreter line, all others are executed by bash, after sourcing the
+// toolkit if the step uses it.
+func commandScript(step *api.LiteralTestStep) string {
+	if step.RunAsScript != nil && *step.RunAsScript {
+		return step.Commands
+	}
+	if usesToolkit(step) {
+		return CommandPrefix + fmt.Sprintf("source %s\n", toolkitPath()) + step.Commands
+	}
+	return CommandPrefix + step.Commands
+}
+
+// execCommand is the command of a step in exec form, which is executed
+// directly in the image without a shell, or nil for steps with `commands`.
+func execCommand(step *api.LiteralTestStep) []string {
+	if len(step.Command) == 0 {
+		return nil
+	}
+	return append(append([]string{}, step.Command...), step.Args...)
 }
 
 func addCommandScript(name string, pod *coreapi.Pod) {
@@ -1,8 +1,10 @@
 package multi_stage
 
 import (
+	"errors"
 	"fmt"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -10,13 +12,16 @@ import (
 
 	coreapi "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/apimachinery/pkg/util/diff"
 	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/apimachinery/pkg/util/sets"
 	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
 	prowdapi "k8s.io/test-infra/prow/pod-utils/downwardapi"
+	utilpointer "k8s.io/utils/pointer"
 
 	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/steps/compression"
 	"github.com/openshift/ci-tools/pkg/testhelper"
 )
 
@@ -42,6 +47,8 @@ func TestGeneratePods(t *testing.T) {
 						Nameservers: []string{"nameserver1", "nameserver2"},
 						Searches:    []string{"my.dns.search1", "my.dns.search2"},
 					},
+				}, {
+					As: "step4", From: "src", Commands: "command4", ShardCount: utilpointer.Int(2),
 				}},
 			}},
 		},
@@ -77,15 +84,14 @@ func TestGeneratePods(t *testing.T) {
 		},
 	}
 	jobSpec.SetNamespace("namespace")
-	step := newMultiStageTestStep(config.Tests[0], &config, nil, nil, &jobSpec, nil, "node-name", "")
+	step := newMultiStageTestStep(config.Tests[0], &config, nil, nil, &jobSpec, nil, "node-name", "", nil, Options{})
 	step.test[0].Resources = api.ResourceRequirements{
 		Requests: api.ResourceList{api.ShmResource: "2G"},
 		Limits:   api.ResourceList{api.ShmResource: "2G"}}
-	env := []coreapi.EnvVar{
+	env := newEnvBuilder().add(envSourceRelease, []coreapi.EnvVar{
 		{Name: "RELEASE_IMAGE_INITIAL", Value: "release:initial"},
 		{Name: "RELEASE_IMAGE_LATEST", Value: "release:latest"},
-		{Name: "LEASED_RESOURCE", Value: "uuid"},
-	}
+	}...).add(envSourceLease, coreapi.EnvVar{Name: "LEASED_RESOURCE", Value: "uuid"})
 	secretVolumes := []coreapi.Volume{{
 		Name:         "secret",
 		VolumeSource: coreapi.VolumeSource{Secret: &coreapi.SecretVolumeSource{SecretName: "k8-secret"}},
@@ -101,6 +107,397 @@ func TestGeneratePods(t *testing.T) {
 	testhelper.CompareWithFixture(t, ret)
 }
 
+func TestGeneratePodsContainerOnly(t *testing.T) {
+	yes := true
+	test := api.TestStepConfiguration{
+		As: "test",
+		MultiStageTestConfigurationLiteral: &api.MultiStageTestConfigurationLiteral{
+			ContainerOnly: &yes,
+			Test:          []api.LiteralTestStep{{As: "lint", From: "src", Commands: "make lint"}},
+		},
+	}
+	config := api.ReleaseBuildConfiguration{Tests: []api.TestStepConfiguration{test}}
+	jobSpec := api.JobSpec{JobSpec: prowdapi.JobSpec{
+		Job:     "job",
+		BuildID: "build id",
+		Refs:    &prowapi.Refs{Org: "org", Repo: "repo", BaseRef: "base ref", BaseSHA: "base sha"},
+		Type:    "postsubmit",
+		DecorationConfig: &prowapi.DecorationConfig{
+			UtilityImages: &prowapi.UtilityImages{Sidecar: "sidecar", Entrypoint: "entrypoint"},
+		},
+	}}
+	jobSpec.SetNamespace("namespace")
+	step := newMultiStageTestStep(test, &config, nil, nil, &jobSpec, nil, "node-name", "", nil, Options{})
+	pods, _, err := step.generatePods(step.test, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pods) != 1 {
+		t.Fatalf("expected one pod, got %d", len(pods))
+	}
+	pod := pods[0]
+	if pod.Spec.ServiceAccountName != "test" {
+		t.Errorf("expected the test service account to be used to update the shared directory, got %q", pod.Spec.ServiceAccountName)
+	}
+	for _, env := range pod.Spec.Containers[0].Env {
+		if strings.HasPrefix(env.Name, "KUBECONFIG") {
+			t.Errorf("unexpected kubeconfig variable: %s", env.Name)
+		}
+	}
+	if timeout := jobSpec.DecorationConfig.Timeout.Duration; timeout != time.Hour {
+		t.Errorf("expected the default timeout to be an hour, got %s", timeout)
+	}
+}
+
+func TestGeneratePodsSidecars(t *testing.T) {
+	test := api.TestStepConfiguration{
+		As: "test",
+		MultiStageTestConfigurationLiteral: &api.MultiStageTestConfigurationLiteral{
+			Test: []api.LiteralTestStep{{
+				As:       "e2e",
+				From:     "src",
+				Commands: "make e2e",
+				Sidecars: []api.StepSidecar{{
+					Name:      "registry",
+					From:      "pipeline:registry",
+					Commands:  "registry serve",
+					Readiness: "curl -sf localhost:5000",
+					Resources: api.ResourceRequirements{Requests: api.ResourceList{"cpu": "100m"}},
+				}},
+			}},
+		},
+	}
+	config := api.ReleaseBuildConfiguration{Tests: []api.TestStepConfiguration{test}}
+	jobSpec := api.JobSpec{JobSpec: prowdapi.JobSpec{
+		Job:     "job",
+		BuildID: "build id",
+		Refs:    &prowapi.Refs{Org: "org", Repo: "repo", BaseRef: "base ref", BaseSHA: "base sha"},
+		Type:    "postsubmit",
+		DecorationConfig: &prowapi.DecorationConfig{
+			UtilityImages: &prowapi.UtilityImages{Sidecar: "sidecar", Entrypoint: "entrypoint"},
+		},
+	}}
+	jobSpec.SetNamespace("namespace")
+	step := newMultiStageTestStep(test, &config, nil, nil, &jobSpec, nil, "node-name", "", nil, Options{})
+	pods, _, err := step.generatePods(step.test, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pods) != 1 {
+		t.Fatalf("expected one pod, got %d", len(pods))
+	}
+	pod := pods[0]
+	var sidecar *coreapi.Container
+	for i := range pod.Spec.Containers {
+		if c := &pod.Spec.Containers[i]; c.Name == "sidecar-registry" {
+			sidecar = c
+		}
+	}
+	if sidecar == nil {
+		t.Fatal("expected a container for the sidecar")
+	}
+	if sidecar.Image != "pipeline:registry" {
+		t.Errorf("unexpected sidecar image: %s", sidecar.Image)
+	}
+	testhelper.Diff(t, "sidecar arguments", sidecar.Command[3:], []string{"sidecar", "registry serve", "curl -sf localhost:5000", "/var/run/ci.openshift.io/sidecars/registry.ready"})
+	testhelper.Diff(t, "sidecar mounts", sidecar.VolumeMounts, []coreapi.VolumeMount{
+		{Name: "sidecars", MountPath: SidecarDirPath},
+		{Name: "logs", MountPath: "/logs"},
+	})
+	container := pod.Spec.Containers[0]
+	var mounted, hasEnv bool
+	for _, m := range container.VolumeMounts {
+		mounted = mounted || m.Name == "sidecars" && m.MountPath == SidecarDirPath
+	}
+	for _, e := range container.Env {
+		hasEnv = hasEnv || e.Name == SidecarDirEnv && e.Value == SidecarDirPath
+	}
+	if !mounted || !hasEnv {
+		t.Errorf("expected the sidecar directory to be shared with the test container, mounted: %t, env: %t", mounted, hasEnv)
+	}
+	args := strings.Join(container.Args, " ")
+	if expected := "--wait-for-file /var/run/ci.openshift.io/sidecars/registry.ready --wait-timeout 10m0s"; !strings.Contains(args, expected) {
+		t.Errorf("expected the test to wait for the sidecar, got arguments %q", args)
+	}
+}
+
+func TestGeneratePodsExecForm(t *testing.T) {
+	test := api.TestStepConfiguration{
+		As: "test",
+		MultiStageTestConfigurationLiteral: &api.MultiStageTestConfigurationLiteral{
+			Test: []api.LiteralTestStep{{As: "scan", From: "src", Command: []string{"/usr/bin/scanner"}, Args: []string{"--format", "junit"}}},
+		},
+	}
+	config := api.ReleaseBuildConfiguration{Tests: []api.TestStepConfiguration{test}}
+	jobSpec := api.JobSpec{JobSpec: prowdapi.JobSpec{
+		Job:     "job",
+		BuildID: "build id",
+		Refs:    &prowapi.Refs{Org: "org", Repo: "repo", BaseRef: "base ref", BaseSHA: "base sha"},
+		Type:    "postsubmit",
+		DecorationConfig: &prowapi.DecorationConfig{
+			UtilityImages: &prowapi.UtilityImages{Sidecar: "sidecar", Entrypoint: "entrypoint"},
+		},
+	}}
+	jobSpec.SetNamespace("namespace")
+	step := newMultiStageTestStep(test, &config, nil, nil, &jobSpec, nil, "node-name", "", nil, Options{})
+	if scripts := step.commandScripts(); len(scripts) != 0 {
+		t.Errorf("expected no scripts for a step in exec form, got %v", scripts)
+	}
+	pods, _, err := step.generatePods(step.test, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pods) != 1 {
+		t.Fatalf("expected one pod, got %d", len(pods))
+	}
+	pod := pods[0]
+	for _, v := range pod.Spec.Volumes {
+		if v.Name == "commands-script" {
+			t.Error("unexpected commands script volume for a step in exec form")
+		}
+	}
+	var options string
+	for _, env := range pod.Spec.Containers[0].Env {
+		if env.Name == "ENTRYPOINT_OPTIONS" {
+			options = env.Value
+		}
+	}
+	if !strings.Contains(options, `"args":["/usr/bin/scanner","--format","junit"]`) {
+		t.Errorf("expected the command to be executed directly, got entrypoint options %s", options)
+	}
+}
+
+func TestExpandParameters(t *testing.T) {
+	credentials := []api.CredentialReference{{Namespace: "ns", Name: "creds", MountPath: "/var/run/$(PLATFORM)"}}
+	step := api.LiteralTestStep{
+		As:           "install",
+		Credentials:  credentials,
+		Dependencies: []api.StepDependency{{Name: "installer", Env: "INSTALLER_$(PLATFORM)"}},
+		Workdir:      "$(DIR)/install",
+	}
+	params := []coreapi.EnvVar{{Name: "PLATFORM", Value: "aws"}, {Name: "DIR", Value: "/tmp"}}
+	if err := expandParameters(&step, params); err != nil {
+		t.Fatal(err)
+	}
+	testhelper.Diff(t, "credentials", step.Credentials, []api.CredentialReference{{Namespace: "ns", Name: "creds", MountPath: "/var/run/aws"}})
+	testhelper.Diff(t, "dependencies", step.Dependencies, []api.StepDependency{{Name: "installer", Env: "INSTALLER_aws"}})
+	testhelper.Diff(t, "workdir", step.Workdir, "/tmp/install")
+	testhelper.Diff(t, "original credentials", credentials[0].MountPath, "/var/run/$(PLATFORM)")
+
+	relative := api.LiteralTestStep{As: "install", Workdir: "$(DIR)/install"}
+	err := expandParameters(&relative, []coreapi.EnvVar{{Name: "DIR", Value: "tmp"}})
+	testhelper.Diff(t, "error", err, errors.New(`workdir: "$(DIR)/install" expands to "tmp/install", which is not an absolute path`), testhelper.EquateErrorMessage)
+}
+
+func TestGeneratePodsNetworkSettings(t *testing.T) {
+	yes := true
+	test := api.TestStepConfiguration{
+		As: "test",
+		MultiStageTestConfigurationLiteral: &api.MultiStageTestConfigurationLiteral{
+			ClusterProfile: api.ClusterProfileAWS,
+			Test: []api.LiteralTestStep{{
+				As:          "e2e",
+				From:        "src",
+				Commands:    "make e2e",
+				HostNetwork: &yes,
+				Sysctls:     []api.StepSysctl{{Name: "kernel.shm_rmid_forced", Value: "1"}},
+			}},
+		},
+	}
+	config := api.ReleaseBuildConfiguration{Tests: []api.TestStepConfiguration{test}}
+	jobSpec := api.JobSpec{JobSpec: prowdapi.JobSpec{
+		Job:     "job",
+		BuildID: "build id",
+		Refs:    &prowapi.Refs{Org: "org", Repo: "repo", BaseRef: "base ref", BaseSHA: "base sha"},
+		Type:    "postsubmit",
+		DecorationConfig: &prowapi.DecorationConfig{
+			UtilityImages: &prowapi.UtilityImages{Sidecar: "sidecar", Entrypoint: "entrypoint"},
+		},
+	}}
+	jobSpec.SetNamespace("namespace")
+	step := newMultiStageTestStep(test, &config, nil, nil, &jobSpec, nil, "node-name", "", nil, Options{})
+	if err := step.readIPFamily(&coreapi.Secret{Data: map[string][]byte{"ip-family": []byte("dual-stack\n")}}); err != nil {
+		t.Fatal(err)
+	}
+	pods, _, err := step.generatePods(step.test, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pods) != 1 {
+		t.Fatalf("expected one pod, got %d", len(pods))
+	}
+	pod := pods[0]
+	if !pod.Spec.HostNetwork || pod.Spec.DNSPolicy != coreapi.DNSClusterFirstWithHostNet {
+		t.Errorf("expected the pod to use the host network, got %t with DNS policy %q", pod.Spec.HostNetwork, pod.Spec.DNSPolicy)
+	}
+	testhelper.Diff(t, "sysctls", pod.Spec.SecurityContext.Sysctls, []coreapi.Sysctl{{Name: "kernel.shm_rmid_forced", Value: "1"}})
+	var family string
+	for _, env := range pod.Spec.Containers[0].Env {
+		if env.Name == IPFamilyEnv {
+			family = env.Value
+		}
+	}
+	if family != "dual-stack" {
+		t.Errorf("expected the IP family to be exposed, got %q", family)
+	}
+}
+
+func TestReadIPFamily(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		data        map[string][]byte
+		expected    string
+		expectedErr string
+	}{{
+		name:     "IPv4 by default",
+		expected: "ipv4",
+	}, {
+		name:     "set by the profile",
+		data:     map[string][]byte{"ip-family": []byte("ipv6")},
+		expected: "ipv6",
+	}, {
+		name:        "invalid value",
+		data:        map[string][]byte{"ip-family": []byte("ipx")},
+		expected:    "ipv4",
+		expectedErr: `invalid IP family "ipx", must be one of: ipv4, ipv6, dual-stack`,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			var s multiStageTestStep
+			err := s.readIPFamily(&coreapi.Secret{Data: tc.data})
+			var actualErr string
+			if err != nil {
+				actualErr = err.Error()
+			}
+			testhelper.Diff(t, "error", actualErr, tc.expectedErr)
+			testhelper.Diff(t, "IP family", s.ipFamily, tc.expected)
+		})
+	}
+}
+
+func TestGeneratePodsEncryptedSharedDir(t *testing.T) {
+	test := api.TestStepConfiguration{
+		As: "test",
+		MultiStageTestConfigurationLiteral: &api.MultiStageTestConfigurationLiteral{
+			Test: []api.LiteralTestStep{{As: "e2e", From: "src", Commands: "make e2e"}},
+		},
+	}
+	config := api.ReleaseBuildConfiguration{Tests: []api.TestStepConfiguration{test}}
+	jobSpec := api.JobSpec{JobSpec: prowdapi.JobSpec{
+		Job:     "job",
+		BuildID: "build id",
+		Refs:    &prowapi.Refs{Org: "org", Repo: "repo", BaseRef: "base ref", BaseSHA: "base sha"},
+		Type:    "postsubmit",
+		DecorationConfig: &prowapi.DecorationConfig{
+			UtilityImages: &prowapi.UtilityImages{Sidecar: "sidecar", Entrypoint: "entrypoint"},
+		},
+	}}
+	jobSpec.SetNamespace("namespace")
+	step := newMultiStageTestStep(test, &config, nil, nil, &jobSpec, nil, "node-name", "", nil, Options{EncryptSharedDir: true})
+	step.sharedDirKey = []byte("key")
+	pods, _, err := step.generatePods(step.test, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pods) != 1 {
+		t.Fatalf("expected one pod, got %d", len(pods))
+	}
+	pod := pods[0]
+	volumes := map[string]coreapi.VolumeSource{}
+	for _, v := range pod.Spec.Volumes {
+		volumes[v.Name] = v.VolumeSource
+	}
+	if v := volumes["shared-dir-key"].Secret; v == nil || v.SecretName != "test-shared-dir-key" {
+		t.Errorf("expected the key secret to be mounted, got %v", volumes["shared-dir-key"])
+	}
+	if v := volumes["shared-dir"].EmptyDir; v == nil || v.Medium != coreapi.StorageMediumMemory {
+		t.Errorf("expected a memory-backed volume for the decrypted contents, got %v", volumes["shared-dir"])
+	}
+	mounts := map[string]string{}
+	for _, m := range pod.Spec.Containers[0].VolumeMounts {
+		mounts[m.Name] = m.MountPath
+	}
+	expectedMounts := map[string]string{"shared-dir-key": SharedDirKeyMountPath, "shared-dir": "/tmp/secret"}
+	for name, path := range expectedMounts {
+		if mounts[name] != path {
+			t.Errorf("expected volume %s to be mounted at %s, got %q", name, path, mounts[name])
+		}
+	}
+	args := strings.Join(pod.Spec.Containers[0].Args, " ")
+	if expected := "--shared-dir-key " + SharedDirKeyMountPath + "/key --shared-dir-step e2e"; !strings.Contains(args, expected) {
+		t.Errorf("expected arguments to contain %q, got %q", expected, args)
+	}
+}
+
+func TestAddScratchDir(t *testing.T) {
+	size := resource.MustParse("20Gi")
+	for _, tc := range []struct {
+		name         string
+		step         api.LiteralTestStep
+		resources    coreapi.ResourceRequirements
+		expectedPath string
+		expectedSize *resource.Quantity
+		expected     coreapi.ResourceRequirements
+	}{{
+		name:         "default path without a size",
+		step:         api.LiteralTestStep{As: "e2e"},
+		expectedPath: "/scratch",
+	}, {
+		name:         "declared path and size",
+		step:         api.LiteralTestStep{As: "e2e", Workdir: "/go/src", ScratchSize: "20Gi"},
+		resources:    coreapi.ResourceRequirements{Requests: coreapi.ResourceList{coreapi.ResourceCPU: resource.MustParse("1")}},
+		expectedPath: "/go/src",
+		expectedSize: &size,
+		expected: coreapi.ResourceRequirements{Requests: coreapi.ResourceList{
+			coreapi.ResourceCPU:              resource.MustParse("1"),
+			coreapi.ResourceEphemeralStorage: resource.MustParse("20Gi"),
+		}},
+	}, {
+		name: "size is added to the requests and raises the limit",
+		step: api.LiteralTestStep{As: "e2e", ScratchSize: "20Gi"},
+		resources: coreapi.ResourceRequirements{
+			Requests: coreapi.ResourceList{coreapi.ResourceEphemeralStorage: resource.MustParse("10Gi")},
+			Limits:   coreapi.ResourceList{coreapi.ResourceEphemeralStorage: resource.MustParse("15Gi")},
+		},
+		expectedPath: "/scratch",
+		expectedSize: &size,
+		expected: coreapi.ResourceRequirements{
+			Requests: coreapi.ResourceList{coreapi.ResourceEphemeralStorage: resource.MustParse("30Gi")},
+			Limits:   coreapi.ResourceList{coreapi.ResourceEphemeralStorage: resource.MustParse("30Gi")},
+		},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			pod := &coreapi.Pod{Spec: coreapi.PodSpec{Containers: []coreapi.Container{{Name: "test", Resources: tc.resources}}}}
+			if err := addScratchDir(&tc.step, pod, &pod.Spec.Containers[0]); err != nil {
+				t.Fatal(err)
+			}
+			container := pod.Spec.Containers[0]
+			testhelper.Diff(t, "volumes", pod.Spec.Volumes, []coreapi.Volume{{
+				Name:         "scratch",
+				VolumeSource: coreapi.VolumeSource{EmptyDir: &coreapi.EmptyDirVolumeSource{SizeLimit: tc.expectedSize}},
+			}})
+			testhelper.Diff(t, "mounts", container.VolumeMounts, []coreapi.VolumeMount{{Name: "scratch", MountPath: tc.expectedPath}})
+			testhelper.Diff(t, "working directory", container.WorkingDir, tc.expectedPath)
+			testhelper.Diff(t, "environment", container.Env, []coreapi.EnvVar{{Name: "SCRATCH_DIR", Value: tc.expectedPath}})
+			if !equality.Semantic.DeepEqual(container.Resources, tc.expected) {
+				t.Errorf("unexpected resources: %v", container.Resources)
+			}
+		})
+	}
+}
+
+func TestShardSteps(t *testing.T) {
+	one, three := 1, 3
+	steps := []api.LiteralTestStep{{As: "lint"}, {As: "unit", ShardCount: &one}, {As: "e2e", ShardCount: &three}}
+	var names []string
+	for _, shard := range shardSteps(steps) {
+		names = append(names, fmt.Sprintf("%s %d/%d", shard.name(), shard.index, shard.total))
+	}
+	expected := []string{"lint 0/1", "unit 0/1", "e2e-0 0/3", "e2e-1 1/3", "e2e-2 2/3"}
+	if diff := cmp.Diff(expected, names); diff != "" {
+		t.Errorf("unexpected shards: %s", diff)
+	}
+}
+
 func TestGenerateObservers(t *testing.T) {
 	config := api.ReleaseBuildConfiguration{
 		Tests: []api.TestStepConfiguration{{
@@ -155,7 +552,7 @@ func TestGenerateObservers(t *testing.T) {
 		},
 	}
 	jobSpec.SetNamespace("namespace")
-	step := newMultiStageTestStep(config.Tests[0], &config, nil, nil, &jobSpec, nil, "node-name", "")
+	step := newMultiStageTestStep(config.Tests[0], &config, nil, nil, &jobSpec, nil, "node-name", "", nil, Options{})
 	ret, err := step.generateObservers(observers, nil, nil, nil)
 	if err != nil {
 		t.Fatal(err)
@@ -229,7 +626,7 @@ func TestGeneratePodsEnvironment(t *testing.T) {
 					Test:        test,
 					Environment: tc.env,
 				},
-			}, &api.ReleaseBuildConfiguration{}, nil, nil, &jobSpec, nil, "node-name", "")
+			}, &api.ReleaseBuildConfiguration{}, nil, nil, &jobSpec, nil, "node-name", "", nil, Options{})
 			pods, _, err := step.(*multiStageTestStep).generatePods(test, nil, nil, nil, nil)
 			if err != nil {
 				t.Fatal(err)
@@ -247,6 +644,50 @@ func TestGeneratePodsEnvironment(t *testing.T) {
 	}
 }
 
+func TestGeneratePodsStepReleases(t *testing.T) {
+	jobSpec := api.JobSpec{JobSpec: prowdapi.JobSpec{
+		Job:     "job",
+		BuildID: "build id",
+		Type:    prowapi.PeriodicJob,
+		DecorationConfig: &prowapi.DecorationConfig{
+			UtilityImages: &prowapi.UtilityImages{Sidecar: "sidecar", Entrypoint: "entrypoint"},
+		},
+	}}
+	jobSpec.SetNamespace("ns")
+	params := api.NewDeferredParameters(nil)
+	params.Set("RELEASE_IMAGE_HOSTED", "registry.ci/hosted:payload")
+	test := []api.LiteralTestStep{
+		{As: "management", From: "src", Commands: "make management"},
+		{As: "hosted", From: "src", Commands: "make hosted"},
+	}
+	step := newMultiStageTestStep(api.TestStepConfiguration{
+		As: "test",
+		MultiStageTestConfigurationLiteral: &api.MultiStageTestConfigurationLiteral{
+			ClusterProfile: api.ClusterProfileAWS,
+			Test:           test,
+			StepReleases:   api.StepReleases{"hosted": "hosted"},
+		},
+	}, &api.ReleaseBuildConfiguration{}, params, nil, &jobSpec, nil, "node-name", "", nil, Options{})
+	env := newEnvBuilder().add(envSourceClusterProfile, coreapi.EnvVar{Name: "RELEASE_IMAGE_LATEST", Value: "registry.ci/latest:payload"})
+	pods, _, err := step.generatePods(test, env, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual := map[string]string{}
+	for _, pod := range pods {
+		for _, v := range pod.Spec.Containers[0].Env {
+			if v.Name == "RELEASE_IMAGE_LATEST" {
+				actual[pod.Name] = v.Value
+			}
+		}
+	}
+	expected := map[string]string{
+		"test-management": "registry.ci/latest:payload",
+		"test-hosted":     "registry.ci/hosted:payload",
+	}
+	testhelper.Diff(t, "release images", actual, expected)
+}
+
 func TestGeneratePodBestEffort(t *testing.T) {
 	yes := true
 	no := false
@@ -297,7 +738,7 @@ func TestGeneratePodBestEffort(t *testing.T) {
 		},
 	}
 	jobSpec.SetNamespace("namespace")
-	step := newMultiStageTestStep(config.Tests[0], &config, nil, nil, &jobSpec, nil, "node-name", "")
+	step := newMultiStageTestStep(config.Tests[0], &config, nil, nil, &jobSpec, nil, "node-name", "", nil, Options{})
 	_, bestEffortSteps, err := step.generatePods(config.Tests[0].MultiStageTestConfigurationLiteral.Post, nil, nil, nil, nil)
 	if err != nil {
 		t.Fatal(err)
@@ -334,7 +775,7 @@ func TestAddCredentials(t *testing.T) {
 				Volumes:    []coreapi.Volume{},
 			}},
 			expected: coreapi.Pod{Spec: coreapi.PodSpec{
-				Containers: []coreapi.Container{{VolumeMounts: []coreapi.VolumeMount{{Name: "ns-name", MountPath: "/tmp"}}}},
+				Containers: []coreapi.Container{{VolumeMounts: []coreapi.VolumeMount{{Name: "ns-name", MountPath: "/tmp", ReadOnly: true}}}},
 				Volumes:    []coreapi.Volume{{Name: "ns-name", VolumeSource: coreapi.VolumeSource{Secret: &coreapi.SecretVolumeSource{SecretName: "ns-name"}}}},
 			}},
 		},
@@ -350,8 +791,8 @@ func TestAddCredentials(t *testing.T) {
 			}},
 			expected: coreapi.Pod{Spec: coreapi.PodSpec{
 				Containers: []coreapi.Container{{VolumeMounts: []coreapi.VolumeMount{
-					{Name: "ns-name", MountPath: "/tmp"},
-					{Name: "other-name", MountPath: "/tamp"},
+					{Name: "ns-name", MountPath: "/tmp", ReadOnly: true},
+					{Name: "other-name", MountPath: "/tamp", ReadOnly: true},
 				}}},
 				Volumes: []coreapi.Volume{
 					{Name: "ns-name", VolumeSource: coreapi.VolumeSource{Secret: &coreapi.SecretVolumeSource{SecretName: "ns-name"}}},
@@ -370,7 +811,7 @@ func TestAddCredentials(t *testing.T) {
 			}},
 			expected: coreapi.Pod{Spec: coreapi.PodSpec{
 				Containers: []coreapi.Container{{VolumeMounts: []coreapi.VolumeMount{
-					{Name: "ns-hive-hive-credentials", MountPath: "/tmp"},
+					{Name: "ns-hive-hive-credentials", MountPath: "/tmp", ReadOnly: true},
 				}}},
 				Volumes: []coreapi.Volume{
 					{Name: "ns-hive-hive-credentials", VolumeSource: coreapi.VolumeSource{Secret: &coreapi.SecretVolumeSource{SecretName: "ns-hive-hive-credentials"}}},
@@ -481,3 +922,67 @@ func TestSetSecurityContexts(t *testing.T) {
 		})
 	}
 }
+
+func TestWrapperArgs(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		options  Options
+		provides []string
+		stdin    *api.StepStdin
+		split    *bool
+		sidecars []api.StepSidecar
+		vpn      *vpnConf
+		expected []string
+	}{{
+		name: "no options",
+	}, {
+		name:     "metrics sink",
+		options:  Options{MetricsSink: "https://metrics.example.com/push"},
+		expected: []string{"--metrics-sink", "https://metrics.example.com/push", "--metrics-step", "step"},
+	}, {
+		name:     "artifact compression",
+		options:  Options{ArtifactCompression: compression.Zstd, ArtifactCompressionThreshold: 1024},
+		expected: []string{"--artifact-compression", "zstd", "--artifact-compression-threshold", "1024"},
+	}, {
+		name:     "declared outputs",
+		provides: []string{"kubeconfig", "metadata.json"},
+		expected: []string{"--provides-shared-files", "kubeconfig,metadata.json"},
+	}, {
+		name:     "standard input from a parameter",
+		stdin:    &api.StepStdin{Parameter: "ANSWERS"},
+		expected: []string{"--stdin-from-env", "ANSWERS"},
+	}, {
+		name:     "standard input from a shared file",
+		stdin:    &api.StepStdin{SharedFile: "install-answers"},
+		expected: []string{"--stdin-from-shared-file", "install-answers"},
+	}, {
+		name:     "split logs",
+		split:    utilpointer.Bool(true),
+		expected: []string{"--logs-max-size", "10485760"},
+	}, {
+		name:  "split logs disabled",
+		split: utilpointer.Bool(false),
+	}, {
+		name:     "sidecars with readiness",
+		sidecars: []api.StepSidecar{{Name: "registry", Readiness: "true"}, {Name: "forwarder"}},
+		expected: []string{"--wait-for-file", "/var/run/ci.openshift.io/sidecars/registry.ready", "--wait-timeout", "10m0s"},
+	}, {
+		name:     "sidecars with the timeout of the VPN client",
+		sidecars: []api.StepSidecar{{Name: "registry", Readiness: "true"}},
+		vpn:      &vpnConf{WaitTimeout: utilpointer.String("5m")},
+		expected: []string{"--wait-for-file", "/var/run/ci.openshift.io/sidecars/registry.ready"},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			step := api.LiteralTestStep{As: "step", ProvidesSharedFiles: tc.provides, StdinFrom: tc.stdin, SplitLogs: tc.split, Sidecars: tc.sidecars}
+			s := multiStageTestStep{options: tc.options, vpnConf: tc.vpn}
+			testhelper.Diff(t, "args", s.wrapperArgs(&step), tc.expected)
+		})
+	}
+}
+
+func TestCommandConfigMapForStep(t *testing.T) {
+	testhelper.Diff(t, "name", commandConfigMapForStep("e2e", "install"), "e2e-install-commands-81fa3803")
+	if a, b := commandConfigMapForStep("e2e", "aws-install"), commandConfigMapForStep("e2e-aws", "install"); a == b {
+		t.Errorf("the steps of different tests share ConfigMap %s", a)
+	}
+}
@@ -0,0 +1,229 @@
+package multi_stage
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/openshift/ci-tools/pkg/api"
+)
+
+const (
+	// GraphDOTArtifact is the name of the artifact file, relative to the
+	// test's artifact directory, which holds the graph of the steps of the
+	// test in the DOT language of graphviz.
+	GraphDOTArtifact = "graph.dot"
+	// GraphMermaidArtifact is the name of the artifact file, relative to the
+	// test's artifact directory, which holds the graph of the steps of the
+	// test as a mermaid diagram, rendered by most markdown viewers.
+	GraphMermaidArtifact = "graph.md"
+)
+
+// mermaidInvalidID matches the characters which cannot be used in the
+// identifiers of mermaid nodes.
+var mermaidInvalidID = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+// stepGraph is the order in which the steps of a test are executed.
+type stepGraph struct {
+	name      string
+	phases    []graphPhase
+	observers []string
+	// files are the edges from the steps which provide a file in the shared
+	// directory to those which require it.
+	files []graphEdge
+}
+
+type graphPhase struct {
+	name string
+	// groups are executed in sequence, the steps of a group in parallel.
+	groups []graphGroup
+}
+
+type graphGroup struct {
+	// gang is the name of the gang of the steps, if any.
+	gang  string
+	steps []graphStep
+}
+
+type graphStep struct {
+	name string
+	// details describe the step: its image, parameters and shards.
+	details []string
+}
+
+type graphEdge struct {
+	from, to, label string
+}
+
+// newStepGraph computes the graph of the steps of the test, grouping the
+// steps of a gang as they are when executed.
+func (s *multiStageTestStep) newStepGraph() *stepGraph {
+	g := &stepGraph{name: s.name}
+	providers := map[string]string{}
+	for _, phase := range []struct {
+		name  string
+		steps []api.LiteralTestStep
+	}{{name: "pre", steps: s.pre}, {name: "test", steps: s.test}, {name: "post", steps: s.post}} {
+		p := graphPhase{name: phase.name}
+		for _, step := range phase.steps {
+			node := graphStep{name: step.As, details: stepGraphDetails(step)}
+			if n := len(p.groups); n != 0 && step.Gang != "" && p.groups[n-1].gang == step.Gang {
+				p.groups[n-1].steps = append(p.groups[n-1].steps, node)
+			} else {
+				p.groups = append(p.groups, graphGroup{gang: step.Gang, steps: []graphStep{node}})
+			}
+			for _, file := range step.RequiresSharedFiles {
+				if from, ok := providers[file]; ok {
+					g.files = append(g.files, graphEdge{from: from, to: step.As, label: file})
+				}
+			}
+			for _, file := range step.ProvidesSharedFiles {
+				providers[file] = step.As
+			}
+		}
+		if len(p.groups) != 0 {
+			g.phases = append(g.phases, p)
+		}
+	}
+	for _, observer := range s.observers {
+		g.observers = append(g.observers, observer.Name)
+	}
+	return g
+}
+
+func stepGraphDetails(step api.LiteralTestStep) []string {
+	var ret []string
+	switch {
+	case step.Upgrade != nil:
+		ret = append(ret, fmt.Sprintf("upgrade to %s", step.Upgrade.Release))
+	case step.FromImage != nil:
+		ret = append(ret, fmt.Sprintf("from: %s/%s:%s", step.FromImage.Namespace, step.FromImage.Name, step.FromImage.Tag))
+	case step.From != "":
+		ret = append(ret, fmt.Sprintf("from: %s", step.From))
+	}
+	if len(step.Environment) != 0 {
+		var names []string
+		for _, param := range step.Environment {
+			names = append(names, param.Name)
+		}
+		ret = append(ret, fmt.Sprintf("env: %s", strings.Join(names, ", ")))
+	}
+	if step.ShardCount != nil && *step.ShardCount > 1 {
+		ret = append(ret, fmt.Sprintf("%d shards", *step.ShardCount))
+	}
+	return ret
+}
+
+// sequence returns the edges between the groups of steps executed one after
+// the other, across phases.
+func (g *stepGraph) sequence() []graphEdge {
+	var ret []graphEdge
+	var prev *graphGroup
+	for _, phase := range g.phases {
+		for i := range phase.groups {
+			group := &phase.groups[i]
+			if prev != nil {
+				for _, from := range prev.steps {
+					for _, to := range group.steps {
+						ret = append(ret, graphEdge{from: from.name, to: to.name})
+					}
+				}
+			}
+			prev = group
+		}
+	}
+	return ret
+}
+
+// dot renders the graph in the DOT language.
+func (g *stepGraph) dot() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "digraph %q {\n\tnode [shape=box];\n", g.name)
+	for _, phase := range g.phases {
+		fmt.Fprintf(&b, "\tsubgraph %q {\n\t\tlabel=%q;\n", "cluster_"+phase.name, phase.name)
+		for _, group := range phase.groups {
+			indent := "\t\t"
+			if group.gang != "" {
+				fmt.Fprintf(&b, "\t\tsubgraph %q {\n\t\t\tlabel=%q;\n", "cluster_gang_"+group.gang, "gang "+group.gang)
+				indent = "\t\t\t"
+			}
+			for _, step := range group.steps {
+				fmt.Fprintf(&b, "%s%q [label=%q];\n", indent, step.name, strings.Join(append([]string{step.name}, step.details...), "\n"))
+			}
+			if group.gang != "" {
+				b.WriteString("\t\t}\n")
+			}
+		}
+		b.WriteString("\t}\n")
+	}
+	if len(g.observers) != 0 {
+		b.WriteString("\tsubgraph \"cluster_observers\" {\n\t\tlabel=\"observers\";\n\t\tnode [style=dotted];\n")
+		for _, observer := range g.observers {
+			fmt.Fprintf(&b, "\t\t%q;\n", observer)
+		}
+		b.WriteString("\t}\n")
+	}
+	for _, edge := range g.sequence() {
+		fmt.Fprintf(&b, "\t%q -> %q;\n", edge.from, edge.to)
+	}
+	for _, edge := range g.files {
+		fmt.Fprintf(&b, "\t%q -> %q [style=dashed, label=%q];\n", edge.from, edge.to, edge.label)
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// mermaidID returns the identifier of a node or subgraph, prefixed with its
+// kind so that steps, gangs and observers never collide.
+func mermaidID(kind, name string) string {
+	return kind + "_" + mermaidInvalidID.ReplaceAllString(name, "_")
+}
+
+// mermaid renders the graph as a mermaid flowchart in a markdown document.
+func (g *stepGraph) mermaid() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Steps of test %s\n\n```mermaid\nflowchart TD\n", g.name)
+	for _, phase := range g.phases {
+		fmt.Fprintf(&b, "  subgraph %s [%s]\n", mermaidID("phase", phase.name), phase.name)
+		for _, group := range phase.groups {
+			indent := "    "
+			if group.gang != "" {
+				fmt.Fprintf(&b, "    subgraph %s [\"gang %s\"]\n", mermaidID("gang", group.gang), group.gang)
+				indent = "      "
+			}
+			for _, step := range group.steps {
+				fmt.Fprintf(&b, "%s%s[\"%s\"]\n", indent, mermaidID("step", step.name), strings.Join(append([]string{step.name}, step.details...), "<br/>"))
+			}
+			if group.gang != "" {
+				b.WriteString("    end\n")
+			}
+		}
+		b.WriteString("  end\n")
+	}
+	if len(g.observers) != 0 {
+		b.WriteString("  subgraph observers [observers]\n")
+		for _, observer := range g.observers {
+			fmt.Fprintf(&b, "    %s([\"%s\"])\n", mermaidID("observer", observer), observer)
+		}
+		b.WriteString("  end\n")
+	}
+	for _, edge := range g.sequence() {
+		fmt.Fprintf(&b, "  %s --> %s\n", mermaidID("step", edge.from), mermaidID("step", edge.to))
+	}
+	for _, edge := range g.files {
+		fmt.Fprintf(&b, "  %s -. \"%s\" .-> %s\n", mermaidID("step", edge.from), edge.label, mermaidID("step", edge.to))
+	}
+	b.WriteString("```\n")
+	return b.String()
+}
+
+// saveGraph writes the graph of the steps of the test to its artifact
+// directory, for reviewers to see what runs when.
+func (s *multiStageTestStep) saveGraph() error {
+	g := s.newStepGraph()
+	if err := api.SaveArtifact(s.censor, path.Join(s.name, GraphDOTArtifact), []byte(g.dot())); err != nil {
+		return err
+	}
+	return api.SaveArtifact(s.censor, path.Join(s.name, GraphMermaidArtifact), []byte(g.mermaid()))
+}
@@ -0,0 +1,75 @@
+package multi_stage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	utilpointer "k8s.io/utils/pointer"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/secrets"
+	"github.com/openshift/ci-tools/pkg/testhelper"
+)
+
+func TestStepGraph(t *testing.T) {
+	s := multiStageTestStep{
+		name: "e2e-aws",
+		pre: []api.LiteralTestStep{{
+			As:                  "ipi-install",
+			From:                "installer",
+			Environment:         []api.StepParameter{{Name: "INSTALL_TIMEOUT"}},
+			ProvidesSharedFiles: []string{"kubeconfig", "metadata.json"},
+		}, {
+			As:                  "registry",
+			From:                "cli",
+			Gang:                "network",
+			RequiresSharedFiles: []string{"kubeconfig"},
+		}, {
+			As:   "tunnel",
+			From: "cli",
+			Gang: "network",
+		}},
+		test: []api.LiteralTestStep{{
+			As:          "e2e",
+			FromImage:   &api.ImageStreamTagReference{Namespace: "ci", Name: "tests", Tag: "latest"},
+			ShardCount:  utilpointer.Int(3),
+			Environment: []api.StepParameter{{Name: "TEST_SUITE"}, {Name: "TEST_SKIPS"}},
+		}, {
+			As:      "upgrade",
+			Upgrade: &api.UpgradeStep{Release: "latest"},
+		}},
+		post: []api.LiteralTestStep{{
+			As:                  "ipi-deprovision",
+			From:                "installer",
+			RequiresSharedFiles: []string{"metadata.json"},
+		}},
+		observers: []api.Observer{{Name: "monitor"}},
+	}
+	g := s.newStepGraph()
+	t.Run("dot", func(t *testing.T) {
+		testhelper.CompareWithFixture(t, g.dot(), testhelper.WithExtension(".dot"))
+	})
+	t.Run("mermaid", func(t *testing.T) {
+		testhelper.CompareWithFixture(t, g.mermaid(), testhelper.WithExtension(".md"))
+	})
+}
+
+func TestSaveGraph(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("ARTIFACTS", dir)
+	censor := secrets.NewDynamicCensor()
+	s := multiStageTestStep{
+		name:   "e2e",
+		test:   []api.LiteralTestStep{{As: "test0", From: "src"}},
+		censor: &censor,
+	}
+	if err := s.saveGraph(); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{GraphDOTArtifact, GraphMermaidArtifact} {
+		if _, err := os.Stat(filepath.Join(dir, "e2e", name)); err != nil {
+			t.Errorf("expected artifact %s to be written: %v", name, err)
+		}
+	}
+}
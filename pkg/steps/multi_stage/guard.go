@@ -0,0 +1,113 @@
+package multi_stage
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+
+	coreapi "k8s.io/api/core/v1"
+	rbacapi "k8s.io/api/rbac/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/steps/shareddir"
+)
+
+// postPhaseFinalizer is the finalizer which keeps the objects the steps of a
+// test depend on until its post phase has completed.  The objects are owned
+// by the job and may otherwise be garbage-collected, or removed with the
+// namespace, while the post steps of an aborted job still run.  Credentials
+// are shared by the tests of the namespace, so each test has its own
+// finalizer.
+func (s *multiStageTestStep) postPhaseFinalizer() string {
+	return s.name + api.PostPhaseFinalizerSuffix
+}
+
+// guardedObjects returns the objects which must be kept until the post phase
+// has completed: the service account the steps run as with its role and
+// bindings, and the secrets they mount, including the copy of the cluster
+// profile.
+func (s *multiStageTestStep) guardedObjects() []ctrlruntimeclient.Object {
+	ns := s.jobSpec.Namespace()
+	ret := []ctrlruntimeclient.Object{
+		&coreapi.ServiceAccount{ObjectMeta: meta.ObjectMeta{Namespace: ns, Name: s.name}},
+		&rbacapi.Role{ObjectMeta: meta.ObjectMeta{Namespace: ns, Name: s.name}},
+	}
+	_, _, bindings := s.rbacObjects()
+	for _, binding := range bindings {
+		ret = append(ret, &rbacapi.RoleBinding{ObjectMeta: meta.ObjectMeta{Namespace: ns, Name: binding.Name}})
+	}
+	secrets := []string{s.sharedDirSecret().Name, s.resultsSecret().Name}
+	if s.options.EncryptSharedDir {
+		secrets = append(secrets, shareddir.KeySecretName(s.name))
+	}
+	if s.profileCopy != "" {
+		secrets = append(secrets, s.profileCopy)
+	}
+	seen := map[string]bool{}
+	for _, step := range s.allSteps() {
+		for _, credential := range s.stepCredentials(step.Credentials) {
+			if name := credentialSecretName(credential); !seen[name] {
+				seen[name] = true
+				secrets = append(secrets, name)
+			}
+		}
+	}
+	for _, name := range secrets {
+		ret = append(ret, &coreapi.Secret{ObjectMeta: meta.ObjectMeta{Namespace: ns, Name: name}})
+	}
+	return ret
+}
+
+// guardObjects adds the finalizer of the test to the objects its steps depend
+// on.  They may have been created by a previous execution or, for
+// credentials, by another test, so the finalizer is added to the existing
+// objects.
+func (s *multiStageTestStep) guardObjects(ctx context.Context) error {
+	finalizer := s.postPhaseFinalizer()
+	for _, obj := range s.guardedObjects() {
+		if err := s.updateFinalizers(ctx, obj, func(o ctrlruntimeclient.Object) bool {
+			return controllerutil.AddFinalizer(o, finalizer)
+		}); err != nil {
+			return fmt.Errorf("could not protect %T %s until the post phase has completed: %w", obj, obj.GetName(), err)
+		}
+	}
+	return nil
+}
+
+// releaseObjects removes the finalizer of the test from the objects its steps
+// depend on, once the test has completed.  Objects deleted in the meantime
+// are removed as soon as their finalizers are.  Failures are logged: the
+// finalizers left behind, also when ci-operator is killed, are removed by the
+// postphasefinalizercleaner controller once the namespace has been
+// terminating for longer than any job runs.
+func (s *multiStageTestStep) releaseObjects(ctx context.Context) {
+	finalizer := s.postPhaseFinalizer()
+	for _, obj := range s.guardedObjects() {
+		if err := s.updateFinalizers(ctx, obj, func(o ctrlruntimeclient.Object) bool {
+			return controllerutil.RemoveFinalizer(o, finalizer)
+		}); err != nil && !kerrors.IsNotFound(err) {
+			logrus.WithError(err).Warnf("Failed to remove the finalizer %s from %T %s.", finalizer, obj, obj.GetName())
+		}
+	}
+}
+
+// updateFinalizers applies a change to the finalizers of an object, retrying
+// on conflicts with other writers.
+func (s *multiStageTestStep) updateFinalizers(ctx context.Context, obj ctrlruntimeclient.Object, change func(ctrlruntimeclient.Object) bool) error {
+	key := ctrlruntimeclient.ObjectKeyFromObject(obj)
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		if err := s.client.Get(ctx, key, obj); err != nil {
+			return err
+		}
+		if !change(obj) {
+			return nil
+		}
+		return s.client.Update(ctx, obj)
+	})
+}
@@ -0,0 +1,94 @@
+package multi_stage
+
+import (
+	"context"
+	"testing"
+
+	coreapi "k8s.io/api/core/v1"
+	rbacapi "k8s.io/api/rbac/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	fakectrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/kubernetes"
+	"github.com/openshift/ci-tools/pkg/steps/loggingclient"
+	"github.com/openshift/ci-tools/pkg/testhelper"
+)
+
+func TestGuardObjects(t *testing.T) {
+	const ns = "ns"
+	credential := &coreapi.Secret{ObjectMeta: meta.ObjectMeta{
+		Namespace:  ns,
+		Name:       "vault-aws",
+		Finalizers: []string{"other.tests.ci.openshift.io/post-phase"},
+	}}
+	objects := []ctrlruntimeclient.Object{
+		&coreapi.ServiceAccount{ObjectMeta: meta.ObjectMeta{Namespace: ns, Name: "e2e"}},
+		&rbacapi.Role{ObjectMeta: meta.ObjectMeta{Namespace: ns, Name: "e2e"}},
+		&rbacapi.RoleBinding{ObjectMeta: meta.ObjectMeta{Namespace: ns, Name: "e2e"}},
+		&rbacapi.RoleBinding{ObjectMeta: meta.ObjectMeta{Namespace: ns, Name: "e2e-view"}},
+		&coreapi.Secret{ObjectMeta: meta.ObjectMeta{Namespace: ns, Name: "e2e"}},
+		&coreapi.Secret{ObjectMeta: meta.ObjectMeta{Namespace: ns, Name: "e2e-junit"}},
+		&coreapi.Secret{ObjectMeta: meta.ObjectMeta{Namespace: ns, Name: "e2e-cluster-profile"}},
+		credential,
+	}
+	fakeClient := fakectrlruntimeclient.NewClientBuilder().WithObjects(objects...).Build()
+	client := kubernetes.NewPodClient(loggingclient.New(fakeClient), nil, nil, 0)
+	jobSpec := api.JobSpec{}
+	jobSpec.SetNamespace(ns)
+	test := api.TestStepConfiguration{
+		As: "e2e",
+		MultiStageTestConfigurationLiteral: &api.MultiStageTestConfigurationLiteral{
+			Post: []api.LiteralTestStep{{
+				As:          "deprovision",
+				Credentials: []api.CredentialReference{{Namespace: "vault", Name: "aws", MountPath: "/var/run/aws"}},
+			}},
+		},
+	}
+	s := newMultiStageTestStep(test, &api.ReleaseBuildConfiguration{}, nil, client, &jobSpec, nil, "", "", nil, Options{})
+	s.profileCopy = "e2e-cluster-profile"
+	ctx := context.Background()
+	if err := s.guardObjects(ctx); err != nil {
+		t.Fatal(err)
+	}
+	finalizer := s.postPhaseFinalizer()
+	testhelper.Diff(t, "finalizer", finalizer, "e2e.tests.ci.openshift.io/post-phase")
+	// the job is aborted: the namespace is deleted while the post phase runs
+	for _, obj := range objects {
+		if err := fakeClient.Get(ctx, ctrlruntimeclient.ObjectKeyFromObject(obj), obj); err != nil {
+			t.Fatal(err)
+		}
+		var found bool
+		for _, f := range obj.GetFinalizers() {
+			found = found || f == finalizer
+		}
+		if !found {
+			t.Errorf("expected %T %s to have the finalizer of the test, got %v", obj, obj.GetName(), obj.GetFinalizers())
+		}
+		if err := fakeClient.Delete(ctx, obj); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, obj := range objects {
+		if err := fakeClient.Get(ctx, ctrlruntimeclient.ObjectKeyFromObject(obj), obj); err != nil {
+			t.Errorf("expected %T %s to be available to the post phase, got %v", obj, obj.GetName(), err)
+		}
+	}
+	s.releaseObjects(ctx)
+	for _, obj := range objects {
+		err := fakeClient.Get(ctx, ctrlruntimeclient.ObjectKeyFromObject(obj), obj)
+		if obj.GetName() == credential.Name {
+			if err != nil {
+				t.Errorf("expected the credential to be kept for the other test, got %v", err)
+			} else {
+				testhelper.Diff(t, "credential finalizers", obj.GetFinalizers(), []string{"other.tests.ci.openshift.io/post-phase"})
+			}
+		} else if !kerrors.IsNotFound(err) {
+			t.Errorf("expected %T %s to be removed once released, got %v", obj, obj.GetName(), err)
+		}
+	}
+	// releasing again, e.g. at the start of the next execution, is a no-op
+	s.releaseObjects(ctx)
+}
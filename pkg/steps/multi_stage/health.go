@@ -0,0 +1,206 @@
+package multi_stage
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	configv1 "github.com/openshift/api/config/v1"
+	coreapi "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/clientcmd"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/junit"
+	"github.com/openshift/ci-tools/pkg/results"
+)
+
+const (
+	defaultClusterHealthTimeout  = 10 * time.Minute
+	defaultClusterHealthInterval = 15 * time.Second
+	// sharedDirKubeconfig is the key in the shared directory where
+	// installation steps place the admin kubeconfig of the cluster.
+	sharedDirKubeconfig = "kubeconfig"
+)
+
+// runClusterHealthGate waits for the cluster installed in the `pre` phase to
+// become healthy, if the test configures a gate.
+func (s *multiStageTestStep) runClusterHealthGate(ctx context.Context) error {
+	if s.resolved == nil || s.resolved.ClusterHealthGate == nil {
+		return nil
+	}
+	gate := s.resolved.ClusterHealthGate
+	checks := gate.Checks
+	if len(checks) == 0 {
+		checks = api.ClusterHealthChecks
+	}
+	timeout := defaultClusterHealthTimeout
+	if gate.Timeout != nil {
+		timeout = gate.Timeout.Duration
+	}
+	logrus.Infof("Waiting up to %s for the cluster of test %s to become healthy", timeout, s.name)
+	start := time.Now()
+	client, err := s.clusterClient(ctx)
+	if err == nil {
+		err = waitForClusterHealth(ctx, client, checks, defaultClusterHealthInterval, timeout)
+	}
+	testCase := &junit.TestCase{
+		Name:     fmt.Sprintf("Cluster of multi-stage test %s becomes healthy before the test phase", s.name),
+		Duration: time.Since(start).Seconds(),
+	}
+	if err != nil {
+		err = &results.DependencyError{Err: results.ForReason("install_never_became_healthy").WithError(err).Errorf("install never became healthy: %v", err)}
+		testCase.FailureOutput = &junit.FailureOutput{Output: err.Error()}
+	}
+	s.subLock.Lock()
+	s.subTests = append(s.subTests, testCase)
+	s.subLock.Unlock()
+	return err
+}
+
+// sharedKubeconfig returns the kubeconfig of the cluster under test, written
+// to the shared directory by the steps which install it.
+func (s *multiStageTestStep) sharedKubeconfig(ctx context.Context) ([]byte, error) {
+	secret := &coreapi.Secret{}
+	if err := s.client.Get(ctx, ctrlruntimeclient.ObjectKey{Namespace: s.jobSpec.Namespace(), Name: s.name}, secret); err != nil {
+		return nil, fmt.Errorf("failed to get the shared directory: %w", err)
+	}
+	data, err := s.sharedDirData(secret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt the shared directory: %w", err)
+	}
+	kubeconfig, ok := data[sharedDirKubeconfig]
+	if !ok {
+		return nil, fmt.Errorf("no %s was found in the shared directory", sharedDirKubeconfig)
+	}
+	return kubeconfig, nil
+}
+
+// clusterClient creates a client for the cluster under test from the
+// kubeconfig in the shared directory.
+func (s *multiStageTestStep) clusterClient(ctx context.Context) (ctrlruntimeclient.Client, error) {
+	kubeconfig, err := s.sharedKubeconfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	config, err := clientcmd.RESTConfigFromKubeConfig(kubeconfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the kubeconfig from the shared directory: %w", err)
+	}
+	scheme := runtime.NewScheme()
+	if err := coreapi.AddToScheme(scheme); err != nil {
+		return nil, err
+	}
+	if err := configv1.Install(scheme); err != nil {
+		return nil, err
+	}
+	return ctrlruntimeclient.New(config, ctrlruntimeclient.Options{Scheme: scheme})
+}
+
+// waitForClusterHealth runs the checks until they all pass or the timeout
+// expires, in which case the last problems found are returned.
+func waitForClusterHealth(ctx context.Context, client ctrlruntimeclient.Client, checks []api.ClusterHealthCheck, interval, timeout time.Duration) error {
+	var problems []string
+	err := wait.PollUntilContextTimeout(ctx, interval, timeout, true, func(ctx context.Context) (bool, error) {
+		problems = checkClusterHealth(ctx, client, checks)
+		for _, p := range problems {
+			logrus.Debugf("Cluster is not healthy: %s", p)
+		}
+		return len(problems) == 0, nil
+	})
+	if err != nil && len(problems) != 0 {
+		return fmt.Errorf("cluster was not healthy after %s: %s", timeout, strings.Join(problems, "; "))
+	}
+	return err
+}
+
+func checkClusterHealth(ctx context.Context, client ctrlruntimeclient.Client, checks []api.ClusterHealthCheck) []string {
+	var problems []string
+	for _, check := range checks {
+		switch check {
+		case api.ClusterHealthCheckClusterVersion:
+			problems = append(problems, checkClusterVersion(ctx, client)...)
+		case api.ClusterHealthCheckClusterOperators:
+			problems = append(problems, checkClusterOperators(ctx, client)...)
+		case api.ClusterHealthCheckNodes:
+			problems = append(problems, checkNodes(ctx, client)...)
+		}
+	}
+	return problems
+}
+
+func checkClusterVersion(ctx context.Context, client ctrlruntimeclient.Client) []string {
+	cv := &configv1.ClusterVersion{}
+	if err := client.Get(ctx, ctrlruntimeclient.ObjectKey{Name: "version"}, cv); err != nil {
+		return []string{fmt.Sprintf("failed to get ClusterVersion: %v", err)}
+	}
+	if c := findClusterStatusCondition(cv.Status.Conditions, configv1.OperatorAvailable); c == nil || c.Status != configv1.ConditionTrue {
+		return []string{"ClusterVersion is not Available" + conditionMessage(c)}
+	}
+	return nil
+}
+
+func checkClusterOperators(ctx context.Context, client ctrlruntimeclient.Client) []string {
+	operators := &configv1.ClusterOperatorList{}
+	if err := client.List(ctx, operators); err != nil {
+		return []string{fmt.Sprintf("failed to list ClusterOperators: %v", err)}
+	}
+	sort.Slice(operators.Items, func(i, j int) bool { return operators.Items[i].Name < operators.Items[j].Name })
+	var problems []string
+	for _, co := range operators.Items {
+		if c := findClusterStatusCondition(co.Status.Conditions, configv1.OperatorAvailable); c == nil || c.Status != configv1.ConditionTrue {
+			problems = append(problems, fmt.Sprintf("ClusterOperator %s is not Available%s", co.Name, conditionMessage(c)))
+		}
+		if c := findClusterStatusCondition(co.Status.Conditions, configv1.OperatorDegraded); c != nil && c.Status == configv1.ConditionTrue {
+			problems = append(problems, fmt.Sprintf("ClusterOperator %s is Degraded%s", co.Name, conditionMessage(c)))
+		}
+	}
+	return problems
+}
+
+func checkNodes(ctx context.Context, client ctrlruntimeclient.Client) []string {
+	nodes := &coreapi.NodeList{}
+	if err := client.List(ctx, nodes); err != nil {
+		return []string{fmt.Sprintf("failed to list Nodes: %v", err)}
+	}
+	if len(nodes.Items) == 0 {
+		return []string{"no Nodes were found"}
+	}
+	sort.Slice(nodes.Items, func(i, j int) bool { return nodes.Items[i].Name < nodes.Items[j].Name })
+	var problems []string
+	for _, node := range nodes.Items {
+		ready := false
+		for _, c := range node.Status.Conditions {
+			if c.Type == coreapi.NodeReady {
+				ready = c.Status == coreapi.ConditionTrue
+				break
+			}
+		}
+		if !ready {
+			problems = append(problems, fmt.Sprintf("Node %s is not Ready", node.Name))
+		}
+	}
+	return problems
+}
+
+func findClusterStatusCondition(conditions []configv1.ClusterOperatorStatusCondition, t configv1.ClusterStatusConditionType) *configv1.ClusterOperatorStatusCondition {
+	for i := range conditions {
+		if conditions[i].Type == t {
+			return &conditions[i]
+		}
+	}
+	return nil
+}
+
+func conditionMessage(c *configv1.ClusterOperatorStatusCondition) string {
+	if c == nil || c.Message == "" {
+		return ""
+	}
+	return ": " + c.Message
+}
@@ -0,0 +1,145 @@
+package multi_stage
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	configv1 "github.com/openshift/api/config/v1"
+	coreapi "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	fakectrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/kubernetes"
+	"github.com/openshift/ci-tools/pkg/results"
+	"github.com/openshift/ci-tools/pkg/steps/loggingclient"
+)
+
+func healthScheme(t *testing.T) *runtime.Scheme {
+	scheme := runtime.NewScheme()
+	if err := coreapi.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := configv1.Install(scheme); err != nil {
+		t.Fatal(err)
+	}
+	return scheme
+}
+
+func TestCheckClusterHealth(t *testing.T) {
+	healthyVersion := &configv1.ClusterVersion{
+		ObjectMeta: meta.ObjectMeta{Name: "version"},
+		Status: configv1.ClusterVersionStatus{Conditions: []configv1.ClusterOperatorStatusCondition{
+			{Type: configv1.OperatorAvailable, Status: configv1.ConditionTrue},
+		}},
+	}
+	operator := func(name string, available, degraded configv1.ConditionStatus, msg string) *configv1.ClusterOperator {
+		return &configv1.ClusterOperator{
+			ObjectMeta: meta.ObjectMeta{Name: name},
+			Status: configv1.ClusterOperatorStatus{Conditions: []configv1.ClusterOperatorStatusCondition{
+				{Type: configv1.OperatorAvailable, Status: available, Message: msg},
+				{Type: configv1.OperatorDegraded, Status: degraded, Message: msg},
+			}},
+		}
+	}
+	node := func(name string, ready coreapi.ConditionStatus) *coreapi.Node {
+		return &coreapi.Node{
+			ObjectMeta: meta.ObjectMeta{Name: name},
+			Status: coreapi.NodeStatus{Conditions: []coreapi.NodeCondition{
+				{Type: coreapi.NodeMemoryPressure, Status: coreapi.ConditionFalse},
+				{Type: coreapi.NodeReady, Status: ready},
+			}},
+		}
+	}
+	for _, tc := range []struct {
+		name     string
+		objects  []runtime.Object
+		checks   []api.ClusterHealthCheck
+		expected []string
+	}{{
+		name: "healthy cluster",
+		objects: []runtime.Object{
+			healthyVersion,
+			operator("etcd", configv1.ConditionTrue, configv1.ConditionFalse, ""),
+			node("master-0", coreapi.ConditionTrue),
+		},
+		checks: api.ClusterHealthChecks,
+	}, {
+		name:     "nothing to check",
+		checks:   api.ClusterHealthChecks,
+		expected: []string{`failed to get ClusterVersion: clusterversions.config.openshift.io "version" not found`, "no Nodes were found"},
+	}, {
+		name: "unhealthy cluster",
+		objects: []runtime.Object{
+			&configv1.ClusterVersion{ObjectMeta: meta.ObjectMeta{Name: "version"}},
+			operator("etcd", configv1.ConditionTrue, configv1.ConditionTrue, "member is unhealthy"),
+			operator("authentication", configv1.ConditionFalse, configv1.ConditionFalse, "route is not ready"),
+			node("worker-0", coreapi.ConditionFalse),
+			node("master-0", coreapi.ConditionTrue),
+		},
+		checks: api.ClusterHealthChecks,
+		expected: []string{
+			"ClusterVersion is not Available",
+			"ClusterOperator authentication is not Available: route is not ready",
+			"ClusterOperator etcd is Degraded: member is unhealthy",
+			"Node worker-0 is not Ready",
+		},
+	}, {
+		name: "only selected checks are run",
+		objects: []runtime.Object{
+			healthyVersion,
+			operator("etcd", configv1.ConditionTrue, configv1.ConditionTrue, "member is unhealthy"),
+			node("worker-0", coreapi.ConditionFalse),
+		},
+		checks:   []api.ClusterHealthCheck{api.ClusterHealthCheckClusterVersion, api.ClusterHealthCheckNodes},
+		expected: []string{"Node worker-0 is not Ready"},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			client := fakectrlruntimeclient.NewClientBuilder().WithScheme(healthScheme(t)).WithRuntimeObjects(tc.objects...).Build()
+			problems := checkClusterHealth(context.Background(), client, tc.checks)
+			if diff := cmp.Diff(tc.expected, problems); diff != "" {
+				t.Errorf("unexpected problems: %s", diff)
+			}
+		})
+	}
+}
+
+func TestWaitForClusterHealth(t *testing.T) {
+	client := fakectrlruntimeclient.NewClientBuilder().WithScheme(healthScheme(t)).Build()
+	err := waitForClusterHealth(context.Background(), client, []api.ClusterHealthCheck{api.ClusterHealthCheckNodes}, time.Millisecond, 10*time.Millisecond)
+	expected := "cluster was not healthy after 10ms: no Nodes were found"
+	if err == nil || err.Error() != expected {
+		t.Errorf("expected error %q, got %v", expected, err)
+	}
+}
+
+func TestRunClusterHealthGateWithoutKubeconfig(t *testing.T) {
+	jobSpec := api.JobSpec{}
+	jobSpec.SetNamespace("ns")
+	client := kubernetes.NewPodClient(loggingclient.New(fakectrlruntimeclient.NewClientBuilder().WithRuntimeObjects(
+		&coreapi.Secret{ObjectMeta: meta.ObjectMeta{Namespace: "ns", Name: "test"}},
+	).Build()), nil, nil, 0)
+	s := multiStageTestStep{
+		name:     "test",
+		jobSpec:  &jobSpec,
+		client:   client,
+		subLock:  &sync.Mutex{},
+		resolved: &api.MultiStageTestConfigurationLiteral{ClusterHealthGate: &api.ClusterHealthGate{}},
+	}
+	err := s.runClusterHealthGate(context.Background())
+	if diff := cmp.Diff([]string{"install_never_became_healthy"}, results.Reasons(err)); diff != "" {
+		t.Errorf("unexpected reasons: %s", diff)
+	}
+	expected := "install never became healthy: no kubeconfig was found in the shared directory"
+	if diff := cmp.Diff(expected, err.Error()); diff != "" {
+		t.Errorf("unexpected error: %s", diff)
+	}
+	if len(s.subTests) != 1 || s.subTests[0].FailureOutput == nil {
+		t.Errorf("expected a failed test case, got %v", s.subTests)
+	}
+}
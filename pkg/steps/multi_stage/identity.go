@@ -0,0 +1,90 @@
+package multi_stage
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	coreapi "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/test-infra/prow/entrypoint"
+
+	"github.com/openshift/ci-tools/pkg/steps/identity"
+)
+
+const (
+	// StepIdentityTokenEnv exposes the path of the file which holds the
+	// identity token of the step.
+	StepIdentityTokenEnv = "CI_STEP_IDENTITY_TOKEN_FILE"
+	// StepIdentityMountPath is where the identity token of a step is mounted.
+	StepIdentityMountPath = "/var/run/ci.openshift.io/identity"
+	stepIdentityTokenKey  = "token"
+	stepIdentityVolume    = "step-identity"
+	// stepIdentityMargin is added to the validity of tokens, for the pod to
+	// be scheduled and its image pulled before the commands start.
+	stepIdentityMargin = 30 * time.Minute
+)
+
+func stepIdentitySecretName(podName string) string {
+	return podName + "-identity"
+}
+
+// addStepIdentity issues a token which identifies the step of the pod and
+// mounts it in the test container.  The token expires once the step would be
+// terminated, so that it cannot be used after the step finished.
+func (s *multiStageTestStep) addStepIdentity(ctx context.Context, pod *coreapi.Pod) error {
+	if s.options.StepIdentity == nil {
+		return nil
+	}
+	step, ok := s.stepFor(pod)
+	if !ok {
+		return nil
+	}
+	gracePeriod := entrypoint.DefaultGracePeriod
+	if step.GracePeriod != nil {
+		gracePeriod = step.GracePeriod.Duration
+	}
+	token, err := s.options.StepIdentity.Issue(identity.Identity{
+		Job:       s.jobSpec.Job,
+		BuildID:   s.jobSpec.BuildID,
+		ProwJobID: s.jobSpec.ProwJobID,
+		Namespace: s.jobSpec.Namespace(),
+		Test:      s.name,
+		Step:      step.As,
+	}, s.stepTimeout(&step)+gracePeriod+stepIdentityMargin)
+	if err != nil {
+		return fmt.Errorf("failed to issue identity token for %s: %w", pod.Name, err)
+	}
+	s.censor.AddSecrets(token)
+	secret := &coreapi.Secret{
+		ObjectMeta: meta.ObjectMeta{
+			Namespace:       pod.Namespace,
+			Name:            stepIdentitySecretName(pod.Name),
+			OwnerReferences: s.ownerReferences(),
+		},
+		StringData: map[string]string{stepIdentityTokenKey: token},
+	}
+	s.addMetadata(secret)
+	if err := s.client.Delete(ctx, secret); err != nil && !kerrors.IsNotFound(err) {
+		return fmt.Errorf("cannot delete identity token %q: %w", secret.Name, err)
+	}
+	if err := s.client.Create(ctx, secret); err != nil {
+		return fmt.Errorf("cannot create identity token %q: %w", secret.Name, err)
+	}
+	pod.Spec.Volumes = append(pod.Spec.Volumes, coreapi.Volume{
+		Name: stepIdentityVolume,
+		VolumeSource: coreapi.VolumeSource{
+			Secret: &coreapi.SecretVolumeSource{SecretName: secret.Name},
+		},
+	})
+	container := &pod.Spec.Containers[0]
+	container.VolumeMounts = append(container.VolumeMounts, coreapi.VolumeMount{
+		Name:      stepIdentityVolume,
+		MountPath: StepIdentityMountPath,
+		ReadOnly:  true,
+	})
+	container.Env = append(container.Env, coreapi.EnvVar{Name: StepIdentityTokenEnv, Value: filepath.Join(StepIdentityMountPath, stepIdentityTokenKey)})
+	return nil
+}
@@ -0,0 +1,99 @@
+package multi_stage
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"testing"
+
+	jose "github.com/go-jose/go-jose/v3"
+	"github.com/go-jose/go-jose/v3/jwt"
+
+	coreapi "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/pod-utils/downwardapi"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	fakectrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/secrets"
+	base_steps "github.com/openshift/ci-tools/pkg/steps"
+	"github.com/openshift/ci-tools/pkg/steps/identity"
+	"github.com/openshift/ci-tools/pkg/steps/loggingclient"
+	"github.com/openshift/ci-tools/pkg/testhelper"
+	testhelper_kube "github.com/openshift/ci-tools/pkg/testhelper/kubernetes"
+)
+
+func TestAddStepIdentity(t *testing.T) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuer, err := identity.NewIssuer(jose.SigningKey{Algorithm: jose.EdDSA, Key: private}, "ci-operator", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		name     string
+		issuer   *identity.Issuer
+		expected bool
+	}{{
+		name: "no issuer",
+	}, {
+		name:     "token is mounted",
+		issuer:   issuer,
+		expected: true,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			jobSpec := api.JobSpec{JobSpec: downwardapi.JobSpec{Job: "job", BuildID: "1", Type: prowapi.PeriodicJob}}
+			jobSpec.SetNamespace("ns")
+			client := &testhelper_kube.FakePodClient{FakePodExecutor: &testhelper_kube.FakePodExecutor{
+				LoggingClient: loggingclient.New(fakectrlruntimeclient.NewClientBuilder().Build()),
+			}}
+			censor := secrets.NewDynamicCensor()
+			s := multiStageTestStep{
+				name:    "e2e",
+				test:    []api.LiteralTestStep{{As: "upload"}},
+				jobSpec: &jobSpec,
+				client:  client,
+				censor:  &censor,
+				options: Options{StepIdentity: tc.issuer},
+			}
+			pod := &coreapi.Pod{
+				ObjectMeta: meta.ObjectMeta{Namespace: "ns", Name: "e2e-upload", Labels: map[string]string{base_steps.LabelMetadataStep: "upload"}},
+				Spec:       coreapi.PodSpec{Containers: []coreapi.Container{{Name: containerName}}},
+			}
+			if err := s.addStepIdentity(context.Background(), pod); err != nil {
+				t.Fatal(err)
+			}
+			if !tc.expected {
+				testhelper.Diff(t, "pod", pod.Spec, coreapi.PodSpec{Containers: []coreapi.Container{{Name: containerName}}})
+				return
+			}
+			testhelper.Diff(t, "environment", pod.Spec.Containers[0].Env, []coreapi.EnvVar{{Name: StepIdentityTokenEnv, Value: StepIdentityMountPath + "/token"}})
+			testhelper.Diff(t, "volumes", pod.Spec.Volumes, []coreapi.Volume{{
+				Name:         "step-identity",
+				VolumeSource: coreapi.VolumeSource{Secret: &coreapi.SecretVolumeSource{SecretName: "e2e-upload-identity"}},
+			}})
+			secret := &coreapi.Secret{}
+			if err := client.Get(context.Background(), ctrlruntimeclient.ObjectKey{Namespace: "ns", Name: "e2e-upload-identity"}, secret); err != nil {
+				t.Fatal(err)
+			}
+			token, err := jwt.ParseSigned(secret.StringData["token"])
+			if err != nil {
+				t.Fatal(err)
+			}
+			var claims identity.Claims
+			if err := token.Claims(public, &claims); err != nil {
+				t.Fatal(err)
+			}
+			testhelper.Diff(t, "identity", claims.Identity, identity.Identity{Job: "job", BuildID: "1", Namespace: "ns", Test: "e2e", Step: "upload"})
+			censored := []byte(secret.StringData["token"])
+			censor.Censor(&censored)
+			if string(censored) == secret.StringData["token"] {
+				t.Error("expected the token to be censored")
+			}
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package multi_stage
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/sirupsen/logrus"
+
+	coreapi "k8s.io/api/core/v1"
+	utilpointer "k8s.io/utils/pointer"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/steps/metrics"
+)
+
+const (
+	// imagePullWarningDuration is the pull time above which an image is
+	// reported as slow to pull.
+	imagePullWarningDuration = 5 * time.Minute
+	// imagePullWarningSize is the size in bytes above which an image is
+	// reported as large.
+	imagePullWarningSize = 5 * 1024 * 1024 * 1024
+)
+
+var (
+	// pulledMessage parses the message of the events emitted by the kubelet
+	// when it has pulled an image, e.g.:
+	// Successfully pulled image "quay.io/org/image:tag" in 1m2.5s (1m10s including waiting). Image size: 1234 bytes.
+	// Older kubelets only report the duration of the pull.
+	pulledMessage = regexp.MustCompile(`^Successfully pulled image "([^"]+)" in ([^ ]+?)(?: \(([^ ]+) including waiting\))?(?:\. Image size: (\d+) bytes)?\.?$`)
+	// containerFieldPath parses the container an event refers to.
+	containerFieldPath = regexp.MustCompile(`^spec\.(?:initContainers|containers|ephemeralContainers)\{(.+)\}$`)
+)
+
+// imagePull is the pull of the image of a container of a step pod.
+type imagePull struct {
+	pod, container, image string
+	duration              time.Duration
+	// size is the size of the image in bytes, zero if the kubelet does not
+	// report it.
+	size int64
+	// finished is when the pull completed, zero if unknown.
+	finished time.Time
+}
+
+// imagePulls extracts the image pulls from the events of a pod.  Images which
+// were already present on the node do not appear.
+func imagePulls(pod string, events []coreapi.Event) []imagePull {
+	var ret []imagePull
+	for _, event := range events {
+		if event.Reason != "Pulled" {
+			continue
+		}
+		match := pulledMessage.FindStringSubmatch(event.Message)
+		if match == nil {
+			continue
+		}
+		duration, err := time.ParseDuration(match[2])
+		if err != nil {
+			logrus.WithError(err).Debugf("Failed to parse the image pull time in event %q", event.Message)
+			continue
+		}
+		pull := imagePull{pod: pod, image: match[1], duration: duration}
+		if m := containerFieldPath.FindStringSubmatch(event.InvolvedObject.FieldPath); m != nil {
+			pull.container = m[1]
+		}
+		if match[4] != "" {
+			pull.size, _ = strconv.ParseInt(match[4], 10, 64)
+		}
+		switch {
+		case !event.EventTime.IsZero():
+			pull.finished = event.EventTime.Time
+		case !event.LastTimestamp.IsZero():
+			pull.finished = event.LastTimestamp.Time
+		}
+		ret = append(ret, pull)
+	}
+	return ret
+}
+
+// warnings returns the reasons for which the pull deserves attention.
+func (p imagePull) warnings() []string {
+	var ret []string
+	if p.duration > imagePullWarningDuration {
+		ret = append(ret, fmt.Sprintf("took %s", p.duration.Truncate(time.Second)))
+	}
+	if p.size > imagePullWarningSize {
+		ret = append(ret, fmt.Sprintf("is %.1f GiB", float64(p.size)/(1024*1024*1024)))
+	}
+	return ret
+}
+
+// detail returns the entry of the pull in the timeline of the test.
+func (p imagePull) detail() api.CIOperatorStepDetailInfo {
+	detail := api.CIOperatorStepDetailInfo{
+		StepName:    fmt.Sprintf("%s-pull-%s", p.pod, p.container),
+		Description: fmt.Sprintf("Pull image %s for container %s of pod %s", p.image, p.container, p.pod),
+		Duration:    &p.duration,
+	}
+	if !p.finished.IsZero() {
+		started, finished := p.finished.Add(-p.duration), p.finished
+		detail.StartedAt, detail.FinishedAt = &started, &finished
+	}
+	return detail
+}
+
+// recordImagePulls records the image pulls of a step pod, warning about those
+// which are slow or large, and returns the entries for the timeline.
+func (s *multiStageTestStep) recordImagePulls(pod *coreapi.Pod, events []coreapi.Event) []api.CIOperatorStepDetailInfo {
+	pulls := imagePulls(pod.Name, events)
+	var details []api.CIOperatorStepDetailInfo
+	for _, pull := range pulls {
+		if warnings := pull.warnings(); len(warnings) != 0 {
+			logrus.Warnf("Pulling image %s for container %s of step %s %s, consider using a smaller image.", pull.image, pull.container, pod.Name, strings.Join(warnings, " and "))
+		}
+		details = append(details, pull.detail())
+	}
+	s.subLock.Lock()
+	s.imagePulls = append(s.imagePulls, pulls...)
+	s.subLock.Unlock()
+	return details
+}
+
+// imagePullMetrics returns the pull times and sizes of the images of the
+// steps, labeled with the identity of the job.
+func (s *multiStageTestStep) imagePullMetrics() []*dto.MetricFamily {
+	s.subLock.Lock()
+	defer s.subLock.Unlock()
+	if len(s.imagePulls) == 0 {
+		return nil
+	}
+	gauge := dto.MetricType_GAUGE
+	durations := &dto.MetricFamily{
+		Name: utilpointer.String("ci_step_image_pull_duration_seconds"),
+		Help: utilpointer.String("Time taken to pull the image of a container of a multi-stage test step."),
+		Type: &gauge,
+	}
+	sizes := &dto.MetricFamily{
+		Name: utilpointer.String("ci_step_image_size_bytes"),
+		Help: utilpointer.String("Size of the image of a container of a multi-stage test step."),
+		Type: &gauge,
+	}
+	for _, pull := range s.imagePulls {
+		labels := []*dto.LabelPair{
+			{Name: utilpointer.String("build_id"), Value: utilpointer.String(s.jobSpec.BuildID)},
+			{Name: utilpointer.String("container"), Value: utilpointer.String(pull.container)},
+			{Name: utilpointer.String("image"), Value: utilpointer.String(pull.image)},
+			{Name: utilpointer.String("job"), Value: utilpointer.String(s.jobSpec.Job)},
+			{Name: utilpointer.String("step"), Value: utilpointer.String(strings.TrimPrefix(pull.pod, s.name+"-"))},
+			{Name: utilpointer.String("test"), Value: utilpointer.String(s.name)},
+		}
+		seconds := pull.duration.Seconds()
+		durations.Metric = append(durations.Metric, &dto.Metric{Label: labels, Gauge: &dto.Gauge{Value: &seconds}})
+		if pull.size != 0 {
+			size := float64(pull.size)
+			sizes.Metric = append(sizes.Metric, &dto.Metric{Label: labels, Gauge: &dto.Gauge{Value: &size}})
+		}
+	}
+	ret := []*dto.MetricFamily{durations}
+	if len(sizes.Metric) != 0 {
+		ret = append(ret, sizes)
+	}
+	return ret
+}
+
+// forwardPodMetrics sends the image pull, quota wait and organization quota
+// wait metrics of the test to the metrics sink, if one is configured.
+func (s *multiStageTestStep) forwardPodMetrics(ctx context.Context) error {
+	if s.options.MetricsSink == "" {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+	return metrics.Forward(ctx, http.DefaultClient, s.options.MetricsSink, append(append(s.imagePullMetrics(), s.quotaWaitMetrics()...), s.orgQuotaWaitMetrics()...))
+}
@@ -0,0 +1,119 @@
+package multi_stage
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	coreapi "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	prowdapi "k8s.io/test-infra/prow/pod-utils/downwardapi"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/testhelper"
+)
+
+func TestImagePulls(t *testing.T) {
+	finished := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	event := func(reason, fieldPath, message string) coreapi.Event {
+		return coreapi.Event{
+			Reason:         reason,
+			Message:        message,
+			InvolvedObject: coreapi.ObjectReference{FieldPath: fieldPath},
+			LastTimestamp:  meta.NewTime(finished),
+		}
+	}
+	for _, tc := range []struct {
+		name     string
+		events   []coreapi.Event
+		expected []imagePull
+		warnings [][]string
+	}{{
+		name: "image already present",
+		events: []coreapi.Event{
+			event("Pulled", "spec.containers{test}", `Container image "quay.io/org/image:tag" already present on machine`),
+		},
+	}, {
+		name: "pull with size",
+		events: []coreapi.Event{
+			event("Scheduled", "", "Successfully assigned ns/pod to node"),
+			event("Pulled", "spec.containers{test}", `Successfully pulled image "quay.io/org/tests:latest" in 6m2.5s (6m10s including waiting). Image size: 7516192768 bytes.`),
+		},
+		expected: []imagePull{{pod: "e2e-step", container: "test", image: "quay.io/org/tests:latest", duration: 6*time.Minute + 2500*time.Millisecond, size: 7516192768, finished: finished}},
+		warnings: [][]string{{"took 6m2s", "is 7.0 GiB"}},
+	}, {
+		name: "pull reported by an older kubelet",
+		events: []coreapi.Event{
+			event("Pulled", "spec.initContainers{cp-entrypoint-wrapper}", `Successfully pulled image "registry.ci.openshift.org/ci/entrypoint-wrapper:latest" in 1.5s`),
+		},
+		expected: []imagePull{{pod: "e2e-step", container: "cp-entrypoint-wrapper", image: "registry.ci.openshift.org/ci/entrypoint-wrapper:latest", duration: 1500 * time.Millisecond, finished: finished}},
+		warnings: [][]string{nil},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			pulls := imagePulls("e2e-step", tc.events)
+			testhelper.Diff(t, "pulls", pulls, tc.expected, cmp.AllowUnexported(imagePull{}))
+			for i, pull := range pulls {
+				testhelper.Diff(t, "warnings", pull.warnings(), tc.warnings[i])
+			}
+		})
+	}
+}
+
+func TestImagePullDetail(t *testing.T) {
+	finished := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	pull := imagePull{pod: "e2e-step", container: "test", image: "quay.io/org/tests:latest", duration: time.Minute, finished: finished}
+	detail := pull.detail()
+	testhelper.Diff(t, "name", detail.StepName, "e2e-step-pull-test")
+	testhelper.Diff(t, "description", detail.Description, "Pull image quay.io/org/tests:latest for container test of pod e2e-step")
+	if detail.StartedAt == nil || !detail.StartedAt.Equal(finished.Add(-time.Minute)) {
+		t.Errorf("expected the pull to start a minute before it finished, got %v", detail.StartedAt)
+	}
+	if detail.FinishedAt == nil || !detail.FinishedAt.Equal(finished) {
+		t.Errorf("expected the pull to finish at %v, got %v", finished, detail.FinishedAt)
+	}
+}
+
+func TestForwardPodMetrics(t *testing.T) {
+	var lock sync.Mutex
+	var received string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+		}
+		lock.Lock()
+		received = string(raw)
+		lock.Unlock()
+	}))
+	defer server.Close()
+	jobSpec := api.JobSpec{JobSpec: prowdapi.JobSpec{Job: "job", BuildID: "1"}}
+	s := multiStageTestStep{
+		name:    "e2e",
+		jobSpec: &jobSpec,
+		subLock: &sync.Mutex{},
+		options: Options{MetricsSink: server.URL},
+		imagePulls: []imagePull{
+			{pod: "e2e-install", container: "test", image: "quay.io/org/installer:latest", duration: 90 * time.Second, size: 2048},
+			{pod: "e2e-install", container: "cp-entrypoint-wrapper", image: "registry.ci.openshift.org/ci/entrypoint-wrapper:latest", duration: 500 * time.Millisecond},
+		},
+	}
+	if err := s.forwardPodMetrics(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	testhelper.Diff(t, "metrics", received, `# HELP ci_step_image_pull_duration_seconds Time taken to pull the image of a container of a multi-stage test step.
+# TYPE ci_step_image_pull_duration_seconds gauge
+ci_step_image_pull_duration_seconds{build_id="1",container="test",image="quay.io/org/installer:latest",job="job",step="install",test="e2e"} 90
+ci_step_image_pull_duration_seconds{build_id="1",container="cp-entrypoint-wrapper",image="registry.ci.openshift.org/ci/entrypoint-wrapper:latest",job="job",step="install",test="e2e"} 0.5
+# HELP ci_step_image_size_bytes Size of the image of a container of a multi-stage test step.
+# TYPE ci_step_image_size_bytes gauge
+ci_step_image_size_bytes{build_id="1",container="test",image="quay.io/org/installer:latest",job="job",step="install",test="e2e"} 2048
+`)
+}
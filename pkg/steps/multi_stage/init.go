@@ -3,8 +3,11 @@ package multi_stage
 import (
 	"context"
 	"fmt"
+	"path"
 	"regexp"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/sirupsen/logrus"
@@ -17,6 +20,8 @@ import (
 
 	"github.com/openshift/ci-tools/pkg/api"
 	"github.com/openshift/ci-tools/pkg/kubernetes"
+	"github.com/openshift/ci-tools/pkg/steps/shareddir"
+	"github.com/openshift/ci-tools/pkg/steps/testresults"
 	"github.com/openshift/ci-tools/pkg/util"
 )
 
@@ -27,15 +32,146 @@ var (
 	uidRangeRegexp = regexp.MustCompile(`^(\d+)/\d+`)
 )
 
+// ownerReferences returns the references with which objects created for the
+// test are owned by the job, so they are garbage-collected with it even if
+// the namespace outlives the job.
+func (s *multiStageTestStep) ownerReferences() []meta.OwnerReference {
+	if owner := s.jobSpec.Owner(); owner != nil {
+		return []meta.OwnerReference{*owner}
+	}
+	return nil
+}
+
+// addMetadata adds the labels and annotations of the test to an object to be
+// created, without replacing those set by ci-operator.
+func (s *multiStageTestStep) addMetadata(obj meta.Object) {
+	obj.SetLabels(mergeMetadata(obj.GetLabels(), s.labels))
+	obj.SetAnnotations(mergeMetadata(obj.GetAnnotations(), s.annotations))
+}
+
+func mergeMetadata(into, from map[string]string) map[string]string {
+	if len(from) == 0 {
+		return into
+	}
+	ret := make(map[string]string, len(into)+len(from))
+	for k, v := range from {
+		ret[k] = v
+	}
+	for k, v := range into {
+		ret[k] = v
+	}
+	return ret
+}
+
+// scrubLabels marks a secret to be scrubbed when the job exits and claims it
+// for the job.
+func (s *multiStageTestStep) scrubLabels() map[string]string {
+	return map[string]string{api.ScrubOnExitLabel: "true", api.ScrubOwnerLabel(s.jobSpec.ScrubOwner()): "true"}
+}
+
+// sharedDirSecret returns the secret which holds the shared directory.
+func (s *multiStageTestStep) sharedDirSecret() *coreapi.Secret {
+	secret := &coreapi.Secret{ObjectMeta: meta.ObjectMeta{
+		Namespace:       s.jobSpec.Namespace(),
+		Name:            s.name,
+		Labels:          mergeMetadata(map[string]string{api.SkipCensoringLabel: "true"}, s.scrubLabels()),
+		OwnerReferences: s.ownerReferences(),
+	}}
+	s.addMetadata(secret)
+	return secret
+}
+
 func (s *multiStageTestStep) createSharedDirSecret(ctx context.Context) error {
 	logrus.Debugf("Creating multi-stage test shared directory %q", s.name)
+	secret := s.sharedDirSecret()
+	if err := s.client.Delete(ctx, secret); err != nil && !kerrors.IsNotFound(err) {
+		return fmt.Errorf("cannot delete shared directory %q: %w", s.name, err)
+	}
+	return s.client.Create(ctx, secret)
+}
+
+// createSharedDirKey generates the key with which the contents of the shared
+// directory are encrypted and stores it in the secret mounted by the steps.
+// The secret is a plain secret in the namespace of the test, so until
+// deleteSharedDirKey removes it, the key does not protect the contents from
+// anyone who can read the secrets of the namespace.
+func (s *multiStageTestStep) createSharedDirKey(ctx context.Context) error {
+	key, err := shareddir.GenerateKey()
+	if err != nil {
+		return err
+	}
+	name := shareddir.KeySecretName(s.name)
+	logrus.Debugf("Creating multi-stage test shared directory key %q", name)
+	secret := s.sharedDirKeySecret(key)
+	if err := s.client.Delete(ctx, secret); err != nil && !kerrors.IsNotFound(err) {
+		return fmt.Errorf("cannot delete shared directory key %q: %w", name, err)
+	}
+	if err := s.client.Create(ctx, secret); err != nil {
+		return fmt.Errorf("cannot create shared directory key %q: %w", name, err)
+	}
+	s.sharedDirKey = key
+	return nil
+}
+
+// sharedDirKeySecret returns the secret which holds the key of the shared
+// directory.
+func (s *multiStageTestStep) sharedDirKeySecret(key []byte) *coreapi.Secret {
+	secret := &coreapi.Secret{
+		ObjectMeta: meta.ObjectMeta{
+			Namespace:       s.jobSpec.Namespace(),
+			Name:            shareddir.KeySecretName(s.name),
+			Labels:          s.scrubLabels(),
+			OwnerReferences: s.ownerReferences(),
+		},
+		Data: map[string][]byte{shareddir.KeySecretKey: key},
+	}
+	s.addMetadata(secret)
+	return secret
+}
+
+// deleteSharedDirKey removes the key of the shared directory once the test
+// has finished, after which its contents can no longer be read.
+func (s *multiStageTestStep) deleteSharedDirKey(ctx context.Context) {
+	secret := &coreapi.Secret{ObjectMeta: meta.ObjectMeta{Namespace: s.jobSpec.Namespace(), Name: shareddir.KeySecretName(s.name)}}
+	if err := s.client.Delete(ctx, secret); err != nil && !kerrors.IsNotFound(err) {
+		logrus.WithError(err).Warnf("Failed to delete the shared directory key of test %s", s.name)
+	}
+}
+
+// sharedDirData returns the contents of the shared directory secret,
+// decrypted and with their provenance verified if encryption is enabled.
+func (s *multiStageTestStep) sharedDirData(secret *coreapi.Secret) (map[string][]byte, error) {
+	if s.sharedDirKey == nil {
+		return secret.Data, nil
+	}
+	data, err := shareddir.DecryptData(s.sharedDirKey, secret.Data)
+	if err != nil {
+		return nil, err
+	}
+	data, _, err = shareddir.VerifiedData(s.sharedDirKey, data)
+	return data, err
+}
+
+// resultsSecret returns the secret where steps store their jUnit results.
+func (s *multiStageTestStep) resultsSecret() *coreapi.Secret {
 	secret := &coreapi.Secret{ObjectMeta: meta.ObjectMeta{
-		Namespace: s.jobSpec.Namespace(),
-		Name:      s.name,
-		Labels:    map[string]string{api.SkipCensoringLabel: "true"},
+		Namespace:       s.jobSpec.Namespace(),
+		Name:            testresults.SecretName(s.name),
+		Labels:          map[string]string{api.SkipCensoringLabel: "true"},
+		OwnerReferences: s.ownerReferences(),
 	}}
+	s.addMetadata(secret)
+	return secret
+}
+
+// createResultsSecret creates the secret where steps store their jUnit
+// results.
+func (s *multiStageTestStep) createResultsSecret(ctx context.Context) error {
+	name := testresults.SecretName(s.name)
+	logrus.Debugf("Creating multi-stage test results secret %q", name)
+	secret := s.resultsSecret()
 	if err := s.client.Delete(ctx, secret); err != nil && !kerrors.IsNotFound(err) {
-		return fmt.Errorf("cannot delete shared directory %q: %w", s.name, err)
+		return fmt.Errorf("cannot delete results secret %q: %w", name, err)
 	}
 	return s.client.Create(ctx, secret)
 }
@@ -43,13 +179,23 @@ func (s *multiStageTestStep) createSharedDirSecret(ctx context.Context) error {
 func (s *multiStageTestStep) createCredentials(ctx context.Context) error {
 	logrus.Debugf("Creating multi-stage test credentials for %q", s.name)
 	toCreate := map[string]*coreapi.Secret{}
-	for _, step := range append(s.pre, append(s.test, s.post...)...) {
+	for _, step := range s.allSteps() {
 		for _, credential := range step.Credentials {
-			// we don't want secrets imported from separate namespaces to collide
-			// but we want to keep them generally recognizable for debugging, and the
-			// chance we get a second-level collision (ns-a, name) and (ns, a-name) is
-			// small, so we can get away with this string prefixing
-			name := fmt.Sprintf("%s-%s", credential.Namespace, credential.Name)
+			if credential.Selector != nil {
+				if _, ok := s.credentialCollections[credentialCollectionKey(credential)]; ok {
+					continue
+				}
+				collection, err := s.resolveCredentialCollection(ctx, credential)
+				if err != nil {
+					return err
+				}
+				for i := range collection {
+					source := api.CredentialReference{Namespace: credential.Namespace, Name: collection[i].Name}
+					toCreate[credentialSecretName(source)] = s.credentialSecret(source, &collection[i])
+				}
+				continue
+			}
+			name := credentialSecretName(credential)
 			if _, ok := toCreate[name]; ok {
 				continue
 			}
@@ -57,43 +203,92 @@ func (s *multiStageTestStep) createCredentials(ctx context.Context) error {
 			if err := s.client.Get(ctx, ctrlruntimeclient.ObjectKey{Namespace: credential.Namespace, Name: credential.Name}, raw); err != nil {
 				return fmt.Errorf("could not read source credential: %w", err)
 			}
-			toCreate[name] = &coreapi.Secret{
-				TypeMeta: raw.TypeMeta,
-				ObjectMeta: meta.ObjectMeta{
-					Name:      name,
-					Namespace: s.jobSpec.Namespace(),
-				},
-				Type:       raw.Type,
-				Data:       raw.Data,
-				StringData: raw.StringData,
-			}
+			toCreate[name] = s.credentialSecret(credential, raw)
 		}
 	}
 
 	for name := range toCreate {
-		if err := s.client.Create(ctx, toCreate[name]); err != nil && !kerrors.IsAlreadyExists(err) {
-			return fmt.Errorf("could not create source credential: %w", err)
+		if err := s.client.Create(ctx, toCreate[name]); err != nil {
+			if !kerrors.IsAlreadyExists(err) {
+				return fmt.Errorf("could not create source credential: %w", err)
+			}
+			// the copy is shared with the other jobs in the namespace
+			if err := util.AddScrubOwner(ctx, s.client, ctrlruntimeclient.ObjectKeyFromObject(toCreate[name]), s.jobSpec.ScrubOwner()); err != nil {
+				return fmt.Errorf("could not claim source credential: %w", err)
+			}
 		}
 	}
 	return nil
 }
 
-func (s *multiStageTestStep) createCommandConfigMaps(ctx context.Context) error {
-	logrus.Debugf("Creating multi-stage test commands configmap for %q", s.name)
-	data := make(map[string]string)
-	for _, step := range append(s.pre, append(s.test, s.post...)...) {
-		data[step.As] = step.Commands
-	}
-	name := commandConfigMapForTest(s.name)
-	yes := true
-	commands := &coreapi.ConfigMap{
+// credentialSecretName returns the name of the copy of a credential in the
+// test namespace.  We don't want secrets imported from separate namespaces to
+// collide but we want to keep them generally recognizable for debugging, and
+// the chance we get a second-level collision (ns-a, name) and (ns, a-name) is
+// small, so we can get away with this string prefixing.
+func credentialSecretName(credential api.CredentialReference) string {
+	return fmt.Sprintf("%s-%s", credential.Namespace, credential.Name)
+}
+
+// credentialSecret returns the copy of a credential in the test namespace.
+func (s *multiStageTestStep) credentialSecret(credential api.CredentialReference, raw *coreapi.Secret) *coreapi.Secret {
+	secret := &coreapi.Secret{
+		TypeMeta: raw.TypeMeta,
 		ObjectMeta: meta.ObjectMeta{
-			Name:      name,
-			Namespace: s.jobSpec.Namespace(),
+			Name:            credentialSecretName(credential),
+			Namespace:       s.jobSpec.Namespace(),
+			Labels:          s.scrubLabels(),
+			OwnerReferences: s.ownerReferences(),
 		},
-		Data:      data,
-		Immutable: &yes,
+		Type:       raw.Type,
+		Data:       raw.Data,
+		StringData: raw.StringData,
+	}
+	s.addMetadata(secret)
+	return secret
+}
+
+// commandScripts returns the executable scripts of the steps and observers.
+// Steps in exec form have no script.
+func (s *multiStageTestStep) commandScripts() map[string]string {
+	scripts := make(map[string]string)
+	for _, step := range s.allSteps() {
+		if execCommand(&step) == nil {
+			scripts[step.As] = commandScript(&step)
+		}
 	}
+	for _, observer := range s.observers {
+		scripts[observer.Name] = CommandPrefix + observer.Commands
+	}
+	return scripts
+}
+
+func (s *multiStageTestStep) createCommandConfigMaps(ctx context.Context) error {
+	logrus.Debugf("Creating multi-stage test commands configmaps for %q", s.name)
+	scripts := s.commandScripts()
+	report, _ := commandSizeReport(scripts)
+	logrus.Debugf("Script sizes for multi-stage test %q:\n%s", s.name, report)
+	for step, script := range scripts {
+		if size := len(script); size > api.MaxStepCommandsSize {
+			return fmt.Errorf("the commands of step %s are %d bytes, exceeding the maximum size of %d bytes; script sizes per step:\n%s", step, size, api.MaxStepCommandsSize, report)
+		}
+	}
+	for step, script := range scripts {
+		if err := s.createCommandConfigMap(ctx, step, script); err != nil {
+			return err
+		}
+		if err := api.SaveArtifact(s.censor, path.Join(s.name, step, commandScriptArtifact), []byte(script)); err != nil {
+			logrus.WithError(err).Warnf("Failed to save the script for step %s", step)
+		}
+	}
+	return nil
+}
+
+// createCommandConfigMap creates the ConfigMap from which the executable
+// script of a step is mounted.
+func (s *multiStageTestStep) createCommandConfigMap(ctx context.Context, step, script string) error {
+	name := commandConfigMapForStep(s.name, step)
+	commands := s.commandConfigMap(step, script)
 	// delete old command configmap if it exists
 	if err := s.client.Delete(ctx, commands); err != nil && !kerrors.IsNotFound(err) {
 		return fmt.Errorf("could not delete command configmap %s: %w", name, err)
@@ -104,7 +299,56 @@ func (s *multiStageTestStep) createCommandConfigMaps(ctx context.Context) error
 	return nil
 }
 
+// commandConfigMap returns the ConfigMap which holds the script of a step.
+func (s *multiStageTestStep) commandConfigMap(step, script string) *coreapi.ConfigMap {
+	yes := true
+	commands := &coreapi.ConfigMap{
+		ObjectMeta: meta.ObjectMeta{
+			Name:      commandConfigMapForStep(s.name, step),
+			Namespace: s.jobSpec.Namespace(),
+			Labels:    map[string]string{MultiStageTestLabel: s.name},
+		},
+		Data:      map[string]string{step: script},
+		Immutable: &yes,
+	}
+	s.addMetadata(commands)
+	return commands
+}
+
+// commandSizeReport lists the size of each step script, largest first, and
+// returns the total size of all scripts.
+func commandSizeReport(data map[string]string) (string, int) {
+	steps := make([]string, 0, len(data))
+	total := 0
+	for step, commands := range data {
+		steps = append(steps, step)
+		total += len(commands)
+	}
+	sort.Slice(steps, func(i, j int) bool {
+		if li, lj := len(data[steps[i]]), len(data[steps[j]]); li != lj {
+			return li > lj
+		}
+		return steps[i] < steps[j]
+	})
+	var report strings.Builder
+	for _, step := range steps {
+		report.WriteString(fmt.Sprintf("  * %s: %d bytes\n", step, len(data[step])))
+	}
+	return report.String(), total
+}
+
 func (s *multiStageTestStep) setupRBAC(ctx context.Context) error {
+	sa, role, bindings := s.rbacObjects()
+	if err := util.CreateRBACs(ctx, sa, role, bindings, s.client, 1*time.Second, 1*time.Minute); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// rbacObjects returns the service account the steps run as, with its role
+// and bindings.
+func (s *multiStageTestStep) rbacObjects() (*coreapi.ServiceAccount, *rbacapi.Role, []rbacapi.RoleBinding) {
 	labels := map[string]string{MultiStageTestLabel: s.name}
 	ns := s.jobSpec.Namespace()
 	m := meta.ObjectMeta{Namespace: ns, Name: s.name, Labels: labels}
@@ -116,18 +360,19 @@ func (s *multiStageTestStep) setupRBAC(ctx context.Context) error {
 			},
 		},
 	}
+	sharedDirRule := rbacapi.PolicyRule{
+		APIGroups:     []string{""},
+		Resources:     []string{"secrets"},
+		ResourceNames: []string{s.name, testresults.SecretName(s.name)},
+		Verbs:         []string{"get", "update"},
+	}
 	role := &rbacapi.Role{
 		ObjectMeta: m,
 		Rules: []rbacapi.PolicyRule{{
 			APIGroups: []string{"rbac.authorization.k8s.io"},
 			Resources: []string{"rolebindings", "roles"},
 			Verbs:     []string{"create", "list"},
-		}, {
-			APIGroups:     []string{""},
-			Resources:     []string{"secrets"},
-			ResourceNames: []string{s.name},
-			Verbs:         []string{"get", "update"},
-		}, {
+		}, sharedDirRule, {
 			APIGroups: []string{"", "image.openshift.io"},
 			Resources: []string{"imagestreams/layers"},
 			Verbs:     []string{"get"},
@@ -150,6 +395,11 @@ func (s *multiStageTestStep) setupRBAC(ctx context.Context) error {
 			Subjects: subj,
 		},
 	}
+	if s.flags&containerOnly != 0 {
+		// steps only need to update the shared directory
+		role.Rules = []rbacapi.PolicyRule{sharedDirRule}
+		bindings = bindings[:1]
+	}
 	if s.vpnConf != nil {
 		bindings = append(bindings, rbacapi.RoleBinding{
 			ObjectMeta: meta.ObjectMeta{Namespace: ns, Name: s.name + "-vpn"},
@@ -160,11 +410,12 @@ func (s *multiStageTestStep) setupRBAC(ctx context.Context) error {
 			Subjects: subj,
 		})
 	}
-	if err := util.CreateRBACs(ctx, sa, role, bindings, s.client, 1*time.Second, 1*time.Minute); err != nil {
-		return err
+	s.addMetadata(sa)
+	s.addMetadata(role)
+	for i := range bindings {
+		s.addMetadata(&bindings[i])
 	}
-
-	return nil
+	return sa, role, bindings
 }
 
 // getNamespaceUID retrieves the base UID configured for the test namespace.
@@ -1,8 +1,21 @@
 package multi_stage
 
 import (
+	"context"
 	"testing"
 
+	"github.com/google/go-cmp/cmp"
+
+	coreapi "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	fakectrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/kubernetes"
+	"github.com/openshift/ci-tools/pkg/steps/loggingclient"
+	"github.com/openshift/ci-tools/pkg/steps/shareddir"
 	"github.com/openshift/ci-tools/pkg/testhelper"
 )
 
@@ -37,3 +50,106 @@ func TestParseNamespaceUID(t *testing.T) {
 		})
 	}
 }
+
+func TestCommandSizeReport(t *testing.T) {
+	data := map[string]string{
+		"small":   "true",
+		"large":   "make test && make e2e",
+		"similar": "exit",
+	}
+	report, total := commandSizeReport(data)
+	expected := `  * large: 21 bytes
+  * similar: 4 bytes
+  * small: 4 bytes
+`
+	testhelper.Diff(t, "report", report, expected)
+	testhelper.Diff(t, "total", total, 29)
+}
+
+func TestSharedDirKey(t *testing.T) {
+	ctx := context.Background()
+	jobSpec := api.JobSpec{}
+	jobSpec.SetNamespace("ns")
+	owner := meta.OwnerReference{APIVersion: "image.openshift.io/v1", Kind: "ImageStream", Name: "pipeline", UID: "uid"}
+	jobSpec.SetOwner(&owner)
+	client := kubernetes.NewPodClient(loggingclient.New(fakectrlruntimeclient.NewClientBuilder().Build()), nil, nil, 0)
+	s := multiStageTestStep{name: "test", jobSpec: &jobSpec, client: client}
+	if err := s.createSharedDirKey(ctx); err != nil {
+		t.Fatalf("failed to create key: %v", err)
+	}
+	key := ctrlruntimeclient.ObjectKey{Namespace: "ns", Name: "test-shared-dir-key"}
+	secret := &coreapi.Secret{}
+	if err := client.Get(ctx, key, secret); err != nil {
+		t.Fatalf("failed to get key secret: %v", err)
+	}
+	if diff := cmp.Diff(s.sharedDirKey, secret.Data[shareddir.KeySecretKey]); diff != "" {
+		t.Errorf("unexpected key: %s", diff)
+	}
+	if diff := cmp.Diff([]meta.OwnerReference{owner}, secret.OwnerReferences); diff != "" {
+		t.Errorf("unexpected owner references: %s", diff)
+	}
+	if secret.Labels[api.ScrubOnExitLabel] != "true" {
+		t.Errorf("expected the key secret to be scrubbed on exit, got labels %v", secret.Labels)
+	}
+	plain := map[string][]byte{"kubeadmin-password": []byte("hunter2")}
+	provenance, err := shareddir.Chain(nil).Append(s.sharedDirKey, "install", plain).Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed := map[string][]byte{"kubeadmin-password": plain["kubeadmin-password"], shareddir.ProvenanceKey: provenance}
+	encrypted, err := shareddir.EncryptData(s.sharedDirKey, signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := s.sharedDirData(&coreapi.Secret{ObjectMeta: meta.ObjectMeta{Name: "test"}, Data: encrypted})
+	if err != nil {
+		t.Fatalf("failed to decrypt shared directory: %v", err)
+	}
+	if diff := cmp.Diff(plain, data); diff != "" {
+		t.Errorf("unexpected shared directory contents: %s", diff)
+	}
+	unsigned, err := shareddir.EncryptData(s.sharedDirKey, plain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.sharedDirData(&coreapi.Secret{ObjectMeta: meta.ObjectMeta{Name: "test"}, Data: unsigned}); err == nil {
+		t.Error("expected contents which were not written by a step to be rejected")
+	}
+	s.deleteSharedDirKey(ctx)
+	if err := client.Get(ctx, key, &coreapi.Secret{}); !kerrors.IsNotFound(err) {
+		t.Errorf("expected the key secret to be deleted, got %v", err)
+	}
+}
+
+func TestAddMetadata(t *testing.T) {
+	ctx := context.Background()
+	jobSpec := api.JobSpec{}
+	jobSpec.SetNamespace("ns")
+	client := kubernetes.NewPodClient(loggingclient.New(fakectrlruntimeclient.NewClientBuilder().Build()), nil, nil, 0)
+	s := multiStageTestStep{
+		name:        "test",
+		jobSpec:     &jobSpec,
+		client:      client,
+		labels:      map[string]string{"team": "installer", api.SkipCensoringLabel: "false"},
+		annotations: map[string]string{"example.com/owner": "installer"},
+	}
+	if err := s.createSharedDirSecret(ctx); err != nil {
+		t.Fatalf("failed to create shared directory: %v", err)
+	}
+	secret := &coreapi.Secret{}
+	if err := client.Get(ctx, ctrlruntimeclient.ObjectKey{Namespace: "ns", Name: "test"}, secret); err != nil {
+		t.Fatalf("failed to get shared directory: %v", err)
+	}
+	testhelper.Diff(t, "labels", secret.Labels, map[string]string{
+		api.SkipCensoringLabel:                    "true",
+		api.ScrubOnExitLabel:                      "true",
+		api.ScrubOwnerLabel(jobSpec.ScrubOwner()): "true",
+		"team": "installer",
+	})
+	testhelper.Diff(t, "annotations", secret.Annotations, map[string]string{"example.com/owner": "installer"})
+	s.labels, s.annotations = nil, nil
+	pod := &coreapi.Pod{}
+	s.addMetadata(pod)
+	testhelper.Diff(t, "labels without metadata", pod.Labels, map[string]string(nil))
+	testhelper.Diff(t, "annotations without metadata", pod.Annotations, map[string]string(nil))
+}
@@ -0,0 +1,225 @@
+package multi_stage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	batchv1 "k8s.io/api/batch/v1"
+	coreapi "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/kubernetes"
+	base_steps "github.com/openshift/ci-tools/pkg/steps"
+	"github.com/openshift/ci-tools/pkg/util"
+)
+
+// jobPollInterval is how often the Job of a step is checked for a new pod
+// once its previous pod has finished.
+var jobPollInterval = 5 * time.Second
+
+// jobFor returns the Job which runs the pod of a step.  The Job has the name
+// of the pod, whose metadata and spec become its template.
+func jobFor(pod *coreapi.Pod, config api.StepJob) *batchv1.Job {
+	template := coreapi.PodTemplateSpec{
+		ObjectMeta: meta.ObjectMeta{
+			Labels:      pod.Labels,
+			Annotations: pod.Annotations,
+		},
+		Spec: *pod.Spec.DeepCopy(),
+	}
+	// the Job controller owns the pods, failed pods are replaced instead
+	template.Spec.RestartPolicy = coreapi.RestartPolicyNever
+	backoffLimit := config.BackoffLimit
+	return &batchv1.Job{
+		ObjectMeta: meta.ObjectMeta{
+			Namespace:       pod.Namespace,
+			Name:            pod.Name,
+			Labels:          pod.Labels,
+			Annotations:     pod.Annotations,
+			OwnerReferences: pod.OwnerReferences,
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit:            &backoffLimit,
+			TTLSecondsAfterFinished: config.TTLSecondsAfterFinished,
+			Template:                template,
+		},
+	}
+}
+
+// nextJobPod returns the oldest pod of the Job which has not been seen yet,
+// so that the attempts are followed in order.
+// If there is none and the Job has failed, the reason is returned as an
+// error.  Neither is returned while the Job controller has yet to create the
+// next pod.
+func nextJobPod(job *batchv1.Job, pods []coreapi.Pod, seen sets.Set[string]) (*coreapi.Pod, error) {
+	var next *coreapi.Pod
+	for i := range pods {
+		pod := &pods[i]
+		if seen.Has(pod.Name) {
+			continue
+		}
+		if next == nil || pod.CreationTimestamp.Before(&next.CreationTimestamp) {
+			next = pod
+		}
+	}
+	if next != nil {
+		return next, nil
+	}
+	for _, condition := range job.Status.Conditions {
+		if condition.Type == batchv1.JobFailed && condition.Status == coreapi.ConditionTrue {
+			return nil, fmt.Errorf("job %s failed: %s: %s", job.Name, condition.Reason, condition.Message)
+		}
+	}
+	return nil, nil
+}
+
+// waitForJobPod waits until the Job has created a pod which has not been
+// seen yet, or has failed.
+func waitForJobPod(ctx context.Context, client ctrlruntimeclient.Reader, job *batchv1.Job, seen sets.Set[string]) (*coreapi.Pod, error) {
+	key := ctrlruntimeclient.ObjectKeyFromObject(job)
+	for {
+		current := &batchv1.Job{}
+		if err := client.Get(ctx, key, current); err != nil {
+			return nil, fmt.Errorf("failed to get job %s: %w", job.Name, err)
+		}
+		pods := &coreapi.PodList{}
+		if err := client.List(ctx, pods, ctrlruntimeclient.InNamespace(job.Namespace), ctrlruntimeclient.MatchingLabels{batchv1.JobNameLabel: job.Name}); err != nil {
+			return nil, fmt.Errorf("failed to list the pods of job %s: %w", job.Name, err)
+		}
+		if pod, err := nextJobPod(current, pods.Items, seen); pod != nil || err != nil {
+			return pod, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(jobPollInterval):
+		}
+	}
+}
+
+// jobFinished determines whether the Job has completed or failed.
+func jobFinished(job *batchv1.Job) bool {
+	for _, condition := range job.Status.Conditions {
+		if (condition.Type == batchv1.JobComplete || condition.Type == batchv1.JobFailed) && condition.Status == coreapi.ConditionTrue {
+			return true
+		}
+	}
+	return false
+}
+
+// createOrRestartJob creates the Job of a step.  Like pods, a Job which
+// already exists is left to run, in which case the pods which have already
+// finished are returned as seen.  A Job which has finished, e.g. when the
+// test is retried, is deleted with its pods and created again.
+func createOrRestartJob(ctx context.Context, client ctrlruntimeclient.Client, job *batchv1.Job) (sets.Set[string], error) {
+	seen := sets.New[string]()
+	key := ctrlruntimeclient.ObjectKeyFromObject(job)
+	for {
+		err := client.Create(ctx, job.DeepCopy())
+		if err == nil {
+			return seen, nil
+		}
+		if !kerrors.IsAlreadyExists(err) {
+			return nil, fmt.Errorf("failed to create job %s: %w", job.Name, err)
+		}
+		existing := &batchv1.Job{}
+		if err := client.Get(ctx, key, existing); err != nil {
+			if kerrors.IsNotFound(err) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to get job %s: %w", job.Name, err)
+		}
+		pods := &coreapi.PodList{}
+		if err := client.List(ctx, pods, ctrlruntimeclient.InNamespace(job.Namespace), ctrlruntimeclient.MatchingLabels{batchv1.JobNameLabel: job.Name}); err != nil {
+			return nil, fmt.Errorf("failed to list the pods of job %s: %w", job.Name, err)
+		}
+		if existing.DeletionTimestamp == nil && !jobFinished(existing) {
+			logrus.Debugf("Job %s already exists, following its pods.", job.Name)
+			for _, pod := range pods.Items {
+				if pod.Status.Phase == coreapi.PodSucceeded || pod.Status.Phase == coreapi.PodFailed {
+					seen.Insert(pod.Name)
+				}
+			}
+			return seen, nil
+		}
+		logrus.Debugf("Job %s has finished, deleting it to run it again.", job.Name)
+		uid := existing.UID
+		if err := client.Delete(ctx, existing, ctrlruntimeclient.PropagationPolicy(meta.DeletePropagationForeground), ctrlruntimeclient.Preconditions(meta.Preconditions{UID: &uid})); err != nil && !kerrors.IsNotFound(err) && !kerrors.IsConflict(err) {
+			return nil, fmt.Errorf("failed to delete job %s: %w", job.Name, err)
+		}
+		// the pods of the Job are deleted with it, unless it was orphaning
+		// them, in which case they would be taken for attempts of the new one
+		for i := range pods.Items {
+			if err := client.Delete(ctx, &pods.Items[i]); err != nil && !kerrors.IsNotFound(err) {
+				return nil, fmt.Errorf("failed to delete pod %s of job %s: %w", pods.Items[i].Name, job.Name, err)
+			}
+		}
+		if err := waitForJobDeletion(ctx, client, key, uid); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// waitForJobDeletion waits until the Job, and with it its pods, is gone.
+func waitForJobDeletion(ctx context.Context, client ctrlruntimeclient.Reader, key ctrlruntimeclient.ObjectKey, uid types.UID) error {
+	for {
+		current := &batchv1.Job{}
+		if err := client.Get(ctx, key, current); err != nil {
+			if kerrors.IsNotFound(err) {
+				return nil
+			}
+			return fmt.Errorf("failed to get job %s: %w", key.Name, err)
+		}
+		if current.UID != uid {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("failed to wait for the deletion of job %s: %w", key.Name, ctx.Err())
+		case <-time.After(jobPollInterval):
+		}
+	}
+}
+
+// runJob executes the pod of a step as a Job, which replaces the pod when it
+// fails or is lost until the backoff limit of the step is reached.  Each pod
+// is followed until it finishes.  The last one is returned under the name of
+// the step, for it to be reported like the pods of other steps.
+func (s *multiStageTestStep) runJob(ctx context.Context, client kubernetes.PodClient, pod *coreapi.Pod, config api.StepJob, notifier *base_steps.TestCaseNotifier, flags util.WaitForPodFlag) (*coreapi.Pod, error) {
+	job := jobFor(pod, config)
+	seen, err := createOrRestartJob(ctx, client, job)
+	if err != nil {
+		return nil, err
+	}
+	var last *coreapi.Pod
+	var lastErr error
+	for {
+		attempt, err := waitForJobPod(ctx, client, job, seen)
+		if err != nil {
+			if lastErr != nil {
+				err = fmt.Errorf("%w (last attempt: %v)", err, lastErr)
+			}
+			return last, err
+		}
+		seen.Insert(attempt.Name)
+		logrus.Infof("Step %s is running in pod %s (attempt %d of %d).", pod.Name, attempt.Name, seen.Len(), config.BackoffLimit+1)
+		finished, err := util.WaitForPodCompletion(ctx, client, attempt.Namespace, attempt.Name, notifier, flags)
+		if finished != nil {
+			last = finished.DeepCopy()
+			last.Name = pod.Name
+		}
+		if err == nil || ctx.Err() != nil {
+			return last, err
+		}
+		logrus.WithError(err).Infof("Pod %s of step %s failed.", attempt.Name, pod.Name)
+		lastErr = err
+	}
+}
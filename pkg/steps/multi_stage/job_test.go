@@ -0,0 +1,278 @@
+package multi_stage
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	coreapi "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	utilpointer "k8s.io/utils/pointer"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	fakectrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	base_steps "github.com/openshift/ci-tools/pkg/steps"
+	"github.com/openshift/ci-tools/pkg/steps/loggingclient"
+	"github.com/openshift/ci-tools/pkg/testhelper"
+	testhelper_kube "github.com/openshift/ci-tools/pkg/testhelper/kubernetes"
+	"github.com/openshift/ci-tools/pkg/util"
+)
+
+func TestJobFor(t *testing.T) {
+	pod := &coreapi.Pod{
+		ObjectMeta: meta.ObjectMeta{
+			Namespace:       "ns",
+			Name:            "e2e-test",
+			Labels:          map[string]string{MultiStageTestLabel: "e2e"},
+			Annotations:     map[string]string{base_steps.AnnotationSaveContainerLogs: "true"},
+			OwnerReferences: []meta.OwnerReference{{Name: "owner"}},
+		},
+		Spec: coreapi.PodSpec{
+			RestartPolicy: coreapi.RestartPolicyAlways,
+			Containers:    []coreapi.Container{{Name: containerName}},
+		},
+	}
+	job := jobFor(pod, api.StepJob{BackoffLimit: 2, TTLSecondsAfterFinished: utilpointer.Int32(60)})
+	testhelper.Diff(t, "job", job, &batchv1.Job{
+		ObjectMeta: meta.ObjectMeta{
+			Namespace:       "ns",
+			Name:            "e2e-test",
+			Labels:          map[string]string{MultiStageTestLabel: "e2e"},
+			Annotations:     map[string]string{base_steps.AnnotationSaveContainerLogs: "true"},
+			OwnerReferences: []meta.OwnerReference{{Name: "owner"}},
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit:            utilpointer.Int32(2),
+			TTLSecondsAfterFinished: utilpointer.Int32(60),
+			Template: coreapi.PodTemplateSpec{
+				ObjectMeta: meta.ObjectMeta{
+					Labels:      map[string]string{MultiStageTestLabel: "e2e"},
+					Annotations: map[string]string{base_steps.AnnotationSaveContainerLogs: "true"},
+				},
+				Spec: coreapi.PodSpec{
+					RestartPolicy: coreapi.RestartPolicyNever,
+					Containers:    []coreapi.Container{{Name: containerName}},
+				},
+			},
+		},
+	})
+}
+
+func jobPod(name string, created time.Time) *coreapi.Pod {
+	return &coreapi.Pod{
+		ObjectMeta: meta.ObjectMeta{
+			Namespace:         "ns",
+			Name:              name,
+			Labels:            map[string]string{batchv1.JobNameLabel: "e2e-test"},
+			CreationTimestamp: meta.NewTime(created),
+		},
+		Spec:   coreapi.PodSpec{Containers: []coreapi.Container{{Name: containerName}}},
+		Status: coreapi.PodStatus{Phase: coreapi.PodPending},
+	}
+}
+
+func failedJob() *batchv1.Job {
+	return &batchv1.Job{
+		ObjectMeta: meta.ObjectMeta{Namespace: "ns", Name: "e2e-test"},
+		Status: batchv1.JobStatus{Conditions: []batchv1.JobCondition{{
+			Type:    batchv1.JobFailed,
+			Status:  coreapi.ConditionTrue,
+			Reason:  "BackoffLimitExceeded",
+			Message: "Job has reached the specified backoff limit",
+		}}},
+	}
+}
+
+func TestNextJobPod(t *testing.T) {
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	pods := []coreapi.Pod{*jobPod("e2e-test-b", start.Add(time.Minute)), *jobPod("e2e-test-a", start)}
+	for _, tc := range []struct {
+		name     string
+		job      *batchv1.Job
+		seen     sets.Set[string]
+		expected string
+		err      error
+	}{{
+		name:     "oldest pod first",
+		job:      &batchv1.Job{},
+		seen:     sets.New[string](),
+		expected: "e2e-test-a",
+	}, {
+		name:     "pods which were seen are skipped",
+		job:      failedJob(),
+		seen:     sets.New[string]("e2e-test-a"),
+		expected: "e2e-test-b",
+	}, {
+		name: "waiting for the next pod",
+		job:  &batchv1.Job{},
+		seen: sets.New[string]("e2e-test-a", "e2e-test-b"),
+	}, {
+		name: "job failed",
+		job:  failedJob(),
+		seen: sets.New[string]("e2e-test-a", "e2e-test-b"),
+		err:  errors.New("job e2e-test failed: BackoffLimitExceeded: Job has reached the specified backoff limit"),
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			pod, err := nextJobPod(tc.job, pods, tc.seen)
+			testhelper.Diff(t, "error", err, tc.err, testhelper.EquateErrorMessage)
+			var name string
+			if pod != nil {
+				name = pod.Name
+			}
+			testhelper.Diff(t, "pod", name, tc.expected)
+		})
+	}
+}
+
+// fakeJobController stands in for the Job controller, creating the pods and
+// setting the status of a Job when it is created.
+type fakeJobController struct {
+	*testhelper_kube.FakePodClient
+	pods   []*coreapi.Pod
+	status batchv1.JobStatus
+}
+
+func (c *fakeJobController) Create(ctx context.Context, o ctrlruntimeclient.Object, opts ...ctrlruntimeclient.CreateOption) error {
+	if err := c.FakePodClient.Create(ctx, o, opts...); err != nil {
+		return err
+	}
+	job, ok := o.(*batchv1.Job)
+	if !ok {
+		return nil
+	}
+	for _, pod := range c.pods {
+		if err := c.FakePodClient.Create(ctx, pod.DeepCopy()); err != nil {
+			return err
+		}
+	}
+	job.Status = c.status
+	return c.FakePodClient.Update(ctx, job)
+}
+
+func TestRunJob(t *testing.T) {
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	finished := func(pod *coreapi.Pod) *coreapi.Pod {
+		pod.Status.Phase = coreapi.PodFailed
+		return pod
+	}
+	for _, tc := range []struct {
+		name     string
+		objects  []ctrlruntimeclient.Object
+		pods     []*coreapi.Pod
+		status   batchv1.JobStatus
+		expected coreapi.PodPhase
+		// the error includes the age of the pod, only its start is compared
+		errPrefix string
+		deleted   []string
+	}{{
+		name:     "retried pod succeeds",
+		pods:     []*coreapi.Pod{jobPod("e2e-test-a", start), jobPod("e2e-test-b", start.Add(time.Minute))},
+		expected: coreapi.PodSucceeded,
+	}, {
+		name:      "job fails",
+		pods:      []*coreapi.Pod{jobPod("e2e-test-a", start)},
+		status:    failedJob().Status,
+		expected:  coreapi.PodFailed,
+		errPrefix: "job e2e-test failed: BackoffLimitExceeded: Job has reached the specified backoff limit (last attempt: could not watch pod: the pod ns/e2e-test-a failed after ",
+	}, {
+		name:     "finished job is restarted",
+		objects:  []ctrlruntimeclient.Object{failedJob(), finished(jobPod("e2e-test-a", start))},
+		pods:     []*coreapi.Pod{jobPod("e2e-test-b", start.Add(time.Hour))},
+		expected: coreapi.PodSucceeded,
+		deleted:  []string{"e2e-test-a"},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			client := &fakeJobController{
+				FakePodClient: &testhelper_kube.FakePodClient{FakePodExecutor: &testhelper_kube.FakePodExecutor{
+					LoggingClient: loggingclient.New(fakectrlruntimeclient.NewClientBuilder().
+						WithIndex(&coreapi.Pod{}, "metadata.name", fakePodNameIndexer).
+						WithObjects(tc.objects...).
+						Build()),
+					Failures: sets.New[string]("e2e-test-a"),
+				}},
+				pods:   tc.pods,
+				status: tc.status,
+			}
+			s := multiStageTestStep{name: "e2e"}
+			pod := &coreapi.Pod{ObjectMeta: meta.ObjectMeta{Namespace: "ns", Name: "e2e-test"}}
+			last, err := s.runJob(ctx, client, pod, api.StepJob{BackoffLimit: 1}, base_steps.NewTestCaseNotifier(util.NopNotifier), util.SkipLogs)
+			if tc.errPrefix == "" && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			} else if tc.errPrefix != "" && (err == nil || !strings.HasPrefix(err.Error(), tc.errPrefix)) {
+				t.Errorf("expected an error starting with %q, got %v", tc.errPrefix, err)
+			}
+			if last == nil {
+				t.Fatal("expected the last pod of the job")
+			}
+			testhelper.Diff(t, "name", last.Name, "e2e-test")
+			testhelper.Diff(t, "phase", last.Status.Phase, tc.expected)
+			if err := client.Get(ctx, ctrlruntimeclient.ObjectKey{Namespace: "ns", Name: "e2e-test"}, &batchv1.Job{}); err != nil {
+				t.Errorf("failed to get the job: %v", err)
+			}
+			for _, name := range tc.deleted {
+				if err := client.Get(ctx, ctrlruntimeclient.ObjectKey{Namespace: "ns", Name: name}, &coreapi.Pod{}); !kerrors.IsNotFound(err) {
+					t.Errorf("expected pod %s to be deleted, got: %v", name, err)
+				}
+			}
+		})
+	}
+}
+
+func TestCreateOrRestartJob(t *testing.T) {
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	finished := jobPod("e2e-test-a", start)
+	finished.Status.Phase = coreapi.PodFailed
+	running := jobPod("e2e-test-b", start.Add(time.Minute))
+	for _, tc := range []struct {
+		name         string
+		objects      []ctrlruntimeclient.Object
+		expectedSeen sets.Set[string]
+		expectedPods []string
+	}{{
+		name:         "new job",
+		expectedSeen: sets.New[string](),
+	}, {
+		name:         "running job is followed",
+		objects:      []ctrlruntimeclient.Object{&batchv1.Job{ObjectMeta: meta.ObjectMeta{Namespace: "ns", Name: "e2e-test"}}, finished.DeepCopy(), running.DeepCopy()},
+		expectedSeen: sets.New[string]("e2e-test-a"),
+		expectedPods: []string{"e2e-test-a", "e2e-test-b"},
+	}, {
+		name:         "finished job is restarted",
+		objects:      []ctrlruntimeclient.Object{failedJob(), finished.DeepCopy()},
+		expectedSeen: sets.New[string](),
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			client := fakectrlruntimeclient.NewClientBuilder().WithObjects(tc.objects...).Build()
+			job := &batchv1.Job{ObjectMeta: meta.ObjectMeta{Namespace: "ns", Name: "e2e-test"}}
+			seen, err := createOrRestartJob(context.Background(), client, job)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			testhelper.Diff(t, "seen pods", seen, tc.expectedSeen)
+			current := &batchv1.Job{}
+			if err := client.Get(context.Background(), ctrlruntimeclient.ObjectKeyFromObject(job), current); err != nil {
+				t.Fatalf("failed to get the job: %v", err)
+			}
+			if jobFinished(current) {
+				t.Error("the finished job was not replaced")
+			}
+			pods := &coreapi.PodList{}
+			if err := client.List(context.Background(), pods); err != nil {
+				t.Fatal(err)
+			}
+			var names []string
+			for _, pod := range pods.Items {
+				names = append(names, pod.Name)
+			}
+			testhelper.Diff(t, "pods", names, tc.expectedPods)
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package multi_stage
+
+import (
+	_ "embed"
+	"fmt"
+	"strconv"
+
+	coreapi "k8s.io/api/core/v1"
+)
+
+// KubectlRetryEnv is the parameter with which steps opt into the retries of
+// the `oc` calls which fail with transient errors of the API server, set to
+// the maximum number of retries.
+const KubectlRetryEnv = "KUBECTL_RETRY"
+
+// kubectlRetryShim wraps the injected `oc` binary to retry its calls.
+//
+//go:embed kubectlretry.sh
+var kubectlRetryShim string
+
+// kubectlRetries returns the number of retries requested by a step through
+// its parameters, zero if it did not opt in.
+func kubectlRetries(params []coreapi.EnvVar) (int, error) {
+	for _, param := range params {
+		if param.Name != KubectlRetryEnv || param.Value == "" {
+			continue
+		}
+		retries, err := strconv.Atoi(param.Value)
+		if err != nil || retries < 0 {
+			return 0, fmt.Errorf("invalid value for %s, must be a non-negative integer: %q", KubectlRetryEnv, param.Value)
+		}
+		return retries, nil
+	}
+	return 0, nil
+}
+
+// addKubectlRetryShim makes the cli injector install the shim in place of the
+// `oc` binary, which is moved to a directory the shim executes it from.
+func addKubectlRetryShim(pod *coreapi.Pod) {
+	for i := range pod.Spec.InitContainers {
+		container := &pod.Spec.InitContainers[i]
+		if container.Name != cliInjectorName {
+			continue
+		}
+		container.Command = []string{"/bin/sh", "-c"}
+		container.Args = []string{
+			`mkdir -p "$1/.real" && cp /usr/bin/oc "$1/.real/oc" && printf '%s' "$2" > "$1/oc" && chmod +x "$1/oc"`,
+			cliInjectorName,
+			CliMountPath,
+			kubectlRetryShim,
+		}
+	}
+}
@@ -0,0 +1,35 @@
+#!/bin/sh
+# Wrapper of the oc binary injected into steps which set $KUBECTL_RETRY.
+#
+# Calls which fail with a transient error of the API server are retried, up to
+# $KUBECTL_RETRY times, waiting $KUBECTL_RETRY_DELAY seconds (5 by default)
+# more before each retry.  Only errors which guarantee that the request was
+# not processed are retried: refused connections, e.g. while the API server
+# restarts, and 429 responses.  Calls which read their standard input from a
+# pipe cannot be replayed and are never retried.
+
+oc="$(dirname "$0")/.real/oc"
+retries="${KUBECTL_RETRY:-0}"
+delay="${KUBECTL_RETRY_DELAY:-5}"
+
+if [ "${retries}" -le 0 ] 2>/dev/null || [ -p /dev/stdin ]; then
+	exec "${oc}" "$@"
+fi
+
+tmp="$(mktemp -d)"
+trap 'rm -rf "${tmp}"' EXIT
+attempt=0
+while true; do
+	# stderr is streamed as usual and kept to detect transient errors
+	{ { "${oc}" "$@"; echo "$?" > "${tmp}/status"; } 2>&1 1>&3 3>&- | tee "${tmp}/stderr" >&2 3>&-; } 3>&1
+	status="$(cat "${tmp}/status" 2>/dev/null || echo 1)"
+	if [ "${status}" -eq 0 ] || [ "${attempt}" -ge "${retries}" ]; then
+		exit "${status}"
+	fi
+	if ! grep -qiE 'connection refused|too many requests|TooManyRequests' "${tmp}/stderr"; then
+		exit "${status}"
+	fi
+	attempt=$((attempt + 1))
+	echo "oc: transient error of the API server, retrying in $((delay * attempt))s (retry ${attempt}/${retries})" >&2
+	sleep "$((delay * attempt))"
+done
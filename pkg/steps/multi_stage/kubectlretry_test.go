@@ -0,0 +1,147 @@
+package multi_stage
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	coreapi "k8s.io/api/core/v1"
+
+	"github.com/openshift/ci-tools/pkg/testhelper"
+)
+
+func TestKubectlRetries(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		params      []coreapi.EnvVar
+		expected    int
+		expectedErr string
+	}{{
+		name: "not requested",
+	}, {
+		name:   "empty value",
+		params: []coreapi.EnvVar{{Name: KubectlRetryEnv}},
+	}, {
+		name:     "retries",
+		params:   []coreapi.EnvVar{{Name: "OTHER", Value: "1"}, {Name: KubectlRetryEnv, Value: "3"}},
+		expected: 3,
+	}, {
+		name:        "invalid value",
+		params:      []coreapi.EnvVar{{Name: KubectlRetryEnv, Value: "yes"}},
+		expectedErr: `invalid value for KUBECTL_RETRY, must be a non-negative integer: "yes"`,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, err := kubectlRetries(tc.params)
+			var actualErr string
+			if err != nil {
+				actualErr = err.Error()
+			}
+			testhelper.Diff(t, "error", actualErr, tc.expectedErr)
+			testhelper.Diff(t, "retries", actual, tc.expected)
+		})
+	}
+}
+
+func TestAddKubectlRetryShim(t *testing.T) {
+	pod := &coreapi.Pod{Spec: coreapi.PodSpec{Containers: []coreapi.Container{{}}}}
+	addCliInjector("stable", pod)
+	addKubectlRetryShim(pod)
+	injector := pod.Spec.InitContainers[0]
+	testhelper.Diff(t, "command", injector.Command, []string{"/bin/sh", "-c"})
+	testhelper.Diff(t, "args", injector.Args[1:], []string{"inject-cli", "/cli", kubectlRetryShim})
+}
+
+func TestKubectlRetryShim(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is not available")
+	}
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, ".real"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	shim := filepath.Join(dir, "oc")
+	if err := os.WriteFile(shim, []byte(kubectlRetryShim), 0755); err != nil {
+		t.Fatal(err)
+	}
+	// the fake oc fails with the error in $FAIL_WITH until it was called
+	// $FAIL_TIMES times
+	fake := `#!/bin/sh
+echo "$*" >> "${CALLS}"
+if [ "$(wc -l < "${CALLS}")" -le "${FAIL_TIMES}" ]; then
+	echo "${FAIL_WITH}" >&2
+	exit 1
+fi
+echo ok
+`
+	if err := os.WriteFile(filepath.Join(dir, ".real", "oc"), []byte(fake), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		name          string
+		retries       string
+		failTimes     string
+		failWith      string
+		expectedCalls int
+		fail          bool
+	}{{
+		name:          "success",
+		retries:       "3",
+		failTimes:     "0",
+		expectedCalls: 1,
+	}, {
+		name:          "refused connection is retried",
+		retries:       "3",
+		failTimes:     "2",
+		failWith:      "The connection to the server api:6443 was refused - did you specify the right host or port? dial tcp: connect: connection refused",
+		expectedCalls: 3,
+	}, {
+		name:          "throttling is retried",
+		retries:       "3",
+		failTimes:     "1",
+		failWith:      "Error from server (TooManyRequests): the server has received too many requests and has asked us to try again later",
+		expectedCalls: 2,
+	}, {
+		name:          "retries are exhausted",
+		retries:       "1",
+		failTimes:     "5",
+		failWith:      "dial tcp: connect: connection refused",
+		expectedCalls: 2,
+		fail:          true,
+	}, {
+		name:          "other errors are not retried",
+		retries:       "3",
+		failTimes:     "1",
+		failWith:      `Error from server (NotFound): pods "e2e" not found`,
+		expectedCalls: 1,
+		fail:          true,
+	}, {
+		name:          "not requested",
+		failTimes:     "1",
+		failWith:      "dial tcp: connect: connection refused",
+		expectedCalls: 1,
+		fail:          true,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			calls := filepath.Join(t.TempDir(), "calls")
+			cmd := exec.Command(shim, "get", "pods")
+			cmd.Env = append(os.Environ(),
+				"CALLS="+calls,
+				"FAIL_TIMES="+tc.failTimes,
+				"FAIL_WITH="+tc.failWith,
+				"KUBECTL_RETRY="+tc.retries,
+				"KUBECTL_RETRY_DELAY=0",
+			)
+			out, err := cmd.CombinedOutput()
+			if failed := err != nil; failed != tc.fail {
+				t.Errorf("expected failure: %t, got %v: %s", tc.fail, err, out)
+			}
+			raw, err := os.ReadFile(calls)
+			if err != nil {
+				t.Fatal(err)
+			}
+			testhelper.Diff(t, "calls", strings.Count(string(raw), "get pods\n"), tc.expectedCalls)
+		})
+	}
+}
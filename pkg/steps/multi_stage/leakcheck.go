@@ -0,0 +1,81 @@
+package multi_stage
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/junit"
+	"github.com/openshift/ci-tools/pkg/steps/leakcheck"
+)
+
+// LeakedResourcesArtifact is the name of the artifact file, relative to the
+// test's artifact directory, which lists the cloud resources which remain
+// after the cluster of the test was deprovisioned, for the pruners of the
+// cloud accounts.
+const LeakedResourcesArtifact = "leaked-resources.json"
+
+// leakedResources is the content of LeakedResourcesArtifact.
+type leakedResources struct {
+	ClusterProfile api.ClusterProfile `json:"cluster_profile"`
+	Job            string             `json:"job"`
+	BuildID        string             `json:"build_id"`
+	Namespace      string             `json:"namespace"`
+	Resources      []string           `json:"resources"`
+}
+
+// withLeakCheck appends the built-in step which checks for leaked cloud
+// resources to the post steps of a test, if its cluster profile is checked.
+// The step runs last, after the cluster was deprovisioned.
+func withLeakCheck(post []api.LiteralTestStep, profile api.ClusterProfile, config *leakcheck.Config) []api.LiteralTestStep {
+	if config == nil {
+		return post
+	}
+	step, ok := config.Step(profile)
+	if !ok {
+		return post
+	}
+	for _, existing := range post {
+		if existing.As == step.As {
+			return post
+		}
+	}
+	// do not modify the steps of the resolved configuration
+	return append(post[:len(post):len(post)], step)
+}
+
+// recordLeakedResources reports the cloud resources found by the built-in
+// leak check step, if the test runs it.  The check is a test case which fails
+// when resources leaked, in which case they are also listed in the artifact.
+func (s *multiStageTestStep) recordLeakedResources() error {
+	if s.options.LeakCheck == nil {
+		return nil
+	}
+	if _, ok := s.options.LeakCheck.Step(s.profile); !ok {
+		return nil
+	}
+	s.subLock.Lock()
+	defer s.subLock.Unlock()
+	resources := leakcheck.Resources(s.stepOutputs[leakcheck.StepName][leakcheck.OutputKey])
+	test := &junit.TestCase{Name: fmt.Sprintf("%s - %s-%s cloud resources are deprovisioned", s.Description(), s.name, leakcheck.StepName)}
+	s.subTests = append(s.subTests, test)
+	if len(resources) == 0 {
+		return nil
+	}
+	test.FailureOutput = &junit.FailureOutput{
+		Output: fmt.Sprintf("%d resources of the cluster remain after it was deprovisioned:\n%s", len(resources), strings.Join(resources, "\n")),
+	}
+	data, err := json.MarshalIndent(leakedResources{
+		ClusterProfile: s.profile,
+		Job:            s.jobSpec.Job,
+		BuildID:        s.jobSpec.BuildID,
+		Namespace:      s.jobSpec.Namespace(),
+		Resources:      resources,
+	}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal leaked resources: %w", err)
+	}
+	return api.SaveArtifact(s.censor, path.Join(s.name, LeakedResourcesArtifact), data)
+}
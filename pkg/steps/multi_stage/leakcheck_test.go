@@ -0,0 +1,103 @@
+package multi_stage
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"k8s.io/test-infra/prow/pod-utils/downwardapi"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/junit"
+	"github.com/openshift/ci-tools/pkg/secrets"
+	"github.com/openshift/ci-tools/pkg/steps/leakcheck"
+	"github.com/openshift/ci-tools/pkg/testhelper"
+)
+
+func TestWithLeakCheck(t *testing.T) {
+	config := &leakcheck.Config{Profiles: map[api.ClusterProfile]leakcheck.Profile{api.ClusterProfileAWS: {}}}
+	post := []api.LiteralTestStep{{As: "deprovision"}}
+	names := func(steps []api.LiteralTestStep) (ret []string) {
+		for _, step := range steps {
+			ret = append(ret, step.As)
+		}
+		return ret
+	}
+	testhelper.Diff(t, "no configuration", names(withLeakCheck(post, api.ClusterProfileAWS, nil)), []string{"deprovision"})
+	testhelper.Diff(t, "profile which is not checked", names(withLeakCheck(post, api.ClusterProfileGCP, config)), []string{"deprovision"})
+	testhelper.Diff(t, "test without a profile", names(withLeakCheck(post, "", config)), []string{"deprovision"})
+	testhelper.Diff(t, "checked profile", names(withLeakCheck(post, api.ClusterProfileAWS, config)), []string{"deprovision", leakcheck.StepName})
+	testhelper.Diff(t, "original steps", names(post), []string{"deprovision"})
+}
+
+func TestRecordLeakedResources(t *testing.T) {
+	config := &leakcheck.Config{Profiles: map[api.ClusterProfile]leakcheck.Profile{api.ClusterProfileAWS: {}}}
+	for _, tc := range []struct {
+		name     string
+		profile  api.ClusterProfile
+		outputs  map[string]map[string]string
+		expected []*junit.TestCase
+		artifact *leakedResources
+	}{{
+		name:    "profile which is not checked",
+		profile: api.ClusterProfileGCP,
+	}, {
+		name:     "no leaked resources",
+		profile:  api.ClusterProfileAWS,
+		expected: []*junit.TestCase{{Name: "Run multi-stage test e2e - e2e-leak-check cloud resources are deprovisioned"}},
+	}, {
+		name:    "leaked resources",
+		profile: api.ClusterProfileAWS,
+		outputs: map[string]map[string]string{leakcheck.StepName: {leakcheck.OutputKey: "arn:aws:ec2:us-east-1:123:volume/vol-1\narn:aws:s3:::bucket"}},
+		expected: []*junit.TestCase{{
+			Name:          "Run multi-stage test e2e - e2e-leak-check cloud resources are deprovisioned",
+			FailureOutput: &junit.FailureOutput{Output: "2 resources of the cluster remain after it was deprovisioned:\narn:aws:ec2:us-east-1:123:volume/vol-1\narn:aws:s3:::bucket"},
+		}},
+		artifact: &leakedResources{
+			ClusterProfile: api.ClusterProfileAWS,
+			Job:            "job",
+			BuildID:        "1",
+			Namespace:      "ns",
+			Resources:      []string{"arn:aws:ec2:us-east-1:123:volume/vol-1", "arn:aws:s3:::bucket"},
+		},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			t.Setenv("ARTIFACTS", dir)
+			censor := secrets.NewDynamicCensor()
+			jobSpec := api.JobSpec{JobSpec: downwardapi.JobSpec{Job: "job", BuildID: "1"}}
+			jobSpec.SetNamespace("ns")
+			s := multiStageTestStep{
+				name:        "e2e",
+				profile:     tc.profile,
+				jobSpec:     &jobSpec,
+				subLock:     &sync.Mutex{},
+				censor:      &censor,
+				stepOutputs: tc.outputs,
+				options:     Options{LeakCheck: config},
+			}
+			if err := s.recordLeakedResources(); err != nil {
+				t.Fatal(err)
+			}
+			testhelper.Diff(t, "test cases", s.subTests, tc.expected)
+			raw, err := os.ReadFile(filepath.Join(dir, "e2e", LeakedResourcesArtifact))
+			if errors.Is(err, fs.ErrNotExist) {
+				if tc.artifact != nil {
+					t.Error("expected the leaked resources to be saved")
+				}
+				return
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			var artifact leakedResources
+			if err := json.Unmarshal(raw, &artifact); err != nil {
+				t.Fatal(err)
+			}
+			testhelper.Diff(t, "artifact", &artifact, tc.artifact)
+		})
+	}
+}
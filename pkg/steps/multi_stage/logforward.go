@@ -0,0 +1,76 @@
+package multi_stage
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	coreapi "k8s.io/api/core/v1"
+
+	"github.com/openshift/ci-tools/pkg/kubernetes"
+)
+
+// logForwardingGracePeriod is how long the output of a step which finished is
+// still forwarded before it is given up.
+const logForwardingGracePeriod = time.Minute
+
+// forwardLogs follows the output of the pod of a step and forwards it to the
+// log store, if one is configured.  It returns the function to call once the
+// pod has finished, which waits for the rest of the output to be forwarded.
+func (s *multiStageTestStep) forwardLogs(ctx context.Context, client kubernetes.PodClient, pod *coreapi.Pod) func() {
+	if s.options.LogForwarder == nil {
+		return func() {}
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	finished, done := make(chan struct{}), make(chan struct{})
+	labels := map[string]string{
+		"build_id":  s.jobSpec.BuildID,
+		"container": containerName,
+		"job":       s.jobSpec.Job,
+		"step":      strings.TrimPrefix(pod.Name, s.name+"-"),
+		"test":      s.name,
+	}
+	go func() {
+		defer close(done)
+		var final bool
+		for {
+			stream, err := client.GetLogs(pod.Namespace, pod.Name, &coreapi.PodLogOptions{Container: containerName, Follow: true, Timestamps: true}).Stream(ctx)
+			if err == nil {
+				err = s.options.LogForwarder.Follow(ctx, labels, stream, s.censor)
+				if closeErr := stream.Close(); err == nil {
+					err = closeErr
+				}
+				if err != nil && ctx.Err() == nil {
+					logrus.WithError(err).Warnf("Failed to forward the output of step %s.", pod.Name)
+				}
+				return
+			}
+			if final {
+				// the container never started, there is no output to forward
+				logrus.WithError(err).Debugf("No output of step %s to forward.", pod.Name)
+				return
+			}
+			logrus.WithError(err).Debugf("Waiting for the output of step %s to forward it.", pod.Name)
+			select {
+			case <-ctx.Done():
+				return
+			case <-finished:
+				// the output of a container which exited can still be read
+				final = true
+			case <-time.After(progressRetryInterval):
+			}
+		}
+	}()
+	return func() {
+		close(finished)
+		select {
+		case <-done:
+		case <-time.After(logForwardingGracePeriod):
+			logrus.Warnf("Timed out forwarding the output of step %s.", pod.Name)
+		}
+		cancel()
+		<-done
+	}
+}
@@ -0,0 +1,99 @@
+package multi_stage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/junit"
+	"github.com/openshift/ci-tools/pkg/results"
+	base_steps "github.com/openshift/ci-tools/pkg/steps"
+)
+
+// manifestDescription identifies an object of the manifests of a step in
+// logs and failures.
+func manifestDescription(obj *unstructured.Unstructured) string {
+	if ns := obj.GetNamespace(); ns != "" {
+		return fmt.Sprintf("%s %s/%s", obj.GetKind(), ns, obj.GetName())
+	}
+	return fmt.Sprintf("%s %s", obj.GetKind(), obj.GetName())
+}
+
+// applyManifests creates the objects of the manifests of a step on the
+// cluster under test, replacing those which exist.  The returned function
+// deletes them once the step completes.  A test case reports the objects
+// which could not be created, in which case those already created are
+// deleted and the step is not executed.
+func (s *multiStageTestStep) applyManifests(ctx context.Context, podName string, step api.LiteralTestStep) (func(), error) {
+	if step.Manifests == "" {
+		return func() {}, nil
+	}
+	objects, err := api.DecodeManifests(step.Manifests)
+	if err != nil {
+		return nil, results.ForReason("applying_manifests").WithError(err).Errorf("invalid manifests for step %s: %v", step.As, err)
+	}
+	client, _, err := s.ephemeralClusterClient(ctx, fmt.Sprintf("step %s has manifests for the ephemeral cluster", step.As))
+	if err != nil {
+		return nil, err
+	}
+	var applied []*unstructured.Unstructured
+	remove := func() {
+		for i := len(applied) - 1; i >= 0; i-- {
+			obj := applied[i]
+			if err := client.Delete(base_steps.CleanupCtx, obj); err != nil && !kerrors.IsNotFound(err) {
+				logrus.WithError(err).Warnf("Failed to delete %s of step %s from the ephemeral cluster.", manifestDescription(obj), podName)
+			}
+		}
+	}
+	var failures []string
+	for _, obj := range objects {
+		labels := obj.GetLabels()
+		if labels == nil {
+			labels = map[string]string{}
+		}
+		labels[MultiStageTestLabel] = s.name
+		obj.SetLabels(labels)
+		if err := applyManifest(ctx, client, obj); err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", manifestDescription(obj), err))
+			continue
+		}
+		applied = append(applied, obj)
+	}
+	testCase := &junit.TestCase{Name: fmt.Sprintf("%s - %s manifests are applied to the ephemeral cluster", s.Description(), podName)}
+	if failures != nil {
+		remove()
+		output := fmt.Sprintf("%d of %d objects could not be applied:\n%s", len(failures), len(objects), strings.Join(failures, "\n"))
+		testCase.FailureOutput = &junit.FailureOutput{Output: output}
+		err = results.ForReason("applying_manifests").ForError(fmt.Errorf("failed to apply the manifests of step %s: %s", podName, output))
+	}
+	s.subLock.Lock()
+	s.subTests = append(s.subTests, testCase)
+	s.subLock.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	logrus.Infof("Applied %d objects to the ephemeral cluster for step %s.", len(applied), podName)
+	return remove, nil
+}
+
+// applyManifest creates an object, replacing it if it exists.
+func applyManifest(ctx context.Context, client ctrlruntimeclient.Client, obj *unstructured.Unstructured) error {
+	err := client.Create(ctx, obj)
+	if !kerrors.IsAlreadyExists(err) {
+		return err
+	}
+	existing := &unstructured.Unstructured{}
+	existing.SetGroupVersionKind(obj.GroupVersionKind())
+	if err := client.Get(ctx, ctrlruntimeclient.ObjectKeyFromObject(obj), existing); err != nil {
+		return err
+	}
+	obj.SetResourceVersion(existing.GetResourceVersion())
+	return client.Update(ctx, obj)
+}
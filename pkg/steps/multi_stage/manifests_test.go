@@ -0,0 +1,139 @@
+package multi_stage
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	coreapi "k8s.io/api/core/v1"
+	rbacapi "k8s.io/api/rbac/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	fakectrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/junit"
+	"github.com/openshift/ci-tools/pkg/kubernetes"
+	"github.com/openshift/ci-tools/pkg/steps/loggingclient"
+	"github.com/openshift/ci-tools/pkg/testhelper"
+	testhelper_kube "github.com/openshift/ci-tools/pkg/testhelper/kubernetes"
+)
+
+const stepManifests = `apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: e2e
+rules:
+- apiGroups: [""]
+  resources: [pods]
+  verbs: [get]
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: e2e
+  namespace: default
+data:
+  key: value
+`
+
+func TestApplyManifests(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		existing []ctrlruntimeclient.Object
+		expected *junit.TestCase
+		err      string
+	}{{
+		name:     "objects are created",
+		expected: &junit.TestCase{Name: "Run multi-stage test test - test-e2e manifests are applied to the ephemeral cluster"},
+	}, {
+		name: "existing objects are replaced",
+		existing: []ctrlruntimeclient.Object{
+			&coreapi.ConfigMap{ObjectMeta: meta.ObjectMeta{Namespace: "default", Name: "e2e"}, Data: map[string]string{"key": "old"}},
+		},
+		expected: &junit.TestCase{Name: "Run multi-stage test test - test-e2e manifests are applied to the ephemeral cluster"},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			remote := loggingclient.New(fakectrlruntimeclient.NewClientBuilder().WithObjects(tc.existing...).Build())
+			s := manifestsTestStep(remote)
+			ctx := context.Background()
+			remove, err := s.applyManifests(ctx, "test-e2e", s.test[0])
+			if err != nil {
+				t.Fatal(err)
+			}
+			testhelper.Diff(t, "test cases", s.subTests, []*junit.TestCase{tc.expected})
+			role := &rbacapi.ClusterRole{}
+			if err := remote.Get(ctx, ctrlruntimeclient.ObjectKey{Name: "e2e"}, role); err != nil {
+				t.Fatalf("the cluster role was not created: %v", err)
+			}
+			testhelper.Diff(t, "labels", role.Labels, map[string]string{MultiStageTestLabel: "test"})
+			cm := &coreapi.ConfigMap{}
+			if err := remote.Get(ctx, ctrlruntimeclient.ObjectKey{Namespace: "default", Name: "e2e"}, cm); err != nil {
+				t.Fatalf("the configmap was not created: %v", err)
+			}
+			testhelper.Diff(t, "data", cm.Data, map[string]string{"key": "value"})
+			remove()
+			for _, obj := range []ctrlruntimeclient.Object{role, cm} {
+				if err := remote.Get(ctx, ctrlruntimeclient.ObjectKeyFromObject(obj), obj); !kerrors.IsNotFound(err) {
+					t.Errorf("expected %s to be deleted, got %v", obj.GetName(), err)
+				}
+			}
+		})
+	}
+}
+
+func TestApplyManifestsFailure(t *testing.T) {
+	remote := loggingclient.New(fakectrlruntimeclient.NewClientBuilder().WithInterceptorFuncs(interceptor.Funcs{
+		Create: func(ctx context.Context, client ctrlruntimeclient.WithWatch, obj ctrlruntimeclient.Object, opts ...ctrlruntimeclient.CreateOption) error {
+			if obj.GetObjectKind().GroupVersionKind().Kind == "Unknown" {
+				return errors.New("no matches for kind Unknown")
+			}
+			return client.Create(ctx, obj, opts...)
+		},
+	}).Build())
+	s := manifestsTestStep(remote)
+	s.test[0].Manifests = stepManifests + `---
+apiVersion: example.com/v1
+kind: Unknown
+metadata:
+  name: e2e
+`
+	ctx := context.Background()
+	_, err := s.applyManifests(ctx, "test-e2e", s.test[0])
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if len(s.subTests) != 1 || s.subTests[0].FailureOutput == nil {
+		t.Fatalf("expected a failed test case, got %v", s.subTests)
+	}
+	testhelper.Diff(t, "failure", s.subTests[0].FailureOutput.Output, "1 of 3 objects could not be applied:\nUnknown e2e: no matches for kind Unknown")
+	if err := remote.Get(ctx, ctrlruntimeclient.ObjectKey{Name: "e2e"}, &rbacapi.ClusterRole{}); !kerrors.IsNotFound(err) {
+		t.Errorf("expected the objects which were created to be deleted, got %v", err)
+	}
+}
+
+func manifestsTestStep(remote loggingclient.LoggingClient) *multiStageTestStep {
+	jobSpec := api.JobSpec{}
+	jobSpec.SetNamespace("ns")
+	return &multiStageTestStep{
+		name:    "test",
+		test:    []api.LiteralTestStep{{As: "e2e", Manifests: stepManifests}},
+		jobSpec: &jobSpec,
+		subLock: &sync.Mutex{},
+		client: &testhelper_kube.FakePodClient{FakePodExecutor: &testhelper_kube.FakePodExecutor{
+			LoggingClient: loggingclient.New(fakectrlruntimeclient.NewClientBuilder().WithObjects(
+				&coreapi.Secret{
+					ObjectMeta: meta.ObjectMeta{Namespace: "ns", Name: "test"},
+					Data:       map[string][]byte{ephemeralKubeconfig: []byte("kubeconfig")},
+				},
+			).Build()),
+		}},
+		ephemeralClient: func([]byte, time.Duration) (kubernetes.PodClient, error) {
+			return &testhelper_kube.FakePodClient{FakePodExecutor: &testhelper_kube.FakePodExecutor{LoggingClient: remote}}, nil
+		},
+	}
+}
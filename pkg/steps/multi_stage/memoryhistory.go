@@ -0,0 +1,115 @@
+package multi_stage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/openhistogram/circonusllhist"
+	"github.com/sirupsen/logrus"
+
+	coreapi "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	base_steps "github.com/openshift/ci-tools/pkg/steps"
+)
+
+// historicalUsageQuantile is the quantile of the historical memory usage
+// reported for containers which were OOM killed.
+const historicalUsageQuantile = 0.95
+
+// MemoryHistory provides the historical memory usage of the containers of
+// step pods.
+type MemoryHistory interface {
+	// MemoryUsage returns the memory usage of the container at the quantile,
+	// or nil if no usage was recorded for it.
+	MemoryUsage(ctx context.Context, pod *coreapi.Pod, container string, quantile float64) (*resource.Quantity, error)
+}
+
+// PodScalerMemoryHistory reads the memory usage of step containers from the
+// data the pod-scaler frontend serves for its UI, which holds a histogram of
+// the usage recorded for each container.
+type PodScalerMemoryHistory struct {
+	URL    string
+	Client *http.Client
+}
+
+func (h *PodScalerMemoryHistory) MemoryUsage(ctx context.Context, pod *coreapi.Pod, container string, quantile float64) (*resource.Quantity, error) {
+	query := url.Values{}
+	for param, label := range map[string]string{
+		"org":     base_steps.LabelMetadataOrg,
+		"repo":    base_steps.LabelMetadataRepo,
+		"branch":  base_steps.LabelMetadataBranch,
+		"variant": base_steps.LabelMetadataVariant,
+		"target":  base_steps.LabelMetadataTarget,
+		"step":    base_steps.LabelMetadataStep,
+	} {
+		if value := pod.Labels[label]; value != "" {
+			query.Set(param, value)
+		}
+	}
+	query.Set("container", container)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(h.URL, "/")+"/api/data/steps?"+query.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	client := h.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch usage: %w", err)
+	}
+	defer resp.Body.Close()
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read usage: %w", err)
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("pod-scaler responded with status code %d: %s", resp.StatusCode, string(raw))
+	}
+	var data map[coreapi.ResourceName]struct {
+		Merged *circonusllhist.Histogram `json:"merged"`
+	}
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return nil, fmt.Errorf("failed to parse usage: %w", err)
+	}
+	merged := data[coreapi.ResourceMemory].Merged
+	if merged == nil || merged.Count() == 0 {
+		return nil, nil
+	}
+	return roundToMebibytes(merged.ValueAtQuantile(quantile)), nil
+}
+
+// roundToMebibytes returns the quantity of bytes rounded up to a whole number
+// of mebibytes, which keeps it readable.
+func roundToMebibytes(bytes float64) *resource.Quantity {
+	const mebibyte = 1 << 20
+	return resource.NewQuantity(int64(math.Ceil(bytes/mebibyte))*mebibyte, resource.BinarySI)
+}
+
+// addMemoryUsage sets the historical memory usage of the OOM killed
+// containers of a pod.  The usage is only informative, so failures to read it
+// are logged and do not affect the result of the step.
+func (s *multiStageTestStep) addMemoryUsage(ctx context.Context, pod *coreapi.Pod, kills []oomKill) {
+	if s.options.MemoryHistory == nil {
+		return
+	}
+	for i := range kills {
+		usage, err := s.options.MemoryHistory.MemoryUsage(ctx, pod, kills[i].container, historicalUsageQuantile)
+		if err != nil {
+			logrus.WithError(err).Debugf("Failed to read the historical memory usage of container %s of pod %s.", kills[i].container, pod.Name)
+			continue
+		}
+		kills[i].usage = usage
+	}
+}
@@ -0,0 +1,91 @@
+package multi_stage
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/openhistogram/circonusllhist"
+
+	coreapi "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	base_steps "github.com/openshift/ci-tools/pkg/steps"
+	"github.com/openshift/ci-tools/pkg/testhelper"
+)
+
+func TestPodScalerMemoryHistory(t *testing.T) {
+	usage := circonusllhist.New()
+	for i := 1; i <= 100; i++ {
+		if err := usage.RecordValue(float64(i) * (1 << 25)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/data/steps" {
+			http.NotFound(w, r)
+			return
+		}
+		query := r.URL.Query()
+		expected := map[string]string{"org": "org", "repo": "repo", "branch": "main", "variant": "", "target": "e2e", "step": "install", "container": "test"}
+		for param, value := range expected {
+			if query.Get(param) != value {
+				http.NotFound(w, r)
+				return
+			}
+		}
+		switch query.Get("container") {
+		case "test":
+			if err := json.NewEncoder(w).Encode(map[string]interface{}{
+				"memory": map[string]interface{}{"cutoff": 0, "merged": usage},
+			}); err != nil {
+				t.Errorf("failed to encode response: %v", err)
+			}
+		}
+	}))
+	defer server.Close()
+	pod := &coreapi.Pod{ObjectMeta: meta.ObjectMeta{Name: "e2e-install", Labels: map[string]string{
+		base_steps.LabelMetadataOrg:    "org",
+		base_steps.LabelMetadataRepo:   "repo",
+		base_steps.LabelMetadataBranch: "main",
+		base_steps.LabelMetadataTarget: "e2e",
+		base_steps.LabelMetadataStep:   "install",
+	}}}
+	history := &PodScalerMemoryHistory{URL: server.URL + "/"}
+	for _, tc := range []struct {
+		name      string
+		container string
+		expected  *resource.Quantity
+	}{{
+		name:      "usage recorded for the container",
+		container: "test",
+		expected:  roundToMebibytes(usage.ValueAtQuantile(historicalUsageQuantile)),
+	}, {
+		name:      "no usage recorded",
+		container: "sidecar",
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, err := history.MemoryUsage(context.Background(), pod, tc.container, historicalUsageQuantile)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			testhelper.Diff(t, "usage", actual, tc.expected)
+		})
+	}
+}
+
+func TestRoundToMebibytes(t *testing.T) {
+	for _, tc := range []struct {
+		bytes    float64
+		expected string
+	}{
+		{bytes: 3 << 30, expected: "3Gi"},
+		{bytes: 3.2 * (1 << 30), expected: "3277Mi"},
+		{bytes: 1, expected: "1Mi"},
+	} {
+		testhelper.Diff(t, tc.expected, roundToMebibytes(tc.bytes).String(), tc.expected)
+	}
+}
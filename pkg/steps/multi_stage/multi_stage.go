@@ -2,6 +2,7 @@ package multi_stage
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"path"
 	"strings"
@@ -12,15 +13,30 @@ import (
 
 	coreapi "k8s.io/api/core/v1"
 	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
 	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/yaml"
 
 	"github.com/openshift/ci-tools/pkg/api"
 	"github.com/openshift/ci-tools/pkg/junit"
 	"github.com/openshift/ci-tools/pkg/kubernetes"
+	"github.com/openshift/ci-tools/pkg/lease"
 	"github.com/openshift/ci-tools/pkg/results"
+	"github.com/openshift/ci-tools/pkg/secrets"
+	base_steps "github.com/openshift/ci-tools/pkg/steps"
+	"github.com/openshift/ci-tools/pkg/steps/compression"
+	"github.com/openshift/ci-tools/pkg/steps/costattribution"
+	"github.com/openshift/ci-tools/pkg/steps/identity"
+	"github.com/openshift/ci-tools/pkg/steps/leakcheck"
 	"github.com/openshift/ci-tools/pkg/steps/loggingclient"
+	"github.com/openshift/ci-tools/pkg/steps/logs"
+	"github.com/openshift/ci-tools/pkg/steps/nodecapabilities"
+	"github.com/openshift/ci-tools/pkg/steps/orgquota"
+	"github.com/openshift/ci-tools/pkg/steps/podpolicy"
+	"github.com/openshift/ci-tools/pkg/steps/profileschema"
+	"github.com/openshift/ci-tools/pkg/steps/riskanalysis"
 	"github.com/openshift/ci-tools/pkg/steps/utils"
+	"github.com/openshift/ci-tools/pkg/util"
 )
 
 // stepFlag controls the behavior of a test throughout its execution.
@@ -42,6 +58,96 @@ type vpnConf struct {
 	namespaceUID int64
 }
 
+// Options holds settings of the ci-operator process which apply to all
+// multi-stage tests.
+type Options struct {
+	// MetricsSink is the URL to which metrics published by steps are forwarded.
+	MetricsSink string
+	// LogForwarder ships the output of the steps to a central log store, if
+	// set.
+	LogForwarder *logs.Forwarder
+	// ArtifactCompression is the algorithm used to compress the artifacts of
+	// steps. When set, a manifest of the artifacts is also written.
+	ArtifactCompression compression.Algorithm
+	// ArtifactCompressionThreshold is the size in bytes above which an
+	// artifact is compressed.
+	ArtifactCompressionThreshold int64
+	// FailureHistory holds the historical results of test cases.  When set,
+	// failures of the test are classified as new or previously failing.
+	FailureHistory *riskanalysis.History
+	// EncryptSharedDir enables the encryption of the contents of the shared
+	// directory with a key generated for the job.  The key is stored in the
+	// namespace of the test while it runs, so the contents are only protected
+	// once it is deleted when the test finishes.
+	EncryptSharedDir bool
+	// PodMutators change each pod before it is created, in order.
+	PodMutators []PodMutator
+	// MemoryHistory provides the historical memory usage reported for
+	// containers which are OOM killed, if set.
+	MemoryHistory MemoryHistory
+	// PodPolicy is evaluated for each pod before it is created, after the
+	// mutators.
+	PodPolicy *podpolicy.Policy
+	// LeaseClient acquires the leases which limit the concurrency of steps
+	// with a `concurrency_group`.
+	LeaseClient *lease.Client
+	// CostAttribution labels are exposed to steps, for them to tag the cloud
+	// resources they create.
+	CostAttribution costattribution.Labels
+	// PreviousJob locates the artifacts of the last successful run of the
+	// job, for steps which compare their results with it.
+	PreviousJob PreviousJobFinder
+	// Debug allows debug containers to be attached to the running pods of
+	// steps with `ci-operator debug attach`.
+	Debug bool
+	// NodeCapabilities are the capabilities of the nodes of the build farm,
+	// against which the requirements of steps are checked.  Requirements are
+	// not checked if nil.
+	NodeCapabilities *nodecapabilities.Config
+	// ClusterShares coordinates the tests which reuse the cluster of another
+	// test of the job.
+	ClusterShares *ClusterShares
+	// SharedLeases are the leases held by the job for all the tests which
+	// need resources of their type.
+	SharedLeases *base_steps.SharedLeases
+	// StepPodLimiter caps the number of step pods which run at the same
+	// time, unlimited if nil.
+	StepPodLimiter *StepPodLimiter
+	// StepProgress follows the output of running steps to report the
+	// progress markers they print.
+	StepProgress bool
+	// DebugService receives the kubeconfig of the cluster of failed tests
+	// when Debug is set, for the author of the job to retrieve it.
+	DebugService *DebugService
+	// BurstLimitCaps are the maximum limits of the steps which burst to
+	// their limits, per resource.
+	BurstLimitCaps coreapi.ResourceList
+	// PodQuotaBackoff is how the creation of step pods is retried while the
+	// namespace has exhausted its quota, util.DefaultPodQuotaBackoff if nil.
+	PodQuotaBackoff *util.PodQuotaBackoff
+	// CheckStepImages verifies that the images of all steps exist before
+	// the first step runs.
+	CheckStepImages bool
+	// StepIdentity issues the tokens which identify steps to external
+	// services.  Steps are not given a token if nil.
+	StepIdentity *identity.Issuer
+	// OrgQuota limits the share of the build farm used by the step pods of
+	// each organization.  Organizations are not limited if nil.
+	OrgQuota *orgquota.Config
+	// LeakCheck adds a step which reports the cloud resources which remain
+	// after the cluster was deprovisioned to the tests of the cluster
+	// profiles it lists.  No test is checked if nil.
+	LeakCheck *leakcheck.Config
+	// ProfileSchemas describe the contents of the secrets of cluster
+	// profiles, which are verified before the test runs.  Profiles are not
+	// verified if nil.
+	ProfileSchemas *profileschema.Registry
+	// TimeoutSnapshotLeadTime is how long before a step is killed for
+	// exceeding its timeout the state of its container is captured.  No
+	// snapshot is captured if zero.
+	TimeoutSnapshotLeadTime time.Duration
+}
+
 const (
 	// A test failure should terminate the current phase.
 	// Set for `pre` and `test`, unset for `post`.
@@ -53,17 +159,46 @@ const (
 	allowSkipOnSuccess
 	// The test was configured to allow best-effort steps.
 	allowBestEffortPostSteps
+	// The test was declared as not interacting with any cluster.
+	containerOnly
+)
+
+// IP families of the clusters created by tests.
+const (
+	ipFamilyIPv4      = "ipv4"
+	ipFamilyIPv6      = "ipv6"
+	ipFamilyDualStack = "dual-stack"
 )
 
+// containerOnlyDefaultTimeout is the default timeout of steps in tests which
+// do not interact with a cluster, where steps are not expected to wait for
+// long-running operations.
+const containerOnlyDefaultTimeout = time.Hour
+
 const (
 	// MultiStageTestLabel is the label we use to mark a pod as part of a multi-stage test
 	MultiStageTestLabel = "ci.openshift.io/multi-stage-test"
+	// ShardLabel is the label we use to mark the pods of a sharded step, with
+	// the name of the step as its value
+	ShardLabel = "ci.openshift.io/multi-stage-shard"
+	// GangLabel is the label we use to mark the pods of steps which must be
+	// scheduled together, with the name of the gang as its value
+	GangLabel = "ci.openshift.io/multi-stage-gang"
+	// GangSchedulingGate holds the pods of a gang back from the scheduler
+	// until all of them were created
+	GangSchedulingGate = "ci.openshift.io/multi-stage-gang"
 	// ClusterProfileMountPath is where we mount the cluster profile in a pod
 	ClusterProfileMountPath = "/var/run/secrets/ci.openshift.io/cluster-profile"
 	// SecretMountPath is where we mount the shared dir secret
 	SecretMountPath = "/var/run/secrets/ci.openshift.io/multi-stage"
 	// SecretMountEnv is the env we use to expose the shared dir
 	SecretMountEnv = "SHARED_DIR"
+	// SharedDirKeyMountPath is where we mount the key of the shared dir
+	SharedDirKeyMountPath = "/var/run/secrets/ci.openshift.io/shared-dir-key"
+	// decryptedSharedDirPath is where the entrypoint wrapper copies the
+	// contents of the shared dir, mounted as a memory-backed volume when they
+	// are encrypted
+	decryptedSharedDirPath = "/tmp/secret"
 	// ClusterProfileMountEnv is the env we use to expose the cluster profile dir
 	ClusterProfileMountEnv = "CLUSTER_PROFILE_DIR"
 	// CliMountPath is where we mount the cli in a pod
@@ -75,6 +210,26 @@ const (
 	homeVolumeName         = "home"
 	// vpnConfPath is the path of the configuration file in the cluster profile.
 	vpnConfPath = "vpn.yaml"
+	// ipFamilyPath is the path of the file in the cluster profile which holds
+	// the IP family of the clusters created with it.
+	ipFamilyPath = "ip-family"
+	// IPFamilyEnv is the env we use to expose the IP family of the cluster
+	IPFamilyEnv = "IP_FAMILY"
+	// ResolvedTestArtifact is the name of the artifact file, relative to the
+	// test's artifact directory, which holds the fully-resolved configuration.
+	ResolvedTestArtifact = "resolved-test.yaml"
+	// commandScriptArtifact is the name of the artifact file, relative to the
+	// step's artifact directory, which holds the script executed by the step.
+	commandScriptArtifact = "commands.sh"
+	// CredentialsArtifact is the name of the artifact file, relative to the
+	// test's artifact directory, which lists the credentials mounted by steps.
+	CredentialsArtifact = "credentials.json"
+	// PodArtifact is the name of the artifact file, relative to the step's
+	// artifact directory, which holds the final state of the step pod.
+	PodArtifact = "pod.yaml"
+	// EventsArtifact is the name of the artifact file, relative to the step's
+	// artifact directory, which holds the events recorded for the step pod.
+	EventsArtifact = "events.json"
 )
 
 var envForProfile = []string{
@@ -97,11 +252,78 @@ type multiStageTestStep struct {
 	pre, test, post []api.LiteralTestStep
 	subLock         *sync.Mutex
 	subTests        []*junit.TestCase
+	subSuites       []*junit.TestSuite
+	waivers         []waiverUse
+	budget          []budgetUse
+	imagePulls      []imagePull
+	quotaWaits      []quotaWait
+	orgQuotaWaits   []orgQuotaWait
+	stepOutputs     map[string]map[string]string
+	contracts       map[string]*stepContract
 	subSteps        []api.CIOperatorStepDetailInfo
 	flags           stepFlag
 	leases          []api.StepLease
 	clusterClaim    *api.ClusterClaim
 	vpnConf         *vpnConf
+	tunnelConf      *tunnelConf
+	// podOverrides are merged into the step pods, when set by the cluster
+	// profile
+	podOverrides *podOverrides
+	// ephemeralClient creates clients for the cluster under test, used by
+	// steps which run on it
+	ephemeralClient ephemeralClientFunc
+	// ipFamily is the IP family of the cluster under test, when it is created
+	// with a cluster profile
+	ipFamily string
+	// clusterNaming determines the name of the cluster created with the
+	// cluster profile
+	clusterNaming *clusterNaming
+	// credentialCollections are the names of the secrets of the collections
+	// of credentials used by the steps, resolved when the credentials are
+	// created
+	credentialCollections map[string][]string
+	// placement holds the default region and the zones of the cluster
+	// created with the cluster profile, when set by the profile
+	placement *placement
+	// reuseClusterFrom is the test whose cluster this test runs against
+	reuseClusterFrom string
+	// profileCopy is the name of the copy of the cluster profile secret
+	// owned by the test, which is mounted in the steps
+	profileCopy string
+	// featureSet is the feature set the cluster under test is installed with
+	featureSet api.FeatureSet
+	// stepReleases are the releases used by steps in place of `latest`
+	stepReleases api.StepReleases
+	// clusterInfo holds the details of the cluster under test, resolved
+	// after the `pre` phase
+	clusterInfo []coreapi.EnvVar
+	// sharedDirKey encrypts the contents of the shared directory, if enabled
+	sharedDirKey []byte
+	// previousJobURL is the URL of the artifact directory of the previous
+	// successful run of the job, if any
+	previousJobURL string
+	// previousJobFiles are the files fetched from the previous run for each
+	// step, as items of the secret created for it
+	previousJobFiles map[string][]coreapi.KeyToPath
+	// failedSteps are the names of the steps which failed, in the order in
+	// which they failed
+	failedSteps []string
+	// timeline holds the transitions of the steps of the test
+	timeline []timelineEntry
+	// rollbacks are executed when the steps which reference them fail
+	rollbacks []api.LiteralTestStep
+	// rollbackPods are the pods of the rollbacks of the phase being executed,
+	// keyed by the name of the rollback step
+	rollbackPods map[string]coreapi.Pod
+	// labels and annotations are added to every object created for the test
+	labels, annotations map[string]string
+	// rendering is set when the objects of the test are only rendered, in
+	// which case values only known at runtime are replaced by placeholders
+	rendering bool
+	// resolved is the literal configuration the step was created from
+	resolved *api.MultiStageTestConfigurationLiteral
+	censor   *secrets.DynamicCensor
+	options  Options
 }
 
 func MultiStageTestStep(
@@ -113,8 +335,10 @@ func MultiStageTestStep(
 	leases []api.StepLease,
 	nodeName string,
 	targetAdditionalSuffix string,
+	censor *secrets.DynamicCensor,
+	options Options,
 ) api.Step {
-	return newMultiStageTestStep(testConfig, config, params, client, jobSpec, leases, nodeName, targetAdditionalSuffix)
+	return newMultiStageTestStep(testConfig, config, params, client, jobSpec, leases, nodeName, targetAdditionalSuffix, censor, options)
 }
 
 func newMultiStageTestStep(
@@ -126,6 +350,8 @@ func newMultiStageTestStep(
 	leases []api.StepLease,
 	nodeName string,
 	targetAdditionalSuffix string,
+	censor *secrets.DynamicCensor,
+	options Options,
 ) *multiStageTestStep {
 	ms := testConfig.MultiStageTestConfigurationLiteral
 	var flags stepFlag
@@ -135,6 +361,9 @@ func newMultiStageTestStep(
 	if p := ms.AllowBestEffortPostSteps; p != nil && *p {
 		flags |= allowBestEffortPostSteps
 	}
+	if p := ms.ContainerOnly; p != nil && *p {
+		flags |= containerOnly
+	}
 	return &multiStageTestStep{
 		name:             testConfig.As,
 		additionalSuffix: targetAdditionalSuffix,
@@ -148,14 +377,32 @@ func newMultiStageTestStep(
 		observers:        ms.Observers,
 		pre:              ms.Pre,
 		test:             ms.Test,
-		post:             ms.Post,
+		post:             withLeakCheck(ms.Post, ms.ClusterProfile, options.LeakCheck),
+		rollbacks:        ms.Rollbacks,
+		labels:           testConfig.Labels,
+		annotations:      testConfig.Annotations,
 		flags:            flags,
 		leases:           leases,
 		clusterClaim:     testConfig.ClusterClaim,
+		stepReleases:     ms.StepReleases,
+		featureSet:       ms.FeatureSet,
+		reuseClusterFrom: ms.ReuseClusterFrom,
 		subLock:          &sync.Mutex{},
+		resolved:         ms,
+		censor:           censor,
+		options:          options,
 	}
 }
 
+// mountedProfileSecretName returns the name of the cluster profile secret
+// mounted in the steps, the copy owned by the test once it has been made.
+func (s *multiStageTestStep) mountedProfileSecretName() string {
+	if s.profileCopy != "" {
+		return s.profileCopy
+	}
+	return s.profileSecretName()
+}
+
 func (s *multiStageTestStep) profileSecretName() string {
 	name := s.name
 	if s.additionalSuffix != "" {
@@ -168,7 +415,12 @@ func (s *multiStageTestStep) Inputs() (api.InputDefinition, error) {
 	return nil, nil
 }
 
-func (*multiStageTestStep) Validate() error { return nil }
+func (s *multiStageTestStep) Validate() error {
+	if s.options.LeaseClient == nil && s.needsLeaseClient() {
+		return base_steps.NoLeaseClientErr
+	}
+	return s.validateNodeCapabilities()
+}
 
 func (s *multiStageTestStep) Run(ctx context.Context) error {
 	return results.ForReason("executing_multi_stage_test").ForError(s.run(ctx))
@@ -176,6 +428,15 @@ func (s *multiStageTestStep) Run(ctx context.Context) error {
 
 func (s *multiStageTestStep) run(ctx context.Context) error {
 	logrus.Infof("Running multi-stage test %s", s.name)
+	if err := s.saveResolvedConfig(); err != nil {
+		logrus.WithError(err).Warnf("Failed to save the resolved configuration for test %s", s.name)
+	}
+	if err := s.saveGraph(); err != nil {
+		logrus.WithError(err).Warnf("Failed to save the step graph for test %s", s.name)
+	}
+	if err := s.saveCredentialsAudit(); err != nil {
+		logrus.WithError(err).Warnf("Failed to save the credentials audit for test %s", s.name)
+	}
 	if s.profile != "" {
 		if err := s.getProfileData(ctx); err != nil {
 			return err
@@ -185,18 +446,57 @@ func (s *multiStageTestStep) run(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
+	if s.options.CheckStepImages {
+		if err := s.checkStepImages(ctx); err != nil {
+			return err
+		}
+	}
+	// a previous execution which did not complete may have left its
+	// finalizer on objects which are about to be replaced
+	s.releaseObjects(ctx)
+	defer s.releaseObjects(base_steps.CleanupCtx)
 	if err := s.createSharedDirSecret(ctx); err != nil {
 		return fmt.Errorf("failed to create secret: %w", err)
 	}
+	if s.options.EncryptSharedDir {
+		if err := s.createSharedDirKey(ctx); err != nil {
+			return fmt.Errorf("failed to create shared directory key: %w", err)
+		}
+		defer s.deleteSharedDirKey(base_steps.CleanupCtx)
+	}
+	if s.reuseClusterFrom != "" {
+		release, err := s.attachToCluster(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to reuse the cluster of test %s: %w", s.reuseClusterFrom, err)
+		}
+		defer release()
+	}
+	if err := s.createResultsSecret(ctx); err != nil {
+		return fmt.Errorf("failed to create results secret: %w", err)
+	}
 	if err := s.createCredentials(ctx); err != nil {
 		return fmt.Errorf("failed to create credentials: %w", err)
 	}
+	if credentials := s.refreshedCredentials(); len(credentials) != 0 {
+		refreshCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		go s.watchCredentials(refreshCtx, credentials, credentialRefreshInterval)
+	}
 	if err := s.createCommandConfigMaps(ctx); err != nil {
 		return fmt.Errorf("failed to create command configmap: %w", err)
 	}
+	if err := s.createToolkit(ctx); err != nil {
+		return fmt.Errorf("failed to create the step toolkit: %w", err)
+	}
+	if err := s.resolvePreviousJob(ctx); err != nil {
+		return fmt.Errorf("failed to fetch the artifacts of the previous job: %w", err)
+	}
 	if err := s.setupRBAC(ctx); err != nil {
 		return fmt.Errorf("failed to create RBAC objects: %w", err)
 	}
+	if err := s.guardObjects(ctx); err != nil {
+		return err
+	}
 	if s.vpnConf != nil {
 		if s.vpnConf.namespaceUID, err = getNamespaceUID(ctx, s.jobSpec.Namespace(), s.client); err != nil {
 			return fmt.Errorf("failed to determine namespace UID range: %w", err)
@@ -218,20 +518,115 @@ func (s *multiStageTestStep) run(ctx context.Context) error {
 	observerDone := make(chan struct{})
 	go s.runObservers(observerContext, ctx, observers, observerDone)
 	s.flags |= shortCircuit
+	testPhaseResult := testPhaseSkipped
 	if err := s.runSteps(ctx, "pre", s.pre, env, secretVolumes, secretVolumeMounts); err != nil {
 		errs = append(errs, fmt.Errorf("%q pre steps failed: %w", s.name, err))
-	} else if err := s.runSteps(ctx, "test", s.test, env, secretVolumes, secretVolumeMounts); err != nil {
-		errs = append(errs, fmt.Errorf("%q test steps failed: %w", s.name, err))
+	} else if err := s.runClusterHealthGate(ctx); err != nil {
+		s.flags |= hasPrevErrs
+		errs = append(errs, fmt.Errorf("%q cluster health gate failed: %w", s.name, err))
+	} else {
+		testPhaseResult = testPhaseSucceeded
+		s.resolveClusterInfo(ctx)
+		s.shareCluster(ctx)
+		stopMonitor := s.startDisruptionMonitor(ctx)
+		if err := s.runSteps(ctx, "test", s.test, env, secretVolumes, secretVolumeMounts); err != nil {
+			testPhaseResult = testPhaseFailed
+			errs = append(errs, fmt.Errorf("%q test steps failed: %w", s.name, err))
+		}
+		if err := stopMonitor(); err != nil {
+			errs = append(errs, fmt.Errorf("%q disruption monitor: %w", s.name, err))
+		}
+	}
+	// failures to install the cluster are debugged on it as well
+	if len(errs) != 0 {
+		s.uploadKubeconfig(ctx)
 	}
 	cancel() // signal to observers that we're tearing down
+	s.releaseSharedCluster(ctx)
 	s.flags &= ^shortCircuit
-	if err := s.runSteps(context.Background(), "post", s.post, env, secretVolumes, secretVolumeMounts); err != nil {
+	post, gather := splitGatherSteps(s.post)
+	postEnv := newEnvBuilder().merge(env).add(envSourceStepResults, s.stepResultsEnv(testPhaseResult)...)
+	if err := s.runSteps(context.Background(), "post", post, postEnv, secretVolumes, secretVolumeMounts); err != nil {
 		errs = append(errs, fmt.Errorf("%q post steps failed: %w", s.name, err))
 	}
 	<-observerDone // wait for the observers to finish so we get their jUnit
+	if len(gather) != 0 {
+		if err := s.runGatherSteps(gather, postEnv, secretVolumes, secretVolumeMounts); err != nil {
+			errs = append(errs, fmt.Errorf("%q gather steps failed: %w", s.name, err))
+		}
+	}
+	if err := s.collectResults(base_steps.CleanupCtx); err != nil {
+		logrus.WithError(err).Warnf("Failed to collect the jUnit results of test %s", s.name)
+	}
+	if err := s.recordLeakedResources(); err != nil {
+		logrus.WithError(err).Warnf("Failed to record the leaked resources of test %s", s.name)
+	}
+	if err := s.saveStepOutputs(); err != nil {
+		logrus.WithError(err).Warnf("Failed to save the step outputs of test %s", s.name)
+	}
+	if err := s.saveQuarantineReport(); err != nil {
+		logrus.WithError(err).Warnf("Failed to save the quarantine report of test %s", s.name)
+	}
+	if err := s.saveBudgetReport(); err != nil {
+		logrus.WithError(err).Warnf("Failed to save the budget report of test %s", s.name)
+	}
+	if err := s.saveContract(); err != nil {
+		logrus.WithError(err).Warnf("Failed to save the step contracts of test %s", s.name)
+	}
+	if err := s.saveTimeline(); err != nil {
+		logrus.WithError(err).Warnf("Failed to save the timeline of test %s", s.name)
+	}
+	if err := s.forwardPodMetrics(base_steps.CleanupCtx); err != nil {
+		logrus.WithError(err).Warnf("Failed to forward the pod metrics of test %s", s.name)
+	}
+	if s.options.FailureHistory != nil {
+		if err := s.analyzeRisk(); err != nil {
+			logrus.WithError(err).Warnf("Failed to analyze the failures of test %s", s.name)
+		}
+	}
 	return utilerrors.NewAggregate(errs)
 }
 
+// saveResolvedConfig writes the literal configuration of the test, as it was
+// resolved from the registry and with all parameters and overrides applied,
+// to the artifact directory of the test.
+func (s *multiStageTestStep) saveResolvedConfig() error {
+	data, err := yaml.Marshal(s.resolved)
+	if err != nil {
+		return fmt.Errorf("failed to marshal resolved configuration: %w", err)
+	}
+	return api.SaveArtifact(s.censor, path.Join(s.name, ResolvedTestArtifact), data)
+}
+
+// credentialUse records a credential mounted by a step.
+type credentialUse struct {
+	Step      string            `json:"step"`
+	Namespace string            `json:"namespace"`
+	Name      string            `json:"name,omitempty"`
+	Selector  map[string]string `json:"selector,omitempty"`
+	MountPath string            `json:"mount_path"`
+}
+
+// saveCredentialsAudit writes the credentials mounted by each step to the
+// artifact directory of the test, to support reviews of their usage.
+func (s *multiStageTestStep) saveCredentialsAudit() error {
+	uses := []credentialUse{}
+	for _, step := range s.allSteps() {
+		for _, credential := range step.Credentials {
+			use := credentialUse{Step: step.As, Namespace: credential.Namespace, Name: credential.Name, MountPath: credential.MountPath}
+			if credential.Selector != nil {
+				use.Selector = credential.Selector.MatchLabels
+			}
+			uses = append(uses, use)
+		}
+	}
+	data, err := json.MarshalIndent(uses, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal credentials audit: %w", err)
+	}
+	return api.SaveArtifact(s.censor, path.Join(s.name, CredentialsArtifact), data)
+}
+
 func (s *multiStageTestStep) Name() string { return s.name }
 func (s *multiStageTestStep) Description() string {
 	return fmt.Sprintf("Run multi-stage test %s", s.name)
@@ -244,13 +639,32 @@ func (s *multiStageTestStep) SubSteps() []api.CIOperatorStepDetailInfo {
 	return s.subSteps
 }
 
-func (s *multiStageTestStep) Requires() (ret []api.StepLink) {
-	var claimRelease *api.ClaimRelease
+// claimReleaseFor returns the release which replaces `latest` for a step,
+// either the one the step is configured to use or that of the cluster claim.
+func (s *multiStageTestStep) claimReleaseFor(step string) *api.ClaimRelease {
+	if release, ok := s.stepReleases[step]; ok {
+		return &api.ClaimRelease{ReleaseName: release, OverrideName: api.LatestReleaseName}
+	}
 	if s.clusterClaim != nil {
-		claimRelease = s.clusterClaim.ClaimRelease(s.name)
+		return s.clusterClaim.ClaimRelease(s.name)
 	}
-	var needsReleaseImage, needsReleasePayload bool
-	for _, step := range append(append(s.pre, s.test...), s.post...) {
+	return nil
+}
+
+func (s *multiStageTestStep) Requires() (ret []api.StepLink) {
+	var needsReleasePayload bool
+	releaseImages := sets.New[string]()
+	for _, step := range s.allSteps() {
+		claimRelease := s.claimReleaseFor(step.As)
+		if release, ok := s.stepReleases[step.As]; ok && s.profile != "" {
+			if link, ok := utils.LinkForEnv(utils.ReleaseImageEnv(release)); ok {
+				ret = append(ret, link)
+			}
+		}
+		if isUpgradeStep(step) {
+			ret = append(ret, api.ReleasePayloadImageLink(step.Upgrade.Release))
+			continue
+		}
 		if link, ok := step.FromImageTag(); ok {
 			ret = append(ret, api.InternalImageLink(link))
 		} else {
@@ -261,7 +675,7 @@ func (s *multiStageTestStep) Requires() (ret []api.StepLink) {
 			} else {
 				// if the user did not specify an explicit namespace for this image,
 				// it's likely coming from an imported release we need to wait for
-				needsReleaseImage = true
+				releaseImages.Insert(releaseImagesName(claimRelease))
 			}
 		}
 
@@ -284,6 +698,15 @@ func (s *multiStageTestStep) Requires() (ret []api.StepLink) {
 			imageStream, name, _ := s.config.DependencyParts(dependency, claimRelease)
 			ret = append(ret, api.LinkForImage(imageStream, name))
 		}
+
+		for _, sidecar := range step.Sidecars {
+			imageStream, name, explicit := s.config.DependencyParts(api.StepDependency{Name: sidecar.From}, claimRelease)
+			if explicit {
+				ret = append(ret, api.LinkForImage(imageStream, name))
+			} else {
+				releaseImages.Insert(releaseImagesName(claimRelease))
+			}
+		}
 	}
 	if s.profile != "" {
 		needsReleasePayload = true
@@ -293,34 +716,70 @@ func (s *multiStageTestStep) Requires() (ret []api.StepLink) {
 			}
 		}
 	}
-	if needsReleaseImage && !needsReleasePayload {
-		releaseName := api.LatestReleaseName
-		if claimRelease != nil && claimRelease.OverrideName == api.LatestReleaseName {
-			releaseName = claimRelease.ReleaseName
+	if !needsReleasePayload {
+		for _, name := range sets.List(releaseImages) {
+			ret = append(ret, api.ReleaseImagesLink(name))
 		}
-		ret = append(ret, api.ReleaseImagesLink(releaseName))
 	}
 	return
 }
 
+// releaseImagesName returns the name of the release whose images are used by
+// a step which does not reference a release explicitly.
+func releaseImagesName(claimRelease *api.ClaimRelease) string {
+	if claimRelease != nil && claimRelease.OverrideName == api.LatestReleaseName {
+		return claimRelease.ReleaseName
+	}
+	return api.LatestReleaseName
+}
+
 func (s *multiStageTestStep) Creates() []api.StepLink { return nil }
 func (s *multiStageTestStep) Provides() api.ParameterMap {
 	return nil
 }
-func (s *multiStageTestStep) SubTests() []*junit.TestCase { return s.subTests }
+func (s *multiStageTestStep) SubTests() []*junit.TestCase   { return s.subTests }
+func (s *multiStageTestStep) SubSuites() []*junit.TestSuite { return s.subSuites }
 
-// getProfileData fetches the content of the cluster profile secret.
+// getProfileData fetches the content of the cluster profile secret and copies
+// it for the test.
 // This is done both to guarantee it has been correctly imported into the test
 // namespace and to gather information used when generating the test pods.
+// The secret is verified against the schema of the profile before it is used.
 func (s *multiStageTestStep) getProfileData(ctx context.Context) error {
 	var secret coreapi.Secret
 	name := s.profileSecretName()
 	if err := s.client.Get(ctx, ctrlruntimeclient.ObjectKey{Namespace: s.jobSpec.Namespace(), Name: name}, &secret); err != nil {
 		return fmt.Errorf("could not get cluster profile secret %q: %w", name, err)
 	}
+	if s.options.ProfileSchemas != nil {
+		if problems := s.options.ProfileSchemas.Check(s.profile, secret.Data); len(problems) != 0 {
+			return results.ForReason("invalid_cluster_profile").ForError(fmt.Errorf("cluster profile secret %q does not match the schema of profile %s: %s", name, s.profile, strings.Join(problems, "; ")))
+		}
+	}
+	profile, err := s.copyProfile(ctx, &secret)
+	if err != nil {
+		return err
+	}
+	s.profileCopy = profile.Name
+	secret = *profile
 	if err := s.readVPNData(&secret); err != nil {
 		return fmt.Errorf("failed to read VPN configuration from cluster profile: %w", err)
 	}
+	if err := s.readTunnelData(&secret); err != nil {
+		return fmt.Errorf("failed to read bastion configuration from cluster profile: %w", err)
+	}
+	if err := s.readIPFamily(&secret); err != nil {
+		return fmt.Errorf("failed to read IP family from cluster profile: %w", err)
+	}
+	if err := s.readPodOverrides(&secret); err != nil {
+		return fmt.Errorf("failed to read pod overrides from cluster profile: %w", err)
+	}
+	if err := s.readClusterNaming(&secret); err != nil {
+		return fmt.Errorf("failed to read cluster naming from cluster profile: %w", err)
+	}
+	if err := s.readPlacement(&secret); err != nil {
+		return fmt.Errorf("failed to read placement from cluster profile: %w", err)
+	}
 	return nil
 }
 
@@ -354,28 +813,90 @@ func (s *multiStageTestStep) readVPNData(secret *coreapi.Secret) error {
 	return nil
 }
 
-func (s *multiStageTestStep) environment() ([]coreapi.EnvVar, error) {
-	var ret []coreapi.EnvVar
+// readIPFamily determines the IP family of the cluster from the profile.
+// Clusters are assumed to be single-stack IPv4 unless stated otherwise.
+func (s *multiStageTestStep) readIPFamily(secret *coreapi.Secret) error {
+	s.ipFamily = ipFamilyIPv4
+	bytes, ok := secret.Data[ipFamilyPath]
+	if !ok {
+		return nil
+	}
+	switch family := strings.TrimSpace(string(bytes)); family {
+	case ipFamilyIPv4, ipFamilyIPv6, ipFamilyDualStack:
+		s.ipFamily = family
+	default:
+		return fmt.Errorf("invalid IP family %q, must be one of: %s, %s, %s", family, ipFamilyIPv4, ipFamilyIPv6, ipFamilyDualStack)
+	}
+	return nil
+}
+
+func (s *multiStageTestStep) environment() (*envBuilder, error) {
+	ret := newEnvBuilder()
 	for _, l := range s.leases {
 		val, err := s.params.Get(l.Env)
 		if err != nil {
 			return nil, err
 		}
-		ret = append(ret, coreapi.EnvVar{Name: l.Env, Value: val})
+		ret.add(envSourceLease, coreapi.EnvVar{Name: l.Env, Value: val})
 	}
 
+	for _, name := range releaseNames(s.config) {
+		e := utils.ReleaseProvenanceEnv(name)
+		if !s.params.Has(e) {
+			continue
+		}
+		val, err := s.params.Get(e)
+		if err != nil {
+			return nil, err
+		}
+		if val != "" {
+			ret.add(envSourceRelease, coreapi.EnvVar{Name: e, Value: val})
+		}
+	}
+	if s.featureSet != "" {
+		ret.add(envSourceFeatureSet, coreapi.EnvVar{Name: api.FeatureSetEnv, Value: string(s.featureSet)})
+	}
+	if tags := s.options.CostAttribution.Tags(); tags != "" {
+		ret.add(envSourceCostAttribution, coreapi.EnvVar{Name: costattribution.TagsEnv, Value: tags})
+	}
 	if s.profile != "" {
 		for _, e := range envForProfile {
 			val, err := s.params.Get(e)
 			if err != nil {
 				return nil, err
 			}
-			ret = append(ret, coreapi.EnvVar{Name: e, Value: val})
+			ret.add(envSourceClusterProfile, coreapi.EnvVar{Name: e, Value: val})
+		}
+		clusterEnv, err := s.clusterEnv()
+		if err != nil {
+			return nil, err
+		}
+		ret.add(envSourceClusterProfile, clusterEnv...)
+		placementEnv, err := s.placementEnv()
+		if err != nil {
+			return nil, err
 		}
+		ret.add(envSourceClusterProfile, placementEnv...)
 	}
 	return ret, nil
 }
 
+// releaseNames returns the names of the releases configured for the job,
+// sorted.
+func releaseNames(config *api.ReleaseBuildConfiguration) []string {
+	if config == nil {
+		return nil
+	}
+	names := sets.New[string]()
+	for name := range config.Releases {
+		names.Insert(name)
+	}
+	if config.ReleaseTagConfiguration != nil {
+		names.Insert(api.InitialReleaseName, api.LatestReleaseName)
+	}
+	return sets.List(names)
+}
+
 // secretsForCensoring returns the secret volumes and mounts that will allow sidecar to censor
 // their content from uploads. This is the full secret list in our namespace, except for the ones
 // we created to store shared directory content and autogenerated secrets for ServiceAccounts.
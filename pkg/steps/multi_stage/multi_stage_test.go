@@ -2,7 +2,10 @@ package multi_stage
 
 import (
 	"context"
+	"encoding/json"
+	"os"
 	"path"
+	"path/filepath"
 	"sort"
 	"testing"
 
@@ -11,9 +14,12 @@ import (
 	coreapi "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	fakectrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/yaml"
 
 	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/secrets"
 	"github.com/openshift/ci-tools/pkg/steps"
+	"github.com/openshift/ci-tools/pkg/steps/costattribution"
 	"github.com/openshift/ci-tools/pkg/steps/loggingclient"
 )
 
@@ -74,13 +80,40 @@ func TestRequires(t *testing.T) {
 			api.InternalImageLink(
 				api.PipelineImageStreamTagReferenceSource),
 		},
+	}, {
+		name: "steps use different releases, should have a ReleaseImagesLink for each",
+		steps: api.MultiStageTestConfigurationLiteral{
+			Test: []api.LiteralTestStep{
+				{As: "management", From: "from-release"},
+				{As: "hosted", From: "from-release", Dependencies: []api.StepDependency{{Name: "release:latest", Env: "RELEASE_IMAGE_HOSTED"}}},
+			},
+			StepReleases: api.StepReleases{"hosted": "hosted"},
+		},
+		req: []api.StepLink{
+			api.ReleasePayloadImageLink("hosted"),
+			api.ReleaseImagesLink("hosted"),
+			api.ReleaseImagesLink(api.LatestReleaseName),
+		},
+	}, {
+		name: "step with a cluster profile uses another release, should have its ReleasePayloadImageLink",
+		steps: api.MultiStageTestConfigurationLiteral{
+			ClusterProfile: api.ClusterProfileAWS,
+			Test:           []api.LiteralTestStep{{As: "hosted", From: "src"}},
+			StepReleases:   api.StepReleases{"hosted": "hosted"},
+		},
+		req: []api.StepLink{
+			api.ReleasePayloadImageLink("hosted"),
+			api.InternalImageLink(api.PipelineImageStreamTagReferenceSource),
+			api.ReleasePayloadImageLink(api.LatestReleaseName),
+			api.ImagesReadyLink(),
+		},
 	}} {
 		t.Run(tc.name, func(t *testing.T) {
 			step := MultiStageTestStep(api.TestStepConfiguration{
 				As:                                 "some-e2e",
 				ClusterClaim:                       tc.clusterClaim,
 				MultiStageTestConfigurationLiteral: &tc.steps,
-			}, &tc.config, api.NewDeferredParameters(nil), nil, nil, nil, "node-name", "")
+			}, &tc.config, api.NewDeferredParameters(nil), nil, nil, nil, "node-name", "", nil, Options{})
 			ret := step.Requires()
 			if len(ret) == len(tc.req) {
 				matches := true
@@ -209,11 +242,14 @@ func (f fakeStepParams) Get(key string) (string, error) {
 func TestEnvironment(t *testing.T) {
 	t.Parallel()
 	tests := []struct {
-		name      string
-		params    api.Parameters
-		leases    []api.StepLease
-		expected  []coreapi.EnvVar
-		expectErr bool
+		name       string
+		params     api.Parameters
+		leases     []api.StepLease
+		labels     costattribution.Labels
+		config     *api.ReleaseBuildConfiguration
+		featureSet api.FeatureSet
+		expected   []coreapi.EnvVar
+		expectErr  bool
 	}{
 		{
 			name:     "leases are exposed in environment",
@@ -221,6 +257,28 @@ func TestEnvironment(t *testing.T) {
 			leases:   []api.StepLease{{Env: "LEASE_ONE"}, {Env: "LEASE_TWO"}},
 			expected: []coreapi.EnvVar{{Name: "LEASE_ONE", Value: "ONE"}, {Name: "LEASE_TWO", Value: "TWO"}},
 		},
+		{
+			name:     "cost attribution labels are exposed in environment",
+			labels:   costattribution.Labels{"team": "installer", "cost-center": "700"},
+			expected: []coreapi.EnvVar{{Name: costattribution.TagsEnv, Value: "cost-center=700,team=installer"}},
+		},
+		{
+			name: "provenance of releases is exposed in environment",
+			params: fakeStepParams{
+				"RELEASE_PROVENANCE_LATEST":  `{"installer":"abc"}`,
+				"RELEASE_PROVENANCE_INITIAL": "",
+			},
+			config: &api.ReleaseBuildConfiguration{InputConfiguration: api.InputConfiguration{
+				ReleaseTagConfiguration: &api.ReleaseTagConfiguration{Name: "4.14"},
+				Releases:                map[string]api.UnresolvedRelease{"custom": {}},
+			}},
+			expected: []coreapi.EnvVar{{Name: "RELEASE_PROVENANCE_LATEST", Value: `{"installer":"abc"}`}},
+		},
+		{
+			name:       "feature set is exposed in environment",
+			featureSet: api.FeatureSetTechPreviewNoUpgrade,
+			expected:   []coreapi.EnvVar{{Name: "FEATURE_SET", Value: "TechPreviewNoUpgrade"}},
+		},
 		{
 			name: "arbitrary variables are not exposed in environment",
 			params: fakeStepParams{
@@ -233,10 +291,13 @@ func TestEnvironment(t *testing.T) {
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
 			s := &multiStageTestStep{
-				params: tc.params,
-				leases: tc.leases,
+				params:     tc.params,
+				leases:     tc.leases,
+				config:     tc.config,
+				featureSet: tc.featureSet,
+				options:    Options{CostAttribution: tc.labels},
 			}
-			got, err := s.environment()
+			builder, err := s.environment()
 			if (err != nil) != tc.expectErr {
 				t.Errorf("environment() error = %v, wantErr %v", err, tc.expectErr)
 				return
@@ -244,6 +305,7 @@ func TestEnvironment(t *testing.T) {
 			sort.Slice(tc.expected, func(i, j int) bool {
 				return tc.expected[i].Name < tc.expected[j].Name
 			})
+			got := builder.env()
 			sort.Slice(got, func(i, j int) bool {
 				return got[i].Name < got[j].Name
 			})
@@ -284,3 +346,68 @@ func TestProfileSecretName(t *testing.T) {
 		})
 	}
 }
+
+func TestSaveResolvedConfig(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("ARTIFACTS", dir)
+	censor := secrets.NewDynamicCensor()
+	literal := &api.MultiStageTestConfigurationLiteral{
+		ClusterProfile: api.ClusterProfileAWS,
+		Test:           []api.LiteralTestStep{{As: "test0", From: "src", Commands: "make test"}},
+		Environment:    api.TestEnvironment{"PARAM": "value"},
+	}
+	step := multiStageTestStep{name: "e2e", resolved: literal, censor: &censor}
+	if err := step.saveResolvedConfig(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	raw, err := os.ReadFile(filepath.Join(dir, "e2e", ResolvedTestArtifact))
+	if err != nil {
+		t.Fatalf("failed to read artifact: %v", err)
+	}
+	var got api.MultiStageTestConfigurationLiteral
+	if err := yaml.Unmarshal(raw, &got); err != nil {
+		t.Fatalf("failed to unmarshal artifact: %v", err)
+	}
+	if diff := cmp.Diff(literal, &got); diff != "" {
+		t.Errorf("resolved configuration differs from expected:\n%s", diff)
+	}
+}
+
+func TestSaveCredentialsAudit(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("ARTIFACTS", dir)
+	censor := secrets.NewDynamicCensor()
+	step := multiStageTestStep{
+		name: "e2e",
+		pre: []api.LiteralTestStep{{As: "install", Credentials: []api.CredentialReference{
+			{Namespace: "ns", Name: "cloud", MountPath: "/var/run/cloud"},
+		}}},
+		test: []api.LiteralTestStep{{As: "test"}},
+		post: []api.LiteralTestStep{{As: "deprovision", Credentials: []api.CredentialReference{
+			{Namespace: "ns", Name: "cloud", MountPath: "/var/run/cloud"},
+			{Namespace: "other", Name: "token", MountPath: "/var/run/token"},
+			{Namespace: "ns", Selector: &api.CredentialSelector{MatchLabels: map[string]string{"partner": "true"}}, MountPath: "/var/run/partners"},
+		}}},
+		censor: &censor,
+	}
+	if err := step.saveCredentialsAudit(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	raw, err := os.ReadFile(filepath.Join(dir, "e2e", CredentialsArtifact))
+	if err != nil {
+		t.Fatalf("failed to read artifact: %v", err)
+	}
+	var got []credentialUse
+	if err := json.Unmarshal(raw, &got); err != nil {
+		t.Fatalf("failed to unmarshal artifact: %v", err)
+	}
+	expected := []credentialUse{
+		{Step: "install", Namespace: "ns", Name: "cloud", MountPath: "/var/run/cloud"},
+		{Step: "deprovision", Namespace: "ns", Name: "cloud", MountPath: "/var/run/cloud"},
+		{Step: "deprovision", Namespace: "other", Name: "token", MountPath: "/var/run/token"},
+		{Step: "deprovision", Namespace: "ns", Selector: map[string]string{"partner": "true"}, MountPath: "/var/run/partners"},
+	}
+	if diff := cmp.Diff(expected, got); diff != "" {
+		t.Errorf("unexpected credentials audit: %s", diff)
+	}
+}
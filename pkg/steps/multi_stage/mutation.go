@@ -0,0 +1,84 @@
+package multi_stage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	coreapi "k8s.io/api/core/v1"
+
+	"github.com/openshift/ci-tools/pkg/results"
+)
+
+// PodMutator changes the pods of multi-stage tests before they are created,
+// e.g. to add labels, sidecars or a scheduler name required by a deployment.
+// Mutators are called in order for every step and observer pod and may
+// modify the pod in place.
+type PodMutator interface {
+	MutatePod(ctx context.Context, pod *coreapi.Pod) error
+}
+
+// PodMutatorFunc adapts a function to the PodMutator interface.
+type PodMutatorFunc func(ctx context.Context, pod *coreapi.Pod) error
+
+func (f PodMutatorFunc) MutatePod(ctx context.Context, pod *coreapi.Pod) error {
+	return f(ctx, pod)
+}
+
+// WebhookPodMutator delegates the mutation of pods to an external service.
+// The pod is sent as the JSON body of a POST request and the service responds
+// with the mutated pod.
+type WebhookPodMutator struct {
+	URL    string
+	Client *http.Client
+}
+
+func (w *WebhookPodMutator) MutatePod(ctx context.Context, pod *coreapi.Pod) error {
+	body, err := json.Marshal(pod)
+	if err != nil {
+		return fmt.Errorf("failed to marshal pod: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	client := w.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read webhook response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("webhook responded with status code %d: %s", resp.StatusCode, string(raw))
+	}
+	mutated := &coreapi.Pod{}
+	if err := json.Unmarshal(raw, mutated); err != nil {
+		return fmt.Errorf("failed to parse webhook response: %w", err)
+	}
+	if mutated.Name != pod.Name || mutated.Namespace != pod.Namespace {
+		return fmt.Errorf("webhook changed the name of the pod to %s/%s", mutated.Namespace, mutated.Name)
+	}
+	*pod = *mutated
+	return nil
+}
+
+// mutatePod passes a pod through the configured mutators.
+func (s *multiStageTestStep) mutatePod(ctx context.Context, pod *coreapi.Pod) error {
+	for _, m := range s.options.PodMutators {
+		if err := m.MutatePod(ctx, pod); err != nil {
+			return results.ForReason("mutating_pod").WithError(err).Errorf("failed to mutate pod %s: %v", pod.Name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package multi_stage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	coreapi "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/openshift/ci-tools/pkg/results"
+)
+
+func TestMutatePod(t *testing.T) {
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pod := &coreapi.Pod{}
+		if err := json.NewDecoder(r.Body).Decode(pod); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		switch r.URL.Path {
+		case "/rename":
+			pod.Name = "renamed"
+		case "/fail":
+			http.Error(w, "denied", http.StatusForbidden)
+			return
+		default:
+			pod.Spec.SchedulerName = "ci-scheduler"
+			pod.Labels["from-webhook"] = pod.Labels["from-func"]
+		}
+		if err := json.NewEncoder(w).Encode(pod); err != nil {
+			t.Errorf("failed to encode pod: %v", err)
+		}
+	}))
+	defer webhook.Close()
+	addLabel := PodMutatorFunc(func(_ context.Context, pod *coreapi.Pod) error {
+		pod.Labels["from-func"] = "true"
+		return nil
+	})
+	for _, tc := range []struct {
+		name        string
+		mutators    []PodMutator
+		expected    *coreapi.Pod
+		expectedErr string
+	}{{
+		name:     "no mutators",
+		expected: &coreapi.Pod{ObjectMeta: meta.ObjectMeta{Name: "test-e2e", Namespace: "ns", Labels: map[string]string{}}},
+	}, {
+		name:     "mutators are called in order",
+		mutators: []PodMutator{addLabel, &WebhookPodMutator{URL: webhook.URL}},
+		expected: &coreapi.Pod{
+			ObjectMeta: meta.ObjectMeta{Name: "test-e2e", Namespace: "ns", Labels: map[string]string{"from-func": "true", "from-webhook": "true"}},
+			Spec:       coreapi.PodSpec{SchedulerName: "ci-scheduler"},
+		},
+	}, {
+		name:        "webhook fails",
+		mutators:    []PodMutator{&WebhookPodMutator{URL: webhook.URL + "/fail"}},
+		expectedErr: "failed to mutate pod test-e2e: webhook responded with status code 403: denied\n",
+	}, {
+		name:        "webhook renames the pod",
+		mutators:    []PodMutator{&WebhookPodMutator{URL: webhook.URL + "/rename"}},
+		expectedErr: "failed to mutate pod test-e2e: webhook changed the name of the pod to ns/renamed",
+	}, {
+		name: "mutator fails",
+		mutators: []PodMutator{PodMutatorFunc(func(context.Context, *coreapi.Pod) error {
+			return errors.New("injected")
+		})},
+		expectedErr: "failed to mutate pod test-e2e: injected",
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			s := multiStageTestStep{options: Options{PodMutators: tc.mutators}}
+			pod := &coreapi.Pod{ObjectMeta: meta.ObjectMeta{Name: "test-e2e", Namespace: "ns", Labels: map[string]string{}}}
+			err := s.mutatePod(context.Background(), pod)
+			if tc.expectedErr != "" {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				if diff := cmp.Diff(tc.expectedErr, err.Error()); diff != "" {
+					t.Errorf("unexpected error: %s", diff)
+				}
+				if diff := cmp.Diff([]string{"mutating_pod"}, results.Reasons(err)); diff != "" {
+					t.Errorf("unexpected reasons: %s", diff)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tc.expected, pod); diff != "" {
+				t.Errorf("unexpected pod: %s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package multi_stage
+
+import (
+	"fmt"
+	"strings"
+
+	coreapi "k8s.io/api/core/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+
+	"github.com/openshift/ci-tools/pkg/api"
+)
+
+// validateNodeCapabilities verifies that the build farm provides the node
+// capabilities required by the steps, so that tests which cannot run fail
+// before any step is executed instead of waiting for pods which can never
+// be scheduled.
+func (s *multiStageTestStep) validateNodeCapabilities() error {
+	capabilities := s.options.NodeCapabilities
+	if capabilities == nil {
+		return nil
+	}
+	var errs []error
+	for _, step := range s.allSteps() {
+		if missing := capabilities.Missing(step.NodeCapabilities); len(missing) != 0 {
+			errs = append(errs, fmt.Errorf("this farm can't run step %s: it requires node capabilities which the farm does not provide: %s", step.As, strings.Join(missing, ", ")))
+			continue
+		}
+		if _, err := capabilities.NodeSelector(step.NodeCapabilities); err != nil {
+			errs = append(errs, fmt.Errorf("this farm can't run step %s: %w", step.As, err))
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// applyNodeCapabilities schedules the pod of a step on the nodes which
+// provide the capabilities it requires.
+func (s *multiStageTestStep) applyNodeCapabilities(pod *coreapi.Pod, step api.LiteralTestStep) error {
+	if len(step.NodeCapabilities) == 0 || s.options.NodeCapabilities == nil {
+		return nil
+	}
+	if err := s.options.NodeCapabilities.Apply(pod, step.NodeCapabilities); err != nil {
+		return fmt.Errorf("failed to schedule step %s: %w", step.As, err)
+	}
+	return nil
+}
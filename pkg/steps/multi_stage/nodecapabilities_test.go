@@ -0,0 +1,48 @@
+package multi_stage
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/steps/nodecapabilities"
+	"github.com/openshift/ci-tools/pkg/testhelper"
+)
+
+func TestValidateNodeCapabilities(t *testing.T) {
+	capabilities := &nodecapabilities.Config{Capabilities: map[string]nodecapabilities.Capability{
+		"kvm":   {NodeSelector: map[string]string{"devices.kubevirt.io/kvm": "true"}},
+		"arm64": {NodeSelector: map[string]string{"kubernetes.io/arch": "arm64"}},
+		"amd64": {NodeSelector: map[string]string{"kubernetes.io/arch": "amd64"}},
+	}}
+	for _, tc := range []struct {
+		name         string
+		capabilities *nodecapabilities.Config
+		test         []api.LiteralTestStep
+		post         []api.LiteralTestStep
+		err          error
+	}{{
+		name: "requirements are not checked without the capabilities of the farm",
+		test: []api.LiteralTestStep{{As: "e2e", NodeCapabilities: []string{"large-disk"}}},
+	}, {
+		name:         "capabilities provided by the farm",
+		capabilities: capabilities,
+		test:         []api.LiteralTestStep{{As: "e2e", NodeCapabilities: []string{"kvm", "arm64"}}},
+	}, {
+		name:         "missing and conflicting capabilities",
+		capabilities: capabilities,
+		test:         []api.LiteralTestStep{{As: "e2e", NodeCapabilities: []string{"kvm", "large-disk", "sse4"}}},
+		post:         []api.LiteralTestStep{{As: "gather", NodeCapabilities: []string{"arm64", "amd64"}}},
+		err: errors.New(`[this farm can't run step e2e: it requires node capabilities which the farm does not provide: large-disk, sse4, ` +
+			`this farm can't run step gather: capabilities arm64 and amd64 select different values of node label kubernetes.io/arch: "arm64" and "amd64"]`),
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			s := multiStageTestStep{test: tc.test, post: tc.post, options: Options{NodeCapabilities: tc.capabilities}}
+			if diff := cmp.Diff(tc.err, s.Validate(), testhelper.EquateErrorMessage); diff != "" {
+				t.Errorf("unexpected error: %s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package multi_stage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/sirupsen/logrus"
+
+	coreapi "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	utilpointer "k8s.io/utils/pointer"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/junit"
+	"github.com/openshift/ci-tools/pkg/results"
+	base_steps "github.com/openshift/ci-tools/pkg/steps"
+	"github.com/openshift/ci-tools/pkg/steps/orgquota"
+)
+
+// orgQuotaPollInterval is how often the usage of an organization is checked
+// while a pod is queued.
+var orgQuotaPollInterval = 30 * time.Second
+
+// orgQuotaWait is the time for which the creation of a step pod was queued
+// because its organization used its share of the build farm.
+type orgQuotaWait struct {
+	pod      string
+	org      string
+	duration time.Duration
+	finished time.Time
+}
+
+// orgUsage returns the resources requested by the running step pods of an
+// organization in all the namespaces of the build farm, except for the pod.
+func (s *multiStageTestStep) orgUsage(ctx context.Context, org string, pod *coreapi.Pod) (coreapi.ResourceList, error) {
+	pods := &coreapi.PodList{}
+	if err := s.client.List(ctx, pods, ctrlruntimeclient.MatchingLabels{
+		base_steps.LabelMetadataOrg: org,
+		base_steps.CreatedByCILabel: "true",
+	}); err != nil {
+		return nil, fmt.Errorf("failed to list the pods of organization %s: %w", org, err)
+	}
+	var others []coreapi.Pod
+	for _, p := range pods.Items {
+		if p.Namespace != pod.Namespace || p.Name != pod.Name {
+			others = append(others, p)
+		}
+	}
+	return orgquota.Usage(others), nil
+}
+
+// admitOrgPod blocks until the organization of a pod may run it within its
+// share of the build farm, or rejects it if the policy does not queue pods.
+// The time spent queued is recorded for the timeline and metrics of the test.
+func (s *multiStageTestStep) admitOrgPod(ctx context.Context, pod *coreapi.Pod) error {
+	config := s.options.OrgQuota
+	if config == nil {
+		return nil
+	}
+	org := pod.Labels[base_steps.LabelMetadataOrg]
+	if org == "" {
+		return nil
+	}
+	reject := func(exceeded []string) error {
+		err := results.ForReason("org_quota_exceeded").ForError(fmt.Errorf("pod %s exceeds the share of the build farm of organization %s: %s", pod.Name, org, strings.Join(exceeded, "; ")))
+		s.subLock.Lock()
+		defer s.subLock.Unlock()
+		s.subTests = append(s.subTests, &junit.TestCase{
+			Name:          fmt.Sprintf("%s - %s organization quota", s.Description(), pod.Name),
+			FailureOutput: &junit.FailureOutput{Output: err.Error()},
+		})
+		return err
+	}
+	// a pod which exceeds the share on its own would never be admitted
+	if exceeded := config.Exceeded(org, nil, pod); len(exceeded) != 0 {
+		return reject(exceeded)
+	}
+	start := time.Now()
+	var exceeded []string
+	check := func(ctx context.Context) (bool, error) {
+		usage, err := s.orgUsage(ctx, org, pod)
+		if err != nil {
+			return false, err
+		}
+		exceeded = config.Exceeded(org, usage, pod)
+		return len(exceeded) == 0, nil
+	}
+	if done, err := check(ctx); err != nil {
+		return err
+	} else if done {
+		return nil
+	} else if config.Reject() {
+		return reject(exceeded)
+	}
+	logrus.Infof("Queueing step %s until organization %s uses less of the build farm: %s", pod.Name, org, strings.Join(exceeded, "; "))
+	waitCtx, cancel := context.WithTimeout(ctx, config.Timeout())
+	defer cancel()
+	err := wait.PollUntilContextCancel(waitCtx, orgQuotaPollInterval, false, check)
+	s.recordOrgQuotaWait(pod.Name, org, time.Since(start))
+	if err != nil {
+		if ctx.Err() == nil && waitCtx.Err() != nil {
+			return reject(exceeded)
+		}
+		return err
+	}
+	logrus.Infof("Step %s was queued for %s on the share of organization %s.", pod.Name, time.Since(start).Truncate(time.Second), org)
+	return nil
+}
+
+func (s *multiStageTestStep) recordOrgQuotaWait(pod, org string, duration time.Duration) {
+	queued := orgQuotaWait{pod: pod, org: org, duration: duration, finished: time.Now()}
+	started := queued.finished.Add(-duration)
+	s.subLock.Lock()
+	defer s.subLock.Unlock()
+	s.orgQuotaWaits = append(s.orgQuotaWaits, queued)
+	s.subSteps = append(s.subSteps, api.CIOperatorStepDetailInfo{
+		StepName:    fmt.Sprintf("%s-org-quota-wait", pod),
+		Description: fmt.Sprintf("Wait for the share of organization %s to create pod %s", org, pod),
+		StartedAt:   &started,
+		FinishedAt:  &queued.finished,
+		Duration:    &queued.duration,
+	})
+}
+
+// orgQuotaWaitMetrics returns the time for which the steps were queued on the
+// share of their organization, labeled with the identity of the job.
+func (s *multiStageTestStep) orgQuotaWaitMetrics() []*dto.MetricFamily {
+	s.subLock.Lock()
+	defer s.subLock.Unlock()
+	if len(s.orgQuotaWaits) == 0 {
+		return nil
+	}
+	gauge := dto.MetricType_GAUGE
+	waits := &dto.MetricFamily{
+		Name: utilpointer.String("ci_step_org_quota_wait_seconds"),
+		Help: utilpointer.String("Time for which the creation of the pod of a multi-stage test step was queued on the share of the build farm of its organization."),
+		Type: &gauge,
+	}
+	for _, queued := range s.orgQuotaWaits {
+		seconds := queued.duration.Seconds()
+		waits.Metric = append(waits.Metric, &dto.Metric{
+			Label: []*dto.LabelPair{
+				{Name: utilpointer.String("build_id"), Value: utilpointer.String(s.jobSpec.BuildID)},
+				{Name: utilpointer.String("job"), Value: utilpointer.String(s.jobSpec.Job)},
+				{Name: utilpointer.String("org"), Value: utilpointer.String(queued.org)},
+				{Name: utilpointer.String("step"), Value: utilpointer.String(strings.TrimPrefix(queued.pod, s.name+"-"))},
+				{Name: utilpointer.String("test"), Value: utilpointer.String(s.name)},
+			},
+			Gauge: &dto.Gauge{Value: &seconds},
+		})
+	}
+	return []*dto.MetricFamily{waits}
+}
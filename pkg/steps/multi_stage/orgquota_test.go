@@ -0,0 +1,122 @@
+package multi_stage
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	coreapi "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	prowv1 "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	prowdapi "k8s.io/test-infra/prow/pod-utils/downwardapi"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	fakectrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	base_steps "github.com/openshift/ci-tools/pkg/steps"
+	"github.com/openshift/ci-tools/pkg/steps/loggingclient"
+	"github.com/openshift/ci-tools/pkg/steps/orgquota"
+	"github.com/openshift/ci-tools/pkg/testhelper"
+	testhelper_kube "github.com/openshift/ci-tools/pkg/testhelper/kubernetes"
+)
+
+func TestAdmitOrgPod(t *testing.T) {
+	defer func(interval time.Duration) { orgQuotaPollInterval = interval }(orgQuotaPollInterval)
+	orgQuotaPollInterval = 10 * time.Millisecond
+	orgPod := func(namespace, name, org, cpu string) *coreapi.Pod {
+		return &coreapi.Pod{
+			ObjectMeta: meta.ObjectMeta{Namespace: namespace, Name: name, Labels: map[string]string{
+				base_steps.LabelMetadataOrg: org,
+				base_steps.CreatedByCILabel: "true",
+			}},
+			Spec: coreapi.PodSpec{Containers: []coreapi.Container{{Resources: coreapi.ResourceRequirements{
+				Requests: coreapi.ResourceList{coreapi.ResourceCPU: resource.MustParse(cpu)},
+			}}}},
+			Status: coreapi.PodStatus{Phase: coreapi.PodRunning},
+		}
+	}
+	config := func(action orgquota.Action) *orgquota.Config {
+		return &orgquota.Config{
+			Farm:          coreapi.ResourceList{coreapi.ResourceCPU: resource.MustParse("100")},
+			Organizations: map[string]int{"org": 10},
+			Action:        action,
+			QueueTimeout:  &prowv1.Duration{Duration: 200 * time.Millisecond},
+		}
+	}
+	for _, tc := range []struct {
+		name    string
+		config  *orgquota.Config
+		pod     *coreapi.Pod
+		running []ctrlruntimeclient.Object
+		finish  bool
+		err     error
+		queued  bool
+	}{{
+		name: "no policy",
+		pod:  orgPod("ns", "e2e-test", "org", "50"),
+	}, {
+		name:    "within the share",
+		config:  config(orgquota.ActionQueue),
+		pod:     orgPod("ns", "e2e-test", "org", "5"),
+		running: []ctrlruntimeclient.Object{orgPod("other", "e2e-test", "org", "4"), orgPod("other", "e2e-other", "another", "50")},
+	}, {
+		name:   "pod exceeds the share on its own",
+		config: config(orgquota.ActionQueue),
+		pod:    orgPod("ns", "e2e-test", "org", "11"),
+		err:    errors.New("pod e2e-test exceeds the share of the build farm of organization org: cpu: 11 requested with 0 in use, the share of organization org is 10"),
+	}, {
+		name:    "rejected",
+		config:  config(orgquota.ActionReject),
+		pod:     orgPod("ns", "e2e-test", "org", "5"),
+		running: []ctrlruntimeclient.Object{orgPod("other", "e2e-test", "org", "6")},
+		err:     errors.New("pod e2e-test exceeds the share of the build farm of organization org: cpu: 5 requested with 6 in use, the share of organization org is 10"),
+	}, {
+		name:    "queued until other pods finish",
+		config:  config(orgquota.ActionQueue),
+		pod:     orgPod("ns", "e2e-test", "org", "5"),
+		running: []ctrlruntimeclient.Object{orgPod("other", "e2e-test", "org", "6")},
+		finish:  true,
+		queued:  true,
+	}, {
+		name:    "queued until the timeout",
+		config:  config(orgquota.ActionQueue),
+		pod:     orgPod("ns", "e2e-test", "org", "5"),
+		running: []ctrlruntimeclient.Object{orgPod("other", "e2e-test", "org", "6")},
+		err:     errors.New("pod e2e-test exceeds the share of the build farm of organization org: cpu: 5 requested with 6 in use, the share of organization org is 10"),
+		queued:  true,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			jobSpec := api.JobSpec{JobSpec: prowdapi.JobSpec{Job: "job", BuildID: "1"}}
+			jobSpec.SetNamespace("ns")
+			client := &testhelper_kube.FakePodClient{FakePodExecutor: &testhelper_kube.FakePodExecutor{
+				LoggingClient: loggingclient.New(fakectrlruntimeclient.NewClientBuilder().WithObjects(tc.running...).Build()),
+			}}
+			s := multiStageTestStep{
+				name:    "e2e",
+				jobSpec: &jobSpec,
+				client:  client,
+				subLock: &sync.Mutex{},
+				options: Options{OrgQuota: tc.config},
+			}
+			if tc.finish {
+				go func() {
+					time.Sleep(50 * time.Millisecond)
+					if err := client.Delete(context.Background(), tc.running[0]); err != nil {
+						t.Error(err)
+					}
+				}()
+			}
+			err := s.admitOrgPod(context.Background(), tc.pod)
+			testhelper.Diff(t, "error", err, tc.err, testhelper.EquateErrorMessage)
+			if queued := len(s.orgQuotaWaits) != 0; queued != tc.queued {
+				t.Errorf("expected queued to be %t, got %t", tc.queued, queued)
+			}
+			if metrics := s.orgQuotaWaitMetrics(); tc.queued && (len(metrics) != 1 || len(metrics[0].Metric) != 1) {
+				t.Errorf("expected a metric for the wait, got %v", metrics)
+			}
+		})
+	}
+}
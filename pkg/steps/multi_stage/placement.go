@@ -0,0 +1,79 @@
+package multi_stage
+
+import (
+	"fmt"
+	"strings"
+
+	coreapi "k8s.io/api/core/v1"
+	"sigs.k8s.io/yaml"
+
+	"github.com/openshift/ci-tools/pkg/api"
+)
+
+const (
+	// RegionEnv is the env we use to expose the region in which the cluster
+	// of the cluster profile is created.
+	RegionEnv = "REGION"
+	// ZonesEnv is the env we use to expose the availability zones of the
+	// region, separated by spaces.
+	ZonesEnv = "ZONES"
+	// placementPath is the path of the file in the cluster profile which
+	// holds the default region and the zones of the regions of the profile.
+	placementPath = "placement.yaml"
+)
+
+// placement holds where the clusters of a profile are created.
+type placement struct {
+	// Region is the region of clusters whose lease does not name one.
+	Region string `json:"region,omitempty"`
+	// Zones are the availability zones of the clusters, by region.
+	Zones map[string][]string `json:"zones,omitempty"`
+}
+
+// readPlacement reads the default region and the zones of the regions from
+// the profile, if it declares them.
+func (s *multiStageTestStep) readPlacement(secret *coreapi.Secret) error {
+	bytes, ok := secret.Data[placementPath]
+	if !ok {
+		return nil
+	}
+	var p placement
+	if err := yaml.UnmarshalStrict(bytes, &p); err != nil {
+		return fmt.Errorf("failed to read placement file: %w", err)
+	}
+	for region, zones := range p.Zones {
+		if len(zones) == 0 {
+			return fmt.Errorf("no zones listed for region %s", region)
+		}
+	}
+	s.placement = &p
+	return nil
+}
+
+// placementEnv returns the region and zones of the cluster of a test.  The
+// region named by the lease of the profile takes precedence over the default
+// of the profile.  Neither is set if the region cannot be determined.
+func (s *multiStageTestStep) placementEnv() ([]coreapi.EnvVar, error) {
+	var region string
+	if s.params.Has(api.LeasedRegionParam) {
+		var err error
+		if region, err = s.params.Get(api.LeasedRegionParam); err != nil {
+			return nil, err
+		}
+	}
+	var p placement
+	if s.placement != nil {
+		p = *s.placement
+	}
+	if region == "" {
+		region = p.Region
+	}
+	if region == "" {
+		return nil, nil
+	}
+	ret := []coreapi.EnvVar{{Name: RegionEnv, Value: region}}
+	if zones := p.Zones[region]; len(zones) != 0 {
+		ret = append(ret, coreapi.EnvVar{Name: ZonesEnv, Value: strings.Join(zones, " ")})
+	}
+	return ret, nil
+}
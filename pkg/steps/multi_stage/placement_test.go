@@ -0,0 +1,92 @@
+package multi_stage
+
+import (
+	"testing"
+
+	coreapi "k8s.io/api/core/v1"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/testhelper"
+)
+
+func TestReadPlacement(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		data        map[string][]byte
+		expected    *placement
+		expectedErr string
+	}{{
+		name: "no placement",
+	}, {
+		name: "region and zones",
+		data: map[string][]byte{placementPath: []byte(`region: us-east-1
+zones:
+  us-east-1: [us-east-1a, us-east-1b]
+  us-west-2: [us-west-2a]
+`)},
+		expected: &placement{
+			Region: "us-east-1",
+			Zones:  map[string][]string{"us-east-1": {"us-east-1a", "us-east-1b"}, "us-west-2": {"us-west-2a"}},
+		},
+	}, {
+		name:        "region without zones",
+		data:        map[string][]byte{placementPath: []byte("zones:\n  us-east-1: []\n")},
+		expectedErr: "no zones listed for region us-east-1",
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			s := multiStageTestStep{profile: api.ClusterProfileAWS}
+			err := s.readPlacement(&coreapi.Secret{Data: tc.data})
+			var actualErr string
+			if err != nil {
+				actualErr = err.Error()
+			}
+			testhelper.Diff(t, "error", actualErr, tc.expectedErr)
+			testhelper.Diff(t, "placement", s.placement, tc.expected)
+		})
+	}
+}
+
+func TestPlacementEnv(t *testing.T) {
+	profilePlacement := &placement{
+		Region: "us-east-1",
+		Zones:  map[string][]string{"us-east-1": {"us-east-1a", "us-east-1b"}},
+	}
+	for _, tc := range []struct {
+		name      string
+		leased    bool
+		region    string
+		placement *placement
+		expected  []coreapi.EnvVar
+	}{{
+		name: "no lease and no placement",
+	}, {
+		name:     "region of the lease",
+		leased:   true,
+		region:   "us-west-2",
+		expected: []coreapi.EnvVar{{Name: RegionEnv, Value: "us-west-2"}},
+	}, {
+		name:      "lease without a region uses the default of the profile",
+		leased:    true,
+		placement: profilePlacement,
+		expected:  []coreapi.EnvVar{{Name: RegionEnv, Value: "us-east-1"}, {Name: ZonesEnv, Value: "us-east-1a us-east-1b"}},
+	}, {
+		name:      "region of the lease takes precedence",
+		leased:    true,
+		region:    "us-west-2",
+		placement: profilePlacement,
+		expected:  []coreapi.EnvVar{{Name: RegionEnv, Value: "us-west-2"}},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			params := api.NewDeferredParameters(nil)
+			if tc.leased {
+				params.Add(api.LeasedRegionParam, func() (string, error) { return tc.region, nil })
+			}
+			s := multiStageTestStep{profile: api.ClusterProfileAWS, params: params, placement: tc.placement}
+			actual, err := s.placementEnv()
+			if err != nil {
+				t.Fatal(err)
+			}
+			testhelper.Diff(t, "env", actual, tc.expected)
+		})
+	}
+}
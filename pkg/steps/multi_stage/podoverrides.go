@@ -0,0 +1,98 @@
+package multi_stage
+
+import (
+	"fmt"
+
+	coreapi "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/yaml"
+)
+
+// podOverridesPath is the path of the pod overrides file in the cluster
+// profile.
+const podOverridesPath = "pod-overrides.yaml"
+
+// podOverrides is the format of the pod overrides file in the cluster profile.
+// Its contents are merged into every step pod which uses the profile, so that
+// settings required by the environment, e.g. a CA bundle or DNS options, do
+// not have to be set up by each step.
+type podOverrides struct {
+	// Volumes are added to the pod.  Their names cannot conflict with those
+	// of the volumes ci-operator adds.
+	Volumes []coreapi.Volume `json:"volumes,omitempty"`
+	// VolumeMounts are added to the test container.  They can refer to the
+	// volumes above or to the cluster profile itself.
+	VolumeMounts []coreapi.VolumeMount `json:"volume_mounts,omitempty"`
+	// Env is added to the environment of the test container.  Parameters of
+	// the step take precedence over these values.
+	Env []coreapi.EnvVar `json:"env,omitempty"`
+	// Tolerations are added to the pod.
+	Tolerations []coreapi.Toleration `json:"tolerations,omitempty"`
+	// DNSConfig is merged into the DNS configuration of the pod.
+	DNSConfig *coreapi.PodDNSConfig `json:"dns_config,omitempty"`
+}
+
+func (s *multiStageTestStep) readPodOverrides(secret *coreapi.Secret) error {
+	bytes, ok := secret.Data[podOverridesPath]
+	if !ok {
+		return nil
+	}
+	var o podOverrides
+	if err := yaml.UnmarshalStrict(bytes, &o); err != nil {
+		return fmt.Errorf("failed to read pod overrides file: %w", err)
+	}
+	volumes := sets.New[string](profileVolumeName)
+	for _, v := range o.Volumes {
+		if v.Name == "" {
+			return fmt.Errorf("volume name missing in pod overrides file")
+		}
+		if volumes.Has(v.Name) {
+			return fmt.Errorf("duplicate volume %q in pod overrides file", v.Name)
+		}
+		volumes.Insert(v.Name)
+	}
+	for _, m := range o.VolumeMounts {
+		if !volumes.Has(m.Name) {
+			return fmt.Errorf("volume mount %q does not refer to a volume in pod overrides file", m.Name)
+		}
+	}
+	for _, e := range o.Env {
+		if e.Name == "" {
+			return fmt.Errorf("environment variable name missing in pod overrides file")
+		}
+		if e.ValueFrom != nil {
+			return fmt.Errorf("environment variable %q in pod overrides file must have a literal value", e.Name)
+		}
+	}
+	s.podOverrides = &o
+	return nil
+}
+
+// addPodOverrides merges the pod overrides of the cluster profile into a step
+// pod, which must already have the cluster profile volume.
+func (s *multiStageTestStep) addPodOverrides(pod *coreapi.Pod) error {
+	o := s.podOverrides
+	existing := sets.New[string]()
+	for _, v := range pod.Spec.Volumes {
+		existing.Insert(v.Name)
+	}
+	for _, v := range o.Volumes {
+		if existing.Has(v.Name) {
+			return fmt.Errorf("volume %q from the pod overrides of the cluster profile conflicts with a volume of pod %s", v.Name, pod.Name)
+		}
+	}
+	pod.Spec.Volumes = append(pod.Spec.Volumes, o.Volumes...)
+	container := &pod.Spec.Containers[0]
+	container.VolumeMounts = append(container.VolumeMounts, o.VolumeMounts...)
+	pod.Spec.Tolerations = append(pod.Spec.Tolerations, o.Tolerations...)
+	if c := o.DNSConfig; c != nil {
+		if pod.Spec.DNSConfig == nil {
+			pod.Spec.DNSConfig = &coreapi.PodDNSConfig{}
+		}
+		dns := pod.Spec.DNSConfig
+		dns.Nameservers = append(dns.Nameservers, c.Nameservers...)
+		dns.Searches = append(dns.Searches, c.Searches...)
+		dns.Options = append(dns.Options, c.Options...)
+	}
+	return nil
+}
@@ -0,0 +1,169 @@
+package multi_stage
+
+import (
+	"slices"
+	"testing"
+
+	coreapi "k8s.io/api/core/v1"
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	prowdapi "k8s.io/test-infra/prow/pod-utils/downwardapi"
+	utilpointer "k8s.io/utils/pointer"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/testhelper"
+)
+
+func TestReadPodOverrides(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		data        map[string][]byte
+		expected    *podOverrides
+		expectedErr string
+	}{{
+		name: "no overrides",
+	}, {
+		name: "overrides are read",
+		data: map[string][]byte{podOverridesPath: []byte(`volumes:
+- name: ca-bundle
+  configMap:
+    name: vsphere-ca
+volume_mounts:
+- name: ca-bundle
+  mountPath: /etc/pki/vsphere
+- name: cluster-profile
+  mountPath: /etc/vsphere/credentials
+  subPath: credentials
+env:
+- name: SSL_CERT_DIR
+  value: /etc/pki/vsphere
+dns_config:
+  options:
+  - name: ndots
+    value: "1"
+`)},
+		expected: &podOverrides{
+			Volumes: []coreapi.Volume{{
+				Name:         "ca-bundle",
+				VolumeSource: coreapi.VolumeSource{ConfigMap: &coreapi.ConfigMapVolumeSource{LocalObjectReference: coreapi.LocalObjectReference{Name: "vsphere-ca"}}},
+			}},
+			VolumeMounts: []coreapi.VolumeMount{
+				{Name: "ca-bundle", MountPath: "/etc/pki/vsphere"},
+				{Name: "cluster-profile", MountPath: "/etc/vsphere/credentials", SubPath: "credentials"},
+			},
+			Env: []coreapi.EnvVar{{Name: "SSL_CERT_DIR", Value: "/etc/pki/vsphere"}},
+			DNSConfig: &coreapi.PodDNSConfig{
+				Options: []coreapi.PodDNSConfigOption{{Name: "ndots", Value: utilpointer.String("1")}},
+			},
+		},
+	}, {
+		name:        "unknown field",
+		data:        map[string][]byte{podOverridesPath: []byte("containers: []\n")},
+		expectedErr: `failed to read pod overrides file: error unmarshaling JSON: while decoding JSON: json: unknown field "containers"`,
+	}, {
+		name:        "volume conflicts with the cluster profile",
+		data:        map[string][]byte{podOverridesPath: []byte("volumes:\n- name: cluster-profile\n  emptyDir: {}\n")},
+		expectedErr: `duplicate volume "cluster-profile" in pod overrides file`,
+	}, {
+		name:        "mount of an unknown volume",
+		data:        map[string][]byte{podOverridesPath: []byte("volume_mounts:\n- name: ca-bundle\n  mountPath: /etc/pki\n")},
+		expectedErr: `volume mount "ca-bundle" does not refer to a volume in pod overrides file`,
+	}, {
+		name:        "variable from a reference",
+		data:        map[string][]byte{podOverridesPath: []byte("env:\n- name: NODE\n  valueFrom:\n    fieldRef:\n      fieldPath: spec.nodeName\n")},
+		expectedErr: `environment variable "NODE" in pod overrides file must have a literal value`,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			var s multiStageTestStep
+			err := s.readPodOverrides(&coreapi.Secret{Data: tc.data})
+			var actualErr string
+			if err != nil {
+				actualErr = err.Error()
+			}
+			testhelper.Diff(t, "error", actualErr, tc.expectedErr)
+			testhelper.Diff(t, "overrides", s.podOverrides, tc.expected)
+		})
+	}
+}
+
+func TestGeneratePodsPodOverrides(t *testing.T) {
+	test := api.TestStepConfiguration{
+		As: "test",
+		MultiStageTestConfigurationLiteral: &api.MultiStageTestConfigurationLiteral{
+			ClusterProfile: api.ClusterProfileAWS,
+			Test: []api.LiteralTestStep{{
+				As:          "e2e",
+				From:        "src",
+				Commands:    "make e2e",
+				Environment: []api.StepParameter{{Name: "GOVC_INSECURE", Default: utilpointer.String("false")}},
+			}},
+		},
+	}
+	config := api.ReleaseBuildConfiguration{Tests: []api.TestStepConfiguration{test}}
+	jobSpec := api.JobSpec{JobSpec: prowdapi.JobSpec{
+		Job:     "job",
+		BuildID: "build id",
+		Refs:    &prowapi.Refs{Org: "org", Repo: "repo", BaseRef: "base ref", BaseSHA: "base sha"},
+		Type:    "postsubmit",
+		DecorationConfig: &prowapi.DecorationConfig{
+			UtilityImages: &prowapi.UtilityImages{Sidecar: "sidecar", Entrypoint: "entrypoint"},
+		},
+	}}
+	jobSpec.SetNamespace("namespace")
+	for _, tc := range []struct {
+		name        string
+		overrides   *podOverrides
+		expectedErr string
+	}{{
+		name: "overrides are merged",
+		overrides: &podOverrides{
+			Volumes:      []coreapi.Volume{{Name: "ca-bundle", VolumeSource: coreapi.VolumeSource{EmptyDir: &coreapi.EmptyDirVolumeSource{}}}},
+			VolumeMounts: []coreapi.VolumeMount{{Name: "ca-bundle", MountPath: "/etc/pki/vsphere"}},
+			Env: []coreapi.EnvVar{
+				{Name: "SSL_CERT_DIR", Value: "/etc/pki/vsphere"},
+				{Name: "GOVC_INSECURE", Value: "true"},
+			},
+			Tolerations: []coreapi.Toleration{{Key: "vsphere", Operator: coreapi.TolerationOpExists}},
+			DNSConfig:   &coreapi.PodDNSConfig{Searches: []string{"vsphere.example.com"}},
+		},
+	}, {
+		name:        "conflicting volume",
+		overrides:   &podOverrides{Volumes: []coreapi.Volume{{Name: homeVolumeName}}},
+		expectedErr: `volume "home" from the pod overrides of the cluster profile conflicts with a volume of pod test-e2e`,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			step := newMultiStageTestStep(test, &config, nil, nil, &jobSpec, nil, "node-name", "", nil, Options{})
+			step.podOverrides = tc.overrides
+			pods, _, err := step.generatePods(step.test, nil, nil, nil, nil)
+			var actualErr string
+			if err != nil {
+				actualErr = err.Error()
+			}
+			testhelper.Diff(t, "error", actualErr, tc.expectedErr)
+			if err != nil {
+				return
+			}
+			if len(pods) != 1 {
+				t.Fatalf("expected one pod, got %d", len(pods))
+			}
+			pod := pods[0]
+			var volumes, mounts []string
+			for _, v := range pod.Spec.Volumes {
+				volumes = append(volumes, v.Name)
+			}
+			for _, m := range pod.Spec.Containers[0].VolumeMounts {
+				mounts = append(mounts, m.Name)
+			}
+			if !slices.Contains(volumes, "ca-bundle") || !slices.Contains(mounts, "ca-bundle") {
+				t.Errorf("expected the volume to be added and mounted, got volumes %v and mounts %v", volumes, mounts)
+			}
+			env := map[string]string{}
+			for _, e := range pod.Spec.Containers[0].Env {
+				env[e.Name] = e.Value
+			}
+			testhelper.Diff(t, "profile variable", env["SSL_CERT_DIR"], "/etc/pki/vsphere")
+			testhelper.Diff(t, "step parameter", env["GOVC_INSECURE"], "false")
+			testhelper.Diff(t, "tolerations", pod.Spec.Tolerations, tc.overrides.Tolerations)
+			testhelper.Diff(t, "DNS configuration", pod.Spec.DNSConfig, tc.overrides.DNSConfig)
+		})
+	}
+}
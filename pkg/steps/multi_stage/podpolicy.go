@@ -0,0 +1,36 @@
+package multi_stage
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	coreapi "k8s.io/api/core/v1"
+
+	"github.com/openshift/ci-tools/pkg/junit"
+	"github.com/openshift/ci-tools/pkg/results"
+)
+
+// enforcePodPolicy evaluates the pod policy, if any, for a pod before it is
+// created, fixing the pod where the policy allows it.
+func (s *multiStageTestStep) enforcePodPolicy(pod *coreapi.Pod) error {
+	if s.options.PodPolicy == nil {
+		return nil
+	}
+	mutations, violations := s.options.PodPolicy.Apply(pod)
+	for _, m := range mutations {
+		logrus.Warnf("Pod policy applied to step %s: %s", pod.Name, m)
+	}
+	if len(violations) == 0 {
+		return nil
+	}
+	err := results.ForReason("pod_policy_violation").ForError(fmt.Errorf("pod %s violates the pod policy: %s", pod.Name, strings.Join(violations, "; ")))
+	s.subLock.Lock()
+	defer s.subLock.Unlock()
+	s.subTests = append(s.subTests, &junit.TestCase{
+		Name:          fmt.Sprintf("%s - %s pod policy", s.Description(), pod.Name),
+		FailureOutput: &junit.FailureOutput{Output: err.Error()},
+	})
+	return err
+}
@@ -0,0 +1,73 @@
+package multi_stage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	imagev1 "github.com/openshift/api/image/v1"
+
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/results"
+)
+
+// stepImages returns the image stream tags run by the containers of the steps
+// and observers of the test, with the names of the steps which use each.
+func (s *multiStageTestStep) stepImages() map[string]sets.Set[string] {
+	ret := map[string]sets.Set[string]{}
+	add := func(image, step string) {
+		if ret[image] == nil {
+			ret[image] = sets.New[string]()
+		}
+		ret[image].Insert(step)
+	}
+	steps := s.allSteps()
+	for _, observer := range s.observers {
+		steps = append(steps, api.LiteralTestStep{As: observer.Name, From: observer.From, FromImage: observer.FromImage})
+	}
+	for _, step := range steps {
+		if isUpgradeStep(step) {
+			continue
+		}
+		claimRelease := s.claimReleaseFor(step.As)
+		add(s.stepImage(step, claimRelease), step.As)
+		if step.Cli != "" {
+			add(s.dependencyImage(fmt.Sprintf("%s:cli", api.ReleaseStreamFor(step.Cli)), claimRelease), step.As)
+		}
+		for _, sidecar := range step.Sidecars {
+			add(s.dependencyImage(sidecar.From, claimRelease), step.As)
+		}
+	}
+	return ret
+}
+
+// checkStepImages verifies that the images of all steps were imported into
+// the namespace before any step runs, so that a test which references an
+// image which does not exist fails immediately instead of once the step is
+// reached.  All missing images are reported together.
+func (s *multiStageTestStep) checkStepImages(ctx context.Context) error {
+	images := s.stepImages()
+	var missing []string
+	for _, image := range sets.List(sets.KeySet(images)) {
+		users := strings.Join(sets.List(images[image]), ", ")
+		ist := &imagev1.ImageStreamTag{}
+		if err := s.client.Get(ctx, ctrlruntimeclient.ObjectKey{Namespace: s.jobSpec.Namespace(), Name: image}, ist); err != nil {
+			if !kerrors.IsNotFound(err) {
+				return fmt.Errorf("failed to get image %s: %w", image, err)
+			}
+			missing = append(missing, fmt.Sprintf("%s (used by %s): not found", image, users))
+			continue
+		}
+		if ist.Image.DockerImageReference == "" {
+			missing = append(missing, fmt.Sprintf("%s (used by %s): not imported", image, users))
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	return &results.DependencyError{Err: results.ForReason("missing_step_images").ForError(fmt.Errorf("%d images of the steps of test %s are not available:\n  * %s", len(missing), s.name, strings.Join(missing, "\n  * ")))}
+}
@@ -0,0 +1,85 @@
+package multi_stage
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	imagev1 "github.com/openshift/api/image/v1"
+
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	fakectrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/kubernetes"
+	"github.com/openshift/ci-tools/pkg/results"
+	"github.com/openshift/ci-tools/pkg/steps/loggingclient"
+	"github.com/openshift/ci-tools/pkg/testhelper"
+)
+
+func TestCheckStepImages(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := imagev1.Install(scheme); err != nil {
+		t.Fatal(err)
+	}
+	imported := func(name string) *imagev1.ImageStreamTag {
+		return &imagev1.ImageStreamTag{
+			ObjectMeta: meta.ObjectMeta{Namespace: "ns", Name: name},
+			Image:      imagev1.Image{DockerImageReference: "registry.ci.example.com/ns/" + name},
+		}
+	}
+	pre := []api.LiteralTestStep{{
+		As:       "install",
+		From:     "installer",
+		Cli:      "latest",
+		Sidecars: []api.StepSidecar{{Name: "proxy", From: "src"}},
+	}}
+	test := []api.LiteralTestStep{
+		{As: "e2e", From: "src"},
+		{As: "upgrade", Upgrade: &api.UpgradeStep{Release: "latest"}},
+	}
+	for _, tc := range []struct {
+		name    string
+		objects []ctrlruntimeclient.Object
+		err     error
+	}{{
+		name: "all images exist",
+		objects: []ctrlruntimeclient.Object{
+			imported("pipeline:src"),
+			imported("stable:installer"),
+			imported("stable:cli"),
+			imported("stable:monitor"),
+		},
+	}, {
+		name: "missing images are reported together",
+		objects: []ctrlruntimeclient.Object{
+			imported("pipeline:src"),
+			&imagev1.ImageStreamTag{ObjectMeta: meta.ObjectMeta{Namespace: "ns", Name: "stable:installer"}},
+		},
+		err: errors.New(`3 images of the steps of test e2e are not available:
+  * stable:cli (used by install): not found
+  * stable:installer (used by install): not imported
+  * stable:monitor (used by monitor): not found`),
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			jobSpec := api.JobSpec{}
+			jobSpec.SetNamespace("ns")
+			s := multiStageTestStep{
+				name:      "e2e",
+				config:    &api.ReleaseBuildConfiguration{},
+				jobSpec:   &jobSpec,
+				pre:       pre,
+				test:      test,
+				observers: []api.Observer{{Name: "monitor", From: "monitor"}},
+				client:    kubernetes.NewPodClient(loggingclient.New(fakectrlruntimeclient.NewClientBuilder().WithScheme(scheme).WithObjects(tc.objects...).Build()), nil, nil, 0),
+			}
+			err := s.checkStepImages(context.Background())
+			testhelper.Diff(t, "error", err, tc.err, testhelper.EquateErrorMessage)
+			if err != nil {
+				testhelper.Diff(t, "kind", results.KindString(err), string(results.KindDependency))
+			}
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package multi_stage
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/sirupsen/logrus"
+
+	coreapi "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/openshift/ci-tools/pkg/api"
+)
+
+const (
+	// PreviousJobArtifactsURLEnv exposes the URL of the artifact directory of
+	// the last successful run of the job.
+	PreviousJobArtifactsURLEnv = "PREVIOUS_JOB_ARTIFACTS_URL"
+	// PreviousJobArtifactsDirEnv exposes the directory where the files fetched
+	// from the previous run are mounted.
+	PreviousJobArtifactsDirEnv = "PREVIOUS_JOB_ARTIFACTS_DIR"
+	// PreviousJobArtifactsMountPath is where the files fetched from the
+	// previous run are mounted.
+	PreviousJobArtifactsMountPath = "/var/run/ci.openshift.io/previous-job"
+	// maxPreviousJobFilesSize bounds the total size of the files fetched for
+	// a step, which must fit in a secret.
+	maxPreviousJobFilesSize = 1000 * 1000
+)
+
+// PreviousJobFinder locates the artifacts of the last successful run of the
+// job.
+type PreviousJobFinder interface {
+	// LastSuccessful returns the URL of the artifact directory of the run,
+	// or an empty string if there is none.
+	LastSuccessful(ctx context.Context) (string, error)
+	// Fetch downloads a file from an artifact directory.
+	Fetch(ctx context.Context, artifactsURL, file string) ([]byte, error)
+}
+
+func previousJobSecretName(testName, stepName string) string {
+	return fmt.Sprintf("%s-%s-previous-job", testName, stepName)
+}
+
+// resolvePreviousJob locates the previous run of the job for the steps which
+// access its artifacts and fetches the files they request.  Steps are
+// expected to handle the absence of a baseline, so failures are not fatal.
+func (s *multiStageTestStep) resolvePreviousJob(ctx context.Context) error {
+	var steps []api.LiteralTestStep
+	for _, step := range s.allSteps() {
+		if step.PreviousJobArtifacts != nil {
+			steps = append(steps, step)
+		}
+	}
+	if len(steps) == 0 || s.options.PreviousJob == nil {
+		return nil
+	}
+	url, err := s.options.PreviousJob.LastSuccessful(ctx)
+	if err != nil {
+		logrus.WithError(err).Warnf("Failed to locate the previous run of the job for test %s", s.name)
+		return nil
+	}
+	if url == "" {
+		logrus.Infof("No previous successful run of the job was found for test %s.", s.name)
+		return nil
+	}
+	logrus.Infof("Using the artifacts of the previous run of the job at %s.", url)
+	s.previousJobURL = url
+	s.previousJobFiles = map[string][]coreapi.KeyToPath{}
+	for _, step := range steps {
+		data := map[string][]byte{}
+		var items []coreapi.KeyToPath
+		var size int
+		for _, file := range step.PreviousJobArtifacts.Files {
+			raw, err := s.options.PreviousJob.Fetch(ctx, url, file)
+			if err != nil {
+				logrus.WithError(err).Warnf("Failed to fetch %s from the previous run of the job for step %s", file, step.As)
+				continue
+			}
+			if size += len(raw); size > maxPreviousJobFilesSize {
+				logrus.Warnf("Skipping %s from the previous run of the job for step %s: the files exceed %d bytes", file, step.As, maxPreviousJobFilesSize)
+				size -= len(raw)
+				continue
+			}
+			key := "file-" + strconv.Itoa(len(items))
+			data[key] = raw
+			items = append(items, coreapi.KeyToPath{Key: key, Path: file})
+		}
+		if len(items) == 0 {
+			continue
+		}
+		secret := &coreapi.Secret{
+			ObjectMeta: meta.ObjectMeta{
+				Namespace:       s.jobSpec.Namespace(),
+				Name:            previousJobSecretName(s.name, step.As),
+				Labels:          map[string]string{api.SkipCensoringLabel: "true"},
+				OwnerReferences: s.ownerReferences(),
+			},
+			Data: data,
+		}
+		s.addMetadata(secret)
+		if err := s.client.Delete(ctx, secret); err != nil && !kerrors.IsNotFound(err) {
+			return fmt.Errorf("cannot delete previous job artifacts %q: %w", secret.Name, err)
+		}
+		if err := s.client.Create(ctx, secret); err != nil {
+			return fmt.Errorf("cannot create previous job artifacts %q: %w", secret.Name, err)
+		}
+		s.previousJobFiles[step.As] = items
+	}
+	return nil
+}
+
+// addPreviousJobArtifacts exposes the artifacts of the previous run of the
+// job to the pod of a step which requests them.
+func (s *multiStageTestStep) addPreviousJobArtifacts(step *api.LiteralTestStep, pod *coreapi.Pod) {
+	if step.PreviousJobArtifacts == nil || s.previousJobURL == "" {
+		return
+	}
+	container := &pod.Spec.Containers[0]
+	container.Env = append(container.Env, coreapi.EnvVar{Name: PreviousJobArtifactsURLEnv, Value: s.previousJobURL})
+	items, ok := s.previousJobFiles[step.As]
+	if !ok {
+		return
+	}
+	volumeName := "previous-job-artifacts"
+	pod.Spec.Volumes = append(pod.Spec.Volumes, coreapi.Volume{
+		Name: volumeName,
+		VolumeSource: coreapi.VolumeSource{
+			Secret: &coreapi.SecretVolumeSource{SecretName: previousJobSecretName(s.name, step.As), Items: items},
+		},
+	})
+	container.VolumeMounts = append(container.VolumeMounts, coreapi.VolumeMount{
+		Name:      volumeName,
+		MountPath: PreviousJobArtifactsMountPath,
+		ReadOnly:  true,
+	})
+	container.Env = append(container.Env, coreapi.EnvVar{Name: PreviousJobArtifactsDirEnv, Value: PreviousJobArtifactsMountPath})
+}
@@ -0,0 +1,101 @@
+package multi_stage
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	coreapi "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	fakectrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/steps/loggingclient"
+	"github.com/openshift/ci-tools/pkg/testhelper"
+	testhelper_kube "github.com/openshift/ci-tools/pkg/testhelper/kubernetes"
+)
+
+type fakePreviousJobFinder struct {
+	url   string
+	files map[string]string
+}
+
+func (f *fakePreviousJobFinder) LastSuccessful(context.Context) (string, error) {
+	return f.url, nil
+}
+
+func (f *fakePreviousJobFinder) Fetch(_ context.Context, url, file string) ([]byte, error) {
+	if content, ok := f.files[url+"/"+file]; ok {
+		return []byte(content), nil
+	}
+	return nil, errors.New("not found")
+}
+
+func TestPreviousJobArtifacts(t *testing.T) {
+	url := "https://storage.googleapis.com/bucket/logs/job/100/artifacts"
+	for _, tc := range []struct {
+		name            string
+		finder          PreviousJobFinder
+		expectedEnv     []coreapi.EnvVar
+		expectedVolumes []coreapi.Volume
+		expectedData    map[string][]byte
+	}{{
+		name: "no finder",
+	}, {
+		name:   "no previous run",
+		finder: &fakePreviousJobFinder{},
+	}, {
+		name: "files are fetched from the previous run",
+		finder: &fakePreviousJobFinder{url: url, files: map[string]string{
+			url + "/perf/results.json": "{}",
+		}},
+		expectedEnv: []coreapi.EnvVar{
+			{Name: PreviousJobArtifactsURLEnv, Value: url},
+			{Name: PreviousJobArtifactsDirEnv, Value: PreviousJobArtifactsMountPath},
+		},
+		expectedVolumes: []coreapi.Volume{{
+			Name: "previous-job-artifacts",
+			VolumeSource: coreapi.VolumeSource{Secret: &coreapi.SecretVolumeSource{
+				SecretName: "test-perf-previous-job",
+				Items:      []coreapi.KeyToPath{{Key: "file-0", Path: "perf/results.json"}},
+			}},
+		}},
+		expectedData: map[string][]byte{"file-0": []byte("{}")},
+	}, {
+		name:        "no file exists in the previous run",
+		finder:      &fakePreviousJobFinder{url: url},
+		expectedEnv: []coreapi.EnvVar{{Name: PreviousJobArtifactsURLEnv, Value: url}},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			jobSpec := api.JobSpec{}
+			jobSpec.SetNamespace("ns")
+			client := &testhelper_kube.FakePodClient{FakePodExecutor: &testhelper_kube.FakePodExecutor{
+				LoggingClient: loggingclient.New(fakectrlruntimeclient.NewClientBuilder().Build()),
+			}}
+			step := api.LiteralTestStep{
+				As:                   "perf",
+				PreviousJobArtifacts: &api.PreviousJobArtifacts{Files: []string{"perf/results.json", "missing.json"}},
+			}
+			s := multiStageTestStep{
+				name:    "test",
+				test:    []api.LiteralTestStep{{As: "e2e"}, step},
+				jobSpec: &jobSpec,
+				client:  client,
+				options: Options{PreviousJob: tc.finder},
+			}
+			if err := s.resolvePreviousJob(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			pod := &coreapi.Pod{Spec: coreapi.PodSpec{Containers: []coreapi.Container{{Name: containerName}}}}
+			s.addPreviousJobArtifacts(&step, pod)
+			testhelper.Diff(t, "environment", pod.Spec.Containers[0].Env, tc.expectedEnv)
+			testhelper.Diff(t, "volumes", pod.Spec.Volumes, tc.expectedVolumes)
+			secret := &coreapi.Secret{ObjectMeta: meta.ObjectMeta{Namespace: "ns", Name: "test-perf-previous-job"}}
+			if err := client.Get(context.Background(), ctrlruntimeclient.ObjectKeyFromObject(secret), secret); err != nil && tc.expectedData != nil {
+				t.Fatal(err)
+			}
+			testhelper.Diff(t, "secret data", secret.Data, tc.expectedData)
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package multi_stage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+
+	"github.com/sirupsen/logrus"
+
+	coreapi "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/openshift/ci-tools/pkg/util"
+)
+
+const (
+	// ClusterProfileHashAnnotation holds the checksum of the contents of the
+	// copy of the cluster profile used by a test.
+	ClusterProfileHashAnnotation = "ci.openshift.io/cluster-profile-hash"
+	// profileOverridesSuffix is the suffix of the name of the secret, e.g.
+	// imported with --secret-dir, whose keys replace those of the cluster
+	// profile for a single test.
+	profileOverridesSuffix = "-cluster-profile-overrides"
+	// profileHashLength is the length of the checksum in the name of the
+	// copy of the cluster profile.
+	profileHashLength = 10
+)
+
+// profileHash returns the checksum of the contents of a cluster profile.
+func profileHash(data map[string][]byte) string {
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	h := sha256.New()
+	for _, k := range keys {
+		fmt.Fprintf(h, "%d:%s%d:", len(k), k, len(data[k]))
+		h.Write(data[k])
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// copyProfile copies the cluster profile, with the overrides of the test if
+// any, into a secret owned by the test.  The secret of the profile is shared
+// by the tests of the job which use it, while the copy is immutable and named
+// after its contents, so tests running at the same time never observe each
+// other's changes.  It returns the copy, which is mounted in the steps.
+func (s *multiStageTestStep) copyProfile(ctx context.Context, profile *coreapi.Secret) (*coreapi.Secret, error) {
+	data := make(map[string][]byte, len(profile.Data))
+	for k, v := range profile.Data {
+		data[k] = v
+	}
+	overrides := &coreapi.Secret{}
+	name := s.name + profileOverridesSuffix
+	if err := s.client.Get(ctx, ctrlruntimeclient.ObjectKey{Namespace: s.jobSpec.Namespace(), Name: name}, overrides); err != nil {
+		if !kerrors.IsNotFound(err) {
+			return nil, fmt.Errorf("could not get cluster profile overrides %q: %w", name, err)
+		}
+	} else {
+		logrus.Infof("Overriding %d keys of the cluster profile of test %s with secret %s.", len(overrides.Data), s.name, name)
+		for k, v := range overrides.Data {
+			data[k] = v
+		}
+	}
+	hash := profileHash(data)
+	immutable := true
+	secret := &coreapi.Secret{
+		ObjectMeta: meta.ObjectMeta{
+			Namespace:       s.jobSpec.Namespace(),
+			Name:            fmt.Sprintf("%s-cluster-profile-%s", s.name, hash[:profileHashLength]),
+			Labels:          s.scrubLabels(),
+			Annotations:     map[string]string{ClusterProfileHashAnnotation: hash},
+			OwnerReferences: s.ownerReferences(),
+		},
+		Type:      profile.Type,
+		Data:      data,
+		Immutable: &immutable,
+	}
+	s.addMetadata(secret)
+	logrus.Debugf("Copying cluster profile secret %q to %q", profile.Name, secret.Name)
+	if err := s.client.Create(ctx, secret); err != nil {
+		if !kerrors.IsAlreadyExists(err) {
+			return nil, fmt.Errorf("could not copy cluster profile secret %q: %w", profile.Name, err)
+		}
+		if err := util.AddScrubOwner(ctx, s.client, ctrlruntimeclient.ObjectKeyFromObject(secret), s.jobSpec.ScrubOwner()); err != nil {
+			return nil, fmt.Errorf("could not claim cluster profile secret %q: %w", secret.Name, err)
+		}
+	}
+	return secret, nil
+}
@@ -0,0 +1,86 @@
+package multi_stage
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	coreapi "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	fakectrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/kubernetes"
+	"github.com/openshift/ci-tools/pkg/steps/loggingclient"
+	"github.com/openshift/ci-tools/pkg/steps/profileschema"
+	"github.com/openshift/ci-tools/pkg/testhelper"
+)
+
+func TestGetProfileDataCopiesProfile(t *testing.T) {
+	ctx := context.Background()
+	jobSpec := api.JobSpec{}
+	jobSpec.SetNamespace("ns")
+	client := kubernetes.NewPodClient(loggingclient.New(fakectrlruntimeclient.NewClientBuilder().WithObjects(
+		&coreapi.Secret{
+			ObjectMeta: meta.ObjectMeta{Namespace: "ns", Name: "e2e-cluster-profile"},
+			Data:       map[string][]byte{"base-domain": []byte("ci.example.com"), "ssh-publickey": []byte("key")},
+		},
+		&coreapi.Secret{
+			ObjectMeta: meta.ObjectMeta{Namespace: "ns", Name: "e2e-upgrade-cluster-profile-overrides"},
+			Data:       map[string][]byte{"base-domain": []byte("upgrade.example.com")},
+		},
+	).Build()), nil, nil, 0)
+	for _, tc := range []struct {
+		name, suffix string
+		copyName     string
+		data         map[string][]byte
+	}{{
+		name:     "e2e",
+		copyName: "e2e-cluster-profile-6a9ba16577",
+		data:     map[string][]byte{"base-domain": []byte("ci.example.com"), "ssh-publickey": []byte("key")},
+	}, {
+		name:     "e2e-upgrade",
+		suffix:   "upgrade",
+		copyName: "e2e-upgrade-cluster-profile-1465004ae1",
+		data:     map[string][]byte{"base-domain": []byte("upgrade.example.com"), "ssh-publickey": []byte("key")},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			s := multiStageTestStep{name: tc.name, additionalSuffix: tc.suffix, profile: api.ClusterProfileAWS, jobSpec: &jobSpec, client: client}
+			// copying the profile again, e.g. when the test is retried, reuses the copy
+			for i := 0; i < 2; i++ {
+				if err := s.getProfileData(ctx); err != nil {
+					t.Fatal(err)
+				}
+			}
+			testhelper.Diff(t, "mounted secret", s.mountedProfileSecretName(), tc.copyName)
+			secret := &coreapi.Secret{}
+			if err := client.Get(ctx, ctrlruntimeclient.ObjectKey{Namespace: "ns", Name: tc.copyName}, secret); err != nil {
+				t.Fatalf("failed to get the copy of the profile: %v", err)
+			}
+			testhelper.Diff(t, "data", secret.Data, tc.data)
+			testhelper.Diff(t, "hash", secret.Annotations[ClusterProfileHashAnnotation][:profileHashLength], tc.copyName[len(tc.copyName)-profileHashLength:])
+			if secret.Immutable == nil || !*secret.Immutable {
+				t.Error("expected the copy of the profile to be immutable")
+			}
+		})
+	}
+}
+
+func TestGetProfileDataVerifiesSchema(t *testing.T) {
+	jobSpec := api.JobSpec{}
+	jobSpec.SetNamespace("ns")
+	client := kubernetes.NewPodClient(loggingclient.New(fakectrlruntimeclient.NewClientBuilder().WithObjects(
+		&coreapi.Secret{
+			ObjectMeta: meta.ObjectMeta{Namespace: "ns", Name: "e2e-cluster-profile"},
+			Data:       map[string][]byte{"ssh-publickey": []byte("key")},
+		},
+	).Build()), nil, nil, 0)
+	registry := &profileschema.Registry{Kinds: map[string]profileschema.Schema{
+		"aws": {Keys: map[string]profileschema.Key{".awscred": {Required: true, Type: profileschema.TypeINI}}},
+	}}
+	s := multiStageTestStep{name: "e2e", profile: api.ClusterProfileAWS, jobSpec: &jobSpec, client: client, options: Options{ProfileSchemas: registry}}
+	err := s.getProfileData(context.Background())
+	testhelper.Diff(t, "error", err, errors.New(`cluster profile secret "e2e-cluster-profile" does not match the schema of profile aws: key ".awscred" is missing`), testhelper.EquateErrorMessage)
+	testhelper.Diff(t, "mounted secret", s.mountedProfileSecretName(), "e2e-cluster-profile")
+}
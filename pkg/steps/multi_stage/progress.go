@@ -0,0 +1,61 @@
+package multi_stage
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	coreapi "k8s.io/api/core/v1"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/openshift/ci-tools/pkg/kubernetes"
+	"github.com/openshift/ci-tools/pkg/steps/progress"
+)
+
+// progressRetryInterval is how often the output of a step is requested until
+// its container has started.
+const progressRetryInterval = 10 * time.Second
+
+// watchProgress follows the output of the pod of a step until its container
+// exits and reports the progress markers it prints.
+func watchProgress(ctx context.Context, client kubernetes.PodClient, namespace, name string) {
+	for {
+		stream, err := client.GetLogs(namespace, name, &coreapi.PodLogOptions{Container: containerName, Follow: true}).Stream(ctx)
+		if err == nil {
+			err = progress.Scan(stream, func(p progress.Progress) { reportProgress(ctx, client, namespace, name, p) })
+			if closeErr := stream.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil && ctx.Err() == nil {
+				logrus.WithError(err).Debugf("Failed to follow the output of step %s.", name)
+			}
+			return
+		}
+		logrus.WithError(err).Debugf("Waiting for the output of step %s.", name)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(progressRetryInterval):
+		}
+	}
+}
+
+// reportProgress reports the progress of a step in the output and in the
+// annotation of its pod, where tooling can observe it while the step runs.
+func reportProgress(ctx context.Context, client ctrlruntimeclient.Client, namespace, name string, p progress.Progress) {
+	logrus.Infof("Step %s: %s", name, p)
+	pod := &coreapi.Pod{}
+	if err := client.Get(ctx, ctrlruntimeclient.ObjectKey{Namespace: namespace, Name: name}, pod); err != nil {
+		logrus.WithError(err).Debugf("Failed to get pod %s to record its progress.", name)
+		return
+	}
+	original := pod.DeepCopy()
+	if pod.Annotations == nil {
+		pod.Annotations = map[string]string{}
+	}
+	pod.Annotations[progress.Annotation] = p.String()
+	if err := client.Patch(ctx, pod, ctrlruntimeclient.MergeFrom(original)); err != nil {
+		logrus.WithError(err).Debugf("Failed to record the progress of pod %s.", name)
+	}
+}
@@ -0,0 +1,49 @@
+package multi_stage
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	coreapi "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	fakerest "k8s.io/client-go/rest/fake"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	fakectrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/openshift/ci-tools/pkg/kubernetes"
+	"github.com/openshift/ci-tools/pkg/steps/loggingclient"
+	"github.com/openshift/ci-tools/pkg/steps/progress"
+	"github.com/openshift/ci-tools/pkg/testhelper"
+)
+
+func TestWatchProgress(t *testing.T) {
+	// the output is requested again if it cannot be streamed, until the
+	// test times out
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	output := "level=info msg=Creating infrastructure resources...\n##ci-progress: creating infrastructure 10\n##ci-progress: waiting for bootstrap 40\nlevel=info msg=Waiting...\n"
+	var requested string
+	rest := &fakerest.RESTClient{
+		NegotiatedSerializer: scheme.Codecs.WithoutConversion(),
+		GroupVersion:         coreapi.SchemeGroupVersion,
+		Client: fakerest.CreateHTTPClient(func(req *http.Request) (*http.Response, error) {
+			requested = req.URL.RequestURI()
+			return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(output))}, nil
+		}),
+	}
+	client := kubernetes.NewPodClient(loggingclient.New(fakectrlruntimeclient.NewClientBuilder().WithObjects(
+		&coreapi.Pod{ObjectMeta: meta.ObjectMeta{Namespace: "ns", Name: "e2e-ipi-install"}},
+	).Build()), nil, rest, 0)
+	watchProgress(ctx, client, "ns", "e2e-ipi-install")
+	testhelper.Diff(t, "request", requested, "/namespaces/ns/pods/e2e-ipi-install/log?container=test&follow=true")
+	pod := &coreapi.Pod{}
+	if err := client.Get(ctx, ctrlruntimeclient.ObjectKey{Namespace: "ns", Name: "e2e-ipi-install"}, pod); err != nil {
+		t.Fatal(err)
+	}
+	testhelper.Diff(t, "progress", pod.Annotations[progress.Annotation], "40% waiting for bootstrap")
+}
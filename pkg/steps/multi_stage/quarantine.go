@@ -0,0 +1,150 @@
+package multi_stage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	coreapi "k8s.io/api/core/v1"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/junit"
+	base_steps "github.com/openshift/ci-tools/pkg/steps"
+	"github.com/openshift/ci-tools/pkg/steps/testresults"
+)
+
+// QuarantineArtifact is the name of the artifact file, relative to the test's
+// artifact directory, which records the waivers used by the test.
+const QuarantineArtifact = "quarantine.json"
+
+// waiverUse records a failure waived by the quarantine list.
+type waiverUse struct {
+	Step     string `json:"step"`
+	TestCase string `json:"test_case,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+	Expires  string `json:"expires,omitempty"`
+	// StepFailureWaived is set when the step failed only because of
+	// quarantined test cases and its failure was ignored.
+	StepFailureWaived bool `json:"step_failure_waived,omitempty"`
+}
+
+// activeQuarantine returns the waivers of the configuration which have not
+// expired, by test case name.
+func (s *multiStageTestStep) activeQuarantine(now time.Time) map[string]api.QuarantinedTestCase {
+	ret := map[string]api.QuarantinedTestCase{}
+	if s.config == nil {
+		return ret
+	}
+	for _, q := range s.config.Quarantine {
+		if q.Active(now) {
+			ret[q.Name] = q
+		} else {
+			logrus.Warnf("The quarantine of test case %q expired on %s, its failures are no longer waived", q.Name, q.Expires)
+		}
+	}
+	return ret
+}
+
+// waiveQuarantinedFailure determines whether the failure of a step pod is
+// waived because it was caused only by quarantined test cases.
+func (s *multiStageTestStep) waiveQuarantinedFailure(pod *coreapi.Pod) bool {
+	if pod.Status.Phase != coreapi.PodFailed || pod.Status.Reason == "DeadlineExceeded" || len(oomKilledContainers(pod)) != 0 {
+		return false
+	}
+	quarantine := s.activeQuarantine(time.Now())
+	if len(quarantine) == 0 {
+		return false
+	}
+	waived, err := s.onlyQuarantinedFailures(base_steps.CleanupCtx, strings.TrimPrefix(pod.Name, s.name+"-"), quarantine)
+	if err != nil {
+		logrus.WithError(err).Warnf("Failed to determine whether the failure of step %s is quarantined", pod.Name)
+		return false
+	}
+	if waived {
+		logrus.Infof("Step %s failed only in quarantined test cases, ignoring the failure.", pod.Name)
+	}
+	return waived
+}
+
+// onlyQuarantinedFailures determines whether all failures reported by a step
+// in its jUnit results are quarantined.  Steps which did not report any
+// failed test case are not considered, as they failed for some other reason.
+func (s *multiStageTestStep) onlyQuarantinedFailures(ctx context.Context, step string, quarantine map[string]api.QuarantinedTestCase) (bool, error) {
+	secret := &coreapi.Secret{}
+	name := testresults.SecretName(s.name)
+	if err := s.client.Get(ctx, ctrlruntimeclient.ObjectKey{Namespace: s.jobSpec.Namespace(), Name: name}, secret); err != nil {
+		return false, fmt.Errorf("failed to get results secret %s: %w", name, err)
+	}
+	data, ok := secret.Data[step]
+	if !ok {
+		return false, nil
+	}
+	suites, err := testresults.Decode(data)
+	if err != nil {
+		return false, err
+	}
+	var failed int
+	for _, test := range suiteTestCases(suites.Suites) {
+		if test.FailureOutput == nil {
+			continue
+		}
+		if _, ok := quarantine[test.Name]; !ok {
+			return false, nil
+		}
+		failed++
+	}
+	return failed != 0, nil
+}
+
+// applyQuarantine reports the quarantined failures of a suite and its
+// children as skipped, updating their counts.
+func applyQuarantine(step string, suite *junit.TestSuite, quarantine map[string]api.QuarantinedTestCase) []waiverUse {
+	var ret []waiverUse
+	for _, test := range suite.TestCases {
+		q, ok := quarantine[test.Name]
+		if !ok || test.FailureOutput == nil {
+			continue
+		}
+		skip(test, fmt.Sprintf("quarantined until %s: %s", q.Expires, q.Reason))
+		test.Properties = append(test.Properties, &junit.TestSuiteProperty{Name: "quarantined", Value: q.Reason})
+		if suite.NumFailed != 0 {
+			suite.NumFailed--
+		}
+		suite.NumSkipped++
+		ret = append(ret, waiverUse{Step: step, TestCase: test.Name, Reason: q.Reason, Expires: q.Expires})
+	}
+	for _, child := range suite.Children {
+		ret = append(ret, applyQuarantine(step, child, quarantine)...)
+	}
+	return ret
+}
+
+// skip reports a failed test case as skipped, keeping the failure output.
+func skip(test *junit.TestCase, message string) {
+	test.SkipMessage = &junit.SkipMessage{Message: message}
+	if output := test.FailureOutput.Output; output != "" {
+		test.SystemErr = strings.TrimPrefix(test.SystemErr+"\n"+output, "\n")
+	}
+	test.FailureOutput = nil
+}
+
+// saveQuarantineReport writes the waivers used by the test to its artifact
+// directory.
+func (s *multiStageTestStep) saveQuarantineReport() error {
+	s.subLock.Lock()
+	defer s.subLock.Unlock()
+	if len(s.waivers) == 0 {
+		return nil
+	}
+	data, err := json.MarshalIndent(s.waivers, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal quarantine report: %w", err)
+	}
+	return api.SaveArtifact(s.censor, path.Join(s.name, QuarantineArtifact), data)
+}
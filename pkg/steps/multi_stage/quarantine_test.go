@@ -0,0 +1,126 @@
+package multi_stage
+
+import (
+	"context"
+	"encoding/xml"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+
+	coreapi "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakectrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/junit"
+	"github.com/openshift/ci-tools/pkg/steps/loggingclient"
+	"github.com/openshift/ci-tools/pkg/steps/testresults"
+	testhelper_kube "github.com/openshift/ci-tools/pkg/testhelper/kubernetes"
+)
+
+func quarantineStep(t *testing.T, results map[string]*junit.TestSuite) *multiStageTestStep {
+	secret := &coreapi.Secret{
+		ObjectMeta: meta.ObjectMeta{Namespace: "ns", Name: "test-junit"},
+		Data:       map[string][]byte{},
+	}
+	for step, suite := range results {
+		raw, err := testresults.Encode(&junit.TestSuites{Suites: []*junit.TestSuite{suite}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		secret.Data[step] = raw
+	}
+	jobSpec := api.JobSpec{}
+	jobSpec.SetNamespace("ns")
+	return &multiStageTestStep{
+		name:    "test",
+		jobSpec: &jobSpec,
+		config: &api.ReleaseBuildConfiguration{Quarantine: []api.QuarantinedTestCase{
+			{Name: "flaky", Reason: "flakes", Expires: time.Now().AddDate(0, 0, 1).Format(api.QuarantineExpiryFormat)},
+			{Name: "expired", Reason: "used to flake", Expires: "2020-01-01"},
+		}},
+		client:  &testhelper_kube.FakePodClient{FakePodExecutor: &testhelper_kube.FakePodExecutor{LoggingClient: loggingclient.New(fakectrlruntimeclient.NewClientBuilder().WithObjects(secret).Build())}},
+		subLock: &sync.Mutex{},
+	}
+}
+
+func failedCase(name string) *junit.TestCase {
+	return &junit.TestCase{Name: name, FailureOutput: &junit.FailureOutput{Output: "failed"}}
+}
+
+func TestWaiveQuarantinedFailure(t *testing.T) {
+	s := quarantineStep(t, map[string]*junit.TestSuite{
+		"quarantined": {TestCases: []*junit.TestCase{failedCase("flaky"), {Name: "passes"}}},
+		"expired":     {TestCases: []*junit.TestCase{failedCase("flaky"), failedCase("expired")}},
+		"passed":      {TestCases: []*junit.TestCase{{Name: "flaky"}}},
+	})
+	for _, tc := range []struct {
+		name     string
+		pod      coreapi.Pod
+		expected bool
+	}{{
+		name:     "only quarantined failures",
+		pod:      coreapi.Pod{ObjectMeta: meta.ObjectMeta{Name: "test-quarantined"}, Status: coreapi.PodStatus{Phase: coreapi.PodFailed}},
+		expected: true,
+	}, {
+		name: "expired waiver",
+		pod:  coreapi.Pod{ObjectMeta: meta.ObjectMeta{Name: "test-expired"}, Status: coreapi.PodStatus{Phase: coreapi.PodFailed}},
+	}, {
+		name: "no failed test cases",
+		pod:  coreapi.Pod{ObjectMeta: meta.ObjectMeta{Name: "test-passed"}, Status: coreapi.PodStatus{Phase: coreapi.PodFailed}},
+	}, {
+		name: "no results",
+		pod:  coreapi.Pod{ObjectMeta: meta.ObjectMeta{Name: "test-missing"}, Status: coreapi.PodStatus{Phase: coreapi.PodFailed}},
+	}, {
+		name: "timeout",
+		pod:  coreapi.Pod{ObjectMeta: meta.ObjectMeta{Name: "test-quarantined"}, Status: coreapi.PodStatus{Phase: coreapi.PodFailed, Reason: "DeadlineExceeded"}},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			if waived := s.waiveQuarantinedFailure(&tc.pod); waived != tc.expected {
+				t.Errorf("expected waived to be %t, got %t", tc.expected, waived)
+			}
+		})
+	}
+}
+
+func TestCollectResultsQuarantine(t *testing.T) {
+	s := quarantineStep(t, map[string]*junit.TestSuite{
+		"e2e": {
+			Name:      "e2e",
+			NumTests:  3,
+			NumFailed: 2,
+			TestCases: []*junit.TestCase{failedCase("flaky"), failedCase("expired"), {Name: "passes"}},
+		},
+	})
+	if err := s.collectResults(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []*junit.TestSuite{{
+		Name:       "Run multi-stage test test - test-e2e",
+		NumTests:   3,
+		NumFailed:  1,
+		NumSkipped: 1,
+		Children: []*junit.TestSuite{{
+			Name:       "e2e",
+			NumTests:   3,
+			NumFailed:  1,
+			NumSkipped: 1,
+			TestCases: []*junit.TestCase{{
+				Name:        "flaky",
+				Properties:  []*junit.TestSuiteProperty{{Name: "quarantined", Value: "flakes"}},
+				SkipMessage: &junit.SkipMessage{Message: "quarantined until " + s.config.Quarantine[0].Expires + ": flakes"},
+				SystemErr:   "failed",
+			}, failedCase("expired"), {Name: "passes"}},
+		}},
+	}}
+	if diff := cmp.Diff(expected, s.subSuites, cmpopts.IgnoreTypes(xml.Name{})); diff != "" {
+		t.Errorf("unexpected suites: %s", diff)
+	}
+	expectedWaivers := []waiverUse{{Step: "e2e", TestCase: "flaky", Reason: "flakes", Expires: s.config.Quarantine[0].Expires}}
+	if diff := cmp.Diff(expectedWaivers, s.waivers); diff != "" {
+		t.Errorf("unexpected waivers: %s", diff)
+	}
+}
@@ -0,0 +1,80 @@
+package multi_stage
+
+import (
+	coreapi "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/openshift/ci-tools/pkg/api"
+)
+
+// PeakResources returns the largest amount of resources requested by the pods
+// of the test which run in the build farm at the same time.  Steps run one
+// after the other, except for the shards of a step and the steps of a gang,
+// and the observers run for the whole test.  Steps executed on the ephemeral
+// cluster and built-in steps, which have no pod, are not counted.
+func (s *multiStageTestStep) PeakResources() coreapi.ResourceList {
+	peak := coreapi.ResourceList{}
+	for _, phase := range [][]api.LiteralTestStep{s.pre, s.test, s.post, s.rollbacks} {
+		maxResources(peak, phasePeakResources(phase))
+	}
+	for _, observer := range s.observers {
+		addResources(peak, observer.Resources.Requests, 1)
+	}
+	return peak
+}
+
+// phasePeakResources returns the largest amount of resources requested by the
+// pods of a phase which run at the same time.
+func phasePeakResources(steps []api.LiteralTestStep) coreapi.ResourceList {
+	peak := coreapi.ResourceList{}
+	for i := 0; i < len(steps); {
+		group := coreapi.ResourceList{}
+		j := i
+		for ; j < len(steps) && (j == i || (steps[i].Gang != "" && steps[j].Gang == steps[i].Gang)); j++ {
+			addStepResources(group, steps[j])
+		}
+		maxResources(peak, group)
+		i = j
+	}
+	return peak
+}
+
+// addStepResources adds the resources requested by the pods of a step.
+func addStepResources(total coreapi.ResourceList, step api.LiteralTestStep) {
+	if isUpgradeStep(step) || (step.RunOnEphemeralCluster != nil && *step.RunOnEphemeralCluster) {
+		return
+	}
+	pods := 1
+	if step.ShardCount != nil && *step.ShardCount > 1 {
+		pods = *step.ShardCount
+	}
+	addResources(total, step.Resources.Requests, pods)
+	for _, sidecar := range step.Sidecars {
+		addResources(total, sidecar.Resources.Requests, pods)
+	}
+}
+
+// addResources adds n times the requests to the total.  Invalid quantities,
+// which are rejected when the configuration is validated, are ignored.
+func addResources(total coreapi.ResourceList, requests api.ResourceList, n int) {
+	for name, value := range requests {
+		q, err := resource.ParseQuantity(value)
+		if err != nil {
+			continue
+		}
+		sum := total[coreapi.ResourceName(name)]
+		for i := 0; i < n; i++ {
+			sum.Add(q)
+		}
+		total[coreapi.ResourceName(name)] = sum
+	}
+}
+
+// maxResources raises each resource of the peak to its value in the list.
+func maxResources(peak, list coreapi.ResourceList) {
+	for name, q := range list {
+		if current, ok := peak[name]; !ok || q.Cmp(current) > 0 {
+			peak[name] = q
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package multi_stage
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	coreapi "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/openshift/ci-tools/pkg/api"
+)
+
+func TestPeakResources(t *testing.T) {
+	yes, shards := true, 3
+	requests := func(cpu, memory string) api.ResourceRequirements {
+		return api.ResourceRequirements{Requests: api.ResourceList{"cpu": cpu, "memory": memory}}
+	}
+	for _, tc := range []struct {
+		name     string
+		s        multiStageTestStep
+		expected coreapi.ResourceList
+	}{{
+		name:     "no steps",
+		expected: coreapi.ResourceList{},
+	}, {
+		name: "largest step of all phases",
+		s: multiStageTestStep{
+			pre:  []api.LiteralTestStep{{As: "install", Resources: requests("1", "2Gi")}, {As: "wait", Resources: requests("100m", "8Gi")}},
+			test: []api.LiteralTestStep{{As: "e2e", Resources: requests("2", "1Gi")}},
+		},
+		expected: coreapi.ResourceList{coreapi.ResourceCPU: resource.MustParse("2"), coreapi.ResourceMemory: resource.MustParse("8Gi")},
+	}, {
+		name: "shards, gangs, sidecars and observers",
+		s: multiStageTestStep{
+			test: []api.LiteralTestStep{
+				{As: "e2e", Resources: requests("1", "1Gi"), ShardCount: &shards},
+				{As: "server", Gang: "perf", Resources: requests("2", "1Gi"), Sidecars: []api.StepSidecar{{Name: "proxy", Resources: requests("500m", "512Mi")}}},
+				{As: "client", Gang: "perf", Resources: requests("2", "1Gi")},
+				{As: "remote", Resources: requests("16", "64Gi"), RunOnEphemeralCluster: &yes},
+			},
+			observers: []api.Observer{{Name: "monitor", Resources: requests("100m", "256Mi")}},
+		},
+		expected: coreapi.ResourceList{coreapi.ResourceCPU: resource.MustParse("4600m"), coreapi.ResourceMemory: resource.MustParse("3328Mi")},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.expected, tc.s.PeakResources(), cmp.Comparer(func(a, b resource.Quantity) bool { return a.Cmp(b) == 0 })); diff != "" {
+				t.Errorf("unexpected peak resources: %s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package multi_stage
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+
+	utilpointer "k8s.io/utils/pointer"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/util"
+)
+
+// quotaWait is the time for which the creation of a step pod was blocked
+// because the namespace had exhausted its quota.
+type quotaWait struct {
+	pod      string
+	duration time.Duration
+	// finished is when the pod could be created, or creation was given up.
+	finished time.Time
+}
+
+func (s *multiStageTestStep) podQuotaBackoff() util.PodQuotaBackoff {
+	if s.options.PodQuotaBackoff != nil {
+		return *s.options.PodQuotaBackoff
+	}
+	return util.DefaultPodQuotaBackoff
+}
+
+// recordQuotaWait records the time for which the creation of a pod was
+// blocked on quota and returns its entry for the timeline, if any.
+func (s *multiStageTestStep) recordQuotaWait(pod string, blocked time.Duration) []api.CIOperatorStepDetailInfo {
+	if blocked == 0 {
+		return nil
+	}
+	wait := quotaWait{pod: pod, duration: blocked, finished: time.Now()}
+	s.subLock.Lock()
+	s.quotaWaits = append(s.quotaWaits, wait)
+	s.subLock.Unlock()
+	return []api.CIOperatorStepDetailInfo{wait.detail()}
+}
+
+// detail returns the entry of the wait in the timeline of the test.
+func (w quotaWait) detail() api.CIOperatorStepDetailInfo {
+	started := w.finished.Add(-w.duration)
+	return api.CIOperatorStepDetailInfo{
+		StepName:    fmt.Sprintf("%s-quota-wait", w.pod),
+		Description: fmt.Sprintf("Wait for quota to create pod %s", w.pod),
+		StartedAt:   &started,
+		FinishedAt:  &w.finished,
+		Duration:    &w.duration,
+	}
+}
+
+// quotaWaitMetrics returns the time for which the steps were blocked on
+// quota, labeled with the identity of the job.
+func (s *multiStageTestStep) quotaWaitMetrics() []*dto.MetricFamily {
+	s.subLock.Lock()
+	defer s.subLock.Unlock()
+	if len(s.quotaWaits) == 0 {
+		return nil
+	}
+	gauge := dto.MetricType_GAUGE
+	waits := &dto.MetricFamily{
+		Name: utilpointer.String("ci_step_quota_wait_seconds"),
+		Help: utilpointer.String("Time for which the creation of the pod of a multi-stage test step was blocked on the quota of the namespace."),
+		Type: &gauge,
+	}
+	for _, wait := range s.quotaWaits {
+		seconds := wait.duration.Seconds()
+		waits.Metric = append(waits.Metric, &dto.Metric{
+			Label: []*dto.LabelPair{
+				{Name: utilpointer.String("build_id"), Value: utilpointer.String(s.jobSpec.BuildID)},
+				{Name: utilpointer.String("job"), Value: utilpointer.String(s.jobSpec.Job)},
+				{Name: utilpointer.String("step"), Value: utilpointer.String(strings.TrimPrefix(wait.pod, s.name+"-"))},
+				{Name: utilpointer.String("test"), Value: utilpointer.String(s.name)},
+			},
+			Gauge: &dto.Gauge{Value: &seconds},
+		})
+	}
+	return []*dto.MetricFamily{waits}
+}
@@ -0,0 +1,51 @@
+package multi_stage
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	prowdapi "k8s.io/test-infra/prow/pod-utils/downwardapi"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/testhelper"
+	"github.com/openshift/ci-tools/pkg/util"
+)
+
+func TestRecordQuotaWait(t *testing.T) {
+	jobSpec := api.JobSpec{JobSpec: prowdapi.JobSpec{Job: "job", BuildID: "1"}}
+	s := multiStageTestStep{name: "e2e", jobSpec: &jobSpec, subLock: &sync.Mutex{}}
+	if details := s.recordQuotaWait("e2e-install", 0); details != nil {
+		t.Errorf("expected no entry for a pod which was not blocked, got %v", details)
+	}
+	if metrics := s.quotaWaitMetrics(); metrics != nil {
+		t.Errorf("expected no metrics without waits, got %v", metrics)
+	}
+	details := s.recordQuotaWait("e2e-install", 90*time.Second)
+	if len(details) != 1 {
+		t.Fatalf("expected an entry for the wait, got %v", details)
+	}
+	testhelper.Diff(t, "name", details[0].StepName, "e2e-install-quota-wait")
+	testhelper.Diff(t, "description", details[0].Description, "Wait for quota to create pod e2e-install")
+	if started := details[0].FinishedAt.Sub(*details[0].StartedAt); started != 90*time.Second {
+		t.Errorf("expected the wait to last 90s, got %s", started)
+	}
+	metrics := s.quotaWaitMetrics()
+	if len(metrics) != 1 || len(metrics[0].Metric) != 1 {
+		t.Fatalf("expected a single metric, got %v", metrics)
+	}
+	testhelper.Diff(t, "value", metrics[0].Metric[0].Gauge.GetValue(), 90.0)
+	var labels []string
+	for _, l := range metrics[0].Metric[0].Label {
+		labels = append(labels, l.GetName()+"="+l.GetValue())
+	}
+	testhelper.Diff(t, "labels", labels, []string{"build_id=1", "job=job", "step=install", "test=e2e"})
+}
+
+func TestPodQuotaBackoff(t *testing.T) {
+	s := multiStageTestStep{}
+	testhelper.Diff(t, "default", s.podQuotaBackoff(), util.DefaultPodQuotaBackoff)
+	configured := util.PodQuotaBackoff{Initial: time.Second, Max: time.Second}
+	s.options.PodQuotaBackoff = &configured
+	testhelper.Diff(t, "configured", s.podQuotaBackoff(), configured)
+}
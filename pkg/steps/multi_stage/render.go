@@ -0,0 +1,128 @@
+package multi_stage
+
+import (
+	"fmt"
+
+	coreapi "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes/scheme"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	base_steps "github.com/openshift/ci-tools/pkg/steps"
+)
+
+// RenderedObjects are the objects a multi-stage test creates in the test
+// namespace: those shared by all steps and those created for each step,
+// keyed by the name of the step.
+type RenderedObjects struct {
+	Test  []ctrlruntimeclient.Object
+	Steps map[string][]ctrlruntimeclient.Object
+}
+
+// renderParameters replaces the parameters of the test, which are only known
+// once the images and releases it uses are resolved, with placeholders.
+type renderParameters struct{}
+
+func (renderParameters) Has(string) bool      { return true }
+func (renderParameters) HasInput(string) bool { return false }
+func (renderParameters) Get(name string) (string, error) {
+	return renderPlaceholder(name), nil
+}
+
+func renderPlaceholder(name string) string {
+	return fmt.Sprintf("${%s}", name)
+}
+
+// RenderObjects returns the objects a resolved multi-stage test would create,
+// without executing it, so they can be reviewed before the test is allowed to
+// run on a cluster.  Values which are only known at runtime, e.g. the pull
+// specifications of images, the contents of credentials and the secrets of
+// collections of credentials, are replaced with placeholders.
+func RenderObjects(testConfig api.TestStepConfiguration, config *api.ReleaseBuildConfiguration, jobSpec *api.JobSpec, options Options) (*RenderedObjects, error) {
+	ms := testConfig.MultiStageTestConfigurationLiteral
+	if ms == nil {
+		return nil, fmt.Errorf("test %s is not a resolved multi-stage test", testConfig.As)
+	}
+	s := newMultiStageTestStep(testConfig, config, renderParameters{}, nil, jobSpec, api.LeasesForTest(ms), "", "", nil, options)
+	s.rendering = true
+	ret := &RenderedObjects{Steps: map[string][]ctrlruntimeclient.Object{}}
+	ret.Test = append(ret.Test, s.sharedDirSecret(), s.resultsSecret())
+	if options.EncryptSharedDir {
+		ret.Test = append(ret.Test, s.sharedDirKeySecret(nil))
+	}
+	// the secrets of a collection of credentials are only known once the test
+	// runs, so each collection is rendered as a single placeholder secret
+	for _, step := range s.allSteps() {
+		for _, credential := range step.Credentials {
+			if credential.Selector != nil {
+				if s.credentialCollections == nil {
+					s.credentialCollections = map[string][]string{}
+				}
+				s.credentialCollections[credentialCollectionKey(credential)] = []string{renderPlaceholder(labels.SelectorFromSet(credential.Selector.MatchLabels).String())}
+			}
+		}
+	}
+	credentials := map[string]bool{}
+	for _, step := range s.allSteps() {
+		for _, credential := range s.stepCredentials(step.Credentials) {
+			if name := credentialSecretName(credential); !credentials[name] {
+				credentials[name] = true
+				ret.Test = append(ret.Test, s.credentialSecret(credential, &coreapi.Secret{}))
+			}
+		}
+	}
+	sa, role, bindings := s.rbacObjects()
+	ret.Test = append(ret.Test, sa, role)
+	for i := range bindings {
+		ret.Test = append(ret.Test, &bindings[i])
+	}
+	for step, script := range s.commandScripts() {
+		ret.Steps[step] = append(ret.Steps[step], s.commandConfigMap(step, script))
+	}
+	if cm := s.toolkitConfigMap(); cm != nil {
+		ret.Test = append(ret.Test, cm)
+	}
+	env, err := s.environment()
+	if err != nil {
+		return nil, err
+	}
+	pods, _, err := s.generatePods(s.allSteps(), env, nil, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate the pods of test %s: %w", testConfig.As, err)
+	}
+	observerOpts := defaultGeneratePodOptions()
+	observerOpts.IsObserver = true
+	observers, err := s.generateObservers(s.observers, nil, nil, observerOpts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate the observer pods of test %s: %w", testConfig.As, err)
+	}
+	for _, pod := range append(pods, observers...) {
+		pod := pod
+		step := pod.Labels[base_steps.LabelMetadataStep]
+		ret.Steps[step] = append(ret.Steps[step], &pod)
+	}
+	if err := setKinds(ret.Test); err != nil {
+		return nil, err
+	}
+	for _, objects := range ret.Steps {
+		if err := setKinds(objects); err != nil {
+			return nil, err
+		}
+	}
+	return ret, nil
+}
+
+// setKinds sets the kinds of the objects, which typed objects leave empty, so
+// they can be told apart once serialized.
+func setKinds(objects []ctrlruntimeclient.Object) error {
+	for _, obj := range objects {
+		gvk, err := apiutil.GVKForObject(obj, scheme.Scheme)
+		if err != nil {
+			return fmt.Errorf("failed to determine the kind of %s: %w", obj.GetName(), err)
+		}
+		obj.GetObjectKind().SetGroupVersionKind(gvk)
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package multi_stage
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	coreapi "k8s.io/api/core/v1"
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	prowdapi "k8s.io/test-infra/prow/pod-utils/downwardapi"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/testhelper"
+)
+
+func TestRenderObjects(t *testing.T) {
+	two := 2
+	test := api.TestStepConfiguration{
+		As:     "e2e",
+		Labels: map[string]string{"team": "installer"},
+		MultiStageTestConfigurationLiteral: &api.MultiStageTestConfigurationLiteral{
+			Pre: []api.LiteralTestStep{{
+				As:       "install",
+				From:     "installer",
+				Commands: "install",
+				Credentials: []api.CredentialReference{
+					{Namespace: "ci", Name: "aws", MountPath: "/aws"},
+					{Namespace: "ci", Selector: &api.CredentialSelector{MatchLabels: map[string]string{"partner": "true"}}, MountPath: "/partners"},
+				},
+			}},
+			Test: []api.LiteralTestStep{{
+				As:           "test",
+				From:         "tests",
+				Commands:     "test",
+				ShardCount:   &two,
+				Credentials:  []api.CredentialReference{{Namespace: "ci", Name: "aws", MountPath: "/aws"}},
+				Dependencies: []api.StepDependency{{Name: "pipeline:bin", Env: "BIN_IMAGE"}},
+			}},
+			Observers: []api.Observer{{Name: "monitor", From: "tests", Commands: "monitor"}},
+		},
+	}
+	jobSpec := api.JobSpec{JobSpec: prowdapi.JobSpec{
+		Job:  "job",
+		Type: prowapi.PeriodicJob,
+		DecorationConfig: &prowapi.DecorationConfig{
+			Timeout:     &prowapi.Duration{Duration: time.Minute},
+			GracePeriod: &prowapi.Duration{Duration: time.Second},
+			UtilityImages: &prowapi.UtilityImages{
+				Sidecar:    "sidecar",
+				Entrypoint: "entrypoint",
+			},
+		},
+	}}
+	jobSpec.SetNamespace("ci-op-{id}")
+	rendered, err := RenderObjects(test, &api.ReleaseBuildConfiguration{}, &jobSpec, Options{EncryptSharedDir: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := func(objects []ctrlruntimeclient.Object) []string {
+		var ret []string
+		for _, obj := range objects {
+			if obj.GetNamespace() != "ci-op-{id}" || obj.GetLabels()["team"] != "installer" {
+				t.Errorf("unexpected metadata for %s: namespace %q, labels %v", obj.GetName(), obj.GetNamespace(), obj.GetLabels())
+			}
+			ret = append(ret, fmt.Sprintf("%s/%s", obj.GetObjectKind().GroupVersionKind().Kind, obj.GetName()))
+		}
+		sort.Strings(ret)
+		return ret
+	}
+	testhelper.Diff(t, "test objects", names(rendered.Test), []string{
+		"Role/e2e",
+		"RoleBinding/e2e",
+		"RoleBinding/e2e-view",
+		"Secret/ci-${partner=true}",
+		"Secret/ci-aws",
+		"Secret/e2e",
+		"Secret/e2e-junit",
+		"Secret/e2e-shared-dir-key",
+		"ServiceAccount/e2e",
+	})
+	steps := map[string][]string{}
+	for step, objects := range rendered.Steps {
+		steps[step] = names(objects)
+	}
+	testhelper.Diff(t, "step objects", steps, map[string][]string{
+		"install": {"ConfigMap/e2e-install-commands-81fa3803", "Pod/e2e-install"},
+		"monitor": {"ConfigMap/e2e-monitor-commands-a8e1b3ed", "Pod/e2e-monitor"},
+		"test":    {"ConfigMap/e2e-test-commands-bc58d067", "Pod/e2e-test-0", "Pod/e2e-test-1"},
+	})
+	var mounts []string
+	for _, obj := range rendered.Steps["install"] {
+		if pod, ok := obj.(*coreapi.Pod); ok {
+			for _, mount := range pod.Spec.Containers[0].VolumeMounts {
+				if strings.HasPrefix(mount.MountPath, "/partners") {
+					mounts = append(mounts, mount.MountPath)
+				}
+			}
+		}
+	}
+	testhelper.Diff(t, "collection placeholder mounts", mounts, []string{"/partners/${partner=true}"})
+	var env []coreapi.EnvVar
+	for _, obj := range rendered.Steps["test"] {
+		if pod, ok := obj.(*coreapi.Pod); ok && pod.Name == "e2e-test-0" {
+			env = pod.Spec.Containers[0].Env
+		}
+	}
+	var dependency string
+	for _, e := range env {
+		if e.Name == "BIN_IMAGE" {
+			dependency = e.Value
+		}
+	}
+	testhelper.Diff(t, "dependency placeholder", dependency, "${BIN_IMAGE}")
+}
@@ -0,0 +1,245 @@
+package multi_stage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	coreapi "k8s.io/api/core/v1"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/openshift/ci-tools/pkg/steps/shareddir"
+)
+
+// clusterReuseAttachTimeout is how long a test whose cluster is reused waits
+// for the tests reusing it to start, e.g. while their images are built,
+// before it tears the cluster down.
+const clusterReuseAttachTimeout = 2 * time.Hour
+
+// ClusterShares coordinates the tests of a job which reuse the cluster
+// provisioned by another test with `reuse_cluster_from`.  A single instance
+// is shared by all the tests executed by ci-operator.
+type ClusterShares struct {
+	lock   sync.Mutex
+	shares map[string]*clusterShare
+}
+
+// clusterShare is the cluster of a test, reused by other tests.
+type clusterShare struct {
+	// published is closed once the cluster is installed, or failed to be.
+	published chan struct{}
+	sharedDir map[string][]byte
+	err       error
+
+	lock sync.Mutex
+	// expected is the number of tests reusing the cluster which have not
+	// started yet.
+	expected int
+	// active is the number of tests using the cluster.
+	active int
+	// closed is set once the cluster is torn down.
+	closed bool
+	// changed is closed and replaced whenever the counts change.
+	changed chan struct{}
+}
+
+// NewClusterShares returns an empty set of shared clusters.
+func NewClusterShares() *ClusterShares {
+	return &ClusterShares{shares: map[string]*clusterShare{}}
+}
+
+func (c *ClusterShares) share(test string) *clusterShare {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	share, ok := c.shares[test]
+	if !ok {
+		share = &clusterShare{published: make(chan struct{}), changed: make(chan struct{})}
+		c.shares[test] = share
+	}
+	return share
+}
+
+// Expect registers a test which will reuse the cluster of another test, so
+// that the cluster is not torn down before the test has run.  It is called
+// for each such test of the graph before it is executed.
+func (c *ClusterShares) Expect(test string) {
+	share := c.share(test)
+	share.lock.Lock()
+	defer share.lock.Unlock()
+	share.expected++
+}
+
+// reused determines whether other tests will reuse the cluster of a test.
+func (c *ClusterShares) reused(test string) bool {
+	if c == nil {
+		return false
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	_, ok := c.shares[test]
+	return ok
+}
+
+// notify signals a change of the counts, with the lock held.
+func (s *clusterShare) notify() {
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+// publish makes the cluster, or the reason it could not be installed,
+// available to the tests reusing it.
+func (s *clusterShare) publish(sharedDir map[string][]byte, err error) {
+	select {
+	case <-s.published:
+		return
+	default:
+	}
+	s.sharedDir, s.err = sharedDir, err
+	close(s.published)
+}
+
+// attach waits for the cluster to be installed and returns the snapshot of
+// the shared directory of the test which installed it, and the function to
+// call once the cluster is no longer used.
+func (s *clusterShare) attach(ctx context.Context, from string) (map[string][]byte, func(), error) {
+	s.lock.Lock()
+	if s.expected > 0 {
+		s.expected--
+	}
+	if s.closed {
+		s.lock.Unlock()
+		return nil, nil, fmt.Errorf("the cluster of test %s was torn down before this test started", from)
+	}
+	s.active++
+	s.notify()
+	s.lock.Unlock()
+	release := func() {
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		s.active--
+		s.notify()
+	}
+	select {
+	case <-s.published:
+	case <-ctx.Done():
+		release()
+		return nil, nil, ctx.Err()
+	}
+	if s.err != nil {
+		release()
+		return nil, nil, fmt.Errorf("the cluster of test %s is not available: %w", from, s.err)
+	}
+	return s.sharedDir, release, nil
+}
+
+// wait blocks until the tests reusing the cluster have finished, or those
+// which have not started yet did not do so within the timeout, after which
+// the cluster can no longer be attached to.
+func (s *clusterShare) wait(ctx context.Context, timeout time.Duration) {
+	deadline := time.After(timeout)
+	var timedOut bool
+	for {
+		s.lock.Lock()
+		if s.active == 0 && (s.expected == 0 || timedOut) {
+			s.closed = true
+			s.lock.Unlock()
+			return
+		}
+		changed := s.changed
+		logrus.Infof("Waiting for %d tests to finish using the cluster and %d tests to start.", s.active, s.expected)
+		s.lock.Unlock()
+		select {
+		case <-changed:
+		case <-deadline:
+			timedOut, deadline = true, nil
+		case <-ctx.Done():
+			s.lock.Lock()
+			s.closed = true
+			s.lock.Unlock()
+			return
+		}
+	}
+}
+
+// configName returns the name of the test in the configuration, without the
+// suffix added to the targets of the job.
+func (s *multiStageTestStep) configName() string {
+	if s.additionalSuffix != "" {
+		return strings.TrimSuffix(s.name, fmt.Sprintf("-%s", s.additionalSuffix))
+	}
+	return s.name
+}
+
+// shareCluster publishes the cluster installed by the test to the tests which
+// reuse it, with a snapshot of its shared directory.
+func (s *multiStageTestStep) shareCluster(ctx context.Context) {
+	if !s.options.ClusterShares.reused(s.configName()) {
+		return
+	}
+	share := s.options.ClusterShares.share(s.configName())
+	secret := &coreapi.Secret{}
+	if err := s.client.Get(ctx, ctrlruntimeclient.ObjectKey{Namespace: s.jobSpec.Namespace(), Name: s.name}, secret); err != nil {
+		share.publish(nil, fmt.Errorf("failed to get the shared directory: %w", err))
+		return
+	}
+	data, err := s.sharedDirData(secret)
+	share.publish(data, err)
+}
+
+// releaseSharedCluster waits for the tests reusing the cluster of the test to
+// finish, before it is torn down.  The tests are notified if the cluster was
+// never installed.
+func (s *multiStageTestStep) releaseSharedCluster(ctx context.Context) {
+	if !s.options.ClusterShares.reused(s.configName()) {
+		return
+	}
+	share := s.options.ClusterShares.share(s.configName())
+	share.publish(nil, fmt.Errorf("test %s failed to install it", s.name))
+	share.wait(ctx, clusterReuseAttachTimeout)
+}
+
+// attachToCluster waits for the cluster of the test this test reuses and
+// seeds the shared directory with its snapshot.  It returns the function to
+// call once the test no longer uses the cluster.
+func (s *multiStageTestStep) attachToCluster(ctx context.Context) (func(), error) {
+	if s.options.ClusterShares == nil {
+		return nil, fmt.Errorf("the cluster of test %s cannot be reused without coordination between the tests", s.reuseClusterFrom)
+	}
+	logrus.Infof("Waiting for the cluster of test %s to be installed.", s.reuseClusterFrom)
+	data, release, err := s.options.ClusterShares.share(s.reuseClusterFrom).attach(ctx, s.reuseClusterFrom)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.seedSharedDir(ctx, data); err != nil {
+		release()
+		return nil, fmt.Errorf("failed to copy the shared directory of test %s: %w", s.reuseClusterFrom, err)
+	}
+	return release, nil
+}
+
+// seedSharedDir writes the initial contents of the shared directory, signed
+// and encrypted if encryption is enabled.
+func (s *multiStageTestStep) seedSharedDir(ctx context.Context, data map[string][]byte) error {
+	secret := s.sharedDirSecret()
+	secret.Data = make(map[string][]byte, len(data)+1)
+	for k, v := range data {
+		secret.Data[k] = v
+	}
+	if s.sharedDirKey != nil {
+		provenance, err := shareddir.Chain(nil).Append(s.sharedDirKey, "reuse_cluster_from: "+s.reuseClusterFrom, data).Marshal()
+		if err != nil {
+			return err
+		}
+		secret.Data[shareddir.ProvenanceKey] = provenance
+		encrypted, err := shareddir.EncryptData(s.sharedDirKey, secret.Data)
+		if err != nil {
+			return err
+		}
+		secret.Data = encrypted
+	}
+	return s.client.Update(ctx, secret)
+}
@@ -0,0 +1,97 @@
+package multi_stage
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	coreapi "k8s.io/api/core/v1"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	fakectrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/kubernetes"
+	"github.com/openshift/ci-tools/pkg/steps/loggingclient"
+	"github.com/openshift/ci-tools/pkg/steps/shareddir"
+	"github.com/openshift/ci-tools/pkg/testhelper"
+)
+
+func TestClusterShare(t *testing.T) {
+	ctx := context.Background()
+	shares := NewClusterShares()
+	if shares.reused("e2e") {
+		t.Fatal("the cluster of e2e is not reused")
+	}
+	shares.Expect("e2e")
+	shares.Expect("e2e")
+	if !shares.reused("e2e") {
+		t.Fatal("the cluster of e2e is reused")
+	}
+	share := shares.share("e2e")
+	sharedDir := map[string][]byte{"kubeconfig": []byte("config")}
+	share.publish(sharedDir, nil)
+	share.publish(nil, errors.New("ignored once published"))
+	data, release, err := share.attach(ctx, "e2e")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testhelper.Diff(t, "shared directory", data, sharedDir)
+	done := make(chan struct{})
+	go func() {
+		share.wait(ctx, 10*time.Millisecond)
+		close(done)
+	}()
+	select {
+	case <-done:
+		t.Fatal("the cluster was released while a test is using it")
+	case <-time.After(50 * time.Millisecond):
+	}
+	release()
+	select {
+	case <-done:
+	case <-time.After(time.Minute):
+		t.Fatal("the cluster was not released after the test finished")
+	}
+	// the second test did not start in time
+	if _, _, err := share.attach(ctx, "e2e"); err == nil || err.Error() != "the cluster of test e2e was torn down before this test started" {
+		t.Errorf("unexpected error attaching to a torn down cluster: %v", err)
+	}
+
+	failed := shares.share("e2e-failed")
+	failed.publish(nil, errors.New("test e2e-failed failed to install it"))
+	if _, _, err := failed.attach(ctx, "e2e-failed"); err == nil || err.Error() != "the cluster of test e2e-failed is not available: test e2e-failed failed to install it" {
+		t.Errorf("unexpected error attaching to a cluster which failed to install: %v", err)
+	}
+	failed.wait(ctx, time.Minute)
+}
+
+func TestSeedSharedDir(t *testing.T) {
+	ctx := context.Background()
+	jobSpec := api.JobSpec{}
+	jobSpec.SetNamespace("ns")
+	client := kubernetes.NewPodClient(loggingclient.New(fakectrlruntimeclient.NewClientBuilder().Build()), nil, nil, 0)
+	key, err := shareddir.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sharedDir := map[string][]byte{"kubeconfig": []byte("config"), "metadata.json": []byte("{}")}
+	for _, key := range [][]byte{nil, key} {
+		s := multiStageTestStep{name: "e2e-serial", reuseClusterFrom: "e2e", jobSpec: &jobSpec, client: client, sharedDirKey: key}
+		if err := s.createSharedDirSecret(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.seedSharedDir(ctx, sharedDir); err != nil {
+			t.Fatal(err)
+		}
+		secret := &coreapi.Secret{}
+		if err := client.Get(ctx, ctrlruntimeclient.ObjectKey{Namespace: "ns", Name: "e2e-serial"}, secret); err != nil {
+			t.Fatal(err)
+		}
+		data, err := s.sharedDirData(secret)
+		if err != nil {
+			t.Fatal(err)
+		}
+		testhelper.Diff(t, "shared directory", data, sharedDir)
+	}
+}
@@ -0,0 +1,73 @@
+package multi_stage
+
+import (
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+
+	coreapi "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/results"
+	base_steps "github.com/openshift/ci-tools/pkg/steps"
+	"github.com/openshift/ci-tools/pkg/util"
+)
+
+// allSteps returns the steps of all phases, followed by the rollbacks.
+func (s *multiStageTestStep) allSteps() []api.LiteralTestStep {
+	var ret []api.LiteralTestStep
+	for _, steps := range [][]api.LiteralTestStep{s.pre, s.test, s.post, s.rollbacks} {
+		ret = append(ret, steps...)
+	}
+	return ret
+}
+
+// rollbacksFor returns the rollbacks referenced by the steps of a phase.
+func (s *multiStageTestStep) rollbacksFor(steps []api.LiteralTestStep) (ret []api.LiteralTestStep) {
+	names := sets.New[string]()
+	for _, step := range steps {
+		if step.Rollback != "" {
+			names.Insert(step.Rollback)
+		}
+	}
+	for _, rollback := range s.rollbacks {
+		if names.Has(rollback.As) {
+			ret = append(ret, rollback)
+		}
+	}
+	return ret
+}
+
+// generateRollbackPods generates the pods of the rollbacks referenced by the
+// steps of a phase, so they can be executed as soon as a step fails.
+func (s *multiStageTestStep) generateRollbackPods(
+	steps []api.LiteralTestStep,
+	env *envBuilder,
+	secretVolumes []coreapi.Volume,
+	secretVolumeMounts []coreapi.VolumeMount,
+) error {
+	pods, _, err := s.generatePods(s.rollbacksFor(steps), env, secretVolumes, secretVolumeMounts, nil)
+	if err != nil {
+		return err
+	}
+	s.rollbackPods = make(map[string]coreapi.Pod, len(pods))
+	for _, pod := range pods {
+		s.rollbackPods[pod.Labels[base_steps.LabelMetadataStep]] = pod
+	}
+	return nil
+}
+
+// runRollback executes the rollback of a failed step.  It runs even if the
+// test is cancelled, as it restores state which outlives the test.
+func (s *multiStageTestStep) runRollback(name string, step api.LiteralTestStep) error {
+	pod, ok := s.rollbackPods[step.Rollback]
+	if !ok {
+		return results.ForReason("rolling_back_step").ForError(fmt.Errorf("no pod was generated for rollback step %s", step.Rollback))
+	}
+	logrus.Warnf("Step %s failed, running rollback step %s.", name, pod.Name)
+	if _, err := s.runPod(base_steps.CleanupCtx, pod.DeepCopy(), base_steps.NewTestCaseNotifier(util.NopNotifier), util.WaitForPodFlag(0)); err != nil {
+		return results.ForReason("rolling_back_step").WithError(err).Errorf("rollback step %s of step %s failed: %v", pod.Name, name, err)
+	}
+	return nil
+}
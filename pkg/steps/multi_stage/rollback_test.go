@@ -0,0 +1,92 @@
+package multi_stage
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	coreapi "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	prowdapi "k8s.io/test-infra/prow/pod-utils/downwardapi"
+	fakectrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/secrets"
+	"github.com/openshift/ci-tools/pkg/steps/loggingclient"
+	"github.com/openshift/ci-tools/pkg/testhelper"
+	testhelper_kube "github.com/openshift/ci-tools/pkg/testhelper/kubernetes"
+)
+
+func TestRunRollback(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		failures    sets.Set[string]
+		expected    []string
+		expectedErr bool
+	}{{
+		name:     "rollback is not executed when the step succeeds",
+		expected: []string{"test-dns", "test-e2e", "test-post"},
+	}, {
+		name:        "rollback is executed before the test proceeds when the step fails",
+		failures:    sets.New[string]("test-dns"),
+		expected:    []string{"test-dns", "test-dns-restore", "test-post"},
+		expectedErr: true,
+	}, {
+		name:        "failure of the rollback is reported",
+		failures:    sets.New[string]("test-dns", "test-dns-restore"),
+		expected:    []string{"test-dns", "test-dns-restore", "test-post"},
+		expectedErr: true,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			sa := &coreapi.ServiceAccount{ObjectMeta: meta.ObjectMeta{Name: "test", Namespace: "test-namespace", Labels: map[string]string{MultiStageTestLabel: "test"}}}
+			crclient := &testhelper_kube.FakePodExecutor{
+				Lock: sync.RWMutex{},
+				LoggingClient: loggingclient.New(
+					fakectrlruntimeclient.NewClientBuilder().
+						WithIndex(&coreapi.Pod{}, "metadata.name", fakePodNameIndexer).
+						WithObjects(sa).
+						Build()),
+				Failures: tc.failures,
+			}
+			jobSpec := api.JobSpec{
+				JobSpec: prowdapi.JobSpec{
+					Job:       "job",
+					BuildID:   "build_id",
+					ProwJobID: "prow_job_id",
+					Type:      prowapi.PeriodicJob,
+					DecorationConfig: &prowapi.DecorationConfig{
+						Timeout:     &prowapi.Duration{Duration: time.Minute},
+						GracePeriod: &prowapi.Duration{Duration: time.Second},
+						UtilityImages: &prowapi.UtilityImages{
+							Sidecar:    "sidecar",
+							Entrypoint: "entrypoint",
+						},
+					},
+				},
+			}
+			jobSpec.SetNamespace("test-namespace")
+			client := &testhelper_kube.FakePodClient{FakePodExecutor: crclient}
+			censor := secrets.NewDynamicCensor()
+			step := MultiStageTestStep(api.TestStepConfiguration{
+				As: "test",
+				MultiStageTestConfigurationLiteral: &api.MultiStageTestConfigurationLiteral{
+					Test:      []api.LiteralTestStep{{As: "dns", Rollback: "dns-restore"}, {As: "e2e"}},
+					Post:      []api.LiteralTestStep{{As: "post"}},
+					Rollbacks: []api.LiteralTestStep{{As: "dns-restore"}},
+				},
+			}, &api.ReleaseBuildConfiguration{}, nil, client, &jobSpec, nil, "node-name", "", &censor, Options{})
+			err := step.Run(context.Background())
+			if (err != nil) != tc.expectedErr {
+				t.Errorf("expected error: %t, got %v", tc.expectedErr, err)
+			}
+			var names []string
+			for _, pod := range crclient.CreatedPods {
+				names = append(names, pod.Name)
+			}
+			testhelper.Diff(t, "pods", names, tc.expected)
+		})
+	}
+}
@@ -2,24 +2,37 @@ package multi_stage
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"path"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/sirupsen/logrus"
 
+	batchv1 "k8s.io/api/batch/v1"
 	coreapi "k8s.io/api/core/v1"
 	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
 	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/apimachinery/pkg/util/sets"
 	utilpointer "k8s.io/utils/pointer"
 	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
 
 	"github.com/openshift/ci-tools/pkg/api"
 	"github.com/openshift/ci-tools/pkg/junit"
+	"github.com/openshift/ci-tools/pkg/kubernetes"
+	"github.com/openshift/ci-tools/pkg/results"
 	base_steps "github.com/openshift/ci-tools/pkg/steps"
+	"github.com/openshift/ci-tools/pkg/steps/riskanalysis"
+	"github.com/openshift/ci-tools/pkg/steps/stepruntime"
+	"github.com/openshift/ci-tools/pkg/steps/testresults"
 	"github.com/openshift/ci-tools/pkg/util"
 )
 
@@ -27,7 +40,7 @@ func (s *multiStageTestStep) runSteps(
 	ctx context.Context,
 	phase string,
 	steps []api.LiteralTestStep,
-	env []coreapi.EnvVar,
+	env *envBuilder,
 	secretVolumes []coreapi.Volume,
 	secretVolumeMounts []coreapi.VolumeMount,
 ) error {
@@ -38,6 +51,10 @@ func (s *multiStageTestStep) runSteps(
 		s.flags |= hasPrevErrs
 		return err
 	}
+	if err := s.generateRollbackPods(steps, env, secretVolumes, secretVolumeMounts); err != nil {
+		s.flags |= hasPrevErrs
+		return err
+	}
 	var errs []error
 	defer func() {
 		if len(errs) != 0 {
@@ -49,6 +66,11 @@ func (s *multiStageTestStep) runSteps(
 	}
 	select {
 	case <-ctx.Done():
+		// Jobs would replace their pods, so they are deleted first
+		logrus.Infof("cleanup: Deleting jobs with label %s=%s", MultiStageTestLabel, s.name)
+		if err := s.client.DeleteAllOf(base_steps.CleanupCtx, &batchv1.Job{}, ctrlruntimeclient.InNamespace(s.jobSpec.Namespace()), ctrlruntimeclient.MatchingLabels{MultiStageTestLabel: s.name}, ctrlruntimeclient.PropagationPolicy(meta.DeletePropagationBackground)); err != nil && !kerrors.IsNotFound(err) {
+			errs = append(errs, fmt.Errorf("failed to delete jobs with label %s=%s: %w", MultiStageTestLabel, s.name, err))
+		}
 		logrus.Infof("cleanup: Deleting pods with label %s=%s", MultiStageTestLabel, s.name)
 		if err := s.client.DeleteAllOf(base_steps.CleanupCtx, &coreapi.Pod{}, ctrlruntimeclient.InNamespace(s.jobSpec.Namespace()), ctrlruntimeclient.MatchingLabels{MultiStageTestLabel: s.name}); err != nil && !kerrors.IsNotFound(err) {
 			errs = append(errs, fmt.Errorf("failed to delete pods with label %s=%s: %w", MultiStageTestLabel, s.name, err))
@@ -81,21 +103,160 @@ func (s *multiStageTestStep) runSteps(
 
 func (s *multiStageTestStep) runPods(ctx context.Context, pods []coreapi.Pod, bestEffortSteps sets.Set[string]) error {
 	var errs []error
-	for _, pod := range pods {
-		err := s.runPod(ctx, &pod, base_steps.NewTestCaseNotifier(util.NopNotifier), util.WaitForPodFlag(0))
+	for _, group := range groupShards(pods) {
+		var groupErrs []error
+		if group[0].Labels[GangLabel] != "" {
+			groupErrs = s.runGang(ctx, group, bestEffortSteps)
+		} else {
+			groupErrs = s.runShards(ctx, group, bestEffortSteps)
+		}
+		errs = append(errs, groupErrs...)
+		if len(groupErrs) != 0 && s.flags&shortCircuit != 0 {
+			break
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// groupShards splits the pods into groups which are executed in sequence.
+// The shards of a step and the steps of a gang are grouped together, all
+// other pods run by themselves.
+func groupShards(pods []coreapi.Pod) [][]coreapi.Pod {
+	var ret [][]coreapi.Pod
+	for i, pod := range pods {
+		if i != 0 {
+			step, prev := pod.Labels[ShardLabel], pods[i-1].Labels[ShardLabel]
+			gang, prevGang := pod.Labels[GangLabel], pods[i-1].Labels[GangLabel]
+			if (step != "" && step == prev) || (gang != "" && gang == prevGang) {
+				ret[len(ret)-1] = append(ret[len(ret)-1], pod)
+				continue
+			}
+		}
+		ret = append(ret, []coreapi.Pod{pod})
+	}
+	return ret
+}
+
+// runShards executes a group of pods in parallel and returns the errors of
+// those which are not executed in best-effort mode.
+func (s *multiStageTestStep) runShards(ctx context.Context, pods []coreapi.Pod, bestEffortSteps sets.Set[string]) []error {
+	errs := make([]error, len(pods))
+	var wg sync.WaitGroup
+	wg.Add(len(pods))
+	for i := range pods {
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = s.runStepPod(ctx, &pods[i])
+		}(i)
+	}
+	wg.Wait()
+	if len(pods) > 1 && pods[0].Labels[ShardLabel] != "" {
+		s.subLock.Lock()
+		s.subTests = append(s.subTests, shardsTestCase(s.Description(), pods, errs))
+		s.subLock.Unlock()
+	}
+	var ret []error
+	for i, err := range errs {
 		if err == nil {
 			continue
 		}
-		if bestEffortSteps != nil && bestEffortSteps.Has(pod.Name) {
-			logrus.Infof("Pod %s is running in best-effort mode, ignoring the failure...", pod.Name)
+		if bestEffortSteps != nil && bestEffortSteps.Has(pods[i].Name) {
+			logrus.Infof("Pod %s is running in best-effort mode, ignoring the failure...", pods[i].Name)
 			continue
 		}
-		errs = append(errs, err)
-		if s.flags&shortCircuit != 0 {
+		ret = append(ret, err)
+	}
+	return ret
+}
+
+// runStepPod executes a step pod once the files it requires are present in
+// the shared directory, executing it again if the step requested to be
+// retried, and records the contract observed for the step and whether it
+// failed.
+func (s *multiStageTestStep) runStepPod(ctx context.Context, pod *coreapi.Pod) (ret error) {
+	defer func() {
+		if ret != nil {
+			s.recordFailedStep(pod)
+		}
+	}()
+	if err := s.checkSharedFiles(ctx, pod); err != nil {
+		return err
+	}
+	before, err := s.sharedDirSnapshot(ctx)
+	if err != nil {
+		logrus.WithError(err).Debugf("Failed to determine the files available to step %s", pod.Name)
+	}
+	defer s.recordContract(base_steps.CleanupCtx, pod, before)
+	step, ok := s.stepFor(pod)
+	run := s.runPod
+	if ok && runsOnEphemeralCluster(step) {
+		run = s.runOnEphemeralCluster
+	}
+	if ok && isUpgradeStep(step) {
+		run = s.runUpgrade
+	}
+	ctx, release, err := s.acquireStepLeases(ctx, pod.Name, step)
+	if err != nil {
+		return err
+	}
+	defer release()
+	// the pods of a gang are acquired together before any of them runs and
+	// upgrade steps do not run a pod
+	if pod.Labels[GangLabel] == "" && !isUpgradeStep(step) {
+		releasePod, err := s.options.StepPodLimiter.acquire(ctx, "step "+pod.Name, 1)
+		if err != nil {
+			return err
+		}
+		defer releasePod()
+	}
+	removeManifests, err := s.applyManifests(ctx, pod.Name, step)
+	if err != nil {
+		return err
+	}
+	defer removeManifests()
+	if err := s.waitForConditions(ctx, pod.Name, step, waitForInterval); err != nil {
+		return err
+	}
+	var attempts []stepAttempt
+	for retries := 0; ; retries++ {
+		attempt := stepAttempt{started: time.Now()}
+		attempt.tests, err = run(ctx, pod.DeepCopy(), base_steps.NewTestCaseNotifier(util.NopNotifier), util.WaitForPodFlag(0))
+		attempt.duration, attempt.err = time.Since(attempt.started), err
+		attempts = append(attempts, attempt)
+		var retry *retryRequestedError
+		if !errors.As(err, &retry) || retries == maxRetryRequests || ctx.Err() != nil {
 			break
 		}
+		logrus.Infof("Step %s requested to be retried with exit code %d, executing it again.", pod.Name, ExitCodeRetry)
 	}
-	return utilerrors.NewAggregate(errs)
+	s.recordAttempts(pod.Name, attempts)
+	if err != nil && step.Rollback != "" {
+		if rollbackErr := s.runRollback(pod.Name, step); rollbackErr != nil {
+			return utilerrors.NewAggregate([]error{err, rollbackErr})
+		}
+	}
+	return err
+}
+
+// shardsTestCase aggregates the results of the shards of a step in a single
+// jUnit test case, which fails if any of the shards failed.
+func shardsTestCase(description string, pods []coreapi.Pod, errs []error) *junit.TestCase {
+	testCase := &junit.TestCase{
+		Name:       fmt.Sprintf("%s - %s shards", description, pods[0].Labels[ShardLabel]),
+		Properties: []*junit.TestSuiteProperty{{Name: "shard_total", Value: strconv.Itoa(len(pods))}},
+	}
+	var failed []string
+	for i, err := range errs {
+		if err != nil {
+			failed = append(failed, pods[i].Name)
+		}
+	}
+	if len(failed) != 0 {
+		testCase.FailureOutput = &junit.FailureOutput{
+			Output: fmt.Sprintf("%d of %d shards failed: %s", len(failed), len(pods), strings.Join(failed, ", ")),
+		}
+	}
+	return testCase
 }
 
 func (s *multiStageTestStep) runObservers(ctx, textCtx context.Context, pods []coreapi.Pod, done chan<- struct{}) {
@@ -111,7 +272,7 @@ func (s *multiStageTestStep) runObservers(ctx, textCtx context.Context, pods []c
 			}
 		}(pod)
 		go func(p coreapi.Pod) {
-			err := s.runPod(textCtx, &p, base_steps.NewTestCaseNotifier(util.NopNotifier), util.Interruptible)
+			_, err := s.runPod(textCtx, &p, base_steps.NewTestCaseNotifier(util.NopNotifier), util.Interruptible)
 			if ctx.Err() == nil {
 				// when the observer is cancelled, we get an error here that we need to ignore, as it's not an error
 				// for the Pod to be deleted when it's cancelled, it's just expected
@@ -132,17 +293,111 @@ func (s *multiStageTestStep) runObservers(ctx, textCtx context.Context, pods []c
 	done <- struct{}{}
 }
 
-func (s *multiStageTestStep) runPod(ctx context.Context, pod *coreapi.Pod, notifier *base_steps.TestCaseNotifier, flags util.WaitForPodFlag) error {
+func (s *multiStageTestStep) runPod(ctx context.Context, pod *coreapi.Pod, notifier *base_steps.TestCaseNotifier, flags util.WaitForPodFlag) ([]*junit.TestCase, error) {
+	if err := s.mutatePod(ctx, pod); err != nil {
+		return nil, err
+	}
+	if err := s.enforcePodPolicy(pod); err != nil {
+		return nil, err
+	}
+	if err := s.admitOrgPod(ctx, pod); err != nil {
+		return nil, err
+	}
+	if err := s.addStepIdentity(ctx, pod); err != nil {
+		return nil, err
+	}
+	release, err := s.protectCriticalPod(ctx, pod)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return s.runPodOn(ctx, s.client, pod, notifier, flags)
+}
+
+// runPodOn executes a pod on the cluster of the client, which is the build
+// farm unless the step runs on the cluster under test.  Mutators and the pod
+// policy only apply to the build farm and are not used here.  The test cases
+// reported for the pod are returned along with its result.
+func (s *multiStageTestStep) runPodOn(ctx context.Context, podClient kubernetes.PodClient, pod *coreapi.Pod, notifier *base_steps.TestCaseNotifier, flags util.WaitForPodFlag) ([]*junit.TestCase, error) {
 	start := time.Now()
 	logrus.Infof("Running step %s.", pod.Name)
-	client := s.client.WithNewLoggingClient()
-	if _, err := util.CreateOrRestartPod(ctx, client, pod); err != nil {
-		return fmt.Errorf("failed to create or restart %s pod: %w", pod.Name, err)
+	client := podClient.WithNewLoggingClient()
+	anomalies := newPodAnomalies(pod.Name)
+	var newPod *coreapi.Pod
+	var quotaWaits []api.CIOperatorStepDetailInfo
+	var err error
+	if step, _ := s.stepFor(pod); step.Job != nil {
+		// the pods of the Job are named by the cluster and replaced when they
+		// fail, so they are only watched until they finish
+		newPod, err = s.runJob(ctx, client, pod, *step.Job, notifier, flags)
+	} else {
+		var blocked time.Duration
+		_, blocked, err = util.CreateOrRestartPodWithQuotaBackoff(ctx, client, pod, s.podQuotaBackoff())
+		quotaWaits = s.recordQuotaWait(pod.Name, blocked)
+		if err != nil {
+			s.subLock.Lock()
+			s.subSteps = append(s.subSteps, quotaWaits...)
+			s.subLock.Unlock()
+			return nil, fmt.Errorf("failed to create or restart %s pod: %w", pod.Name, err)
+		}
+		s.recordStepEvent(ctx, pod, time.Now(), coreapi.EventTypeNormal, eventStepCreated, fmt.Sprintf("Created pod %s", pod.Name))
+		watchCtx, stopWatch := context.WithCancel(ctx)
+		watched := make(chan struct{})
+		go func() {
+			defer close(watched)
+			watchPodAnomalies(watchCtx, client, pod.Namespace, pod.Name, anomalyPollInterval, anomalies)
+		}()
+		if s.options.StepProgress {
+			go watchProgress(watchCtx, client, pod.Namespace, pod.Name)
+		}
+		completion := newWatchedPodClient(client)
+		started := make(chan struct{})
+		go func() {
+			defer close(started)
+			running, ok := s.recordStepStart(watchCtx, client, pod.Namespace, pod.Name, completion.watched, stepStartInterval)
+			if lead := s.options.TimeoutSnapshotLeadTime; ok && lead > 0 {
+				s.watchTimeout(watchCtx, client, running, s.stepTimeout(&step), lead)
+			}
+		}()
+		waitForLogs := s.forwardLogs(ctx, client, pod)
+		newPod, err = util.WaitForPodCompletion(ctx, completion, pod.Namespace, pod.Name, notifier, flags)
+		stopWatch()
+		<-watched
+		<-started
+		waitForLogs()
 	}
-	newPod, err := util.WaitForPodCompletion(ctx, client, pod.Namespace, pod.Name, notifier, flags)
+	var pulls []api.CIOperatorStepDetailInfo
 	if newPod != nil {
 		pod = newPod
+		events := podEvents(base_steps.CleanupCtx, client, pod)
+		anomalies.observe(pod, events)
+		pulls = s.recordImagePulls(pod, events)
+		if err := s.savePodArtifacts(base_steps.CleanupCtx, client, pod); err != nil {
+			logrus.WithError(err).Warnf("Failed to save the state of pod %s", pod.Name)
+		}
 	}
+	waived := err != nil && newPod != nil && s.waiveQuarantinedFailure(pod)
+	if waived {
+		err = nil
+	}
+	var intent stepIntent
+	var hasIntent bool
+	if err != nil && newPod != nil {
+		intent, hasIntent = failedStepIntent(pod)
+	}
+	if intent == intentSkip {
+		logrus.Infof("Step %s requested to be skipped.", pod.Name)
+		err = nil
+	}
+	step, _ := s.stepFor(pod)
+	anomalyCase, restartErr := anomalies.testCase(fmt.Sprintf("%s - %s anomalies", s.Description(), pod.Name), step.FailOnRestart != nil && *step.FailOnRestart)
+	if err == nil && restartErr != nil {
+		err = restartErr
+	}
+	if err != nil && len(results.Kinds(err)) == 0 {
+		err = results.WithKind(stepErrorKind(pod, intent), err)
+	}
+	kind := results.KindString(err)
 	finished := time.Now()
 	duration := finished.Sub(start)
 	verb := "succeeded"
@@ -150,6 +405,10 @@ func (s *multiStageTestStep) runPod(ctx context.Context, pod *coreapi.Pod, notif
 		verb = "failed"
 	}
 	logrus.Infof("Step %s %s after %s.", pod.Name, verb, duration.Truncate(time.Second))
+	s.recordStepEnd(pod, finished, duration, err)
+	if newPod != nil {
+		s.recordBudget(pod, duration)
+	}
 	s.subLock.Lock()
 	s.subSteps = append(s.subSteps, api.CIOperatorStepDetailInfo{
 		StepName:    pod.Name,
@@ -160,7 +419,39 @@ func (s *multiStageTestStep) runPod(ctx context.Context, pod *coreapi.Pod, notif
 		Failed:      utilpointer.Bool(err != nil),
 		Manifests:   client.Objects(),
 	})
-	s.subTests = append(s.subTests, notifier.SubTests(fmt.Sprintf("%s - %s ", s.Description(), pod.Name))...)
+	s.subSteps = append(s.subSteps, quotaWaits...)
+	s.subSteps = append(s.subSteps, pulls...)
+	subTests := notifier.SubTests(fmt.Sprintf("%s - %s ", s.Description(), pod.Name))
+	if waived {
+		for _, test := range subTests {
+			if test.FailureOutput != nil {
+				skip(test, "the step failed only in quarantined test cases")
+			}
+		}
+		s.waivers = append(s.waivers, waiverUse{Step: strings.TrimPrefix(pod.Name, s.name+"-"), StepFailureWaived: true})
+	}
+	if hasIntent {
+		output := fmt.Sprintf("step %s exited with the code for %s", pod.Name, strings.ReplaceAll(string(intent), "_", " "))
+		if intent == intentSkip {
+			for _, test := range subTests {
+				if test.FailureOutput != nil {
+					skip(test, "the step requested to be skipped")
+				}
+			}
+		}
+		subTests = append(subTests, intentTestCase(fmt.Sprintf("%s - %s exit code", s.Description(), pod.Name), intent, output))
+	}
+	if anomalyCase != nil {
+		subTests = append(subTests, anomalyCase)
+	}
+	if kind != "" {
+		for _, test := range subTests {
+			if test.FailureOutput != nil {
+				test.Properties = append(test.Properties, &junit.TestSuiteProperty{Name: base_steps.ErrorKindProperty, Value: kind})
+			}
+		}
+	}
+	s.subTests = append(s.subTests, subTests...)
 	s.subLock.Unlock()
 	if err != nil {
 		linksText := strings.Builder{}
@@ -176,7 +467,225 @@ func (s *multiStageTestStep) runPod(ctx context.Context, pod *coreapi.Pod, notif
 				status = fmt.Sprintf("%s activeDeadlineSeconds=%d", status, *pod.Spec.ActiveDeadlineSeconds)
 			}
 		}
-		return fmt.Errorf("%q pod %q %s: %w\n%s", s.name, pod.Name, status, err, linksText.String())
+		if kills := oomKilledContainers(pod); len(kills) != 0 {
+			s.addMemoryUsage(ctx, pod, kills)
+			var messages []string
+			s.subLock.Lock()
+			for _, kill := range kills {
+				messages = append(messages, kill.String())
+				test := kill.testCase(fmt.Sprintf("%s - %s ", s.Description(), pod.Name))
+				subTests = append(subTests, test)
+				s.subTests = append(s.subTests, test)
+			}
+			s.subLock.Unlock()
+			status = fmt.Sprintf("was OOM killed (%s)", strings.Join(messages, "; "))
+		}
+		if intent == intentInfrastructureFailure {
+			status = fmt.Sprintf("failed because of an infrastructure problem (exit code %d)", ExitCodeInfrastructureFailure)
+		}
+		return subTests, wrapStepError(intent, fmt.Errorf("%q pod %q %s: %w\n%s", s.name, pod.Name, status, err, linksText.String()))
+	}
+	return subTests, nil
+}
+
+// collectResults reads the jUnit results uploaded by the steps and nests them
+// in a suite for each step.  Test cases with the same name as those created by
+// ci-operator for the step pods are not repeated.  The requests made by the
+// steps with ci-step are stored in the same secret and reported as well.
+func (s *multiStageTestStep) collectResults(ctx context.Context) error {
+	secret := &coreapi.Secret{}
+	name := testresults.SecretName(s.name)
+	if err := s.client.Get(ctx, ctrlruntimeclient.ObjectKey{Namespace: s.jobSpec.Namespace(), Name: name}, secret); err != nil {
+		return fmt.Errorf("failed to get results secret %s: %w", name, err)
+	}
+	s.subLock.Lock()
+	defer s.subLock.Unlock()
+	synthetic := sets.New[string]()
+	for _, test := range append(append([]*junit.TestCase{}, s.subTests...), suiteTestCases(s.subSuites)...) {
+		synthetic.Insert(test.Name)
+	}
+	quarantine := s.activeQuarantine(time.Now())
+	var errs []error
+	for _, step := range sets.List(sets.KeySet(secret.Data)) {
+		if runtimeStep, ok := stepruntime.StepForSecretKey(step); ok {
+			if err := s.recordStepRuntime(runtimeStep, secret.Data[step]); err != nil {
+				errs = append(errs, fmt.Errorf("invalid ci-step record for step %s: %w", runtimeStep, err))
+			}
+			continue
+		}
+		suites, err := testresults.Decode(secret.Data[step])
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid results for step %s: %w", step, err))
+			continue
+		}
+		suite := &junit.TestSuite{Name: fmt.Sprintf("%s - %s-%s", s.Description(), s.name, step)}
+		for _, child := range suites.Suites {
+			s.waivers = append(s.waivers, applyQuarantine(step, child, quarantine)...)
+			removeTestCases(child, synthetic)
+			suite.NumTests += child.NumTests
+			suite.NumSkipped += child.NumSkipped
+			suite.NumFailed += child.NumFailed
+			suite.Duration += child.Duration
+			suite.Children = append(suite.Children, child)
+		}
+		s.subSuites = append(s.subSuites, suite)
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// analyzeRisk classifies the failed test cases of the test, including those
+// reported by the steps, using the failure history and writes the summary to
+// the artifact directory of the test.
+func (s *multiStageTestStep) analyzeRisk() error {
+	s.subLock.Lock()
+	cases := append(append([]*junit.TestCase{}, s.subTests...), suiteTestCases(s.subSuites)...)
+	summary := riskanalysis.Analyze(s.options.FailureHistory, s.name, cases)
+	s.subLock.Unlock()
+	data, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal risk analysis: %w", err)
+	}
+	return api.SaveArtifact(s.censor, path.Join(s.name, riskanalysis.Artifact), data)
+}
+
+func suiteTestCases(suites []*junit.TestSuite) []*junit.TestCase {
+	var ret []*junit.TestCase
+	for _, suite := range suites {
+		ret = append(ret, suite.TestCases...)
+		ret = append(ret, suiteTestCases(suite.Children)...)
+	}
+	return ret
+}
+
+// removeTestCases removes test cases with the given names from a suite and its
+// children, updating their counts.
+func removeTestCases(suite *junit.TestSuite, names sets.Set[string]) {
+	var cases []*junit.TestCase
+	for _, test := range suite.TestCases {
+		if !names.Has(test.Name) {
+			cases = append(cases, test)
+			continue
+		}
+		if suite.NumTests != 0 {
+			suite.NumTests--
+		}
+		switch {
+		case test.FailureOutput != nil && suite.NumFailed != 0:
+			suite.NumFailed--
+		case test.SkipMessage != nil && suite.NumSkipped != 0:
+			suite.NumSkipped--
+		}
+	}
+	suite.TestCases = cases
+	for _, child := range suite.Children {
+		removeTestCases(child, names)
+	}
+}
+
+// savePodArtifacts writes the final state of a step pod and the events
+// recorded for it to the artifact directory of the step, so failures can be
+// investigated without access to the build cluster.
+func (s *multiStageTestStep) savePodArtifacts(ctx context.Context, client ctrlruntimeclient.Reader, pod *coreapi.Pod) error {
+	dir := path.Join(s.name, strings.TrimPrefix(pod.Name, s.name+"-"))
+	data, err := yaml.Marshal(pod)
+	if err != nil {
+		return fmt.Errorf("failed to marshal pod: %w", err)
+	}
+	if err := api.SaveArtifact(s.censor, path.Join(dir, PodArtifact), data); err != nil {
+		return err
+	}
+	events := &coreapi.EventList{}
+	if err := client.List(ctx, events, ctrlruntimeclient.InNamespace(pod.Namespace), ctrlruntimeclient.MatchingFields{"involvedObject.uid": string(pod.UID)}); err != nil {
+		return fmt.Errorf("failed to list events: %w", err)
+	}
+	sort.SliceStable(events.Items, func(i, j int) bool {
+		return events.Items[i].FirstTimestamp.Before(&events.Items[j].FirstTimestamp)
+	})
+	if data, err = json.MarshalIndent(events.Items, "", "  "); err != nil {
+		return fmt.Errorf("failed to marshal events: %w", err)
+	}
+	return api.SaveArtifact(s.censor, path.Join(dir, EventsArtifact), data)
+}
+
+// oomKill describes a container terminated for exceeding its memory limit.
+// The usage is the historical memory usage of the container, if known.
+type oomKill struct {
+	container             string
+	limit, request, usage *resource.Quantity
+}
+
+// stepErrorKind classifies the failure of the pod of a step.
+func stepErrorKind(pod *coreapi.Pod, intent stepIntent) results.Kind {
+	switch {
+	case intent == intentInfrastructureFailure:
+		return results.KindInfrastructure
+	case pod.Status.Phase == coreapi.PodFailed && pod.Status.Reason == "DeadlineExceeded":
+		return results.KindTimeout
+	case pod.Status.Phase == coreapi.PodFailed:
+		// the commands of the step failed or exceeded their resources
+		return results.KindUser
+	}
+	return ""
+}
+
+// oomKilledContainers returns the containers of a pod which were killed for
+// exceeding their memory limit.
+func oomKilledContainers(pod *coreapi.Pod) []oomKill {
+	specs := map[string]coreapi.Container{}
+	for _, c := range append(pod.Spec.InitContainers, pod.Spec.Containers...) {
+		specs[c.Name] = c
+	}
+	var ret []oomKill
+	for _, status := range append(pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses...) {
+		if t := status.State.Terminated; t == nil || t.Reason != "OOMKilled" {
+			continue
+		}
+		kill := oomKill{container: status.Name}
+		resources := specs[status.Name].Resources
+		if q, ok := resources.Limits[coreapi.ResourceMemory]; ok {
+			kill.limit = &q
+		}
+		if q, ok := resources.Requests[coreapi.ResourceMemory]; ok {
+			kill.request = &q
+		}
+		ret = append(ret, kill)
+	}
+	return ret
+}
+
+func (k oomKill) String() string {
+	msg := fmt.Sprintf("container %s was OOM killed", k.container)
+	if k.limit != nil {
+		msg += fmt.Sprintf(" at its memory limit of %s", k.limit.String())
+	}
+	if k.request != nil {
+		msg += fmt.Sprintf(", memory request %s", k.request.String())
+	}
+	if k.usage != nil {
+		msg += fmt.Sprintf(", p95 historical usage %s", k.usage.String())
+	}
+	if k.limit != nil {
+		msg += ", consider raising `resources.limits.memory` for the step"
+	}
+	return msg
+}
+
+// testCase returns a failed jUnit test case for the kill, with the memory
+// settings of the container as properties.
+func (k oomKill) testCase(prefix string) *junit.TestCase {
+	testCase := &junit.TestCase{
+		Name:          fmt.Sprintf("%scontainer %s is not OOM killed", prefix, k.container),
+		Properties:    []*junit.TestSuiteProperty{{Name: "oom_killed", Value: "true"}},
+		FailureOutput: &junit.FailureOutput{Output: k.String()},
+	}
+	if k.limit != nil {
+		testCase.Properties = append(testCase.Properties, &junit.TestSuiteProperty{Name: "memory_limit", Value: k.limit.String()})
+	}
+	if k.request != nil {
+		testCase.Properties = append(testCase.Properties, &junit.TestSuiteProperty{Name: "memory_request", Value: k.request.String()})
+	}
+	if k.usage != nil {
+		testCase.Properties = append(testCase.Properties, &junit.TestSuiteProperty{Name: "memory_usage_p95", Value: k.usage.String()})
 	}
-	return nil
+	return testCase
 }
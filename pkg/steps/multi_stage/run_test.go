@@ -2,16 +2,23 @@ package multi_stage
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"os"
+	"path/filepath"
 	"reflect"
+	"sort"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
 
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/diff"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apimachinery/pkg/watch"
@@ -19,10 +26,16 @@ import (
 	prowdapi "k8s.io/test-infra/prow/pod-utils/downwardapi"
 	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
 	fakectrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/yaml"
 
 	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/junit"
+	"github.com/openshift/ci-tools/pkg/results"
+	"github.com/openshift/ci-tools/pkg/secrets"
 	"github.com/openshift/ci-tools/pkg/steps"
 	"github.com/openshift/ci-tools/pkg/steps/loggingclient"
+	"github.com/openshift/ci-tools/pkg/steps/riskanalysis"
+	"github.com/openshift/ci-tools/pkg/steps/testresults"
 	testhelper_kube "github.com/openshift/ci-tools/pkg/testhelper/kubernetes"
 )
 
@@ -166,6 +179,7 @@ func TestRun(t *testing.T) {
 				PendingTimeout:  30 * time.Minute,
 				FakePodExecutor: crclient,
 			}
+			censor := secrets.NewDynamicCensor()
 			step := MultiStageTestStep(api.TestStepConfiguration{
 				As: name,
 				MultiStageTestConfigurationLiteral: &api.MultiStageTestConfigurationLiteral{
@@ -175,7 +189,7 @@ func TestRun(t *testing.T) {
 					Observers:          tc.observers,
 					AllowSkipOnSuccess: &yes,
 				},
-			}, &api.ReleaseBuildConfiguration{}, nil, client, &jobSpec, nil, "node-name", "")
+			}, &api.ReleaseBuildConfiguration{}, nil, client, &jobSpec, nil, "node-name", "", &censor, Options{})
 			if err := step.Run(context.Background()); (err != nil) != (tc.failures != nil) {
 				t.Errorf("expected error: %t, got error: %v", (tc.failures != nil), err)
 			}
@@ -183,7 +197,7 @@ func TestRun(t *testing.T) {
 			if err := crclient.List(context.TODO(), secrets, ctrlruntimeclient.InNamespace(jobSpec.Namespace())); err != nil {
 				t.Fatal(err)
 			}
-			if l := secrets.Items; len(l) != 1 || l[0].ObjectMeta.Name != name {
+			if l := secrets.Items; len(l) != 2 || l[0].ObjectMeta.Name != name || l[1].ObjectMeta.Name != name+"-junit" {
 				t.Errorf("unexpected secrets: %#v", l)
 			}
 			var names []string
@@ -296,6 +310,7 @@ func TestJUnit(t *testing.T) {
 			}
 			jobSpec.SetNamespace("test-namespace")
 			client := &testhelper_kube.FakePodClient{FakePodExecutor: crclient}
+			censor := secrets.NewDynamicCensor()
 			step := MultiStageTestStep(api.TestStepConfiguration{
 				As: "test",
 				MultiStageTestConfigurationLiteral: &api.MultiStageTestConfigurationLiteral{
@@ -303,7 +318,7 @@ func TestJUnit(t *testing.T) {
 					Test: []api.LiteralTestStep{{As: "test0"}, {As: "test1"}},
 					Post: []api.LiteralTestStep{{As: "post0"}, {As: "post1"}},
 				},
-			}, &api.ReleaseBuildConfiguration{}, nil, client, &jobSpec, nil, "node-name", "")
+			}, &api.ReleaseBuildConfiguration{}, nil, client, &jobSpec, nil, "node-name", "", &censor, Options{})
 			if err := step.Run(context.Background()); tc.failures == nil && err != nil {
 				t.Error(err)
 				return
@@ -319,6 +334,296 @@ func TestJUnit(t *testing.T) {
 	}
 }
 
+func TestRunSharded(t *testing.T) {
+	sa := &v1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test-namespace", Labels: map[string]string{"ci.openshift.io/multi-stage-test": "test"}}}
+	crclient := &testhelper_kube.FakePodExecutor{
+		Lock: sync.RWMutex{},
+		LoggingClient: loggingclient.New(
+			fakectrlruntimeclient.NewClientBuilder().
+				WithIndex(&v1.Pod{}, "metadata.name", fakePodNameIndexer).
+				WithObjects(sa).
+				Build()),
+		Failures: sets.New[string]("test-e2e-1"),
+	}
+	jobSpec := api.JobSpec{
+		JobSpec: prowdapi.JobSpec{
+			Job:       "job",
+			BuildID:   "build_id",
+			ProwJobID: "prow_job_id",
+			Type:      prowapi.PeriodicJob,
+			DecorationConfig: &prowapi.DecorationConfig{
+				Timeout:     &prowapi.Duration{Duration: time.Minute},
+				GracePeriod: &prowapi.Duration{Duration: time.Second},
+				UtilityImages: &prowapi.UtilityImages{
+					Sidecar:    "sidecar",
+					Entrypoint: "entrypoint",
+				},
+			},
+		},
+	}
+	jobSpec.SetNamespace("test-namespace")
+	client := &testhelper_kube.FakePodClient{FakePodExecutor: crclient}
+	censor := secrets.NewDynamicCensor()
+	shards := 3
+	step := MultiStageTestStep(api.TestStepConfiguration{
+		As: "test",
+		MultiStageTestConfigurationLiteral: &api.MultiStageTestConfigurationLiteral{
+			Test: []api.LiteralTestStep{{As: "e2e", ShardCount: &shards}, {As: "after"}},
+			Post: []api.LiteralTestStep{{As: "post"}},
+		},
+	}, &api.ReleaseBuildConfiguration{}, nil, client, &jobSpec, nil, "node-name", "", &censor, Options{})
+	if err := step.Run(context.Background()); err == nil {
+		t.Fatal("expected an error")
+	}
+	var names []string
+	for _, pod := range crclient.CreatedPods {
+		names = append(names, pod.Name)
+	}
+	sort.Strings(names)
+	if diff := cmp.Diff([]string{"test-e2e-0", "test-e2e-1", "test-e2e-2", "test-post"}, names); diff != "" {
+		t.Errorf("did not execute correct pods: %s", diff)
+	}
+	var aggregated *junit.TestCase
+	for _, tc := range step.(steps.SubtestReporter).SubTests() {
+		if tc.Name == "Run multi-stage test test - e2e shards" {
+			aggregated = tc
+		}
+	}
+	if aggregated == nil {
+		t.Fatal("no test case was created for the shards")
+	}
+	expected := &junit.TestCase{
+		Name:          "Run multi-stage test test - e2e shards",
+		Properties:    []*junit.TestSuiteProperty{{Name: "shard_total", Value: "3"}},
+		FailureOutput: &junit.FailureOutput{Output: "1 of 3 shards failed: test-e2e-1"},
+	}
+	if diff := cmp.Diff(expected, aggregated); diff != "" {
+		t.Errorf("unexpected test case: %s", diff)
+	}
+}
+
+func TestRunExitCodes(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		code     int32
+		expected []string
+		reason   string
+		kind     string
+		intent   *junit.TestCase
+	}{{
+		name:     "skip does not fail the test",
+		code:     ExitCodeSkip,
+		expected: []string{"test-e2e", "test-after", "test-post"},
+		intent: &junit.TestCase{
+			Name:        "Run multi-stage test test - test-e2e exit code",
+			Properties:  []*junit.TestSuiteProperty{{Name: ExitCodeProperty, Value: "skip"}},
+			SkipMessage: &junit.SkipMessage{Message: "the step requested to be skipped with exit code 80"},
+			SystemErr:   "step test-e2e exited with the code for skip",
+		},
+	}, {
+		name:     "retry executes the step again",
+		code:     ExitCodeRetry,
+		expected: []string{"test-e2e", "test-e2e", "test-post"},
+		reason:   "executing_multi_stage_test",
+		kind:     "user",
+		intent: &junit.TestCase{
+			Name:          "Run multi-stage test test - test-e2e exit code",
+			Properties:    []*junit.TestSuiteProperty{{Name: ExitCodeProperty, Value: "retry"}, {Name: steps.ErrorKindProperty, Value: "user"}, {Name: AttemptProperty, Value: "2"}},
+			FailureOutput: &junit.FailureOutput{Output: "step test-e2e exited with the code for retry"},
+		},
+	}, {
+		name:     "infrastructure failure fails with a distinct reason",
+		code:     ExitCodeInfrastructureFailure,
+		expected: []string{"test-e2e", "test-post"},
+		reason:   "executing_multi_stage_test:step_infrastructure_failure",
+		kind:     "infrastructure",
+		intent: &junit.TestCase{
+			Name:          "Run multi-stage test test - test-e2e exit code",
+			Properties:    []*junit.TestSuiteProperty{{Name: ExitCodeProperty, Value: "infrastructure_failure"}, {Name: steps.ErrorKindProperty, Value: "infrastructure"}},
+			FailureOutput: &junit.FailureOutput{Output: "step test-e2e exited with the code for infrastructure failure"},
+		},
+	}, {
+		name:     "other codes are plain failures",
+		code:     1,
+		expected: []string{"test-e2e", "test-post"},
+		reason:   "executing_multi_stage_test",
+		kind:     "user",
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			sa := &v1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test-namespace", Labels: map[string]string{"ci.openshift.io/multi-stage-test": "test"}}}
+			crclient := &testhelper_kube.FakePodExecutor{
+				Lock: sync.RWMutex{},
+				LoggingClient: loggingclient.New(
+					fakectrlruntimeclient.NewClientBuilder().
+						WithIndex(&v1.Pod{}, "metadata.name", fakePodNameIndexer).
+						WithObjects(sa).
+						Build()),
+				Failures:  sets.New[string]("test-e2e"),
+				ExitCodes: map[string]int32{"test-e2e": tc.code},
+			}
+			jobSpec := api.JobSpec{
+				JobSpec: prowdapi.JobSpec{
+					Job:       "job",
+					BuildID:   "build_id",
+					ProwJobID: "prow_job_id",
+					Type:      prowapi.PeriodicJob,
+					DecorationConfig: &prowapi.DecorationConfig{
+						Timeout:     &prowapi.Duration{Duration: time.Minute},
+						GracePeriod: &prowapi.Duration{Duration: time.Second},
+						UtilityImages: &prowapi.UtilityImages{
+							Sidecar:    "sidecar",
+							Entrypoint: "entrypoint",
+						},
+					},
+				},
+			}
+			jobSpec.SetNamespace("test-namespace")
+			client := &testhelper_kube.FakePodClient{FakePodExecutor: crclient}
+			censor := secrets.NewDynamicCensor()
+			step := MultiStageTestStep(api.TestStepConfiguration{
+				As: "test",
+				MultiStageTestConfigurationLiteral: &api.MultiStageTestConfigurationLiteral{
+					Test: []api.LiteralTestStep{{As: "e2e"}, {As: "after"}},
+					Post: []api.LiteralTestStep{{As: "post"}},
+				},
+			}, &api.ReleaseBuildConfiguration{}, nil, client, &jobSpec, nil, "node-name", "", &censor, Options{})
+			err := step.Run(context.Background())
+			var reason string
+			if reasons := results.Reasons(err); len(reasons) != 0 {
+				reason = reasons[0]
+			}
+			if diff := cmp.Diff(tc.reason, reason); diff != "" {
+				t.Errorf("unexpected failure reason: %s", diff)
+			}
+			if diff := cmp.Diff(tc.kind, results.KindString(err)); diff != "" {
+				t.Errorf("unexpected failure kind: %s", diff)
+			}
+			var names []string
+			for _, pod := range crclient.CreatedPods {
+				names = append(names, pod.Name)
+			}
+			if diff := cmp.Diff(tc.expected, names); diff != "" {
+				t.Errorf("did not execute correct pods: %s", diff)
+			}
+			var intent *junit.TestCase
+			for _, test := range step.(steps.SubtestReporter).SubTests() {
+				if test.Name == "Run multi-stage test test - test-e2e exit code" {
+					intent = test
+				}
+			}
+			if diff := cmp.Diff(tc.intent, intent); diff != "" {
+				t.Errorf("unexpected test case: %s", diff)
+			}
+		})
+	}
+}
+
+func TestCollectResults(t *testing.T) {
+	encode := func(suites ...*junit.TestSuite) []byte {
+		raw, err := testresults.Encode(&junit.TestSuites{Suites: suites})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return raw
+	}
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "test-junit"},
+		Data: map[string][]byte{
+			"e2e": encode(&junit.TestSuite{
+				Name:      "e2e",
+				NumTests:  2,
+				NumFailed: 1,
+				Duration:  10,
+				TestCases: []*junit.TestCase{
+					{Name: "Run multi-stage test test - test-e2e container test", FailureOutput: &junit.FailureOutput{Output: "failed"}},
+					{Name: "passes"},
+				},
+			}),
+			"invalid":     []byte("not gzip"),
+			"e2e.runtime": []byte(`{"outputs":{"region":"us-east-1"},"flaky":["the API server was briefly unavailable"]}`),
+		},
+	}
+	jobSpec := api.JobSpec{}
+	jobSpec.SetNamespace("ns")
+	s := multiStageTestStep{
+		name:     "test",
+		jobSpec:  &jobSpec,
+		client:   &testhelper_kube.FakePodClient{FakePodExecutor: &testhelper_kube.FakePodExecutor{LoggingClient: loggingclient.New(fakectrlruntimeclient.NewClientBuilder().WithObjects(secret).Build())}},
+		subLock:  &sync.Mutex{},
+		subTests: []*junit.TestCase{{Name: "Run multi-stage test test - test-e2e container test"}},
+	}
+	if err := s.collectResults(context.Background()); err == nil {
+		t.Error("expected an error for invalid results")
+	}
+	expected := []*junit.TestSuite{{
+		Name:     "Run multi-stage test test - test-e2e",
+		NumTests: 1,
+		Duration: 10,
+		Children: []*junit.TestSuite{{
+			Name:      "e2e",
+			NumTests:  1,
+			Duration:  10,
+			TestCases: []*junit.TestCase{{Name: "passes"}},
+		}},
+	}}
+	if diff := cmp.Diff(expected, s.SubSuites(), cmpopts.IgnoreFields(junit.TestSuite{}, "XMLName"), cmpopts.IgnoreFields(junit.TestCase{}, "XMLName")); diff != "" {
+		t.Errorf("unexpected suites: %s", diff)
+	}
+	expectedTests := []*junit.TestCase{
+		{Name: "Run multi-stage test test - test-e2e container test"},
+		{
+			Name:       "Run multi-stage test test - test-e2e flaky",
+			Properties: []*junit.TestSuiteProperty{{Name: FlakyProperty, Value: "the API server was briefly unavailable"}},
+			SystemOut:  "step test-e2e reported a flake: the API server was briefly unavailable",
+		},
+	}
+	if diff := cmp.Diff(expectedTests, s.subTests); diff != "" {
+		t.Errorf("unexpected test cases: %s", diff)
+	}
+	if diff := cmp.Diff(map[string]map[string]string{"e2e": {"region": "us-east-1"}}, s.stepOutputs); diff != "" {
+		t.Errorf("unexpected step outputs: %s", diff)
+	}
+}
+
+func TestAnalyzeRisk(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("ARTIFACTS", dir)
+	censor := secrets.NewDynamicCensor()
+	s := multiStageTestStep{
+		name:     "test",
+		censor:   &censor,
+		subLock:  &sync.Mutex{},
+		subTests: []*junit.TestCase{{Name: "Run multi-stage test test - test-e2e container test", FailureOutput: &junit.FailureOutput{}}},
+		subSuites: []*junit.TestSuite{{
+			Name: "Run multi-stage test test - test-e2e",
+			Children: []*junit.TestSuite{{
+				TestCases: []*junit.TestCase{{Name: "flaky", FailureOutput: &junit.FailureOutput{}}, {Name: "passes"}},
+			}},
+		}},
+		options: Options{FailureHistory: &riskanalysis.History{Tests: map[string]riskanalysis.CaseHistory{
+			"flaky": {Runs: 2, Failures: 1},
+		}}},
+	}
+	if err := s.analyzeRisk(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	raw, err := os.ReadFile(filepath.Join(dir, "test", riskanalysis.Artifact))
+	if err != nil {
+		t.Fatalf("failed to read artifact: %v", err)
+	}
+	var summary riskanalysis.Summary
+	if err := json.Unmarshal(raw, &summary); err != nil {
+		t.Fatalf("failed to unmarshal artifact: %v", err)
+	}
+	expected := riskanalysis.Summary{Test: "test", Risk: riskanalysis.High, Failures: []riskanalysis.Failure{
+		{Name: "Run multi-stage test test - test-e2e container test", Classification: riskanalysis.New},
+		{Name: "flaky", Classification: riskanalysis.PreviouslyFailing, History: &riskanalysis.CaseHistory{Runs: 2, Failures: 1}},
+	}}
+	if diff := cmp.Diff(expected, summary); diff != "" {
+		t.Errorf("unexpected summary: %s", diff)
+	}
+}
+
 func fakePodNameIndexer(object ctrlruntimeclient.Object) []string {
 	p, ok := object.(*v1.Pod)
 	if !ok {
@@ -326,3 +631,122 @@ func fakePodNameIndexer(object ctrlruntimeclient.Object) []string {
 	}
 	return []string{p.Name}
 }
+
+func TestSavePodArtifacts(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("ARTIFACTS", dir)
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "test-step", UID: "uid"},
+		Status:     v1.PodStatus{Phase: v1.PodFailed, Reason: "DeadlineExceeded"},
+	}
+	event := func(name, uid, reason string, at time.Time) *v1.Event {
+		return &v1.Event{
+			ObjectMeta:     metav1.ObjectMeta{Namespace: "ns", Name: name},
+			InvolvedObject: v1.ObjectReference{UID: types.UID(uid)},
+			Reason:         reason,
+			FirstTimestamp: metav1.NewTime(at),
+		}
+	}
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	crclient := fakectrlruntimeclient.NewClientBuilder().
+		WithIndex(&v1.Event{}, "involvedObject.uid", func(o ctrlruntimeclient.Object) []string {
+			return []string{string(o.(*v1.Event).InvolvedObject.UID)}
+		}).
+		WithRuntimeObjects(
+			event("scheduled", "uid", "Scheduled", now.Add(time.Minute)),
+			event("failed-scheduling", "uid", "FailedScheduling", now),
+			event("other", "other-uid", "Pulled", now),
+		).Build()
+	censor := secrets.NewDynamicCensor()
+	s := multiStageTestStep{
+		name:   "test",
+		client: &testhelper_kube.FakePodClient{FakePodExecutor: &testhelper_kube.FakePodExecutor{LoggingClient: loggingclient.New(crclient)}},
+		censor: &censor,
+	}
+	if err := s.savePodArtifacts(context.Background(), s.client, pod); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	raw, err := os.ReadFile(filepath.Join(dir, "test", "step", PodArtifact))
+	if err != nil {
+		t.Fatalf("failed to read pod artifact: %v", err)
+	}
+	var savedPod v1.Pod
+	if err := yaml.Unmarshal(raw, &savedPod); err != nil {
+		t.Fatalf("failed to unmarshal pod: %v", err)
+	}
+	if diff := cmp.Diff(pod, &savedPod); diff != "" {
+		t.Errorf("unexpected pod: %s", diff)
+	}
+	raw, err = os.ReadFile(filepath.Join(dir, "test", "step", EventsArtifact))
+	if err != nil {
+		t.Fatalf("failed to read events artifact: %v", err)
+	}
+	var events []v1.Event
+	if err := json.Unmarshal(raw, &events); err != nil {
+		t.Fatalf("failed to unmarshal events: %v", err)
+	}
+	var reasons []string
+	for _, e := range events {
+		reasons = append(reasons, e.Reason)
+	}
+	if diff := cmp.Diff([]string{"FailedScheduling", "Scheduled"}, reasons); diff != "" {
+		t.Errorf("unexpected events: %s", diff)
+	}
+}
+
+func TestOOMKilledContainers(t *testing.T) {
+	terminated := func(name, reason string) v1.ContainerStatus {
+		return v1.ContainerStatus{Name: name, State: v1.ContainerState{Terminated: &v1.ContainerStateTerminated{ExitCode: 137, Reason: reason}}}
+	}
+	pod := &v1.Pod{
+		Spec: v1.PodSpec{
+			InitContainers: []v1.Container{{Name: "cp-secret-wrapper"}},
+			Containers: []v1.Container{{
+				Name: "test",
+				Resources: v1.ResourceRequirements{
+					Requests: v1.ResourceList{v1.ResourceMemory: resource.MustParse("3Gi")},
+					Limits:   v1.ResourceList{v1.ResourceMemory: resource.MustParse("4Gi")},
+				},
+			}, {
+				Name: "sidecar",
+			}},
+		},
+		Status: v1.PodStatus{
+			InitContainerStatuses: []v1.ContainerStatus{terminated("cp-secret-wrapper", "OOMKilled")},
+			ContainerStatuses:     []v1.ContainerStatus{terminated("test", "OOMKilled"), terminated("sidecar", "Error")},
+		},
+	}
+	kills := oomKilledContainers(pod)
+	usage := resource.MustParse("3277Mi")
+	kills[1].usage = &usage
+	var messages []string
+	var testCases []*junit.TestCase
+	for _, kill := range kills {
+		messages = append(messages, kill.String())
+		testCases = append(testCases, kill.testCase("prefix - "))
+	}
+	expectedMessages := []string{
+		"container cp-secret-wrapper was OOM killed",
+		"container test was OOM killed at its memory limit of 4Gi, memory request 3Gi, p95 historical usage 3277Mi, consider raising `resources.limits.memory` for the step",
+	}
+	if diff := cmp.Diff(expectedMessages, messages); diff != "" {
+		t.Errorf("unexpected messages: %s", diff)
+	}
+	expectedTestCases := []*junit.TestCase{{
+		Name:          "prefix - container cp-secret-wrapper is not OOM killed",
+		Properties:    []*junit.TestSuiteProperty{{Name: "oom_killed", Value: "true"}},
+		FailureOutput: &junit.FailureOutput{Output: expectedMessages[0]},
+	}, {
+		Name: "prefix - container test is not OOM killed",
+		Properties: []*junit.TestSuiteProperty{
+			{Name: "oom_killed", Value: "true"},
+			{Name: "memory_limit", Value: "4Gi"},
+			{Name: "memory_request", Value: "3Gi"},
+			{Name: "memory_usage_p95", Value: "3277Mi"},
+		},
+		FailureOutput: &junit.FailureOutput{Output: expectedMessages[1]},
+	}}
+	if diff := cmp.Diff(expectedTestCases, testCases); diff != "" {
+		t.Errorf("unexpected test cases: %s", diff)
+	}
+}
@@ -0,0 +1,82 @@
+package multi_stage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	coreapi "k8s.io/api/core/v1"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/junit"
+	"github.com/openshift/ci-tools/pkg/results"
+	base_steps "github.com/openshift/ci-tools/pkg/steps"
+)
+
+// stepFor returns the step a pod was generated for.
+func (s *multiStageTestStep) stepFor(pod *coreapi.Pod) (api.LiteralTestStep, bool) {
+	name := pod.Labels[base_steps.LabelMetadataStep]
+	for _, step := range s.allSteps() {
+		if step.As == name {
+			return step, true
+		}
+	}
+	return api.LiteralTestStep{}, false
+}
+
+// checkSharedFiles verifies that the files required by the step of a pod are
+// present in the shared directory before the pod is created.
+func (s *multiStageTestStep) checkSharedFiles(ctx context.Context, pod *coreapi.Pod) error {
+	step, ok := s.stepFor(pod)
+	if !ok || len(step.RequiresSharedFiles) == 0 {
+		return nil
+	}
+	secret := &coreapi.Secret{}
+	if err := s.client.Get(ctx, ctrlruntimeclient.ObjectKey{Namespace: s.jobSpec.Namespace(), Name: s.name}, secret); err != nil {
+		return fmt.Errorf("failed to get the shared directory: %w", err)
+	}
+	var missing []string
+	for _, file := range step.RequiresSharedFiles {
+		if _, ok := secret.Data[file]; !ok {
+			missing = append(missing, file)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	s.subLock.Lock()
+	defer s.subLock.Unlock()
+	var ran []string
+	for _, sub := range s.subSteps {
+		ran = append(ran, strings.TrimPrefix(sub.StepName, s.name+"-"))
+	}
+	var problems []string
+	for _, file := range missing {
+		if producer, ok := s.producerOf(file); ok {
+			problems = append(problems, fmt.Sprintf("producer step %s did not provide file %s", producer, file))
+		} else if len(ran) != 0 {
+			problems = append(problems, fmt.Sprintf("file %s was not provided by any of the previous steps (%s)", file, strings.Join(ran, ", ")))
+		} else {
+			problems = append(problems, fmt.Sprintf("file %s was not provided, as no step ran before", file))
+		}
+	}
+	err := results.ForReason("missing_shared_files").ForError(fmt.Errorf("step %s is missing files in the shared directory: %s", step.As, strings.Join(problems, "; ")))
+	s.subTests = append(s.subTests, &junit.TestCase{
+		Name:          fmt.Sprintf("%s - %s required shared files", s.Description(), pod.Name),
+		FailureOutput: &junit.FailureOutput{Output: err.Error()},
+	})
+	return &results.DependencyError{Err: err}
+}
+
+// producerOf returns the first step which declares that it provides a file.
+func (s *multiStageTestStep) producerOf(file string) (string, bool) {
+	for _, step := range s.allSteps() {
+		for _, f := range step.ProvidesSharedFiles {
+			if f == file {
+				return step.As, true
+			}
+		}
+	}
+	return "", false
+}
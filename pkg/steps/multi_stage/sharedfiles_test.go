@@ -0,0 +1,93 @@
+package multi_stage
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	coreapi "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakectrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/kubernetes"
+	"github.com/openshift/ci-tools/pkg/results"
+	base_steps "github.com/openshift/ci-tools/pkg/steps"
+	"github.com/openshift/ci-tools/pkg/steps/loggingclient"
+)
+
+func TestCheckSharedFiles(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		step     string
+		provides []string
+		ran      []string
+		expected string
+	}{{
+		name: "all files present",
+		step: "e2e",
+	}, {
+		name: "step without requirements",
+		step: "install",
+	}, {
+		name:     "missing file after previous steps",
+		step:     "gather",
+		ran:      []string{"test-install", "test-e2e"},
+		expected: "step gather is missing files in the shared directory: file metadata.json was not provided by any of the previous steps (install, e2e)",
+	}, {
+		name:     "missing file without previous steps",
+		step:     "gather",
+		expected: "step gather is missing files in the shared directory: file metadata.json was not provided, as no step ran before",
+	}, {
+		name:     "missing file declared by a producer",
+		step:     "gather",
+		provides: []string{"metadata.json"},
+		ran:      []string{"test-install", "test-e2e"},
+		expected: "step gather is missing files in the shared directory: producer step install did not provide file metadata.json",
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			jobSpec := api.JobSpec{}
+			jobSpec.SetNamespace("ns")
+			client := kubernetes.NewPodClient(loggingclient.New(fakectrlruntimeclient.NewClientBuilder().WithObjects(
+				&coreapi.Secret{ObjectMeta: meta.ObjectMeta{Namespace: "ns", Name: "test"}, Data: map[string][]byte{"kubeconfig": {}}},
+			).Build()), nil, nil, 0)
+			s := multiStageTestStep{
+				name:    "test",
+				jobSpec: &jobSpec,
+				client:  client,
+				subLock: &sync.Mutex{},
+				pre:     []api.LiteralTestStep{{As: "install", ProvidesSharedFiles: tc.provides}},
+				test:    []api.LiteralTestStep{{As: "e2e", RequiresSharedFiles: []string{"kubeconfig"}}},
+				post:    []api.LiteralTestStep{{As: "gather", RequiresSharedFiles: []string{"kubeconfig", "metadata.json"}}},
+			}
+			for _, name := range tc.ran {
+				s.subSteps = append(s.subSteps, api.CIOperatorStepDetailInfo{StepName: name})
+			}
+			pod := &coreapi.Pod{ObjectMeta: meta.ObjectMeta{Name: "test-" + tc.step, Labels: map[string]string{base_steps.LabelMetadataStep: tc.step}}}
+			err := s.checkSharedFiles(context.Background(), pod)
+			if tc.expected == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if len(s.subTests) != 0 {
+					t.Errorf("unexpected test cases: %v", s.subTests)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if diff := cmp.Diff(tc.expected, err.Error()); diff != "" {
+				t.Errorf("unexpected error: %s", diff)
+			}
+			if diff := cmp.Diff([]string{"missing_shared_files"}, results.Reasons(err)); diff != "" {
+				t.Errorf("unexpected reasons: %s", diff)
+			}
+			if len(s.subTests) != 1 || s.subTests[0].FailureOutput == nil {
+				t.Errorf("expected a failed test case, got %v", s.subTests)
+			}
+		})
+	}
+}
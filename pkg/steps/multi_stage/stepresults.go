@@ -0,0 +1,53 @@
+package multi_stage
+
+import (
+	"strings"
+
+	coreapi "k8s.io/api/core/v1"
+
+	base_steps "github.com/openshift/ci-tools/pkg/steps"
+)
+
+const (
+	// FailedStepsEnv is the env we use to expose the names of the steps
+	// which failed before the `post` phase, separated by spaces.
+	FailedStepsEnv = "FAILED_STEPS"
+	// TestPhaseResultEnv is the env we use to expose the result of the
+	// `test` phase to the `post` phase.
+	TestPhaseResultEnv = "TEST_PHASE_RESULT"
+)
+
+// Results of the `test` phase.
+const (
+	testPhaseSucceeded = "succeeded"
+	testPhaseFailed    = "failed"
+	// testPhaseSkipped is the result when the `test` phase did not run, as
+	// the `pre` phase or the cluster health gate failed.
+	testPhaseSkipped = "skipped"
+)
+
+// recordFailedStep records that the step of a pod failed.  The shards of a
+// step are recorded once.
+func (s *multiStageTestStep) recordFailedStep(pod *coreapi.Pod) {
+	name := pod.Labels[base_steps.LabelMetadataStep]
+	s.subLock.Lock()
+	defer s.subLock.Unlock()
+	for _, failed := range s.failedSteps {
+		if failed == name {
+			return
+		}
+	}
+	s.failedSteps = append(s.failedSteps, name)
+}
+
+// stepResultsEnv describes the results of the steps which ran before the
+// `post` phase, so that its steps can adjust what they do, e.g. skip
+// gathering from a cluster which was never installed.
+func (s *multiStageTestStep) stepResultsEnv(testPhaseResult string) []coreapi.EnvVar {
+	s.subLock.Lock()
+	defer s.subLock.Unlock()
+	return []coreapi.EnvVar{
+		{Name: FailedStepsEnv, Value: strings.Join(s.failedSteps, " ")},
+		{Name: TestPhaseResultEnv, Value: testPhaseResult},
+	}
+}
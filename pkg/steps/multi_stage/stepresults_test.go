@@ -0,0 +1,59 @@
+package multi_stage
+
+import (
+	"sync"
+	"testing"
+
+	coreapi "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	base_steps "github.com/openshift/ci-tools/pkg/steps"
+	"github.com/openshift/ci-tools/pkg/testhelper"
+)
+
+func TestStepResultsEnv(t *testing.T) {
+	pod := func(name, step string) *coreapi.Pod {
+		return &coreapi.Pod{ObjectMeta: meta.ObjectMeta{Name: name, Labels: map[string]string{base_steps.LabelMetadataStep: step}}}
+	}
+	for _, tc := range []struct {
+		name     string
+		failed   []*coreapi.Pod
+		result   string
+		expected []coreapi.EnvVar
+	}{{
+		name:   "no failures",
+		result: testPhaseSucceeded,
+		expected: []coreapi.EnvVar{
+			{Name: FailedStepsEnv},
+			{Name: TestPhaseResultEnv, Value: "succeeded"},
+		},
+	}, {
+		name:   "installation failed",
+		failed: []*coreapi.Pod{pod("test-ipi-install", "ipi-install")},
+		result: testPhaseSkipped,
+		expected: []coreapi.EnvVar{
+			{Name: FailedStepsEnv, Value: "ipi-install"},
+			{Name: TestPhaseResultEnv, Value: "skipped"},
+		},
+	}, {
+		name: "shards are reported once",
+		failed: []*coreapi.Pod{
+			pod("test-e2e-0", "e2e"),
+			pod("test-e2e-2", "e2e"),
+			pod("test-conformance", "conformance"),
+		},
+		result: testPhaseFailed,
+		expected: []coreapi.EnvVar{
+			{Name: FailedStepsEnv, Value: "e2e conformance"},
+			{Name: TestPhaseResultEnv, Value: "failed"},
+		},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			s := multiStageTestStep{subLock: &sync.Mutex{}}
+			for _, pod := range tc.failed {
+				s.recordFailedStep(pod)
+			}
+			testhelper.Diff(t, "env", s.stepResultsEnv(tc.result), tc.expected)
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package multi_stage
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/junit"
+	"github.com/openshift/ci-tools/pkg/steps/stepruntime"
+)
+
+// StepOutputsArtifact is the name of the artifact file, relative to the
+// test's artifact directory, which holds the values published by the steps
+// with `ci-step output set`, by step.
+const StepOutputsArtifact = "step-outputs.json"
+
+// FlakyProperty is the name of the jUnit property of the test cases which
+// report the flakes observed by a step with `ci-step mark-flaky`, with the
+// reason given by the step as the value.
+const FlakyProperty = "step_flaky"
+
+// recordStepRuntime reports the requests made by the command of a step with
+// the ci-step helper.  Flakes are reported as test cases and outputs are kept
+// for the outputs artifact.  The caller must hold subLock.
+func (s *multiStageTestStep) recordStepRuntime(step string, raw []byte) error {
+	record, err := stepruntime.Decode(raw)
+	if err != nil {
+		return err
+	}
+	for _, reason := range record.Flaky {
+		s.subTests = append(s.subTests, &junit.TestCase{
+			Name:       fmt.Sprintf("%s - %s-%s flaky", s.Description(), s.name, step),
+			Properties: []*junit.TestSuiteProperty{{Name: FlakyProperty, Value: reason}},
+			SystemOut:  fmt.Sprintf("step %s-%s reported a flake: %s", s.name, step, reason),
+		})
+	}
+	if len(record.Outputs) != 0 {
+		if s.stepOutputs == nil {
+			s.stepOutputs = map[string]map[string]string{}
+		}
+		s.stepOutputs[step] = record.Outputs
+	}
+	return nil
+}
+
+// saveStepOutputs writes the values published by the steps of the test to
+// the artifact directory, if any.
+func (s *multiStageTestStep) saveStepOutputs() error {
+	s.subLock.Lock()
+	defer s.subLock.Unlock()
+	if len(s.stepOutputs) == 0 {
+		return nil
+	}
+	data, err := json.MarshalIndent(s.stepOutputs, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal step outputs: %w", err)
+	}
+	return api.SaveArtifact(s.censor, path.Join(s.name, StepOutputsArtifact), data)
+}
@@ -0,0 +1,190 @@
+package multi_stage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	coreapi "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/kubernetes"
+	base_steps "github.com/openshift/ci-tools/pkg/steps"
+)
+
+// TimelineArtifact lists the transitions of the steps of a test in the order
+// in which they happened.
+const TimelineArtifact = "timeline.txt"
+
+// Reasons of the events recorded for the transitions of the steps of a test.
+const (
+	eventStepCreated   = "StepCreated"
+	eventStepStarted   = "StepStarted"
+	eventStepSucceeded = "StepSucceeded"
+	eventStepFailed    = "StepFailed"
+)
+
+const (
+	// stepStartInterval is how often the pod of a step is checked until its
+	// container has started.
+	stepStartInterval = 10 * time.Second
+	// maxEventMessageLength is the length above which the messages of events
+	// are truncated, as is done by the event recorders of Kubernetes.
+	maxEventMessageLength = 1024
+)
+
+// timelineEntry is a transition of a step.
+type timelineEntry struct {
+	time      time.Time
+	eventType string
+	reason    string
+	step      string
+	message   string
+}
+
+// recordStepEvent adds a transition of a step to the timeline of the test and
+// records it as an event of the shared directory of the test, which exists as
+// long as the test runs, so that `oc get events` lists the transitions of all
+// the steps of the test together.  The event is best-effort.
+func (s *multiStageTestStep) recordStepEvent(ctx context.Context, pod *coreapi.Pod, at time.Time, eventType, reason, message string) {
+	step := strings.TrimPrefix(pod.Name, s.name+"-")
+	s.subLock.Lock()
+	s.timeline = append(s.timeline, timelineEntry{time: at, eventType: eventType, reason: reason, step: step, message: message})
+	s.subLock.Unlock()
+	if s.censor != nil {
+		data := []byte(message)
+		s.censor.Censor(&data)
+		message = string(data)
+	}
+	if len(message) > maxEventMessageLength {
+		message = message[:maxEventMessageLength-3] + "..."
+	}
+	namespace := s.jobSpec.Namespace()
+	timestamp := meta.NewTime(at)
+	event := &coreapi.Event{
+		ObjectMeta: meta.ObjectMeta{
+			Namespace: namespace,
+			Name:      fmt.Sprintf("%s.%x", pod.Name, at.UnixNano()),
+			Labels:    map[string]string{MultiStageTestLabel: s.name},
+		},
+		InvolvedObject: coreapi.ObjectReference{APIVersion: "v1", Kind: "Secret", Namespace: namespace, Name: s.name},
+		Reason:         reason,
+		Message:        message,
+		Type:           eventType,
+		Source:         coreapi.EventSource{Component: "ci-operator"},
+		FirstTimestamp: timestamp,
+		LastTimestamp:  timestamp,
+		Count:          1,
+	}
+	// events are not objects of the test and are not logged with them
+	if err := s.client.WithNewLoggingClient().Create(ctx, event); err != nil {
+		logrus.WithError(err).Debugf("Failed to record event %s for step %s.", reason, pod.Name)
+	}
+}
+
+// watchedPodClient signals when the pod of a step is first watched through
+// it, i.e. when the wait for the completion of the step has begun.
+type watchedPodClient struct {
+	kubernetes.PodClient
+	once    sync.Once
+	watched chan struct{}
+}
+
+func newWatchedPodClient(client kubernetes.PodClient) *watchedPodClient {
+	return &watchedPodClient{PodClient: client, watched: make(chan struct{})}
+}
+
+func (c *watchedPodClient) Watch(ctx context.Context, list ctrlruntimeclient.ObjectList, opts ...ctrlruntimeclient.ListOption) (watch.Interface, error) {
+	ret, err := c.PodClient.Watch(ctx, list, opts...)
+	if err == nil {
+		c.once.Do(func() { close(c.watched) })
+	}
+	return ret, err
+}
+
+// recordStepStart waits for the container of a step to start, records the
+// transition and returns the pod of the step as it started.  The pod is only
+// polled once `watched` is closed, after the wait for the completion of the
+// step observed it first.  It returns false if the context is done first.
+func (s *multiStageTestStep) recordStepStart(ctx context.Context, client kubernetes.PodClient, namespace, name string, watched <-chan struct{}, interval time.Duration) (*coreapi.Pod, bool) {
+	select {
+	case <-ctx.Done():
+		return nil, false
+	case <-watched:
+	}
+	var started *coreapi.Pod
+	var at time.Time
+	_ = wait.PollUntilContextCancel(ctx, interval, true, func(ctx context.Context) (bool, error) {
+		pod := &coreapi.Pod{}
+		if err := client.Get(ctx, ctrlruntimeclient.ObjectKey{Namespace: namespace, Name: name}, pod); err != nil {
+			logrus.WithError(err).Debugf("Failed to get pod %s to determine whether it started.", name)
+			return false, nil
+		}
+		for _, status := range pod.Status.ContainerStatuses {
+			if status.Name == containerName && status.State.Running != nil {
+				// the object given to the client is not kept, in case the
+				// client still refers to it
+				started, at = pod.DeepCopy(), status.State.Running.StartedAt.Time
+				return true, nil
+			}
+		}
+		return false, nil
+	})
+	if started == nil {
+		return nil, false
+	}
+	s.recordStepEvent(ctx, started, at, coreapi.EventTypeNormal, eventStepStarted, fmt.Sprintf("Step %s started", name))
+	return started, true
+}
+
+// recordStepEnd records the result of a step, with the reason of its failure.
+func (s *multiStageTestStep) recordStepEnd(pod *coreapi.Pod, at time.Time, duration time.Duration, err error) {
+	if err == nil {
+		s.recordStepEvent(base_steps.CleanupCtx, pod, at, coreapi.EventTypeNormal, eventStepSucceeded, fmt.Sprintf("Step %s succeeded after %s", pod.Name, duration.Truncate(time.Second)))
+		return
+	}
+	s.recordStepEvent(base_steps.CleanupCtx, pod, at, coreapi.EventTypeWarning, eventStepFailed, fmt.Sprintf("Step %s failed after %s: %v", pod.Name, duration.Truncate(time.Second), err))
+}
+
+// saveTimeline writes the transitions of the steps of the test in the order in
+// which they happened.
+func (s *multiStageTestStep) saveTimeline() error {
+	s.subLock.Lock()
+	entries := make([]timelineEntry, len(s.timeline))
+	copy(entries, s.timeline)
+	s.subLock.Unlock()
+	if len(entries) == 0 {
+		return nil
+	}
+	return api.SaveArtifact(s.censor, path.Join(s.name, TimelineArtifact), renderTimeline(entries))
+}
+
+// renderTimeline formats transitions as a table sorted by time, with the
+// offset of each from the first one.
+func renderTimeline(entries []timelineEntry) []byte {
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].time.Before(entries[j].time)
+	})
+	var buf bytes.Buffer
+	w := tabwriter.NewWriter(&buf, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "TIME\tOFFSET\tTYPE\tREASON\tSTEP\tMESSAGE")
+	for _, entry := range entries {
+		// the messages of failures may span multiple lines
+		message := strings.ReplaceAll(strings.TrimSpace(entry.message), "\n", " ")
+		fmt.Fprintf(w, "%s\t+%s\t%s\t%s\t%s\t%s\n", entry.time.UTC().Format(time.RFC3339), entry.time.Sub(entries[0].time).Truncate(time.Second), entry.eventType, entry.reason, entry.step, message)
+	}
+	_ = w.Flush()
+	return buf.Bytes()
+}
@@ -0,0 +1,146 @@
+package multi_stage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	coreapi "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	fakectrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/kubernetes"
+	"github.com/openshift/ci-tools/pkg/secrets"
+	"github.com/openshift/ci-tools/pkg/steps/loggingclient"
+	"github.com/openshift/ci-tools/pkg/testhelper"
+)
+
+func timelineTestStep(objects ...ctrlruntimeclient.Object) *multiStageTestStep {
+	jobSpec := api.JobSpec{}
+	jobSpec.SetNamespace("ns")
+	censor := secrets.NewDynamicCensor()
+	censor.AddSecrets("hunter2")
+	return &multiStageTestStep{
+		name:    "test",
+		jobSpec: &jobSpec,
+		client:  kubernetes.NewPodClient(loggingclient.New(fakectrlruntimeclient.NewClientBuilder().WithObjects(objects...).Build()), nil, nil, 0),
+		subLock: &sync.Mutex{},
+		censor:  &censor,
+	}
+}
+
+func TestRecordStepEvent(t *testing.T) {
+	s := timelineTestStep()
+	pod := &coreapi.Pod{ObjectMeta: meta.ObjectMeta{Namespace: "ns", Name: "test-e2e"}}
+	at := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	s.recordStepEvent(context.Background(), pod, at, coreapi.EventTypeWarning, eventStepFailed, "Step test-e2e failed: invalid password hunter2"+strings.Repeat(".", maxEventMessageLength))
+	events := &coreapi.EventList{}
+	if err := s.client.List(context.Background(), events, ctrlruntimeclient.InNamespace("ns")); err != nil {
+		t.Fatal(err)
+	}
+	if len(events.Items) != 1 {
+		t.Fatalf("expected one event, got %d", len(events.Items))
+	}
+	event := events.Items[0]
+	if n := len(event.Message); n != maxEventMessageLength {
+		t.Errorf("expected the message to be truncated to %d characters, got %d", maxEventMessageLength, n)
+	}
+	if !strings.HasPrefix(event.Message, "Step test-e2e failed: invalid password XXXXXXX...") || !strings.HasSuffix(event.Message, "...") {
+		t.Errorf("unexpected message: %s", event.Message)
+	}
+	event.ObjectMeta.ResourceVersion, event.Message = "", ""
+	testhelper.Diff(t, "event", event, coreapi.Event{
+		ObjectMeta: meta.ObjectMeta{
+			Namespace: "ns",
+			Name:      fmt.Sprintf("test-e2e.%x", at.UnixNano()),
+			Labels:    map[string]string{MultiStageTestLabel: "test"},
+		},
+		InvolvedObject: coreapi.ObjectReference{APIVersion: "v1", Kind: "Secret", Namespace: "ns", Name: "test"},
+		Reason:         eventStepFailed,
+		Type:           coreapi.EventTypeWarning,
+		Source:         coreapi.EventSource{Component: "ci-operator"},
+		FirstTimestamp: meta.NewTime(at),
+		LastTimestamp:  meta.NewTime(at),
+		Count:          1,
+	})
+	testhelper.Diff(t, "timeline steps", len(s.timeline), 1)
+	testhelper.Diff(t, "timeline step", s.timeline[0].step, "e2e")
+}
+
+func TestRecordStepStart(t *testing.T) {
+	started := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		name       string
+		pod        *coreapi.Pod
+		notWatched bool
+		expected   []timelineEntry
+	}{{
+		name: "container started",
+		pod: &coreapi.Pod{
+			ObjectMeta: meta.ObjectMeta{Namespace: "ns", Name: "test-e2e"},
+			Status: coreapi.PodStatus{ContainerStatuses: []coreapi.ContainerStatus{{
+				Name:  containerName,
+				State: coreapi.ContainerState{Running: &coreapi.ContainerStateRunning{StartedAt: meta.NewTime(started)}},
+			}}},
+		},
+		expected: []timelineEntry{{time: started, eventType: coreapi.EventTypeNormal, reason: eventStepStarted, step: "e2e", message: "Step test-e2e started"}},
+	}, {
+		name: "container waiting",
+		pod: &coreapi.Pod{
+			ObjectMeta: meta.ObjectMeta{Namespace: "ns", Name: "test-e2e"},
+			Status: coreapi.PodStatus{ContainerStatuses: []coreapi.ContainerStatus{{
+				Name:  containerName,
+				State: coreapi.ContainerState{Waiting: &coreapi.ContainerStateWaiting{Reason: "ContainerCreating"}},
+			}}},
+		},
+	}, {
+		name: "pod not watched yet",
+		pod: &coreapi.Pod{
+			ObjectMeta: meta.ObjectMeta{Namespace: "ns", Name: "test-e2e"},
+			Status: coreapi.PodStatus{ContainerStatuses: []coreapi.ContainerStatus{{
+				Name:  containerName,
+				State: coreapi.ContainerState{Running: &coreapi.ContainerStateRunning{StartedAt: meta.NewTime(started)}},
+			}}},
+		},
+		notWatched: true,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			s := timelineTestStep(tc.pod)
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+			watched := make(chan struct{})
+			if !tc.notWatched {
+				close(watched)
+			}
+			pod, ok := s.recordStepStart(ctx, s.client, "ns", "test-e2e", watched, 10*time.Millisecond)
+			testhelper.Diff(t, "started", ok, tc.expected != nil)
+			if ok && pod.Name != "test-e2e" {
+				t.Errorf("unexpected pod: %s", pod.Name)
+			}
+			testhelper.Diff(t, "timeline", s.timeline, tc.expected, cmp.AllowUnexported(timelineEntry{}))
+		})
+	}
+}
+
+func TestRenderTimeline(t *testing.T) {
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	entries := []timelineEntry{
+		{time: start.Add(90 * time.Second), eventType: coreapi.EventTypeNormal, reason: eventStepStarted, step: "install", message: "Step test-install started"},
+		{time: start, eventType: coreapi.EventTypeNormal, reason: eventStepCreated, step: "install", message: "Created pod test-install"},
+		{time: start.Add(time.Hour), eventType: coreapi.EventTypeWarning, reason: eventStepFailed, step: "install", message: "Step test-install failed after 58m30s: the pod failed\nwith exit code 1\n"},
+		{time: start.Add(time.Hour), eventType: coreapi.EventTypeNormal, reason: eventStepCreated, step: "gather", message: "Created pod test-gather"},
+	}
+	testhelper.Diff(t, "timeline", string(renderTimeline(entries)), `TIME                  OFFSET   TYPE     REASON       STEP     MESSAGE
+2024-01-01T10:00:00Z  +0s      Normal   StepCreated  install  Created pod test-install
+2024-01-01T10:01:30Z  +1m30s   Normal   StepStarted  install  Step test-install started
+2024-01-01T11:00:00Z  +1h0m0s  Warning  StepFailed   install  Step test-install failed after 58m30s: the pod failed with exit code 1
+2024-01-01T11:00:00Z  +1h0m0s  Normal   StepCreated  gather   Created pod test-gather
+`)
+}
@@ -0,0 +1,114 @@
+package multi_stage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	coreapi "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/remotecommand"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/kubernetes"
+)
+
+const (
+	// TimeoutSnapshotArtifact is the state of a step captured shortly before
+	// it was killed for exceeding its timeout.
+	TimeoutSnapshotArtifact = "timeout-snapshot.txt"
+	// timeoutSnapshotLogLines is the number of lines of the output of the
+	// step included in the snapshot.
+	timeoutSnapshotLogLines = 200
+)
+
+// timeoutSnapshotScript lists the processes of the container of a step and
+// the files of the shared directory.  The content of the files is not
+// captured, as the shared directory holds the credentials of the cluster.
+const timeoutSnapshotScript = `echo "=== processes"
+ps -eo pid,ppid,etime,args 2>/dev/null || for p in /proc/[0-9]*; do printf '%s %s\n' "${p#/proc/}" "$(tr '\0' ' ' < "${p}/cmdline" 2>/dev/null)"; done
+echo "=== ${SHARED_DIR}"
+ls -la "${SHARED_DIR}"`
+
+// stepDeadline returns when a step is killed: when its timeout expires after
+// its container started or when the active deadline of its pod expires,
+// whichever comes first.  The deadline is unknown until the container runs.
+func stepDeadline(pod *coreapi.Pod, timeout time.Duration) (time.Time, bool) {
+	var deadline time.Time
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.Name == containerName && status.State.Running != nil {
+			deadline = status.State.Running.StartedAt.Add(timeout)
+		}
+	}
+	if deadline.IsZero() {
+		return deadline, false
+	}
+	if seconds := pod.Spec.ActiveDeadlineSeconds; seconds != nil && pod.Status.StartTime != nil {
+		if active := pod.Status.StartTime.Add(time.Duration(*seconds) * time.Second); active.Before(deadline) {
+			deadline = active
+		}
+	}
+	return deadline, true
+}
+
+// watchTimeout captures a snapshot of the state of a step whose container
+// started when it is about to be killed for exceeding its deadline, `lead`
+// before it expires.  Nothing is captured if the context is done first, i.e.
+// if the step completes in time.
+func (s *multiStageTestStep) watchTimeout(ctx context.Context, client kubernetes.PodClient, pod *coreapi.Pod, timeout, lead time.Duration) {
+	deadline, ok := stepDeadline(pod, timeout)
+	if !ok {
+		return
+	}
+	select {
+	case <-ctx.Done():
+		return
+	case <-time.After(time.Until(deadline.Add(-lead))):
+	}
+	namespace, name := pod.Namespace, pod.Name
+	logrus.Infof("Step %s is about to time out, capturing a snapshot of its state.", name)
+	snapshotCtx, cancel := context.WithDeadline(ctx, deadline)
+	defer cancel()
+	data := s.timeoutSnapshot(snapshotCtx, client, namespace, name, deadline)
+	if err := api.SaveArtifact(s.censor, path.Join(s.name, strings.TrimPrefix(name, s.name+"-"), TimeoutSnapshotArtifact), data); err != nil {
+		logrus.WithError(err).Warnf("Failed to save the snapshot of step %s.", name)
+	}
+}
+
+// timeoutSnapshot captures, as far as possible, the processes of a step, the
+// files of its shared directory and the last lines of its output.  Failures
+// to capture a part are recorded in its place.
+func (s *multiStageTestStep) timeoutSnapshot(ctx context.Context, client kubernetes.PodClient, namespace, name string, deadline time.Time) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "Snapshot of step %s taken at %s, before it times out at %s.\n", name, time.Now().UTC().Format(time.RFC3339), deadline.UTC().Format(time.RFC3339))
+	exec, err := client.Exec(namespace, name, &coreapi.PodExecOptions{
+		Container: containerName,
+		Stdout:    true,
+		Stderr:    true,
+		Command:   []string{"/bin/sh", "-c", timeoutSnapshotScript},
+	})
+	if err == nil {
+		err = exec.StreamWithContext(ctx, remotecommand.StreamOptions{Stdout: &buf, Stderr: &buf})
+	}
+	if err != nil {
+		fmt.Fprintf(&buf, "failed to inspect the container: %v\n", err)
+	}
+	fmt.Fprintf(&buf, "=== last %d lines of output\n", timeoutSnapshotLogLines)
+	lines := int64(timeoutSnapshotLogLines)
+	stream, err := client.GetLogs(namespace, name, &coreapi.PodLogOptions{Container: containerName, TailLines: &lines}).Stream(ctx)
+	if err == nil {
+		_, err = io.Copy(&buf, stream)
+		if closeErr := stream.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(&buf, "failed to get the output: %v\n", err)
+	}
+	return buf.Bytes()
+}
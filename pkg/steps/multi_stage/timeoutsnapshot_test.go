@@ -0,0 +1,194 @@
+package multi_stage
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	coreapi "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	fakerest "k8s.io/client-go/rest/fake"
+	"k8s.io/client-go/tools/remotecommand"
+	utilpointer "k8s.io/utils/pointer"
+	fakectrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/openshift/ci-tools/pkg/kubernetes"
+	"github.com/openshift/ci-tools/pkg/secrets"
+	"github.com/openshift/ci-tools/pkg/steps/loggingclient"
+	"github.com/openshift/ci-tools/pkg/testhelper"
+)
+
+func TestStepDeadline(t *testing.T) {
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	running := func(started time.Time) []coreapi.ContainerStatus {
+		return []coreapi.ContainerStatus{
+			{Name: "sidecar", State: coreapi.ContainerState{Running: &coreapi.ContainerStateRunning{StartedAt: meta.NewTime(start)}}},
+			{Name: containerName, State: coreapi.ContainerState{Running: &coreapi.ContainerStateRunning{StartedAt: meta.NewTime(started)}}},
+		}
+	}
+	for _, tc := range []struct {
+		name     string
+		pod      coreapi.Pod
+		expected time.Time
+		known    bool
+	}{{
+		name: "container not started",
+		pod: coreapi.Pod{Status: coreapi.PodStatus{ContainerStatuses: []coreapi.ContainerStatus{
+			{Name: containerName, State: coreapi.ContainerState{Waiting: &coreapi.ContainerStateWaiting{}}},
+		}}},
+	}, {
+		name:     "timeout after the container started",
+		pod:      coreapi.Pod{Status: coreapi.PodStatus{ContainerStatuses: running(start.Add(time.Minute))}},
+		expected: start.Add(time.Hour + time.Minute),
+		known:    true,
+	}, {
+		name: "active deadline expires first",
+		pod: coreapi.Pod{
+			Spec:   coreapi.PodSpec{ActiveDeadlineSeconds: utilpointer.Int64(1800)},
+			Status: coreapi.PodStatus{StartTime: &meta.Time{Time: start}, ContainerStatuses: running(start.Add(time.Minute))},
+		},
+		expected: start.Add(30 * time.Minute),
+		known:    true,
+	}, {
+		name: "timeout expires first",
+		pod: coreapi.Pod{
+			Spec:   coreapi.PodSpec{ActiveDeadlineSeconds: utilpointer.Int64(7200)},
+			Status: coreapi.PodStatus{StartTime: &meta.Time{Time: start}, ContainerStatuses: running(start.Add(time.Minute))},
+		},
+		expected: start.Add(time.Hour + time.Minute),
+		known:    true,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			deadline, known := stepDeadline(&tc.pod, time.Hour)
+			testhelper.Diff(t, "known", known, tc.known)
+			testhelper.Diff(t, "deadline", deadline, tc.expected)
+		})
+	}
+}
+
+// snapshotPodClient executes commands in pods by printing their output.
+type snapshotPodClient struct {
+	kubernetes.PodClient
+	output   string
+	commands [][]string
+}
+
+func (c *snapshotPodClient) Exec(_, _ string, opts *coreapi.PodExecOptions) (remotecommand.Executor, error) {
+	c.commands = append(c.commands, opts.Command)
+	return snapshotExecutor{output: c.output}, nil
+}
+
+type snapshotExecutor struct {
+	output string
+}
+
+func (e snapshotExecutor) Stream(opts remotecommand.StreamOptions) error {
+	return e.StreamWithContext(context.Background(), opts)
+}
+
+func (e snapshotExecutor) StreamWithContext(_ context.Context, opts remotecommand.StreamOptions) error {
+	_, err := io.WriteString(opts.Stdout, e.output)
+	return err
+}
+
+func TestWatchTimeout(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		started  time.Duration
+		expected string
+	}{{
+		name:    "step about to time out",
+		started: -59 * time.Minute,
+		expected: `=== processes
+  PID  PPID     ELAPSED COMMAND
+    1     0       59:00 /bin/bash /var/run/configmaps/ci.openshift.io/multi-stage/e2e
+=== /var/run/secrets/ci.openshift.io/multi-stage
+-rw-r--r-- 1 root root 12 kubeconfig
+=== last 200 lines of output
+Waiting for the cluster...
+`,
+	}, {
+		name:    "step completes before its deadline",
+		started: -time.Minute,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			t.Setenv("ARTIFACTS", dir)
+			var requested string
+			rest := &fakerest.RESTClient{
+				NegotiatedSerializer: scheme.Codecs.WithoutConversion(),
+				GroupVersion:         coreapi.SchemeGroupVersion,
+				Client: fakerest.CreateHTTPClient(func(req *http.Request) (*http.Response, error) {
+					requested = req.URL.RequestURI()
+					return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("Waiting for the cluster...\n"))}, nil
+				}),
+			}
+			client := &snapshotPodClient{
+				PodClient: kubernetes.NewPodClient(loggingclient.New(fakectrlruntimeclient.NewClientBuilder().Build()), nil, rest, 0),
+				output:    "=== processes\n  PID  PPID     ELAPSED COMMAND\n    1     0       59:00 /bin/bash /var/run/configmaps/ci.openshift.io/multi-stage/e2e\n=== /var/run/secrets/ci.openshift.io/multi-stage\n-rw-r--r-- 1 root root 12 kubeconfig\n",
+			}
+			censor := secrets.NewDynamicCensor()
+			s := &multiStageTestStep{name: "test", censor: &censor}
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			pod := &coreapi.Pod{
+				ObjectMeta: meta.ObjectMeta{Namespace: "ns", Name: "test-e2e"},
+				Status: coreapi.PodStatus{ContainerStatuses: []coreapi.ContainerStatus{{
+					Name:  containerName,
+					State: coreapi.ContainerState{Running: &coreapi.ContainerStateRunning{StartedAt: meta.NewTime(time.Now().Add(tc.started))}},
+				}}},
+			}
+			s.watchTimeout(ctx, client, pod, time.Hour, 2*time.Minute)
+			data, err := os.ReadFile(filepath.Join(dir, "test", "e2e", TimeoutSnapshotArtifact))
+			if tc.expected == "" {
+				if !os.IsNotExist(err) {
+					t.Fatalf("expected no snapshot, got: %v", err)
+				}
+				testhelper.Diff(t, "commands", client.commands, [][]string(nil))
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			header, snapshot, _ := strings.Cut(string(data), "\n")
+			if !strings.HasPrefix(header, "Snapshot of step test-e2e taken at ") {
+				t.Errorf("unexpected header: %s", header)
+			}
+			testhelper.Diff(t, "snapshot", snapshot, tc.expected)
+			testhelper.Diff(t, "commands", client.commands, [][]string{{"/bin/sh", "-c", timeoutSnapshotScript}})
+			testhelper.Diff(t, "request", requested, "/namespaces/ns/pods/test-e2e/log?container=test&tailLines=200")
+		})
+	}
+}
+
+func TestTimeoutSnapshotScript(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "kubeconfig"), []byte("c3VwZXJzZWNyZXQ="), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command("/bin/sh", "-c", timeoutSnapshotScript)
+	cmd.Env = append(os.Environ(), "SHARED_DIR="+dir)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("failed to run the script: %v: %s", err, out)
+	}
+	if !strings.HasPrefix(string(out), "=== processes\n") {
+		t.Errorf("expected the processes first in the output:\n%s", out)
+	}
+	// the processes of the host are listed, only the shared directory is
+	// checked
+	_, shared, found := strings.Cut(string(out), "=== "+dir+"\n")
+	if !found || !strings.Contains(shared, "kubeconfig\n") {
+		t.Errorf("expected the files of the shared directory in the output:\n%s", out)
+	}
+	if strings.Contains(shared, "c3VwZXJzZWNyZXQ=") {
+		t.Errorf("the content of the shared directory was captured:\n%s", out)
+	}
+}
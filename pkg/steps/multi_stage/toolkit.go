@@ -0,0 +1,105 @@
+package multi_stage
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"path/filepath"
+
+	coreapi "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/openshift/ci-tools/pkg/api"
+)
+
+const (
+	// ToolkitMountPath is where we mount the shell function library of steps
+	ToolkitMountPath = "/var/run/configmaps/ci.openshift.io/toolkit"
+	// ToolkitEnv is the env we use to expose the path of the library
+	ToolkitEnv = "CI_TOOLKIT"
+	// toolkitVersion is the version of the library sourced by steps.  The
+	// library is never changed in place: its ConfigMap is named after the
+	// version and immutable.
+	toolkitVersion = "v1"
+	// toolkitVolumeName is the name of the volume of the library.
+	toolkitVolumeName = "toolkit"
+)
+
+// toolkit is the shell function library of steps, sourced before their
+// commands when they set `toolkit`.
+//
+//go:embed toolkit.sh
+var toolkit string
+
+// toolkitFile is the name of the file of the current version of the library.
+func toolkitFile() string {
+	return fmt.Sprintf("toolkit-%s.sh", toolkitVersion)
+}
+
+// toolkitPath is the path of the library in the pods of steps.
+func toolkitPath() string {
+	return filepath.Join(ToolkitMountPath, toolkitFile())
+}
+
+// toolkitConfigMapName is the name of the ConfigMap which holds the current
+// version of the library, shared by the tests of the namespace.
+func toolkitConfigMapName() string {
+	return fmt.Sprintf("step-toolkit-%s", toolkitVersion)
+}
+
+func usesToolkit(step *api.LiteralTestStep) bool {
+	return step.Toolkit != nil && *step.Toolkit
+}
+
+// toolkitConfigMap returns the ConfigMap of the library if any step of the
+// test uses it.
+func (s *multiStageTestStep) toolkitConfigMap() *coreapi.ConfigMap {
+	var used bool
+	for _, step := range s.allSteps() {
+		used = used || usesToolkit(&step)
+	}
+	if !used {
+		return nil
+	}
+	yes := true
+	cm := &coreapi.ConfigMap{
+		ObjectMeta: meta.ObjectMeta{Namespace: s.jobSpec.Namespace(), Name: toolkitConfigMapName()},
+		Data:       map[string]string{toolkitFile(): toolkit},
+		Immutable:  &yes,
+	}
+	s.addMetadata(cm)
+	return cm
+}
+
+// createToolkit creates the ConfigMap of the library, which may have been
+// created by another test already.
+func (s *multiStageTestStep) createToolkit(ctx context.Context) error {
+	cm := s.toolkitConfigMap()
+	if cm == nil {
+		return nil
+	}
+	if err := s.client.Create(ctx, cm); err != nil && !kerrors.IsAlreadyExists(err) {
+		return fmt.Errorf("could not create toolkit configmap %s: %w", cm.Name, err)
+	}
+	return nil
+}
+
+// addToolkit mounts the library in the pod of a step.
+func addToolkit(pod *coreapi.Pod) {
+	pod.Spec.Volumes = append(pod.Spec.Volumes, coreapi.Volume{
+		Name: toolkitVolumeName,
+		VolumeSource: coreapi.VolumeSource{
+			ConfigMap: &coreapi.ConfigMapVolumeSource{
+				LocalObjectReference: coreapi.LocalObjectReference{Name: toolkitConfigMapName()},
+			},
+		},
+	})
+	container := &pod.Spec.Containers[0]
+	container.VolumeMounts = append(container.VolumeMounts, coreapi.VolumeMount{
+		Name:      toolkitVolumeName,
+		MountPath: ToolkitMountPath,
+		ReadOnly:  true,
+	})
+	container.Env = append(container.Env, coreapi.EnvVar{Name: ToolkitEnv, Value: toolkitPath()})
+}
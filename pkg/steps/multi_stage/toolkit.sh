@@ -0,0 +1,67 @@
+# Shell function library of multi-stage test steps, version 1.
+#
+# Sourced before the commands of steps with `toolkit: true`.  Steps running
+# their commands as-is can source it from $CI_TOOLKIT.  Functions are only
+# ever added to a version: changes in behavior are shipped as a new version.
+
+CI_TOOLKIT_VERSION=1
+
+# log prints a message to stderr, prefixed with the current time.
+#
+#   log <message>...
+function log() {
+	echo "$(date -u +%Y-%m-%dT%H:%M:%SZ) $*" >&2
+}
+
+# retry runs a command until it succeeds, at most <attempts> times, waiting
+# <delay> seconds between attempts.  The status of the last attempt is
+# returned.
+#
+#   retry <attempts> <delay> <command> [<arg>...]
+function retry() {
+	local attempts="$1" delay="$2" attempt=1 status=0
+	shift 2
+	while true; do
+		"$@" && return 0 || status=$?
+		if [[ "${attempt}" -ge "${attempts}" ]]; then
+			log "Command failed after ${attempt} attempt(s) with status ${status}: $*"
+			return "${status}"
+		fi
+		log "Attempt ${attempt}/${attempts} failed with status ${status}, retrying in ${delay}s: $*"
+		attempt=$((attempt + 1))
+		sleep "${delay}"
+	done
+}
+
+# wait_for runs a command every <interval> seconds until it succeeds or
+# <timeout> seconds have passed.
+#
+#   wait_for <timeout> <interval> <command> [<arg>...]
+function wait_for() {
+	local timeout="$1" interval="$2" deadline
+	shift 2
+	deadline=$(($(date +%s) + timeout))
+	until "$@"; do
+		if [[ "$(date +%s)" -ge "${deadline}" ]]; then
+			log "Timed out after ${timeout}s waiting for: $*"
+			return 1
+		fi
+		sleep "${interval}"
+	done
+}
+
+# collect copies files and directories to the artifacts of the step, into a
+# sub-directory if $COLLECT_DIR is set.  Missing paths are skipped.
+#
+#   collect <path>...
+function collect() {
+	local dest="${ARTIFACT_DIR:?ARTIFACT_DIR is not set}/${COLLECT_DIR:-}" path
+	mkdir -p "${dest}"
+	for path in "$@"; do
+		if [[ -e "${path}" ]]; then
+			cp -r "${path}" "${dest}"
+		else
+			log "Not collecting ${path}, which does not exist"
+		fi
+	done
+}
@@ -0,0 +1,95 @@
+package multi_stage
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	coreapi "k8s.io/api/core/v1"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	fakectrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/kubernetes"
+	"github.com/openshift/ci-tools/pkg/steps/loggingclient"
+	"github.com/openshift/ci-tools/pkg/testhelper"
+)
+
+func TestToolkitFunctions(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash is not available")
+	}
+	dir := t.TempDir()
+	library := filepath.Join(dir, toolkitFile())
+	if err := os.WriteFile(library, []byte(toolkit), 0644); err != nil {
+		t.Fatal(err)
+	}
+	artifacts := filepath.Join(dir, "artifacts")
+	if err := os.WriteFile(filepath.Join(dir, "result.txt"), []byte("result"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		name     string
+		commands string
+		fail     bool
+	}{{
+		name:     "retry succeeds eventually",
+		commands: `retry 3 0 bash -c 'echo >> attempts; [[ $(wc -l < attempts) -ge 2 ]]' && [[ $(wc -l < attempts) -eq 2 ]]`,
+	}, {
+		name:     "retry gives up",
+		commands: "retry 2 0 false",
+		fail:     true,
+	}, {
+		name:     "wait_for succeeds",
+		commands: "wait_for 5 0 true",
+	}, {
+		name:     "wait_for times out",
+		commands: "wait_for 0 0 false",
+		fail:     true,
+	}, {
+		name:     "collect",
+		commands: "collect result.txt missing.txt && test -f \"${ARTIFACT_DIR}/result.txt\"",
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			cmd := exec.Command("bash", "-c", CommandPrefix+"source "+library+"\n"+tc.commands)
+			cmd.Dir = dir
+			cmd.Env = append(os.Environ(), "ARTIFACT_DIR="+artifacts)
+			out, err := cmd.CombinedOutput()
+			if failed := err != nil; failed != tc.fail {
+				t.Errorf("expected failure: %t, got %v: %s", tc.fail, err, out)
+			}
+		})
+	}
+}
+
+func TestToolkit(t *testing.T) {
+	yes := true
+	step := api.LiteralTestStep{As: "install", From: "src", Commands: "retry 3 10 make install", Toolkit: &yes}
+	testhelper.Diff(t, "script", commandScript(&step), CommandPrefix+"source /var/run/configmaps/ci.openshift.io/toolkit/toolkit-v1.sh\nretry 3 10 make install")
+
+	jobSpec := api.JobSpec{}
+	jobSpec.SetNamespace("ns")
+	client := kubernetes.NewPodClient(loggingclient.New(fakectrlruntimeclient.NewClientBuilder().Build()), nil, nil, 0)
+	s := multiStageTestStep{name: "e2e", jobSpec: &jobSpec, client: client, test: []api.LiteralTestStep{step}}
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		if err := s.createToolkit(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cm := &coreapi.ConfigMap{}
+	if err := client.Get(ctx, ctrlruntimeclient.ObjectKey{Namespace: "ns", Name: "step-toolkit-v1"}, cm); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(cm.Data["toolkit-v1.sh"], "function retry()") {
+		t.Errorf("unexpected toolkit: %v", cm.Data)
+	}
+
+	pod := &coreapi.Pod{Spec: coreapi.PodSpec{Containers: []coreapi.Container{{Name: containerName}}}}
+	addToolkit(pod)
+	testhelper.Diff(t, "mounts", pod.Spec.Containers[0].VolumeMounts, []coreapi.VolumeMount{{Name: "toolkit", MountPath: ToolkitMountPath, ReadOnly: true}})
+	testhelper.Diff(t, "env", pod.Spec.Containers[0].Env, []coreapi.EnvVar{{Name: ToolkitEnv, Value: "/var/run/configmaps/ci.openshift.io/toolkit/toolkit-v1.sh"}})
+}
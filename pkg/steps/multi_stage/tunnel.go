@@ -0,0 +1,142 @@
+package multi_stage
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	coreapi "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// tunnelConfPath is the path of the bastion configuration file in the
+	// cluster profile.
+	tunnelConfPath = "bastion.yaml"
+	// tunnelContainerName is the name of the container which maintains the
+	// tunnel.  It is not prefixed like the sidecars declared by steps, so the
+	// names cannot conflict.
+	tunnelContainerName = "bastion-tunnel"
+	// tunnelReadyFile is created in the sidecar directory once the proxy
+	// accepts connections.
+	tunnelReadyFile   = SidecarDirPath + "/" + tunnelContainerName
+	defaultSSHPort    = 22
+	defaultProxyPort  = 1080
+	tunnelKeyEnv      = "BASTION_KEY"
+	tunnelHostEnv     = "BASTION_HOST"
+	tunnelPortEnv     = "BASTION_PORT"
+	tunnelUserEnv     = "BASTION_USER"
+	tunnelProxyEnv    = "BASTION_PROXY_PORT"
+	tunnelDefaultUser = "core"
+)
+
+// tunnelScript keeps an SSH connection to the bastion open, exposing a SOCKS
+// proxy on the loopback interface of the pod.  The connection is re-created
+// when it is lost, which is detected by the keep-alive messages.
+const tunnelScript = `install -m 0600 "${BASTION_KEY}" /tmp/bastion-key
+while true; do
+  ssh -N -D "127.0.0.1:${BASTION_PROXY_PORT}" -i /tmp/bastion-key -p "${BASTION_PORT}" \
+    -o StrictHostKeyChecking=no -o UserKnownHostsFile=/dev/null \
+    -o ServerAliveInterval=30 -o ServerAliveCountMax=3 -o ExitOnForwardFailure=yes \
+    "${BASTION_USER}@${BASTION_HOST}"
+  echo "tunnel to ${BASTION_HOST} closed, reconnecting"
+  sleep 5
+done
+`
+
+// tunnelReadiness succeeds once the proxy accepts connections.
+const tunnelReadiness = `exec 3<>"/dev/tcp/127.0.0.1/${BASTION_PROXY_PORT}"`
+
+// tunnelConf is the format of the bastion configuration file in the cluster
+// profile.  The presence of this file adds a container to each step pod which
+// tunnels the traffic of the step through the bastion, so that workflows can
+// reach private clusters.
+type tunnelConf struct {
+	// Image is the pull spec of the image used for the container.  It must
+	// contain bash and an SSH client which works with an arbitrary UID.
+	Image string `json:"image"`
+	// Host is the address of the bastion.
+	Host string `json:"host"`
+	// Port is the SSH port of the bastion, 22 by default.
+	Port int `json:"port,omitempty"`
+	// User is the user used to log into the bastion, `core` by default.
+	User string `json:"user,omitempty"`
+	// PrivateKey is the key in the cluster profile which holds the SSH key.
+	PrivateKey string `json:"private_key"`
+	// ProxyPort is the local port of the SOCKS proxy, 1080 by default.
+	ProxyPort int `json:"proxy_port,omitempty"`
+	// NoProxy lists additional hosts which are not reached through the
+	// tunnel.
+	NoProxy []string `json:"no_proxy,omitempty"`
+}
+
+func (s *multiStageTestStep) readTunnelData(secret *coreapi.Secret) error {
+	bytes, ok := secret.Data[tunnelConfPath]
+	if !ok {
+		return nil
+	}
+	var c tunnelConf
+	if err := yaml.UnmarshalStrict(bytes, &c); err != nil {
+		return fmt.Errorf("failed to read bastion configuration file: %w", err)
+	}
+	if c.Image == "" {
+		return fmt.Errorf("bastion image missing in configuration file")
+	}
+	if c.Host == "" {
+		return fmt.Errorf("bastion host missing in configuration file")
+	}
+	if c.PrivateKey == "" {
+		return fmt.Errorf("bastion private key missing in configuration file")
+	}
+	if _, ok := secret.Data[c.PrivateKey]; !ok {
+		return fmt.Errorf(`invalid "private_key" value %q, not found`, c.PrivateKey)
+	}
+	if c.Port == 0 {
+		c.Port = defaultSSHPort
+	}
+	if c.User == "" {
+		c.User = tunnelDefaultUser
+	}
+	if c.ProxyPort == 0 {
+		c.ProxyPort = defaultProxyPort
+	}
+	s.tunnelConf = &c
+	return nil
+}
+
+// addTunnel adds the tunnel container to a pod and configures the test
+// container to use its proxy.  The proxy is bypassed for the local and
+// build cluster addresses, which are used by the entrypoint wrapper.
+func (s *multiStageTestStep) addTunnel(pod *coreapi.Pod) {
+	c := s.tunnelConf
+	resources := coreapi.ResourceRequirements{
+		Requests: coreapi.ResourceList{
+			coreapi.ResourceCPU:    resource.MustParse("10m"),
+			coreapi.ResourceMemory: resource.MustParse("50Mi"),
+		},
+	}
+	addSidecarContainer(pod, tunnelContainerName, c.Image, tunnelScript, tunnelReadiness, tunnelReadyFile, resources)
+	tunnel := &pod.Spec.Containers[len(pod.Spec.Containers)-1]
+	tunnel.Env = append(tunnel.Env, []coreapi.EnvVar{
+		{Name: tunnelKeyEnv, Value: filepath.Join(ClusterProfileMountPath, c.PrivateKey)},
+		{Name: tunnelHostEnv, Value: c.Host},
+		{Name: tunnelPortEnv, Value: strconv.Itoa(c.Port)},
+		{Name: tunnelUserEnv, Value: c.User},
+		{Name: tunnelProxyEnv, Value: strconv.Itoa(c.ProxyPort)},
+	}...)
+	tunnel.VolumeMounts = append(tunnel.VolumeMounts, coreapi.VolumeMount{
+		Name:      profileVolumeName,
+		MountPath: ClusterProfileMountPath,
+	})
+	proxy := fmt.Sprintf("socks5://127.0.0.1:%d", c.ProxyPort)
+	noProxy := strings.Join(append([]string{"localhost", "127.0.0.1", "$(KUBERNETES_SERVICE_HOST)", ".svc", ".cluster.local"}, c.NoProxy...), ",")
+	test := &pod.Spec.Containers[0]
+	for _, name := range []string{"HTTP_PROXY", "HTTPS_PROXY", "http_proxy", "https_proxy"} {
+		test.Env = append(test.Env, coreapi.EnvVar{Name: name, Value: proxy})
+	}
+	for _, name := range []string{"NO_PROXY", "no_proxy"} {
+		test.Env = append(test.Env, coreapi.EnvVar{Name: name, Value: noProxy})
+	}
+}
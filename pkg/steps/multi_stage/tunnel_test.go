@@ -0,0 +1,132 @@
+package multi_stage
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	coreapi "k8s.io/api/core/v1"
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	prowdapi "k8s.io/test-infra/prow/pod-utils/downwardapi"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/testhelper"
+)
+
+func TestReadTunnelData(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		data        map[string][]byte
+		expected    *tunnelConf
+		expectedErr string
+	}{{
+		name: "no configuration",
+	}, {
+		name: "defaults are set",
+		data: map[string][]byte{
+			tunnelConfPath:   []byte("image: quay.io/ci/bastion\nhost: bastion.example.com\nprivate_key: ssh-privatekey\n"),
+			"ssh-privatekey": []byte("key"),
+		},
+		expected: &tunnelConf{
+			Image:      "quay.io/ci/bastion",
+			Host:       "bastion.example.com",
+			Port:       22,
+			User:       "core",
+			PrivateKey: "ssh-privatekey",
+			ProxyPort:  1080,
+		},
+	}, {
+		name:        "missing host",
+		data:        map[string][]byte{tunnelConfPath: []byte("image: quay.io/ci/bastion\nprivate_key: ssh-privatekey\n")},
+		expectedErr: "bastion host missing in configuration file",
+	}, {
+		name:        "missing key",
+		data:        map[string][]byte{tunnelConfPath: []byte("image: quay.io/ci/bastion\nhost: bastion.example.com\nprivate_key: ssh-privatekey\n")},
+		expectedErr: `invalid "private_key" value "ssh-privatekey", not found`,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			var s multiStageTestStep
+			err := s.readTunnelData(&coreapi.Secret{Data: tc.data})
+			var actualErr string
+			if err != nil {
+				actualErr = err.Error()
+			}
+			if diff := cmp.Diff(tc.expectedErr, actualErr); diff != "" {
+				t.Errorf("unexpected error: %s", diff)
+			}
+			testhelper.Diff(t, "configuration", s.tunnelConf, tc.expected)
+		})
+	}
+}
+
+func TestGeneratePodsTunnel(t *testing.T) {
+	test := api.TestStepConfiguration{
+		As: "test",
+		MultiStageTestConfigurationLiteral: &api.MultiStageTestConfigurationLiteral{
+			ClusterProfile: api.ClusterProfileAWS,
+			Test:           []api.LiteralTestStep{{As: "e2e", From: "src", Commands: "make e2e"}},
+		},
+	}
+	config := api.ReleaseBuildConfiguration{Tests: []api.TestStepConfiguration{test}}
+	jobSpec := api.JobSpec{JobSpec: prowdapi.JobSpec{
+		Job:     "job",
+		BuildID: "build id",
+		Refs:    &prowapi.Refs{Org: "org", Repo: "repo", BaseRef: "base ref", BaseSHA: "base sha"},
+		Type:    "postsubmit",
+		DecorationConfig: &prowapi.DecorationConfig{
+			UtilityImages: &prowapi.UtilityImages{Sidecar: "sidecar", Entrypoint: "entrypoint"},
+		},
+	}}
+	jobSpec.SetNamespace("namespace")
+	step := newMultiStageTestStep(test, &config, nil, nil, &jobSpec, nil, "node-name", "", nil, Options{})
+	step.tunnelConf = &tunnelConf{
+		Image:      "quay.io/ci/bastion",
+		Host:       "bastion.example.com",
+		Port:       2222,
+		User:       "ci",
+		PrivateKey: "ssh-privatekey",
+		ProxyPort:  1080,
+		NoProxy:    []string{".ci.example.com"},
+	}
+	pods, _, err := step.generatePods(step.test, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pods) != 1 {
+		t.Fatalf("expected one pod, got %d", len(pods))
+	}
+	pod := pods[0]
+	var tunnel *coreapi.Container
+	for i := range pod.Spec.Containers {
+		if c := &pod.Spec.Containers[i]; c.Name == tunnelContainerName {
+			tunnel = c
+		}
+	}
+	if tunnel == nil {
+		t.Fatal("expected a container for the tunnel")
+	}
+	testhelper.Diff(t, "tunnel environment", tunnel.Env, []coreapi.EnvVar{
+		{Name: SidecarDirEnv, Value: SidecarDirPath},
+		{Name: "BASTION_KEY", Value: "/var/run/secrets/ci.openshift.io/cluster-profile/ssh-privatekey"},
+		{Name: "BASTION_HOST", Value: "bastion.example.com"},
+		{Name: "BASTION_PORT", Value: "2222"},
+		{Name: "BASTION_USER", Value: "ci"},
+		{Name: "BASTION_PROXY_PORT", Value: "1080"},
+	})
+	env := map[string]string{}
+	for _, e := range pod.Spec.Containers[0].Env {
+		env[e.Name] = e.Value
+	}
+	for name, expected := range map[string]string{
+		"HTTPS_PROXY": "socks5://127.0.0.1:1080",
+		"no_proxy":    "localhost,127.0.0.1,$(KUBERNETES_SERVICE_HOST),.svc,.cluster.local,.ci.example.com",
+		SidecarDirEnv: SidecarDirPath,
+	} {
+		if actual := env[name]; actual != expected {
+			t.Errorf("expected %s to be %q, got %q", name, expected, actual)
+		}
+	}
+	testhelper.Diff(t, "wait arguments", pod.Spec.Containers[0].Args[:4], []string{
+		"--wait-for-file", tunnelReadyFile, "--wait-timeout", "10m0s",
+	})
+}
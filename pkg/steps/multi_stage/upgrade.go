@@ -0,0 +1,199 @@
+package multi_stage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	configv1 "github.com/openshift/api/config/v1"
+	coreapi "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/junit"
+	"github.com/openshift/ci-tools/pkg/results"
+	base_steps "github.com/openshift/ci-tools/pkg/steps"
+	"github.com/openshift/ci-tools/pkg/steps/utils"
+	"github.com/openshift/ci-tools/pkg/util"
+)
+
+const (
+	upgradePollInterval = 30 * time.Second
+	// upgradeAcceptTimeout bounds the time the cluster version operator
+	// takes to verify and accept the target release.
+	upgradeAcceptTimeout = 10 * time.Minute
+	// Conditions of the ClusterVersion which have no constant in the API.
+	clusterVersionFailing         configv1.ClusterStatusConditionType = "Failing"
+	clusterVersionReleaseAccepted configv1.ClusterStatusConditionType = "ReleaseAccepted"
+)
+
+func isUpgradeStep(step api.LiteralTestStep) bool {
+	return step.Upgrade != nil
+}
+
+// runUpgrade executes a built-in upgrade step in place of its pod, driving
+// the upgrade of the cluster under test through its ClusterVersion.  Upgrades
+// are not retried, so the test cases of their phases are not returned.
+func (s *multiStageTestStep) runUpgrade(ctx context.Context, pod *coreapi.Pod, _ *base_steps.TestCaseNotifier, _ util.WaitForPodFlag) ([]*junit.TestCase, error) {
+	step, _ := s.stepFor(pod)
+	upgrade := step.Upgrade
+	target, err := s.params.Get(utils.ReleaseImageEnv(upgrade.Release))
+	if err != nil {
+		return nil, results.ForReason("upgrading_cluster").WithError(err).Errorf("failed to resolve release %s: %v", upgrade.Release, err)
+	}
+	if target == "" {
+		return nil, results.ForReason("upgrading_cluster").ForError(fmt.Errorf("release %s is not available for step %s", upgrade.Release, step.As))
+	}
+	client, err := s.clusterClient(ctx)
+	if err != nil {
+		return nil, results.ForReason("upgrading_cluster").WithError(err).Errorf("failed to create a client for the cluster under test: %v", err)
+	}
+	start := time.Now()
+	logrus.Infof("Running step %s, upgrading the cluster to %s.", pod.Name, target)
+	err = s.upgradeCluster(ctx, client, pod.Name, upgrade, target, s.stepTimeout(&step), upgradePollInterval)
+	if err != nil {
+		logrus.Infof("Step %s failed after %s.", pod.Name, time.Since(start).Truncate(time.Second))
+		return nil, results.ForReason("upgrading_cluster").ForError(err)
+	}
+	logrus.Infof("Step %s succeeded after %s.", pod.Name, time.Since(start).Truncate(time.Second))
+	return nil, nil
+}
+
+// upgradeCluster requests the upgrade to the target release and waits for the
+// cluster version operator to accept and complete it.  If it fails and the
+// step requests it, the cluster is returned to the release it was running.
+func (s *multiStageTestStep) upgradeCluster(ctx context.Context, client ctrlruntimeclient.Client, name string, upgrade *api.UpgradeStep, target string, timeout, interval time.Duration) error {
+	upgradeCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	var original string
+	err := s.upgradePhase(name, "requested", func() error {
+		cv := &configv1.ClusterVersion{}
+		if err := client.Get(upgradeCtx, ctrlruntimeclient.ObjectKey{Name: "version"}, cv); err != nil {
+			return fmt.Errorf("failed to get ClusterVersion: %w", err)
+		}
+		original = cv.Status.Desired.Image
+		return requestUpgrade(upgradeCtx, client, cv, upgrade.Channel, target, upgrade.Force)
+	})
+	if err == nil {
+		err = s.upgradePhase(name, "accepted", func() error {
+			return waitForClusterVersion(upgradeCtx, client, interval, upgradeAcceptTimeout, func(cv *configv1.ClusterVersion) (bool, string) {
+				return releaseAccepted(cv, target)
+			})
+		})
+	}
+	if err == nil {
+		err = s.upgradePhase(name, "completed", func() error {
+			return waitForClusterVersion(upgradeCtx, client, interval, timeout, func(cv *configv1.ClusterVersion) (bool, string) {
+				return upgradeCompleted(cv, target)
+			})
+		})
+	}
+	if err == nil || !upgrade.Rollback || original == "" || original == target {
+		return err
+	}
+	logrus.Warnf("Upgrade of step %s failed, rolling the cluster back to %s.", name, original)
+	// the upgrade may have used the whole timeout of the step
+	rollbackCtx, cancel := context.WithTimeout(base_steps.CleanupCtx, timeout)
+	defer cancel()
+	if rollbackErr := s.upgradePhase(name, "rolled back", func() error {
+		cv := &configv1.ClusterVersion{}
+		if err := client.Get(rollbackCtx, ctrlruntimeclient.ObjectKey{Name: "version"}, cv); err != nil {
+			return fmt.Errorf("failed to get ClusterVersion: %w", err)
+		}
+		if err := requestUpgrade(rollbackCtx, client, cv, "", original, true); err != nil {
+			return err
+		}
+		return waitForClusterVersion(rollbackCtx, client, interval, timeout, func(cv *configv1.ClusterVersion) (bool, string) {
+			return upgradeCompleted(cv, original)
+		})
+	}); rollbackErr != nil {
+		return fmt.Errorf("%w; rollback failed: %v", err, rollbackErr)
+	}
+	return err
+}
+
+// upgradePhase executes a phase of an upgrade and records it as a test case.
+func (s *multiStageTestStep) upgradePhase(name, phase string, f func() error) error {
+	start := time.Now()
+	err := f()
+	duration := time.Since(start)
+	testCase := &junit.TestCase{
+		Name:      fmt.Sprintf("%s - %s upgrade %s", s.Description(), name, phase),
+		Duration:  duration.Seconds(),
+		SystemOut: fmt.Sprintf("Upgrade %s after %s.", phase, duration.Truncate(time.Second)),
+	}
+	if err != nil {
+		testCase.FailureOutput = &junit.FailureOutput{Output: err.Error()}
+	}
+	s.subLock.Lock()
+	s.subTests = append(s.subTests, testCase)
+	s.subLock.Unlock()
+	return err
+}
+
+func requestUpgrade(ctx context.Context, client ctrlruntimeclient.Client, cv *configv1.ClusterVersion, channel, image string, force bool) error {
+	if channel != "" {
+		cv.Spec.Channel = channel
+	}
+	cv.Spec.DesiredUpdate = &configv1.Update{Image: image, Force: force}
+	if err := client.Update(ctx, cv); err != nil {
+		return fmt.Errorf("failed to request the upgrade to %s: %w", image, err)
+	}
+	return nil
+}
+
+// waitForClusterVersion polls the ClusterVersion until the check passes or
+// the timeout expires, in which case the last status is returned.  Failures
+// to get the ClusterVersion are tolerated, as the API may be unavailable
+// while the cluster is upgraded.
+func waitForClusterVersion(ctx context.Context, client ctrlruntimeclient.Client, interval, timeout time.Duration, check func(*configv1.ClusterVersion) (bool, string)) error {
+	var status string
+	err := wait.PollUntilContextTimeout(ctx, interval, timeout, true, func(ctx context.Context) (bool, error) {
+		cv := &configv1.ClusterVersion{}
+		if err := client.Get(ctx, ctrlruntimeclient.ObjectKey{Name: "version"}, cv); err != nil {
+			status = fmt.Sprintf("failed to get ClusterVersion: %v", err)
+			logrus.Debug(status)
+			return false, nil
+		}
+		done, current := check(cv)
+		if current != status && current != "" {
+			logrus.Infof("Cluster upgrade: %s", current)
+		}
+		status = current
+		return done, nil
+	})
+	if err != nil && status != "" {
+		return fmt.Errorf("%w: %s", err, status)
+	}
+	return err
+}
+
+// releaseAccepted determines whether the cluster version operator accepted
+// the target release.
+func releaseAccepted(cv *configv1.ClusterVersion, target string) (bool, string) {
+	if cv.Status.Desired.Image == target {
+		return true, fmt.Sprintf("release %s was accepted", target)
+	}
+	if c := findClusterStatusCondition(cv.Status.Conditions, clusterVersionReleaseAccepted); c != nil && c.Status == configv1.ConditionFalse {
+		return false, "release was not accepted" + conditionMessage(c)
+	}
+	return false, fmt.Sprintf("waiting for release %s to be accepted", target)
+}
+
+// upgradeCompleted determines whether the cluster finished the upgrade to the
+// target release.
+func upgradeCompleted(cv *configv1.ClusterVersion, target string) (bool, string) {
+	if h := cv.Status.History; len(h) != 0 && h[0].Image == target && h[0].State == configv1.CompletedUpdate {
+		return true, fmt.Sprintf("upgrade to %s completed", target)
+	}
+	if c := findClusterStatusCondition(cv.Status.Conditions, clusterVersionFailing); c != nil && c.Status == configv1.ConditionTrue {
+		return false, "upgrade is failing" + conditionMessage(c)
+	}
+	if c := findClusterStatusCondition(cv.Status.Conditions, configv1.OperatorProgressing); c != nil && c.Status == configv1.ConditionTrue {
+		return false, "upgrade is progressing" + conditionMessage(c)
+	}
+	return false, fmt.Sprintf("waiting for the upgrade to %s to start", target)
+}
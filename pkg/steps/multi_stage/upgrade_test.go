@@ -0,0 +1,139 @@
+package multi_stage
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	configv1 "github.com/openshift/api/config/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	fakectrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/testhelper"
+)
+
+func TestUpgradeCompleted(t *testing.T) {
+	for _, tc := range []struct {
+		name           string
+		status         configv1.ClusterVersionStatus
+		expected       bool
+		expectedStatus string
+	}{{
+		name: "upgrade completed",
+		status: configv1.ClusterVersionStatus{History: []configv1.UpdateHistory{
+			{Image: "target", State: configv1.CompletedUpdate},
+			{Image: "original", State: configv1.CompletedUpdate},
+		}},
+		expected:       true,
+		expectedStatus: "upgrade to target completed",
+	}, {
+		name: "upgrade is progressing",
+		status: configv1.ClusterVersionStatus{
+			History:    []configv1.UpdateHistory{{Image: "target", State: configv1.PartialUpdate}},
+			Conditions: []configv1.ClusterOperatorStatusCondition{{Type: configv1.OperatorProgressing, Status: configv1.ConditionTrue, Message: "Working towards target: 104 of 831 done"}},
+		},
+		expectedStatus: "upgrade is progressing: Working towards target: 104 of 831 done",
+	}, {
+		name: "upgrade is failing",
+		status: configv1.ClusterVersionStatus{
+			History: []configv1.UpdateHistory{{Image: "target", State: configv1.PartialUpdate}},
+			Conditions: []configv1.ClusterOperatorStatusCondition{
+				{Type: configv1.OperatorProgressing, Status: configv1.ConditionTrue},
+				{Type: clusterVersionFailing, Status: configv1.ConditionTrue, Message: "Cluster operator etcd is degraded"},
+			},
+		},
+		expectedStatus: "upgrade is failing: Cluster operator etcd is degraded",
+	}, {
+		name:           "upgrade did not start",
+		status:         configv1.ClusterVersionStatus{History: []configv1.UpdateHistory{{Image: "original", State: configv1.CompletedUpdate}}},
+		expectedStatus: "waiting for the upgrade to target to start",
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			done, status := upgradeCompleted(&configv1.ClusterVersion{Status: tc.status}, "target")
+			testhelper.Diff(t, "done", done, tc.expected)
+			testhelper.Diff(t, "status", status, tc.expectedStatus)
+		})
+	}
+}
+
+func TestReleaseAccepted(t *testing.T) {
+	cv := &configv1.ClusterVersion{Status: configv1.ClusterVersionStatus{
+		Desired:    configv1.Release{Image: "original"},
+		Conditions: []configv1.ClusterOperatorStatusCondition{{Type: clusterVersionReleaseAccepted, Status: configv1.ConditionFalse, Message: "The update cannot be verified"}},
+	}}
+	done, status := releaseAccepted(cv, "target")
+	testhelper.Diff(t, "done", done, false)
+	testhelper.Diff(t, "status", status, "release was not accepted: The update cannot be verified")
+	cv.Status.Desired.Image = "target"
+	done, _ = releaseAccepted(cv, "target")
+	testhelper.Diff(t, "done", done, true)
+}
+
+func TestUpgradeCluster(t *testing.T) {
+	for _, tc := range []struct {
+		name            string
+		upgrade         api.UpgradeStep
+		status          configv1.ClusterVersionStatus
+		expectedErr     string
+		expectedUpdate  *configv1.Update
+		expectedChannel string
+		expectedCases   map[string]bool
+	}{{
+		name:    "upgrade succeeds",
+		upgrade: api.UpgradeStep{Release: "latest", Channel: "candidate-4.15", Force: true},
+		status: configv1.ClusterVersionStatus{
+			Desired: configv1.Release{Image: "target"},
+			History: []configv1.UpdateHistory{{Image: "target", State: configv1.CompletedUpdate}},
+		},
+		expectedUpdate:  &configv1.Update{Image: "target", Force: true},
+		expectedChannel: "candidate-4.15",
+		expectedCases: map[string]bool{
+			"Run multi-stage test test - test-upgrade upgrade requested": true,
+			"Run multi-stage test test - test-upgrade upgrade accepted":  true,
+			"Run multi-stage test test - test-upgrade upgrade completed": true,
+		},
+	}, {
+		name:    "failed upgrade is rolled back",
+		upgrade: api.UpgradeStep{Release: "latest", Rollback: true},
+		status: configv1.ClusterVersionStatus{
+			Desired:    configv1.Release{Image: "original"},
+			History:    []configv1.UpdateHistory{{Image: "original", State: configv1.CompletedUpdate}},
+			Conditions: []configv1.ClusterOperatorStatusCondition{{Type: clusterVersionReleaseAccepted, Status: configv1.ConditionFalse, Message: "The update cannot be verified"}},
+		},
+		expectedErr:    "context deadline exceeded: release was not accepted: The update cannot be verified",
+		expectedUpdate: &configv1.Update{Image: "original", Force: true},
+		expectedCases: map[string]bool{
+			"Run multi-stage test test - test-upgrade upgrade requested":   true,
+			"Run multi-stage test test - test-upgrade upgrade accepted":    false,
+			"Run multi-stage test test - test-upgrade upgrade rolled back": true,
+		},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			client := fakectrlruntimeclient.NewClientBuilder().WithScheme(healthScheme(t)).WithObjects(&configv1.ClusterVersion{
+				ObjectMeta: meta.ObjectMeta{Name: "version"},
+				Status:     tc.status,
+			}).Build()
+			s := multiStageTestStep{name: "test", subLock: &sync.Mutex{}}
+			err := s.upgradeCluster(context.Background(), client, "test-upgrade", &tc.upgrade, "target", 50*time.Millisecond, time.Millisecond)
+			var actualErr string
+			if err != nil {
+				actualErr = err.Error()
+			}
+			testhelper.Diff(t, "error", actualErr, tc.expectedErr)
+			cv := &configv1.ClusterVersion{}
+			if err := client.Get(context.Background(), ctrlruntimeclient.ObjectKey{Name: "version"}, cv); err != nil {
+				t.Fatal(err)
+			}
+			testhelper.Diff(t, "desired update", cv.Spec.DesiredUpdate, tc.expectedUpdate)
+			testhelper.Diff(t, "channel", cv.Spec.Channel, tc.expectedChannel)
+			cases := map[string]bool{}
+			for _, c := range s.subTests {
+				cases[c.Name] = c.FailureOutput == nil
+			}
+			testhelper.Diff(t, "test cases", cases, tc.expectedCases)
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package multi_stage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/jsonpath"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/junit"
+	"github.com/openshift/ci-tools/pkg/results"
+)
+
+const (
+	defaultWaitForTimeout = 10 * time.Minute
+	waitForInterval       = 10 * time.Second
+)
+
+// waitConditionDescription identifies the object of a condition in logs and
+// failures.
+func waitConditionDescription(condition api.WaitCondition) string {
+	if condition.Namespace != "" {
+		return fmt.Sprintf("%s %s/%s", condition.Kind, condition.Namespace, condition.Name)
+	}
+	return fmt.Sprintf("%s %s", condition.Kind, condition.Name)
+}
+
+// waitForConditions waits for the conditions of a step to be met on the
+// cluster under test before the step starts.  A test case reports the first
+// condition which is not met in time, in which case the step is not executed.
+func (s *multiStageTestStep) waitForConditions(ctx context.Context, podName string, step api.LiteralTestStep, interval time.Duration) error {
+	if len(step.WaitFor) == 0 {
+		return nil
+	}
+	client, _, err := s.ephemeralClusterClient(ctx, fmt.Sprintf("step %s waits for conditions on the ephemeral cluster", step.As))
+	if err != nil {
+		return err
+	}
+	start := time.Now()
+	for _, condition := range step.WaitFor {
+		logrus.Infof("Waiting for %s of %s to be met before step %s.", condition.JSONPath, waitConditionDescription(condition), podName)
+		if err = waitForCondition(ctx, client, condition, interval); err != nil {
+			err = results.ForReason("waiting_for_conditions").ForError(fmt.Errorf("%s: %w", waitConditionDescription(condition), err))
+			break
+		}
+	}
+	testCase := &junit.TestCase{
+		Name:     fmt.Sprintf("%s - %s conditions are met on the ephemeral cluster", s.Description(), podName),
+		Duration: time.Since(start).Seconds(),
+	}
+	if err != nil {
+		testCase.FailureOutput = &junit.FailureOutput{Output: err.Error()}
+	}
+	s.subLock.Lock()
+	s.subTests = append(s.subTests, testCase)
+	s.subLock.Unlock()
+	return err
+}
+
+// waitForCondition polls an object until the template of the condition
+// produces the expected value or the timeout expires, in which case the last
+// output is reported.  Objects which do not exist yet are tolerated.
+func waitForCondition(ctx context.Context, client ctrlruntimeclient.Client, condition api.WaitCondition, interval time.Duration) error {
+	template := jsonpath.New(condition.Kind).AllowMissingKeys(true)
+	if err := template.Parse(condition.JSONPath); err != nil {
+		return fmt.Errorf("invalid jsonpath %s: %w", condition.JSONPath, err)
+	}
+	timeout := defaultWaitForTimeout
+	if condition.Timeout != nil {
+		timeout = condition.Timeout.Duration
+	}
+	var status string
+	err := wait.PollUntilContextTimeout(ctx, interval, timeout, true, func(ctx context.Context) (bool, error) {
+		obj := &unstructured.Unstructured{}
+		obj.SetAPIVersion(condition.APIVersion)
+		obj.SetKind(condition.Kind)
+		if err := client.Get(ctx, ctrlruntimeclient.ObjectKey{Namespace: condition.Namespace, Name: condition.Name}, obj); err != nil {
+			status = fmt.Sprintf("failed to get the object: %v", err)
+			logrus.Debug(status)
+			return false, nil
+		}
+		var out bytes.Buffer
+		if err := template.Execute(&out, obj.Object); err != nil {
+			status = fmt.Sprintf("failed to evaluate %s: %v", condition.JSONPath, err)
+			return false, nil
+		}
+		status = fmt.Sprintf("%s is %q", condition.JSONPath, out.String())
+		if condition.Value == "" {
+			return out.Len() != 0, nil
+		}
+		return out.String() == condition.Value, nil
+	})
+	if err != nil && ctx.Err() == nil {
+		return fmt.Errorf("not met after %s: %s", timeout, status)
+	}
+	return err
+}
@@ -0,0 +1,100 @@
+package multi_stage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	coreapi "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	prowv1 "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	fakectrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/junit"
+	"github.com/openshift/ci-tools/pkg/steps/loggingclient"
+	"github.com/openshift/ci-tools/pkg/testhelper"
+)
+
+func TestWaitForCondition(t *testing.T) {
+	timeout := &prowv1.Duration{Duration: 10 * time.Millisecond}
+	configMap := &coreapi.ConfigMap{
+		ObjectMeta: meta.ObjectMeta{Namespace: "ns", Name: "config"},
+		Data:       map[string]string{"ready": "true"},
+	}
+	for _, tc := range []struct {
+		name        string
+		condition   api.WaitCondition
+		expectedErr string
+	}{{
+		name:      "value is met",
+		condition: api.WaitCondition{APIVersion: "v1", Kind: "ConfigMap", Namespace: "ns", Name: "config", JSONPath: "{.data.ready}", Value: "true"},
+	}, {
+		name:      "any value is met",
+		condition: api.WaitCondition{APIVersion: "v1", Kind: "ConfigMap", Namespace: "ns", Name: "config", JSONPath: "{.data.ready}"},
+	}, {
+		name:        "value is not met",
+		condition:   api.WaitCondition{APIVersion: "v1", Kind: "ConfigMap", Namespace: "ns", Name: "config", JSONPath: "{.data.ready}", Value: "false", Timeout: timeout},
+		expectedErr: `not met after 10ms: {.data.ready} is "true"`,
+	}, {
+		name:        "missing field",
+		condition:   api.WaitCondition{APIVersion: "v1", Kind: "ConfigMap", Namespace: "ns", Name: "config", JSONPath: "{.data.missing}", Timeout: timeout},
+		expectedErr: `not met after 10ms: {.data.missing} is ""`,
+	}, {
+		name:        "missing object",
+		condition:   api.WaitCondition{APIVersion: "v1", Kind: "ConfigMap", Namespace: "ns", Name: "missing", JSONPath: "{.data.ready}", Timeout: timeout},
+		expectedErr: `not met after 10ms: failed to get the object: configmaps "missing" not found`,
+	}, {
+		name:        "invalid template",
+		condition:   api.WaitCondition{APIVersion: "v1", Kind: "ConfigMap", Namespace: "ns", Name: "config", JSONPath: "{.data.ready"},
+		expectedErr: "invalid jsonpath {.data.ready: unclosed action",
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			client := fakectrlruntimeclient.NewClientBuilder().WithObjects(configMap.DeepCopy()).Build()
+			err := waitForCondition(context.Background(), client, tc.condition, time.Millisecond)
+			var actualErr string
+			if err != nil {
+				actualErr = err.Error()
+			}
+			testhelper.Diff(t, "error", actualErr, tc.expectedErr)
+		})
+	}
+}
+
+func TestWaitForConditions(t *testing.T) {
+	remote := loggingclient.New(fakectrlruntimeclient.NewClientBuilder().WithObjects(
+		&coreapi.ConfigMap{ObjectMeta: meta.ObjectMeta{Namespace: "ns", Name: "config"}, Data: map[string]string{"ready": "true"}},
+	).Build())
+	for _, tc := range []struct {
+		name     string
+		waitFor  []api.WaitCondition
+		expected []*junit.TestCase
+	}{{
+		name: "no conditions",
+	}, {
+		name:     "conditions are met",
+		waitFor:  []api.WaitCondition{{APIVersion: "v1", Kind: "ConfigMap", Namespace: "ns", Name: "config", JSONPath: "{.data.ready}", Value: "true"}},
+		expected: []*junit.TestCase{{Name: "Run multi-stage test test - test-e2e conditions are met on the ephemeral cluster"}},
+	}, {
+		name: "condition is not met",
+		waitFor: []api.WaitCondition{
+			{APIVersion: "v1", Kind: "ConfigMap", Namespace: "ns", Name: "config", JSONPath: "{.data.ready}", Value: "false", Timeout: &prowv1.Duration{Duration: 10 * time.Millisecond}},
+			{APIVersion: "v1", Kind: "ConfigMap", Namespace: "ns", Name: "other", JSONPath: "{.data.ready}"},
+		},
+		expected: []*junit.TestCase{{
+			Name:          "Run multi-stage test test - test-e2e conditions are met on the ephemeral cluster",
+			FailureOutput: &junit.FailureOutput{Output: `ConfigMap ns/config: not met after 10ms: {.data.ready} is "true"`},
+		}},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			s := manifestsTestStep(remote)
+			s.test[0] = api.LiteralTestStep{As: "e2e", WaitFor: tc.waitFor}
+			err := s.waitForConditions(context.Background(), "test-e2e", s.test[0], time.Millisecond)
+			testhelper.Diff(t, "error", err != nil, tc.expected != nil && tc.expected[0].FailureOutput != nil)
+			for _, testCase := range s.subTests {
+				testCase.Duration = 0
+			}
+			testhelper.Diff(t, "test cases", s.subTests, tc.expected)
+		})
+	}
+}
@@ -0,0 +1,102 @@
+// Package nodecapabilities describes the capabilities of the nodes of a build
+// farm, e.g. hardware virtualization or large disks, so that the steps which
+// require them can be checked before a test runs and scheduled on the nodes
+// which provide them.
+package nodecapabilities
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	coreapi "k8s.io/api/core/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// Config lists the node capabilities of a build farm.
+type Config struct {
+	// Capabilities are the capabilities provided by the farm, by name, e.g.
+	// `kvm`, `sse4`, `large-disk` or `arm64`.
+	Capabilities map[string]Capability `json:"capabilities"`
+}
+
+// Capability describes how to schedule pods on the nodes which provide a
+// capability.  Capabilities of all nodes need neither.
+type Capability struct {
+	// NodeSelector selects the nodes which provide the capability.
+	NodeSelector map[string]string `json:"node_selector,omitempty"`
+	// Tolerations allow pods on the nodes which provide the capability, if
+	// they are tainted for dedicated use.
+	Tolerations []coreapi.Toleration `json:"tolerations,omitempty"`
+}
+
+// Load reads the capabilities of a farm from a YAML file.
+func Load(path string) (*Config, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read node capabilities: %w", err)
+	}
+	config := &Config{}
+	if err := yaml.UnmarshalStrict(raw, config); err != nil {
+		return nil, fmt.Errorf("failed to parse node capabilities: %w", err)
+	}
+	return config, nil
+}
+
+// Missing returns the capabilities in a list which the farm does not provide.
+func (c *Config) Missing(required []string) (ret []string) {
+	for _, name := range required {
+		if _, ok := c.Capabilities[name]; !ok {
+			ret = append(ret, name)
+		}
+	}
+	return ret
+}
+
+// NodeSelector merges the node selectors of a list of capabilities.  It
+// fails if they select different values of the same label, which no node
+// can satisfy.
+func (c *Config) NodeSelector(required []string) (map[string]string, error) {
+	var ret map[string]string
+	owners := map[string]string{}
+	for _, name := range required {
+		for k, v := range c.Capabilities[name].NodeSelector {
+			if existing, ok := ret[k]; ok && existing != v {
+				return nil, fmt.Errorf("capabilities %s and %s select different values of node label %s: %q and %q", owners[k], name, k, existing, v)
+			}
+			if ret == nil {
+				ret = map[string]string{}
+			}
+			ret[k], owners[k] = v, name
+		}
+	}
+	return ret, nil
+}
+
+// Apply schedules a pod on the nodes which provide a list of capabilities.
+func (c *Config) Apply(pod *coreapi.Pod, required []string) error {
+	selector, err := c.NodeSelector(required)
+	if err != nil {
+		return err
+	}
+	var conflicts []string
+	for k, v := range selector {
+		if existing, ok := pod.Spec.NodeSelector[k]; ok && existing != v {
+			conflicts = append(conflicts, fmt.Sprintf("%s=%s", k, existing))
+			continue
+		}
+		if pod.Spec.NodeSelector == nil {
+			pod.Spec.NodeSelector = map[string]string{}
+		}
+		pod.Spec.NodeSelector[k] = v
+	}
+	if len(conflicts) != 0 {
+		sort.Strings(conflicts)
+		return fmt.Errorf("the node capabilities conflict with the node selector of pod %s: %s", pod.Name, strings.Join(conflicts, ", "))
+	}
+	for _, name := range required {
+		pod.Spec.Tolerations = append(pod.Spec.Tolerations, c.Capabilities[name].Tolerations...)
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package nodecapabilities
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	coreapi "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/openshift/ci-tools/pkg/testhelper"
+)
+
+var config = Config{Capabilities: map[string]Capability{
+	"sse4": {},
+	"kvm": {
+		NodeSelector: map[string]string{"devices.kubevirt.io/kvm": "true"},
+		Tolerations:  []coreapi.Toleration{{Key: "ci.openshift.io/kvm", Operator: coreapi.TolerationOpExists, Effect: coreapi.TaintEffectNoSchedule}},
+	},
+	"arm64": {NodeSelector: map[string]string{"kubernetes.io/arch": "arm64"}},
+	"amd64": {NodeSelector: map[string]string{"kubernetes.io/arch": "amd64"}},
+}}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "capabilities.yaml")
+	raw := []byte(`capabilities:
+  sse4: {}
+  arm64:
+    node_selector:
+      kubernetes.io/arch: arm64
+`)
+	if err := os.WriteFile(path, raw, 0644); err != nil {
+		t.Fatal(err)
+	}
+	actual, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &Config{Capabilities: map[string]Capability{
+		"sse4":  {},
+		"arm64": {NodeSelector: map[string]string{"kubernetes.io/arch": "arm64"}},
+	}}
+	if diff := cmp.Diff(expected, actual); diff != "" {
+		t.Errorf("unexpected config: %s", diff)
+	}
+}
+
+func TestMissing(t *testing.T) {
+	if diff := cmp.Diff([]string{"large-disk", "gpu"}, config.Missing([]string{"kvm", "large-disk", "sse4", "gpu"})); diff != "" {
+		t.Errorf("unexpected missing capabilities: %s", diff)
+	}
+}
+
+func TestApply(t *testing.T) {
+	for _, tc := range []struct {
+		name         string
+		pod          coreapi.Pod
+		capabilities []string
+		expected     coreapi.Pod
+		err          error
+	}{{
+		name:         "capabilities of all nodes",
+		pod:          coreapi.Pod{},
+		capabilities: []string{"sse4"},
+		expected:     coreapi.Pod{},
+	}, {
+		name:         "node selectors and tolerations are added",
+		pod:          coreapi.Pod{Spec: coreapi.PodSpec{NodeSelector: map[string]string{"kubernetes.io/arch": "arm64"}}},
+		capabilities: []string{"kvm", "arm64"},
+		expected: coreapi.Pod{Spec: coreapi.PodSpec{
+			NodeSelector: map[string]string{"kubernetes.io/arch": "arm64", "devices.kubevirt.io/kvm": "true"},
+			Tolerations:  []coreapi.Toleration{{Key: "ci.openshift.io/kvm", Operator: coreapi.TolerationOpExists, Effect: coreapi.TaintEffectNoSchedule}},
+		}},
+	}, {
+		name:         "conflicting capabilities",
+		capabilities: []string{"arm64", "amd64"},
+		err:          errors.New(`capabilities arm64 and amd64 select different values of node label kubernetes.io/arch: "arm64" and "amd64"`),
+	}, {
+		name:         "capability conflicting with the pod",
+		pod:          coreapi.Pod{ObjectMeta: meta.ObjectMeta{Name: "e2e"}, Spec: coreapi.PodSpec{NodeSelector: map[string]string{"kubernetes.io/arch": "amd64"}}},
+		capabilities: []string{"arm64"},
+		err:          errors.New("the node capabilities conflict with the node selector of pod e2e: kubernetes.io/arch=amd64"),
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := config.Apply(&tc.pod, tc.capabilities)
+			if diff := cmp.Diff(tc.err, err, testhelper.EquateErrorMessage); diff != "" {
+				t.Fatalf("unexpected error: %s", diff)
+			}
+			if err != nil {
+				return
+			}
+			if diff := cmp.Diff(tc.expected, tc.pod); diff != "" {
+				t.Errorf("unexpected pod: %s", diff)
+			}
+		})
+	}
+}
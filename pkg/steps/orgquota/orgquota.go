@@ -0,0 +1,206 @@
+// Package orgquota limits the share of a build farm which the step pods of
+// each organization may use at the same time.  The consumption of an
+// organization is the sum of the requests of the running pods labeled with
+// it, across all the namespaces of the farm.  When a new pod would exceed the
+// share of its organization, it is either queued until other pods finish or
+// rejected, so that a single organization cannot starve the others.
+package orgquota
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"time"
+
+	coreapi "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	prowv1 "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// Action is what happens to pods which exceed the share of their
+// organization.
+type Action string
+
+const (
+	// ActionQueue delays the creation of pods until the organization uses
+	// less than its share.
+	ActionQueue Action = "queue"
+	// ActionReject fails the steps of the pods.
+	ActionReject Action = "reject"
+)
+
+// DefaultQueueTimeout is how long pods are queued by default before the step
+// fails.
+const DefaultQueueTimeout = time.Hour
+
+// Config is the policy of the shares of a build farm.
+type Config struct {
+	// Farm is the capacity of the build farm which is shared among the
+	// organizations, e.g. `cpu: 2000`.  Only the resources listed here are
+	// limited.
+	Farm coreapi.ResourceList `json:"farm"`
+	// DefaultShare is the percentage of the farm which each organization may
+	// use unless it is listed in Organizations.  Organizations which are not
+	// listed are not limited if zero.
+	DefaultShare int `json:"default_share,omitempty"`
+	// Organizations are the percentages of the farm which organizations may
+	// use, by name.
+	Organizations map[string]int `json:"organizations,omitempty"`
+	// Action is either `queue`, the default, or `reject`.
+	Action Action `json:"action,omitempty"`
+	// QueueTimeout is how long a pod may be queued before its step fails,
+	// DefaultQueueTimeout if empty.
+	QueueTimeout *prowv1.Duration `json:"queue_timeout,omitempty"`
+}
+
+// Load reads the shares of a farm from a YAML file.
+func Load(path string) (*Config, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read organization quotas: %w", err)
+	}
+	config := &Config{}
+	if err := yaml.UnmarshalStrict(raw, config); err != nil {
+		return nil, fmt.Errorf("failed to parse organization quotas: %w", err)
+	}
+	if err := config.validate(); err != nil {
+		return nil, fmt.Errorf("invalid organization quotas: %w", err)
+	}
+	return config, nil
+}
+
+func (c *Config) validate() error {
+	if len(c.Farm) == 0 {
+		return errors.New("`farm` is required")
+	}
+	validShare := func(share int) bool { return share >= 0 && share <= 100 }
+	if !validShare(c.DefaultShare) {
+		return fmt.Errorf("`default_share` must be a percentage between 0 and 100, got %d", c.DefaultShare)
+	}
+	for org, share := range c.Organizations {
+		if !validShare(share) {
+			return fmt.Errorf("organizations.%s: share must be a percentage between 0 and 100, got %d", org, share)
+		}
+	}
+	switch c.Action {
+	case "", ActionQueue, ActionReject:
+	default:
+		return fmt.Errorf("`action` must be one of %s or %s, got %q", ActionQueue, ActionReject, c.Action)
+	}
+	if c.QueueTimeout != nil && c.QueueTimeout.Duration <= 0 {
+		return fmt.Errorf("`queue_timeout` must be positive, got %s", c.QueueTimeout.Duration)
+	}
+	return nil
+}
+
+// Reject determines whether pods which exceed their share are rejected
+// instead of queued.
+func (c *Config) Reject() bool {
+	return c.Action == ActionReject
+}
+
+// Timeout is how long pods are queued before their step fails.
+func (c *Config) Timeout() time.Duration {
+	if c.QueueTimeout == nil {
+		return DefaultQueueTimeout
+	}
+	return c.QueueTimeout.Duration
+}
+
+// Limit returns the resources an organization may use, or nil if it is not
+// limited.
+func (c *Config) Limit(org string) coreapi.ResourceList {
+	share, ok := c.Organizations[org]
+	if !ok {
+		share = c.DefaultShare
+		if share == 0 {
+			return nil
+		}
+	}
+	ret := coreapi.ResourceList{}
+	for name, capacity := range c.Farm {
+		ret[name] = shareOf(capacity, share)
+	}
+	return ret
+}
+
+// shareOf returns a percentage of a quantity.  Large quantities, like the
+// memory of a farm, are computed in units instead of milli-units so that they
+// do not overflow.
+func shareOf(q resource.Quantity, share int) resource.Quantity {
+	if value := q.Value(); value > math.MaxInt64/1000/100 {
+		return *resource.NewQuantity(value/100*int64(share), q.Format)
+	}
+	return *resource.NewMilliQuantity(q.MilliValue()*int64(share)/100, q.Format)
+}
+
+// Usage returns the resources requested by the pods which have not finished.
+func Usage(pods []coreapi.Pod) coreapi.ResourceList {
+	ret := coreapi.ResourceList{}
+	for _, pod := range pods {
+		if pod.Status.Phase == coreapi.PodSucceeded || pod.Status.Phase == coreapi.PodFailed {
+			continue
+		}
+		for name, q := range PodRequests(&pod) {
+			sum := ret[name]
+			sum.Add(q)
+			ret[name] = sum
+		}
+	}
+	return ret
+}
+
+// PodRequests returns the resources requested by a pod: the sum of the
+// requests of its containers, or the largest request of an init container if
+// it is higher, since they run one after the other before the containers.
+func PodRequests(pod *coreapi.Pod) coreapi.ResourceList {
+	ret := coreapi.ResourceList{}
+	for _, c := range pod.Spec.Containers {
+		for name, q := range c.Resources.Requests {
+			sum := ret[name]
+			sum.Add(q)
+			ret[name] = sum
+		}
+	}
+	for _, c := range pod.Spec.InitContainers {
+		for name, q := range c.Resources.Requests {
+			if current, ok := ret[name]; !ok || q.Cmp(current) > 0 {
+				ret[name] = q
+			}
+		}
+	}
+	return ret
+}
+
+// Exceeded returns a description of each resource of which the organization
+// would use more than its share if the pod was created in addition to the
+// current usage.
+func (c *Config) Exceeded(org string, usage coreapi.ResourceList, pod *coreapi.Pod) []string {
+	limit := c.Limit(org)
+	if limit == nil {
+		return nil
+	}
+	requests := PodRequests(pod)
+	var names []string
+	for name := range limit {
+		names = append(names, string(name))
+	}
+	sort.Strings(names)
+	var ret []string
+	for _, name := range names {
+		request, ok := requests[coreapi.ResourceName(name)]
+		if !ok || request.IsZero() {
+			continue
+		}
+		total := usage[coreapi.ResourceName(name)]
+		total.Add(request)
+		if max := limit[coreapi.ResourceName(name)]; total.Cmp(max) > 0 {
+			used := usage[coreapi.ResourceName(name)]
+			ret = append(ret, fmt.Sprintf("%s: %s requested with %s in use, the share of organization %s is %s", name, request.String(), used.String(), org, max.String()))
+		}
+	}
+	return ret
+}
@@ -0,0 +1,118 @@
+package orgquota
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	coreapi "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	prowv1 "k8s.io/test-infra/prow/apis/prowjobs/v1"
+
+	"github.com/openshift/ci-tools/pkg/testhelper"
+)
+
+func TestLoad(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		raw      string
+		expected *Config
+		err      error
+	}{{
+		name: "valid config",
+		raw: `farm:
+  cpu: "2000"
+default_share: 10
+organizations:
+  openshift: 60
+action: reject
+queue_timeout: 30m
+`,
+		expected: &Config{
+			Farm:          coreapi.ResourceList{coreapi.ResourceCPU: resource.MustParse("2000")},
+			DefaultShare:  10,
+			Organizations: map[string]int{"openshift": 60},
+			Action:        ActionReject,
+			QueueTimeout:  &prowv1.Duration{Duration: 30 * time.Minute},
+		},
+	}, {
+		name: "no farm",
+		raw:  "default_share: 10\n",
+		err:  errors.New("invalid organization quotas: `farm` is required"),
+	}, {
+		name: "share above 100",
+		raw: `farm:
+  cpu: "2000"
+organizations:
+  openshift: 120
+`,
+		err: errors.New("invalid organization quotas: organizations.openshift: share must be a percentage between 0 and 100, got 120"),
+	}, {
+		name: "unknown action",
+		raw: `farm:
+  cpu: "2000"
+action: drop
+`,
+		err: errors.New(`invalid organization quotas: ` + "`action`" + ` must be one of queue or reject, got "drop"`),
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "quota.yaml")
+			if err := os.WriteFile(path, []byte(tc.raw), 0644); err != nil {
+				t.Fatal(err)
+			}
+			config, err := Load(path)
+			testhelper.Diff(t, "error", err, tc.err, testhelper.EquateErrorMessage)
+			testhelper.Diff(t, "config", config, tc.expected)
+		})
+	}
+}
+
+func TestExceeded(t *testing.T) {
+	config := &Config{
+		Farm: coreapi.ResourceList{
+			coreapi.ResourceCPU:    resource.MustParse("100"),
+			coreapi.ResourceMemory: resource.MustParse("200Ti"),
+		},
+		Organizations: map[string]int{"openshift": 50},
+	}
+	pod := func(cpu string, phase coreapi.PodPhase) coreapi.Pod {
+		return coreapi.Pod{
+			Spec: coreapi.PodSpec{Containers: []coreapi.Container{{Resources: coreapi.ResourceRequirements{
+				Requests: coreapi.ResourceList{coreapi.ResourceCPU: resource.MustParse(cpu)},
+			}}}},
+			Status: coreapi.PodStatus{Phase: phase},
+		}
+	}
+	for _, tc := range []struct {
+		name     string
+		org      string
+		running  []coreapi.Pod
+		pod      coreapi.Pod
+		expected []string
+	}{{
+		name: "organization which is not limited",
+		org:  "other",
+		pod:  pod("1000", coreapi.PodPending),
+	}, {
+		name:    "within the share",
+		org:     "openshift",
+		running: []coreapi.Pod{pod("40", coreapi.PodRunning), pod("40", coreapi.PodSucceeded)},
+		pod:     pod("10", coreapi.PodPending),
+	}, {
+		name:     "exceeds the share",
+		org:      "openshift",
+		running:  []coreapi.Pod{pod("40", coreapi.PodRunning), pod("5", coreapi.PodPending)},
+		pod:      pod("10", coreapi.PodPending),
+		expected: []string{"cpu: 10 requested with 45 in use, the share of organization openshift is 50"},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			testhelper.Diff(t, "exceeded", config.Exceeded(tc.org, Usage(tc.running), &tc.pod), tc.expected)
+		})
+	}
+	memory := config.Limit("openshift")[coreapi.ResourceMemory]
+	if expected := resource.MustParse("100Ti"); memory.Cmp(expected) != 0 {
+		t.Errorf("expected a memory share of %s, got %s", expected.String(), memory.String())
+	}
+}
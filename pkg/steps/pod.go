@@ -29,6 +29,10 @@ const (
 	testSecretDefaultPath  = "/usr/test-secrets"
 
 	openshiftCIEnv = "OPENSHIFT_CI"
+
+	// AnnotationPodScalerScale determines whether the pod-scaler mutates the
+	// resources of a pod, which it does unless set to "false".
+	AnnotationPodScalerScale = "ci-workload-autoscaler.openshift.io/scale"
 )
 
 // CleanupCtx is used by steps when the primary context is cancelled.
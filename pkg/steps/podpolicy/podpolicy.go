@@ -0,0 +1,122 @@
+// Package podpolicy enforces policies on the pods of multi-stage tests before
+// they are created.  Policies are defined centrally for all jobs executed by
+// ci-operator, independently of the admission configuration of the build
+// clusters.  Violations which can be corrected, such as excessive resources,
+// are optionally fixed in the pod instead of failing the step.
+package podpolicy
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	coreapi "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"sigs.k8s.io/yaml"
+)
+
+// Policy restricts the pods created for steps.
+type Policy struct {
+	// AllowedRegistries lists the registries images may be pulled from.
+	// Any registry is allowed when empty.
+	AllowedRegistries []string `json:"allowed_registries,omitempty"`
+	// DenyPrivileged rejects privileged containers.
+	DenyPrivileged bool `json:"deny_privileged,omitempty"`
+	// MaxResources caps the requests and limits of each container, by
+	// resource name.
+	MaxResources map[coreapi.ResourceName]resource.Quantity `json:"max_resources,omitempty"`
+	// Mutate fixes violations which can be corrected instead of reporting
+	// them: privileged containers are made unprivileged and resources are
+	// lowered to the maximum.  Images from other registries are always
+	// rejected.
+	Mutate bool `json:"mutate,omitempty"`
+}
+
+// Load reads a policy from a YAML file.
+func Load(path string) (*Policy, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy: %w", err)
+	}
+	policy := &Policy{}
+	if err := yaml.UnmarshalStrict(raw, policy); err != nil {
+		return nil, fmt.Errorf("failed to parse policy: %w", err)
+	}
+	return policy, nil
+}
+
+// Apply evaluates the policy for a pod, fixing it if the policy allows it.
+// It returns the changes made to the pod and the violations which remain.
+func (p *Policy) Apply(pod *coreapi.Pod) (mutations, violations []string) {
+	for _, containers := range [][]coreapi.Container{pod.Spec.InitContainers, pod.Spec.Containers} {
+		for i := range containers {
+			m, v := p.applyContainer(&containers[i])
+			mutations, violations = append(mutations, m...), append(violations, v...)
+		}
+	}
+	return mutations, violations
+}
+
+func (p *Policy) applyContainer(c *coreapi.Container) (mutations, violations []string) {
+	if len(p.AllowedRegistries) != 0 {
+		if registry := Registry(c.Image); !contains(p.AllowedRegistries, registry) {
+			violations = append(violations, fmt.Sprintf("container %s: image %s is pulled from %s, which is not an allowed registry", c.Name, c.Image, registry))
+		}
+	}
+	if p.DenyPrivileged && c.SecurityContext != nil && c.SecurityContext.Privileged != nil && *c.SecurityContext.Privileged {
+		if p.Mutate {
+			privileged := false
+			c.SecurityContext.Privileged = &privileged
+			mutations = append(mutations, fmt.Sprintf("container %s: removed privileged mode", c.Name))
+		} else {
+			violations = append(violations, fmt.Sprintf("container %s: privileged containers are not allowed", c.Name))
+		}
+	}
+	var names []string
+	for name := range p.MaxResources {
+		names = append(names, string(name))
+	}
+	sort.Strings(names)
+	for _, list := range []struct {
+		name string
+		list coreapi.ResourceList
+	}{{"request", c.Resources.Requests}, {"limit", c.Resources.Limits}} {
+		for _, n := range names {
+			name, limit := coreapi.ResourceName(n), p.MaxResources[coreapi.ResourceName(n)]
+			value, ok := list.list[name]
+			if !ok || value.Cmp(limit) <= 0 {
+				continue
+			}
+			if p.Mutate {
+				list.list[name] = limit
+				mutations = append(mutations, fmt.Sprintf("container %s: lowered %s %s from %s to %s", c.Name, name, list.name, value.String(), limit.String()))
+			} else {
+				violations = append(violations, fmt.Sprintf("container %s: %s %s of %s exceeds the maximum of %s", c.Name, name, list.name, value.String(), limit.String()))
+			}
+		}
+	}
+	return mutations, violations
+}
+
+// Registry returns the registry of an image pull spec, following the
+// conventions of the container runtimes for references without one.
+func Registry(image string) string {
+	i := strings.Index(image, "/")
+	if i == -1 {
+		return "docker.io"
+	}
+	if host := image[:i]; strings.ContainsAny(host, ".:") || host == "localhost" {
+		return host
+	}
+	return "docker.io"
+}
+
+func contains(l []string, s string) bool {
+	for _, x := range l {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,134 @@
+package podpolicy
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	coreapi "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/openshift/ci-tools/pkg/testhelper"
+)
+
+func TestRegistry(t *testing.T) {
+	for image, expected := range map[string]string{
+		"centos":                                 "docker.io",
+		"library/centos:8":                       "docker.io",
+		"quay.io/openshift/ci:latest":            "quay.io",
+		"localhost/image":                        "localhost",
+		"registry.ci.openshift.org:443/ns/image": "registry.ci.openshift.org:443",
+		"image-registry.openshift-image-registry.svc:5000/ci-op/pipeline@sha256:abc": "image-registry.openshift-image-registry.svc:5000",
+	} {
+		if actual := Registry(image); actual != expected {
+			t.Errorf("%s: expected registry %s, got %s", image, expected, actual)
+		}
+	}
+}
+
+func TestApply(t *testing.T) {
+	privileged := true
+	pod := func() *coreapi.Pod {
+		return &coreapi.Pod{Spec: coreapi.PodSpec{
+			InitContainers: []coreapi.Container{{Name: "cp-secret-wrapper", Image: "quay.io/ci/entrypoint-wrapper"}},
+			Containers: []coreapi.Container{{
+				Name:            "test",
+				Image:           "docker.io/library/centos",
+				SecurityContext: &coreapi.SecurityContext{Privileged: &privileged},
+				Resources: coreapi.ResourceRequirements{
+					Requests: coreapi.ResourceList{coreapi.ResourceCPU: resource.MustParse("8"), coreapi.ResourceMemory: resource.MustParse("1Gi")},
+					Limits:   coreapi.ResourceList{coreapi.ResourceMemory: resource.MustParse("64Gi")},
+				},
+			}},
+		}}
+	}
+	restrictive := Policy{
+		AllowedRegistries: []string{"quay.io"},
+		DenyPrivileged:    true,
+		MaxResources:      map[coreapi.ResourceName]resource.Quantity{coreapi.ResourceCPU: resource.MustParse("4"), coreapi.ResourceMemory: resource.MustParse("16Gi")},
+	}
+	mutating := restrictive
+	mutating.Mutate = true
+	for _, tc := range []struct {
+		name               string
+		policy             Policy
+		expectedMutations  []string
+		expectedViolations []string
+		check              func(*testing.T, *coreapi.Pod)
+	}{{
+		name: "empty policy",
+	}, {
+		name:   "violations are reported",
+		policy: restrictive,
+		expectedViolations: []string{
+			"container test: image docker.io/library/centos is pulled from docker.io, which is not an allowed registry",
+			"container test: privileged containers are not allowed",
+			"container test: cpu request of 8 exceeds the maximum of 4",
+			"container test: memory limit of 64Gi exceeds the maximum of 16Gi",
+		},
+	}, {
+		name:   "violations are fixed when possible",
+		policy: mutating,
+		expectedMutations: []string{
+			"container test: removed privileged mode",
+			"container test: lowered cpu request from 8 to 4",
+			"container test: lowered memory limit from 64Gi to 16Gi",
+		},
+		expectedViolations: []string{
+			"container test: image docker.io/library/centos is pulled from docker.io, which is not an allowed registry",
+		},
+		check: func(t *testing.T, pod *coreapi.Pod) {
+			c := pod.Spec.Containers[0]
+			if *c.SecurityContext.Privileged {
+				t.Error("expected the container to be unprivileged")
+			}
+			if cpu := c.Resources.Requests[coreapi.ResourceCPU]; cpu.String() != "4" {
+				t.Errorf("expected the cpu request to be lowered, got %s", cpu.String())
+			}
+			if memory := c.Resources.Limits[coreapi.ResourceMemory]; memory.String() != "16Gi" {
+				t.Errorf("expected the memory limit to be lowered, got %s", memory.String())
+			}
+		},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			pod := pod()
+			mutations, violations := tc.policy.Apply(pod)
+			testhelper.Diff(t, "mutations", mutations, tc.expectedMutations)
+			testhelper.Diff(t, "violations", violations, tc.expectedViolations)
+			if tc.check != nil {
+				tc.check(t, pod)
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.yaml")
+	raw := `allowed_registries:
+- quay.io
+deny_privileged: true
+max_resources:
+  cpu: "4"
+mutate: true
+`
+	if err := os.WriteFile(path, []byte(raw), 0644); err != nil {
+		t.Fatal(err)
+	}
+	policy, err := Load(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := &Policy{
+		AllowedRegistries: []string{"quay.io"},
+		DenyPrivileged:    true,
+		MaxResources:      map[coreapi.ResourceName]resource.Quantity{coreapi.ResourceCPU: resource.MustParse("4")},
+		Mutate:            true,
+	}
+	testhelper.Diff(t, "policy", policy, expected)
+	if err := os.WriteFile(path, []byte("deny_privileged: yes\nunknown: field\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil {
+		t.Error("expected an error for an unknown field")
+	}
+}
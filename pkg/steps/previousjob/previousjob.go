@@ -0,0 +1,196 @@
+// Package previousjob locates the last successful run of a job in the bucket
+// where Prow uploads the artifacts of jobs, so that steps can compare their
+// results against a baseline.
+package previousjob
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/pod-utils/downwardapi"
+	"k8s.io/test-infra/prow/pod-utils/gcs"
+)
+
+const (
+	// DefaultStorageURL is the base URL of the public GCS API.
+	DefaultStorageURL = "https://storage.googleapis.com"
+	// maxCandidates is the number of runs, starting from the most recent,
+	// which are inspected before giving up.
+	maxCandidates = 20
+	// maxPages bounds the number of pages listed in the bucket.
+	maxPages = 100
+	// maxFileSize is the size above which files are not fetched.
+	maxFileSize = 1024 * 1024
+)
+
+// Finder locates the previous successful run of a job.
+type Finder struct {
+	// StorageURL is the base URL of the GCS API.
+	StorageURL string
+	Client     *http.Client
+	bucket     string
+	// root is the directory which holds the runs of the job, or links to
+	// them for presubmit jobs.
+	root      string
+	isLinks   bool
+	currentID int64
+}
+
+// NewFinder creates a finder for the job of a spec.  It returns nil when the
+// artifacts of the job are not uploaded to a GCS bucket.
+func NewFinder(spec *downwardapi.JobSpec, client *http.Client) *Finder {
+	if spec.DecorationConfig == nil || spec.DecorationConfig.GCSConfiguration == nil {
+		return nil
+	}
+	bucket := spec.DecorationConfig.GCSConfiguration.Bucket
+	if strings.Contains(bucket, "://") && !strings.HasPrefix(bucket, "gs://") {
+		return nil
+	}
+	current, _ := strconv.ParseInt(spec.BuildID, 10, 64)
+	return &Finder{
+		StorageURL: DefaultStorageURL,
+		Client:     client,
+		bucket:     strings.TrimPrefix(bucket, "gs://"),
+		root:       path.Join(spec.DecorationConfig.GCSConfiguration.PathPrefix, gcs.RootForSpec(spec)),
+		isLinks:    spec.Type == prowapi.PresubmitJob || spec.Type == prowapi.BatchJob,
+		currentID:  current,
+	}
+}
+
+// LastSuccessful returns the URL of the artifact directory of the most recent
+// successful run of the job before the current one, or an empty string if
+// there is none.
+func (f *Finder) LastSuccessful(ctx context.Context) (string, error) {
+	candidates, err := f.candidates(ctx)
+	if err != nil {
+		return "", err
+	}
+	for _, candidate := range candidates {
+		dir := candidate.dir
+		if f.isLinks {
+			link, err := f.fetch(ctx, candidate.dir)
+			if err != nil {
+				return "", err
+			}
+			dir = strings.TrimPrefix(strings.TrimSpace(string(link)), "gs://"+f.bucket+"/")
+		}
+		raw, err := f.fetch(ctx, path.Join(dir, "finished.json"))
+		if err != nil {
+			// runs which did not finish are skipped
+			continue
+		}
+		var finished struct {
+			Passed *bool `json:"passed"`
+		}
+		if err := json.Unmarshal(raw, &finished); err != nil {
+			continue
+		}
+		if finished.Passed != nil && *finished.Passed {
+			return fmt.Sprintf("%s/%s/%s/artifacts", f.StorageURL, f.bucket, dir), nil
+		}
+	}
+	return "", nil
+}
+
+type candidate struct {
+	id  int64
+	dir string
+}
+
+// candidates lists the previous runs of the job, most recent first.
+func (f *Finder) candidates(ctx context.Context) ([]candidate, error) {
+	var ret []candidate
+	add := func(name string) {
+		base := path.Base(strings.TrimSuffix(name, "/"))
+		if f.isLinks {
+			if !strings.HasSuffix(base, ".txt") {
+				return
+			}
+			base = strings.TrimSuffix(base, ".txt")
+		}
+		id, err := strconv.ParseInt(base, 10, 64)
+		if err != nil || (f.currentID != 0 && id >= f.currentID) {
+			return
+		}
+		ret = append(ret, candidate{id: id, dir: strings.TrimSuffix(name, "/")})
+	}
+	var token string
+	for page := 0; page < maxPages; page++ {
+		query := url.Values{"prefix": {f.root + "/"}, "delimiter": {"/"}}
+		if token != "" {
+			query.Set("pageToken", token)
+		}
+		raw, err := f.get(ctx, fmt.Sprintf("%s/storage/v1/b/%s/o?%s", f.StorageURL, f.bucket, query.Encode()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to list the runs of the job: %w", err)
+		}
+		var list struct {
+			Prefixes []string `json:"prefixes"`
+			Items    []struct {
+				Name string `json:"name"`
+			} `json:"items"`
+			NextPageToken string `json:"nextPageToken"`
+		}
+		if err := json.Unmarshal(raw, &list); err != nil {
+			return nil, fmt.Errorf("failed to parse the runs of the job: %w", err)
+		}
+		if f.isLinks {
+			for _, item := range list.Items {
+				add(item.Name)
+			}
+		} else {
+			for _, prefix := range list.Prefixes {
+				add(prefix)
+			}
+		}
+		if token = list.NextPageToken; token == "" {
+			break
+		}
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].id > ret[j].id })
+	if len(ret) > maxCandidates {
+		ret = ret[:maxCandidates]
+	}
+	return ret, nil
+}
+
+func (f *Finder) fetch(ctx context.Context, object string) ([]byte, error) {
+	return f.get(ctx, fmt.Sprintf("%s/%s/%s", f.StorageURL, f.bucket, object))
+}
+
+// Fetch downloads a file from the artifact directory of a run.
+func (f *Finder) Fetch(ctx context.Context, artifactsURL, file string) ([]byte, error) {
+	return f.get(ctx, strings.TrimSuffix(artifactsURL, "/")+"/"+strings.TrimPrefix(file, "/"))
+}
+
+func (f *Finder) get(ctx context.Context, u string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := f.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s responded with status code %d", u, resp.StatusCode)
+	}
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, maxFileSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(raw) > maxFileSize {
+		return nil, fmt.Errorf("GET %s: response exceeds %d bytes", u, maxFileSize)
+	}
+	return raw, nil
+}
@@ -0,0 +1,109 @@
+package previousjob
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	prowapi "k8s.io/test-infra/prow/apis/prowjobs/v1"
+	"k8s.io/test-infra/prow/pod-utils/downwardapi"
+
+	"github.com/openshift/ci-tools/pkg/testhelper"
+)
+
+func TestLastSuccessful(t *testing.T) {
+	objects := map[string]string{
+		"logs/periodic/100/finished.json":                     `{"passed": true}`,
+		"logs/periodic/200/finished.json":                     `{"passed": false}`,
+		"logs/periodic/300/finished.json":                     `{"passed": true}`,
+		"logs/failing/100/finished.json":                      `{"passed": false}`,
+		"pr-logs/directory/presubmit/100.txt":                 "gs://bucket/pr-logs/pull/org_repo/1/presubmit/100",
+		"pr-logs/directory/presubmit/200.txt":                 "gs://bucket/pr-logs/pull/org_repo/2/presubmit/200",
+		"pr-logs/pull/org_repo/1/presubmit/100/finished.json": `{"passed": true}`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/storage/v1/b/bucket/o" {
+			prefix, delimiter := r.URL.Query().Get("prefix"), r.URL.Query().Get("delimiter")
+			type item struct {
+				Name string `json:"name"`
+			}
+			var list struct {
+				Prefixes []string `json:"prefixes"`
+				Items    []item   `json:"items"`
+			}
+			seen := map[string]bool{}
+			for name := range objects {
+				if !strings.HasPrefix(name, prefix) {
+					continue
+				}
+				rest := strings.TrimPrefix(name, prefix)
+				if i := strings.Index(rest, delimiter); i != -1 {
+					if p := prefix + rest[:i+1]; !seen[p] {
+						seen[p] = true
+						list.Prefixes = append(list.Prefixes, p)
+					}
+				} else {
+					list.Items = append(list.Items, item{Name: name})
+				}
+			}
+			if err := json.NewEncoder(w).Encode(list); err != nil {
+				t.Error(err)
+			}
+			return
+		}
+		if content, ok := objects[strings.TrimPrefix(r.URL.Path, "/bucket/")]; ok {
+			_, _ = w.Write([]byte(content))
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+	for _, tc := range []struct {
+		name     string
+		spec     downwardapi.JobSpec
+		expected string
+	}{{
+		name:     "periodic job skips failed runs",
+		spec:     downwardapi.JobSpec{Type: prowapi.PeriodicJob, Job: "periodic", BuildID: "400"},
+		expected: server.URL + "/bucket/logs/periodic/300/artifacts",
+	}, {
+		name:     "later runs are ignored",
+		spec:     downwardapi.JobSpec{Type: prowapi.PeriodicJob, Job: "periodic", BuildID: "250"},
+		expected: server.URL + "/bucket/logs/periodic/100/artifacts",
+	}, {
+		name: "no successful run",
+		spec: downwardapi.JobSpec{Type: prowapi.PeriodicJob, Job: "failing", BuildID: "400"},
+	}, {
+		name:     "presubmit job follows links",
+		spec:     downwardapi.JobSpec{Type: prowapi.PresubmitJob, Job: "presubmit", BuildID: "300"},
+		expected: server.URL + "/bucket/pr-logs/pull/org_repo/1/presubmit/100/artifacts",
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.spec.DecorationConfig = &prowapi.DecorationConfig{GCSConfiguration: &prowapi.GCSConfiguration{Bucket: "gs://bucket"}}
+			finder := NewFinder(&tc.spec, server.Client())
+			finder.StorageURL = server.URL
+			actual, err := finder.LastSuccessful(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			testhelper.Diff(t, "URL", actual, tc.expected)
+		})
+	}
+}
+
+func TestNewFinder(t *testing.T) {
+	if finder := NewFinder(&downwardapi.JobSpec{Type: prowapi.PeriodicJob, Job: "job"}, nil); finder != nil {
+		t.Errorf("expected no finder for a job without a GCS configuration")
+	}
+	spec := downwardapi.JobSpec{
+		Type:             prowapi.PeriodicJob,
+		Job:              "job",
+		DecorationConfig: &prowapi.DecorationConfig{GCSConfiguration: &prowapi.GCSConfiguration{Bucket: "s3://bucket"}},
+	}
+	if finder := NewFinder(&spec, nil); finder != nil {
+		t.Errorf("expected no finder for a job whose artifacts are not in GCS")
+	}
+}
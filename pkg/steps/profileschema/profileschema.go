@@ -0,0 +1,244 @@
+// Package profileschema describes the contents expected in the secrets of
+// cluster profiles, so that a malformed profile is reported when the secret is
+// read, with all the keys which are missing or malformed, instead of failing
+// the steps which use it later in ways unrelated to the cause.  Schemas are
+// defined for the kinds of profiles, i.e. their cluster types, and may be
+// overridden for individual profiles.
+package profileschema
+
+import (
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+
+	"github.com/openshift/ci-tools/pkg/api"
+)
+
+// Type is the format of the value of a key of the secret.
+type Type string
+
+const (
+	// TypeText accepts any value.
+	TypeText Type = "text"
+	// TypeJSON values are JSON objects.
+	TypeJSON Type = "json"
+	// TypeYAML values are YAML mappings.
+	TypeYAML Type = "yaml"
+	// TypeINI values are INI files, like the credentials of AWS.
+	TypeINI Type = "ini"
+	// TypePEM values hold at least one PEM block, like keys and
+	// certificates.
+	TypePEM Type = "pem"
+)
+
+var types = []Type{TypeText, TypeJSON, TypeYAML, TypeINI, TypePEM}
+
+// Registry holds the schemas of the cluster profiles.
+type Registry struct {
+	// Kinds are the schemas of the profiles of a cluster type, e.g. `aws`.
+	Kinds map[string]Schema `json:"kinds,omitempty"`
+	// Profiles are the schemas of individual profiles, which replace the
+	// schema of their kind.
+	Profiles map[api.ClusterProfile]Schema `json:"profiles,omitempty"`
+}
+
+// Schema describes the keys of the secret of a profile.  Keys which are not
+// listed are not checked.
+type Schema struct {
+	// Keys are the known keys of the secret, by name, e.g. `.awscred`.
+	Keys map[string]Key `json:"keys"`
+}
+
+// Key describes a key of the secret of a profile.
+type Key struct {
+	// Required keys must be present and not empty.  Optional keys are only
+	// checked if present.
+	Required bool `json:"required,omitempty"`
+	// Type is the format of the value, TypeText if empty.
+	Type Type `json:"type,omitempty"`
+	// Fields are the fields of structured values which must be set: the
+	// top-level fields of JSON and YAML values, the keys of INI values in any
+	// section.
+	Fields []string `json:"fields,omitempty"`
+	// Pattern is a regular expression which text values must match.
+	Pattern string `json:"pattern,omitempty"`
+
+	pattern *regexp.Regexp
+}
+
+// Load reads the schemas of the profiles from a YAML file.
+func Load(path string) (*Registry, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cluster profile schemas: %w", err)
+	}
+	registry := &Registry{}
+	if err := yaml.UnmarshalStrict(raw, registry); err != nil {
+		return nil, fmt.Errorf("failed to parse cluster profile schemas: %w", err)
+	}
+	if err := registry.complete(); err != nil {
+		return nil, fmt.Errorf("invalid cluster profile schemas: %w", err)
+	}
+	return registry, nil
+}
+
+func (r *Registry) complete() error {
+	var errs []string
+	completeSchema := func(prefix string, schema Schema) {
+		for name, key := range schema.Keys {
+			if err := key.complete(); err != nil {
+				errs = append(errs, fmt.Sprintf("%s.keys.%s: %v", prefix, name, err))
+			}
+			schema.Keys[name] = key
+		}
+	}
+	for kind, schema := range r.Kinds {
+		completeSchema("kinds."+kind, schema)
+	}
+	for profile, schema := range r.Profiles {
+		completeSchema("profiles."+string(profile), schema)
+	}
+	if errs != nil {
+		sort.Strings(errs)
+		return errors.New(strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+func (k *Key) complete() error {
+	if k.Type == "" {
+		k.Type = TypeText
+	}
+	var valid bool
+	for _, t := range types {
+		valid = valid || k.Type == t
+	}
+	if !valid {
+		return fmt.Errorf("invalid type %q, must be one of %v", k.Type, types)
+	}
+	if len(k.Fields) != 0 && (k.Type == TypeText || k.Type == TypePEM) {
+		return fmt.Errorf("`fields` cannot be set for type %s", k.Type)
+	}
+	if k.Pattern != "" {
+		if k.Type != TypeText {
+			return fmt.Errorf("`pattern` cannot be set for type %s", k.Type)
+		}
+		pattern, err := regexp.Compile(k.Pattern)
+		if err != nil {
+			return fmt.Errorf("invalid pattern: %w", err)
+		}
+		k.pattern = pattern
+	}
+	return nil
+}
+
+// SchemaFor returns the schema of a profile, if it has one.
+func (r *Registry) SchemaFor(profile api.ClusterProfile) (Schema, bool) {
+	if schema, ok := r.Profiles[profile]; ok {
+		return schema, true
+	}
+	schema, ok := r.Kinds[profile.ClusterType()]
+	return schema, ok
+}
+
+// Check verifies the data of the secret of a profile against its schema and
+// returns all the problems, sorted by key.  Profiles without a schema are not
+// checked.
+func (r *Registry) Check(profile api.ClusterProfile, data map[string][]byte) []string {
+	schema, ok := r.SchemaFor(profile)
+	if !ok {
+		return nil
+	}
+	names := make([]string, 0, len(schema.Keys))
+	for name := range schema.Keys {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var ret []string
+	for _, name := range names {
+		key := schema.Keys[name]
+		value, present := data[name]
+		switch {
+		case !present && key.Required:
+			ret = append(ret, fmt.Sprintf("key %q is missing", name))
+		case len(value) == 0 && key.Required:
+			ret = append(ret, fmt.Sprintf("key %q is empty", name))
+		case present:
+			for _, problem := range key.check(value) {
+				ret = append(ret, fmt.Sprintf("key %q: %s", name, problem))
+			}
+		}
+	}
+	return ret
+}
+
+// check verifies a value against the type of the key.
+func (k *Key) check(value []byte) []string {
+	var fields map[string]bool
+	switch k.Type {
+	case TypeText, "":
+		if k.pattern != nil && !k.pattern.Match(value) {
+			return []string{fmt.Sprintf("does not match %s", k.Pattern)}
+		}
+		return nil
+	case TypePEM:
+		if block, _ := pem.Decode(value); block == nil {
+			return []string{"no PEM block found"}
+		}
+		return nil
+	case TypeJSON:
+		var object map[string]interface{}
+		if err := json.Unmarshal(value, &object); err != nil {
+			return []string{fmt.Sprintf("not a JSON object: %v", err)}
+		}
+		fields = present(object)
+	case TypeYAML:
+		var object map[string]interface{}
+		if err := yaml.Unmarshal(value, &object); err != nil {
+			return []string{fmt.Sprintf("not a YAML mapping: %v", err)}
+		}
+		fields = present(object)
+	case TypeINI:
+		fields = iniKeys(string(value))
+	}
+	var ret []string
+	for _, field := range k.Fields {
+		if !fields[field] {
+			ret = append(ret, fmt.Sprintf("field %q is missing", field))
+		}
+	}
+	return ret
+}
+
+// present returns the fields of an object which are set.
+func present(object map[string]interface{}) map[string]bool {
+	ret := map[string]bool{}
+	for field, value := range object {
+		if value != nil && value != "" {
+			ret[field] = true
+		}
+	}
+	return ret
+}
+
+// iniKeys returns the keys with a value in any section of an INI file.
+func iniKeys(content string) map[string]bool {
+	ret := map[string]bool{}
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") || strings.HasPrefix(line, "[") {
+			continue
+		}
+		if key, value, ok := strings.Cut(line, "="); ok && strings.TrimSpace(value) != "" {
+			ret[strings.TrimSpace(key)] = true
+		}
+	}
+	return ret
+}
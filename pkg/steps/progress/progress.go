@@ -0,0 +1,78 @@
+// Package progress implements the markers steps print to report their
+// progress, e.g. during hour-long installations.  A marker is a line of the
+// output of the step of the form:
+//
+//	##ci-progress: <message> <percent>
+//
+// for example `##ci-progress: waiting for the bootstrap to complete 40`.
+package progress
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const (
+	// Marker starts the lines which report the progress of a step.
+	Marker = "##ci-progress:"
+	// Annotation holds the last progress reported by the pod of a step.
+	Annotation = "ci-operator.openshift.io/progress"
+	// maxLineLength is the length of the longest line scanned, longer lines
+	// are skipped.
+	maxLineLength = 64 * 1024
+)
+
+// Progress is the progress of a step.
+type Progress struct {
+	Message string
+	// Percent is the completion of the step, between 0 and 100.
+	Percent int
+}
+
+func (p Progress) String() string {
+	return fmt.Sprintf("%d%% %s", p.Percent, p.Message)
+}
+
+// Parse parses a line of the output of a step, which reports progress if it
+// starts with the marker.
+func Parse(line string) (Progress, bool) {
+	rest, ok := strings.CutPrefix(strings.TrimSpace(line), Marker)
+	if !ok {
+		return Progress{}, false
+	}
+	rest = strings.TrimSpace(rest)
+	i := strings.LastIndexAny(rest, " \t")
+	if i == -1 {
+		return Progress{}, false
+	}
+	percent, err := strconv.Atoi(strings.TrimSuffix(rest[i+1:], "%"))
+	if err != nil || percent < 0 || percent > 100 {
+		return Progress{}, false
+	}
+	return Progress{Message: strings.TrimSpace(rest[:i]), Percent: percent}, true
+}
+
+// Scan reads the output of a step and reports each progress marker in it.
+func Scan(r io.Reader, report func(Progress)) error {
+	reader := bufio.NewReaderSize(r, maxLineLength)
+	for {
+		line, isPrefix, err := reader.ReadLine()
+		if isPrefix {
+			// markers are short, skip the rest of the line
+			for isPrefix && err == nil {
+				_, isPrefix, err = reader.ReadLine()
+			}
+		} else if p, ok := Parse(string(line)); ok {
+			report(p)
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package progress
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/openshift/ci-tools/pkg/testhelper"
+)
+
+func TestParse(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		line     string
+		expected Progress
+		ok       bool
+	}{{
+		name:     "marker",
+		line:     "##ci-progress: waiting for the bootstrap to complete 40",
+		expected: Progress{Message: "waiting for the bootstrap to complete", Percent: 40},
+		ok:       true,
+	}, {
+		name:     "percent sign and surrounding space",
+		line:     "  ##ci-progress:   installing  100%\n",
+		expected: Progress{Message: "installing", Percent: 100},
+		ok:       true,
+	}, {
+		name: "not a marker",
+		line: "level=info msg=Waiting up to 40m0s for bootstrapping to complete...",
+	}, {
+		name: "marker echoed by the shell",
+		line: "+ echo '##ci-progress: installing 10'",
+	}, {
+		name: "no percent",
+		line: "##ci-progress: installing",
+	}, {
+		name: "percent out of range",
+		line: "##ci-progress: installing 150",
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, ok := Parse(tc.line)
+			testhelper.Diff(t, "progress", actual, tc.expected)
+			testhelper.Diff(t, "ok", ok, tc.ok)
+		})
+	}
+}
+
+func TestScan(t *testing.T) {
+	output := strings.Join([]string{
+		"starting",
+		"##ci-progress: creating infrastructure 10",
+		"level=info msg=Creating infrastructure resources...",
+		"##ci-progress: " + strings.Repeat("long ", maxLineLength) + "20",
+		"##ci-progress: waiting for bootstrap 40",
+	}, "\n")
+	var reported []Progress
+	if err := Scan(strings.NewReader(output), func(p Progress) { reported = append(reported, p) }); err != nil {
+		t.Fatal(err)
+	}
+	testhelper.Diff(t, "progress", reported, []Progress{{Message: "creating infrastructure", Percent: 10}, {Message: "waiting for bootstrap", Percent: 40}})
+}
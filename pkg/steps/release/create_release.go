@@ -53,6 +53,8 @@ type assembleReleaseStep struct {
 	resources api.ResourceConfiguration
 	client    kubernetes.PodClient
 	jobSpec   *api.JobSpec
+	// provenance maps the components of the release to their commits
+	provenance string
 }
 
 func (s *assembleReleaseStep) Inputs() (api.InputDefinition, error) {
@@ -237,6 +239,7 @@ oc adm release extract --from=%q --to=${ARTIFACT_DIR}/release-payload-%s
 		return results.ForReason("creating_release").ForError(err)
 	}
 	logrus.Infof("Snapshot integration stream into release %s to tag %s:%s ", version, api.ReleaseImageStream, s.name)
+	s.provenance = recordProvenance(ctx, s.client, s.jobSpec.Namespace(), s.name)
 	return nil
 }
 
@@ -253,7 +256,8 @@ func (s *assembleReleaseStep) Creates() []api.StepLink {
 
 func (s *assembleReleaseStep) Provides() api.ParameterMap {
 	return api.ParameterMap{
-		utils.ReleaseImageEnv(s.name): utils.ImageDigestFor(s.client, s.jobSpec.Namespace, api.ReleaseImageStream, s.name),
+		utils.ReleaseImageEnv(s.name):      utils.ImageDigestFor(s.client, s.jobSpec.Namespace, api.ReleaseImageStream, s.name),
+		utils.ReleaseProvenanceEnv(s.name): func() (string, error) { return s.provenance, nil },
 	}
 }
 
@@ -63,6 +63,8 @@ type importReleaseStep struct {
 	pullSecret *coreapi.Secret
 	// overrideCLIReleaseExtractImage is given for non-amd64 releases
 	overrideCLIReleaseExtractImage *coreapi.ObjectReference
+	// provenance maps the components of the release to their commits
+	provenance string
 }
 
 func (s *importReleaseStep) Inputs() (api.InputDefinition, error) {
@@ -349,6 +351,7 @@ oc create configmap release-%s --from-file=%s.yaml=${ARTIFACT_DIR}/%s
 	}
 
 	logrus.Infof("Imported release %s created at %s with %d images to tag release:%s", releaseIS.Name, releaseIS.CreationTimestamp, len(releaseIS.Spec.Tags), s.name)
+	s.provenance = recordProvenance(ctx, s.client, s.jobSpec.Namespace(), s.name)
 	return nil
 }
 
@@ -400,7 +403,8 @@ func (s *importReleaseStep) Creates() []api.StepLink {
 
 func (s *importReleaseStep) Provides() api.ParameterMap {
 	return api.ParameterMap{
-		utils.ReleaseImageEnv(s.name): utils.ImageDigestFor(s.client, s.jobSpec.Namespace, api.ReleaseImageStream, s.name),
+		utils.ReleaseImageEnv(s.name):      utils.ImageDigestFor(s.client, s.jobSpec.Namespace, api.ReleaseImageStream, s.name),
+		utils.ReleaseProvenanceEnv(s.name): func() (string, error) { return s.provenance, nil },
 	}
 }
 
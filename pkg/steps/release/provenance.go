@@ -0,0 +1,81 @@
+package release
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/test-infra/prow/secretutil"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	imagev1 "github.com/openshift/api/image/v1"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/api/helper"
+)
+
+const (
+	// ProvenanceArtifact is the artifact which maps the components of a
+	// release to the commits they were built from, formatted with the name of
+	// the release.
+	ProvenanceArtifact = "release-%s-provenance.json"
+	// commitAnnotation records the commit an image was built from, both as an
+	// annotation of the tags in a release and as a label of the images.
+	commitAnnotation = "io.openshift.build.commit.id"
+)
+
+// releaseProvenance maps the components in the stream of a release to the
+// commits they were built from.  The commits are read from the annotations of
+// the tags, as recorded in release payloads, and from the labels of the images
+// for tags which do not carry them, e.g. images built by the job.  Components
+// whose commit is unknown are omitted.
+func releaseProvenance(ctx context.Context, client ctrlruntimeclient.Client, namespace, streamName string) (map[string]string, error) {
+	stream := &imagev1.ImageStream{}
+	if err := client.Get(ctx, ctrlruntimeclient.ObjectKey{Namespace: namespace, Name: streamName}, stream); err != nil {
+		return nil, fmt.Errorf("could not resolve imagestream %s: %w", streamName, err)
+	}
+	ret := map[string]string{}
+	for _, tag := range stream.Spec.Tags {
+		if commit := tag.Annotations[commitAnnotation]; commit != "" {
+			ret[tag.Name] = commit
+			continue
+		}
+		ist := &imagev1.ImageStreamTag{}
+		if err := client.Get(ctx, ctrlruntimeclient.ObjectKey{Namespace: namespace, Name: fmt.Sprintf("%s:%s", streamName, tag.Name)}, ist); err != nil {
+			logrus.WithError(err).Debugf("Could not resolve %s:%s to determine its commit.", streamName, tag.Name)
+			continue
+		}
+		labels, err := helper.LabelsOnISTagImage(ctx, client, ist, api.ReleaseArchitectureAMD64)
+		if err != nil {
+			logrus.WithError(err).Debugf("Could not read the labels of %s:%s to determine its commit.", streamName, tag.Name)
+			continue
+		}
+		if commit := labels[commitAnnotation]; commit != "" {
+			ret[tag.Name] = commit
+		}
+	}
+	return ret, nil
+}
+
+// recordProvenance determines the provenance of the components of a release
+// and saves it as an artifact.  It returns the serialized mapping, which is
+// exposed to tests.  The provenance is informational, so failures to determine
+// it are not fatal to the release.
+func recordProvenance(ctx context.Context, client ctrlruntimeclient.Client, namespace, name string) string {
+	provenance, err := releaseProvenance(ctx, client, namespace, api.ReleaseStreamFor(name))
+	if err != nil {
+		logrus.WithError(err).Warnf("Failed to determine the provenance of the components of release %s.", name)
+		return ""
+	}
+	data, err := json.Marshal(provenance)
+	if err != nil {
+		logrus.WithError(err).Warnf("Failed to serialize the provenance of the components of release %s.", name)
+		return ""
+	}
+	if err := api.SaveArtifact(secretutil.NewCensorer(), fmt.Sprintf(ProvenanceArtifact, name), data); err != nil {
+		logrus.WithError(err).Warnf("Failed to save the provenance of the components of release %s.", name)
+	}
+	return string(data)
+}
@@ -0,0 +1,56 @@
+package release
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	fakectrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	imagev1 "github.com/openshift/api/image/v1"
+
+	"github.com/openshift/ci-tools/pkg/testhelper"
+)
+
+func TestRecordProvenance(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("ARTIFACTS", dir)
+	scheme := runtime.NewScheme()
+	if err := imagev1.Install(scheme); err != nil {
+		t.Fatal(err)
+	}
+	client := fakectrlruntimeclient.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&imagev1.ImageStream{
+			ObjectMeta: meta.ObjectMeta{Namespace: "ns", Name: "stable"},
+			Spec: imagev1.ImageStreamSpec{Tags: []imagev1.TagReference{
+				{Name: "installer", Annotations: map[string]string{commitAnnotation: "abc"}},
+				{Name: "built"},
+				{Name: "unlabeled"},
+				{Name: "missing"},
+			}},
+		},
+		&imagev1.ImageStreamTag{
+			ObjectMeta: meta.ObjectMeta{Namespace: "ns", Name: "stable:built"},
+			Image: imagev1.Image{DockerImageMetadata: runtime.RawExtension{
+				Raw: []byte(`{"Config": {"Labels": {"io.openshift.build.commit.id": "def"}}}`),
+			}},
+		},
+		&imagev1.ImageStreamTag{
+			ObjectMeta: meta.ObjectMeta{Namespace: "ns", Name: "stable:unlabeled"},
+			Image: imagev1.Image{DockerImageMetadata: runtime.RawExtension{
+				Raw: []byte(`{"Config": {"Labels": {"vcs-type": "git"}}}`),
+			}},
+		},
+	).Build()
+	expected := `{"built":"def","installer":"abc"}`
+	testhelper.Diff(t, "provenance", recordProvenance(context.Background(), client, "ns", "latest"), expected)
+	artifact, err := os.ReadFile(filepath.Join(dir, "release-latest-provenance.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	testhelper.Diff(t, "artifact", string(artifact), expected)
+	testhelper.Diff(t, "missing release", recordProvenance(context.Background(), client, "ns", "initial"), "")
+}
@@ -0,0 +1,137 @@
+// Package riskanalysis classifies the failures of multi-stage tests using the
+// historical results of their test cases.  A failure of a test case which
+// has failed before is likely unrelated to the change being tested, while a
+// new failure is a risk that gating tooling should consider.
+package riskanalysis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/openshift/ci-tools/pkg/junit"
+)
+
+// Artifact is the name of the summary, relative to the artifact directory of
+// the test.
+const Artifact = "risk-analysis.json"
+
+// Property is the name of the jUnit property added to failed test cases with
+// their classification as the value.
+const Property = "failure_classification"
+
+// CaseHistory holds the historical results of a test case.
+type CaseHistory struct {
+	Runs     int `json:"runs"`
+	Failures int `json:"failures"`
+}
+
+// History holds the historical results of test cases, by name.
+type History struct {
+	Tests map[string]CaseHistory `json:"tests"`
+}
+
+// Load reads the history from a local file or an HTTP(S) URL.
+func Load(ctx context.Context, client *http.Client, source string) (*History, error) {
+	var raw []byte
+	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, source, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch history: %w", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("failed to fetch history: unexpected status code %d", resp.StatusCode)
+		}
+		if raw, err = io.ReadAll(resp.Body); err != nil {
+			return nil, fmt.Errorf("failed to read history: %w", err)
+		}
+	} else {
+		var err error
+		if raw, err = os.ReadFile(source); err != nil {
+			return nil, fmt.Errorf("failed to read history: %w", err)
+		}
+	}
+	history := &History{}
+	if err := json.Unmarshal(raw, history); err != nil {
+		return nil, fmt.Errorf("failed to parse history: %w", err)
+	}
+	return history, nil
+}
+
+// Classification describes whether a failure is expected from history.
+type Classification string
+
+const (
+	// New failures were never seen before for the test case.
+	New Classification = "new"
+	// PreviouslyFailing failures have occurred in previous runs.
+	PreviouslyFailing Classification = "previously_failing"
+)
+
+// Classify determines whether a failure of a test case is new.
+func (h *History) Classify(name string) Classification {
+	if h.Tests[name].Failures != 0 {
+		return PreviouslyFailing
+	}
+	return New
+}
+
+// Level is the overall risk of the failures of a test.
+type Level string
+
+const (
+	// None is the level of a test without failures.
+	None Level = "none"
+	// Low is the level of a test where all failures are known.
+	Low Level = "low"
+	// High is the level of a test with new failures.
+	High Level = "high"
+)
+
+// Failure is a failed test case and its classification.
+type Failure struct {
+	Name           string         `json:"name"`
+	Classification Classification `json:"classification"`
+	// History is the historical record of the test case, if there is one.
+	History *CaseHistory `json:"history,omitempty"`
+}
+
+// Summary is the risk analysis of a test.
+type Summary struct {
+	Test     string    `json:"test"`
+	Risk     Level     `json:"risk"`
+	Failures []Failure `json:"failures"`
+}
+
+// Analyze classifies the failed test cases and marks each of them with their
+// classification as a property.
+func Analyze(history *History, test string, cases []*junit.TestCase) *Summary {
+	summary := &Summary{Test: test, Risk: None, Failures: []Failure{}}
+	for _, c := range cases {
+		if c.FailureOutput == nil {
+			continue
+		}
+		failure := Failure{Name: c.Name, Classification: history.Classify(c.Name)}
+		if h, ok := history.Tests[c.Name]; ok {
+			failure.History = &h
+		}
+		c.Properties = append(c.Properties, &junit.TestSuiteProperty{Name: Property, Value: string(failure.Classification)})
+		summary.Failures = append(summary.Failures, failure)
+		switch {
+		case failure.Classification == New:
+			summary.Risk = High
+		case summary.Risk == None:
+			summary.Risk = Low
+		}
+	}
+	return summary
+}
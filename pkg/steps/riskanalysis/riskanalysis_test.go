@@ -0,0 +1,114 @@
+package riskanalysis
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+
+	"github.com/openshift/ci-tools/pkg/junit"
+)
+
+const rawHistory = `{"tests": {"flaky": {"runs": 10, "failures": 2}, "stable": {"runs": 10, "failures": 0}}}`
+
+var expectedHistory = &History{Tests: map[string]CaseHistory{
+	"flaky":  {Runs: 10, Failures: 2},
+	"stable": {Runs: 10},
+}}
+
+func TestLoad(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/history.json" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(rawHistory))
+	}))
+	defer server.Close()
+	path := filepath.Join(t.TempDir(), "history.json")
+	if err := os.WriteFile(path, []byte(rawHistory), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		name        string
+		source      string
+		expectedErr bool
+	}{{
+		name:   "local file",
+		source: path,
+	}, {
+		name:   "URL",
+		source: server.URL + "/history.json",
+	}, {
+		name:        "missing URL",
+		source:      server.URL + "/missing.json",
+		expectedErr: true,
+	}, {
+		name:        "missing file",
+		source:      path + ".missing",
+		expectedErr: true,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			history, err := Load(context.Background(), server.Client(), tc.source)
+			if (err != nil) != tc.expectedErr {
+				t.Fatalf("expected error: %t, got: %v", tc.expectedErr, err)
+			}
+			if tc.expectedErr {
+				return
+			}
+			if diff := cmp.Diff(expectedHistory, history); diff != "" {
+				t.Errorf("unexpected history: %s", diff)
+			}
+		})
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	failed := func(name string) *junit.TestCase {
+		return &junit.TestCase{Name: name, FailureOutput: &junit.FailureOutput{Output: "failed"}}
+	}
+	for _, tc := range []struct {
+		name     string
+		cases    []*junit.TestCase
+		expected *Summary
+	}{{
+		name:     "no failures",
+		cases:    []*junit.TestCase{{Name: "stable"}},
+		expected: &Summary{Test: "e2e", Risk: None, Failures: []Failure{}},
+	}, {
+		name:  "known failures",
+		cases: []*junit.TestCase{{Name: "stable"}, failed("flaky")},
+		expected: &Summary{Test: "e2e", Risk: Low, Failures: []Failure{
+			{Name: "flaky", Classification: PreviouslyFailing, History: &CaseHistory{Runs: 10, Failures: 2}},
+		}},
+	}, {
+		name:  "new failures",
+		cases: []*junit.TestCase{failed("flaky"), failed("stable"), failed("unknown")},
+		expected: &Summary{Test: "e2e", Risk: High, Failures: []Failure{
+			{Name: "flaky", Classification: PreviouslyFailing, History: &CaseHistory{Runs: 10, Failures: 2}},
+			{Name: "stable", Classification: New, History: &CaseHistory{Runs: 10}},
+			{Name: "unknown", Classification: New},
+		}},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			summary := Analyze(expectedHistory, "e2e", tc.cases)
+			if diff := cmp.Diff(tc.expected, summary); diff != "" {
+				t.Errorf("unexpected summary: %s", diff)
+			}
+			for _, c := range tc.cases {
+				var expected []*junit.TestSuiteProperty
+				if c.FailureOutput != nil {
+					expected = []*junit.TestSuiteProperty{{Name: Property, Value: string(expectedHistory.Classify(c.Name))}}
+				}
+				if diff := cmp.Diff(expected, c.Properties, cmpopts.IgnoreFields(junit.TestSuiteProperty{}, "XMLName")); diff != "" {
+					t.Errorf("unexpected properties of %s: %s", c.Name, diff)
+				}
+			}
+		})
+	}
+}
@@ -11,12 +11,18 @@ import (
 	"github.com/openshift/ci-tools/pkg/results"
 )
 
+// ErrorKindProperty is the property of failed jUnit test cases which holds the
+// kinds of the error, as a comma-delimited list.
+const ErrorKindProperty = "error_kind"
+
 type message struct {
 	node            *api.StepNode
 	duration        time.Duration
 	err             error
 	additionalTests []*junit.TestCase
-	stepDetails     api.CIOperatorStepDetails
+	// additionalSuites are nested under the suite of the step graph
+	additionalSuites []*junit.TestSuite
+	stepDetails      api.CIOperatorStepDetails
 }
 
 func Run(ctx context.Context, graph api.StepGraph) (*junit.TestSuites, []api.CIOperatorStepDetails, []error) {
@@ -58,6 +64,9 @@ func Run(ctx context.Context, graph api.StepGraph) (*junit.TestSuites, []api.CIO
 			stepDetails = append(stepDetails, out.stepDetails)
 			if out.err != nil {
 				testCase.FailureOutput = &junit.FailureOutput{Output: out.err.Error()}
+				if kind := results.KindString(out.err); kind != "" {
+					testCase.Properties = append(testCase.Properties, &junit.TestSuiteProperty{Name: ErrorKindProperty, Value: kind})
+				}
 				executionErrors = append(executionErrors, results.ForReason("step_failed").WithError(out.err).Errorf("step %s failed: %v", out.node.Step.Name(), out.err))
 			} else {
 				seen = append(seen, out.node.Step.Creates()...)
@@ -93,6 +102,7 @@ func Run(ctx context.Context, graph api.StepGraph) (*junit.TestSuites, []api.CIO
 				suite.NumTests++
 				suite.TestCases = append(suite.TestCases, test)
 			}
+			suite.Children = append(suite.Children, out.additionalSuites...)
 
 			wg.Done()
 		case <-done:
@@ -110,6 +120,12 @@ type SubtestReporter interface {
 	SubTests() []*junit.TestCase
 }
 
+// SubSuiteReporter may be implemented by steps that can report JUnit suites
+// produced by the tests they run, in addition to their own test cases.
+type SubSuiteReporter interface {
+	SubSuites() []*junit.TestSuite
+}
+
 // SubStepReporter allows steps to report substeps.
 // TODO: Should this be merged with the SubtestReporter?
 type SubStepReporter interface {
@@ -123,6 +139,10 @@ func runStep(ctx context.Context, node *api.StepNode, out chan<- message) {
 	if reporter, ok := node.Step.(SubtestReporter); ok {
 		additionalTests = reporter.SubTests()
 	}
+	var additionalSuites []*junit.TestSuite
+	if reporter, ok := node.Step.(SubSuiteReporter); ok {
+		additionalSuites = reporter.SubSuites()
+	}
 	duration := time.Since(start)
 	failed := err != nil
 	finishedAt := start.Add(duration)
@@ -133,10 +153,11 @@ func runStep(ctx context.Context, node *api.StepNode, out chan<- message) {
 	}
 
 	out <- message{
-		node:            node,
-		duration:        duration,
-		err:             err,
-		additionalTests: additionalTests,
+		node:             node,
+		duration:         duration,
+		err:              err,
+		additionalTests:  additionalTests,
+		additionalSuites: additionalSuites,
 		stepDetails: api.CIOperatorStepDetails{
 			CIOperatorStepDetailInfo: api.CIOperatorStepDetailInfo{
 				StepName:    node.Step.Name(),
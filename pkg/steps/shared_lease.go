@@ -0,0 +1,137 @@
+package steps
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/lease"
+)
+
+// SharedLeases are the leases declared in `shared_leases`, acquired once for
+// the job and shared by the tests which lease resources of their type.  A
+// single instance is shared by all the tests executed by ci-operator.
+type SharedLeases struct {
+	leases map[string]*sharedLease
+}
+
+// sharedLease is a lease held by the job, reference-counted by the tests
+// using it.
+type sharedLease struct {
+	api.SharedLease
+
+	lock sync.Mutex
+	// expected is the number of tests using the lease which have not started
+	// yet, for which the lease is kept even when no test is using it.
+	expected int
+	// active is the number of tests using the lease.
+	active    int
+	resources []string
+	// job is the lifetime of the job, which bounds that of the lease.
+	job context.Context
+	// ctx is cancelled when the lease is lost or released.
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewSharedLeases returns the leases shared by the tests of the job, nil if
+// the configuration does not declare any.
+func NewSharedLeases(ctx context.Context, leases []api.SharedLease) *SharedLeases {
+	if len(leases) == 0 {
+		return nil
+	}
+	ret := SharedLeases{leases: map[string]*sharedLease{}}
+	for _, l := range leases {
+		if l.Count == 0 {
+			l.Count = 1
+		}
+		ret.leases[l.ResourceType] = &sharedLease{SharedLease: l, job: ctx}
+	}
+	return &ret
+}
+
+func (s *SharedLeases) lease(resourceType string) *sharedLease {
+	if s == nil {
+		return nil
+	}
+	return s.leases[resourceType]
+}
+
+// Expect registers a test which will use the shared leases of the types it
+// needs, so that they are not released before the test has run.  It is
+// called for each such test of the graph before it is executed.
+func (s *SharedLeases) Expect(leases []api.StepLease) {
+	seen := map[*sharedLease]bool{}
+	for _, l := range leases {
+		shared := s.lease(l.ResourceType)
+		if shared == nil || seen[shared] {
+			continue
+		}
+		seen[shared] = true
+		shared.lock.Lock()
+		shared.expected++
+		shared.lock.Unlock()
+	}
+}
+
+// acquire attaches a test to the shared lease, acquiring it if no other test
+// holds it.  The test is cancelled if the lease is lost.  The returned
+// function detaches the test, releasing the lease once the last test which
+// uses it has finished.
+func (l *sharedLease) acquire(client lease.Client, cancel context.CancelFunc, count uint) ([]string, func() error, error) {
+	if count == 0 {
+		count = 1
+	}
+	if count > l.Count {
+		return nil, nil, fmt.Errorf("%d %s lease(s) are needed, but the job shares only %d", count, l.ResourceType, l.Count)
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.expected > 0 {
+		l.expected--
+	}
+	if l.resources == nil {
+		logrus.Debugf("Acquiring %d shared lease(s) for %s", l.Count, l.ResourceType)
+		ctx, cancelLease := context.WithCancel(l.job)
+		names, err := client.Acquire(l.ResourceType, l.Count, ctx, cancelLease)
+		if err != nil {
+			cancelLease()
+			return nil, nil, err
+		}
+		logrus.Infof("Acquired %d shared lease(s) for %s: %v", l.Count, l.ResourceType, names)
+		l.resources, l.ctx, l.cancel = names, ctx, cancelLease
+	}
+	l.active++
+	stop := context.AfterFunc(l.ctx, cancel)
+	release := func() error {
+		stop()
+		return l.release(client)
+	}
+	return l.resources[:count], release, nil
+}
+
+// release detaches a test from the lease and releases it if no other test
+// uses or is expected to use it.
+func (l *sharedLease) release(client lease.Client) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.active--
+	if l.active > 0 || l.expected > 0 || l.resources == nil {
+		return nil
+	}
+	logrus.Infof("Releasing the shared leases for %s, no other test uses them", l.ResourceType)
+	var errs []error
+	for _, r := range l.resources {
+		if err := client.Release(r); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	l.cancel()
+	l.resources, l.ctx, l.cancel = nil, nil, nil
+	return utilerrors.NewAggregate(errs)
+}
@@ -0,0 +1,84 @@
+package steps
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/lease"
+	"github.com/openshift/ci-tools/pkg/testhelper"
+)
+
+func TestSharedLeaseStep(t *testing.T) {
+	var calls []string
+	client := lease.NewFakeClient("owner", "url", 0, nil, &calls)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	shared := NewSharedLeases(ctx, []api.SharedLease{{ResourceType: "shared", Count: 2}})
+	leases := []api.StepLease{
+		{ResourceType: "shared", Env: api.DefaultLeaseEnv, Count: 1},
+		{ResourceType: "own", Env: "OWN_LEASE", Count: 1},
+	}
+	shared.Expect(leases)
+	shared.Expect(leases[:1])
+	first, second := stepNeedsLease{}, stepNeedsLease{}
+	for _, step := range []*stepNeedsLease{&first, &second} {
+		withLease := SharedLeaseStep(&client, shared, leases, step, emptyNamespace)
+		if err := withLease.Run(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if !step.ran {
+			t.Fatal("step was not executed")
+		}
+		resource, err := withLease.Provides()[api.DefaultLeaseEnv]()
+		if err != nil {
+			t.Fatal(err)
+		}
+		testhelper.Diff(t, "shared resource", resource, "shared_1")
+	}
+	testhelper.Diff(t, "calls", calls, []string{
+		"acquire owner own free leased random",
+		"acquire owner shared free leased random",
+		"acquire owner shared free leased random",
+		"releaseone owner own_0 free",
+		"acquire owner own free leased random",
+		"releaseone owner shared_1 free",
+		"releaseone owner shared_2 free",
+		"releaseone owner own_4 free",
+	})
+}
+
+func TestSharedLeaseStepCount(t *testing.T) {
+	var calls []string
+	client := lease.NewFakeClient("owner", "url", 0, nil, &calls)
+	shared := NewSharedLeases(context.Background(), []api.SharedLease{{ResourceType: "shared"}})
+	leases := []api.StepLease{{ResourceType: "shared", Env: api.DefaultLeaseEnv, Count: 2}}
+	step := stepNeedsLease{}
+	err := SharedLeaseStep(&client, shared, leases, &step, emptyNamespace).Run(context.Background())
+	testhelper.Diff(t, "error", err, errors.New(`failed to acquire lease for "shared": 2 shared lease(s) are needed, but the job shares only 1`), testhelper.EquateErrorMessage)
+	if step.ran {
+		t.Error("step was executed without its leases")
+	}
+	testhelper.Diff(t, "calls", calls, []string(nil))
+}
+
+func TestSharedLeaseLost(t *testing.T) {
+	client := lease.NewFakeClient("owner", "url", 0, nil, nil)
+	job, loseLease := context.WithCancel(context.Background())
+	defer loseLease()
+	shared := NewSharedLeases(job, []api.SharedLease{{ResourceType: "shared"}})
+	l := shared.lease("shared")
+	cancelled := make(chan struct{})
+	if _, release, err := l.acquire(client, func() { close(cancelled) }, 1); err != nil {
+		t.Fatal(err)
+	} else {
+		defer func() {
+			if err := release(); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	loseLease()
+	<-cancelled
+}
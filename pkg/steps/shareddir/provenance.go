@@ -0,0 +1,138 @@
+package shareddir
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// ProvenanceKey is the key in the shared directory secret which holds the
+// chain of records signed by the steps which wrote its contents.  It is not
+// part of the shared directory exposed to steps.
+const ProvenanceKey = ".provenance"
+
+// Record is written by a step each time it updates the shared directory.  It
+// is signed with the key of the job and references the record the contents
+// were based on, so that the order of the updates of steps which run in
+// parallel is recorded.  The key is readable in the namespace while the test
+// runs, so the records only detect changes made without access to it.
+type Record struct {
+	// Step is the name of the step which wrote the contents.
+	Step string `json:"step"`
+	// Digest is the checksum of the contents, see Digest.
+	Digest string `json:"digest"`
+	// Previous is the signature of the record the contents were based on.
+	Previous string `json:"previous,omitempty"`
+	// Signature is the HMAC of the other fields with the key of the job.
+	Signature string `json:"signature"`
+}
+
+// Chain holds the records of the updates of the shared directory, oldest
+// first.
+type Chain []Record
+
+// Digest returns the checksum of the contents of the shared directory,
+// excluding the provenance records.
+func Digest(data map[string][]byte) string {
+	var keys []string
+	for k := range data {
+		if k != ProvenanceKey {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	h := sha256.New()
+	for _, k := range keys {
+		fmt.Fprintf(h, "%d:%s%d:", len(k), k, len(data[k]))
+		h.Write(data[k])
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func sign(key []byte, r Record) string {
+	mac := hmac.New(sha256.New, key)
+	fmt.Fprintf(mac, "%s\n%s\n%s", r.Step, r.Digest, r.Previous)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Head returns the signature of the last record, or an empty string for the
+// initial, empty, shared directory.
+func (c Chain) Head() string {
+	if len(c) == 0 {
+		return ""
+	}
+	return c[len(c)-1].Signature
+}
+
+// Append returns the chain with a record for contents written by a step.
+func (c Chain) Append(key []byte, step string, data map[string][]byte) Chain {
+	r := Record{Step: step, Digest: Digest(data), Previous: c.Head()}
+	r.Signature = sign(key, r)
+	return append(append(Chain(nil), c...), r)
+}
+
+// Verify checks that each record was signed with the key and is based on the
+// one before it, and that the contents are those of the last record.
+func (c Chain) Verify(key []byte, data map[string][]byte) error {
+	var previous string
+	for i, r := range c {
+		if !hmac.Equal([]byte(r.Signature), []byte(sign(key, r))) {
+			return fmt.Errorf("invalid signature of record %d, written by step %s", i, r.Step)
+		}
+		if r.Previous != previous {
+			return fmt.Errorf("record %d, written by step %s, is not based on the previous record", i, r.Step)
+		}
+		previous = r.Signature
+	}
+	if len(c) == 0 {
+		if len(data) != 0 {
+			return fmt.Errorf("the shared directory has contents which were not written by a step")
+		}
+		return nil
+	}
+	if last := c[len(c)-1]; last.Digest != Digest(data) {
+		return fmt.Errorf("the contents of the shared directory do not match those written by step %s", last.Step)
+	}
+	return nil
+}
+
+// SplitProvenance separates the provenance records from the contents of the
+// shared directory.
+func SplitProvenance(data map[string][]byte) (map[string][]byte, Chain, error) {
+	raw, ok := data[ProvenanceKey]
+	if !ok {
+		return data, nil, nil
+	}
+	var chain Chain
+	if err := json.Unmarshal(raw, &chain); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse provenance records: %w", err)
+	}
+	ret := make(map[string][]byte, len(data)-1)
+	for k, v := range data {
+		if k != ProvenanceKey {
+			ret[k] = v
+		}
+	}
+	return ret, chain, nil
+}
+
+// VerifiedData returns the contents of the shared directory, without the
+// provenance records, after verifying them.
+func VerifiedData(key []byte, data map[string][]byte) (map[string][]byte, Chain, error) {
+	data, chain, err := SplitProvenance(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := chain.Verify(key, data); err != nil {
+		return nil, nil, fmt.Errorf("failed to verify the provenance of the shared directory: %w", err)
+	}
+	return data, chain, nil
+}
+
+// Marshal serializes the chain, to be stored under ProvenanceKey.
+func (c Chain) Marshal() ([]byte, error) {
+	return json.Marshal(c)
+}
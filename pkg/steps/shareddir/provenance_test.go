@@ -0,0 +1,99 @@
+package shareddir
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestProvenance(t *testing.T) {
+	key, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	install := map[string][]byte{"kubeconfig": []byte("kubeconfig")}
+	configure := map[string][]byte{"kubeconfig": []byte("kubeconfig"), "proxy": []byte("proxy")}
+	chain := Chain(nil).Append(key, "install", install).Append(key, "configure", configure)
+	tampered := append(Chain(nil), chain...)
+	tampered[0].Step = "other"
+	for _, tc := range []struct {
+		name     string
+		chain    Chain
+		key      []byte
+		data     map[string][]byte
+		expected string
+	}{{
+		name: "initial shared directory",
+		key:  key,
+	}, {
+		name:     "contents without records",
+		key:      key,
+		data:     install,
+		expected: "the shared directory has contents which were not written by a step",
+	}, {
+		name:  "valid chain",
+		chain: chain,
+		key:   key,
+		data:  configure,
+	}, {
+		name:     "contents modified after the last step",
+		chain:    chain,
+		key:      key,
+		data:     install,
+		expected: "the contents of the shared directory do not match those written by step configure",
+	}, {
+		name:     "signed with another key",
+		chain:    chain,
+		key:      other,
+		data:     configure,
+		expected: "invalid signature of record 0, written by step install",
+	}, {
+		name:     "modified record",
+		chain:    tampered,
+		key:      key,
+		data:     configure,
+		expected: "invalid signature of record 0, written by step other",
+	}, {
+		name:     "record removed",
+		chain:    chain[1:],
+		key:      key,
+		data:     configure,
+		expected: "record 0, written by step configure, is not based on the previous record",
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			var actual string
+			if err := tc.chain.Verify(tc.key, tc.data); err != nil {
+				actual = err.Error()
+			}
+			if diff := cmp.Diff(tc.expected, actual); diff != "" {
+				t.Errorf("unexpected error: %s", diff)
+			}
+		})
+	}
+}
+
+func TestVerifiedData(t *testing.T) {
+	key, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := map[string][]byte{"kubeconfig": []byte("kubeconfig")}
+	raw, err := Chain(nil).Append(key, "install", data).Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual, chain, err := VerifiedData(key, map[string][]byte{"kubeconfig": []byte("kubeconfig"), ProvenanceKey: raw})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(data, actual); diff != "" {
+		t.Errorf("unexpected contents: %s", diff)
+	}
+	if len(chain) != 1 || chain[0].Step != "install" {
+		t.Errorf("unexpected chain: %v", chain)
+	}
+}
@@ -0,0 +1,111 @@
+// Package shareddir implements the encryption of the contents of the shared
+// directory of multi-stage tests.  The shared directory often holds
+// credentials for the cluster under test; when encryption is enabled, its
+// contents are stored in the secret encrypted with a key generated for each
+// job.  The entrypoint wrapper of the steps decrypts the contents to a
+// memory-backed volume.  Each update of the contents is also recorded in a
+// chain of records signed with the key, which is verified when they are read.
+//
+// The key is stored in a plain secret in the namespace of the test and is
+// mounted into the container of each step, so while the test runs, anyone
+// who can read the secrets of the namespace or run a command in a step can
+// also read the key and decrypt or sign the contents.  The encryption only
+// protects the contents once the test finishes and the key is deleted, e.g.
+// in copies of the secret gathered as artifacts or left behind in the
+// namespace.
+package shareddir
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const (
+	// KeySize is the size in bytes of the AES-256 key.
+	KeySize = 32
+	// KeySecretKey is the key in the key secret under which the key is
+	// stored.
+	KeySecretKey = "key"
+)
+
+// KeySecretName is the name of the secret holding the key of the shared
+// directory of a test.
+func KeySecretName(test string) string {
+	return test + "-shared-dir-key"
+}
+
+// GenerateKey creates a random key.
+func GenerateKey() ([]byte, error) {
+	key := make([]byte, KeySize)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+	return key, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("invalid key size %d, expected %d", len(key), KeySize)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// Encrypt seals the data with AES-GCM, prefixing the result with the random
+// nonce used.
+func Encrypt(key, data []byte) ([]byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return aead.Seal(nonce, nonce, data, nil), nil
+}
+
+// Decrypt opens data sealed by Encrypt.
+func Decrypt(key, data []byte) ([]byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < aead.NonceSize() {
+		return nil, errors.New("encrypted data is too short")
+	}
+	nonce, sealed := data[:aead.NonceSize()], data[aead.NonceSize():]
+	ret, err := aead.Open(nil, nonce, sealed, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt: %w", err)
+	}
+	return ret, nil
+}
+
+// EncryptData encrypts each value in the data of a secret.
+func EncryptData(key []byte, data map[string][]byte) (map[string][]byte, error) {
+	return transform(data, func(v []byte) ([]byte, error) { return Encrypt(key, v) })
+}
+
+// DecryptData decrypts each value in the data of a secret.
+func DecryptData(key []byte, data map[string][]byte) (map[string][]byte, error) {
+	return transform(data, func(v []byte) ([]byte, error) { return Decrypt(key, v) })
+}
+
+func transform(data map[string][]byte, f func([]byte) ([]byte, error)) (map[string][]byte, error) {
+	ret := make(map[string][]byte, len(data))
+	for k, v := range data {
+		var err error
+		if ret[k], err = f(v); err != nil {
+			return nil, fmt.Errorf("%s: %w", k, err)
+		}
+	}
+	return ret, nil
+}
@@ -0,0 +1,57 @@
+package shareddir
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestEncryptDecrypt(t *testing.T) {
+	key, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := map[string][]byte{
+		"kubeconfig":         []byte("apiVersion: v1\nkind: Config\n"),
+		"kubeadmin-password": []byte("hunter2"),
+		"empty":              {},
+	}
+	encrypted, err := EncryptData(key, data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for k, v := range encrypted {
+		if bytes.Contains(v, data[k]) && len(data[k]) != 0 {
+			t.Errorf("%s: encrypted data contains the plain text", k)
+		}
+	}
+	decrypted, err := DecryptData(key, encrypted)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diff := cmp.Diff(data, decrypted, cmpopts.EquateEmpty()); diff != "" {
+		t.Errorf("unexpected decrypted data: %s", diff)
+	}
+	other, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := DecryptData(other, encrypted); err == nil {
+		t.Error("expected decryption with another key to fail")
+	}
+}
+
+func TestInvalidInput(t *testing.T) {
+	if _, err := Encrypt([]byte("short"), []byte("data")); err == nil {
+		t.Error("expected an error for an invalid key")
+	}
+	key, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Decrypt(key, []byte("x")); err == nil {
+		t.Error("expected an error for truncated data")
+	}
+}
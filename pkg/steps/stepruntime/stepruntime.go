@@ -0,0 +1,182 @@
+// Package stepruntime implements the interface through which the commands of
+// multi-stage test steps communicate with ci-operator.  Commands run the
+// `ci-step` helper, which records their requests in a file in the directory
+// at $CI_STEP_RUNTIME_DIR.  Once the command exits, the entrypoint wrapper
+// stores the record in the results secret of the test, from which ci-operator
+// reports it with the results of the test.
+package stepruntime
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const (
+	// DirEnv exposes the directory in which the requests of the command of a
+	// step are recorded.
+	DirEnv = "CI_STEP_RUNTIME_DIR"
+	// ArtifactsDir is the directory in $ARTIFACT_DIR to which the artifacts
+	// added with the helper are copied.
+	ArtifactsDir = "ci-step"
+	// recordFile is the name of the file of the record in the directory.
+	recordFile = "record.json"
+	// secretKeySuffix is appended to the name of a step to form the key of
+	// its record in the results secret of the test.
+	secretKeySuffix = ".runtime"
+)
+
+var outputKeyRegexp = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_.-]*$`)
+
+// Record holds the requests of the command of a step.
+type Record struct {
+	// Outputs are values published by the step, by key.
+	Outputs map[string]string `json:"outputs,omitempty"`
+	// Artifacts are the paths of the files added to the artifacts of the
+	// step, relative to $ARTIFACT_DIR.
+	Artifacts []string `json:"artifacts,omitempty"`
+	// Flaky lists the reasons for which the step reported itself as flaky.
+	Flaky []string `json:"flaky,omitempty"`
+}
+
+// Empty determines whether the command made any request.
+func (r *Record) Empty() bool {
+	return len(r.Outputs) == 0 && len(r.Artifacts) == 0 && len(r.Flaky) == 0
+}
+
+// SecretKey is the key of the record of a step in the results secret.
+func SecretKey(step string) string {
+	return step + secretKeySuffix
+}
+
+// StepForSecretKey returns the step of a key of the results secret, if it
+// holds a record.
+func StepForSecretKey(key string) (string, bool) {
+	if !strings.HasSuffix(key, secretKeySuffix) {
+		return "", false
+	}
+	return strings.TrimSuffix(key, secretKeySuffix), true
+}
+
+// Load reads the record in a directory.  The record is empty if the command
+// did not make any request.
+func Load(dir string) (*Record, error) {
+	raw, err := os.ReadFile(filepath.Join(dir, recordFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return &Record{}, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read step runtime record: %w", err)
+	}
+	return Decode(raw)
+}
+
+// Decode parses a serialized record.
+func Decode(raw []byte) (*Record, error) {
+	var ret Record
+	if err := json.Unmarshal(raw, &ret); err != nil {
+		return nil, fmt.Errorf("failed to parse step runtime record: %w", err)
+	}
+	return &ret, nil
+}
+
+// update applies a change to the record in a directory.  The record is
+// replaced atomically, so that it is never read partially written.
+func update(dir string, f func(*Record) error) error {
+	record, err := Load(dir)
+	if err != nil {
+		return err
+	}
+	if err := f(record); err != nil {
+		return err
+	}
+	raw, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal step runtime record: %w", err)
+	}
+	tmp, err := os.CreateTemp(dir, recordFile)
+	if err != nil {
+		return fmt.Errorf("failed to write step runtime record: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(raw); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write step runtime record: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write step runtime record: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), filepath.Join(dir, recordFile)); err != nil {
+		return fmt.Errorf("failed to write step runtime record: %w", err)
+	}
+	return nil
+}
+
+// SetOutput publishes a value of the step.
+func SetOutput(dir, key, value string) error {
+	if !outputKeyRegexp.MatchString(key) {
+		return fmt.Errorf("invalid output key %q: must match %s", key, outputKeyRegexp.String())
+	}
+	return update(dir, func(r *Record) error {
+		if r.Outputs == nil {
+			r.Outputs = map[string]string{}
+		}
+		r.Outputs[key] = value
+		return nil
+	})
+}
+
+// AddArtifact copies a file to the artifacts of the step.  Files are copied
+// under ArtifactsDir in the artifact directory, keeping their name.
+func AddArtifact(dir, artifactDir, path string) error {
+	name := filepath.Base(path)
+	dst := filepath.Join(ArtifactsDir, name)
+	if err := os.MkdirAll(filepath.Join(artifactDir, ArtifactsDir), 0755); err != nil {
+		return fmt.Errorf("failed to create artifact directory: %w", err)
+	}
+	if err := copyFile(filepath.Join(artifactDir, dst), path); err != nil {
+		return fmt.Errorf("failed to copy %s to the artifacts: %w", path, err)
+	}
+	return update(dir, func(r *Record) error {
+		for _, existing := range r.Artifacts {
+			if existing == dst {
+				return nil
+			}
+		}
+		r.Artifacts = append(r.Artifacts, dst)
+		return nil
+	})
+}
+
+func copyFile(dst, src string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// MarkFlaky reports that the step observed a flake.
+func MarkFlaky(dir, reason string) error {
+	if reason == "" {
+		return errors.New("a reason is required to mark the step as flaky")
+	}
+	return update(dir, func(r *Record) error {
+		r.Flaky = append(r.Flaky, reason)
+		return nil
+	})
+}
@@ -0,0 +1,72 @@
+package stepruntime
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/openshift/ci-tools/pkg/testhelper"
+)
+
+func TestRecord(t *testing.T) {
+	dir, artifactDir := t.TempDir(), t.TempDir()
+	record, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !record.Empty() {
+		t.Errorf("expected an empty record without requests, got %v", record)
+	}
+	for _, f := range []func() error{
+		func() error { return SetOutput(dir, "cluster_version", "4.14.0") },
+		func() error { return SetOutput(dir, "cluster_version", "4.15.0") },
+		func() error { return SetOutput(dir, "region", "us-east-1") },
+		func() error { return MarkFlaky(dir, "the API server was briefly unavailable") },
+	} {
+		if err := f(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	file := filepath.Join(t.TempDir(), "report.html")
+	if err := os.WriteFile(file, []byte("<html/>"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := AddArtifact(dir, artifactDir, file); err != nil {
+			t.Fatal(err)
+		}
+	}
+	record, err = Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testhelper.Diff(t, "record", record, &Record{
+		Outputs:   map[string]string{"cluster_version": "4.15.0", "region": "us-east-1"},
+		Artifacts: []string{"ci-step/report.html"},
+		Flaky:     []string{"the API server was briefly unavailable"},
+	})
+	copied, err := os.ReadFile(filepath.Join(artifactDir, "ci-step", "report.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	testhelper.Diff(t, "artifact", string(copied), "<html/>")
+}
+
+func TestRecordErrors(t *testing.T) {
+	dir := t.TempDir()
+	testhelper.Diff(t, "invalid key", SetOutput(dir, "not a key", "value"), errors.New(`invalid output key "not a key": must match ^[a-zA-Z_][a-zA-Z0-9_.-]*$`), testhelper.EquateErrorMessage)
+	testhelper.Diff(t, "no reason", MarkFlaky(dir, ""), errors.New("a reason is required to mark the step as flaky"), testhelper.EquateErrorMessage)
+	if err := AddArtifact(dir, t.TempDir(), filepath.Join(dir, "missing")); err == nil {
+		t.Error("expected an error for a missing artifact")
+	}
+}
+
+func TestSecretKey(t *testing.T) {
+	step, ok := StepForSecretKey(SecretKey("e2e-0"))
+	testhelper.Diff(t, "step", step, "e2e-0")
+	testhelper.Diff(t, "record key", ok, true)
+	if _, ok := StepForSecretKey("e2e-0"); ok {
+		t.Error("expected the key of jUnit results not to be a record key")
+	}
+}
@@ -0,0 +1,116 @@
+// Package testresults implements the collection of jUnit results produced by
+// multi-stage test steps.  Steps write `junit_*.xml` files anywhere in
+// `$ARTIFACT_DIR`; when the step finishes, those are parsed, merged and stored
+// in a secret, from which ci-operator includes them in its own jUnit output.
+package testresults
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+
+	"github.com/openshift/ci-tools/pkg/junit"
+)
+
+// Pattern matches the names of files collected from the artifact directory.
+const Pattern = "junit_*.xml"
+
+// SecretName is the name of the secret holding the results of the steps of a
+// test, stored with the name of each step as the key.
+func SecretName(test string) string {
+	return test + "-junit"
+}
+
+// Collect parses and merges all result files in `dir`.  Files which cannot be
+// parsed are reported as errors, but do not prevent the others from being
+// collected.  A missing directory is not an error: most steps do not produce
+// any results.
+func Collect(dir string) (*junit.TestSuites, error) {
+	ret := &junit.TestSuites{}
+	var errs []error
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		if match, _ := filepath.Match(Pattern, d.Name()); !match {
+			return nil
+		}
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		suites, err := Parse(raw)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", path, err))
+			return nil
+		}
+		ret.Suites = append(ret.Suites, suites...)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list result files: %w", err)
+	}
+	return ret, utilerrors.NewAggregate(errs)
+}
+
+// Parse reads the suites in a jUnit file, which may have either a
+// `<testsuites>` or a single `<testsuite>` root element.
+func Parse(raw []byte) ([]*junit.TestSuite, error) {
+	suites := junit.TestSuites{}
+	if err := xml.Unmarshal(raw, &suites); err == nil {
+		return suites.Suites, nil
+	}
+	suite := junit.TestSuite{}
+	if err := xml.Unmarshal(raw, &suite); err != nil {
+		return nil, fmt.Errorf("failed to parse jUnit: %w", err)
+	}
+	return []*junit.TestSuite{&suite}, nil
+}
+
+// Encode serializes the results in the format stored in the secret.
+func Encode(suites *junit.TestSuites) ([]byte, error) {
+	raw, err := xml.Marshal(suites)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal jUnit: %w", err)
+	}
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(raw); err != nil {
+		return nil, fmt.Errorf("failed to compress jUnit: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress jUnit: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// Decode parses the results stored in the secret.
+func Decode(data []byte) (*junit.TestSuites, error) {
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress jUnit: %w", err)
+	}
+	raw, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress jUnit: %w", err)
+	}
+	suites := &junit.TestSuites{}
+	if err := xml.Unmarshal(raw, suites); err != nil {
+		return nil, fmt.Errorf("failed to parse jUnit: %w", err)
+	}
+	return suites, nil
+}
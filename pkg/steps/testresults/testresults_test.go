@@ -0,0 +1,77 @@
+package testresults
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+
+	"github.com/openshift/ci-tools/pkg/junit"
+)
+
+func TestCollect(t *testing.T) {
+	dir := t.TempDir()
+	for path, data := range map[string]string{
+		"junit_suites.xml":        `<testsuites><testsuite name="e2e" tests="2" failures="1"><testcase name="passes"></testcase><testcase name="fails"><failure>oops</failure></testcase></testsuite></testsuites>`,
+		"nested/junit_suite.xml":  `<testsuite name="unit" tests="1"><testcase name="passes"></testcase></testsuite>`,
+		"nested/junit_broken.xml": `<testsuite`,
+		"other.xml":               `<testsuite name="ignored"></testsuite>`,
+	} {
+		path = filepath.Join(dir, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	suites, err := Collect(dir)
+	if err == nil {
+		t.Error("expected an error for the invalid file")
+	}
+	expected := &junit.TestSuites{Suites: []*junit.TestSuite{{
+		Name:      "e2e",
+		NumTests:  2,
+		NumFailed: 1,
+		TestCases: []*junit.TestCase{
+			{Name: "passes"},
+			{Name: "fails", FailureOutput: &junit.FailureOutput{Output: "oops"}},
+		},
+	}, {
+		Name:      "unit",
+		NumTests:  1,
+		TestCases: []*junit.TestCase{{Name: "passes"}},
+	}}}
+	ignoreXMLNames := cmp.Options{
+		cmpopts.IgnoreFields(junit.TestSuites{}, "XMLName"),
+		cmpopts.IgnoreFields(junit.TestSuite{}, "XMLName"),
+		cmpopts.IgnoreFields(junit.TestCase{}, "XMLName"),
+		cmpopts.IgnoreFields(junit.FailureOutput{}, "XMLName"),
+	}
+	if diff := cmp.Diff(expected, suites, ignoreXMLNames); diff != "" {
+		t.Errorf("unexpected suites: %s", diff)
+	}
+	raw, err := Encode(suites)
+	if err != nil {
+		t.Fatalf("failed to encode: %v", err)
+	}
+	decoded, err := Decode(raw)
+	if err != nil {
+		t.Fatalf("failed to decode: %v", err)
+	}
+	if diff := cmp.Diff(expected, decoded, ignoreXMLNames); diff != "" {
+		t.Errorf("unexpected decoded suites: %s", diff)
+	}
+}
+
+func TestCollectMissingDirectory(t *testing.T) {
+	suites, err := Collect(filepath.Join(t.TempDir(), "missing"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(suites.Suites) != 0 {
+		t.Errorf("unexpected suites: %v", suites.Suites)
+	}
+}
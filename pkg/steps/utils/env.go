@@ -147,6 +147,13 @@ func IsReleaseImageEnv(envVar string) bool {
 	return strings.HasPrefix(envVar, knownPrefixes[api.ReleaseImageStream])
 }
 
+// ReleaseProvenanceEnv determines the environment variable used to expose
+// the mapping of the components of a release to the commits they were built
+// from, serialized as JSON.
+func ReleaseProvenanceEnv(name string) string {
+	return "RELEASE_PROVENANCE_" + escapedImageName(name)
+}
+
 // ReleaseNameFrom determines the name of the release payload
 // that the pull spec points to.
 func ReleaseNameFrom(envVar string) string {
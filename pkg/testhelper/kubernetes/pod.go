@@ -95,6 +95,7 @@ type FakePodExecutor struct {
 	Lock sync.RWMutex
 	loggingclient.LoggingClient
 	Failures          sets.Set[string]
+	ExitCodes         map[string]int32
 	CreatedPods       []*coreapi.Pod
 	PodPayloadRunners map[string]*PodPayloadRunner
 }
@@ -164,6 +165,9 @@ func (f *FakePodExecutor) process(pod *coreapi.Pod, ch chan watch.Event) bool {
 		terminated := &coreapi.ContainerStateTerminated{}
 		if fail {
 			terminated.ExitCode = 1
+			if code, ok := f.ExitCodes[pod.Name]; ok {
+				terminated.ExitCode = code
+			}
 		}
 		pod.Status.ContainerStatuses = append(pod.Status.ContainerStatuses, coreapi.ContainerStatus{
 			Name:  container.Name,
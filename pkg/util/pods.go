@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math"
 	"sort"
 	"strings"
 	"sync/atomic"
@@ -42,21 +43,88 @@ const (
 	Interruptible
 )
 
+// PodQuotaBackoff is how the creation of a pod is retried while the
+// namespace has exhausted its quota.
+type PodQuotaBackoff struct {
+	// Initial is the delay before the first retry, doubled after each retry
+	// up to Max.
+	Initial, Max time.Duration
+	// Timeout is how long to wait for quota before failing, zero to fail
+	// immediately.
+	Timeout time.Duration
+}
+
+// DefaultPodQuotaBackoff waits for half an hour for the quota of the
+// namespace to be freed by the pods of other steps.
+var DefaultPodQuotaBackoff = PodQuotaBackoff{Initial: 5 * time.Second, Max: time.Minute, Timeout: 30 * time.Minute}
+
+// IsQuotaExceeded determines whether the creation of an object failed because
+// the namespace has exhausted its resource quota.
+func IsQuotaExceeded(err error) bool {
+	return kerrors.IsForbidden(err) && strings.Contains(err.Error(), "exceeded quota")
+}
+
 func CreateOrRestartPod(ctx context.Context, podClient ctrlruntimeclient.Client, pod *corev1.Pod) (*corev1.Pod, error) {
+	pod, _, err := CreateOrRestartPodWithQuotaBackoff(ctx, podClient, pod, DefaultPodQuotaBackoff)
+	return pod, err
+}
+
+// CreateOrRestartPodWithQuotaBackoff is CreateOrRestartPod, waiting with the
+// backoff while the namespace has exhausted its quota.  It returns the time
+// for which the creation of the pod was blocked on quota.
+func CreateOrRestartPodWithQuotaBackoff(ctx context.Context, podClient ctrlruntimeclient.Client, pod *corev1.Pod, quota PodQuotaBackoff) (*corev1.Pod, time.Duration, error) {
 	namespace, name := pod.Namespace, pod.Name
 	if err := waitForCompletedPodDeletion(ctx, podClient, namespace, name); err != nil {
-		return nil, fmt.Errorf("unable to delete completed pod: %w", err)
+		return nil, 0, fmt.Errorf("unable to delete completed pod: %w", err)
 	}
 	if pod.Spec.ActiveDeadlineSeconds == nil {
 		logrus.Debugf("Executing pod %q running image %q", pod.Name, pod.Spec.Containers[0].Image)
 	} else {
 		logrus.Debugf("Executing pod %q with activeDeadlineSeconds=%d", pod.Name, *pod.Spec.ActiveDeadlineSeconds)
 	}
+	backoff := wait.Backoff{Duration: quota.Initial, Factor: 2, Steps: math.MaxInt32, Cap: quota.Max}
+	var blockedSince time.Time
+	for {
+		err := createPod(ctx, podClient, pod)
+		if err == nil {
+			return pod, blockedFor(blockedSince), nil
+		}
+		if !IsQuotaExceeded(err) {
+			return nil, blockedFor(blockedSince), fmt.Errorf("unable to create pod: %w", err)
+		}
+		if blockedSince.IsZero() {
+			blockedSince = time.Now()
+			logrus.Infof("Waiting for quota to create pod %s in namespace %s: %v", name, namespace, err)
+		}
+		if blocked := blockedFor(blockedSince); blocked >= quota.Timeout {
+			return nil, blocked, fmt.Errorf("unable to create pod: still out of quota after %s: %w", blocked.Truncate(time.Second), err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil, blockedFor(blockedSince), fmt.Errorf("unable to create pod while waiting for quota: %w", ctx.Err())
+		case <-time.After(backoff.Step()):
+		}
+	}
+}
+
+func blockedFor(since time.Time) time.Duration {
+	if since.IsZero() {
+		return 0
+	}
+	return time.Since(since)
+}
+
+// createPod creates the pod or retrieves it if it already exists.
+func createPod(ctx context.Context, podClient ctrlruntimeclient.Client, pod *corev1.Pod) error {
+	namespace, name := pod.Namespace, pod.Name
 	// creating a pod in close proximity to namespace creation can result in forbidden errors due to
 	// initializing secrets or policy - use a short backoff to mitigate flakes
 	if err := wait.ExponentialBackoff(wait.Backoff{Steps: 4, Factor: 2, Duration: time.Second}, func() (bool, error) {
 		err := podClient.Create(ctx, pod)
 		if err != nil {
+			if IsQuotaExceeded(err) {
+				return false, err
+			}
 			if kerrors.IsForbidden(err) {
 				logrus.WithError(err).Warnf("Unable to create pod %s, may be temporary.", name)
 				return false, nil
@@ -71,9 +139,9 @@ func CreateOrRestartPod(ctx context.Context, podClient ctrlruntimeclient.Client,
 		}
 		return true, nil
 	}); err != nil {
-		return nil, fmt.Errorf("unable to create pod: %w", err)
+		return err
 	}
-	return pod, nil
+	return nil
 }
 
 func waitForCompletedPodDeletion(ctx context.Context, podClient ctrlruntimeclient.Client, namespace, name string) error {
@@ -1,12 +1,16 @@
 package util
 
 import (
+	"context"
 	"errors"
 	"testing"
 	"time"
 
 	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	fakectrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
 
 	"github.com/openshift/ci-tools/pkg/testhelper"
 )
@@ -235,3 +239,53 @@ func TestCheckPending(t *testing.T) {
 		})
 	}
 }
+
+// quotaExceededClient fails to create pods until the quota is freed.
+type quotaExceededClient struct {
+	ctrlruntimeclient.Client
+	failures int
+}
+
+func (c *quotaExceededClient) Create(ctx context.Context, obj ctrlruntimeclient.Object, opts ...ctrlruntimeclient.CreateOption) error {
+	if c.failures > 0 {
+		c.failures--
+		return kerrors.NewForbidden(corev1.Resource("pods"), obj.GetName(), errors.New("exceeded quota: pod-quota, requested: pods=1, used: pods=10, limited: pods=10"))
+	}
+	return c.Client.Create(ctx, obj, opts...)
+}
+
+func TestCreateOrRestartPodWithQuotaBackoff(t *testing.T) {
+	backoff := PodQuotaBackoff{Initial: time.Millisecond, Max: time.Millisecond, Timeout: time.Minute}
+	for _, tc := range []struct {
+		name     string
+		failures int
+		backoff  PodQuotaBackoff
+		err      error
+	}{{
+		name: "quota available",
+	}, {
+		name:     "quota freed while waiting",
+		failures: 3,
+		backoff:  backoff,
+	}, {
+		name:     "no waiting for quota",
+		failures: 1,
+		err:      errors.New(`unable to create pod: still out of quota after 0s: pods "pod" is forbidden: exceeded quota: pod-quota, requested: pods=1, used: pods=10, limited: pods=10`),
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			client := &quotaExceededClient{Client: fakectrlruntimeclient.NewClientBuilder().Build(), failures: tc.failures}
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "pod"},
+				Spec:       corev1.PodSpec{Containers: []corev1.Container{{Image: "image"}}},
+			}
+			_, blocked, err := CreateOrRestartPodWithQuotaBackoff(context.Background(), client, pod, tc.backoff)
+			testhelper.Diff(t, "error", err, tc.err, testhelper.EquateErrorMessage)
+			if tc.failures == 0 && blocked != 0 {
+				t.Errorf("expected the pod not to be blocked on quota, got %s", blocked)
+			}
+			if tc.failures != 0 && tc.err == nil && blocked == 0 {
+				t.Error("expected the pod to be blocked on quota")
+			}
+		})
+	}
+}
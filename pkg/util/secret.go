@@ -5,14 +5,19 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/sirupsen/logrus"
 
 	coreapi "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
 	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/client-go/util/retry"
 	utilpointer "k8s.io/utils/pointer"
 	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/openshift/ci-tools/pkg/api"
 )
 
 // SecretFromDir creates a secret with the contents of files in a directory.
@@ -69,3 +74,89 @@ func UpsertImmutableSecret(ctx context.Context, client ctrlruntimeclient.Client,
 	// Recreate counts as "Update"
 	return false, client.Create(ctx, secret)
 }
+
+// AddScrubOwner claims an existing secret for a job, so that it is not
+// scrubbed until the job exits.  It is used when the secret may have been
+// created by another job in the namespace.
+func AddScrubOwner(ctx context.Context, client ctrlruntimeclient.Client, key ctrlruntimeclient.ObjectKey, owner string) error {
+	label := api.ScrubOwnerLabel(owner)
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		secret := &coreapi.Secret{}
+		if err := client.Get(ctx, key, secret); err != nil {
+			return err
+		}
+		if secret.Labels[label] == "true" {
+			return nil
+		}
+		if secret.Labels == nil {
+			secret.Labels = map[string]string{}
+		}
+		secret.Labels[label] = "true"
+		return client.Update(ctx, secret)
+	})
+}
+
+// ScrubSecrets removes the secrets in a namespace which are marked to be
+// scrubbed and claimed by the owner.  The claim of the owner is released and
+// secrets which are still claimed by other jobs, or which a multi-stage test
+// still holds until its post phase has completed, are kept.  The data of
+// mutable secrets is zeroed before they are deleted, so it does not outlive
+// the secret if the deletion is not completed, e.g. because of a finalizer.
+func ScrubSecrets(ctx context.Context, client ctrlruntimeclient.Client, namespace, owner string) error {
+	label := api.ScrubOwnerLabel(owner)
+	list := &coreapi.SecretList{}
+	if err := client.List(ctx, list, ctrlruntimeclient.InNamespace(namespace), ctrlruntimeclient.MatchingLabels{api.ScrubOnExitLabel: "true", label: "true"}); err != nil {
+		return fmt.Errorf("failed to list secrets: %w", err)
+	}
+	var errs []error
+	for i := range list.Items {
+		key := ctrlruntimeclient.ObjectKeyFromObject(&list.Items[i])
+		var inUse bool
+		secret := &coreapi.Secret{}
+		if err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+			if err := client.Get(ctx, key, secret); err != nil {
+				return err
+			}
+			delete(secret.Labels, label)
+			inUse = secretInUse(secret)
+			if !inUse && (secret.Immutable == nil || !*secret.Immutable) {
+				for k := range secret.Data {
+					secret.Data[k] = []byte{}
+				}
+				secret.StringData = nil
+			}
+			return client.Update(ctx, secret)
+		}); err != nil {
+			if !kerrors.IsNotFound(err) {
+				errs = append(errs, fmt.Errorf("failed to release secret %s: %w", key.Name, err))
+			}
+			continue
+		}
+		if inUse {
+			logrus.Debugf("Not scrubbing secret %s, which is still in use", key.Name)
+			continue
+		}
+		if err := client.Delete(ctx, secret); err != nil && !kerrors.IsNotFound(err) {
+			errs = append(errs, fmt.Errorf("failed to delete secret %s: %w", key.Name, err))
+			continue
+		}
+		logrus.Debugf("Scrubbed secret %s", key.Name)
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// secretInUse determines whether a secret is still claimed by a job or held
+// by a multi-stage test.
+func secretInUse(secret *coreapi.Secret) bool {
+	for k := range secret.Labels {
+		if strings.HasPrefix(k, api.ScrubOwnerLabelPrefix) {
+			return true
+		}
+	}
+	for _, finalizer := range secret.Finalizers {
+		if strings.HasSuffix(finalizer, api.PostPhaseFinalizerSuffix) {
+			return true
+		}
+	}
+	return false
+}
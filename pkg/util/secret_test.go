@@ -153,3 +153,86 @@ func TestUpsertImmutableSecret(t *testing.T) {
 		})
 	}
 }
+
+func TestScrubSecrets(t *testing.T) {
+	owner := api.ScrubOwnerLabel("job-1")
+	labels := map[string]string{api.ScrubOnExitLabel: "true", owner: "true"}
+	yes := true
+	client := fakeclient.NewClientBuilder().WithObjects(
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "shared-dir", Labels: labels},
+			Data:       map[string][]byte{"kubeadmin-password": []byte("hunter2")},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "finalized", Labels: labels, Finalizers: []string{"example.com/finalizer"}},
+			Data:       map[string][]byte{"token": []byte("secret")},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "profile", Labels: labels},
+			Data:       map[string][]byte{"credentials": []byte("secret")},
+			Immutable:  &yes,
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "claimed-by-other-job", Labels: map[string]string{api.ScrubOnExitLabel: "true", owner: "true", api.ScrubOwnerLabel("job-2"): "true"}},
+			Data:       map[string][]byte{"token": []byte("in use")},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "held-by-other-test", Labels: labels, Finalizers: []string{"e2e" + api.PostPhaseFinalizerSuffix}},
+			Data:       map[string][]byte{"token": []byte("in use")},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "other-job", Labels: map[string]string{api.ScrubOnExitLabel: "true", api.ScrubOwnerLabel("job-2"): "true"}},
+			Data:       map[string][]byte{"token": []byte("kept")},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "unrelated"},
+			Data:       map[string][]byte{"token": []byte("kept")},
+		},
+	).Build()
+	ctx := context.Background()
+	if err := ScrubSecrets(ctx, client, "ns", "job-1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	list := &corev1.SecretList{}
+	if err := client.List(ctx, list, ctrlruntimeclient.InNamespace("ns")); err != nil {
+		t.Fatal(err)
+	}
+	remaining := map[string]map[string][]byte{}
+	for _, s := range list.Items {
+		remaining[s.Name] = s.Data
+		if s.Labels[owner] != "" {
+			t.Errorf("secret %s is still claimed by the job", s.Name)
+		}
+	}
+	expected := map[string]map[string][]byte{
+		"finalized":            {"token": {}},
+		"claimed-by-other-job": {"token": []byte("in use")},
+		"held-by-other-test":   {"token": []byte("in use")},
+		"other-job":            {"token": []byte("kept")},
+		"unrelated":            {"token": []byte("kept")},
+	}
+	if diff := cmp.Diff(expected, remaining); diff != "" {
+		t.Errorf("unexpected secrets: %s", diff)
+	}
+}
+
+func TestAddScrubOwner(t *testing.T) {
+	client := fakeclient.NewClientBuilder().WithObjects(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "credentials", Labels: map[string]string{api.ScrubOnExitLabel: "true", api.ScrubOwnerLabel("job-1"): "true"}},
+	}).Build()
+	ctx := context.Background()
+	key := ctrlruntimeclient.ObjectKey{Namespace: "ns", Name: "credentials"}
+	for _, owner := range []string{"job-2", "job-2"} {
+		if err := AddScrubOwner(ctx, client, key, owner); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	secret := &corev1.Secret{}
+	if err := client.Get(ctx, key, secret); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{api.ScrubOnExitLabel: "true", api.ScrubOwnerLabel("job-1"): "true", api.ScrubOwnerLabel("job-2"): "true"}
+	if diff := cmp.Diff(expected, secret.Labels); diff != "" {
+		t.Errorf("unexpected labels: %s", diff)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"regexp"
 	"strings"
+	"time"
 
 	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/apimachinery/pkg/util/sets"
@@ -20,6 +21,8 @@ type Validator struct {
 	validClusterProfiles clusterProfileMap
 	// hasTrapCache avoids redundant regexp searches on step commands.
 	hasTrapCache map[string]bool
+	// shellChecker analyzes the commands of steps, if set.
+	shellChecker *ShellChecker
 }
 
 // NewValidator creates an object that optimizes bulk validations.
@@ -192,6 +195,9 @@ func (v *Validator) validateConfiguration(ctx *configContext, config *api.Releas
 	// this validation brings together a large amount of data from separate
 	// parts of the configuration, so it's written as a standalone method
 	validationErrors = append(validationErrors, validateTestStepDependencies(config)...)
+	validationErrors = append(validationErrors, validateFeatureSets(config)...)
+	validationErrors = append(validationErrors, validateClusterReuse(config)...)
+	validationErrors = append(validationErrors, validateAggregateTests(config)...)
 	var lines []string
 	for _, err := range validationErrors {
 		if err == nil {
@@ -518,6 +524,46 @@ func validateReleaseBuildConfiguration(input *api.ReleaseBuildConfiguration, org
 	}
 
 	validationErrors = append(validationErrors, validateResources("resources", input.Resources)...)
+	validationErrors = append(validationErrors, validateQuarantine("quarantine", input.Quarantine)...)
+	validationErrors = append(validationErrors, validateSharedLeases("shared_leases", input.SharedLeases)...)
+	return validationErrors
+}
+
+func validateSharedLeases(fieldRoot string, leases []api.SharedLease) []error {
+	var validationErrors []error
+	seen := sets.New[string]()
+	for i, l := range leases {
+		field := fmt.Sprintf("%s[%d]", fieldRoot, i)
+		if l.ResourceType == "" {
+			validationErrors = append(validationErrors, fmt.Errorf("%s.resource_type: value required but not provided", field))
+		} else if seen.Has(l.ResourceType) {
+			validationErrors = append(validationErrors, fmt.Errorf("%s.resource_type: duplicated resource type %q", field, l.ResourceType))
+		} else {
+			seen.Insert(l.ResourceType)
+		}
+	}
+	return validationErrors
+}
+
+func validateQuarantine(fieldRoot string, quarantine []api.QuarantinedTestCase) []error {
+	var validationErrors []error
+	seen := sets.New[string]()
+	for i, q := range quarantine {
+		field := fmt.Sprintf("%s[%d]", fieldRoot, i)
+		if q.Name == "" {
+			validationErrors = append(validationErrors, fmt.Errorf("%s.name: value required but not provided", field))
+		} else if seen.Has(q.Name) {
+			validationErrors = append(validationErrors, fmt.Errorf("%s.name: duplicated test case %q", field, q.Name))
+		} else {
+			seen.Insert(q.Name)
+		}
+		if q.Reason == "" {
+			validationErrors = append(validationErrors, fmt.Errorf("%s.reason: value required but not provided", field))
+		}
+		if _, err := time.Parse(api.QuarantineExpiryFormat, q.Expires); err != nil {
+			validationErrors = append(validationErrors, fmt.Errorf("%s.expires: must be a date in YYYY-MM-DD format, got %q", field, q.Expires))
+		}
+	}
 	return validationErrors
 }
 
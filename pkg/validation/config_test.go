@@ -1251,6 +1251,50 @@ func TestValidateReleaseBuildConfiguration(t *testing.T) {
 				PromotionConfiguration: &api.PromotionConfiguration{AdditionalImages: map[string]string{"name": "src"}},
 			},
 		},
+		{
+			name: "valid quarantine",
+			input: &api.ReleaseBuildConfiguration{
+				Tests: []api.TestStepConfiguration{{As: "unit"}},
+				Quarantine: []api.QuarantinedTestCase{
+					{Name: "flaky", Reason: "https://issues.redhat.com/browse/TRT-1", Expires: "2024-01-31"},
+				},
+			},
+		},
+		{
+			name: "invalid quarantine",
+			input: &api.ReleaseBuildConfiguration{
+				Tests: []api.TestStepConfiguration{{As: "unit"}},
+				Quarantine: []api.QuarantinedTestCase{
+					{Name: "flaky", Reason: "flakes", Expires: "2024-01-31"},
+					{Name: "flaky", Expires: "next week"},
+					{Reason: "flakes", Expires: "2024-01-31"},
+				},
+			},
+			expected: []error{
+				errors.New(`quarantine[1].name: duplicated test case "flaky"`),
+				errors.New("quarantine[1].reason: value required but not provided"),
+				errors.New(`quarantine[1].expires: must be a date in YYYY-MM-DD format, got "next week"`),
+				errors.New("quarantine[2].name: value required but not provided"),
+			},
+		},
+		{
+			name: "valid shared leases",
+			input: &api.ReleaseBuildConfiguration{
+				Tests:        []api.TestStepConfiguration{{As: "unit"}},
+				SharedLeases: []api.SharedLease{{ResourceType: "aws-quota-slice"}, {ResourceType: "gcp-quota-slice", Count: 2}},
+			},
+		},
+		{
+			name: "invalid shared leases",
+			input: &api.ReleaseBuildConfiguration{
+				Tests:        []api.TestStepConfiguration{{As: "unit"}},
+				SharedLeases: []api.SharedLease{{ResourceType: "aws-quota-slice"}, {ResourceType: "aws-quota-slice", Count: 2}, {}},
+			},
+			expected: []error{
+				errors.New(`shared_leases[1].resource_type: duplicated resource type "aws-quota-slice"`),
+				errors.New("shared_leases[2].resource_type: value required but not provided"),
+			},
+		},
 	}
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
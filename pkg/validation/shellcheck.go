@@ -0,0 +1,111 @@
+package validation
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/openshift/ci-tools/pkg/api"
+)
+
+// shellCheckPrefix is prepended to the commands of steps when they are
+// analyzed, matching the prefix added when they are executed, so that the
+// failures caused by `set -eu` are found.
+const shellCheckPrefix = "#!/bin/bash\nset -eu\n"
+
+// ShellCheckSeverities are the severities of the findings of shellcheck, from
+// the most to the least severe.
+var ShellCheckSeverities = sets.New[string]("error", "warning", "info", "style")
+
+// ShellChecker analyzes the commands of steps with shellcheck.  Findings can
+// be suppressed in the commands with the directives of shellcheck, e.g.
+// `# shellcheck disable=SC2086`.  A checker can be shared by validators used
+// concurrently.
+type ShellChecker struct {
+	// Path is the path of the shellcheck binary.
+	Path string
+	// Severity is the minimum severity of the findings reported.
+	Severity string
+
+	lock sync.Mutex
+	// cache avoids analyzing the commands of steps shared by many tests
+	// more than once.
+	cache map[string][]shellCheckFinding
+}
+
+// NewShellChecker creates a checker which executes the given binary.
+func NewShellChecker(path, severity string) (*ShellChecker, error) {
+	if !ShellCheckSeverities.Has(severity) {
+		return nil, fmt.Errorf("invalid shellcheck severity %q, must be one of %v", severity, sets.List(ShellCheckSeverities))
+	}
+	return &ShellChecker{Path: path, Severity: severity, cache: map[string][]shellCheckFinding{}}, nil
+}
+
+// shellCheckFinding is a finding in the `json1` output format of shellcheck.
+type shellCheckFinding struct {
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Level   string `json:"level"`
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// check analyzes a script, caching the result.
+func (c *ShellChecker) check(script string) ([]shellCheckFinding, error) {
+	c.lock.Lock()
+	findings, ok := c.cache[script]
+	c.lock.Unlock()
+	if ok {
+		return findings, nil
+	}
+	cmd := exec.Command(c.Path, "--shell=bash", "--format=json1", "--severity="+c.Severity, "-")
+	cmd.Stdin = strings.NewReader(script)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		// shellcheck exits with 1 when it reports findings
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) || exitErr.ExitCode() != 1 {
+			return nil, fmt.Errorf("failed to run shellcheck: %w: %s", err, stderr.String())
+		}
+	}
+	var output struct {
+		Comments []shellCheckFinding `json:"comments"`
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &output); err != nil {
+		return nil, fmt.Errorf("failed to parse the output of shellcheck: %w", err)
+	}
+	c.lock.Lock()
+	c.cache[script] = output.Comments
+	c.lock.Unlock()
+	return output.Comments, nil
+}
+
+// checkStep analyzes the commands of a step.  Commands run as scripts are not
+// analyzed, as they may not be shell scripts.
+func (c *ShellChecker) checkStep(step api.LiteralTestStep) []error {
+	if step.Commands == "" || (step.RunAsScript != nil && *step.RunAsScript) {
+		return nil
+	}
+	findings, err := c.check(shellCheckPrefix + step.Commands)
+	if err != nil {
+		return []error{fmt.Errorf("test `%s`: %w", step.As, err)}
+	}
+	prefixLines := strings.Count(shellCheckPrefix, "\n")
+	var ret []error
+	for _, f := range findings {
+		ret = append(ret, fmt.Errorf("test `%s` has `commands` with shellcheck %s SC%d at line %d, column %d: %s", step.As, f.Level, f.Code, f.Line-prefixLines, f.Column, f.Message))
+	}
+	return ret
+}
+
+// UseShellChecker enables the analysis of the commands of steps.
+func (v *Validator) UseShellChecker(c *ShellChecker) {
+	v.shellChecker = c
+}
@@ -0,0 +1,80 @@
+package validation
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	utilpointer "k8s.io/utils/pointer"
+
+	"github.com/openshift/ci-tools/pkg/api"
+	"github.com/openshift/ci-tools/pkg/testhelper"
+)
+
+// fakeShellCheck writes a script which records its invocations and reports a
+// finding for scripts which reference $unset.
+func fakeShellCheck(t *testing.T) (string, string) {
+	dir := t.TempDir()
+	calls := filepath.Join(dir, "calls")
+	path := filepath.Join(dir, "shellcheck")
+	script := `#!/bin/bash
+echo "$*" >> ` + calls + `
+if grep -q unset; then
+	echo '{"comments":[{"file":"-","line":4,"column":6,"level":"warning","code":2154,"message":"unset is referenced but not assigned."}]}'
+	exit 1
+fi
+echo '{"comments":[]}'
+`
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return path, calls
+}
+
+func TestShellChecker(t *testing.T) {
+	path, calls := fakeShellCheck(t)
+	checker, err := NewShellChecker(path, "warning")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		name     string
+		step     api.LiteralTestStep
+		expected []error
+	}{{
+		name: "clean commands",
+		step: api.LiteralTestStep{As: "clean", Commands: "echo ${SHARED_DIR}"},
+	}, {
+		name: "finding",
+		step: api.LiteralTestStep{As: "unbound", Commands: "echo start\necho ${unset}"},
+		expected: []error{
+			errors.New("test `unbound` has `commands` with shellcheck warning SC2154 at line 2, column 6: unset is referenced but not assigned."),
+		},
+	}, {
+		name:     "cached finding",
+		step:     api.LiteralTestStep{As: "other", Commands: "echo start\necho ${unset}"},
+		expected: []error{errors.New("test `other` has `commands` with shellcheck warning SC2154 at line 2, column 6: unset is referenced but not assigned.")},
+	}, {
+		name: "commands run as scripts are not analyzed",
+		step: api.LiteralTestStep{As: "script", Commands: "#!/usr/bin/env python3\nprint(unset)", RunAsScript: utilpointer.Bool(true)},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.expected, checker.checkStep(tc.step), testhelper.EquateErrorMessage); diff != "" {
+				t.Errorf("unexpected errors: %s", diff)
+			}
+		})
+	}
+	raw, err := os.ReadFile(calls)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testhelper.Diff(t, "invocations", string(raw), "--shell=bash --format=json1 --severity=warning -\n--shell=bash --format=json1 --severity=warning -\n")
+}
+
+func TestNewShellCheckerInvalidSeverity(t *testing.T) {
+	if _, err := NewShellChecker("shellcheck", "fatal"); err == nil {
+		t.Error("expected an error for an invalid severity")
+	}
+}
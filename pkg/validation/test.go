@@ -2,8 +2,12 @@ package validation
 
 import (
 	"fmt"
+	"net/url"
+	"path"
 	"path/filepath"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -12,6 +16,7 @@ import (
 	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/util/jsonpath"
 
 	"github.com/openshift/ci-tools/pkg/api"
 	"github.com/openshift/ci-tools/pkg/util"
@@ -146,7 +151,11 @@ func (v *Validator) validateTestStepConfiguration(
 		} else if len(validation.IsDNS1123Subdomain(test.As)) != 0 {
 			validationErrors = append(validationErrors, fmt.Errorf("%s.as: '%s' is not a valid Kubernetes object name", fieldRootN, test.As))
 		}
-		if hasCommands, hasSteps, hasLiteral := len(test.Commands) != 0, test.MultiStageTestConfiguration != nil, test.MultiStageTestConfigurationLiteral != nil; !hasCommands && !hasSteps && !hasLiteral {
+		if hasCommands, hasSteps, hasLiteral := len(test.Commands) != 0, test.MultiStageTestConfiguration != nil, test.MultiStageTestConfigurationLiteral != nil; test.AggregateTestConfiguration != nil {
+			if hasCommands || hasSteps || hasLiteral {
+				validationErrors = append(validationErrors, fmt.Errorf("%s: `aggregate` cannot be set together with `commands`, `steps`, or `literal_steps`", fieldRootN))
+			}
+		} else if !hasCommands && !hasSteps && !hasLiteral {
 			validationErrors = append(validationErrors, fmt.Errorf("%s: either `commands`, `steps`, or `literal_steps` should be set", fieldRootN))
 		} else if hasCommands && (hasSteps || hasLiteral) || (hasSteps && hasLiteral) {
 			validationErrors = append(validationErrors, fmt.Errorf("%s: `commands`, `steps`, and `literal_steps` are mutually exclusive", fieldRootN))
@@ -210,6 +219,7 @@ func (v *Validator) validateTestStepConfiguration(
 		if test.Timeout != nil && test.Timeout.Duration > maxJobTimeout {
 			validationErrors = append(validationErrors, fmt.Errorf("%s: job timeout is limited to %s", fieldRootN, maxJobTimeout))
 		}
+		validationErrors = append(validationErrors, validateTestMetadata(fieldRootN, test)...)
 
 		// Validate Secret/Secrets
 		if test.Secret != nil && test.Secrets != nil {
@@ -254,6 +264,47 @@ func (v *Validator) validateTestStepConfiguration(
 	return validationErrors
 }
 
+// reservedMetadataDomains are the domains of the labels and annotations set by
+// ci-operator and Prow, which tests cannot set.
+var reservedMetadataDomains = []string{"ci.openshift.io", "ci-operator.openshift.io", "prow.k8s.io"}
+
+// validateTestMetadata validates the labels and annotations added to the
+// objects created for a test.
+func validateTestMetadata(fieldRoot string, test api.TestStepConfiguration) []error {
+	if len(test.Labels) == 0 && len(test.Annotations) == 0 {
+		return nil
+	}
+	if test.MultiStageTestConfiguration == nil && test.MultiStageTestConfigurationLiteral == nil {
+		return []error{fmt.Errorf("%s: `labels` and `annotations` can only be set for multi-stage tests", fieldRoot)}
+	}
+	var ret []error
+	validateKey := func(field, key string) {
+		if errs := validation.IsQualifiedName(key); len(errs) != 0 {
+			ret = append(ret, fmt.Errorf("%s.%s: invalid key %q: %s", fieldRoot, field, key, strings.Join(errs, ", ")))
+			return
+		}
+		domain, _, found := strings.Cut(key, "/")
+		if !found {
+			return
+		}
+		for _, reserved := range reservedMetadataDomains {
+			if domain == reserved || strings.HasSuffix(domain, "."+reserved) {
+				ret = append(ret, fmt.Errorf("%s.%s: key %q uses the reserved domain %s", fieldRoot, field, key, reserved))
+			}
+		}
+	}
+	for _, key := range sets.List(sets.KeySet(test.Labels)) {
+		validateKey("labels", key)
+		if errs := validation.IsValidLabelValue(test.Labels[key]); len(errs) != 0 {
+			ret = append(ret, fmt.Errorf("%s.labels.%s: invalid value %q: %s", fieldRoot, key, test.Labels[key], strings.Join(errs, ", ")))
+		}
+	}
+	for _, key := range sets.List(sets.KeySet(test.Annotations)) {
+		validateKey("annotations", key)
+	}
+	return ret
+}
+
 // validateTestStepDependencies ensures that users have referenced valid dependencies
 func validateTestStepDependencies(config *api.ReleaseBuildConfiguration) []error {
 	hasOverride := func(test *api.TestStepConfiguration, dep string) bool {
@@ -408,6 +459,7 @@ func validateTestStepDependencies(config *api.ReleaseBuildConfiguration) []error
 				{field: "pre", list: test.MultiStageTestConfigurationLiteral.Pre},
 				{field: "test", list: test.MultiStageTestConfigurationLiteral.Test},
 				{field: "post", list: test.MultiStageTestConfigurationLiteral.Post},
+				{field: "rollbacks", list: test.MultiStageTestConfigurationLiteral.Rollbacks},
 			} {
 				errs = append(errs, processLiteralSteps(item.list, testIdx, "literal_steps", item.field, claimRelease)...)
 			}
@@ -550,6 +602,9 @@ func (v *Validator) validateTestConfigurationType(
 		typeCount++
 		validationErrors = append(validationErrors, v.validateClusterProfile(fieldRoot, testConfig.ClusterProfile, metadata)...)
 	}
+	if test.AggregateTestConfiguration != nil {
+		typeCount++
+	}
 	var claimRelease *api.ClaimRelease
 	if test.ClusterClaim != nil {
 		claimRelease = test.ClusterClaim.ClaimRelease(test.As)
@@ -565,9 +620,35 @@ func (v *Validator) validateTestConfigurationType(
 		}
 		context := newContext(fieldPath(fieldRoot), testConfig.Environment, releases, inputImagesSeen)
 		validationErrors = append(validationErrors, validateLeases(context.addField("leases"), testConfig.Leases)...)
+		validationErrors = append(validationErrors, validateDisruptionMonitor(context.addField("disruption_monitor"), testConfig.DisruptionMonitor)...)
+		validationErrors = append(validationErrors, validateClusterHealthGate(context.addField("cluster_health_gate"), testConfig.ClusterHealthGate)...)
+		validationErrors = append(validationErrors, validateEnvironmentFromFile(context.addField("env_from_file"), testConfig.Environment, testConfig.EnvironmentFromFile)...)
+		validationErrors = append(validationErrors, validateStepReleases(context.addField("step_releases"), testConfig.StepReleases, release != nil, nil)...)
 		validationErrors = append(validationErrors, v.validateTestSteps(context.addField("pre"), testStagePre, testConfig.Pre, claimRelease)...)
 		validationErrors = append(validationErrors, v.validateTestSteps(context.addField("test"), testStageTest, testConfig.Test, claimRelease)...)
 		validationErrors = append(validationErrors, v.validateTestSteps(context.addField("post"), testStagePost, testConfig.Post, claimRelease)...)
+		if p := testConfig.ContainerOnly; p != nil && *p {
+			var observers bool
+			if o := testConfig.Observers; o != nil {
+				observers = len(o.Enable) != 0
+			}
+			var steps []api.LiteralTestStep
+			for _, s := range append(append(append([]api.TestStep{}, testConfig.Pre...), testConfig.Test...), testConfig.Post...) {
+				if s.LiteralTestStep != nil {
+					steps = append(steps, *s.LiteralTestStep)
+				}
+			}
+			validationErrors = append(validationErrors, validateContainerOnly(context, testConfig.ClusterProfile, test.ClusterClaim, observers, testConfig.Leases, steps)...)
+		}
+		literal := func(steps []api.TestStep) (ret []api.LiteralTestStep) {
+			for _, s := range steps {
+				if s.LiteralTestStep != nil {
+					ret = append(ret, *s.LiteralTestStep)
+				}
+			}
+			return ret
+		}
+		validationErrors = append(validationErrors, validateSharedFilesWiring(context, literal(testConfig.Pre), literal(testConfig.Test), literal(testConfig.Post))...)
 	}
 	if testConfig := test.MultiStageTestConfigurationLiteral; testConfig != nil {
 		typeCount++
@@ -577,6 +658,8 @@ func (v *Validator) validateTestConfigurationType(
 			validationErrors = append(validationErrors, v.validateClusterProfile(fieldRoot, testConfig.ClusterProfile, metadata)...)
 		}
 		validationErrors = append(validationErrors, validateLeases(context.addField("leases"), testConfig.Leases)...)
+		validationErrors = append(validationErrors, validateDisruptionMonitor(context.addField("disruption_monitor"), testConfig.DisruptionMonitor)...)
+		validationErrors = append(validationErrors, validateClusterHealthGate(context.addField("cluster_health_gate"), testConfig.ClusterHealthGate)...)
 		for i, s := range testConfig.Pre {
 			validationErrors = append(validationErrors, v.validateLiteralTestStep(context.addField("pre").addIndex(i), testStagePre, s, claimRelease)...)
 		}
@@ -586,6 +669,35 @@ func (v *Validator) validateTestConfigurationType(
 		for i, s := range testConfig.Post {
 			validationErrors = append(validationErrors, v.validateLiteralTestStep(context.addField("post").addIndex(i), testStagePost, s, claimRelease)...)
 		}
+		if p := testConfig.ContainerOnly; p != nil && *p {
+			steps := append(append(append([]api.LiteralTestStep{}, testConfig.Pre...), testConfig.Test...), testConfig.Post...)
+			validationErrors = append(validationErrors, validateContainerOnly(context, testConfig.ClusterProfile, test.ClusterClaim, len(testConfig.Observers) != 0, testConfig.Leases, steps)...)
+		}
+		validationErrors = append(validationErrors, v.validateRollbacks(context, testConfig, claimRelease)...)
+		validationErrors = append(validationErrors, validateGangs(context.addField("pre"), testConfig.Pre)...)
+		validationErrors = append(validationErrors, validateGangs(context.addField("test"), testConfig.Test)...)
+		validationErrors = append(validationErrors, validateGangs(context.addField("post"), testConfig.Post)...)
+		validationErrors = append(validationErrors, validateSharedFilesWiring(context, testConfig.Pre, testConfig.Test, testConfig.Post)...)
+		validationErrors = append(validationErrors, validateStepReleases(context.addField("step_releases"), testConfig.StepReleases, release != nil, stepNames(testConfig.Pre, testConfig.Test, testConfig.Post))...)
+		validationErrors = append(validationErrors, validateClusterCapabilities(context, test.Cluster, testConfig.Pre, testConfig.Test, testConfig.Post)...)
+		if testConfig.ClusterProfile == "" {
+			for _, phase := range []struct {
+				field string
+				steps []api.LiteralTestStep
+			}{{field: "pre", steps: testConfig.Pre}, {field: "test", steps: testConfig.Test}, {field: "post", steps: testConfig.Post}} {
+				for i, step := range phase.steps {
+					if step.RunOnEphemeralCluster != nil && *step.RunOnEphemeralCluster {
+						validationErrors = append(validationErrors, context.addField(phase.field).addIndex(i).errorf("`run_on_ephemeral_cluster` requires a `cluster_profile`"))
+					}
+					if step.Manifests != "" {
+						validationErrors = append(validationErrors, context.addField(phase.field).addIndex(i).errorf("`manifests` requires a `cluster_profile`"))
+					}
+					if len(step.WaitFor) != 0 {
+						validationErrors = append(validationErrors, context.addField(phase.field).addIndex(i).errorf("`wait_for` requires a `cluster_profile`"))
+					}
+				}
+			}
+		}
 	}
 	if typeCount == 0 {
 		validationErrors = append(validationErrors, fmt.Errorf("%s has no type, you may want to specify 'container' for a container based test", fieldRoot))
@@ -625,22 +737,35 @@ func validateTestStep(context *context, step api.TestStep) (ret []error) {
 		ret = append(ret, context.errorf("a reference, chain, or literal test step is required"))
 		return
 	}
+	if step.LiteralTestStep != nil && step.Alias != "" {
+		ret = append(ret, context.addField("alias").errorf("can only be set for a `ref` or `chain`, set `as` instead"))
+	}
+	// an alias replaces the name of a reference and prefixes those of the
+	// steps of a chain
 	if step.Reference != nil {
+		name := *step.Reference
+		if step.Alias != "" {
+			name = step.Alias
+		}
 		if len(*step.Reference) == 0 {
 			ret = append(ret, context.addField("ref").errorf("length cannot be 0"))
-		} else if context.namesSeen.Has(*step.Reference) {
-			ret = append(ret, context.addField("ref").errorf("duplicated name %q", *step.Reference))
+		} else if context.namesSeen.Has(name) {
+			ret = append(ret, context.addField("ref").errorf("duplicated name %q", name))
 		} else {
-			context.namesSeen.Insert(*step.Reference)
+			context.namesSeen.Insert(name)
 		}
 	}
 	if step.Chain != nil {
+		name := *step.Chain
+		if step.Alias != "" {
+			name = step.Alias
+		}
 		if len(*step.Chain) == 0 {
 			ret = append(ret, context.addField("chain").errorf("length cannot be 0"))
-		} else if context.namesSeen.Has(*step.Chain) {
-			ret = append(ret, context.addField("chain").errorf("duplicated name %q", *step.Chain))
+		} else if context.namesSeen.Has(name) {
+			ret = append(ret, context.addField("chain").errorf("duplicated name %q", name))
 		} else {
-			context.namesSeen.Insert(*step.Chain)
+			context.namesSeen.Insert(name)
 		}
 	}
 	return
@@ -660,18 +785,81 @@ func (v *Validator) validateLiteralTestStep(context *context, stage testStage, s
 	if t, ok := step.FromImageTag(); ok {
 		fromImageTag = &t
 	}
-	ret = append(ret, validateFromAndFromImage(context, step.From, step.FromImage, fromImageTag, claimRelease)...)
-	if len(step.Commands) == 0 {
-		ret = append(ret, context.errorf("`commands` is required"))
+	if step.Upgrade != nil {
+		ret = append(ret, validateUpgradeStep(context, step)...)
 	} else {
-		ret = append(ret, v.validateCommands(step)...)
+		ret = append(ret, validateFromAndFromImage(context, step.From, step.FromImage, fromImageTag, claimRelease)...)
+		switch {
+		case len(step.Commands) != 0 && len(step.Command) != 0:
+			ret = append(ret, context.errorf("`commands` and `command` are mutually exclusive"))
+		case len(step.Command) != 0:
+			if step.RunAsScript != nil {
+				ret = append(ret, context.errorf("`run_as_script` cannot be set with `command`"))
+			}
+			if step.Toolkit != nil {
+				ret = append(ret, context.errorf("`toolkit` cannot be set with `command`"))
+			}
+		case len(step.Args) != 0:
+			ret = append(ret, context.errorf("`args` requires `command`"))
+		case len(step.Commands) == 0:
+			ret = append(ret, context.errorf("either `commands` or `command` is required"))
+		default:
+			ret = append(ret, v.validateCommands(step)...)
+		}
 	}
 
 	if step.BestEffort != nil && *step.BestEffort && step.Timeout == nil {
 		ret = append(ret, fmt.Errorf("test %s contains best_effort without timeout", step.As))
 	}
 
+	if step.ShardCount != nil && *step.ShardCount < 1 {
+		ret = append(ret, context.errorf("`shard_count` must be a positive number"))
+	}
+	if step.Rollback != "" && step.ShardCount != nil {
+		ret = append(ret, context.errorf("`rollback` cannot be set for sharded steps"))
+	}
+	if step.Gang != "" {
+		ret = append(ret, validateGangStep(context, step)...)
+	}
+	ret = append(ret, validateSharedFiles(context.addField("requires_shared_files"), step.RequiresSharedFiles)...)
+	ret = append(ret, validateSharedFiles(context.addField("provides_shared_files"), step.ProvidesSharedFiles)...)
+
 	ret = append(ret, validateResourceRequirements(string(context.field)+".resources", step.Resources)...)
+	if step.Burst != nil && *step.Burst {
+		ret = append(ret, validateBurst(context.addField("resources"), step.Resources)...)
+	}
+	ret = append(ret, validateSidecars(context.addField("sidecars"), step.Sidecars)...)
+	ret = append(ret, validateSysctls(context.addField("sysctls"), step.HostNetwork != nil && *step.HostNetwork, step.Sysctls)...)
+	if step.RunOnEphemeralCluster != nil && *step.RunOnEphemeralCluster {
+		ret = append(ret, validateEphemeralClusterStep(context, step)...)
+	}
+	if group := step.ConcurrencyGroup; group != "" {
+		for _, msg := range validation.IsDNS1123Label(api.ConcurrencyGroupLeaseType(group)) {
+			ret = append(ret, context.addField("concurrency_group").errorf("invalid value %q: %s", group, msg))
+		}
+	}
+	if mutex := step.Mutex; mutex != "" {
+		for _, msg := range validation.IsDNS1123Label(api.MutexLeaseType(mutex)) {
+			ret = append(ret, context.addField("mutex").errorf("invalid value %q: %s", mutex, msg))
+		}
+	}
+	ret = append(ret, validateScratchDir(context, step.Workdir, step.ScratchSize)...)
+	ret = append(ret, validateNodeCapabilities(context.addField("node_capabilities"), step.NodeCapabilities)...)
+	if step.Job != nil {
+		ret = append(ret, validateJobStep(context, step)...)
+	}
+	if step.PreviousJobArtifacts != nil {
+		ret = append(ret, validatePreviousJobFiles(context.addField("previous_job_artifacts").addField("files"), step.PreviousJobArtifacts.Files)...)
+	}
+	if step.StdinFrom != nil {
+		ret = append(ret, validateStdinFrom(context.addField("stdin_from"), step)...)
+	}
+	if step.Manifests != "" {
+		if _, err := api.DecodeManifests(step.Manifests); err != nil {
+			ret = append(ret, context.addField("manifests").errorf("invalid manifests: %v", err))
+		}
+	}
+	ret = append(ret, validateWaitFor(context.addField("wait_for"), step.WaitFor)...)
 	ret = append(ret, validateCredentials(string(context.field), step.Credentials)...)
 	if context.env != nil {
 		if err := validateParameters(context, step.Environment); err != nil {
@@ -679,12 +867,677 @@ func (v *Validator) validateLiteralTestStep(context *context, stage testStage, s
 		}
 	}
 	ret = append(ret, validateDependencies(string(context.field), step.Dependencies)...)
+	ret = append(ret, validateParameterReferences(context, step)...)
 	ret = append(ret, validateLeases(context.addField("leases"), step.Leases)...)
 	switch stage {
 	case testStagePre, testStageTest:
 		if step.OptionalOnSuccess != nil {
 			ret = append(ret, context.errorf("`optional_on_success` is only allowed for Post steps"))
 		}
+		if step.Gather != nil {
+			ret = append(ret, context.errorf("`gather` is only allowed for Post steps"))
+		}
+	}
+	if step.Gather != nil && *step.Gather && step.OptionalOnSuccess != nil && *step.OptionalOnSuccess {
+		ret = append(ret, context.errorf("`gather` steps cannot be `optional_on_success`"))
+	}
+	return ret
+}
+
+// validateRollbacks validates the steps executed when the steps which
+// reference them fail.  Their names share the namespace of the other steps.
+func (v *Validator) validateRollbacks(context *context, config *api.MultiStageTestConfigurationLiteral, claimRelease *api.ClaimRelease) (ret []error) {
+	rollbacks := sets.New[string]()
+	for i, step := range config.Rollbacks {
+		context := context.addField("rollbacks").addIndex(i)
+		ret = append(ret, v.validateLiteralTestStep(context, testStageTest, step, claimRelease)...)
+		if step.Rollback != "" {
+			ret = append(ret, context.errorf("rollback steps cannot declare a `rollback`"))
+		}
+		rollbacks.Insert(step.As)
+	}
+	for _, phase := range []struct {
+		field string
+		steps []api.LiteralTestStep
+	}{{field: "pre", steps: config.Pre}, {field: "test", steps: config.Test}, {field: "post", steps: config.Post}} {
+		for i, step := range phase.steps {
+			if step.Rollback != "" && !rollbacks.Has(step.Rollback) {
+				ret = append(ret, context.addField(phase.field).addIndex(i).addField("rollback").errorf("unknown rollback step %q", step.Rollback))
+			}
+		}
+	}
+	return ret
+}
+
+// validateGangStep validates a step which is scheduled together with the
+// other steps of its gang, which run in parallel.
+func validateGangStep(context *context, step api.LiteralTestStep) (ret []error) {
+	for _, msg := range validation.IsDNS1123Label(step.Gang) {
+		ret = append(ret, context.addField("gang").errorf("invalid value %q: %s", step.Gang, msg))
+	}
+	for _, field := range []struct {
+		name string
+		set  bool
+	}{
+		{name: "shard_count", set: step.ShardCount != nil},
+		{name: "run_on_ephemeral_cluster", set: step.RunOnEphemeralCluster != nil && *step.RunOnEphemeralCluster},
+		{name: "upgrade", set: step.Upgrade != nil},
+	} {
+		if field.set {
+			ret = append(ret, context.errorf("`%s` cannot be set for steps of a gang", field.name))
+		}
+	}
+	return ret
+}
+
+// validateGangs verifies that the steps of each gang of a phase are
+// consecutive, as they are executed as a single group.
+func validateGangs(context *context, steps []api.LiteralTestStep) (ret []error) {
+	members := map[string]int{}
+	closed := sets.New[string]()
+	for i, step := range steps {
+		if i != 0 && steps[i-1].Gang != "" && steps[i-1].Gang != step.Gang {
+			closed.Insert(steps[i-1].Gang)
+		}
+		if step.Gang == "" {
+			continue
+		}
+		if closed.Has(step.Gang) {
+			ret = append(ret, context.addIndex(i).addField("gang").errorf("steps of gang %q must be consecutive", step.Gang))
+		}
+		members[step.Gang]++
+	}
+	for _, gang := range sets.List(sets.KeySet(members)) {
+		if members[gang] < 2 {
+			ret = append(ret, context.errorf("gang %q must have at least two steps", gang))
+		}
+	}
+	return ret
+}
+
+// validateEphemeralClusterStep validates a step executed on the cluster under
+// test, where only the image, commands and shared directory of the step are
+// available.
+func validateEphemeralClusterStep(context *context, step api.LiteralTestStep) (ret []error) {
+	for _, field := range []struct {
+		name string
+		set  bool
+	}{
+		{name: "cli", set: step.Cli != ""},
+		{name: "credentials", set: len(step.Credentials) != 0},
+		{name: "sidecars", set: len(step.Sidecars) != 0},
+		{name: "host_network", set: step.HostNetwork != nil && *step.HostNetwork},
+		{name: "toolkit", set: step.Toolkit != nil && *step.Toolkit},
+		{name: "sysctls", set: len(step.Sysctls) != 0},
+		{name: "no_kubeconfig", set: step.NoKubeconfig != nil && *step.NoKubeconfig},
+		{name: "previous_job_artifacts.files", set: step.PreviousJobArtifacts != nil && len(step.PreviousJobArtifacts.Files) != 0},
+		{name: "stdin_from", set: step.StdinFrom != nil},
+		{name: "split_logs", set: step.SplitLogs != nil && *step.SplitLogs},
+	} {
+		if field.set {
+			ret = append(ret, context.errorf("`%s` cannot be set for steps which run on the ephemeral cluster", field.name))
+		}
+	}
+	return ret
+}
+
+// validateJobStep validates a step which runs as a Job, which restarts its
+// pod on the build farm independently of the other steps.
+func validateJobStep(context *context, step api.LiteralTestStep) (ret []error) {
+	job := context.addField("job")
+	if step.Job.BackoffLimit < 0 {
+		ret = append(ret, job.addField("backoff_limit").errorf("must not be negative, got %d", step.Job.BackoffLimit))
+	}
+	if ttl := step.Job.TTLSecondsAfterFinished; ttl != nil && *ttl < 0 {
+		ret = append(ret, job.addField("ttl_seconds_after_finished").errorf("must not be negative, got %d", *ttl))
+	}
+	for _, field := range []struct {
+		name string
+		set  bool
+	}{
+		{name: "run_on_ephemeral_cluster", set: step.RunOnEphemeralCluster != nil && *step.RunOnEphemeralCluster},
+		{name: "gang", set: step.Gang != ""},
+		{name: "upgrade", set: step.Upgrade != nil},
+	} {
+		if field.set {
+			ret = append(ret, context.errorf("`%s` cannot be set for steps which run as a job", field.name))
+		}
+	}
+	return ret
+}
+
+// validateBurst validates the resources of a step which bursts to its limits,
+// which must be set and not lower than its requests.
+func validateBurst(context *context, resources api.ResourceRequirements) (ret []error) {
+	var limited bool
+	for _, name := range []string{"cpu", "memory"} {
+		limit, ok := resources.Limits[name]
+		if !ok {
+			continue
+		}
+		limited = true
+		request, ok := resources.Requests[name]
+		if !ok {
+			continue
+		}
+		l, err := resource.ParseQuantity(limit)
+		if err != nil {
+			continue
+		}
+		r, err := resource.ParseQuantity(request)
+		if err != nil {
+			continue
+		}
+		if l.Cmp(r) < 0 {
+			ret = append(ret, context.addField("limits").addField(name).errorf("cannot be lower than the request (%s) for steps with `burst`, got %s", request, limit))
+		}
+	}
+	if !limited {
+		ret = append(ret, context.errorf("`burst` requires a `cpu` or `memory` limit"))
+	}
+	return ret
+}
+
+// validateNodeCapabilities validates the names of the node capabilities a
+// step requires.
+func validateNodeCapabilities(context *context, capabilities []string) (ret []error) {
+	seen := sets.New[string]()
+	for i, capability := range capabilities {
+		for _, msg := range validation.IsDNS1123Label(capability) {
+			ret = append(ret, context.addIndex(i).errorf("invalid value %q: %s", capability, msg))
+		}
+		if seen.Has(capability) {
+			ret = append(ret, context.addIndex(i).errorf("duplicate capability %q", capability))
+		}
+		seen.Insert(capability)
+	}
+	return ret
+}
+
+// validateScratchDir validates the writable scratch directory of a step.
+func validateScratchDir(context *context, workdir, size string) (ret []error) {
+	if workdir != "" && len(api.ParameterReferences(workdir)) == 0 {
+		if !filepath.IsAbs(workdir) {
+			ret = append(ret, context.addField("workdir").errorf("must be an absolute path, got %s", workdir))
+		} else if clean := filepath.Clean(workdir); clean == "/" {
+			ret = append(ret, context.addField("workdir").errorf("cannot be the root directory"))
+		} else if clean != workdir {
+			ret = append(ret, context.addField("workdir").errorf("must be a clean path, got %s instead of %s", workdir, clean))
+		}
+	}
+	if size != "" {
+		if q, err := resource.ParseQuantity(size); err != nil {
+			ret = append(ret, context.addField("scratch_size").errorf("invalid quantity %q: %v", size, err))
+		} else if q.Sign() <= 0 {
+			ret = append(ret, context.addField("scratch_size").errorf("must be positive, got %s", size))
+		}
+	}
+	return ret
+}
+
+// validateUpgradeStep validates a built-in upgrade step, which is not executed
+// in a container.
+func validateUpgradeStep(context *context, step api.LiteralTestStep) (ret []error) {
+	upgrade := step.Upgrade
+	if upgrade.Release == "" {
+		ret = append(ret, context.addField("upgrade").errorf("`release` is required"))
+	} else if context.releases != nil && !context.releases.Has(upgrade.Release) {
+		ret = append(ret, context.addField("upgrade").addField("release").errorf("unknown release %q", upgrade.Release))
+	}
+	for _, field := range []struct {
+		name string
+		set  bool
+	}{
+		{name: "from", set: step.From != ""},
+		{name: "from_image", set: step.FromImage != nil},
+		{name: "commands", set: step.Commands != ""},
+		{name: "command", set: len(step.Command) != 0},
+		{name: "args", set: len(step.Args) != 0},
+		{name: "toolkit", set: step.Toolkit != nil},
+		{name: "shard_count", set: step.ShardCount != nil},
+		{name: "sidecars", set: len(step.Sidecars) != 0},
+		{name: "run_on_ephemeral_cluster", set: step.RunOnEphemeralCluster != nil && *step.RunOnEphemeralCluster},
+		{name: "fail_on_restart", set: step.FailOnRestart != nil},
+		{name: "workdir", set: step.Workdir != ""},
+		{name: "scratch_size", set: step.ScratchSize != ""},
+		{name: "stdin_from", set: step.StdinFrom != nil},
+		{name: "split_logs", set: step.SplitLogs != nil},
+		{name: "manifests", set: step.Manifests != ""},
+	} {
+		if field.set {
+			ret = append(ret, context.errorf("`%s` cannot be set for upgrade steps", field.name))
+		}
+	}
+	return ret
+}
+
+// validateSidecars validates the helper containers of a step.  Their names
+// are prefixed in the pod, so they cannot conflict with other containers.
+func validateSidecars(context *context, sidecars []api.StepSidecar) (ret []error) {
+	seen := sets.New[string]()
+	for i, sidecar := range sidecars {
+		context := context.addIndex(i)
+		if sidecar.Name == "" {
+			ret = append(ret, context.errorf("`name` is required"))
+		} else if seen.Has(sidecar.Name) {
+			ret = append(ret, context.errorf("duplicated name %q", sidecar.Name))
+		} else {
+			for _, msg := range validation.IsDNS1123Label(api.SidecarContainerPrefix + sidecar.Name) {
+				ret = append(ret, context.errorf("invalid name %q: %s", sidecar.Name, msg))
+			}
+		}
+		seen.Insert(sidecar.Name)
+		if sidecar.From == "" {
+			ret = append(ret, context.errorf("`from` is required"))
+		}
+		if sidecar.Commands == "" {
+			ret = append(ret, context.errorf("`commands` is required"))
+		}
+		ret = append(ret, validateResourceRequirements(string(context.field)+".resources", sidecar.Resources)...)
+	}
+	return ret
+}
+
+// sysctlPattern matches the names of sysctls, as accepted by the API server.
+var sysctlPattern = regexp.MustCompile(`^([a-z0-9]([-_a-z0-9]*[a-z0-9])?[\./])*[a-z0-9]([-_a-z0-9]*[a-z0-9])?$`)
+
+// safeSysctls are the sysctls which the kubelet allows in any cluster.
+var safeSysctls = sets.New[string](
+	"kernel.shm_rmid_forced",
+	"net.ipv4.ip_local_port_range",
+	"net.ipv4.ip_local_reserved_ports",
+	"net.ipv4.ip_unprivileged_port_start",
+	"net.ipv4.ping_group_range",
+	"net.ipv4.tcp_fin_timeout",
+	"net.ipv4.tcp_keepalive_intvl",
+	"net.ipv4.tcp_keepalive_probes",
+	"net.ipv4.tcp_keepalive_time",
+	"net.ipv4.tcp_syncookies",
+)
+
+// validateSysctls validates the sysctls of a step.  Network sysctls are
+// namespaced, so they cannot be set when using the network of the node.
+func validateSysctls(context *context, hostNetwork bool, sysctls []api.StepSysctl) (ret []error) {
+	seen := sets.New[string]()
+	for i, sysctl := range sysctls {
+		context := context.addIndex(i)
+		switch {
+		case sysctl.Name == "":
+			ret = append(ret, context.errorf("`name` is required"))
+		case !sysctlPattern.MatchString(sysctl.Name):
+			ret = append(ret, context.errorf("invalid name %q", sysctl.Name))
+		case seen.Has(sysctl.Name):
+			ret = append(ret, context.errorf("duplicated name %q", sysctl.Name))
+		case hostNetwork && strings.HasPrefix(sysctl.Name, "net."):
+			ret = append(ret, context.errorf("sysctl %q cannot be set for steps using the host network", sysctl.Name))
+		}
+		seen.Insert(sysctl.Name)
+	}
+	return ret
+}
+
+// validateClusterCapabilities validates that the cluster a test runs on
+// supports the pod settings of its steps.
+func validateClusterCapabilities(context *context, cluster api.Cluster, pre, test, post []api.LiteralTestStep) (ret []error) {
+	for _, phase := range []struct {
+		field string
+		steps []api.LiteralTestStep
+	}{{field: "pre", steps: pre}, {field: "test", steps: test}, {field: "post", steps: post}} {
+		for i, step := range phase.steps {
+			context := context.addField(phase.field).addIndex(i)
+			if step.HostNetwork != nil && *step.HostNetwork {
+				if msg := missingCapability(cluster, api.ClusterCapabilityHostNetwork, "using the host network"); msg != "" {
+					ret = append(ret, context.addField("host_network").errorf("%s", msg))
+				}
+			}
+			for j, sysctl := range step.Sysctls {
+				if safeSysctls.Has(sysctl.Name) {
+					continue
+				}
+				if msg := missingCapability(cluster, api.ClusterCapabilityUnsafeSysctls, fmt.Sprintf("unsafe sysctl %q", sysctl.Name)); msg != "" {
+					ret = append(ret, context.addField("sysctls").addIndex(j).errorf("%s", msg))
+				}
+			}
+		}
+	}
+	return ret
+}
+
+// missingCapability describes why a feature cannot be used on a cluster, if
+// the cluster does not support it.
+func missingCapability(cluster api.Cluster, capability api.ClusterCapability, feature string) string {
+	if cluster.HasCapability(capability) {
+		return ""
+	}
+	clusters := strings.Join(api.ClustersWithCapability(capability), ", ")
+	if cluster == "" {
+		return fmt.Sprintf("%s requires `cluster` to be set to one of: %s", feature, clusters)
+	}
+	return fmt.Sprintf("%s is not supported on cluster %s, use one of: %s", feature, cluster, clusters)
+}
+
+// validateSharedFiles validates the names of files in the shared directory,
+// which is a flat directory.
+func validateSharedFiles(context *context, files []string) (ret []error) {
+	seen := sets.New[string]()
+	for i, file := range files {
+		switch {
+		case file == "":
+			ret = append(ret, context.addIndex(i).errorf("file name must not be empty"))
+		case strings.Contains(file, "/"):
+			ret = append(ret, context.addIndex(i).errorf("file name %q must not contain a path separator", file))
+		case seen.Has(file):
+			ret = append(ret, context.addIndex(i).errorf("duplicated file name %q", file))
+		}
+		seen.Insert(file)
+	}
+	return ret
+}
+
+// validateStdinFrom validates the source of the standard input of the
+// commands of a step, which is either a parameter of the step or a file in the
+// shared directory.
+func validateStdinFrom(context *context, step api.LiteralTestStep) (ret []error) {
+	stdin := step.StdinFrom
+	switch {
+	case stdin.Parameter == "" && stdin.SharedFile == "":
+		return []error{context.errorf("one of `parameter` or `shared_file` must be set")}
+	case stdin.Parameter != "" && stdin.SharedFile != "":
+		return []error{context.errorf("only one of `parameter` or `shared_file` may be set")}
+	case stdin.Parameter != "":
+		for _, param := range step.Environment {
+			if param.Name == stdin.Parameter {
+				return nil
+			}
+		}
+		return []error{context.addField("parameter").errorf("%q is not a parameter of the step", stdin.Parameter)}
+	}
+	if strings.Contains(stdin.SharedFile, "/") {
+		return []error{context.addField("shared_file").errorf("file name %q must not contain a path separator", stdin.SharedFile)}
+	}
+	return nil
+}
+
+// validateWaitFor validates the conditions a step waits for before it starts.
+func validateWaitFor(context *context, conditions []api.WaitCondition) (ret []error) {
+	for i, condition := range conditions {
+		context := context.addIndex(i)
+		for _, field := range []struct{ name, value string }{
+			{name: "api_version", value: condition.APIVersion},
+			{name: "kind", value: condition.Kind},
+			{name: "name", value: condition.Name},
+			{name: "jsonpath", value: condition.JSONPath},
+		} {
+			if field.value == "" {
+				ret = append(ret, context.errorf("`%s` is required", field.name))
+			}
+		}
+		if condition.JSONPath != "" {
+			if err := jsonpath.New("").Parse(condition.JSONPath); err != nil {
+				ret = append(ret, context.addField("jsonpath").errorf("invalid template: %v", err))
+			}
+		}
+		if condition.Timeout != nil && condition.Timeout.Duration <= 0 {
+			ret = append(ret, context.addField("timeout").errorf("must be positive"))
+		}
+	}
+	return ret
+}
+
+// validatePreviousJobFiles validates the paths of the files fetched from the
+// artifacts of the previous run of a job, which are relative to its artifact
+// directory.
+func validatePreviousJobFiles(context *context, files []string) (ret []error) {
+	seen := sets.New[string]()
+	for i, file := range files {
+		switch {
+		case file == "":
+			ret = append(ret, context.addIndex(i).errorf("file name must not be empty"))
+		case path.IsAbs(file) || path.Clean(file) != file || file == ".." || strings.HasPrefix(file, "../"):
+			ret = append(ret, context.addIndex(i).errorf("file name %q must be a clean path relative to the artifact directory", file))
+		case seen.Has(file):
+			ret = append(ret, context.addIndex(i).errorf("duplicated file name %q", file))
+		}
+		seen.Insert(file)
+	}
+	return ret
+}
+
+// validateSharedFilesWiring verifies that each file required by a step is
+// provided by a step which runs before it.
+func validateSharedFilesWiring(context *context, pre, test, post []api.LiteralTestStep) (ret []error) {
+	phases := []struct {
+		field string
+		steps []api.LiteralTestStep
+	}{{field: "pre", steps: pre}, {field: "test", steps: test}, {field: "post", steps: post}}
+	providers := map[string]string{}
+	for _, phase := range phases {
+		for _, step := range phase.steps {
+			for _, file := range step.ProvidesSharedFiles {
+				if _, ok := providers[file]; !ok {
+					providers[file] = step.As
+				}
+			}
+		}
+	}
+	provided := sets.New[string]()
+	for _, phase := range phases {
+		for i, step := range phase.steps {
+			for j, file := range step.RequiresSharedFiles {
+				if provided.Has(file) {
+					continue
+				}
+				context := context.addField(phase.field).addIndex(i).addField("requires_shared_files").addIndex(j)
+				if provider, ok := providers[file]; ok && provider != step.As {
+					ret = append(ret, context.errorf("file %q is provided by step %s, which runs after this step", file, provider))
+				} else {
+					ret = append(ret, context.errorf("file %q is not provided by any previous step", file))
+				}
+			}
+			provided.Insert(step.ProvidesSharedFiles...)
+		}
+	}
+	return ret
+}
+
+// minorVersionPattern matches the major and minor components of a version.
+var minorVersionPattern = regexp.MustCompile(`^v?(\d+)\.(\d+)`)
+
+// parseMinorVersion returns the major and minor components of a version.
+func parseMinorVersion(version string) (major, minor int, ok bool) {
+	m := minorVersionPattern.FindStringSubmatch(version)
+	if m == nil {
+		return 0, 0, false
+	}
+	major, _ = strconv.Atoi(m[1])
+	minor, _ = strconv.Atoi(m[2])
+	return major, minor, true
+}
+
+// latestReleaseVersion returns the version of the `latest` release of a
+// configuration, when it can be determined without resolving the release.
+func latestReleaseVersion(config *api.ReleaseBuildConfiguration) string {
+	if release, ok := config.Releases[api.LatestReleaseName]; ok {
+		switch {
+		case release.Candidate != nil:
+			return release.Candidate.Version
+		case release.Release != nil:
+			return release.Release.Version
+		case release.Prerelease != nil:
+			return release.Prerelease.VersionBounds.Lower
+		case release.Integration != nil:
+			return release.Integration.Name
+		}
+		return ""
+	}
+	if config.ReleaseTagConfiguration != nil {
+		return config.ReleaseTagConfiguration.Name
+	}
+	return ""
+}
+
+// validateFeatureSets verifies that the feature sets of tests exist in the
+// version of the `latest` release and that they are only declared once, in
+// place of the parameter of the steps.  Tests with feature sets which do not
+// allow upgrades cannot have upgrade steps.
+func validateFeatureSets(config *api.ReleaseBuildConfiguration) (ret []error) {
+	version := latestReleaseVersion(config)
+	major, minor, hasVersion := parseMinorVersion(version)
+	for i, test := range config.Tests {
+		var featureSet api.FeatureSet
+		var env api.TestEnvironment
+		var field string
+		var steps []api.LiteralTestStep
+		if c := test.MultiStageTestConfiguration; c != nil {
+			featureSet, env, field = c.FeatureSet, c.Environment, "steps"
+		} else if c := test.MultiStageTestConfigurationLiteral; c != nil {
+			featureSet, env, field = c.FeatureSet, c.Environment, "literal_steps"
+			steps = append(append(append(steps, c.Pre...), c.Test...), c.Post...)
+		}
+		if featureSet == "" {
+			continue
+		}
+		fieldRoot := fmt.Sprintf("tests[%d].%s.feature_set", i, field)
+		minimum, ok := api.FeatureSetMinimumVersions[featureSet]
+		if !ok {
+			var valid []string
+			for f := range api.FeatureSetMinimumVersions {
+				valid = append(valid, string(f))
+			}
+			sort.Strings(valid)
+			ret = append(ret, fmt.Errorf("%s: unknown feature set %q, must be one of: %s", fieldRoot, featureSet, strings.Join(valid, ", ")))
+			continue
+		}
+		if minMajor, minMinor, _ := parseMinorVersion(minimum); hasVersion && (major < minMajor || major == minMajor && minor < minMinor) {
+			ret = append(ret, fmt.Errorf("%s: feature set %s requires OpenShift %s or later, but the latest release is %s", fieldRoot, featureSet, minimum, version))
+		}
+		if _, ok := env[api.FeatureSetEnv]; ok {
+			ret = append(ret, fmt.Errorf("%s: cannot be set together with the %s parameter in `env`", fieldRoot, api.FeatureSetEnv))
+		}
+		if !featureSet.AllowsUpgrades() {
+			for _, step := range steps {
+				if step.Upgrade != nil {
+					ret = append(ret, fmt.Errorf("%s: feature set %s does not allow upgrades, but step %s upgrades the cluster", fieldRoot, featureSet, step.As))
+				}
+			}
+		}
+	}
+	return ret
+}
+
+// validateClusterReuse verifies that tests which reuse the cluster of another
+// test reference a multi-stage test of the configuration which provisions a
+// cluster with a profile, and do not provision one themselves.
+func validateClusterReuse(config *api.ReleaseBuildConfiguration) (ret []error) {
+	profiles := map[string]api.ClusterProfile{}
+	reused := map[string]string{}
+	for _, test := range config.Tests {
+		if c := test.MultiStageTestConfiguration; c != nil {
+			profiles[test.As], reused[test.As] = c.ClusterProfile, c.ReuseClusterFrom
+		} else if c := test.MultiStageTestConfigurationLiteral; c != nil {
+			profiles[test.As], reused[test.As] = c.ClusterProfile, c.ReuseClusterFrom
+		}
+	}
+	for i, test := range config.Tests {
+		var field string
+		if test.MultiStageTestConfiguration != nil {
+			field = "steps"
+		} else if test.MultiStageTestConfigurationLiteral != nil {
+			field = "literal_steps"
+		}
+		from := reused[test.As]
+		if from == "" {
+			continue
+		}
+		fieldRoot := fmt.Sprintf("tests[%d].%s.reuse_cluster_from", i, field)
+		profile, ok := profiles[from]
+		switch {
+		case from == test.As:
+			ret = append(ret, fmt.Errorf("%s: a test cannot reuse its own cluster", fieldRoot))
+		case !ok:
+			ret = append(ret, fmt.Errorf("%s: %q is not the name of a multi-stage test", fieldRoot, from))
+		case reused[from] != "":
+			ret = append(ret, fmt.Errorf("%s: test %s reuses the cluster of test %s itself", fieldRoot, from, reused[from]))
+		case profile == "":
+			ret = append(ret, fmt.Errorf("%s: test %s does not provision a cluster with a `cluster_profile`", fieldRoot, from))
+		}
+		if profiles[test.As] != "" {
+			ret = append(ret, fmt.Errorf("%s: cannot be set together with `cluster_profile`", fieldRoot))
+		}
+		if test.ClusterClaim != nil {
+			ret = append(ret, fmt.Errorf("%s: cannot be set together with `cluster_claim`", fieldRoot))
+		}
+	}
+	return ret
+}
+
+// validateAggregateTests verifies that aggregate tests gate on other tests
+// of the configuration, which are not aggregate tests themselves, and that
+// the number of tests which must pass can be reached.
+func validateAggregateTests(config *api.ReleaseBuildConfiguration) (ret []error) {
+	aggregate := map[string]bool{}
+	for _, test := range config.Tests {
+		aggregate[test.As] = test.AggregateTestConfiguration != nil
+	}
+	for i, test := range config.Tests {
+		c := test.AggregateTestConfiguration
+		if c == nil {
+			continue
+		}
+		fieldRoot := fmt.Sprintf("tests[%d].aggregate", i)
+		if len(c.Tests) == 0 {
+			ret = append(ret, fmt.Errorf("%s.tests: at least one test must be aggregated", fieldRoot))
+		}
+		seen := sets.New[string]()
+		for j, name := range c.Tests {
+			isAggregate, ok := aggregate[name]
+			switch {
+			case name == test.As:
+				ret = append(ret, fmt.Errorf("%s.tests[%d]: a test cannot aggregate itself", fieldRoot, j))
+			case !ok:
+				ret = append(ret, fmt.Errorf("%s.tests[%d]: %q is not the name of a test", fieldRoot, j, name))
+			case isAggregate:
+				ret = append(ret, fmt.Errorf("%s.tests[%d]: test %s is an aggregate test itself", fieldRoot, j, name))
+			case seen.Has(name):
+				ret = append(ret, fmt.Errorf("%s.tests[%d]: duplicate test %s", fieldRoot, j, name))
+			}
+			seen.Insert(name)
+		}
+		if m := c.MinimumPassing; m != nil && (*m < 1 || *m > len(c.Tests)) {
+			ret = append(ret, fmt.Errorf("%s.minimum_passing: must be between 1 and the number of tests (%d), got %d", fieldRoot, len(c.Tests), *m))
+		}
+	}
+	return ret
+}
+
+// validateStepReleases verifies that the releases used by steps in place of
+// `latest` are configured, either in `releases` or, for `latest` and
+// `initial`, with a `tag_specification`.  The steps are only verified to
+// exist when the names of the steps of the test are known.
+func validateStepReleases(context *context, stepReleases api.StepReleases, hasTagSpec bool, steps sets.Set[string]) (ret []error) {
+	for _, step := range sets.List(sets.KeySet(stepReleases)) {
+		release := stepReleases[step]
+		if steps != nil && !steps.Has(step) {
+			ret = append(ret, context.errorf("%q is not the name of a step of the test", step))
+		}
+		switch {
+		case release == "":
+			ret = append(ret, context.addField(step).errorf("the release cannot be empty"))
+		case hasTagSpec && (release == api.LatestReleaseName || release == api.InitialReleaseName):
+		case context.releases != nil && !context.releases.Has(release):
+			ret = append(ret, context.addField(step).errorf("unknown release %q", release))
+		}
+	}
+	return ret
+}
+
+// stepNames returns the names of the steps of the phases of a test.
+func stepNames(phases ...[]api.LiteralTestStep) sets.Set[string] {
+	ret := sets.New[string]()
+	for _, steps := range phases {
+		for _, step := range steps {
+			ret.Insert(step.As)
+		}
 	}
 	return ret
 }
@@ -745,6 +1598,12 @@ func (v *Validator) validateCommands(test api.LiteralTestStep) []error {
 	if v.commandHasTrap(test.Commands) && test.GracePeriod == nil {
 		validationErrors = append(validationErrors, fmt.Errorf("test `%s` has `commands` containing `trap` command, but test step is missing grace_period", test.As))
 	}
+	if size := len(test.Commands); size > api.MaxStepCommandsSize {
+		validationErrors = append(validationErrors, fmt.Errorf("test `%s` has `commands` of %d bytes, exceeding the maximum size of %d bytes: move the bulk of the script into the step image", test.As, size, api.MaxStepCommandsSize))
+	}
+	if v.shellChecker != nil {
+		validationErrors = append(validationErrors, v.shellChecker.checkStep(test)...)
+	}
 
 	return validationErrors
 }
@@ -752,19 +1611,30 @@ func (v *Validator) validateCommands(test api.LiteralTestStep) []error {
 func validateCredentials(fieldRoot string, credentials []api.CredentialReference) []error {
 	var errs []error
 	for i, credential := range credentials {
-		if credential.Name == "" {
+		switch {
+		case credential.Name == "" && credential.Selector == nil:
 			errs = append(errs, fmt.Errorf("%s.credentials[%d].name cannot be empty", fieldRoot, i))
+		case credential.Name != "" && credential.Selector != nil:
+			errs = append(errs, fmt.Errorf("%s.credentials[%d]: name and selector are mutually exclusive", fieldRoot, i))
+		case credential.Selector != nil && len(credential.Selector.MatchLabels) == 0:
+			errs = append(errs, fmt.Errorf("%s.credentials[%d].selector.matchLabels cannot be empty", fieldRoot, i))
 		}
 		if credential.Namespace == "" {
 			errs = append(errs, fmt.Errorf("%s.credentials[%d].namespace cannot be empty", fieldRoot, i))
 		}
 		if credential.MountPath == "" {
 			errs = append(errs, fmt.Errorf("%s.credentials[%d].mountPath cannot be empty", fieldRoot, i))
+		} else if len(api.ParameterReferences(credential.MountPath)) != 0 {
+			// the path is only known once the parameters are expanded
+			continue
 		} else if !filepath.IsAbs(credential.MountPath) {
 			errs = append(errs, fmt.Errorf("%s.credentials[%d].mountPath is not absolute: %s", fieldRoot, i, credential.MountPath))
 		}
 		for j, other := range credentials[i+1:] {
 			index := i + j + 1
+			if len(api.ParameterReferences(other.MountPath)) != 0 {
+				continue
+			}
 			if credential.MountPath == other.MountPath {
 				errs = append(errs, fmt.Errorf("%s.credentials[%d] and credentials[%d] mount to the same location (%s)", fieldRoot, i, index, credential.MountPath))
 				continue
@@ -822,6 +1692,30 @@ func validateParameters(context *context, params []api.StepParameter) error {
 	return nil
 }
 
+// validateParameterReferences validates that the `$(NAME)` references in the
+// fields of a step which support expansion name parameters of the step.
+func validateParameterReferences(context *context, step api.LiteralTestStep) (ret []error) {
+	params := sets.New[string]()
+	for _, param := range step.Environment {
+		params.Insert(param.Name)
+	}
+	check := func(field fieldPath, value string) {
+		for _, name := range api.ParameterReferences(value) {
+			if !params.Has(name) {
+				ret = append(ret, field.errorf("references %q, which is not a parameter of the step", name))
+			}
+		}
+	}
+	for i, credential := range step.Credentials {
+		check(context.field.addField("credentials").addIndex(i).addField("mount_path"), credential.MountPath)
+	}
+	for i, dependency := range step.Dependencies {
+		check(context.field.addField("dependencies").addIndex(i).addField("env"), dependency.Env)
+	}
+	check(context.field.addField("workdir"), step.Workdir)
+	return ret
+}
+
 func validateDependencies(fieldRoot string, dependencies []api.StepDependency) []error {
 	var errs []error
 	env := sets.New[string]()
@@ -853,6 +1747,98 @@ func validateDNSConfig(fieldRoot string, dnsConfig []api.StepDNSConfig) (ret []e
 	return errs
 }
 
+func validateDisruptionMonitor(context *context, monitor *api.DisruptionMonitor) (ret []error) {
+	if monitor == nil {
+		return nil
+	}
+	if len(monitor.Targets) == 0 {
+		ret = append(ret, context.errorf("'targets' cannot be empty"))
+	}
+	if monitor.Interval != nil && monitor.Interval.Duration <= 0 {
+		ret = append(ret, context.errorf("'interval' must be positive"))
+	}
+	seen := sets.New[string]()
+	for i, t := range monitor.Targets {
+		context := context.addField("targets").addIndex(i)
+		if t.Name == "" {
+			ret = append(ret, context.errorf("'name' cannot be empty"))
+		} else if seen.Has(t.Name) {
+			ret = append(ret, context.errorf("duplicate name: %s", t.Name))
+		} else {
+			seen.Insert(t.Name)
+		}
+		if u, err := url.Parse(t.URL); err != nil {
+			ret = append(ret, context.errorf("invalid 'url': %v", err))
+		} else if u.Scheme != "http" && u.Scheme != "https" {
+			ret = append(ret, context.errorf("'url' must be an HTTP(S) URL, got %q", t.URL))
+		}
+		if t.MaxDisruption != nil && t.MaxDisruption.Duration < 0 {
+			ret = append(ret, context.errorf("'max_disruption' cannot be negative"))
+		}
+	}
+	return
+}
+
+func validateClusterHealthGate(context *context, gate *api.ClusterHealthGate) (ret []error) {
+	if gate == nil {
+		return nil
+	}
+	valid := sets.New[api.ClusterHealthCheck](api.ClusterHealthChecks...)
+	for i, c := range gate.Checks {
+		if !valid.Has(c) {
+			ret = append(ret, context.addField("checks").addIndex(i).errorf("invalid check %q, valid values are: %v", c, sets.List(valid)))
+		}
+	}
+	if gate.Timeout != nil && gate.Timeout.Duration <= 0 {
+		ret = append(ret, context.errorf("'timeout' must be positive"))
+	}
+	return
+}
+
+// validateContainerOnly verifies that a test which does not interact with a
+// cluster does not request anything which would provide one.
+func validateContainerOnly(context *context, profile api.ClusterProfile, claim *api.ClusterClaim, observers bool, leases []api.StepLease, steps []api.LiteralTestStep) (ret []error) {
+	if profile != "" {
+		ret = append(ret, context.errorf("'cluster_profile' cannot be set in a 'container_only' test"))
+	}
+	if claim != nil {
+		ret = append(ret, context.errorf("'cluster_claim' cannot be set in a 'container_only' test"))
+	}
+	if observers {
+		ret = append(ret, context.errorf("'observers' cannot be set in a 'container_only' test"))
+	}
+	if len(leases) != 0 {
+		ret = append(ret, context.errorf("'leases' cannot be set in a 'container_only' test"))
+	}
+	for _, step := range steps {
+		if len(step.Leases) != 0 {
+			ret = append(ret, context.errorf("step %s: 'leases' cannot be set in a 'container_only' test", step.As))
+		}
+		if step.Cli != "" {
+			ret = append(ret, context.errorf("step %s: 'cli' cannot be set in a 'container_only' test", step.As))
+		}
+	}
+	return
+}
+
+// validateEnvironmentFromFile validates the files from which parameters are
+// read, which must be relative paths and cannot set parameters also set in
+// `env`.
+func validateEnvironmentFromFile(context *context, env, fromFile api.TestEnvironment) (ret []error) {
+	for _, name := range sets.List(sets.KeySet(fromFile)) {
+		path := fromFile[name]
+		if path == "" {
+			ret = append(ret, context.errorf("%s: path cannot be empty", name))
+		} else if filepath.IsAbs(path) {
+			ret = append(ret, context.errorf("%s: path must be relative to the configuration file, got %s", name, path))
+		}
+		if _, ok := env[name]; ok {
+			ret = append(ret, context.errorf("%s: parameter is also set in `env`", name))
+		}
+	}
+	return
+}
+
 func validateLeases(context *context, leases []api.StepLease) (ret []error) {
 	for i, l := range leases {
 		if l.ResourceType == "" {
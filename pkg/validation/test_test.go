@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -12,6 +13,7 @@ import (
 	"k8s.io/apimachinery/pkg/util/sets"
 	prowv1 "k8s.io/test-infra/prow/apis/prowjobs/v1"
 	"k8s.io/utils/diff"
+	utilpointer "k8s.io/utils/pointer"
 
 	"github.com/openshift/ci-tools/pkg/api"
 	"github.com/openshift/ci-tools/pkg/testhelper"
@@ -918,7 +920,128 @@ func TestValidateTestSteps(t *testing.T) {
 				From:      "from",
 				Resources: resources},
 		}},
-		errs: []error{errors.New("test[0]: `commands` is required")},
+		errs: []error{errors.New("test[0]: either `commands` or `command` is required")},
+	}, {
+		name: "burst",
+		steps: []api.TestStep{{
+			LiteralTestStep: &api.LiteralTestStep{
+				As:       "burst",
+				From:     "from",
+				Commands: "commands",
+				Resources: api.ResourceRequirements{
+					Requests: api.ResourceList{"cpu": "100m", "memory": "1Gi"},
+					Limits:   api.ResourceList{"cpu": "4", "memory": "4Gi"},
+				},
+				Burst: &yes},
+		}},
+	}, {
+		name: "burst without limits",
+		steps: []api.TestStep{{
+			LiteralTestStep: &api.LiteralTestStep{
+				As:        "burst",
+				From:      "from",
+				Commands:  "commands",
+				Resources: api.ResourceRequirements{Requests: api.ResourceList{"cpu": "1"}},
+				Burst:     &yes},
+		}},
+		errs: []error{errors.New("test[0].resources: `burst` requires a `cpu` or `memory` limit")},
+	}, {
+		name: "burst with limits lower than requests",
+		steps: []api.TestStep{{
+			LiteralTestStep: &api.LiteralTestStep{
+				As:       "burst",
+				From:     "from",
+				Commands: "commands",
+				Resources: api.ResourceRequirements{
+					Requests: api.ResourceList{"cpu": "2", "memory": "1Gi"},
+					Limits:   api.ResourceList{"cpu": "1", "memory": "2Gi"},
+				},
+				Burst: &yes},
+		}},
+		errs: []error{errors.New("test[0].resources.limits.cpu: cannot be lower than the request (2) for steps with `burst`, got 1")},
+	}, {
+		name: "exec form",
+		steps: []api.TestStep{{
+			LiteralTestStep: &api.LiteralTestStep{
+				As:        "exec",
+				From:      "from",
+				Command:   []string{"/usr/bin/tool"},
+				Args:      []string{"--verbose"},
+				Resources: resources},
+		}},
+	}, {
+		name: "commands and command",
+		steps: []api.TestStep{{
+			LiteralTestStep: &api.LiteralTestStep{
+				As:        "exec",
+				From:      "from",
+				Commands:  "commands",
+				Command:   []string{"/usr/bin/tool"},
+				Resources: resources},
+		}},
+		errs: []error{errors.New("test[0]: `commands` and `command` are mutually exclusive")},
+	}, {
+		name: "args without command",
+		steps: []api.TestStep{{
+			LiteralTestStep: &api.LiteralTestStep{
+				As:        "exec",
+				From:      "from",
+				Args:      []string{"--verbose"},
+				Resources: resources},
+		}},
+		errs: []error{errors.New("test[0]: `args` requires `command`")},
+	}, {
+		name: "run_as_script with command",
+		steps: []api.TestStep{{
+			LiteralTestStep: &api.LiteralTestStep{
+				As:          "exec",
+				From:        "from",
+				Command:     []string{"/usr/bin/tool"},
+				RunAsScript: utilpointer.Bool(true),
+				Resources:   resources},
+		}},
+		errs: []error{errors.New("test[0]: `run_as_script` cannot be set with `command`")},
+	}, {
+		name: "toolkit with command",
+		steps: []api.TestStep{{
+			LiteralTestStep: &api.LiteralTestStep{
+				As:        "exec",
+				From:      "from",
+				Command:   []string{"/usr/bin/tool"},
+				Toolkit:   utilpointer.Bool(true),
+				Resources: resources},
+		}},
+		errs: []error{errors.New("test[0]: `toolkit` cannot be set with `command`")},
+	}, {
+		name: "parameter references",
+		steps: []api.TestStep{{
+			LiteralTestStep: &api.LiteralTestStep{
+				As:           "as",
+				From:         "from",
+				Commands:     "commands",
+				Resources:    resources,
+				Environment:  []api.StepParameter{{Name: "PLATFORM", Default: utilpointer.String("aws")}},
+				Credentials:  []api.CredentialReference{{Namespace: "ns", Name: "creds", MountPath: "/var/run/$(PLATFORM)"}},
+				Dependencies: []api.StepDependency{{Name: "installer", Env: "INSTALLER_$(PLATFORM)"}},
+				Workdir:      "/tmp/$(PLATFORM)"},
+		}},
+	}, {
+		name: "unknown parameter references",
+		steps: []api.TestStep{{
+			LiteralTestStep: &api.LiteralTestStep{
+				As:           "as",
+				From:         "from",
+				Commands:     "commands",
+				Resources:    resources,
+				Credentials:  []api.CredentialReference{{Namespace: "ns", Name: "creds", MountPath: "$(DIR)/creds"}},
+				Dependencies: []api.StepDependency{{Name: "installer", Env: "INSTALLER_$(PLATFORM)"}},
+				Workdir:      "$(DIR)"},
+		}},
+		errs: []error{
+			errors.New(`test[0].credentials[0].mount_path: references "DIR", which is not a parameter of the step`),
+			errors.New(`test[0].dependencies[0].env: references "PLATFORM", which is not a parameter of the step`),
+			errors.New(`test[0].workdir: references "DIR", which is not a parameter of the step`),
+		},
 	}, {
 		name: "invalid resources",
 		steps: []api.TestStep{{
@@ -962,6 +1085,31 @@ func TestValidateTestSteps(t *testing.T) {
 		errs: []error{
 			errors.New("test[1].ref: duplicated name \"as\""),
 		},
+	}, {
+		name: "Reference renamed with an alias",
+		steps: []api.TestStep{{
+			LiteralTestStep: &api.LiteralTestStep{
+				As:        "as",
+				From:      "from",
+				Commands:  "commands",
+				Resources: resources},
+		}, {
+			Reference: &asReference,
+			Alias:     "as-again",
+		}},
+	}, {
+		name: "Alias on a literal step",
+		steps: []api.TestStep{{
+			LiteralTestStep: &api.LiteralTestStep{
+				As:        "as",
+				From:      "from",
+				Commands:  "commands",
+				Resources: resources},
+			Alias: "alias",
+		}},
+		errs: []error{
+			errors.New("test[0].alias: can only be set for a `ref` or `chain`, set `as` instead"),
+		},
 	}, {
 		name: "Test step with forbidden parameter",
 
@@ -976,6 +1124,73 @@ func TestValidateTestSteps(t *testing.T) {
 		errs: []error{
 			errors.New("test[0]: `optional_on_success` is only allowed for Post steps"),
 		},
+	}, {
+		name: "Test step marked as gather",
+		steps: []api.TestStep{{
+			LiteralTestStep: &api.LiteralTestStep{
+				As:        "as",
+				From:      "from",
+				Commands:  "commands",
+				Resources: resources,
+				Gather:    &yes},
+		}},
+		errs: []error{
+			errors.New("test[0]: `gather` is only allowed for Post steps"),
+		},
+	}, {
+		name: "Test step with invalid shard count",
+		steps: []api.TestStep{{
+			LiteralTestStep: &api.LiteralTestStep{
+				As:         "as",
+				From:       "from",
+				Commands:   "commands",
+				Resources:  resources,
+				ShardCount: utilpointer.Int(0)},
+		}},
+		errs: []error{
+			errors.New("test[0]: `shard_count` must be a positive number"),
+		},
+	}, {
+		name: "Step with invalid concurrency group",
+		steps: []api.TestStep{{
+			LiteralTestStep: &api.LiteralTestStep{
+				As:               "as",
+				From:             "from",
+				Commands:         "commands",
+				Resources:        resources,
+				ConcurrencyGroup: "Loki_Ingest"},
+		}},
+		errs: []error{
+			errors.New("test[0].concurrency_group: invalid value \"Loki_Ingest\": a lowercase RFC 1123 label must consist of lower case alphanumeric characters or '-', and must start and end with an alphanumeric character (e.g. 'my-name',  or '123-abc', regex used for validation is '[a-z0-9]([-a-z0-9]*[a-z0-9])?')"),
+		},
+	}, {
+		name: "Step with invalid mutex",
+		steps: []api.TestStep{{
+			LiteralTestStep: &api.LiteralTestStep{
+				As:        "as",
+				From:      "from",
+				Commands:  "commands",
+				Resources: resources,
+				Mutex:     "dns_zone"},
+		}},
+		errs: []error{
+			errors.New("test[0].mutex: invalid value \"dns_zone\": a lowercase RFC 1123 label must consist of lower case alphanumeric characters or '-', and must start and end with an alphanumeric character (e.g. 'my-name',  or '123-abc', regex used for validation is '[a-z0-9]([-a-z0-9]*[a-z0-9])?')"),
+		},
+	}, {
+		name: "Step with invalid required shared files",
+		steps: []api.TestStep{{
+			LiteralTestStep: &api.LiteralTestStep{
+				As:                  "as",
+				From:                "from",
+				Commands:            "commands",
+				Resources:           resources,
+				RequiresSharedFiles: []string{"kubeconfig", "", "dir/file", "kubeconfig"}},
+		}},
+		errs: []error{
+			errors.New("test[0].requires_shared_files[1]: file name must not be empty"),
+			errors.New("test[0].requires_shared_files[2]: file name \"dir/file\" must not contain a path separator"),
+			errors.New("test[0].requires_shared_files[3]: duplicated file name \"kubeconfig\""),
+		},
 	}, {
 		name: "Multiple errors",
 		steps: []api.TestStep{{
@@ -1062,6 +1277,18 @@ func TestValidateTestSteps(t *testing.T) {
 				Resources: resources},
 		}},
 		clusterClaim: api.ClaimRelease{ReleaseName: "myclaim-as", OverrideName: "myclaim"},
+	}, {
+		name: "commands too large",
+		steps: []api.TestStep{{
+			LiteralTestStep: &api.LiteralTestStep{
+				As:        "huge",
+				From:      "from",
+				Commands:  strings.Repeat("#", api.MaxStepCommandsSize+1),
+				Resources: resources},
+		}},
+		errs: []error{
+			fmt.Errorf("test `huge` has `commands` of %d bytes, exceeding the maximum size of %d bytes: move the bulk of the script into the step image", api.MaxStepCommandsSize+1, api.MaxStepCommandsSize),
+		},
 	}} {
 		t.Run(tc.name, func(t *testing.T) {
 			context := newContext("test", nil, tc.releases, make(testInputImages))
@@ -1103,6 +1330,30 @@ func TestValidatePostSteps(t *testing.T) {
 				Resources:         resources,
 				OptionalOnSuccess: &yes},
 		}},
+	}, {
+		name: "Valid gather step",
+		steps: []api.TestStep{{
+			LiteralTestStep: &api.LiteralTestStep{
+				As:        "as",
+				From:      "from",
+				Commands:  "commands",
+				Resources: resources,
+				Gather:    &yes},
+		}},
+	}, {
+		name: "Optional gather step",
+		steps: []api.TestStep{{
+			LiteralTestStep: &api.LiteralTestStep{
+				As:                "as",
+				From:              "from",
+				Commands:          "commands",
+				Resources:         resources,
+				OptionalOnSuccess: &yes,
+				Gather:            &yes},
+		}},
+		errs: []error{
+			errors.New("test[0]: `gather` steps cannot be `optional_on_success`"),
+		},
 	}} {
 		t.Run(tc.name, func(t *testing.T) {
 			context := newContext("test", nil, tc.releases, make(testInputImages))
@@ -1242,6 +1493,30 @@ func TestValidateCredentials(t *testing.T) {
 				{Namespace: "ns", Name: "name", MountPath: "/foo"},
 			},
 		},
+		{
+			name: "cred collection means no error",
+			input: []api.CredentialReference{
+				{Namespace: "ns", Selector: &api.CredentialSelector{MatchLabels: map[string]string{"partner": "true"}}, MountPath: "/foo"},
+			},
+		},
+		{
+			name: "cred with name and selector means error",
+			input: []api.CredentialReference{
+				{Namespace: "ns", Name: "name", Selector: &api.CredentialSelector{MatchLabels: map[string]string{"partner": "true"}}, MountPath: "/foo"},
+			},
+			output: []error{
+				errors.New("root.credentials[0]: name and selector are mutually exclusive"),
+			},
+		},
+		{
+			name: "cred collection without labels means error",
+			input: []api.CredentialReference{
+				{Namespace: "ns", Selector: &api.CredentialSelector{}, MountPath: "/foo"},
+			},
+			output: []error{
+				errors.New("root.credentials[0].selector.matchLabels cannot be empty"),
+			},
+		},
 	}
 	for _, testCase := range testCases {
 		t.Run(testCase.name, func(t *testing.T) {
@@ -1413,6 +1688,159 @@ func TestValidateLeases(t *testing.T) {
 	}
 }
 
+func TestValidateDisruptionMonitor(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		monitor *api.DisruptionMonitor
+		err     []error
+	}{{
+		name: "valid monitor",
+		monitor: &api.DisruptionMonitor{
+			Targets: []api.DisruptionTarget{
+				{Name: "api", URL: "https://api.example.com:6443/readyz", MaxDisruption: &prowv1.Duration{Duration: time.Minute}},
+				{Name: "console", URL: "http://console.example.com"},
+			},
+			Interval: &prowv1.Duration{Duration: 5 * time.Second},
+		},
+	}, {
+		name:    "no targets",
+		monitor: &api.DisruptionMonitor{},
+		err: []error{
+			errors.New("tests[0].steps.disruption_monitor: 'targets' cannot be empty"),
+		},
+	}, {
+		name: "invalid interval",
+		monitor: &api.DisruptionMonitor{
+			Targets:  []api.DisruptionTarget{{Name: "api", URL: "https://api.example.com"}},
+			Interval: &prowv1.Duration{},
+		},
+		err: []error{
+			errors.New("tests[0].steps.disruption_monitor: 'interval' must be positive"),
+		},
+	}, {
+		name: "invalid targets",
+		monitor: &api.DisruptionMonitor{
+			Targets: []api.DisruptionTarget{
+				{URL: "https://api.example.com"},
+				{Name: "console", URL: "ftp://console.example.com"},
+				{Name: "console", URL: "https://console.example.com", MaxDisruption: &prowv1.Duration{Duration: -time.Second}},
+			},
+		},
+		err: []error{
+			errors.New("tests[0].steps.disruption_monitor.targets[0]: 'name' cannot be empty"),
+			errors.New(`tests[0].steps.disruption_monitor.targets[1]: 'url' must be an HTTP(S) URL, got "ftp://console.example.com"`),
+			errors.New("tests[0].steps.disruption_monitor.targets[2]: duplicate name: console"),
+			errors.New("tests[0].steps.disruption_monitor.targets[2]: 'max_disruption' cannot be negative"),
+		},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			test := api.TestStepConfiguration{
+				MultiStageTestConfigurationLiteral: &api.MultiStageTestConfigurationLiteral{DisruptionMonitor: tc.monitor},
+			}
+			v := NewValidator(nil)
+			err := v.validateTestConfigurationType("tests[0]", test, nil, nil, nil, make(testInputImages), true)
+			if diff := cmp.Diff(tc.err, err, testhelper.EquateErrorMessage); diff != "" {
+				t.Errorf("unexpected error: %s", diff)
+			}
+		})
+	}
+}
+
+func TestValidateClusterHealthGate(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		gate *api.ClusterHealthGate
+		err  []error
+	}{{
+		name: "all checks",
+		gate: &api.ClusterHealthGate{},
+	}, {
+		name: "valid checks and timeout",
+		gate: &api.ClusterHealthGate{
+			Checks:  []api.ClusterHealthCheck{api.ClusterHealthCheckNodes, api.ClusterHealthCheckClusterVersion},
+			Timeout: &prowv1.Duration{Duration: time.Hour},
+		},
+	}, {
+		name: "invalid check and timeout",
+		gate: &api.ClusterHealthGate{
+			Checks:  []api.ClusterHealthCheck{api.ClusterHealthCheckNodes, "pods"},
+			Timeout: &prowv1.Duration{Duration: -time.Hour},
+		},
+		err: []error{
+			errors.New(`tests[0].steps.cluster_health_gate.checks[1]: invalid check "pods", valid values are: [cluster_operators cluster_version nodes]`),
+			errors.New("tests[0].steps.cluster_health_gate: 'timeout' must be positive"),
+		},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			test := api.TestStepConfiguration{
+				MultiStageTestConfigurationLiteral: &api.MultiStageTestConfigurationLiteral{ClusterHealthGate: tc.gate},
+			}
+			v := NewValidator(nil)
+			err := v.validateTestConfigurationType("tests[0]", test, nil, nil, nil, make(testInputImages), true)
+			if diff := cmp.Diff(tc.err, err, testhelper.EquateErrorMessage); diff != "" {
+				t.Errorf("unexpected error: %s", diff)
+			}
+		})
+	}
+}
+
+func TestValidateContainerOnly(t *testing.T) {
+	yes := true
+	step := func(as string) api.LiteralTestStep {
+		return api.LiteralTestStep{
+			As:       as,
+			From:     "src",
+			Commands: "make lint",
+			Resources: api.ResourceRequirements{
+				Requests: api.ResourceList{"cpu": "1"},
+			},
+		}
+	}
+	withLeases := step("leases")
+	withLeases.Leases = []api.StepLease{{ResourceType: "aws-quota-slice", Env: "AWS_LEASED_RESOURCE"}}
+	withCli := step("cli")
+	withCli.Cli = "latest"
+	for _, tc := range []struct {
+		name string
+		test api.TestStepConfiguration
+		err  []error
+	}{{
+		name: "valid container chain",
+		test: api.TestStepConfiguration{
+			MultiStageTestConfigurationLiteral: &api.MultiStageTestConfigurationLiteral{
+				ContainerOnly: &yes,
+				Test:          []api.LiteralTestStep{step("lint"), step("unit")},
+			},
+		},
+	}, {
+		name: "cluster settings are rejected",
+		test: api.TestStepConfiguration{
+			ClusterClaim: &api.ClusterClaim{Version: "4.14", Cloud: "aws", Owner: "owner"},
+			MultiStageTestConfigurationLiteral: &api.MultiStageTestConfigurationLiteral{
+				ContainerOnly: &yes,
+				Observers:     []api.Observer{{Name: "observer", From: "src", Commands: "observe"}},
+				Leases:        []api.StepLease{{ResourceType: "gcp-quota-slice", Env: "GCP_LEASED_RESOURCE"}},
+				Test:          []api.LiteralTestStep{withLeases, withCli},
+			},
+		},
+		err: []error{
+			errors.New("tests[0].steps: 'cluster_claim' cannot be set in a 'container_only' test"),
+			errors.New("tests[0].steps: 'observers' cannot be set in a 'container_only' test"),
+			errors.New("tests[0].steps: 'leases' cannot be set in a 'container_only' test"),
+			errors.New("tests[0].steps: step leases: 'leases' cannot be set in a 'container_only' test"),
+			errors.New("tests[0].steps: step cli: 'cli' cannot be set in a 'container_only' test"),
+		},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			v := NewValidator(nil)
+			err := v.validateTestConfigurationType("tests[0]", tc.test, nil, nil, nil, make(testInputImages), true)
+			if diff := cmp.Diff(tc.err, err, testhelper.EquateErrorMessage); diff != "" {
+				t.Errorf("unexpected error: %s", diff)
+			}
+		})
+	}
+}
+
 func TestValidateTestConfigurationType(t *testing.T) {
 	for _, tc := range []struct {
 		name     string
@@ -1732,3 +2160,793 @@ func TestVerifyClusterProfileOwnership(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateSharedFilesWiring(t *testing.T) {
+	step := func(as string, requires, provides []string) api.LiteralTestStep {
+		return api.LiteralTestStep{As: as, RequiresSharedFiles: requires, ProvidesSharedFiles: provides}
+	}
+	for _, tc := range []struct {
+		name            string
+		pre, test, post []api.LiteralTestStep
+		err             []error
+	}{{
+		name: "files are provided before they are required",
+		pre:  []api.LiteralTestStep{step("install", nil, []string{"kubeconfig", "metadata.json"})},
+		test: []api.LiteralTestStep{step("e2e", []string{"kubeconfig"}, nil)},
+		post: []api.LiteralTestStep{step("deprovision", []string{"metadata.json"}, nil)},
+	}, {
+		name: "steps without declarations",
+		pre:  []api.LiteralTestStep{step("install", nil, nil)},
+		test: []api.LiteralTestStep{step("e2e", nil, nil)},
+	}, {
+		name: "file is provided by a later step",
+		pre:  []api.LiteralTestStep{step("setup", []string{"proxy-conf.sh"}, nil), step("proxy", nil, []string{"proxy-conf.sh"})},
+		err: []error{
+			errors.New("tests[0].steps.pre[0].requires_shared_files[0]: file \"proxy-conf.sh\" is provided by step proxy, which runs after this step"),
+		},
+	}, {
+		name: "file is not provided",
+		pre:  []api.LiteralTestStep{step("install", nil, []string{"kubeconfig"})},
+		post: []api.LiteralTestStep{step("gather", []string{"kubeconfig", "metadata.json"}, nil)},
+		err: []error{
+			errors.New("tests[0].steps.post[0].requires_shared_files[1]: file \"metadata.json\" is not provided by any previous step"),
+		},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			context := newContext("tests[0].steps", nil, nil, nil)
+			err := validateSharedFilesWiring(context, tc.pre, tc.test, tc.post)
+			if diff := cmp.Diff(tc.err, err, testhelper.EquateErrorMessage); diff != "" {
+				t.Errorf("unexpected errors: %s", diff)
+			}
+		})
+	}
+}
+
+func TestValidateSidecars(t *testing.T) {
+	resources := api.ResourceRequirements{Requests: api.ResourceList{"cpu": "100m"}}
+	for _, tc := range []struct {
+		name     string
+		sidecars []api.StepSidecar
+		err      []error
+	}{{
+		name:     "valid sidecars",
+		sidecars: []api.StepSidecar{{Name: "registry", From: "registry", Commands: "serve", Readiness: "curl localhost", Resources: resources}},
+	}, {
+		name:     "missing fields",
+		sidecars: []api.StepSidecar{{Resources: resources}},
+		err: []error{
+			errors.New("tests[0].steps.test[0].sidecars[0]: `name` is required"),
+			errors.New("tests[0].steps.test[0].sidecars[0]: `from` is required"),
+			errors.New("tests[0].steps.test[0].sidecars[0]: `commands` is required"),
+		},
+	}, {
+		name: "duplicated and invalid names",
+		sidecars: []api.StepSidecar{
+			{Name: "registry", From: "registry", Commands: "serve", Resources: resources},
+			{Name: "registry", From: "registry", Commands: "serve", Resources: resources},
+			{Name: "Tunnel", From: "bastion", Commands: "sshuttle"},
+		},
+		err: []error{
+			errors.New("tests[0].steps.test[0].sidecars[1]: duplicated name \"registry\""),
+			errors.New("tests[0].steps.test[0].sidecars[2]: invalid name \"Tunnel\": a lowercase RFC 1123 label must consist of lower case alphanumeric characters or '-', and must start and end with an alphanumeric character (e.g. 'my-name',  or '123-abc', regex used for validation is '[a-z0-9]([-a-z0-9]*[a-z0-9])?')"),
+			errors.New("'tests[0].steps.test[0].sidecars[2].resources' should have at least one request or limit"),
+		},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			context := newContext("tests[0].steps.test[0].sidecars", nil, nil, nil)
+			err := validateSidecars(context, tc.sidecars)
+			if diff := cmp.Diff(tc.err, err, testhelper.EquateErrorMessage); diff != "" {
+				t.Errorf("unexpected errors: %s", diff)
+			}
+		})
+	}
+}
+
+func TestValidateSysctls(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		hostNetwork bool
+		sysctls     []api.StepSysctl
+		err         []error
+	}{{
+		name:    "valid sysctls",
+		sysctls: []api.StepSysctl{{Name: "net.ipv6.conf.all.forwarding", Value: "1"}, {Name: "kernel.shm_rmid_forced", Value: "1"}},
+	}, {
+		name:    "invalid sysctls",
+		sysctls: []api.StepSysctl{{Value: "1"}, {Name: "net..ipv6", Value: "1"}, {Name: "kernel.msgmax", Value: "1"}, {Name: "kernel.msgmax", Value: "2"}},
+		err: []error{
+			errors.New("root[0]: `name` is required"),
+			errors.New("root[1]: invalid name \"net..ipv6\""),
+			errors.New("root[3]: duplicated name \"kernel.msgmax\""),
+		},
+	}, {
+		name:        "network sysctls with the host network",
+		hostNetwork: true,
+		sysctls:     []api.StepSysctl{{Name: "net.ipv6.conf.all.forwarding", Value: "1"}, {Name: "kernel.shm_rmid_forced", Value: "1"}},
+		err:         []error{errors.New("root[0]: sysctl \"net.ipv6.conf.all.forwarding\" cannot be set for steps using the host network")},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateSysctls(newContext("root", nil, nil, nil), tc.hostNetwork, tc.sysctls)
+			if diff := cmp.Diff(tc.err, err, testhelper.EquateErrorMessage); diff != "" {
+				t.Errorf("unexpected errors: %s", diff)
+			}
+		})
+	}
+}
+
+func TestValidateClusterCapabilities(t *testing.T) {
+	yes := true
+	steps := []api.LiteralTestStep{{
+		As:          "e2e",
+		HostNetwork: &yes,
+		Sysctls:     []api.StepSysctl{{Name: "net.ipv4.ip_local_port_range", Value: "1024 65535"}, {Name: "kernel.msgmax", Value: "65536"}},
+	}}
+	for _, tc := range []struct {
+		name    string
+		cluster api.Cluster
+		test    []api.LiteralTestStep
+		err     []error
+	}{{
+		name: "no pod settings",
+		test: []api.LiteralTestStep{{As: "e2e"}},
+	}, {
+		name:    "cluster supports the settings",
+		cluster: api.ClusterVSphere02,
+		test:    steps,
+	}, {
+		name: "cluster is not set",
+		test: steps,
+		err: []error{
+			errors.New("tests[0].steps.test[0].host_network: using the host network requires `cluster` to be set to one of: vsphere, vsphere02"),
+			errors.New("tests[0].steps.test[0].sysctls[1]: unsafe sysctl \"kernel.msgmax\" requires `cluster` to be set to one of: vsphere, vsphere02"),
+		},
+	}, {
+		name:    "cluster does not support the settings",
+		cluster: api.ClusterBuild01,
+		test:    steps,
+		err: []error{
+			errors.New("tests[0].steps.test[0].host_network: using the host network is not supported on cluster build01, use one of: vsphere, vsphere02"),
+			errors.New("tests[0].steps.test[0].sysctls[1]: unsafe sysctl \"kernel.msgmax\" is not supported on cluster build01, use one of: vsphere, vsphere02"),
+		},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			context := newContext("tests[0].steps", nil, nil, nil)
+			err := validateClusterCapabilities(context, tc.cluster, nil, tc.test, nil)
+			if diff := cmp.Diff(tc.err, err, testhelper.EquateErrorMessage); diff != "" {
+				t.Errorf("unexpected errors: %s", diff)
+			}
+		})
+	}
+}
+
+func TestValidateEphemeralClusterStep(t *testing.T) {
+	yes := true
+	for _, tc := range []struct {
+		name string
+		step api.LiteralTestStep
+		err  []error
+	}{{
+		name: "valid step",
+		step: api.LiteralTestStep{As: "e2e", From: "src", Commands: "make e2e", RunOnEphemeralCluster: &yes},
+	}, {
+		name: "fields which require the build farm",
+		step: api.LiteralTestStep{
+			As:                    "e2e",
+			Cli:                   "latest",
+			Credentials:           []api.CredentialReference{{Namespace: "ns", Name: "name", MountPath: "/tmp"}},
+			HostNetwork:           &yes,
+			RunOnEphemeralCluster: &yes,
+		},
+		err: []error{
+			errors.New("root: `cli` cannot be set for steps which run on the ephemeral cluster"),
+			errors.New("root: `credentials` cannot be set for steps which run on the ephemeral cluster"),
+			errors.New("root: `host_network` cannot be set for steps which run on the ephemeral cluster"),
+		},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateEphemeralClusterStep(newContext("root", nil, nil, nil), tc.step)
+			if diff := cmp.Diff(tc.err, err, testhelper.EquateErrorMessage); diff != "" {
+				t.Errorf("unexpected errors: %s", diff)
+			}
+		})
+	}
+}
+
+func TestValidateUpgradeStep(t *testing.T) {
+	one, yes := 1, true
+	for _, tc := range []struct {
+		name     string
+		step     api.LiteralTestStep
+		releases sets.Set[string]
+		err      []error
+	}{{
+		name:     "valid step",
+		step:     api.LiteralTestStep{As: "upgrade", Upgrade: &api.UpgradeStep{Release: "latest", Rollback: true}},
+		releases: sets.New[string]("initial", "latest"),
+	}, {
+		name: "release is not validated without releases",
+		step: api.LiteralTestStep{As: "upgrade", Upgrade: &api.UpgradeStep{Release: "target"}},
+	}, {
+		name:     "unknown release",
+		step:     api.LiteralTestStep{As: "upgrade", Upgrade: &api.UpgradeStep{Release: "target"}},
+		releases: sets.New[string]("initial", "latest"),
+		err:      []error{errors.New("root.upgrade.release: unknown release \"target\"")},
+	}, {
+		name: "fields which require a container",
+		step: api.LiteralTestStep{As: "upgrade", From: "cli", Commands: "oc adm upgrade", ShardCount: &one, FailOnRestart: &yes, Manifests: "kind: Role", Upgrade: &api.UpgradeStep{}},
+		err: []error{
+			errors.New("root.upgrade: `release` is required"),
+			errors.New("root: `from` cannot be set for upgrade steps"),
+			errors.New("root: `commands` cannot be set for upgrade steps"),
+			errors.New("root: `shard_count` cannot be set for upgrade steps"),
+			errors.New("root: `fail_on_restart` cannot be set for upgrade steps"),
+			errors.New("root: `manifests` cannot be set for upgrade steps"),
+		},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateUpgradeStep(newContext("root", nil, tc.releases, nil), tc.step)
+			if diff := cmp.Diff(tc.err, err, testhelper.EquateErrorMessage); diff != "" {
+				t.Errorf("unexpected errors: %s", diff)
+			}
+		})
+	}
+}
+
+func TestValidateRollbacks(t *testing.T) {
+	one := 1
+	resources := api.ResourceRequirements{Requests: api.ResourceList{"cpu": "100m"}}
+	restore := api.LiteralTestStep{As: "dns-restore", From: "src", Commands: "restore", Resources: resources}
+	for _, tc := range []struct {
+		name   string
+		config api.MultiStageTestConfigurationLiteral
+		err    []error
+	}{{
+		name: "valid rollback",
+		config: api.MultiStageTestConfigurationLiteral{
+			Test:      []api.LiteralTestStep{{As: "dns", From: "src", Commands: "update", Resources: resources, Rollback: "dns-restore"}},
+			Rollbacks: []api.LiteralTestStep{restore},
+		},
+	}, {
+		name: "unknown rollback",
+		config: api.MultiStageTestConfigurationLiteral{
+			Pre: []api.LiteralTestStep{{As: "dns", From: "src", Commands: "update", Resources: resources, Rollback: "dns-restore"}},
+		},
+		err: []error{errors.New("root.pre[0].rollback: unknown rollback step \"dns-restore\"")},
+	}, {
+		name: "invalid rollbacks",
+		config: api.MultiStageTestConfigurationLiteral{
+			Test: []api.LiteralTestStep{{As: "dns-restore", From: "src", Commands: "update", Resources: resources}},
+			Rollbacks: []api.LiteralTestStep{
+				restore,
+				{As: "tags-restore", From: "src", Commands: "restore", Resources: resources, Rollback: "dns-restore"},
+			},
+		},
+		err: []error{
+			errors.New("root.rollbacks[0]: duplicated name \"dns-restore\""),
+			errors.New("root.rollbacks[1]: rollback steps cannot declare a `rollback`"),
+		},
+	}, {
+		name: "sharded step",
+		config: api.MultiStageTestConfigurationLiteral{
+			Test:      []api.LiteralTestStep{{As: "dns", From: "src", Commands: "update", Resources: resources, ShardCount: &one, Rollback: "dns-restore"}},
+			Rollbacks: []api.LiteralTestStep{restore},
+		},
+		err: []error{errors.New("root.test[0]: `rollback` cannot be set for sharded steps")},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			v := NewValidator(nil)
+			context := newContext("root", nil, nil, nil)
+			var err []error
+			for i, step := range tc.config.Test {
+				err = append(err, v.validateLiteralTestStep(context.addField("test").addIndex(i), testStageTest, step, nil)...)
+			}
+			err = append(err, v.validateRollbacks(context, &tc.config, nil)...)
+			if diff := cmp.Diff(tc.err, err, testhelper.EquateErrorMessage); diff != "" {
+				t.Errorf("unexpected errors: %s", diff)
+			}
+		})
+	}
+}
+
+func TestValidateGangs(t *testing.T) {
+	one := 1
+	for _, tc := range []struct {
+		name  string
+		steps []api.LiteralTestStep
+		err   []error
+	}{{
+		name:  "valid gangs",
+		steps: []api.LiteralTestStep{{As: "install"}, {As: "server", Gang: "perf"}, {As: "client", Gang: "perf"}, {As: "e2e"}},
+	}, {
+		name:  "gang is not consecutive",
+		steps: []api.LiteralTestStep{{As: "server", Gang: "perf"}, {As: "e2e"}, {As: "client", Gang: "perf"}},
+		err:   []error{errors.New("root[2].gang: steps of gang \"perf\" must be consecutive")},
+	}, {
+		name:  "gang with a single step",
+		steps: []api.LiteralTestStep{{As: "server", Gang: "perf"}, {As: "client", Gang: "other"}},
+		err: []error{
+			errors.New("root: gang \"other\" must have at least two steps"),
+			errors.New("root: gang \"perf\" must have at least two steps"),
+		},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateGangs(newContext("root", nil, nil, nil), tc.steps)
+			if diff := cmp.Diff(tc.err, err, testhelper.EquateErrorMessage); diff != "" {
+				t.Errorf("unexpected errors: %s", diff)
+			}
+		})
+	}
+	err := validateGangStep(newContext("root", nil, nil, nil), api.LiteralTestStep{As: "server", Gang: "Perf", ShardCount: &one})
+	expected := []error{
+		errors.New("root.gang: invalid value \"Perf\": a lowercase RFC 1123 label must consist of lower case alphanumeric characters or '-', and must start and end with an alphanumeric character (e.g. 'my-name',  or '123-abc', regex used for validation is '[a-z0-9]([-a-z0-9]*[a-z0-9])?')"),
+		errors.New("root: `shard_count` cannot be set for steps of a gang"),
+	}
+	if diff := cmp.Diff(expected, err, testhelper.EquateErrorMessage); diff != "" {
+		t.Errorf("unexpected errors: %s", diff)
+	}
+}
+
+func TestValidateTestMetadata(t *testing.T) {
+	literal := &api.MultiStageTestConfigurationLiteral{}
+	for _, tc := range []struct {
+		name string
+		test api.TestStepConfiguration
+		err  []error
+	}{{
+		name: "no metadata",
+		test: api.TestStepConfiguration{Commands: "make test"},
+	}, {
+		name: "valid metadata",
+		test: api.TestStepConfiguration{
+			MultiStageTestConfigurationLiteral: literal,
+			Labels:                             map[string]string{"team": "installer", "example.com/tier": "1"},
+			Annotations:                        map[string]string{"example.com/owner": "Installer Team <installer@example.com>"},
+		},
+	}, {
+		name: "metadata on a container test",
+		test: api.TestStepConfiguration{Commands: "make test", Labels: map[string]string{"team": "installer"}},
+		err:  []error{errors.New("root: `labels` and `annotations` can only be set for multi-stage tests")},
+	}, {
+		name: "invalid metadata",
+		test: api.TestStepConfiguration{
+			MultiStageTestConfiguration: &api.MultiStageTestConfiguration{},
+			Labels:                      map[string]string{"a team": "installer", "team": "the installer", "ci.openshift.io/multi-stage-test": "e2e"},
+			Annotations:                 map[string]string{"pods.ci.openshift.io/owner": "installer"},
+		},
+		err: []error{
+			errors.New("root.labels: invalid key \"a team\": name part must consist of alphanumeric characters, '-', '_' or '.', and must start and end with an alphanumeric character (e.g. 'MyName',  or 'my.name',  or '123-abc', regex used for validation is '([A-Za-z0-9][-A-Za-z0-9_.]*)?[A-Za-z0-9]')"),
+			errors.New("root.labels: key \"ci.openshift.io/multi-stage-test\" uses the reserved domain ci.openshift.io"),
+			errors.New("root.labels.team: invalid value \"the installer\": a valid label must be an empty string or consist of alphanumeric characters, '-', '_' or '.', and must start and end with an alphanumeric character (e.g. 'MyValue',  or 'my_value',  or '12345', regex used for validation is '(([A-Za-z0-9][-A-Za-z0-9_.]*)?[A-Za-z0-9])?')"),
+			errors.New("root.annotations: key \"pods.ci.openshift.io/owner\" uses the reserved domain ci.openshift.io"),
+		},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateTestMetadata("root", tc.test)
+			if diff := cmp.Diff(tc.err, err, testhelper.EquateErrorMessage); diff != "" {
+				t.Errorf("unexpected errors: %s", diff)
+			}
+		})
+	}
+}
+
+func TestValidatePreviousJobFiles(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		files []string
+		err   []error
+	}{{
+		name:  "valid files",
+		files: []string{"perf/results.json", "api-surface.txt"},
+	}, {
+		name:  "invalid files",
+		files: []string{"", "/etc/passwd", "../secret", "perf//results.json", "api-surface.txt", "api-surface.txt"},
+		err: []error{
+			errors.New("root[0]: file name must not be empty"),
+			errors.New("root[1]: file name \"/etc/passwd\" must be a clean path relative to the artifact directory"),
+			errors.New("root[2]: file name \"../secret\" must be a clean path relative to the artifact directory"),
+			errors.New("root[3]: file name \"perf//results.json\" must be a clean path relative to the artifact directory"),
+			errors.New("root[5]: duplicated file name \"api-surface.txt\""),
+		},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validatePreviousJobFiles(newContext("root", nil, nil, nil), tc.files)
+			if diff := cmp.Diff(tc.err, err, testhelper.EquateErrorMessage); diff != "" {
+				t.Errorf("unexpected errors: %s", diff)
+			}
+		})
+	}
+}
+
+func TestValidateStdinFrom(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		stdin api.StepStdin
+		err   []error
+	}{{
+		name:  "parameter",
+		stdin: api.StepStdin{Parameter: "ANSWERS"},
+	}, {
+		name:  "shared file",
+		stdin: api.StepStdin{SharedFile: "install-answers"},
+	}, {
+		name: "no source",
+		err:  []error{errors.New("root: one of `parameter` or `shared_file` must be set")},
+	}, {
+		name:  "both sources",
+		stdin: api.StepStdin{Parameter: "ANSWERS", SharedFile: "install-answers"},
+		err:   []error{errors.New("root: only one of `parameter` or `shared_file` may be set")},
+	}, {
+		name:  "unknown parameter",
+		stdin: api.StepStdin{Parameter: "CONFIRM"},
+		err:   []error{errors.New(`root.parameter: "CONFIRM" is not a parameter of the step`)},
+	}, {
+		name:  "shared file with a path",
+		stdin: api.StepStdin{SharedFile: "../answers"},
+		err:   []error{errors.New(`root.shared_file: file name "../answers" must not contain a path separator`)},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			step := api.LiteralTestStep{Environment: []api.StepParameter{{Name: "ANSWERS"}}, StdinFrom: &tc.stdin}
+			err := validateStdinFrom(newContext("root", nil, nil, nil), step)
+			if diff := cmp.Diff(tc.err, err, testhelper.EquateErrorMessage); diff != "" {
+				t.Errorf("unexpected errors: %s", diff)
+			}
+		})
+	}
+}
+
+func TestValidateWaitFor(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		conditions []api.WaitCondition
+		err        []error
+	}{{
+		name: "valid conditions",
+		conditions: []api.WaitCondition{
+			{APIVersion: "apps/v1", Kind: "Deployment", Namespace: "ns", Name: "app", JSONPath: `{.status.conditions[?(@.type=="Available")].status}`, Value: "True"},
+			{APIVersion: "v1", Kind: "Namespace", Name: "ns", JSONPath: "{.metadata.name}", Timeout: &prowv1.Duration{Duration: time.Minute}},
+		},
+	}, {
+		name:       "missing fields",
+		conditions: []api.WaitCondition{{}},
+		err: []error{
+			errors.New("root[0]: `api_version` is required"),
+			errors.New("root[0]: `kind` is required"),
+			errors.New("root[0]: `name` is required"),
+			errors.New("root[0]: `jsonpath` is required"),
+		},
+	}, {
+		name:       "invalid template and timeout",
+		conditions: []api.WaitCondition{{APIVersion: "v1", Kind: "Namespace", Name: "ns", JSONPath: "{.metadata.name", Timeout: &prowv1.Duration{}}},
+		err: []error{
+			errors.New("root[0].jsonpath: invalid template: unclosed action"),
+			errors.New("root[0].timeout: must be positive"),
+		},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateWaitFor(newContext("root", nil, nil, nil), tc.conditions)
+			if diff := cmp.Diff(tc.err, err, testhelper.EquateErrorMessage); diff != "" {
+				t.Errorf("unexpected errors: %s", diff)
+			}
+		})
+	}
+}
+
+func TestValidateStepReleases(t *testing.T) {
+	steps := sets.New[string]("install-management", "install-hosted", "e2e")
+	for _, tc := range []struct {
+		name         string
+		stepReleases api.StepReleases
+		hasTagSpec   bool
+		steps        sets.Set[string]
+		err          []error
+	}{{
+		name: "no releases",
+	}, {
+		name:         "configured releases",
+		stepReleases: api.StepReleases{"install-hosted": "hosted", "e2e": "latest"},
+		hasTagSpec:   true,
+		steps:        steps,
+	}, {
+		name:         "latest without a tag specification",
+		stepReleases: api.StepReleases{"e2e": "latest"},
+		steps:        steps,
+		err:          []error{errors.New("tests[0].steps.step_releases.e2e: unknown release \"latest\"")},
+	}, {
+		name:         "unknown step and releases",
+		stepReleases: api.StepReleases{"install": "hosted", "install-hosted": "other", "e2e": ""},
+		steps:        steps,
+		err: []error{
+			errors.New("tests[0].steps.step_releases.e2e: the release cannot be empty"),
+			errors.New(`tests[0].steps.step_releases: "install" is not the name of a step of the test`),
+			errors.New(`tests[0].steps.step_releases.install-hosted: unknown release "other"`),
+		},
+	}, {
+		name:         "steps are not known before the test is resolved",
+		stepReleases: api.StepReleases{"install": "hosted"},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			context := newContext("tests[0].steps.step_releases", nil, sets.New[string]("hosted"), nil)
+			err := validateStepReleases(context, tc.stepReleases, tc.hasTagSpec, tc.steps)
+			if diff := cmp.Diff(tc.err, err, testhelper.EquateErrorMessage); diff != "" {
+				t.Errorf("unexpected errors: %s", diff)
+			}
+		})
+	}
+}
+
+func TestValidateFeatureSets(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		releases map[string]api.UnresolvedRelease
+		tagSpec  *api.ReleaseTagConfiguration
+		test     api.TestStepConfiguration
+		err      []error
+	}{{
+		name:    "supported feature set",
+		tagSpec: &api.ReleaseTagConfiguration{Name: "4.14"},
+		test: api.TestStepConfiguration{MultiStageTestConfiguration: &api.MultiStageTestConfiguration{
+			FeatureSet: api.FeatureSetTechPreviewNoUpgrade,
+		}},
+	}, {
+		name:     "feature set newer than the release",
+		releases: map[string]api.UnresolvedRelease{"latest": {Candidate: &api.Candidate{Version: "4.14"}}},
+		test: api.TestStepConfiguration{MultiStageTestConfiguration: &api.MultiStageTestConfiguration{
+			FeatureSet: api.FeatureSetDevPreviewNoUpgrade,
+		}},
+		err: []error{errors.New("tests[0].steps.feature_set: feature set DevPreviewNoUpgrade requires OpenShift 4.15 or later, but the latest release is 4.14")},
+	}, {
+		name: "prerelease in the supported versions",
+		releases: map[string]api.UnresolvedRelease{"latest": {Prerelease: &api.Prerelease{
+			VersionBounds: api.VersionBounds{Lower: "4.15.0-0", Upper: "4.16.0-0"},
+		}}},
+		test: api.TestStepConfiguration{MultiStageTestConfiguration: &api.MultiStageTestConfiguration{
+			FeatureSet: api.FeatureSetDevPreviewNoUpgrade,
+		}},
+	}, {
+		name: "unknown feature set",
+		test: api.TestStepConfiguration{MultiStageTestConfiguration: &api.MultiStageTestConfiguration{
+			FeatureSet: "TechPreview",
+		}},
+		err: []error{errors.New(`tests[0].steps.feature_set: unknown feature set "TechPreview", must be one of: CustomNoUpgrade, DevPreviewNoUpgrade, LatencySensitive, TechPreviewNoUpgrade`)},
+	}, {
+		name: "feature set also set as a parameter and upgraded",
+		test: api.TestStepConfiguration{MultiStageTestConfigurationLiteral: &api.MultiStageTestConfigurationLiteral{
+			FeatureSet:  api.FeatureSetTechPreviewNoUpgrade,
+			Environment: api.TestEnvironment{"FEATURE_SET": "TechPreviewNoUpgrade"},
+			Test:        []api.LiteralTestStep{{As: "upgrade", Upgrade: &api.UpgradeStep{Release: "latest"}}},
+		}},
+		err: []error{
+			errors.New("tests[0].literal_steps.feature_set: cannot be set together with the FEATURE_SET parameter in `env`"),
+			errors.New("tests[0].literal_steps.feature_set: feature set TechPreviewNoUpgrade does not allow upgrades, but step upgrade upgrades the cluster"),
+		},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			config := api.ReleaseBuildConfiguration{
+				InputConfiguration: api.InputConfiguration{Releases: tc.releases, ReleaseTagConfiguration: tc.tagSpec},
+				Tests:              []api.TestStepConfiguration{tc.test},
+			}
+			err := validateFeatureSets(&config)
+			if diff := cmp.Diff(tc.err, err, testhelper.EquateErrorMessage); diff != "" {
+				t.Errorf("unexpected errors: %s", diff)
+			}
+		})
+	}
+}
+
+func TestValidateClusterReuse(t *testing.T) {
+	provider := api.TestStepConfiguration{As: "e2e", MultiStageTestConfiguration: &api.MultiStageTestConfiguration{ClusterProfile: api.ClusterProfileAWS}}
+	for _, tc := range []struct {
+		name  string
+		tests []api.TestStepConfiguration
+		err   []error
+	}{{
+		name: "test reusing the cluster of another test",
+		tests: []api.TestStepConfiguration{provider, {
+			As:                                 "e2e-serial",
+			MultiStageTestConfigurationLiteral: &api.MultiStageTestConfigurationLiteral{ReuseClusterFrom: "e2e"},
+		}},
+	}, {
+		name: "unknown, own and profile-less clusters",
+		tests: []api.TestStepConfiguration{
+			provider,
+			{As: "unit", ContainerTestConfiguration: &api.ContainerTestConfiguration{From: "src"}},
+			{As: "lint", MultiStageTestConfiguration: &api.MultiStageTestConfiguration{}},
+			{As: "a", MultiStageTestConfiguration: &api.MultiStageTestConfiguration{ReuseClusterFrom: "unit"}},
+			{As: "b", MultiStageTestConfiguration: &api.MultiStageTestConfiguration{ReuseClusterFrom: "b"}},
+			{As: "c", MultiStageTestConfiguration: &api.MultiStageTestConfiguration{ReuseClusterFrom: "lint"}},
+		},
+		err: []error{
+			errors.New(`tests[3].steps.reuse_cluster_from: "unit" is not the name of a multi-stage test`),
+			errors.New("tests[4].steps.reuse_cluster_from: a test cannot reuse its own cluster"),
+			errors.New("tests[5].steps.reuse_cluster_from: test lint does not provision a cluster with a `cluster_profile`"),
+		},
+	}, {
+		name: "chained reuse and provisioning tests",
+		tests: []api.TestStepConfiguration{
+			provider,
+			{As: "a", MultiStageTestConfiguration: &api.MultiStageTestConfiguration{ReuseClusterFrom: "e2e", ClusterProfile: api.ClusterProfileAWS}},
+			{As: "b", MultiStageTestConfiguration: &api.MultiStageTestConfiguration{ReuseClusterFrom: "a"}, ClusterClaim: &api.ClusterClaim{}},
+		},
+		err: []error{
+			errors.New("tests[1].steps.reuse_cluster_from: cannot be set together with `cluster_profile`"),
+			errors.New("tests[2].steps.reuse_cluster_from: test a reuses the cluster of test e2e itself"),
+			errors.New("tests[2].steps.reuse_cluster_from: cannot be set together with `cluster_claim`"),
+		},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateClusterReuse(&api.ReleaseBuildConfiguration{Tests: tc.tests})
+			if diff := cmp.Diff(tc.err, err, testhelper.EquateErrorMessage); diff != "" {
+				t.Errorf("unexpected errors: %s", diff)
+			}
+		})
+	}
+}
+
+func TestValidateAggregateTests(t *testing.T) {
+	unit := api.TestStepConfiguration{As: "unit", ContainerTestConfiguration: &api.ContainerTestConfiguration{From: "src"}}
+	e2e := api.TestStepConfiguration{As: "e2e", MultiStageTestConfiguration: &api.MultiStageTestConfiguration{}}
+	aggregate := func(as string, minimum *int, tests ...string) api.TestStepConfiguration {
+		return api.TestStepConfiguration{As: as, AggregateTestConfiguration: &api.AggregateTestConfiguration{Tests: tests, MinimumPassing: minimum}}
+	}
+	for _, tc := range []struct {
+		name  string
+		tests []api.TestStepConfiguration
+		err   []error
+	}{{
+		name:  "aggregate of tests with a threshold",
+		tests: []api.TestStepConfiguration{unit, e2e, aggregate("gate", utilpointer.Int(1), "unit", "e2e")},
+	}, {
+		name:  "unknown, own, aggregate and duplicate tests",
+		tests: []api.TestStepConfiguration{unit, aggregate("gate", nil, "unit", "gate", "missing", "unit"), aggregate("outer", nil, "gate")},
+		err: []error{
+			errors.New("tests[1].aggregate.tests[1]: a test cannot aggregate itself"),
+			errors.New(`tests[1].aggregate.tests[2]: "missing" is not the name of a test`),
+			errors.New("tests[1].aggregate.tests[3]: duplicate test unit"),
+			errors.New("tests[2].aggregate.tests[0]: test gate is an aggregate test itself"),
+		},
+	}, {
+		name:  "unreachable thresholds",
+		tests: []api.TestStepConfiguration{unit, e2e, aggregate("none", utilpointer.Int(0), "unit"), aggregate("all", utilpointer.Int(3), "unit", "e2e"), aggregate("empty", nil)},
+		err: []error{
+			errors.New("tests[2].aggregate.minimum_passing: must be between 1 and the number of tests (1), got 0"),
+			errors.New("tests[3].aggregate.minimum_passing: must be between 1 and the number of tests (2), got 3"),
+			errors.New("tests[4].aggregate.tests: at least one test must be aggregated"),
+		},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateAggregateTests(&api.ReleaseBuildConfiguration{Tests: tc.tests})
+			if diff := cmp.Diff(tc.err, err, testhelper.EquateErrorMessage); diff != "" {
+				t.Errorf("unexpected errors: %s", diff)
+			}
+		})
+	}
+}
+
+func TestValidateEnvironmentFromFile(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		env      api.TestEnvironment
+		fromFile api.TestEnvironment
+		err      []error
+	}{{
+		name:     "valid files",
+		env:      api.TestEnvironment{"OTHER": "value"},
+		fromFile: api.TestEnvironment{"INSTALL_CONFIG_PATCH": "patches/install-config.yaml"},
+	}, {
+		name:     "invalid files",
+		env:      api.TestEnvironment{"SET": "value"},
+		fromFile: api.TestEnvironment{"ABSOLUTE": "/etc/passwd", "EMPTY": "", "SET": "set.yaml"},
+		err: []error{
+			errors.New("root: ABSOLUTE: path must be relative to the configuration file, got /etc/passwd"),
+			errors.New("root: EMPTY: path cannot be empty"),
+			errors.New("root: SET: parameter is also set in `env`"),
+		},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateEnvironmentFromFile(newContext("root", nil, nil, nil), tc.env, tc.fromFile)
+			if diff := cmp.Diff(tc.err, err, testhelper.EquateErrorMessage); diff != "" {
+				t.Errorf("unexpected errors: %s", diff)
+			}
+		})
+	}
+}
+
+func TestValidateScratchDir(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		workdir string
+		size    string
+		err     []error
+	}{{
+		name: "no scratch directory",
+	}, {
+		name:    "valid scratch directory",
+		workdir: "/go/src/github.com/openshift/installer",
+		size:    "20Gi",
+	}, {
+		name:    "relative workdir",
+		workdir: "src",
+		err:     []error{errors.New("root.workdir: must be an absolute path, got src")},
+	}, {
+		name:    "root workdir",
+		workdir: "/",
+		err:     []error{errors.New("root.workdir: cannot be the root directory")},
+	}, {
+		name:    "unclean workdir",
+		workdir: "/scratch/../tmp/",
+		err:     []error{errors.New("root.workdir: must be a clean path, got /scratch/../tmp/ instead of /tmp")},
+	}, {
+		name: "invalid size",
+		size: "lots",
+		err:  []error{errors.New(`root.scratch_size: invalid quantity "lots": quantities must match the regular expression '^([+-]?[0-9.]+)([eEinumkKMGTP]*[-+]?[0-9]*)$'`)},
+	}, {
+		name: "zero size",
+		size: "0Gi",
+		err:  []error{errors.New("root.scratch_size: must be positive, got 0Gi")},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateScratchDir(newContext("root", nil, nil, nil), tc.workdir, tc.size)
+			if diff := cmp.Diff(tc.err, err, testhelper.EquateErrorMessage); diff != "" {
+				t.Errorf("unexpected errors: %s", diff)
+			}
+		})
+	}
+}
+
+func TestValidateNodeCapabilities(t *testing.T) {
+	for _, tc := range []struct {
+		name         string
+		capabilities []string
+		err          []error
+	}{{
+		name:         "valid capabilities",
+		capabilities: []string{"kvm", "large-disk", "arm64"},
+	}, {
+		name:         "invalid and duplicate capabilities",
+		capabilities: []string{"kvm", "SSE4", "kvm"},
+		err: []error{
+			errors.New(`root[1]: invalid value "SSE4": a lowercase RFC 1123 label must consist of lower case alphanumeric characters or '-', and must start and end with an alphanumeric character (e.g. 'my-name',  or '123-abc', regex used for validation is '[a-z0-9]([-a-z0-9]*[a-z0-9])?')`),
+			errors.New(`root[2]: duplicate capability "kvm"`),
+		},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateNodeCapabilities(newContext("root", nil, nil, nil), tc.capabilities)
+			if diff := cmp.Diff(tc.err, err, testhelper.EquateErrorMessage); diff != "" {
+				t.Errorf("unexpected errors: %s", diff)
+			}
+		})
+	}
+}
+
+func TestValidateJobStep(t *testing.T) {
+	yes := true
+	for _, tc := range []struct {
+		name string
+		step api.LiteralTestStep
+		err  []error
+	}{{
+		name: "valid job",
+		step: api.LiteralTestStep{As: "e2e", Job: &api.StepJob{BackoffLimit: 2, TTLSecondsAfterFinished: utilpointer.Int32(3600)}},
+	}, {
+		name: "negative values",
+		step: api.LiteralTestStep{As: "e2e", Job: &api.StepJob{BackoffLimit: -1, TTLSecondsAfterFinished: utilpointer.Int32(-1)}},
+		err: []error{
+			errors.New("root.job.backoff_limit: must not be negative, got -1"),
+			errors.New("root.job.ttl_seconds_after_finished: must not be negative, got -1"),
+		},
+	}, {
+		name: "incompatible fields",
+		step: api.LiteralTestStep{As: "e2e", Job: &api.StepJob{}, RunOnEphemeralCluster: &yes, Gang: "perf"},
+		err: []error{
+			errors.New("root: `run_on_ephemeral_cluster` cannot be set for steps which run as a job"),
+			errors.New("root: `gang` cannot be set for steps which run as a job"),
+		},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateJobStep(newContext("root", nil, nil, nil), tc.step)
+			if diff := cmp.Diff(tc.err, err, testhelper.EquateErrorMessage); diff != "" {
+				t.Errorf("unexpected errors: %s", diff)
+			}
+		})
+	}
+}
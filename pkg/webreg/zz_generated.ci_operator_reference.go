@@ -323,6 +323,18 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"          # this will cause both a floating tag and commit-specific tags\n" +
 	"          # to be promoted.\n" +
 	"          tag_by_commit: true\n" +
+	"# Quarantine lists known-flaky jUnit test cases reported by multi-stage\n" +
+	"# test steps. Until their waiver expires, failures of these test cases\n" +
+	"# are reported as skipped and do not fail the step.\n" +
+	"quarantine:\n" +
+	"    - # Expires is the last day, in YYYY-MM-DD format, on which the waiver\n" +
+	"      # applies.\n" +
+	"      expires: ' '\n" +
+	"      # Name is the name of the test case.\n" +
+	"      name: ' '\n" +
+	"      # Reason explains why the test case is quarantined, usually with a link\n" +
+	"      # to the issue tracking the flake.\n" +
+	"      reason: ' '\n" +
 	"# RawSteps are literal Steps that should be\n" +
 	"# included in the final pipeline.\n" +
 	"raw_steps:\n" +
@@ -532,8 +544,19 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"        ref: ' '\n" +
 	"        to: ' '\n" +
 	"      test_step:\n" +
+	"        aggregate:\n" +
+	"            # MinimumPassing is the number of tests which must pass for the\n" +
+	"            # aggregate test to pass, e.g. 4 of 5. All tests must pass by default.\n" +
+	"            minimum_passing: 0\n" +
+	"            # Tests are the names of the tests whose results are aggregated.\n" +
+	"            tests:\n" +
+	"                - \"\"\n" +
 	"        # AlwaysRun can be set to false to disable running the job on every PR\n" +
 	"        always_run: false\n" +
+	"        # Annotations are added to every object created for a multi-stage test,\n" +
+	"        # in addition to those set by ci-operator.\n" +
+	"        annotations:\n" +
+	"            \"\": \"\"\n" +
 	"        # As is the name of the test.\n" +
 	"        as: ' '\n" +
 	"        # Cluster specifies the name of the cluster where the test runs.\n" +
@@ -586,6 +609,10 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"        # on the last time the test ran. Setting this field will\n" +
 	"        # create a periodic job instead of a presubmit\n" +
 	"        interval: \"\"\n" +
+	"        # Labels are added to every object created for a multi-stage test, in\n" +
+	"        # addition to those set by ci-operator.\n" +
+	"        labels:\n" +
+	"            \"\": \"\"\n" +
 	"        literal_steps:\n" +
 	"            # AllowBestEffortPostSteps defines if any `post` steps can be ignored when\n" +
 	"            # they fail. The given step must explicitly ask for being ignored by setting\n" +
@@ -595,8 +622,21 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"            # all previous `pre` and `test` steps were successful. The given step must explicitly\n" +
 	"            # ask for being skipped by setting the OptionalOnSuccess flag to true.\n" +
 	"            allow_skip_on_success: false\n" +
+	"            # ClusterHealthGate configures checks of the health of the cluster under\n" +
+	"            # test which must pass after the `pre` phase before `test` steps are run.\n" +
+	"            cluster_health_gate:\n" +
+	"                # Checks are the checks which must pass. All checks are run when empty.\n" +
+	"                checks:\n" +
+	"                    - \"\"\n" +
+	"                # Timeout is how long to wait for the checks to pass, defaults to 10m.\n" +
+	"                timeout: 0s\n" +
 	"            # ClusterProfile defines the profile/cloud provider for end-to-end test steps.\n" +
 	"            cluster_profile: ' '\n" +
+	"            # ContainerOnly declares that the steps of the test do not interact with\n" +
+	"            # any cluster. Steps are not given a kubeconfig, run with reduced\n" +
+	"            # permissions in the test namespace and have a default timeout of one\n" +
+	"            # hour. Cluster profiles, claims, observers and leases cannot be used.\n" +
+	"            container_only: false\n" +
 	"            # Dependencies holds override values for dependency parameters.\n" +
 	"            dependencies:\n" +
 	"                \"\": \"\"\n" +
@@ -604,6 +644,25 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"            # be used with rehearsals. Otherwise, the overrides should be passed in as parameters to ci-operator.\n" +
 	"            dependency_overrides:\n" +
 	"                \"\": \"\"\n" +
+	"            # DisruptionMonitor configures an availability monitor which runs in\n" +
+	"            # ci-operator during the `test` phase.\n" +
+	"            disruption_monitor:\n" +
+	"                # Interval is the time between two requests to a target, defaults to 1s.\n" +
+	"                interval: 0s\n" +
+	"                # Targets are the endpoints which are polled.\n" +
+	"                targets:\n" +
+	"                    - # InsecureSkipTLSVerify disables the verification of the certificate\n" +
+	"                      # served by the target, which is necessary when polling endpoints of\n" +
+	"                      # ephemeral clusters with self-signed certificates.\n" +
+	"                      insecure_skip_tls_verify: true\n" +
+	"                      # MaxDisruption is the total unavailability tolerated during the `test`\n" +
+	"                      # phase. When unset, disruption is only reported.\n" +
+	"                      max_disruption: 0s\n" +
+	"                      # Name identifies the target in the report.\n" +
+	"                      name: ' '\n" +
+	"                      # URL is requested with GET on every poll. The target is considered\n" +
+	"                      # available when the request succeeds with a status code below 500.\n" +
+	"                      url: ' '\n" +
 	"            # DnsConfig for step's Pod.\n" +
 	"            dnsConfig:\n" +
 	"                # Nameservers is a list of IP addresses that will be used as DNS servers for the Pod\n" +
@@ -615,6 +674,11 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"            # Environment has the values of parameters for the steps.\n" +
 	"            env:\n" +
 	"                \"\": \"\"\n" +
+	"            # FeatureSet is the OpenShift feature set the cluster under test is\n" +
+	"            # installed with, e.g. TechPreviewNoUpgrade. It is exposed to the steps\n" +
+	"            # as $FEATURE_SET and as the value of their parameter of that name, and\n" +
+	"            # is validated against the version of the `latest` release.\n" +
+	"            feature_set: ' '\n" +
 	"            # Leases lists resources that should be acquired for the test.\n" +
 	"            leases:\n" +
 	"                - # Env is the environment variable that will contain the resource name.\n" +
@@ -662,30 +726,73 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"            # Post is the array of test steps run after the tests finish and teardown/deprovision resources.\n" +
 	"            # Post steps always run, even if previous steps fail.\n" +
 	"            post:\n" +
-	"                - # As is the name of the LiteralTestStep.\n" +
+	"                - # Args are the arguments passed to Command.\n" +
+	"                  args:\n" +
+	"                    - \"\"\n" +
+	"                  # As is the name of the LiteralTestStep.\n" +
 	"                  as: ' '\n" +
 	"                  # BestEffort defines if this step should cause the job to fail when the\n" +
 	"                  # step fails. This only applies when AllowBestEffortPostSteps flag is set\n" +
 	"                  # to true in MultiStageTestConfiguration. This option is applicable to\n" +
 	"                  # `post` steps.\n" +
 	"                  best_effort: false\n" +
+	"                  # Burst lets the step use resources up to its limits, which are kept as\n" +
+	"                  # configured instead of being derived from its requests, so that bursty\n" +
+	"                  # steps can request what they use on average. Limits are capped by the\n" +
+	"                  # policy of the build farm and the CPU throttling of the step is recorded\n" +
+	"                  # in its metrics.\n" +
+	"                  burst: false\n" +
 	"                  # Cli is the (optional) name of the release from which the `oc` binary\n" +
-	"                  # will be injected into this step.\n" +
+	"                  # will be injected into this step. If the step has a `KUBECTL_RETRY`\n" +
+	"                  # parameter, the binary is wrapped to retry calls which fail with a\n" +
+	"                  # refused connection or a 429 response, at most that many times.\n" +
 	"                  cli: ' '\n" +
+	"                  # Command is the executable run inside the image, as an alternative to\n" +
+	"                  # `commands` for images without bash, e.g. distroless or scratch-based\n" +
+	"                  # tool images. It is executed directly with Args, without a shell.\n" +
+	"                  command:\n" +
+	"                    - \"\"\n" +
 	"                  # Commands is the command(s) that will be run inside the image.\n" +
 	"                  commands: ' '\n" +
+	"                  # ConcurrencyGroup limits the number of instances of the step which run\n" +
+	"                  # concurrently across all jobs, e.g. for heavyweight steps which would\n" +
+	"                  # overload a shared service. A lease is acquired from the lease server\n" +
+	"                  # for the duration of the step, so the limit is the number of resources\n" +
+	"                  # of type `<group>-concurrency` configured in the server.\n" +
+	"                  concurrency_group: ' '\n" +
 	"                  # Credentials defines the credentials we'll mount into this step.\n" +
 	"                  credentials:\n" +
-	"                    - # MountPath is where the secret should be mounted.\n" +
+	"                    - # MountPath is where the secret should be mounted. References to the\n" +
+	"                      # parameters of the step, e.g. `$(PLATFORM)`, are expanded.\n" +
 	"                      mount_path: ' '\n" +
 	"                      # Names is which source secret to mount.\n" +
 	"                      name: ' '\n" +
 	"                      # Namespace is where the source secret exists.\n" +
 	"                      namespace: ' '\n" +
+	"                      # Refresh propagates updates of the source secret to its copy in the\n" +
+	"                      # test namespace while the test runs, for credentials which are rotated\n" +
+	"                      # during long tests. Mounted files are updated by the kubelet shortly\n" +
+	"                      # after, so steps must read them again instead of caching their content.\n" +
+	"                      refresh: true\n" +
+	"                      # Selector mounts a collection of secrets instead of a single one: every\n" +
+	"                      # secret in the namespace which has the labels is mounted in a directory\n" +
+	"                      # named after it under the mount path. The collection is resolved when\n" +
+	"                      # the test starts, so secrets can be added to or removed from it without\n" +
+	"                      # changing the configuration of the step.\n" +
+	"                      selector:\n" +
+	"                        # MatchLabels are the labels the secrets must have.\n" +
+	"                        matchLabels:\n" +
+	"                            \"\": \"\"\n" +
+	"                  # Critical protects the pods of the step from being evicted while they\n" +
+	"                  # run, e.g. when the build farm scales down, for steps which cannot be\n" +
+	"                  # interrupted without leaking resources, like cluster installation and\n" +
+	"                  # teardown.\n" +
+	"                  critical: false\n" +
 	"                  # Dependencies lists images which must be available before the test runs\n" +
 	"                  # and the environment variables which are used to expose their pull specs.\n" +
 	"                  dependencies:\n" +
-	"                    - # Env is the environment variable that the image's pull spec is exposed with\n" +
+	"                    - # Env is the environment variable that the image's pull spec is exposed with.\n" +
+	"                      # References to the parameters of the step, e.g. `$(PLATFORM)`, are expanded.\n" +
 	"                      env: ' '\n" +
 	"                      # Name is the tag or stream:tag that this dependency references\n" +
 	"                      name: ' '\n" +
@@ -705,6 +812,11 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"                      documentation: ' '\n" +
 	"                      # Name of the environment variable.\n" +
 	"                      name: ' '\n" +
+	"                  # FailOnRestart fails the step if a container of its pod is restarted\n" +
+	"                  # while it runs, e.g. by the kubelet, which violates the restart policy\n" +
+	"                  # of the pod. Restarts and warning events of the pod are reported in the\n" +
+	"                  # results of the step either way.\n" +
+	"                  fail_on_restart: false\n" +
 	"                  # From is the container image that will be used for this step.\n" +
 	"                  from: ' '\n" +
 	"                  # FromImage is a literal ImageStreamTag reference to use for this step.\n" +
@@ -714,19 +826,65 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"                    name: ' '\n" +
 	"                    namespace: ' '\n" +
 	"                    tag: ' '\n" +
+	"                  # Gang is the name of a group of consecutive steps of a phase which run\n" +
+	"                  # in parallel and must be scheduled together, e.g. the client and server\n" +
+	"                  # of a performance test. The pods of a gang are held back from the\n" +
+	"                  # scheduler until all of them were created. If they are not all created\n" +
+	"                  # in time, or a pod of the gang cannot be scheduled while its peers run,\n" +
+	"                  # all pods of the gang are stopped and the gang fails.\n" +
+	"                  # The changes the steps of a gang make to $SHARED_DIR are merged like\n" +
+	"                  # those of shards.\n" +
+	"                  gang: ' '\n" +
+	"                  # Gather marks a `post` step which collects information about the test.\n" +
+	"                  # Gather steps run after all other `post` steps, even if the test failed\n" +
+	"                  # or was cancelled, each with a timeout of its own (30 minutes unless\n" +
+	"                  # `timeout` is set). Their results are reported in a dedicated suite.\n" +
+	"                  gather: false\n" +
 	"                  # GracePeriod is how long the we will wait after sending SIGINT to send\n" +
 	"                  # SIGKILL when aborting a Step.\n" +
 	"                  grace_period: 0s\n" +
+	"                  # HostNetwork runs the step in the network namespace of the node, e.g.\n" +
+	"                  # for dual-stack tests which need the addresses of the node. Tests with\n" +
+	"                  # such steps must run on a cluster with the `host-network` capability.\n" +
+	"                  host_network: false\n" +
+	"                  # Job runs the step as a Kubernetes Job instead of a bare pod, for steps\n" +
+	"                  # which can safely be retried. The cluster replaces the pod of the step\n" +
+	"                  # when it fails or is lost, e.g. with its node, up to `backoff_limit`\n" +
+	"                  # times, and the step fails once the Job does.\n" +
+	"                  job:\n" +
+	"                    # TTLSecondsAfterFinished is the time after which the Job and its pods\n" +
+	"                    # are removed once the step finished. They are kept with the namespace\n" +
+	"                    # of the test if not set.\n" +
+	"                    ttl_seconds_after_finished: 0\n" +
 	"                  # Leases lists resources that should be acquired for the test.\n" +
 	"                  leases:\n" +
 	"                    - # Env is the environment variable that will contain the resource name.\n" +
 	"                      env: ' '\n" +
 	"                      # ResourceType is the type of resource that will be leased.\n" +
 	"                      resource_type: ' '\n" +
+	"                  # Manifests are Kubernetes objects, as a stream of YAML documents, which\n" +
+	"                  # are created on the cluster under test before the step runs and deleted\n" +
+	"                  # once it completes, e.g. the RBAC objects or CRDs the step requires.\n" +
+	"                  # Objects which exist are replaced. The cluster is accessed with the\n" +
+	"                  # `kubeconfig` in the shared directory. In the registry, this is the name\n" +
+	"                  # of a file next to the reference, e.g. `ipi-conf-manifests.yaml`.\n" +
+	"                  manifests: ' '\n" +
+	"                  # Mutex is the name of a lock held for the duration of the step, so that\n" +
+	"                  # no two steps with the same mutex run at the same time across all jobs,\n" +
+	"                  # e.g. for steps which modify a shared DNS zone. The lock is a lease of\n" +
+	"                  # type `<mutex>-mutex`, of which the lease server must hold exactly one\n" +
+	"                  # resource.\n" +
+	"                  mutex: ' '\n" +
 	"                  # NoKubeconfig determines that no $KUBECONFIG will exist in $SHARED_DIR,\n" +
 	"                  # so no local copy of it will be created for the step and if the step\n" +
 	"                  # creates one, it will not be propagated.\n" +
 	"                  no_kubeconfig: false\n" +
+	"                  # NodeCapabilities lists the capabilities the nodes running the step\n" +
+	"                  # must have, e.g. `kvm`, `sse4`, `large-disk` or `arm64`. They are\n" +
+	"                  # checked against the capabilities of the build farm before the test\n" +
+	"                  # runs, and the step is scheduled on the nodes which provide them.\n" +
+	"                  node_capabilities:\n" +
+	"                    - \"\"\n" +
 	"                  # Observers are the observers that should be running\n" +
 	"                  observers:\n" +
 	"                    - \"\"\n" +
@@ -735,6 +893,33 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"                  # flag is set to true in MultiStageTestConfiguration. This option is\n" +
 	"                  # applicable to `post` steps.\n" +
 	"                  optional_on_success: false\n" +
+	"                  # PreviousJobArtifacts exposes the artifacts of the last successful run\n" +
+	"                  # of the same job to the step, e.g. for performance or compatibility\n" +
+	"                  # tests which compare their results with a baseline.\n" +
+	"                  previous_job_artifacts:\n" +
+	"                    # Files are paths relative to the artifact directory of the previous run\n" +
+	"                    # which are fetched before the step starts and mounted in the directory\n" +
+	"                    # at `$PREVIOUS_JOB_ARTIFACTS_DIR`. Files which do not exist in the\n" +
+	"                    # previous run are skipped. Not available to steps which run on the\n" +
+	"                    # ephemeral cluster.\n" +
+	"                    files:\n" +
+	"                        - \"\"\n" +
+	"                  # PrivilegedApproval is the name of the entry of the central allowlist\n" +
+	"                  # which approves the privileged capabilities the step uses, like\n" +
+	"                  # `host_network` or `sysctls`. Where the allowlist is enforced, steps\n" +
+	"                  # which use such capabilities cannot be resolved without an approval\n" +
+	"                  # which lists the step and its capabilities.\n" +
+	"                  privileged_approval: ' '\n" +
+	"                  # ProvidesSharedFiles lists the files this step writes to $SHARED_DIR.\n" +
+	"                  # The step fails if any of them is missing when its commands succeed.\n" +
+	"                  # Steps requiring a file must run after a step providing it.\n" +
+	"                  provides_shared_files:\n" +
+	"                    - \"\"\n" +
+	"                  # RequiresSharedFiles lists the files which previous steps must have\n" +
+	"                  # written to $SHARED_DIR for this step to run. Their presence is\n" +
+	"                  # verified before the step is executed.\n" +
+	"                  requires_shared_files:\n" +
+	"                    - \"\"\n" +
 	"                  # Resources defines the resource requirements for the step.\n" +
 	"                  resources:\n" +
 	"                    # Limits are resource limits applied to an individual step in the job.\n" +
@@ -745,37 +930,557 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"                    # These are directly used in creating the Pods that execute the Job.\n" +
 	"                    requests:\n" +
 	"                        \"\": \"\"\n" +
-	"                  # RunAsScript defines if this step should be executed as a script mounted\n" +
-	"                  # in the test container instead of being executed directly via bash\n" +
+	"                  # Rollback is the name of a step which is executed immediately if this\n" +
+	"                  # step fails, before the test proceeds, to restore external state the\n" +
+	"                  # step modified, e.g. DNS records or image tags. It references a step\n" +
+	"                  # in the registry, which is resolved into the `rollbacks` of the test.\n" +
+	"                  rollback: ' '\n" +
+	"                  # RunAsScript defines if the commands of this step should be executed\n" +
+	"                  # verbatim, using their own interpreter line, instead of being prefixed\n" +
+	"                  # with the default bash preamble. The commands of every step are mounted\n" +
+	"                  # as an executable script in the test container.\n" +
 	"                  run_as_script: false\n" +
+	"                  # RunOnEphemeralCluster runs the step as a pod on the cluster under test\n" +
+	"                  # instead of the build farm, e.g. for tests which must run inside the\n" +
+	"                  # network of the cluster. The step is executed with the `kubeconfig` in\n" +
+	"                  # the shared directory, which must be provided by a previous step. The\n" +
+	"                  # shared directory is available to the step, but changes to it are not\n" +
+	"                  # propagated. The artifacts of the step are copied back once it exits.\n" +
+	"                  run_on_ephemeral_cluster: false\n" +
+	"                  # ScratchSize is the size of the scratch directory, e.g. `20Gi`. That\n" +
+	"                  # much ephemeral storage is requested for the step, so the directory is\n" +
+	"                  # guaranteed to have room for it instead of relying on the image having\n" +
+	"                  # a writable `/tmp` big enough.\n" +
+	"                  scratch_size: ' '\n" +
+	"                  # ShardCount is the number of pods the step is split into. All shards\n" +
+	"                  # run in parallel and receive the $SHARD_INDEX (starting from zero) and\n" +
+	"                  # $SHARD_TOTAL environment variables, which the step uses to select its\n" +
+	"                  # part of the work. The step fails if any of its shards fail. The\n" +
+	"                  # changes the shards make to $SHARED_DIR are merged, when several shards\n" +
+	"                  # write the same file the last one to finish wins.\n" +
+	"                  shard_count: 0\n" +
+	"                  # Sidecars are helper containers which run alongside the commands of\n" +
+	"                  # the step, e.g. local registries, tunnels or log forwarders. They are\n" +
+	"                  # terminated when the commands exit.\n" +
+	"                  sidecars:\n" +
+	"                    - # Commands is the command(s) that will be run inside the sidecar.\n" +
+	"                      commands: ' '\n" +
+	"                      # From is the container image tag used for the sidecar, resolved like\n" +
+	"                      # the `from` field of the step.\n" +
+	"                      from: ' '\n" +
+	"                      # Name is the name of the container, unique within the step.\n" +
+	"                      name: ' '\n" +
+	"                      # Readiness is an optional command executed repeatedly in the sidecar\n" +
+	"                      # until it succeeds. The commands of the step are only started once the\n" +
+	"                      # sidecars are ready.\n" +
+	"                      readiness: ' '\n" +
+	"                      # Resources defines the resource requirements for the sidecar.\n" +
+	"                      resources:\n" +
+	"                        # Limits are resource limits applied to an individual step in the job.\n" +
+	"                        # These are directly used in creating the Pods that execute the Job.\n" +
+	"                        limits:\n" +
+	"                            \"\": \"\"\n" +
+	"                        # Requests are resource requests applied to an individual step in the job.\n" +
+	"                        # These are directly used in creating the Pods that execute the Job.\n" +
+	"                        requests:\n" +
+	"                            \"\": \"\"\n" +
+	"                  # SplitLogs additionally writes the standard output and error of the\n" +
+	"                  # commands of the step to separate files in `$ARTIFACT_DIR/logs`, which\n" +
+	"                  # are rotated once they reach 10MiB. The output is then copied from\n" +
+	"                  # pipes: processes the commands start in the background and which keep\n" +
+	"                  # them open are not waited for longer than ten seconds after the commands\n" +
+	"                  # exit, and what they write afterwards is only in the container log.\n" +
+	"                  split_logs: false\n" +
+	"                  # StdinFrom feeds the standard input of the commands of the step from a\n" +
+	"                  # parameter of the step or a file in the shared directory, e.g. to drive\n" +
+	"                  # installers which prompt for a confirmation. The standard input of the\n" +
+	"                  # commands is empty if not set.\n" +
+	"                  stdin_from:\n" +
+	"                    # Parameter is the name of a parameter of the step, the value of which is\n" +
+	"                    # written to the standard input followed by a newline, unless it already\n" +
+	"                    # ends with one.\n" +
+	"                    parameter: ' '\n" +
+	"                    # SharedFile is the name of a file in the shared directory, the contents\n" +
+	"                    # of which are written to the standard input. The step fails if the file\n" +
+	"                    # does not exist when it starts.\n" +
+	"                    shared_file: ' '\n" +
+	"                  # Sysctls are set for the pod of the step. Sysctls which the kubelet does\n" +
+	"                  # not consider safe require a cluster with the `unsafe-sysctls`\n" +
+	"                  # capability.\n" +
+	"                  sysctls:\n" +
+	"                    - # Name of the parameter, e.g. `net.ipv6.conf.all.forwarding`.\n" +
+	"                      name: ' '\n" +
+	"                      # Value of the parameter.\n" +
+	"                      value: ' '\n" +
 	"                  # Timeout is how long the we will wait before aborting a job with SIGINT.\n" +
 	"                  timeout: 0s\n" +
+	"                  # Toolkit sources the shell function library of steps before their\n" +
+	"                  # commands, which provides `log`, `retry`, `wait_for` and `collect`. The\n" +
+	"                  # library is mounted in the step and its path is exposed as $CI_TOOLKIT,\n" +
+	"                  # for commands run as scripts to source it themselves.\n" +
+	"                  toolkit: false\n" +
+	"                  # Upgrade makes this a built-in step which upgrades the cluster under\n" +
+	"                  # test, instead of running `commands` in a container. The upgrade is\n" +
+	"                  # driven by ci-operator through the ClusterVersion of the cluster, using\n" +
+	"                  # the `kubeconfig` in the shared directory.\n" +
+	"                  upgrade:\n" +
+	"                    # Channel is set on the ClusterVersion before the upgrade, if not empty.\n" +
+	"                    channel: ' '\n" +
+	"                    # Force upgrades to the release even if its signature cannot be verified\n" +
+	"                    # or the cluster is not upgradeable.\n" +
+	"                    force: true\n" +
+	"                    # Release is the name of the release to upgrade to, e.g. `latest`.\n" +
+	"                    release: ' '\n" +
+	"                    # Rollback returns the cluster to the release it was running if the\n" +
+	"                    # upgrade fails. The step still fails.\n" +
+	"                    rollback: true\n" +
+	"                  # WaitFor lists conditions on objects of the cluster under test which\n" +
+	"                  # ci-operator waits for, in order, before the step starts, instead of the\n" +
+	"                  # step polling the cluster itself. The step fails if a condition is not\n" +
+	"                  # met in time. The cluster is accessed with the `kubeconfig` in the\n" +
+	"                  # shared directory.\n" +
+	"                  wait_for:\n" +
+	"                    - # APIVersion of the object, e.g. `apps/v1`.\n" +
+	"                      api_version: ' '\n" +
+	"                      # JSONPath is a template evaluated against the object, in the syntax of\n" +
+	"                      # `oc get -o jsonpath`, e.g.\n" +
+	"                      # `{.status.conditions[?(@.type==\"Available\")].status}`.\n" +
+	"                      jsonpath: ' '\n" +
+	"                      # Kind of the object, e.g. `Deployment`.\n" +
+	"                      kind: ' '\n" +
+	"                      # Name of the object.\n" +
+	"                      name: ' '\n" +
+	"                      # Namespace of the object, empty for cluster-scoped objects.\n" +
+	"                      namespace: ' '\n" +
+	"                      # Timeout is how long to wait for the condition, 10 minutes by default.\n" +
+	"                      timeout: 0s\n" +
+	"                      # Value is the output of the template which meets the condition. If\n" +
+	"                      # empty, any non-empty output meets it.\n" +
+	"                      value: ' '\n" +
+	"                  # Workdir is the path at which a writable scratch directory is mounted\n" +
+	"                  # for the step, which is also the working directory of its commands,\n" +
+	"                  # `/scratch` by default. The path is exposed as $SCRATCH_DIR. The\n" +
+	"                  # scratch directory is only added if this or `scratch_size` is set.\n" +
+	"                  # References to the parameters of the step, e.g. `$(PLATFORM)`, are\n" +
+	"                  # expanded.\n" +
+	"                  workdir: ' '\n" +
 	"            # Pre is the array of test steps run to set up the environment for the test.\n" +
 	"            pre:\n" +
-	"                - # As is the name of the LiteralTestStep.\n" +
+	"                - # Args are the arguments passed to Command.\n" +
+	"                  args:\n" +
+	"                    - \"\"\n" +
+	"                  # As is the name of the LiteralTestStep.\n" +
+	"                  as: ' '\n" +
+	"                  # BestEffort defines if this step should cause the job to fail when the\n" +
+	"                  # step fails. This only applies when AllowBestEffortPostSteps flag is set\n" +
+	"                  # to true in MultiStageTestConfiguration. This option is applicable to\n" +
+	"                  # `post` steps.\n" +
+	"                  best_effort: false\n" +
+	"                  # Burst lets the step use resources up to its limits, which are kept as\n" +
+	"                  # configured instead of being derived from its requests, so that bursty\n" +
+	"                  # steps can request what they use on average. Limits are capped by the\n" +
+	"                  # policy of the build farm and the CPU throttling of the step is recorded\n" +
+	"                  # in its metrics.\n" +
+	"                  burst: false\n" +
+	"                  # Cli is the (optional) name of the release from which the `oc` binary\n" +
+	"                  # will be injected into this step. If the step has a `KUBECTL_RETRY`\n" +
+	"                  # parameter, the binary is wrapped to retry calls which fail with a\n" +
+	"                  # refused connection or a 429 response, at most that many times.\n" +
+	"                  cli: ' '\n" +
+	"                  # Command is the executable run inside the image, as an alternative to\n" +
+	"                  # `commands` for images without bash, e.g. distroless or scratch-based\n" +
+	"                  # tool images. It is executed directly with Args, without a shell.\n" +
+	"                  command:\n" +
+	"                    - \"\"\n" +
+	"                  # Commands is the command(s) that will be run inside the image.\n" +
+	"                  commands: ' '\n" +
+	"                  # ConcurrencyGroup limits the number of instances of the step which run\n" +
+	"                  # concurrently across all jobs, e.g. for heavyweight steps which would\n" +
+	"                  # overload a shared service. A lease is acquired from the lease server\n" +
+	"                  # for the duration of the step, so the limit is the number of resources\n" +
+	"                  # of type `<group>-concurrency` configured in the server.\n" +
+	"                  concurrency_group: ' '\n" +
+	"                  # Credentials defines the credentials we'll mount into this step.\n" +
+	"                  credentials:\n" +
+	"                    - # MountPath is where the secret should be mounted. References to the\n" +
+	"                      # parameters of the step, e.g. `$(PLATFORM)`, are expanded.\n" +
+	"                      mount_path: ' '\n" +
+	"                      # Names is which source secret to mount.\n" +
+	"                      name: ' '\n" +
+	"                      # Namespace is where the source secret exists.\n" +
+	"                      namespace: ' '\n" +
+	"                      # Refresh propagates updates of the source secret to its copy in the\n" +
+	"                      # test namespace while the test runs, for credentials which are rotated\n" +
+	"                      # during long tests. Mounted files are updated by the kubelet shortly\n" +
+	"                      # after, so steps must read them again instead of caching their content.\n" +
+	"                      refresh: true\n" +
+	"                      # Selector mounts a collection of secrets instead of a single one: every\n" +
+	"                      # secret in the namespace which has the labels is mounted in a directory\n" +
+	"                      # named after it under the mount path. The collection is resolved when\n" +
+	"                      # the test starts, so secrets can be added to or removed from it without\n" +
+	"                      # changing the configuration of the step.\n" +
+	"                      selector:\n" +
+	"                        # MatchLabels are the labels the secrets must have.\n" +
+	"                        matchLabels:\n" +
+	"                            \"\": \"\"\n" +
+	"                  # Critical protects the pods of the step from being evicted while they\n" +
+	"                  # run, e.g. when the build farm scales down, for steps which cannot be\n" +
+	"                  # interrupted without leaking resources, like cluster installation and\n" +
+	"                  # teardown.\n" +
+	"                  critical: false\n" +
+	"                  # Dependencies lists images which must be available before the test runs\n" +
+	"                  # and the environment variables which are used to expose their pull specs.\n" +
+	"                  dependencies:\n" +
+	"                    - # Env is the environment variable that the image's pull spec is exposed with.\n" +
+	"                      # References to the parameters of the step, e.g. `$(PLATFORM)`, are expanded.\n" +
+	"                      env: ' '\n" +
+	"                      # Name is the tag or stream:tag that this dependency references\n" +
+	"                      name: ' '\n" +
+	"                  # DnsConfig for step's Pod.\n" +
+	"                  dnsConfig:\n" +
+	"                    # Nameservers is a list of IP addresses that will be used as DNS servers for the Pod\n" +
+	"                    nameservers:\n" +
+	"                        - \"\"\n" +
+	"                    # Searches is a list of DNS search domains for host-name lookup\n" +
+	"                    searches:\n" +
+	"                        - \"\"\n" +
+	"                  # Environment lists parameters that should be set by the test.\n" +
+	"                  env:\n" +
+	"                    - # Default if not set, optional, makes the parameter not required if set.\n" +
+	"                      default: \"\"\n" +
+	"                      # Documentation is a textual description of the parameter.\n" +
+	"                      documentation: ' '\n" +
+	"                      # Name of the environment variable.\n" +
+	"                      name: ' '\n" +
+	"                  # FailOnRestart fails the step if a container of its pod is restarted\n" +
+	"                  # while it runs, e.g. by the kubelet, which violates the restart policy\n" +
+	"                  # of the pod. Restarts and warning events of the pod are reported in the\n" +
+	"                  # results of the step either way.\n" +
+	"                  fail_on_restart: false\n" +
+	"                  # From is the container image that will be used for this step.\n" +
+	"                  from: ' '\n" +
+	"                  # FromImage is a literal ImageStreamTag reference to use for this step.\n" +
+	"                  from_image:\n" +
+	"                    # As is an optional string to use as the intermediate name for this reference.\n" +
+	"                    as: ' '\n" +
+	"                    name: ' '\n" +
+	"                    namespace: ' '\n" +
+	"                    tag: ' '\n" +
+	"                  # Gang is the name of a group of consecutive steps of a phase which run\n" +
+	"                  # in parallel and must be scheduled together, e.g. the client and server\n" +
+	"                  # of a performance test. The pods of a gang are held back from the\n" +
+	"                  # scheduler until all of them were created. If they are not all created\n" +
+	"                  # in time, or a pod of the gang cannot be scheduled while its peers run,\n" +
+	"                  # all pods of the gang are stopped and the gang fails.\n" +
+	"                  # The changes the steps of a gang make to $SHARED_DIR are merged like\n" +
+	"                  # those of shards.\n" +
+	"                  gang: ' '\n" +
+	"                  # Gather marks a `post` step which collects information about the test.\n" +
+	"                  # Gather steps run after all other `post` steps, even if the test failed\n" +
+	"                  # or was cancelled, each with a timeout of its own (30 minutes unless\n" +
+	"                  # `timeout` is set). Their results are reported in a dedicated suite.\n" +
+	"                  gather: false\n" +
+	"                  # GracePeriod is how long the we will wait after sending SIGINT to send\n" +
+	"                  # SIGKILL when aborting a Step.\n" +
+	"                  grace_period: 0s\n" +
+	"                  # HostNetwork runs the step in the network namespace of the node, e.g.\n" +
+	"                  # for dual-stack tests which need the addresses of the node. Tests with\n" +
+	"                  # such steps must run on a cluster with the `host-network` capability.\n" +
+	"                  host_network: false\n" +
+	"                  # Job runs the step as a Kubernetes Job instead of a bare pod, for steps\n" +
+	"                  # which can safely be retried. The cluster replaces the pod of the step\n" +
+	"                  # when it fails or is lost, e.g. with its node, up to `backoff_limit`\n" +
+	"                  # times, and the step fails once the Job does.\n" +
+	"                  job:\n" +
+	"                    # TTLSecondsAfterFinished is the time after which the Job and its pods\n" +
+	"                    # are removed once the step finished. They are kept with the namespace\n" +
+	"                    # of the test if not set.\n" +
+	"                    ttl_seconds_after_finished: 0\n" +
+	"                  # Leases lists resources that should be acquired for the test.\n" +
+	"                  leases:\n" +
+	"                    - # Env is the environment variable that will contain the resource name.\n" +
+	"                      env: ' '\n" +
+	"                      # ResourceType is the type of resource that will be leased.\n" +
+	"                      resource_type: ' '\n" +
+	"                  # Manifests are Kubernetes objects, as a stream of YAML documents, which\n" +
+	"                  # are created on the cluster under test before the step runs and deleted\n" +
+	"                  # once it completes, e.g. the RBAC objects or CRDs the step requires.\n" +
+	"                  # Objects which exist are replaced. The cluster is accessed with the\n" +
+	"                  # `kubeconfig` in the shared directory. In the registry, this is the name\n" +
+	"                  # of a file next to the reference, e.g. `ipi-conf-manifests.yaml`.\n" +
+	"                  manifests: ' '\n" +
+	"                  # Mutex is the name of a lock held for the duration of the step, so that\n" +
+	"                  # no two steps with the same mutex run at the same time across all jobs,\n" +
+	"                  # e.g. for steps which modify a shared DNS zone. The lock is a lease of\n" +
+	"                  # type `<mutex>-mutex`, of which the lease server must hold exactly one\n" +
+	"                  # resource.\n" +
+	"                  mutex: ' '\n" +
+	"                  # NoKubeconfig determines that no $KUBECONFIG will exist in $SHARED_DIR,\n" +
+	"                  # so no local copy of it will be created for the step and if the step\n" +
+	"                  # creates one, it will not be propagated.\n" +
+	"                  no_kubeconfig: false\n" +
+	"                  # NodeCapabilities lists the capabilities the nodes running the step\n" +
+	"                  # must have, e.g. `kvm`, `sse4`, `large-disk` or `arm64`. They are\n" +
+	"                  # checked against the capabilities of the build farm before the test\n" +
+	"                  # runs, and the step is scheduled on the nodes which provide them.\n" +
+	"                  node_capabilities:\n" +
+	"                    - \"\"\n" +
+	"                  # Observers are the observers that should be running\n" +
+	"                  observers:\n" +
+	"                    - \"\"\n" +
+	"                  # OptionalOnSuccess defines if this step should be skipped as long\n" +
+	"                  # as all `pre` and `test` steps were successful and AllowSkipOnSuccess\n" +
+	"                  # flag is set to true in MultiStageTestConfiguration. This option is\n" +
+	"                  # applicable to `post` steps.\n" +
+	"                  optional_on_success: false\n" +
+	"                  # PreviousJobArtifacts exposes the artifacts of the last successful run\n" +
+	"                  # of the same job to the step, e.g. for performance or compatibility\n" +
+	"                  # tests which compare their results with a baseline.\n" +
+	"                  previous_job_artifacts:\n" +
+	"                    # Files are paths relative to the artifact directory of the previous run\n" +
+	"                    # which are fetched before the step starts and mounted in the directory\n" +
+	"                    # at `$PREVIOUS_JOB_ARTIFACTS_DIR`. Files which do not exist in the\n" +
+	"                    # previous run are skipped. Not available to steps which run on the\n" +
+	"                    # ephemeral cluster.\n" +
+	"                    files:\n" +
+	"                        - \"\"\n" +
+	"                  # PrivilegedApproval is the name of the entry of the central allowlist\n" +
+	"                  # which approves the privileged capabilities the step uses, like\n" +
+	"                  # `host_network` or `sysctls`. Where the allowlist is enforced, steps\n" +
+	"                  # which use such capabilities cannot be resolved without an approval\n" +
+	"                  # which lists the step and its capabilities.\n" +
+	"                  privileged_approval: ' '\n" +
+	"                  # ProvidesSharedFiles lists the files this step writes to $SHARED_DIR.\n" +
+	"                  # The step fails if any of them is missing when its commands succeed.\n" +
+	"                  # Steps requiring a file must run after a step providing it.\n" +
+	"                  provides_shared_files:\n" +
+	"                    - \"\"\n" +
+	"                  # RequiresSharedFiles lists the files which previous steps must have\n" +
+	"                  # written to $SHARED_DIR for this step to run. Their presence is\n" +
+	"                  # verified before the step is executed.\n" +
+	"                  requires_shared_files:\n" +
+	"                    - \"\"\n" +
+	"                  # Resources defines the resource requirements for the step.\n" +
+	"                  resources:\n" +
+	"                    # Limits are resource limits applied to an individual step in the job.\n" +
+	"                    # These are directly used in creating the Pods that execute the Job.\n" +
+	"                    limits:\n" +
+	"                        \"\": \"\"\n" +
+	"                    # Requests are resource requests applied to an individual step in the job.\n" +
+	"                    # These are directly used in creating the Pods that execute the Job.\n" +
+	"                    requests:\n" +
+	"                        \"\": \"\"\n" +
+	"                  # Rollback is the name of a step which is executed immediately if this\n" +
+	"                  # step fails, before the test proceeds, to restore external state the\n" +
+	"                  # step modified, e.g. DNS records or image tags. It references a step\n" +
+	"                  # in the registry, which is resolved into the `rollbacks` of the test.\n" +
+	"                  rollback: ' '\n" +
+	"                  # RunAsScript defines if the commands of this step should be executed\n" +
+	"                  # verbatim, using their own interpreter line, instead of being prefixed\n" +
+	"                  # with the default bash preamble. The commands of every step are mounted\n" +
+	"                  # as an executable script in the test container.\n" +
+	"                  run_as_script: false\n" +
+	"                  # RunOnEphemeralCluster runs the step as a pod on the cluster under test\n" +
+	"                  # instead of the build farm, e.g. for tests which must run inside the\n" +
+	"                  # network of the cluster. The step is executed with the `kubeconfig` in\n" +
+	"                  # the shared directory, which must be provided by a previous step. The\n" +
+	"                  # shared directory is available to the step, but changes to it are not\n" +
+	"                  # propagated. The artifacts of the step are copied back once it exits.\n" +
+	"                  run_on_ephemeral_cluster: false\n" +
+	"                  # ScratchSize is the size of the scratch directory, e.g. `20Gi`. That\n" +
+	"                  # much ephemeral storage is requested for the step, so the directory is\n" +
+	"                  # guaranteed to have room for it instead of relying on the image having\n" +
+	"                  # a writable `/tmp` big enough.\n" +
+	"                  scratch_size: ' '\n" +
+	"                  # ShardCount is the number of pods the step is split into. All shards\n" +
+	"                  # run in parallel and receive the $SHARD_INDEX (starting from zero) and\n" +
+	"                  # $SHARD_TOTAL environment variables, which the step uses to select its\n" +
+	"                  # part of the work. The step fails if any of its shards fail. The\n" +
+	"                  # changes the shards make to $SHARED_DIR are merged, when several shards\n" +
+	"                  # write the same file the last one to finish wins.\n" +
+	"                  shard_count: 0\n" +
+	"                  # Sidecars are helper containers which run alongside the commands of\n" +
+	"                  # the step, e.g. local registries, tunnels or log forwarders. They are\n" +
+	"                  # terminated when the commands exit.\n" +
+	"                  sidecars:\n" +
+	"                    - # Commands is the command(s) that will be run inside the sidecar.\n" +
+	"                      commands: ' '\n" +
+	"                      # From is the container image tag used for the sidecar, resolved like\n" +
+	"                      # the `from` field of the step.\n" +
+	"                      from: ' '\n" +
+	"                      # Name is the name of the container, unique within the step.\n" +
+	"                      name: ' '\n" +
+	"                      # Readiness is an optional command executed repeatedly in the sidecar\n" +
+	"                      # until it succeeds. The commands of the step are only started once the\n" +
+	"                      # sidecars are ready.\n" +
+	"                      readiness: ' '\n" +
+	"                      # Resources defines the resource requirements for the sidecar.\n" +
+	"                      resources:\n" +
+	"                        # Limits are resource limits applied to an individual step in the job.\n" +
+	"                        # These are directly used in creating the Pods that execute the Job.\n" +
+	"                        limits:\n" +
+	"                            \"\": \"\"\n" +
+	"                        # Requests are resource requests applied to an individual step in the job.\n" +
+	"                        # These are directly used in creating the Pods that execute the Job.\n" +
+	"                        requests:\n" +
+	"                            \"\": \"\"\n" +
+	"                  # SplitLogs additionally writes the standard output and error of the\n" +
+	"                  # commands of the step to separate files in `$ARTIFACT_DIR/logs`, which\n" +
+	"                  # are rotated once they reach 10MiB. The output is then copied from\n" +
+	"                  # pipes: processes the commands start in the background and which keep\n" +
+	"                  # them open are not waited for longer than ten seconds after the commands\n" +
+	"                  # exit, and what they write afterwards is only in the container log.\n" +
+	"                  split_logs: false\n" +
+	"                  # StdinFrom feeds the standard input of the commands of the step from a\n" +
+	"                  # parameter of the step or a file in the shared directory, e.g. to drive\n" +
+	"                  # installers which prompt for a confirmation. The standard input of the\n" +
+	"                  # commands is empty if not set.\n" +
+	"                  stdin_from:\n" +
+	"                    # Parameter is the name of a parameter of the step, the value of which is\n" +
+	"                    # written to the standard input followed by a newline, unless it already\n" +
+	"                    # ends with one.\n" +
+	"                    parameter: ' '\n" +
+	"                    # SharedFile is the name of a file in the shared directory, the contents\n" +
+	"                    # of which are written to the standard input. The step fails if the file\n" +
+	"                    # does not exist when it starts.\n" +
+	"                    shared_file: ' '\n" +
+	"                  # Sysctls are set for the pod of the step. Sysctls which the kubelet does\n" +
+	"                  # not consider safe require a cluster with the `unsafe-sysctls`\n" +
+	"                  # capability.\n" +
+	"                  sysctls:\n" +
+	"                    - # Name of the parameter, e.g. `net.ipv6.conf.all.forwarding`.\n" +
+	"                      name: ' '\n" +
+	"                      # Value of the parameter.\n" +
+	"                      value: ' '\n" +
+	"                  # Timeout is how long the we will wait before aborting a job with SIGINT.\n" +
+	"                  timeout: 0s\n" +
+	"                  # Toolkit sources the shell function library of steps before their\n" +
+	"                  # commands, which provides `log`, `retry`, `wait_for` and `collect`. The\n" +
+	"                  # library is mounted in the step and its path is exposed as $CI_TOOLKIT,\n" +
+	"                  # for commands run as scripts to source it themselves.\n" +
+	"                  toolkit: false\n" +
+	"                  # Upgrade makes this a built-in step which upgrades the cluster under\n" +
+	"                  # test, instead of running `commands` in a container. The upgrade is\n" +
+	"                  # driven by ci-operator through the ClusterVersion of the cluster, using\n" +
+	"                  # the `kubeconfig` in the shared directory.\n" +
+	"                  upgrade:\n" +
+	"                    # Channel is set on the ClusterVersion before the upgrade, if not empty.\n" +
+	"                    channel: ' '\n" +
+	"                    # Force upgrades to the release even if its signature cannot be verified\n" +
+	"                    # or the cluster is not upgradeable.\n" +
+	"                    force: true\n" +
+	"                    # Release is the name of the release to upgrade to, e.g. `latest`.\n" +
+	"                    release: ' '\n" +
+	"                    # Rollback returns the cluster to the release it was running if the\n" +
+	"                    # upgrade fails. The step still fails.\n" +
+	"                    rollback: true\n" +
+	"                  # WaitFor lists conditions on objects of the cluster under test which\n" +
+	"                  # ci-operator waits for, in order, before the step starts, instead of the\n" +
+	"                  # step polling the cluster itself. The step fails if a condition is not\n" +
+	"                  # met in time. The cluster is accessed with the `kubeconfig` in the\n" +
+	"                  # shared directory.\n" +
+	"                  wait_for:\n" +
+	"                    - # APIVersion of the object, e.g. `apps/v1`.\n" +
+	"                      api_version: ' '\n" +
+	"                      # JSONPath is a template evaluated against the object, in the syntax of\n" +
+	"                      # `oc get -o jsonpath`, e.g.\n" +
+	"                      # `{.status.conditions[?(@.type==\"Available\")].status}`.\n" +
+	"                      jsonpath: ' '\n" +
+	"                      # Kind of the object, e.g. `Deployment`.\n" +
+	"                      kind: ' '\n" +
+	"                      # Name of the object.\n" +
+	"                      name: ' '\n" +
+	"                      # Namespace of the object, empty for cluster-scoped objects.\n" +
+	"                      namespace: ' '\n" +
+	"                      # Timeout is how long to wait for the condition, 10 minutes by default.\n" +
+	"                      timeout: 0s\n" +
+	"                      # Value is the output of the template which meets the condition. If\n" +
+	"                      # empty, any non-empty output meets it.\n" +
+	"                      value: ' '\n" +
+	"                  # Workdir is the path at which a writable scratch directory is mounted\n" +
+	"                  # for the step, which is also the working directory of its commands,\n" +
+	"                  # `/scratch` by default. The path is exposed as $SCRATCH_DIR. The\n" +
+	"                  # scratch directory is only added if this or `scratch_size` is set.\n" +
+	"                  # References to the parameters of the step, e.g. `$(PLATFORM)`, are\n" +
+	"                  # expanded.\n" +
+	"                  workdir: ' '\n" +
+	"            # ReuseClusterFrom is the name of another test of the configuration whose\n" +
+	"            # cluster this test runs against instead of provisioning one. The test\n" +
+	"            # starts with a snapshot of the shared directory of the other test, taken\n" +
+	"            # once its `pre` steps have installed the cluster, and the other test only\n" +
+	"            # runs its `post` steps once this test has finished. Cluster profiles and\n" +
+	"            # claims cannot be used.\n" +
+	"            reuse_cluster_from: ' '\n" +
+	"            # Rollbacks are the steps referenced by the `rollback` of other steps,\n" +
+	"            # executed only when those steps fail.\n" +
+	"            rollbacks:\n" +
+	"                - # Args are the arguments passed to Command.\n" +
+	"                  args:\n" +
+	"                    - \"\"\n" +
+	"                  # As is the name of the LiteralTestStep.\n" +
 	"                  as: ' '\n" +
 	"                  # BestEffort defines if this step should cause the job to fail when the\n" +
 	"                  # step fails. This only applies when AllowBestEffortPostSteps flag is set\n" +
 	"                  # to true in MultiStageTestConfiguration. This option is applicable to\n" +
 	"                  # `post` steps.\n" +
 	"                  best_effort: false\n" +
+	"                  # Burst lets the step use resources up to its limits, which are kept as\n" +
+	"                  # configured instead of being derived from its requests, so that bursty\n" +
+	"                  # steps can request what they use on average. Limits are capped by the\n" +
+	"                  # policy of the build farm and the CPU throttling of the step is recorded\n" +
+	"                  # in its metrics.\n" +
+	"                  burst: false\n" +
 	"                  # Cli is the (optional) name of the release from which the `oc` binary\n" +
-	"                  # will be injected into this step.\n" +
+	"                  # will be injected into this step. If the step has a `KUBECTL_RETRY`\n" +
+	"                  # parameter, the binary is wrapped to retry calls which fail with a\n" +
+	"                  # refused connection or a 429 response, at most that many times.\n" +
 	"                  cli: ' '\n" +
+	"                  # Command is the executable run inside the image, as an alternative to\n" +
+	"                  # `commands` for images without bash, e.g. distroless or scratch-based\n" +
+	"                  # tool images. It is executed directly with Args, without a shell.\n" +
+	"                  command:\n" +
+	"                    - \"\"\n" +
 	"                  # Commands is the command(s) that will be run inside the image.\n" +
 	"                  commands: ' '\n" +
+	"                  # ConcurrencyGroup limits the number of instances of the step which run\n" +
+	"                  # concurrently across all jobs, e.g. for heavyweight steps which would\n" +
+	"                  # overload a shared service. A lease is acquired from the lease server\n" +
+	"                  # for the duration of the step, so the limit is the number of resources\n" +
+	"                  # of type `<group>-concurrency` configured in the server.\n" +
+	"                  concurrency_group: ' '\n" +
 	"                  # Credentials defines the credentials we'll mount into this step.\n" +
 	"                  credentials:\n" +
-	"                    - # MountPath is where the secret should be mounted.\n" +
+	"                    - # MountPath is where the secret should be mounted. References to the\n" +
+	"                      # parameters of the step, e.g. `$(PLATFORM)`, are expanded.\n" +
 	"                      mount_path: ' '\n" +
 	"                      # Names is which source secret to mount.\n" +
 	"                      name: ' '\n" +
 	"                      # Namespace is where the source secret exists.\n" +
 	"                      namespace: ' '\n" +
+	"                      # Refresh propagates updates of the source secret to its copy in the\n" +
+	"                      # test namespace while the test runs, for credentials which are rotated\n" +
+	"                      # during long tests. Mounted files are updated by the kubelet shortly\n" +
+	"                      # after, so steps must read them again instead of caching their content.\n" +
+	"                      refresh: true\n" +
+	"                      # Selector mounts a collection of secrets instead of a single one: every\n" +
+	"                      # secret in the namespace which has the labels is mounted in a directory\n" +
+	"                      # named after it under the mount path. The collection is resolved when\n" +
+	"                      # the test starts, so secrets can be added to or removed from it without\n" +
+	"                      # changing the configuration of the step.\n" +
+	"                      selector:\n" +
+	"                        # MatchLabels are the labels the secrets must have.\n" +
+	"                        matchLabels:\n" +
+	"                            \"\": \"\"\n" +
+	"                  # Critical protects the pods of the step from being evicted while they\n" +
+	"                  # run, e.g. when the build farm scales down, for steps which cannot be\n" +
+	"                  # interrupted without leaking resources, like cluster installation and\n" +
+	"                  # teardown.\n" +
+	"                  critical: false\n" +
 	"                  # Dependencies lists images which must be available before the test runs\n" +
 	"                  # and the environment variables which are used to expose their pull specs.\n" +
 	"                  dependencies:\n" +
-	"                    - # Env is the environment variable that the image's pull spec is exposed with\n" +
+	"                    - # Env is the environment variable that the image's pull spec is exposed with.\n" +
+	"                      # References to the parameters of the step, e.g. `$(PLATFORM)`, are expanded.\n" +
 	"                      env: ' '\n" +
 	"                      # Name is the tag or stream:tag that this dependency references\n" +
 	"                      name: ' '\n" +
@@ -795,6 +1500,11 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"                      documentation: ' '\n" +
 	"                      # Name of the environment variable.\n" +
 	"                      name: ' '\n" +
+	"                  # FailOnRestart fails the step if a container of its pod is restarted\n" +
+	"                  # while it runs, e.g. by the kubelet, which violates the restart policy\n" +
+	"                  # of the pod. Restarts and warning events of the pod are reported in the\n" +
+	"                  # results of the step either way.\n" +
+	"                  fail_on_restart: false\n" +
 	"                  # From is the container image that will be used for this step.\n" +
 	"                  from: ' '\n" +
 	"                  # FromImage is a literal ImageStreamTag reference to use for this step.\n" +
@@ -804,19 +1514,65 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"                    name: ' '\n" +
 	"                    namespace: ' '\n" +
 	"                    tag: ' '\n" +
+	"                  # Gang is the name of a group of consecutive steps of a phase which run\n" +
+	"                  # in parallel and must be scheduled together, e.g. the client and server\n" +
+	"                  # of a performance test. The pods of a gang are held back from the\n" +
+	"                  # scheduler until all of them were created. If they are not all created\n" +
+	"                  # in time, or a pod of the gang cannot be scheduled while its peers run,\n" +
+	"                  # all pods of the gang are stopped and the gang fails.\n" +
+	"                  # The changes the steps of a gang make to $SHARED_DIR are merged like\n" +
+	"                  # those of shards.\n" +
+	"                  gang: ' '\n" +
+	"                  # Gather marks a `post` step which collects information about the test.\n" +
+	"                  # Gather steps run after all other `post` steps, even if the test failed\n" +
+	"                  # or was cancelled, each with a timeout of its own (30 minutes unless\n" +
+	"                  # `timeout` is set). Their results are reported in a dedicated suite.\n" +
+	"                  gather: false\n" +
 	"                  # GracePeriod is how long the we will wait after sending SIGINT to send\n" +
 	"                  # SIGKILL when aborting a Step.\n" +
 	"                  grace_period: 0s\n" +
+	"                  # HostNetwork runs the step in the network namespace of the node, e.g.\n" +
+	"                  # for dual-stack tests which need the addresses of the node. Tests with\n" +
+	"                  # such steps must run on a cluster with the `host-network` capability.\n" +
+	"                  host_network: false\n" +
+	"                  # Job runs the step as a Kubernetes Job instead of a bare pod, for steps\n" +
+	"                  # which can safely be retried. The cluster replaces the pod of the step\n" +
+	"                  # when it fails or is lost, e.g. with its node, up to `backoff_limit`\n" +
+	"                  # times, and the step fails once the Job does.\n" +
+	"                  job:\n" +
+	"                    # TTLSecondsAfterFinished is the time after which the Job and its pods\n" +
+	"                    # are removed once the step finished. They are kept with the namespace\n" +
+	"                    # of the test if not set.\n" +
+	"                    ttl_seconds_after_finished: 0\n" +
 	"                  # Leases lists resources that should be acquired for the test.\n" +
 	"                  leases:\n" +
 	"                    - # Env is the environment variable that will contain the resource name.\n" +
 	"                      env: ' '\n" +
 	"                      # ResourceType is the type of resource that will be leased.\n" +
 	"                      resource_type: ' '\n" +
+	"                  # Manifests are Kubernetes objects, as a stream of YAML documents, which\n" +
+	"                  # are created on the cluster under test before the step runs and deleted\n" +
+	"                  # once it completes, e.g. the RBAC objects or CRDs the step requires.\n" +
+	"                  # Objects which exist are replaced. The cluster is accessed with the\n" +
+	"                  # `kubeconfig` in the shared directory. In the registry, this is the name\n" +
+	"                  # of a file next to the reference, e.g. `ipi-conf-manifests.yaml`.\n" +
+	"                  manifests: ' '\n" +
+	"                  # Mutex is the name of a lock held for the duration of the step, so that\n" +
+	"                  # no two steps with the same mutex run at the same time across all jobs,\n" +
+	"                  # e.g. for steps which modify a shared DNS zone. The lock is a lease of\n" +
+	"                  # type `<mutex>-mutex`, of which the lease server must hold exactly one\n" +
+	"                  # resource.\n" +
+	"                  mutex: ' '\n" +
 	"                  # NoKubeconfig determines that no $KUBECONFIG will exist in $SHARED_DIR,\n" +
 	"                  # so no local copy of it will be created for the step and if the step\n" +
 	"                  # creates one, it will not be propagated.\n" +
 	"                  no_kubeconfig: false\n" +
+	"                  # NodeCapabilities lists the capabilities the nodes running the step\n" +
+	"                  # must have, e.g. `kvm`, `sse4`, `large-disk` or `arm64`. They are\n" +
+	"                  # checked against the capabilities of the build farm before the test\n" +
+	"                  # runs, and the step is scheduled on the nodes which provide them.\n" +
+	"                  node_capabilities:\n" +
+	"                    - \"\"\n" +
 	"                  # Observers are the observers that should be running\n" +
 	"                  observers:\n" +
 	"                    - \"\"\n" +
@@ -825,6 +1581,33 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"                  # flag is set to true in MultiStageTestConfiguration. This option is\n" +
 	"                  # applicable to `post` steps.\n" +
 	"                  optional_on_success: false\n" +
+	"                  # PreviousJobArtifacts exposes the artifacts of the last successful run\n" +
+	"                  # of the same job to the step, e.g. for performance or compatibility\n" +
+	"                  # tests which compare their results with a baseline.\n" +
+	"                  previous_job_artifacts:\n" +
+	"                    # Files are paths relative to the artifact directory of the previous run\n" +
+	"                    # which are fetched before the step starts and mounted in the directory\n" +
+	"                    # at `$PREVIOUS_JOB_ARTIFACTS_DIR`. Files which do not exist in the\n" +
+	"                    # previous run are skipped. Not available to steps which run on the\n" +
+	"                    # ephemeral cluster.\n" +
+	"                    files:\n" +
+	"                        - \"\"\n" +
+	"                  # PrivilegedApproval is the name of the entry of the central allowlist\n" +
+	"                  # which approves the privileged capabilities the step uses, like\n" +
+	"                  # `host_network` or `sysctls`. Where the allowlist is enforced, steps\n" +
+	"                  # which use such capabilities cannot be resolved without an approval\n" +
+	"                  # which lists the step and its capabilities.\n" +
+	"                  privileged_approval: ' '\n" +
+	"                  # ProvidesSharedFiles lists the files this step writes to $SHARED_DIR.\n" +
+	"                  # The step fails if any of them is missing when its commands succeed.\n" +
+	"                  # Steps requiring a file must run after a step providing it.\n" +
+	"                  provides_shared_files:\n" +
+	"                    - \"\"\n" +
+	"                  # RequiresSharedFiles lists the files which previous steps must have\n" +
+	"                  # written to $SHARED_DIR for this step to run. Their presence is\n" +
+	"                  # verified before the step is executed.\n" +
+	"                  requires_shared_files:\n" +
+	"                    - \"\"\n" +
 	"                  # Resources defines the resource requirements for the step.\n" +
 	"                  resources:\n" +
 	"                    # Limits are resource limits applied to an individual step in the job.\n" +
@@ -835,37 +1618,217 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"                    # These are directly used in creating the Pods that execute the Job.\n" +
 	"                    requests:\n" +
 	"                        \"\": \"\"\n" +
-	"                  # RunAsScript defines if this step should be executed as a script mounted\n" +
-	"                  # in the test container instead of being executed directly via bash\n" +
+	"                  # Rollback is the name of a step which is executed immediately if this\n" +
+	"                  # step fails, before the test proceeds, to restore external state the\n" +
+	"                  # step modified, e.g. DNS records or image tags. It references a step\n" +
+	"                  # in the registry, which is resolved into the `rollbacks` of the test.\n" +
+	"                  rollback: ' '\n" +
+	"                  # RunAsScript defines if the commands of this step should be executed\n" +
+	"                  # verbatim, using their own interpreter line, instead of being prefixed\n" +
+	"                  # with the default bash preamble. The commands of every step are mounted\n" +
+	"                  # as an executable script in the test container.\n" +
 	"                  run_as_script: false\n" +
+	"                  # RunOnEphemeralCluster runs the step as a pod on the cluster under test\n" +
+	"                  # instead of the build farm, e.g. for tests which must run inside the\n" +
+	"                  # network of the cluster. The step is executed with the `kubeconfig` in\n" +
+	"                  # the shared directory, which must be provided by a previous step. The\n" +
+	"                  # shared directory is available to the step, but changes to it are not\n" +
+	"                  # propagated. The artifacts of the step are copied back once it exits.\n" +
+	"                  run_on_ephemeral_cluster: false\n" +
+	"                  # ScratchSize is the size of the scratch directory, e.g. `20Gi`. That\n" +
+	"                  # much ephemeral storage is requested for the step, so the directory is\n" +
+	"                  # guaranteed to have room for it instead of relying on the image having\n" +
+	"                  # a writable `/tmp` big enough.\n" +
+	"                  scratch_size: ' '\n" +
+	"                  # ShardCount is the number of pods the step is split into. All shards\n" +
+	"                  # run in parallel and receive the $SHARD_INDEX (starting from zero) and\n" +
+	"                  # $SHARD_TOTAL environment variables, which the step uses to select its\n" +
+	"                  # part of the work. The step fails if any of its shards fail. The\n" +
+	"                  # changes the shards make to $SHARED_DIR are merged, when several shards\n" +
+	"                  # write the same file the last one to finish wins.\n" +
+	"                  shard_count: 0\n" +
+	"                  # Sidecars are helper containers which run alongside the commands of\n" +
+	"                  # the step, e.g. local registries, tunnels or log forwarders. They are\n" +
+	"                  # terminated when the commands exit.\n" +
+	"                  sidecars:\n" +
+	"                    - # Commands is the command(s) that will be run inside the sidecar.\n" +
+	"                      commands: ' '\n" +
+	"                      # From is the container image tag used for the sidecar, resolved like\n" +
+	"                      # the `from` field of the step.\n" +
+	"                      from: ' '\n" +
+	"                      # Name is the name of the container, unique within the step.\n" +
+	"                      name: ' '\n" +
+	"                      # Readiness is an optional command executed repeatedly in the sidecar\n" +
+	"                      # until it succeeds. The commands of the step are only started once the\n" +
+	"                      # sidecars are ready.\n" +
+	"                      readiness: ' '\n" +
+	"                      # Resources defines the resource requirements for the sidecar.\n" +
+	"                      resources:\n" +
+	"                        # Limits are resource limits applied to an individual step in the job.\n" +
+	"                        # These are directly used in creating the Pods that execute the Job.\n" +
+	"                        limits:\n" +
+	"                            \"\": \"\"\n" +
+	"                        # Requests are resource requests applied to an individual step in the job.\n" +
+	"                        # These are directly used in creating the Pods that execute the Job.\n" +
+	"                        requests:\n" +
+	"                            \"\": \"\"\n" +
+	"                  # SplitLogs additionally writes the standard output and error of the\n" +
+	"                  # commands of the step to separate files in `$ARTIFACT_DIR/logs`, which\n" +
+	"                  # are rotated once they reach 10MiB. The output is then copied from\n" +
+	"                  # pipes: processes the commands start in the background and which keep\n" +
+	"                  # them open are not waited for longer than ten seconds after the commands\n" +
+	"                  # exit, and what they write afterwards is only in the container log.\n" +
+	"                  split_logs: false\n" +
+	"                  # StdinFrom feeds the standard input of the commands of the step from a\n" +
+	"                  # parameter of the step or a file in the shared directory, e.g. to drive\n" +
+	"                  # installers which prompt for a confirmation. The standard input of the\n" +
+	"                  # commands is empty if not set.\n" +
+	"                  stdin_from:\n" +
+	"                    # Parameter is the name of a parameter of the step, the value of which is\n" +
+	"                    # written to the standard input followed by a newline, unless it already\n" +
+	"                    # ends with one.\n" +
+	"                    parameter: ' '\n" +
+	"                    # SharedFile is the name of a file in the shared directory, the contents\n" +
+	"                    # of which are written to the standard input. The step fails if the file\n" +
+	"                    # does not exist when it starts.\n" +
+	"                    shared_file: ' '\n" +
+	"                  # Sysctls are set for the pod of the step. Sysctls which the kubelet does\n" +
+	"                  # not consider safe require a cluster with the `unsafe-sysctls`\n" +
+	"                  # capability.\n" +
+	"                  sysctls:\n" +
+	"                    - # Name of the parameter, e.g. `net.ipv6.conf.all.forwarding`.\n" +
+	"                      name: ' '\n" +
+	"                      # Value of the parameter.\n" +
+	"                      value: ' '\n" +
 	"                  # Timeout is how long the we will wait before aborting a job with SIGINT.\n" +
 	"                  timeout: 0s\n" +
+	"                  # Toolkit sources the shell function library of steps before their\n" +
+	"                  # commands, which provides `log`, `retry`, `wait_for` and `collect`. The\n" +
+	"                  # library is mounted in the step and its path is exposed as $CI_TOOLKIT,\n" +
+	"                  # for commands run as scripts to source it themselves.\n" +
+	"                  toolkit: false\n" +
+	"                  # Upgrade makes this a built-in step which upgrades the cluster under\n" +
+	"                  # test, instead of running `commands` in a container. The upgrade is\n" +
+	"                  # driven by ci-operator through the ClusterVersion of the cluster, using\n" +
+	"                  # the `kubeconfig` in the shared directory.\n" +
+	"                  upgrade:\n" +
+	"                    # Channel is set on the ClusterVersion before the upgrade, if not empty.\n" +
+	"                    channel: ' '\n" +
+	"                    # Force upgrades to the release even if its signature cannot be verified\n" +
+	"                    # or the cluster is not upgradeable.\n" +
+	"                    force: true\n" +
+	"                    # Release is the name of the release to upgrade to, e.g. `latest`.\n" +
+	"                    release: ' '\n" +
+	"                    # Rollback returns the cluster to the release it was running if the\n" +
+	"                    # upgrade fails. The step still fails.\n" +
+	"                    rollback: true\n" +
+	"                  # WaitFor lists conditions on objects of the cluster under test which\n" +
+	"                  # ci-operator waits for, in order, before the step starts, instead of the\n" +
+	"                  # step polling the cluster itself. The step fails if a condition is not\n" +
+	"                  # met in time. The cluster is accessed with the `kubeconfig` in the\n" +
+	"                  # shared directory.\n" +
+	"                  wait_for:\n" +
+	"                    - # APIVersion of the object, e.g. `apps/v1`.\n" +
+	"                      api_version: ' '\n" +
+	"                      # JSONPath is a template evaluated against the object, in the syntax of\n" +
+	"                      # `oc get -o jsonpath`, e.g.\n" +
+	"                      # `{.status.conditions[?(@.type==\"Available\")].status}`.\n" +
+	"                      jsonpath: ' '\n" +
+	"                      # Kind of the object, e.g. `Deployment`.\n" +
+	"                      kind: ' '\n" +
+	"                      # Name of the object.\n" +
+	"                      name: ' '\n" +
+	"                      # Namespace of the object, empty for cluster-scoped objects.\n" +
+	"                      namespace: ' '\n" +
+	"                      # Timeout is how long to wait for the condition, 10 minutes by default.\n" +
+	"                      timeout: 0s\n" +
+	"                      # Value is the output of the template which meets the condition. If\n" +
+	"                      # empty, any non-empty output meets it.\n" +
+	"                      value: ' '\n" +
+	"                  # Workdir is the path at which a writable scratch directory is mounted\n" +
+	"                  # for the step, which is also the working directory of its commands,\n" +
+	"                  # `/scratch` by default. The path is exposed as $SCRATCH_DIR. The\n" +
+	"                  # scratch directory is only added if this or `scratch_size` is set.\n" +
+	"                  # References to the parameters of the step, e.g. `$(PLATFORM)`, are\n" +
+	"                  # expanded.\n" +
+	"                  workdir: ' '\n" +
+	"            # StepReleases maps the names of steps to the release they use in place\n" +
+	"            # of `latest`: their dependencies on `release:latest` and on images of the\n" +
+	"            # `stable` stream, and the $RELEASE_IMAGE_LATEST given to them with a\n" +
+	"            # cluster profile, refer to the named release instead. This allows the\n" +
+	"            # steps of one test to use different payloads, e.g. for the management\n" +
+	"            # and the hosted clusters of a HyperShift test.\n" +
+	"            step_releases:\n" +
+	"                \"\": \"\"\n" +
 	"            # Test is the array of test steps that define the actual test.\n" +
 	"            test:\n" +
-	"                - # As is the name of the LiteralTestStep.\n" +
+	"                - # Args are the arguments passed to Command.\n" +
+	"                  args:\n" +
+	"                    - \"\"\n" +
+	"                  # As is the name of the LiteralTestStep.\n" +
 	"                  as: ' '\n" +
 	"                  # BestEffort defines if this step should cause the job to fail when the\n" +
 	"                  # step fails. This only applies when AllowBestEffortPostSteps flag is set\n" +
 	"                  # to true in MultiStageTestConfiguration. This option is applicable to\n" +
 	"                  # `post` steps.\n" +
 	"                  best_effort: false\n" +
+	"                  # Burst lets the step use resources up to its limits, which are kept as\n" +
+	"                  # configured instead of being derived from its requests, so that bursty\n" +
+	"                  # steps can request what they use on average. Limits are capped by the\n" +
+	"                  # policy of the build farm and the CPU throttling of the step is recorded\n" +
+	"                  # in its metrics.\n" +
+	"                  burst: false\n" +
 	"                  # Cli is the (optional) name of the release from which the `oc` binary\n" +
-	"                  # will be injected into this step.\n" +
+	"                  # will be injected into this step. If the step has a `KUBECTL_RETRY`\n" +
+	"                  # parameter, the binary is wrapped to retry calls which fail with a\n" +
+	"                  # refused connection or a 429 response, at most that many times.\n" +
 	"                  cli: ' '\n" +
+	"                  # Command is the executable run inside the image, as an alternative to\n" +
+	"                  # `commands` for images without bash, e.g. distroless or scratch-based\n" +
+	"                  # tool images. It is executed directly with Args, without a shell.\n" +
+	"                  command:\n" +
+	"                    - \"\"\n" +
 	"                  # Commands is the command(s) that will be run inside the image.\n" +
 	"                  commands: ' '\n" +
+	"                  # ConcurrencyGroup limits the number of instances of the step which run\n" +
+	"                  # concurrently across all jobs, e.g. for heavyweight steps which would\n" +
+	"                  # overload a shared service. A lease is acquired from the lease server\n" +
+	"                  # for the duration of the step, so the limit is the number of resources\n" +
+	"                  # of type `<group>-concurrency` configured in the server.\n" +
+	"                  concurrency_group: ' '\n" +
 	"                  # Credentials defines the credentials we'll mount into this step.\n" +
 	"                  credentials:\n" +
-	"                    - # MountPath is where the secret should be mounted.\n" +
+	"                    - # MountPath is where the secret should be mounted. References to the\n" +
+	"                      # parameters of the step, e.g. `$(PLATFORM)`, are expanded.\n" +
 	"                      mount_path: ' '\n" +
 	"                      # Names is which source secret to mount.\n" +
 	"                      name: ' '\n" +
 	"                      # Namespace is where the source secret exists.\n" +
 	"                      namespace: ' '\n" +
+	"                      # Refresh propagates updates of the source secret to its copy in the\n" +
+	"                      # test namespace while the test runs, for credentials which are rotated\n" +
+	"                      # during long tests. Mounted files are updated by the kubelet shortly\n" +
+	"                      # after, so steps must read them again instead of caching their content.\n" +
+	"                      refresh: true\n" +
+	"                      # Selector mounts a collection of secrets instead of a single one: every\n" +
+	"                      # secret in the namespace which has the labels is mounted in a directory\n" +
+	"                      # named after it under the mount path. The collection is resolved when\n" +
+	"                      # the test starts, so secrets can be added to or removed from it without\n" +
+	"                      # changing the configuration of the step.\n" +
+	"                      selector:\n" +
+	"                        # MatchLabels are the labels the secrets must have.\n" +
+	"                        matchLabels:\n" +
+	"                            \"\": \"\"\n" +
+	"                  # Critical protects the pods of the step from being evicted while they\n" +
+	"                  # run, e.g. when the build farm scales down, for steps which cannot be\n" +
+	"                  # interrupted without leaking resources, like cluster installation and\n" +
+	"                  # teardown.\n" +
+	"                  critical: false\n" +
 	"                  # Dependencies lists images which must be available before the test runs\n" +
 	"                  # and the environment variables which are used to expose their pull specs.\n" +
 	"                  dependencies:\n" +
-	"                    - # Env is the environment variable that the image's pull spec is exposed with\n" +
+	"                    - # Env is the environment variable that the image's pull spec is exposed with.\n" +
+	"                      # References to the parameters of the step, e.g. `$(PLATFORM)`, are expanded.\n" +
 	"                      env: ' '\n" +
 	"                      # Name is the tag or stream:tag that this dependency references\n" +
 	"                      name: ' '\n" +
@@ -885,6 +1848,11 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"                      documentation: ' '\n" +
 	"                      # Name of the environment variable.\n" +
 	"                      name: ' '\n" +
+	"                  # FailOnRestart fails the step if a container of its pod is restarted\n" +
+	"                  # while it runs, e.g. by the kubelet, which violates the restart policy\n" +
+	"                  # of the pod. Restarts and warning events of the pod are reported in the\n" +
+	"                  # results of the step either way.\n" +
+	"                  fail_on_restart: false\n" +
 	"                  # From is the container image that will be used for this step.\n" +
 	"                  from: ' '\n" +
 	"                  # FromImage is a literal ImageStreamTag reference to use for this step.\n" +
@@ -894,19 +1862,65 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"                    name: ' '\n" +
 	"                    namespace: ' '\n" +
 	"                    tag: ' '\n" +
+	"                  # Gang is the name of a group of consecutive steps of a phase which run\n" +
+	"                  # in parallel and must be scheduled together, e.g. the client and server\n" +
+	"                  # of a performance test. The pods of a gang are held back from the\n" +
+	"                  # scheduler until all of them were created. If they are not all created\n" +
+	"                  # in time, or a pod of the gang cannot be scheduled while its peers run,\n" +
+	"                  # all pods of the gang are stopped and the gang fails.\n" +
+	"                  # The changes the steps of a gang make to $SHARED_DIR are merged like\n" +
+	"                  # those of shards.\n" +
+	"                  gang: ' '\n" +
+	"                  # Gather marks a `post` step which collects information about the test.\n" +
+	"                  # Gather steps run after all other `post` steps, even if the test failed\n" +
+	"                  # or was cancelled, each with a timeout of its own (30 minutes unless\n" +
+	"                  # `timeout` is set). Their results are reported in a dedicated suite.\n" +
+	"                  gather: false\n" +
 	"                  # GracePeriod is how long the we will wait after sending SIGINT to send\n" +
 	"                  # SIGKILL when aborting a Step.\n" +
 	"                  grace_period: 0s\n" +
+	"                  # HostNetwork runs the step in the network namespace of the node, e.g.\n" +
+	"                  # for dual-stack tests which need the addresses of the node. Tests with\n" +
+	"                  # such steps must run on a cluster with the `host-network` capability.\n" +
+	"                  host_network: false\n" +
+	"                  # Job runs the step as a Kubernetes Job instead of a bare pod, for steps\n" +
+	"                  # which can safely be retried. The cluster replaces the pod of the step\n" +
+	"                  # when it fails or is lost, e.g. with its node, up to `backoff_limit`\n" +
+	"                  # times, and the step fails once the Job does.\n" +
+	"                  job:\n" +
+	"                    # TTLSecondsAfterFinished is the time after which the Job and its pods\n" +
+	"                    # are removed once the step finished. They are kept with the namespace\n" +
+	"                    # of the test if not set.\n" +
+	"                    ttl_seconds_after_finished: 0\n" +
 	"                  # Leases lists resources that should be acquired for the test.\n" +
 	"                  leases:\n" +
 	"                    - # Env is the environment variable that will contain the resource name.\n" +
 	"                      env: ' '\n" +
 	"                      # ResourceType is the type of resource that will be leased.\n" +
 	"                      resource_type: ' '\n" +
+	"                  # Manifests are Kubernetes objects, as a stream of YAML documents, which\n" +
+	"                  # are created on the cluster under test before the step runs and deleted\n" +
+	"                  # once it completes, e.g. the RBAC objects or CRDs the step requires.\n" +
+	"                  # Objects which exist are replaced. The cluster is accessed with the\n" +
+	"                  # `kubeconfig` in the shared directory. In the registry, this is the name\n" +
+	"                  # of a file next to the reference, e.g. `ipi-conf-manifests.yaml`.\n" +
+	"                  manifests: ' '\n" +
+	"                  # Mutex is the name of a lock held for the duration of the step, so that\n" +
+	"                  # no two steps with the same mutex run at the same time across all jobs,\n" +
+	"                  # e.g. for steps which modify a shared DNS zone. The lock is a lease of\n" +
+	"                  # type `<mutex>-mutex`, of which the lease server must hold exactly one\n" +
+	"                  # resource.\n" +
+	"                  mutex: ' '\n" +
 	"                  # NoKubeconfig determines that no $KUBECONFIG will exist in $SHARED_DIR,\n" +
 	"                  # so no local copy of it will be created for the step and if the step\n" +
 	"                  # creates one, it will not be propagated.\n" +
 	"                  no_kubeconfig: false\n" +
+	"                  # NodeCapabilities lists the capabilities the nodes running the step\n" +
+	"                  # must have, e.g. `kvm`, `sse4`, `large-disk` or `arm64`. They are\n" +
+	"                  # checked against the capabilities of the build farm before the test\n" +
+	"                  # runs, and the step is scheduled on the nodes which provide them.\n" +
+	"                  node_capabilities:\n" +
+	"                    - \"\"\n" +
 	"                  # Observers are the observers that should be running\n" +
 	"                  observers:\n" +
 	"                    - \"\"\n" +
@@ -915,6 +1929,33 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"                  # flag is set to true in MultiStageTestConfiguration. This option is\n" +
 	"                  # applicable to `post` steps.\n" +
 	"                  optional_on_success: false\n" +
+	"                  # PreviousJobArtifacts exposes the artifacts of the last successful run\n" +
+	"                  # of the same job to the step, e.g. for performance or compatibility\n" +
+	"                  # tests which compare their results with a baseline.\n" +
+	"                  previous_job_artifacts:\n" +
+	"                    # Files are paths relative to the artifact directory of the previous run\n" +
+	"                    # which are fetched before the step starts and mounted in the directory\n" +
+	"                    # at `$PREVIOUS_JOB_ARTIFACTS_DIR`. Files which do not exist in the\n" +
+	"                    # previous run are skipped. Not available to steps which run on the\n" +
+	"                    # ephemeral cluster.\n" +
+	"                    files:\n" +
+	"                        - \"\"\n" +
+	"                  # PrivilegedApproval is the name of the entry of the central allowlist\n" +
+	"                  # which approves the privileged capabilities the step uses, like\n" +
+	"                  # `host_network` or `sysctls`. Where the allowlist is enforced, steps\n" +
+	"                  # which use such capabilities cannot be resolved without an approval\n" +
+	"                  # which lists the step and its capabilities.\n" +
+	"                  privileged_approval: ' '\n" +
+	"                  # ProvidesSharedFiles lists the files this step writes to $SHARED_DIR.\n" +
+	"                  # The step fails if any of them is missing when its commands succeed.\n" +
+	"                  # Steps requiring a file must run after a step providing it.\n" +
+	"                  provides_shared_files:\n" +
+	"                    - \"\"\n" +
+	"                  # RequiresSharedFiles lists the files which previous steps must have\n" +
+	"                  # written to $SHARED_DIR for this step to run. Their presence is\n" +
+	"                  # verified before the step is executed.\n" +
+	"                  requires_shared_files:\n" +
+	"                    - \"\"\n" +
 	"                  # Resources defines the resource requirements for the step.\n" +
 	"                  resources:\n" +
 	"                    # Limits are resource limits applied to an individual step in the job.\n" +
@@ -925,11 +1966,140 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"                    # These are directly used in creating the Pods that execute the Job.\n" +
 	"                    requests:\n" +
 	"                        \"\": \"\"\n" +
-	"                  # RunAsScript defines if this step should be executed as a script mounted\n" +
-	"                  # in the test container instead of being executed directly via bash\n" +
+	"                  # Rollback is the name of a step which is executed immediately if this\n" +
+	"                  # step fails, before the test proceeds, to restore external state the\n" +
+	"                  # step modified, e.g. DNS records or image tags. It references a step\n" +
+	"                  # in the registry, which is resolved into the `rollbacks` of the test.\n" +
+	"                  rollback: ' '\n" +
+	"                  # RunAsScript defines if the commands of this step should be executed\n" +
+	"                  # verbatim, using their own interpreter line, instead of being prefixed\n" +
+	"                  # with the default bash preamble. The commands of every step are mounted\n" +
+	"                  # as an executable script in the test container.\n" +
 	"                  run_as_script: false\n" +
+	"                  # RunOnEphemeralCluster runs the step as a pod on the cluster under test\n" +
+	"                  # instead of the build farm, e.g. for tests which must run inside the\n" +
+	"                  # network of the cluster. The step is executed with the `kubeconfig` in\n" +
+	"                  # the shared directory, which must be provided by a previous step. The\n" +
+	"                  # shared directory is available to the step, but changes to it are not\n" +
+	"                  # propagated. The artifacts of the step are copied back once it exits.\n" +
+	"                  run_on_ephemeral_cluster: false\n" +
+	"                  # ScratchSize is the size of the scratch directory, e.g. `20Gi`. That\n" +
+	"                  # much ephemeral storage is requested for the step, so the directory is\n" +
+	"                  # guaranteed to have room for it instead of relying on the image having\n" +
+	"                  # a writable `/tmp` big enough.\n" +
+	"                  scratch_size: ' '\n" +
+	"                  # ShardCount is the number of pods the step is split into. All shards\n" +
+	"                  # run in parallel and receive the $SHARD_INDEX (starting from zero) and\n" +
+	"                  # $SHARD_TOTAL environment variables, which the step uses to select its\n" +
+	"                  # part of the work. The step fails if any of its shards fail. The\n" +
+	"                  # changes the shards make to $SHARED_DIR are merged, when several shards\n" +
+	"                  # write the same file the last one to finish wins.\n" +
+	"                  shard_count: 0\n" +
+	"                  # Sidecars are helper containers which run alongside the commands of\n" +
+	"                  # the step, e.g. local registries, tunnels or log forwarders. They are\n" +
+	"                  # terminated when the commands exit.\n" +
+	"                  sidecars:\n" +
+	"                    - # Commands is the command(s) that will be run inside the sidecar.\n" +
+	"                      commands: ' '\n" +
+	"                      # From is the container image tag used for the sidecar, resolved like\n" +
+	"                      # the `from` field of the step.\n" +
+	"                      from: ' '\n" +
+	"                      # Name is the name of the container, unique within the step.\n" +
+	"                      name: ' '\n" +
+	"                      # Readiness is an optional command executed repeatedly in the sidecar\n" +
+	"                      # until it succeeds. The commands of the step are only started once the\n" +
+	"                      # sidecars are ready.\n" +
+	"                      readiness: ' '\n" +
+	"                      # Resources defines the resource requirements for the sidecar.\n" +
+	"                      resources:\n" +
+	"                        # Limits are resource limits applied to an individual step in the job.\n" +
+	"                        # These are directly used in creating the Pods that execute the Job.\n" +
+	"                        limits:\n" +
+	"                            \"\": \"\"\n" +
+	"                        # Requests are resource requests applied to an individual step in the job.\n" +
+	"                        # These are directly used in creating the Pods that execute the Job.\n" +
+	"                        requests:\n" +
+	"                            \"\": \"\"\n" +
+	"                  # SplitLogs additionally writes the standard output and error of the\n" +
+	"                  # commands of the step to separate files in `$ARTIFACT_DIR/logs`, which\n" +
+	"                  # are rotated once they reach 10MiB. The output is then copied from\n" +
+	"                  # pipes: processes the commands start in the background and which keep\n" +
+	"                  # them open are not waited for longer than ten seconds after the commands\n" +
+	"                  # exit, and what they write afterwards is only in the container log.\n" +
+	"                  split_logs: false\n" +
+	"                  # StdinFrom feeds the standard input of the commands of the step from a\n" +
+	"                  # parameter of the step or a file in the shared directory, e.g. to drive\n" +
+	"                  # installers which prompt for a confirmation. The standard input of the\n" +
+	"                  # commands is empty if not set.\n" +
+	"                  stdin_from:\n" +
+	"                    # Parameter is the name of a parameter of the step, the value of which is\n" +
+	"                    # written to the standard input followed by a newline, unless it already\n" +
+	"                    # ends with one.\n" +
+	"                    parameter: ' '\n" +
+	"                    # SharedFile is the name of a file in the shared directory, the contents\n" +
+	"                    # of which are written to the standard input. The step fails if the file\n" +
+	"                    # does not exist when it starts.\n" +
+	"                    shared_file: ' '\n" +
+	"                  # Sysctls are set for the pod of the step. Sysctls which the kubelet does\n" +
+	"                  # not consider safe require a cluster with the `unsafe-sysctls`\n" +
+	"                  # capability.\n" +
+	"                  sysctls:\n" +
+	"                    - # Name of the parameter, e.g. `net.ipv6.conf.all.forwarding`.\n" +
+	"                      name: ' '\n" +
+	"                      # Value of the parameter.\n" +
+	"                      value: ' '\n" +
 	"                  # Timeout is how long the we will wait before aborting a job with SIGINT.\n" +
 	"                  timeout: 0s\n" +
+	"                  # Toolkit sources the shell function library of steps before their\n" +
+	"                  # commands, which provides `log`, `retry`, `wait_for` and `collect`. The\n" +
+	"                  # library is mounted in the step and its path is exposed as $CI_TOOLKIT,\n" +
+	"                  # for commands run as scripts to source it themselves.\n" +
+	"                  toolkit: false\n" +
+	"                  # Upgrade makes this a built-in step which upgrades the cluster under\n" +
+	"                  # test, instead of running `commands` in a container. The upgrade is\n" +
+	"                  # driven by ci-operator through the ClusterVersion of the cluster, using\n" +
+	"                  # the `kubeconfig` in the shared directory.\n" +
+	"                  upgrade:\n" +
+	"                    # Channel is set on the ClusterVersion before the upgrade, if not empty.\n" +
+	"                    channel: ' '\n" +
+	"                    # Force upgrades to the release even if its signature cannot be verified\n" +
+	"                    # or the cluster is not upgradeable.\n" +
+	"                    force: true\n" +
+	"                    # Release is the name of the release to upgrade to, e.g. `latest`.\n" +
+	"                    release: ' '\n" +
+	"                    # Rollback returns the cluster to the release it was running if the\n" +
+	"                    # upgrade fails. The step still fails.\n" +
+	"                    rollback: true\n" +
+	"                  # WaitFor lists conditions on objects of the cluster under test which\n" +
+	"                  # ci-operator waits for, in order, before the step starts, instead of the\n" +
+	"                  # step polling the cluster itself. The step fails if a condition is not\n" +
+	"                  # met in time. The cluster is accessed with the `kubeconfig` in the\n" +
+	"                  # shared directory.\n" +
+	"                  wait_for:\n" +
+	"                    - # APIVersion of the object, e.g. `apps/v1`.\n" +
+	"                      api_version: ' '\n" +
+	"                      # JSONPath is a template evaluated against the object, in the syntax of\n" +
+	"                      # `oc get -o jsonpath`, e.g.\n" +
+	"                      # `{.status.conditions[?(@.type==\"Available\")].status}`.\n" +
+	"                      jsonpath: ' '\n" +
+	"                      # Kind of the object, e.g. `Deployment`.\n" +
+	"                      kind: ' '\n" +
+	"                      # Name of the object.\n" +
+	"                      name: ' '\n" +
+	"                      # Namespace of the object, empty for cluster-scoped objects.\n" +
+	"                      namespace: ' '\n" +
+	"                      # Timeout is how long to wait for the condition, 10 minutes by default.\n" +
+	"                      timeout: 0s\n" +
+	"                      # Value is the output of the template which meets the condition. If\n" +
+	"                      # empty, any non-empty output meets it.\n" +
+	"                      value: ' '\n" +
+	"                  # Workdir is the path at which a writable scratch directory is mounted\n" +
+	"                  # for the step, which is also the working directory of its commands,\n" +
+	"                  # `/scratch` by default. The path is exposed as $SCRATCH_DIR. The\n" +
+	"                  # scratch directory is only added if this or `scratch_size` is set.\n" +
+	"                  # References to the parameters of the step, e.g. `$(PLATFORM)`, are\n" +
+	"                  # expanded.\n" +
+	"                  workdir: ' '\n" +
 	"            # Override job timeout\n" +
 	"            timeout: 0s\n" +
 	"        # MinimumInterval to wait between two runs of the job. Consecutive\n" +
@@ -1003,8 +2173,29 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"            # all previous `pre` and `test` steps were successful. The given step must explicitly\n" +
 	"            # ask for being skipped by setting the OptionalOnSuccess flag to true.\n" +
 	"            allow_skip_on_success: false\n" +
+	"            # ClusterHealthGate configures checks of the health of the cluster under\n" +
+	"            # test which must pass after the `pre` phase before `test` steps are run.\n" +
+	"            cluster_health_gate:\n" +
+	"                # Checks are the checks which must pass. All checks are run when empty.\n" +
+	"                checks:\n" +
+	"                    - \"\"\n" +
+	"                # Timeout is how long to wait for the checks to pass, defaults to 10m.\n" +
+	"                timeout: 0s\n" +
 	"            # ClusterProfile defines the profile/cloud provider for end-to-end test steps.\n" +
+	"            # Steps receive a $CLUSTER_NAME unique to the test and short enough for\n" +
+	"            # the platform and, when it is known, the $BASE_DOMAIN of the profile.\n" +
+	"            # Once the `pre` steps have completed, the following steps receive the\n" +
+	"            # $CONSOLE_URL, $API_URL and $OCP_VERSION of the cluster which was\n" +
+	"            # installed, also as the values of the step parameters of these names.\n" +
+	"            # Steps mount a copy of the profile owned by the test, in which the keys\n" +
+	"            # of the `<test>-cluster-profile-overrides` secret, if it exists, replace\n" +
+	"            # those of the profile.\n" +
 	"            cluster_profile: ' '\n" +
+	"            # ContainerOnly declares that the steps of the test do not interact with\n" +
+	"            # any cluster. Steps are not given a kubeconfig, run with reduced\n" +
+	"            # permissions in the test namespace and have a default timeout of one\n" +
+	"            # hour. Cluster profiles, claims, observers and leases cannot be used.\n" +
+	"            container_only: false\n" +
 	"            # Dependencies holds override values for dependency parameters.\n" +
 	"            dependencies:\n" +
 	"                \"\": \"\"\n" +
@@ -1012,6 +2203,25 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"            # be used with rehearsals. Otherwise, the overrides should be passed in as parameters to ci-operator.\n" +
 	"            dependency_overrides:\n" +
 	"                \"\": \"\"\n" +
+	"            # DisruptionMonitor configures an availability monitor which runs in\n" +
+	"            # ci-operator during the `test` phase.\n" +
+	"            disruption_monitor:\n" +
+	"                # Interval is the time between two requests to a target, defaults to 1s.\n" +
+	"                interval: 0s\n" +
+	"                # Targets are the endpoints which are polled.\n" +
+	"                targets:\n" +
+	"                    - # InsecureSkipTLSVerify disables the verification of the certificate\n" +
+	"                      # served by the target, which is necessary when polling endpoints of\n" +
+	"                      # ephemeral clusters with self-signed certificates.\n" +
+	"                      insecure_skip_tls_verify: true\n" +
+	"                      # MaxDisruption is the total unavailability tolerated during the `test`\n" +
+	"                      # phase. When unset, disruption is only reported.\n" +
+	"                      max_disruption: 0s\n" +
+	"                      # Name identifies the target in the report.\n" +
+	"                      name: ' '\n" +
+	"                      # URL is requested with GET on every poll. The target is considered\n" +
+	"                      # available when the request succeeds with a status code below 500.\n" +
+	"                      url: ' '\n" +
 	"            # DnsConfig for step's Pod.\n" +
 	"            dnsConfig:\n" +
 	"                # Nameservers is a list of IP addresses that will be used as DNS servers for the Pod\n" +
@@ -1023,6 +2233,17 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"            # Environment has the values of parameters for the steps.\n" +
 	"            env:\n" +
 	"                \"\": \"\"\n" +
+	"            # EnvironmentFromFile has the values of parameters for the steps which are\n" +
+	"            # read from files, relative to the directory of the configuration file.\n" +
+	"            # The files are read when the configuration is loaded and their contents\n" +
+	"            # are added to Environment.\n" +
+	"            env_from_file:\n" +
+	"                \"\": \"\"\n" +
+	"            # FeatureSet is the OpenShift feature set the cluster under test is\n" +
+	"            # installed with, e.g. TechPreviewNoUpgrade. It is exposed to the steps\n" +
+	"            # as $FEATURE_SET and as the value of their parameter of that name, and\n" +
+	"            # is validated against the version of the `latest` release.\n" +
+	"            feature_set: ' '\n" +
 	"            # Leases lists resources that should be acquired for the test.\n" +
 	"            leases:\n" +
 	"                - # Env is the environment variable that will contain the resource name.\n" +
@@ -1042,19 +2263,42 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"            # execution if previous Pre and Test steps passed.\n" +
 	"            post:\n" +
 	"                # LiteralTestStep is a full test step definition.\n" +
-	"                - as: ' '\n" +
+	"                - # Alias disambiguates steps whose names would otherwise conflict, e.g.\n" +
+	"                  # when two chains contribute steps with the same name. For a reference,\n" +
+	"                  # it replaces the name of the step. For a chain, it is prepended to the\n" +
+	"                  # names of all the steps of the chain, e.g. `upgrade-gather-must-gather`\n" +
+	"                  # for the step `gather-must-gather` of a chain with the alias `upgrade`.\n" +
+	"                  alias: ' '\n" +
+	"                  args:\n" +
+	"                    # LiteralTestStep is a full test step definition.\n" +
+	"                    - \"\"\n" +
+	"                  as: ' '\n" +
 	"                  best_effort: false\n" +
+	"                  burst: false\n" +
 	"                  # Chain is the name of a step chain reference.\n" +
 	"                  chain: \"\"\n" +
 	"                  # Cli is the (optional) name of the release from which the `oc` binary\n" +
-	"                  # will be injected into this step.\n" +
+	"                  # will be injected into this step. If the step has a `KUBECTL_RETRY`\n" +
+	"                  # parameter, the binary is wrapped to retry calls which fail with a\n" +
+	"                  # refused connection or a 429 response, at most that many times.\n" +
 	"                  cli: ' '\n" +
+	"                  command:\n" +
+	"                    # LiteralTestStep is a full test step definition.\n" +
+	"                    - \"\"\n" +
 	"                  commands: ' '\n" +
+	"                  concurrency_group: ' '\n" +
 	"                  credentials:\n" +
 	"                    # LiteralTestStep is a full test step definition.\n" +
 	"                    - mount_path: ' '\n" +
 	"                      name: ' '\n" +
 	"                      namespace: ' '\n" +
+	"                      refresh: true\n" +
+	"                      selector:\n" +
+	"                        # LiteralTestStep is a full test step definition.\n" +
+	"                        matchLabels:\n" +
+	"                            # LiteralTestStep is a full test step definition.\n" +
+	"                            \"\": \"\"\n" +
+	"                  critical: false\n" +
 	"                  dependencies:\n" +
 	"                    # LiteralTestStep is a full test step definition.\n" +
 	"                    - env: ' '\n" +
@@ -1072,6 +2316,7 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"                    - default: \"\"\n" +
 	"                      documentation: ' '\n" +
 	"                      name: ' '\n" +
+	"                  fail_on_restart: false\n" +
 	"                  from: ' '\n" +
 	"                  from_image:\n" +
 	"                    # LiteralTestStep is a full test step definition.\n" +
@@ -1079,19 +2324,44 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"                    name: ' '\n" +
 	"                    namespace: ' '\n" +
 	"                    tag: ' '\n" +
+	"                  gang: ' '\n" +
+	"                  gather: false\n" +
 	"                  grace_period: 0s\n" +
+	"                  host_network: false\n" +
+	"                  job:\n" +
+	"                    # LiteralTestStep is a full test step definition.\n" +
+	"                    ttl_seconds_after_finished: 0\n" +
 	"                  leases:\n" +
 	"                    # LiteralTestStep is a full test step definition.\n" +
 	"                    - env: ' '\n" +
 	"                      resource_type: ' '\n" +
+	"                  manifests: ' '\n" +
+	"                  mutex: ' '\n" +
 	"                  no_kubeconfig: false\n" +
+	"                  node_capabilities:\n" +
+	"                    # LiteralTestStep is a full test step definition.\n" +
+	"                    - \"\"\n" +
 	"                  observers:\n" +
 	"                    # LiteralTestStep is a full test step definition.\n" +
 	"                    - \"\"\n" +
 	"                  optional_on_success: false\n" +
+	"                  previous_job_artifacts:\n" +
+	"                    # LiteralTestStep is a full test step definition.\n" +
+	"                    files:\n" +
+	"                        # LiteralTestStep is a full test step definition.\n" +
+	"                        - \"\"\n" +
+	"                  privileged_approval: ' '\n" +
+	"                  provides_shared_files:\n" +
+	"                    # LiteralTestStep is a full test step definition.\n" +
+	"                    - \"\"\n" +
 	"                  # Reference is the name of a step reference.\n" +
 	"                  ref: \"\"\n" +
-	"                  # Resources defines the resource requirements for the step.\n" +
+	"                  # RequiresSharedFiles lists the files which previous steps must have\n" +
+	"                  # written to $SHARED_DIR for this step to run. Their presence is\n" +
+	"                  # verified before the step is executed.\n" +
+	"                  requires_shared_files:\n" +
+	"                    # LiteralTestStep is a full test step definition.\n" +
+	"                    - \"\"\n" +
 	"                  resources:\n" +
 	"                    # LiteralTestStep is a full test step definition.\n" +
 	"                    limits:\n" +
@@ -1100,24 +2370,91 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"                    requests:\n" +
 	"                        # LiteralTestStep is a full test step definition.\n" +
 	"                        \"\": \"\"\n" +
+	"                  rollback: ' '\n" +
 	"                  run_as_script: false\n" +
+	"                  run_on_ephemeral_cluster: false\n" +
+	"                  scratch_size: ' '\n" +
+	"                  shard_count: 0\n" +
+	"                  sidecars:\n" +
+	"                    # LiteralTestStep is a full test step definition.\n" +
+	"                    - commands: ' '\n" +
+	"                      from: ' '\n" +
+	"                      name: ' '\n" +
+	"                      readiness: ' '\n" +
+	"                      resources:\n" +
+	"                        # LiteralTestStep is a full test step definition.\n" +
+	"                        limits:\n" +
+	"                            # LiteralTestStep is a full test step definition.\n" +
+	"                            \"\": \"\"\n" +
+	"                        requests:\n" +
+	"                            # LiteralTestStep is a full test step definition.\n" +
+	"                            \"\": \"\"\n" +
+	"                  split_logs: false\n" +
+	"                  stdin_from:\n" +
+	"                    # LiteralTestStep is a full test step definition.\n" +
+	"                    parameter: ' '\n" +
+	"                    shared_file: ' '\n" +
+	"                  sysctls:\n" +
+	"                    # LiteralTestStep is a full test step definition.\n" +
+	"                    - name: ' '\n" +
+	"                      value: ' '\n" +
 	"                  timeout: 0s\n" +
+	"                  toolkit: false\n" +
+	"                  upgrade:\n" +
+	"                    # LiteralTestStep is a full test step definition.\n" +
+	"                    channel: ' '\n" +
+	"                    force: true\n" +
+	"                    release: ' '\n" +
+	"                    rollback: true\n" +
+	"                  wait_for:\n" +
+	"                    # LiteralTestStep is a full test step definition.\n" +
+	"                    - api_version: ' '\n" +
+	"                      jsonpath: ' '\n" +
+	"                      kind: ' '\n" +
+	"                      name: ' '\n" +
+	"                      namespace: ' '\n" +
+	"                      timeout: 0s\n" +
+	"                      value: ' '\n" +
+	"                  workdir: ' '\n" +
 	"            # Pre is the array of test steps run to set up the environment for the test.\n" +
 	"            pre:\n" +
 	"                # LiteralTestStep is a full test step definition.\n" +
-	"                - as: ' '\n" +
+	"                - # Alias disambiguates steps whose names would otherwise conflict, e.g.\n" +
+	"                  # when two chains contribute steps with the same name. For a reference,\n" +
+	"                  # it replaces the name of the step. For a chain, it is prepended to the\n" +
+	"                  # names of all the steps of the chain, e.g. `upgrade-gather-must-gather`\n" +
+	"                  # for the step `gather-must-gather` of a chain with the alias `upgrade`.\n" +
+	"                  alias: ' '\n" +
+	"                  args:\n" +
+	"                    # LiteralTestStep is a full test step definition.\n" +
+	"                    - \"\"\n" +
+	"                  as: ' '\n" +
 	"                  best_effort: false\n" +
+	"                  burst: false\n" +
 	"                  # Chain is the name of a step chain reference.\n" +
 	"                  chain: \"\"\n" +
 	"                  # Cli is the (optional) name of the release from which the `oc` binary\n" +
-	"                  # will be injected into this step.\n" +
+	"                  # will be injected into this step. If the step has a `KUBECTL_RETRY`\n" +
+	"                  # parameter, the binary is wrapped to retry calls which fail with a\n" +
+	"                  # refused connection or a 429 response, at most that many times.\n" +
 	"                  cli: ' '\n" +
+	"                  command:\n" +
+	"                    # LiteralTestStep is a full test step definition.\n" +
+	"                    - \"\"\n" +
 	"                  commands: ' '\n" +
+	"                  concurrency_group: ' '\n" +
 	"                  credentials:\n" +
 	"                    # LiteralTestStep is a full test step definition.\n" +
 	"                    - mount_path: ' '\n" +
 	"                      name: ' '\n" +
 	"                      namespace: ' '\n" +
+	"                      refresh: true\n" +
+	"                      selector:\n" +
+	"                        # LiteralTestStep is a full test step definition.\n" +
+	"                        matchLabels:\n" +
+	"                            # LiteralTestStep is a full test step definition.\n" +
+	"                            \"\": \"\"\n" +
+	"                  critical: false\n" +
 	"                  dependencies:\n" +
 	"                    # LiteralTestStep is a full test step definition.\n" +
 	"                    - env: ' '\n" +
@@ -1135,6 +2472,7 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"                    - default: \"\"\n" +
 	"                      documentation: ' '\n" +
 	"                      name: ' '\n" +
+	"                  fail_on_restart: false\n" +
 	"                  from: ' '\n" +
 	"                  from_image:\n" +
 	"                    # LiteralTestStep is a full test step definition.\n" +
@@ -1142,19 +2480,44 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"                    name: ' '\n" +
 	"                    namespace: ' '\n" +
 	"                    tag: ' '\n" +
+	"                  gang: ' '\n" +
+	"                  gather: false\n" +
 	"                  grace_period: 0s\n" +
+	"                  host_network: false\n" +
+	"                  job:\n" +
+	"                    # LiteralTestStep is a full test step definition.\n" +
+	"                    ttl_seconds_after_finished: 0\n" +
 	"                  leases:\n" +
 	"                    # LiteralTestStep is a full test step definition.\n" +
 	"                    - env: ' '\n" +
 	"                      resource_type: ' '\n" +
+	"                  manifests: ' '\n" +
+	"                  mutex: ' '\n" +
 	"                  no_kubeconfig: false\n" +
+	"                  node_capabilities:\n" +
+	"                    # LiteralTestStep is a full test step definition.\n" +
+	"                    - \"\"\n" +
 	"                  observers:\n" +
 	"                    # LiteralTestStep is a full test step definition.\n" +
 	"                    - \"\"\n" +
 	"                  optional_on_success: false\n" +
+	"                  previous_job_artifacts:\n" +
+	"                    # LiteralTestStep is a full test step definition.\n" +
+	"                    files:\n" +
+	"                        # LiteralTestStep is a full test step definition.\n" +
+	"                        - \"\"\n" +
+	"                  privileged_approval: ' '\n" +
+	"                  provides_shared_files:\n" +
+	"                    # LiteralTestStep is a full test step definition.\n" +
+	"                    - \"\"\n" +
 	"                  # Reference is the name of a step reference.\n" +
 	"                  ref: \"\"\n" +
-	"                  # Resources defines the resource requirements for the step.\n" +
+	"                  # RequiresSharedFiles lists the files which previous steps must have\n" +
+	"                  # written to $SHARED_DIR for this step to run. Their presence is\n" +
+	"                  # verified before the step is executed.\n" +
+	"                  requires_shared_files:\n" +
+	"                    # LiteralTestStep is a full test step definition.\n" +
+	"                    - \"\"\n" +
 	"                  resources:\n" +
 	"                    # LiteralTestStep is a full test step definition.\n" +
 	"                    limits:\n" +
@@ -1163,24 +2526,106 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"                    requests:\n" +
 	"                        # LiteralTestStep is a full test step definition.\n" +
 	"                        \"\": \"\"\n" +
+	"                  rollback: ' '\n" +
 	"                  run_as_script: false\n" +
+	"                  run_on_ephemeral_cluster: false\n" +
+	"                  scratch_size: ' '\n" +
+	"                  shard_count: 0\n" +
+	"                  sidecars:\n" +
+	"                    # LiteralTestStep is a full test step definition.\n" +
+	"                    - commands: ' '\n" +
+	"                      from: ' '\n" +
+	"                      name: ' '\n" +
+	"                      readiness: ' '\n" +
+	"                      resources:\n" +
+	"                        # LiteralTestStep is a full test step definition.\n" +
+	"                        limits:\n" +
+	"                            # LiteralTestStep is a full test step definition.\n" +
+	"                            \"\": \"\"\n" +
+	"                        requests:\n" +
+	"                            # LiteralTestStep is a full test step definition.\n" +
+	"                            \"\": \"\"\n" +
+	"                  split_logs: false\n" +
+	"                  stdin_from:\n" +
+	"                    # LiteralTestStep is a full test step definition.\n" +
+	"                    parameter: ' '\n" +
+	"                    shared_file: ' '\n" +
+	"                  sysctls:\n" +
+	"                    # LiteralTestStep is a full test step definition.\n" +
+	"                    - name: ' '\n" +
+	"                      value: ' '\n" +
 	"                  timeout: 0s\n" +
+	"                  toolkit: false\n" +
+	"                  upgrade:\n" +
+	"                    # LiteralTestStep is a full test step definition.\n" +
+	"                    channel: ' '\n" +
+	"                    force: true\n" +
+	"                    release: ' '\n" +
+	"                    rollback: true\n" +
+	"                  wait_for:\n" +
+	"                    # LiteralTestStep is a full test step definition.\n" +
+	"                    - api_version: ' '\n" +
+	"                      jsonpath: ' '\n" +
+	"                      kind: ' '\n" +
+	"                      name: ' '\n" +
+	"                      namespace: ' '\n" +
+	"                      timeout: 0s\n" +
+	"                      value: ' '\n" +
+	"                  workdir: ' '\n" +
+	"            # ReuseClusterFrom is the name of another test of the configuration whose\n" +
+	"            # cluster this test runs against instead of provisioning one. The test\n" +
+	"            # starts with a snapshot of the shared directory of the other test, taken\n" +
+	"            # once its `pre` steps have installed the cluster, and the other test only\n" +
+	"            # runs its `post` steps once this test has finished. Cluster profiles and\n" +
+	"            # claims cannot be used.\n" +
+	"            reuse_cluster_from: ' '\n" +
+	"            # StepReleases maps the names of steps to the release they use in place\n" +
+	"            # of `latest`: their dependencies on `release:latest` and on images of the\n" +
+	"            # `stable` stream, and the $RELEASE_IMAGE_LATEST given to them with a\n" +
+	"            # cluster profile, refer to the named release instead. This allows the\n" +
+	"            # steps of one test to use different payloads, e.g. for the management\n" +
+	"            # and the hosted clusters of a HyperShift test.\n" +
+	"            step_releases:\n" +
+	"                \"\": \"\"\n" +
 	"            # Test is the array of test steps that define the actual test.\n" +
 	"            test:\n" +
 	"                # LiteralTestStep is a full test step definition.\n" +
-	"                - as: ' '\n" +
+	"                - # Alias disambiguates steps whose names would otherwise conflict, e.g.\n" +
+	"                  # when two chains contribute steps with the same name. For a reference,\n" +
+	"                  # it replaces the name of the step. For a chain, it is prepended to the\n" +
+	"                  # names of all the steps of the chain, e.g. `upgrade-gather-must-gather`\n" +
+	"                  # for the step `gather-must-gather` of a chain with the alias `upgrade`.\n" +
+	"                  alias: ' '\n" +
+	"                  args:\n" +
+	"                    # LiteralTestStep is a full test step definition.\n" +
+	"                    - \"\"\n" +
+	"                  as: ' '\n" +
 	"                  best_effort: false\n" +
+	"                  burst: false\n" +
 	"                  # Chain is the name of a step chain reference.\n" +
 	"                  chain: \"\"\n" +
 	"                  # Cli is the (optional) name of the release from which the `oc` binary\n" +
-	"                  # will be injected into this step.\n" +
+	"                  # will be injected into this step. If the step has a `KUBECTL_RETRY`\n" +
+	"                  # parameter, the binary is wrapped to retry calls which fail with a\n" +
+	"                  # refused connection or a 429 response, at most that many times.\n" +
 	"                  cli: ' '\n" +
+	"                  command:\n" +
+	"                    # LiteralTestStep is a full test step definition.\n" +
+	"                    - \"\"\n" +
 	"                  commands: ' '\n" +
+	"                  concurrency_group: ' '\n" +
 	"                  credentials:\n" +
 	"                    # LiteralTestStep is a full test step definition.\n" +
 	"                    - mount_path: ' '\n" +
 	"                      name: ' '\n" +
 	"                      namespace: ' '\n" +
+	"                      refresh: true\n" +
+	"                      selector:\n" +
+	"                        # LiteralTestStep is a full test step definition.\n" +
+	"                        matchLabels:\n" +
+	"                            # LiteralTestStep is a full test step definition.\n" +
+	"                            \"\": \"\"\n" +
+	"                  critical: false\n" +
 	"                  dependencies:\n" +
 	"                    # LiteralTestStep is a full test step definition.\n" +
 	"                    - env: ' '\n" +
@@ -1198,6 +2643,7 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"                    - default: \"\"\n" +
 	"                      documentation: ' '\n" +
 	"                      name: ' '\n" +
+	"                  fail_on_restart: false\n" +
 	"                  from: ' '\n" +
 	"                  from_image:\n" +
 	"                    # LiteralTestStep is a full test step definition.\n" +
@@ -1205,19 +2651,44 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"                    name: ' '\n" +
 	"                    namespace: ' '\n" +
 	"                    tag: ' '\n" +
+	"                  gang: ' '\n" +
+	"                  gather: false\n" +
 	"                  grace_period: 0s\n" +
+	"                  host_network: false\n" +
+	"                  job:\n" +
+	"                    # LiteralTestStep is a full test step definition.\n" +
+	"                    ttl_seconds_after_finished: 0\n" +
 	"                  leases:\n" +
 	"                    # LiteralTestStep is a full test step definition.\n" +
 	"                    - env: ' '\n" +
 	"                      resource_type: ' '\n" +
+	"                  manifests: ' '\n" +
+	"                  mutex: ' '\n" +
 	"                  no_kubeconfig: false\n" +
+	"                  node_capabilities:\n" +
+	"                    # LiteralTestStep is a full test step definition.\n" +
+	"                    - \"\"\n" +
 	"                  observers:\n" +
 	"                    # LiteralTestStep is a full test step definition.\n" +
 	"                    - \"\"\n" +
 	"                  optional_on_success: false\n" +
+	"                  previous_job_artifacts:\n" +
+	"                    # LiteralTestStep is a full test step definition.\n" +
+	"                    files:\n" +
+	"                        # LiteralTestStep is a full test step definition.\n" +
+	"                        - \"\"\n" +
+	"                  privileged_approval: ' '\n" +
+	"                  provides_shared_files:\n" +
+	"                    # LiteralTestStep is a full test step definition.\n" +
+	"                    - \"\"\n" +
 	"                  # Reference is the name of a step reference.\n" +
 	"                  ref: \"\"\n" +
-	"                  # Resources defines the resource requirements for the step.\n" +
+	"                  # RequiresSharedFiles lists the files which previous steps must have\n" +
+	"                  # written to $SHARED_DIR for this step to run. Their presence is\n" +
+	"                  # verified before the step is executed.\n" +
+	"                  requires_shared_files:\n" +
+	"                    # LiteralTestStep is a full test step definition.\n" +
+	"                    - \"\"\n" +
 	"                  resources:\n" +
 	"                    # LiteralTestStep is a full test step definition.\n" +
 	"                    limits:\n" +
@@ -1226,8 +2697,52 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"                    requests:\n" +
 	"                        # LiteralTestStep is a full test step definition.\n" +
 	"                        \"\": \"\"\n" +
+	"                  rollback: ' '\n" +
 	"                  run_as_script: false\n" +
+	"                  run_on_ephemeral_cluster: false\n" +
+	"                  scratch_size: ' '\n" +
+	"                  shard_count: 0\n" +
+	"                  sidecars:\n" +
+	"                    # LiteralTestStep is a full test step definition.\n" +
+	"                    - commands: ' '\n" +
+	"                      from: ' '\n" +
+	"                      name: ' '\n" +
+	"                      readiness: ' '\n" +
+	"                      resources:\n" +
+	"                        # LiteralTestStep is a full test step definition.\n" +
+	"                        limits:\n" +
+	"                            # LiteralTestStep is a full test step definition.\n" +
+	"                            \"\": \"\"\n" +
+	"                        requests:\n" +
+	"                            # LiteralTestStep is a full test step definition.\n" +
+	"                            \"\": \"\"\n" +
+	"                  split_logs: false\n" +
+	"                  stdin_from:\n" +
+	"                    # LiteralTestStep is a full test step definition.\n" +
+	"                    parameter: ' '\n" +
+	"                    shared_file: ' '\n" +
+	"                  sysctls:\n" +
+	"                    # LiteralTestStep is a full test step definition.\n" +
+	"                    - name: ' '\n" +
+	"                      value: ' '\n" +
 	"                  timeout: 0s\n" +
+	"                  toolkit: false\n" +
+	"                  upgrade:\n" +
+	"                    # LiteralTestStep is a full test step definition.\n" +
+	"                    channel: ' '\n" +
+	"                    force: true\n" +
+	"                    release: ' '\n" +
+	"                    rollback: true\n" +
+	"                  wait_for:\n" +
+	"                    # LiteralTestStep is a full test step definition.\n" +
+	"                    - api_version: ' '\n" +
+	"                      jsonpath: ' '\n" +
+	"                      kind: ' '\n" +
+	"                      name: ' '\n" +
+	"                      namespace: ' '\n" +
+	"                      timeout: 0s\n" +
+	"                      value: ' '\n" +
+	"                  workdir: ' '\n" +
 	"            # Workflow is the name of the workflow to be used for this configuration. For fields defined in both\n" +
 	"            # the config and the workflow, the fields from the config will override what is set in Workflow.\n" +
 	"            workflow: \"\"\n" +
@@ -1313,6 +2828,13 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"rpm_build_location_list:\n" +
 	"    - location: ' '\n" +
 	"      ref: ' '\n" +
+	"# SharedLeases are leases acquired once for the job and shared by all\n" +
+	"# the multi-stage tests which lease resources of the same type, instead\n" +
+	"# of each test acquiring and releasing its own. The lease is released\n" +
+	"# once the last test using it has finished.\n" +
+	"shared_leases:\n" +
+	"    - # ResourceType is the type of resource that will be leased.\n" +
+	"      resource_type: ' '\n" +
 	"# ReleaseTagConfiguration determines how the\n" +
 	"# full release is assembled.\n" +
 	"tag_specification:\n" +
@@ -1342,8 +2864,19 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"# The images launched as pods but have no explicit access to\n" +
 	"# the cluster they are running on.\n" +
 	"tests:\n" +
-	"    - # AlwaysRun can be set to false to disable running the job on every PR\n" +
+	"    - aggregate:\n" +
+	"        # MinimumPassing is the number of tests which must pass for the\n" +
+	"        # aggregate test to pass, e.g. 4 of 5. All tests must pass by default.\n" +
+	"        minimum_passing: 0\n" +
+	"        # Tests are the names of the tests whose results are aggregated.\n" +
+	"        tests:\n" +
+	"            - \"\"\n" +
+	"      # AlwaysRun can be set to false to disable running the job on every PR\n" +
 	"      always_run: false\n" +
+	"      # Annotations are added to every object created for a multi-stage test,\n" +
+	"      # in addition to those set by ci-operator.\n" +
+	"      annotations:\n" +
+	"        \"\": \"\"\n" +
 	"      # As is the name of the test.\n" +
 	"      as: ' '\n" +
 	"      # Cluster specifies the name of the cluster where the test runs.\n" +
@@ -1396,6 +2929,10 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"      # on the last time the test ran. Setting this field will\n" +
 	"      # create a periodic job instead of a presubmit\n" +
 	"      interval: \"\"\n" +
+	"      # Labels are added to every object created for a multi-stage test, in\n" +
+	"      # addition to those set by ci-operator.\n" +
+	"      labels:\n" +
+	"        \"\": \"\"\n" +
 	"      literal_steps:\n" +
 	"        # AllowBestEffortPostSteps defines if any `post` steps can be ignored when\n" +
 	"        # they fail. The given step must explicitly ask for being ignored by setting\n" +
@@ -1405,8 +2942,21 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"        # all previous `pre` and `test` steps were successful. The given step must explicitly\n" +
 	"        # ask for being skipped by setting the OptionalOnSuccess flag to true.\n" +
 	"        allow_skip_on_success: false\n" +
+	"        # ClusterHealthGate configures checks of the health of the cluster under\n" +
+	"        # test which must pass after the `pre` phase before `test` steps are run.\n" +
+	"        cluster_health_gate:\n" +
+	"            # Checks are the checks which must pass. All checks are run when empty.\n" +
+	"            checks:\n" +
+	"                - \"\"\n" +
+	"            # Timeout is how long to wait for the checks to pass, defaults to 10m.\n" +
+	"            timeout: 0s\n" +
 	"        # ClusterProfile defines the profile/cloud provider for end-to-end test steps.\n" +
 	"        cluster_profile: ' '\n" +
+	"        # ContainerOnly declares that the steps of the test do not interact with\n" +
+	"        # any cluster. Steps are not given a kubeconfig, run with reduced\n" +
+	"        # permissions in the test namespace and have a default timeout of one\n" +
+	"        # hour. Cluster profiles, claims, observers and leases cannot be used.\n" +
+	"        container_only: false\n" +
 	"        # Dependencies holds override values for dependency parameters.\n" +
 	"        dependencies:\n" +
 	"            \"\": \"\"\n" +
@@ -1414,6 +2964,25 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"        # be used with rehearsals. Otherwise, the overrides should be passed in as parameters to ci-operator.\n" +
 	"        dependency_overrides:\n" +
 	"            \"\": \"\"\n" +
+	"        # DisruptionMonitor configures an availability monitor which runs in\n" +
+	"        # ci-operator during the `test` phase.\n" +
+	"        disruption_monitor:\n" +
+	"            # Interval is the time between two requests to a target, defaults to 1s.\n" +
+	"            interval: 0s\n" +
+	"            # Targets are the endpoints which are polled.\n" +
+	"            targets:\n" +
+	"                - # InsecureSkipTLSVerify disables the verification of the certificate\n" +
+	"                  # served by the target, which is necessary when polling endpoints of\n" +
+	"                  # ephemeral clusters with self-signed certificates.\n" +
+	"                  insecure_skip_tls_verify: true\n" +
+	"                  # MaxDisruption is the total unavailability tolerated during the `test`\n" +
+	"                  # phase. When unset, disruption is only reported.\n" +
+	"                  max_disruption: 0s\n" +
+	"                  # Name identifies the target in the report.\n" +
+	"                  name: ' '\n" +
+	"                  # URL is requested with GET on every poll. The target is considered\n" +
+	"                  # available when the request succeeds with a status code below 500.\n" +
+	"                  url: ' '\n" +
 	"        # DnsConfig for step's Pod.\n" +
 	"        dnsConfig:\n" +
 	"            # Nameservers is a list of IP addresses that will be used as DNS servers for the Pod\n" +
@@ -1425,6 +2994,11 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"        # Environment has the values of parameters for the steps.\n" +
 	"        env:\n" +
 	"            \"\": \"\"\n" +
+	"        # FeatureSet is the OpenShift feature set the cluster under test is\n" +
+	"        # installed with, e.g. TechPreviewNoUpgrade. It is exposed to the steps\n" +
+	"        # as $FEATURE_SET and as the value of their parameter of that name, and\n" +
+	"        # is validated against the version of the `latest` release.\n" +
+	"        feature_set: ' '\n" +
 	"        # Leases lists resources that should be acquired for the test.\n" +
 	"        leases:\n" +
 	"            - # Env is the environment variable that will contain the resource name.\n" +
@@ -1472,30 +3046,73 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"        # Post is the array of test steps run after the tests finish and teardown/deprovision resources.\n" +
 	"        # Post steps always run, even if previous steps fail.\n" +
 	"        post:\n" +
-	"            - # As is the name of the LiteralTestStep.\n" +
+	"            - # Args are the arguments passed to Command.\n" +
+	"              args:\n" +
+	"                - \"\"\n" +
+	"              # As is the name of the LiteralTestStep.\n" +
 	"              as: ' '\n" +
 	"              # BestEffort defines if this step should cause the job to fail when the\n" +
 	"              # step fails. This only applies when AllowBestEffortPostSteps flag is set\n" +
 	"              # to true in MultiStageTestConfiguration. This option is applicable to\n" +
 	"              # `post` steps.\n" +
 	"              best_effort: false\n" +
+	"              # Burst lets the step use resources up to its limits, which are kept as\n" +
+	"              # configured instead of being derived from its requests, so that bursty\n" +
+	"              # steps can request what they use on average. Limits are capped by the\n" +
+	"              # policy of the build farm and the CPU throttling of the step is recorded\n" +
+	"              # in its metrics.\n" +
+	"              burst: false\n" +
 	"              # Cli is the (optional) name of the release from which the `oc` binary\n" +
-	"              # will be injected into this step.\n" +
+	"              # will be injected into this step. If the step has a `KUBECTL_RETRY`\n" +
+	"              # parameter, the binary is wrapped to retry calls which fail with a\n" +
+	"              # refused connection or a 429 response, at most that many times.\n" +
 	"              cli: ' '\n" +
+	"              # Command is the executable run inside the image, as an alternative to\n" +
+	"              # `commands` for images without bash, e.g. distroless or scratch-based\n" +
+	"              # tool images. It is executed directly with Args, without a shell.\n" +
+	"              command:\n" +
+	"                - \"\"\n" +
 	"              # Commands is the command(s) that will be run inside the image.\n" +
 	"              commands: ' '\n" +
+	"              # ConcurrencyGroup limits the number of instances of the step which run\n" +
+	"              # concurrently across all jobs, e.g. for heavyweight steps which would\n" +
+	"              # overload a shared service. A lease is acquired from the lease server\n" +
+	"              # for the duration of the step, so the limit is the number of resources\n" +
+	"              # of type `<group>-concurrency` configured in the server.\n" +
+	"              concurrency_group: ' '\n" +
 	"              # Credentials defines the credentials we'll mount into this step.\n" +
 	"              credentials:\n" +
-	"                - # MountPath is where the secret should be mounted.\n" +
+	"                - # MountPath is where the secret should be mounted. References to the\n" +
+	"                  # parameters of the step, e.g. `$(PLATFORM)`, are expanded.\n" +
 	"                  mount_path: ' '\n" +
 	"                  # Names is which source secret to mount.\n" +
 	"                  name: ' '\n" +
 	"                  # Namespace is where the source secret exists.\n" +
 	"                  namespace: ' '\n" +
+	"                  # Refresh propagates updates of the source secret to its copy in the\n" +
+	"                  # test namespace while the test runs, for credentials which are rotated\n" +
+	"                  # during long tests. Mounted files are updated by the kubelet shortly\n" +
+	"                  # after, so steps must read them again instead of caching their content.\n" +
+	"                  refresh: true\n" +
+	"                  # Selector mounts a collection of secrets instead of a single one: every\n" +
+	"                  # secret in the namespace which has the labels is mounted in a directory\n" +
+	"                  # named after it under the mount path. The collection is resolved when\n" +
+	"                  # the test starts, so secrets can be added to or removed from it without\n" +
+	"                  # changing the configuration of the step.\n" +
+	"                  selector:\n" +
+	"                    # MatchLabels are the labels the secrets must have.\n" +
+	"                    matchLabels:\n" +
+	"                        \"\": \"\"\n" +
+	"              # Critical protects the pods of the step from being evicted while they\n" +
+	"              # run, e.g. when the build farm scales down, for steps which cannot be\n" +
+	"              # interrupted without leaking resources, like cluster installation and\n" +
+	"              # teardown.\n" +
+	"              critical: false\n" +
 	"              # Dependencies lists images which must be available before the test runs\n" +
 	"              # and the environment variables which are used to expose their pull specs.\n" +
 	"              dependencies:\n" +
-	"                - # Env is the environment variable that the image's pull spec is exposed with\n" +
+	"                - # Env is the environment variable that the image's pull spec is exposed with.\n" +
+	"                  # References to the parameters of the step, e.g. `$(PLATFORM)`, are expanded.\n" +
 	"                  env: ' '\n" +
 	"                  # Name is the tag or stream:tag that this dependency references\n" +
 	"                  name: ' '\n" +
@@ -1515,6 +3132,11 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"                  documentation: ' '\n" +
 	"                  # Name of the environment variable.\n" +
 	"                  name: ' '\n" +
+	"              # FailOnRestart fails the step if a container of its pod is restarted\n" +
+	"              # while it runs, e.g. by the kubelet, which violates the restart policy\n" +
+	"              # of the pod. Restarts and warning events of the pod are reported in the\n" +
+	"              # results of the step either way.\n" +
+	"              fail_on_restart: false\n" +
 	"              # From is the container image that will be used for this step.\n" +
 	"              from: ' '\n" +
 	"              # FromImage is a literal ImageStreamTag reference to use for this step.\n" +
@@ -1524,19 +3146,65 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"                name: ' '\n" +
 	"                namespace: ' '\n" +
 	"                tag: ' '\n" +
+	"              # Gang is the name of a group of consecutive steps of a phase which run\n" +
+	"              # in parallel and must be scheduled together, e.g. the client and server\n" +
+	"              # of a performance test. The pods of a gang are held back from the\n" +
+	"              # scheduler until all of them were created. If they are not all created\n" +
+	"              # in time, or a pod of the gang cannot be scheduled while its peers run,\n" +
+	"              # all pods of the gang are stopped and the gang fails.\n" +
+	"              # The changes the steps of a gang make to $SHARED_DIR are merged like\n" +
+	"              # those of shards.\n" +
+	"              gang: ' '\n" +
+	"              # Gather marks a `post` step which collects information about the test.\n" +
+	"              # Gather steps run after all other `post` steps, even if the test failed\n" +
+	"              # or was cancelled, each with a timeout of its own (30 minutes unless\n" +
+	"              # `timeout` is set). Their results are reported in a dedicated suite.\n" +
+	"              gather: false\n" +
 	"              # GracePeriod is how long the we will wait after sending SIGINT to send\n" +
 	"              # SIGKILL when aborting a Step.\n" +
 	"              grace_period: 0s\n" +
+	"              # HostNetwork runs the step in the network namespace of the node, e.g.\n" +
+	"              # for dual-stack tests which need the addresses of the node. Tests with\n" +
+	"              # such steps must run on a cluster with the `host-network` capability.\n" +
+	"              host_network: false\n" +
+	"              # Job runs the step as a Kubernetes Job instead of a bare pod, for steps\n" +
+	"              # which can safely be retried. The cluster replaces the pod of the step\n" +
+	"              # when it fails or is lost, e.g. with its node, up to `backoff_limit`\n" +
+	"              # times, and the step fails once the Job does.\n" +
+	"              job:\n" +
+	"                # TTLSecondsAfterFinished is the time after which the Job and its pods\n" +
+	"                # are removed once the step finished. They are kept with the namespace\n" +
+	"                # of the test if not set.\n" +
+	"                ttl_seconds_after_finished: 0\n" +
 	"              # Leases lists resources that should be acquired for the test.\n" +
 	"              leases:\n" +
 	"                - # Env is the environment variable that will contain the resource name.\n" +
 	"                  env: ' '\n" +
 	"                  # ResourceType is the type of resource that will be leased.\n" +
 	"                  resource_type: ' '\n" +
+	"              # Manifests are Kubernetes objects, as a stream of YAML documents, which\n" +
+	"              # are created on the cluster under test before the step runs and deleted\n" +
+	"              # once it completes, e.g. the RBAC objects or CRDs the step requires.\n" +
+	"              # Objects which exist are replaced. The cluster is accessed with the\n" +
+	"              # `kubeconfig` in the shared directory. In the registry, this is the name\n" +
+	"              # of a file next to the reference, e.g. `ipi-conf-manifests.yaml`.\n" +
+	"              manifests: ' '\n" +
+	"              # Mutex is the name of a lock held for the duration of the step, so that\n" +
+	"              # no two steps with the same mutex run at the same time across all jobs,\n" +
+	"              # e.g. for steps which modify a shared DNS zone. The lock is a lease of\n" +
+	"              # type `<mutex>-mutex`, of which the lease server must hold exactly one\n" +
+	"              # resource.\n" +
+	"              mutex: ' '\n" +
 	"              # NoKubeconfig determines that no $KUBECONFIG will exist in $SHARED_DIR,\n" +
 	"              # so no local copy of it will be created for the step and if the step\n" +
 	"              # creates one, it will not be propagated.\n" +
 	"              no_kubeconfig: false\n" +
+	"              # NodeCapabilities lists the capabilities the nodes running the step\n" +
+	"              # must have, e.g. `kvm`, `sse4`, `large-disk` or `arm64`. They are\n" +
+	"              # checked against the capabilities of the build farm before the test\n" +
+	"              # runs, and the step is scheduled on the nodes which provide them.\n" +
+	"              node_capabilities:\n" +
+	"                - \"\"\n" +
 	"              # Observers are the observers that should be running\n" +
 	"              observers:\n" +
 	"                - \"\"\n" +
@@ -1545,6 +3213,33 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"              # flag is set to true in MultiStageTestConfiguration. This option is\n" +
 	"              # applicable to `post` steps.\n" +
 	"              optional_on_success: false\n" +
+	"              # PreviousJobArtifacts exposes the artifacts of the last successful run\n" +
+	"              # of the same job to the step, e.g. for performance or compatibility\n" +
+	"              # tests which compare their results with a baseline.\n" +
+	"              previous_job_artifacts:\n" +
+	"                # Files are paths relative to the artifact directory of the previous run\n" +
+	"                # which are fetched before the step starts and mounted in the directory\n" +
+	"                # at `$PREVIOUS_JOB_ARTIFACTS_DIR`. Files which do not exist in the\n" +
+	"                # previous run are skipped. Not available to steps which run on the\n" +
+	"                # ephemeral cluster.\n" +
+	"                files:\n" +
+	"                    - \"\"\n" +
+	"              # PrivilegedApproval is the name of the entry of the central allowlist\n" +
+	"              # which approves the privileged capabilities the step uses, like\n" +
+	"              # `host_network` or `sysctls`. Where the allowlist is enforced, steps\n" +
+	"              # which use such capabilities cannot be resolved without an approval\n" +
+	"              # which lists the step and its capabilities.\n" +
+	"              privileged_approval: ' '\n" +
+	"              # ProvidesSharedFiles lists the files this step writes to $SHARED_DIR.\n" +
+	"              # The step fails if any of them is missing when its commands succeed.\n" +
+	"              # Steps requiring a file must run after a step providing it.\n" +
+	"              provides_shared_files:\n" +
+	"                - \"\"\n" +
+	"              # RequiresSharedFiles lists the files which previous steps must have\n" +
+	"              # written to $SHARED_DIR for this step to run. Their presence is\n" +
+	"              # verified before the step is executed.\n" +
+	"              requires_shared_files:\n" +
+	"                - \"\"\n" +
 	"              # Resources defines the resource requirements for the step.\n" +
 	"              resources:\n" +
 	"                # Limits are resource limits applied to an individual step in the job.\n" +
@@ -1555,37 +3250,209 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"                # These are directly used in creating the Pods that execute the Job.\n" +
 	"                requests:\n" +
 	"                    \"\": \"\"\n" +
-	"              # RunAsScript defines if this step should be executed as a script mounted\n" +
-	"              # in the test container instead of being executed directly via bash\n" +
+	"              # Rollback is the name of a step which is executed immediately if this\n" +
+	"              # step fails, before the test proceeds, to restore external state the\n" +
+	"              # step modified, e.g. DNS records or image tags. It references a step\n" +
+	"              # in the registry, which is resolved into the `rollbacks` of the test.\n" +
+	"              rollback: ' '\n" +
+	"              # RunAsScript defines if the commands of this step should be executed\n" +
+	"              # verbatim, using their own interpreter line, instead of being prefixed\n" +
+	"              # with the default bash preamble. The commands of every step are mounted\n" +
+	"              # as an executable script in the test container.\n" +
 	"              run_as_script: false\n" +
+	"              # RunOnEphemeralCluster runs the step as a pod on the cluster under test\n" +
+	"              # instead of the build farm, e.g. for tests which must run inside the\n" +
+	"              # network of the cluster. The step is executed with the `kubeconfig` in\n" +
+	"              # the shared directory, which must be provided by a previous step. The\n" +
+	"              # shared directory is available to the step, but changes to it are not\n" +
+	"              # propagated. The artifacts of the step are copied back once it exits.\n" +
+	"              run_on_ephemeral_cluster: false\n" +
+	"              # ScratchSize is the size of the scratch directory, e.g. `20Gi`. That\n" +
+	"              # much ephemeral storage is requested for the step, so the directory is\n" +
+	"              # guaranteed to have room for it instead of relying on the image having\n" +
+	"              # a writable `/tmp` big enough.\n" +
+	"              scratch_size: ' '\n" +
+	"              # ShardCount is the number of pods the step is split into. All shards\n" +
+	"              # run in parallel and receive the $SHARD_INDEX (starting from zero) and\n" +
+	"              # $SHARD_TOTAL environment variables, which the step uses to select its\n" +
+	"              # part of the work. The step fails if any of its shards fail. The\n" +
+	"              # changes the shards make to $SHARED_DIR are merged, when several shards\n" +
+	"              # write the same file the last one to finish wins.\n" +
+	"              shard_count: 0\n" +
+	"              # Sidecars are helper containers which run alongside the commands of\n" +
+	"              # the step, e.g. local registries, tunnels or log forwarders. They are\n" +
+	"              # terminated when the commands exit.\n" +
+	"              sidecars:\n" +
+	"                - # Commands is the command(s) that will be run inside the sidecar.\n" +
+	"                  commands: ' '\n" +
+	"                  # From is the container image tag used for the sidecar, resolved like\n" +
+	"                  # the `from` field of the step.\n" +
+	"                  from: ' '\n" +
+	"                  # Name is the name of the container, unique within the step.\n" +
+	"                  name: ' '\n" +
+	"                  # Readiness is an optional command executed repeatedly in the sidecar\n" +
+	"                  # until it succeeds. The commands of the step are only started once the\n" +
+	"                  # sidecars are ready.\n" +
+	"                  readiness: ' '\n" +
+	"                  # Resources defines the resource requirements for the sidecar.\n" +
+	"                  resources:\n" +
+	"                    # Limits are resource limits applied to an individual step in the job.\n" +
+	"                    # These are directly used in creating the Pods that execute the Job.\n" +
+	"                    limits:\n" +
+	"                        \"\": \"\"\n" +
+	"                    # Requests are resource requests applied to an individual step in the job.\n" +
+	"                    # These are directly used in creating the Pods that execute the Job.\n" +
+	"                    requests:\n" +
+	"                        \"\": \"\"\n" +
+	"              # SplitLogs additionally writes the standard output and error of the\n" +
+	"              # commands of the step to separate files in `$ARTIFACT_DIR/logs`, which\n" +
+	"              # are rotated once they reach 10MiB. The output is then copied from\n" +
+	"              # pipes: processes the commands start in the background and which keep\n" +
+	"              # them open are not waited for longer than ten seconds after the commands\n" +
+	"              # exit, and what they write afterwards is only in the container log.\n" +
+	"              split_logs: false\n" +
+	"              # StdinFrom feeds the standard input of the commands of the step from a\n" +
+	"              # parameter of the step or a file in the shared directory, e.g. to drive\n" +
+	"              # installers which prompt for a confirmation. The standard input of the\n" +
+	"              # commands is empty if not set.\n" +
+	"              stdin_from:\n" +
+	"                # Parameter is the name of a parameter of the step, the value of which is\n" +
+	"                # written to the standard input followed by a newline, unless it already\n" +
+	"                # ends with one.\n" +
+	"                parameter: ' '\n" +
+	"                # SharedFile is the name of a file in the shared directory, the contents\n" +
+	"                # of which are written to the standard input. The step fails if the file\n" +
+	"                # does not exist when it starts.\n" +
+	"                shared_file: ' '\n" +
+	"              # Sysctls are set for the pod of the step. Sysctls which the kubelet does\n" +
+	"              # not consider safe require a cluster with the `unsafe-sysctls`\n" +
+	"              # capability.\n" +
+	"              sysctls:\n" +
+	"                - # Name of the parameter, e.g. `net.ipv6.conf.all.forwarding`.\n" +
+	"                  name: ' '\n" +
+	"                  # Value of the parameter.\n" +
+	"                  value: ' '\n" +
 	"              # Timeout is how long the we will wait before aborting a job with SIGINT.\n" +
 	"              timeout: 0s\n" +
+	"              # Toolkit sources the shell function library of steps before their\n" +
+	"              # commands, which provides `log`, `retry`, `wait_for` and `collect`. The\n" +
+	"              # library is mounted in the step and its path is exposed as $CI_TOOLKIT,\n" +
+	"              # for commands run as scripts to source it themselves.\n" +
+	"              toolkit: false\n" +
+	"              # Upgrade makes this a built-in step which upgrades the cluster under\n" +
+	"              # test, instead of running `commands` in a container. The upgrade is\n" +
+	"              # driven by ci-operator through the ClusterVersion of the cluster, using\n" +
+	"              # the `kubeconfig` in the shared directory.\n" +
+	"              upgrade:\n" +
+	"                # Channel is set on the ClusterVersion before the upgrade, if not empty.\n" +
+	"                channel: ' '\n" +
+	"                # Force upgrades to the release even if its signature cannot be verified\n" +
+	"                # or the cluster is not upgradeable.\n" +
+	"                force: true\n" +
+	"                # Release is the name of the release to upgrade to, e.g. `latest`.\n" +
+	"                release: ' '\n" +
+	"                # Rollback returns the cluster to the release it was running if the\n" +
+	"                # upgrade fails. The step still fails.\n" +
+	"                rollback: true\n" +
+	"              # WaitFor lists conditions on objects of the cluster under test which\n" +
+	"              # ci-operator waits for, in order, before the step starts, instead of the\n" +
+	"              # step polling the cluster itself. The step fails if a condition is not\n" +
+	"              # met in time. The cluster is accessed with the `kubeconfig` in the\n" +
+	"              # shared directory.\n" +
+	"              wait_for:\n" +
+	"                - # APIVersion of the object, e.g. `apps/v1`.\n" +
+	"                  api_version: ' '\n" +
+	"                  # JSONPath is a template evaluated against the object, in the syntax of\n" +
+	"                  # `oc get -o jsonpath`, e.g.\n" +
+	"                  # `{.status.conditions[?(@.type==\"Available\")].status}`.\n" +
+	"                  jsonpath: ' '\n" +
+	"                  # Kind of the object, e.g. `Deployment`.\n" +
+	"                  kind: ' '\n" +
+	"                  # Name of the object.\n" +
+	"                  name: ' '\n" +
+	"                  # Namespace of the object, empty for cluster-scoped objects.\n" +
+	"                  namespace: ' '\n" +
+	"                  # Timeout is how long to wait for the condition, 10 minutes by default.\n" +
+	"                  timeout: 0s\n" +
+	"                  # Value is the output of the template which meets the condition. If\n" +
+	"                  # empty, any non-empty output meets it.\n" +
+	"                  value: ' '\n" +
+	"              # Workdir is the path at which a writable scratch directory is mounted\n" +
+	"              # for the step, which is also the working directory of its commands,\n" +
+	"              # `/scratch` by default. The path is exposed as $SCRATCH_DIR. The\n" +
+	"              # scratch directory is only added if this or `scratch_size` is set.\n" +
+	"              # References to the parameters of the step, e.g. `$(PLATFORM)`, are\n" +
+	"              # expanded.\n" +
+	"              workdir: ' '\n" +
 	"        # Pre is the array of test steps run to set up the environment for the test.\n" +
 	"        pre:\n" +
-	"            - # As is the name of the LiteralTestStep.\n" +
+	"            - # Args are the arguments passed to Command.\n" +
+	"              args:\n" +
+	"                - \"\"\n" +
+	"              # As is the name of the LiteralTestStep.\n" +
 	"              as: ' '\n" +
 	"              # BestEffort defines if this step should cause the job to fail when the\n" +
 	"              # step fails. This only applies when AllowBestEffortPostSteps flag is set\n" +
 	"              # to true in MultiStageTestConfiguration. This option is applicable to\n" +
 	"              # `post` steps.\n" +
 	"              best_effort: false\n" +
+	"              # Burst lets the step use resources up to its limits, which are kept as\n" +
+	"              # configured instead of being derived from its requests, so that bursty\n" +
+	"              # steps can request what they use on average. Limits are capped by the\n" +
+	"              # policy of the build farm and the CPU throttling of the step is recorded\n" +
+	"              # in its metrics.\n" +
+	"              burst: false\n" +
 	"              # Cli is the (optional) name of the release from which the `oc` binary\n" +
-	"              # will be injected into this step.\n" +
+	"              # will be injected into this step. If the step has a `KUBECTL_RETRY`\n" +
+	"              # parameter, the binary is wrapped to retry calls which fail with a\n" +
+	"              # refused connection or a 429 response, at most that many times.\n" +
 	"              cli: ' '\n" +
+	"              # Command is the executable run inside the image, as an alternative to\n" +
+	"              # `commands` for images without bash, e.g. distroless or scratch-based\n" +
+	"              # tool images. It is executed directly with Args, without a shell.\n" +
+	"              command:\n" +
+	"                - \"\"\n" +
 	"              # Commands is the command(s) that will be run inside the image.\n" +
 	"              commands: ' '\n" +
+	"              # ConcurrencyGroup limits the number of instances of the step which run\n" +
+	"              # concurrently across all jobs, e.g. for heavyweight steps which would\n" +
+	"              # overload a shared service. A lease is acquired from the lease server\n" +
+	"              # for the duration of the step, so the limit is the number of resources\n" +
+	"              # of type `<group>-concurrency` configured in the server.\n" +
+	"              concurrency_group: ' '\n" +
 	"              # Credentials defines the credentials we'll mount into this step.\n" +
 	"              credentials:\n" +
-	"                - # MountPath is where the secret should be mounted.\n" +
+	"                - # MountPath is where the secret should be mounted. References to the\n" +
+	"                  # parameters of the step, e.g. `$(PLATFORM)`, are expanded.\n" +
 	"                  mount_path: ' '\n" +
 	"                  # Names is which source secret to mount.\n" +
 	"                  name: ' '\n" +
 	"                  # Namespace is where the source secret exists.\n" +
 	"                  namespace: ' '\n" +
+	"                  # Refresh propagates updates of the source secret to its copy in the\n" +
+	"                  # test namespace while the test runs, for credentials which are rotated\n" +
+	"                  # during long tests. Mounted files are updated by the kubelet shortly\n" +
+	"                  # after, so steps must read them again instead of caching their content.\n" +
+	"                  refresh: true\n" +
+	"                  # Selector mounts a collection of secrets instead of a single one: every\n" +
+	"                  # secret in the namespace which has the labels is mounted in a directory\n" +
+	"                  # named after it under the mount path. The collection is resolved when\n" +
+	"                  # the test starts, so secrets can be added to or removed from it without\n" +
+	"                  # changing the configuration of the step.\n" +
+	"                  selector:\n" +
+	"                    # MatchLabels are the labels the secrets must have.\n" +
+	"                    matchLabels:\n" +
+	"                        \"\": \"\"\n" +
+	"              # Critical protects the pods of the step from being evicted while they\n" +
+	"              # run, e.g. when the build farm scales down, for steps which cannot be\n" +
+	"              # interrupted without leaking resources, like cluster installation and\n" +
+	"              # teardown.\n" +
+	"              critical: false\n" +
 	"              # Dependencies lists images which must be available before the test runs\n" +
 	"              # and the environment variables which are used to expose their pull specs.\n" +
 	"              dependencies:\n" +
-	"                - # Env is the environment variable that the image's pull spec is exposed with\n" +
+	"                - # Env is the environment variable that the image's pull spec is exposed with.\n" +
+	"                  # References to the parameters of the step, e.g. `$(PLATFORM)`, are expanded.\n" +
 	"                  env: ' '\n" +
 	"                  # Name is the tag or stream:tag that this dependency references\n" +
 	"                  name: ' '\n" +
@@ -1605,6 +3472,11 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"                  documentation: ' '\n" +
 	"                  # Name of the environment variable.\n" +
 	"                  name: ' '\n" +
+	"              # FailOnRestart fails the step if a container of its pod is restarted\n" +
+	"              # while it runs, e.g. by the kubelet, which violates the restart policy\n" +
+	"              # of the pod. Restarts and warning events of the pod are reported in the\n" +
+	"              # results of the step either way.\n" +
+	"              fail_on_restart: false\n" +
 	"              # From is the container image that will be used for this step.\n" +
 	"              from: ' '\n" +
 	"              # FromImage is a literal ImageStreamTag reference to use for this step.\n" +
@@ -1614,19 +3486,65 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"                name: ' '\n" +
 	"                namespace: ' '\n" +
 	"                tag: ' '\n" +
+	"              # Gang is the name of a group of consecutive steps of a phase which run\n" +
+	"              # in parallel and must be scheduled together, e.g. the client and server\n" +
+	"              # of a performance test. The pods of a gang are held back from the\n" +
+	"              # scheduler until all of them were created. If they are not all created\n" +
+	"              # in time, or a pod of the gang cannot be scheduled while its peers run,\n" +
+	"              # all pods of the gang are stopped and the gang fails.\n" +
+	"              # The changes the steps of a gang make to $SHARED_DIR are merged like\n" +
+	"              # those of shards.\n" +
+	"              gang: ' '\n" +
+	"              # Gather marks a `post` step which collects information about the test.\n" +
+	"              # Gather steps run after all other `post` steps, even if the test failed\n" +
+	"              # or was cancelled, each with a timeout of its own (30 minutes unless\n" +
+	"              # `timeout` is set). Their results are reported in a dedicated suite.\n" +
+	"              gather: false\n" +
 	"              # GracePeriod is how long the we will wait after sending SIGINT to send\n" +
 	"              # SIGKILL when aborting a Step.\n" +
 	"              grace_period: 0s\n" +
+	"              # HostNetwork runs the step in the network namespace of the node, e.g.\n" +
+	"              # for dual-stack tests which need the addresses of the node. Tests with\n" +
+	"              # such steps must run on a cluster with the `host-network` capability.\n" +
+	"              host_network: false\n" +
+	"              # Job runs the step as a Kubernetes Job instead of a bare pod, for steps\n" +
+	"              # which can safely be retried. The cluster replaces the pod of the step\n" +
+	"              # when it fails or is lost, e.g. with its node, up to `backoff_limit`\n" +
+	"              # times, and the step fails once the Job does.\n" +
+	"              job:\n" +
+	"                # TTLSecondsAfterFinished is the time after which the Job and its pods\n" +
+	"                # are removed once the step finished. They are kept with the namespace\n" +
+	"                # of the test if not set.\n" +
+	"                ttl_seconds_after_finished: 0\n" +
 	"              # Leases lists resources that should be acquired for the test.\n" +
 	"              leases:\n" +
 	"                - # Env is the environment variable that will contain the resource name.\n" +
 	"                  env: ' '\n" +
 	"                  # ResourceType is the type of resource that will be leased.\n" +
 	"                  resource_type: ' '\n" +
+	"              # Manifests are Kubernetes objects, as a stream of YAML documents, which\n" +
+	"              # are created on the cluster under test before the step runs and deleted\n" +
+	"              # once it completes, e.g. the RBAC objects or CRDs the step requires.\n" +
+	"              # Objects which exist are replaced. The cluster is accessed with the\n" +
+	"              # `kubeconfig` in the shared directory. In the registry, this is the name\n" +
+	"              # of a file next to the reference, e.g. `ipi-conf-manifests.yaml`.\n" +
+	"              manifests: ' '\n" +
+	"              # Mutex is the name of a lock held for the duration of the step, so that\n" +
+	"              # no two steps with the same mutex run at the same time across all jobs,\n" +
+	"              # e.g. for steps which modify a shared DNS zone. The lock is a lease of\n" +
+	"              # type `<mutex>-mutex`, of which the lease server must hold exactly one\n" +
+	"              # resource.\n" +
+	"              mutex: ' '\n" +
 	"              # NoKubeconfig determines that no $KUBECONFIG will exist in $SHARED_DIR,\n" +
 	"              # so no local copy of it will be created for the step and if the step\n" +
 	"              # creates one, it will not be propagated.\n" +
 	"              no_kubeconfig: false\n" +
+	"              # NodeCapabilities lists the capabilities the nodes running the step\n" +
+	"              # must have, e.g. `kvm`, `sse4`, `large-disk` or `arm64`. They are\n" +
+	"              # checked against the capabilities of the build farm before the test\n" +
+	"              # runs, and the step is scheduled on the nodes which provide them.\n" +
+	"              node_capabilities:\n" +
+	"                - \"\"\n" +
 	"              # Observers are the observers that should be running\n" +
 	"              observers:\n" +
 	"                - \"\"\n" +
@@ -1635,6 +3553,33 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"              # flag is set to true in MultiStageTestConfiguration. This option is\n" +
 	"              # applicable to `post` steps.\n" +
 	"              optional_on_success: false\n" +
+	"              # PreviousJobArtifacts exposes the artifacts of the last successful run\n" +
+	"              # of the same job to the step, e.g. for performance or compatibility\n" +
+	"              # tests which compare their results with a baseline.\n" +
+	"              previous_job_artifacts:\n" +
+	"                # Files are paths relative to the artifact directory of the previous run\n" +
+	"                # which are fetched before the step starts and mounted in the directory\n" +
+	"                # at `$PREVIOUS_JOB_ARTIFACTS_DIR`. Files which do not exist in the\n" +
+	"                # previous run are skipped. Not available to steps which run on the\n" +
+	"                # ephemeral cluster.\n" +
+	"                files:\n" +
+	"                    - \"\"\n" +
+	"              # PrivilegedApproval is the name of the entry of the central allowlist\n" +
+	"              # which approves the privileged capabilities the step uses, like\n" +
+	"              # `host_network` or `sysctls`. Where the allowlist is enforced, steps\n" +
+	"              # which use such capabilities cannot be resolved without an approval\n" +
+	"              # which lists the step and its capabilities.\n" +
+	"              privileged_approval: ' '\n" +
+	"              # ProvidesSharedFiles lists the files this step writes to $SHARED_DIR.\n" +
+	"              # The step fails if any of them is missing when its commands succeed.\n" +
+	"              # Steps requiring a file must run after a step providing it.\n" +
+	"              provides_shared_files:\n" +
+	"                - \"\"\n" +
+	"              # RequiresSharedFiles lists the files which previous steps must have\n" +
+	"              # written to $SHARED_DIR for this step to run. Their presence is\n" +
+	"              # verified before the step is executed.\n" +
+	"              requires_shared_files:\n" +
+	"                - \"\"\n" +
 	"              # Resources defines the resource requirements for the step.\n" +
 	"              resources:\n" +
 	"                # Limits are resource limits applied to an individual step in the job.\n" +
@@ -1645,37 +3590,565 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"                # These are directly used in creating the Pods that execute the Job.\n" +
 	"                requests:\n" +
 	"                    \"\": \"\"\n" +
-	"              # RunAsScript defines if this step should be executed as a script mounted\n" +
-	"              # in the test container instead of being executed directly via bash\n" +
+	"              # Rollback is the name of a step which is executed immediately if this\n" +
+	"              # step fails, before the test proceeds, to restore external state the\n" +
+	"              # step modified, e.g. DNS records or image tags. It references a step\n" +
+	"              # in the registry, which is resolved into the `rollbacks` of the test.\n" +
+	"              rollback: ' '\n" +
+	"              # RunAsScript defines if the commands of this step should be executed\n" +
+	"              # verbatim, using their own interpreter line, instead of being prefixed\n" +
+	"              # with the default bash preamble. The commands of every step are mounted\n" +
+	"              # as an executable script in the test container.\n" +
 	"              run_as_script: false\n" +
-	"              # Timeout is how long the we will wait before aborting a job with SIGINT.\n" +
-	"              timeout: 0s\n" +
-	"        # Test is the array of test steps that define the actual test.\n" +
-	"        test:\n" +
-	"            - # As is the name of the LiteralTestStep.\n" +
-	"              as: ' '\n" +
-	"              # BestEffort defines if this step should cause the job to fail when the\n" +
-	"              # step fails. This only applies when AllowBestEffortPostSteps flag is set\n" +
+	"              # RunOnEphemeralCluster runs the step as a pod on the cluster under test\n" +
+	"              # instead of the build farm, e.g. for tests which must run inside the\n" +
+	"              # network of the cluster. The step is executed with the `kubeconfig` in\n" +
+	"              # the shared directory, which must be provided by a previous step. The\n" +
+	"              # shared directory is available to the step, but changes to it are not\n" +
+	"              # propagated. The artifacts of the step are copied back once it exits.\n" +
+	"              run_on_ephemeral_cluster: false\n" +
+	"              # ScratchSize is the size of the scratch directory, e.g. `20Gi`. That\n" +
+	"              # much ephemeral storage is requested for the step, so the directory is\n" +
+	"              # guaranteed to have room for it instead of relying on the image having\n" +
+	"              # a writable `/tmp` big enough.\n" +
+	"              scratch_size: ' '\n" +
+	"              # ShardCount is the number of pods the step is split into. All shards\n" +
+	"              # run in parallel and receive the $SHARD_INDEX (starting from zero) and\n" +
+	"              # $SHARD_TOTAL environment variables, which the step uses to select its\n" +
+	"              # part of the work. The step fails if any of its shards fail. The\n" +
+	"              # changes the shards make to $SHARED_DIR are merged, when several shards\n" +
+	"              # write the same file the last one to finish wins.\n" +
+	"              shard_count: 0\n" +
+	"              # Sidecars are helper containers which run alongside the commands of\n" +
+	"              # the step, e.g. local registries, tunnels or log forwarders. They are\n" +
+	"              # terminated when the commands exit.\n" +
+	"              sidecars:\n" +
+	"                - # Commands is the command(s) that will be run inside the sidecar.\n" +
+	"                  commands: ' '\n" +
+	"                  # From is the container image tag used for the sidecar, resolved like\n" +
+	"                  # the `from` field of the step.\n" +
+	"                  from: ' '\n" +
+	"                  # Name is the name of the container, unique within the step.\n" +
+	"                  name: ' '\n" +
+	"                  # Readiness is an optional command executed repeatedly in the sidecar\n" +
+	"                  # until it succeeds. The commands of the step are only started once the\n" +
+	"                  # sidecars are ready.\n" +
+	"                  readiness: ' '\n" +
+	"                  # Resources defines the resource requirements for the sidecar.\n" +
+	"                  resources:\n" +
+	"                    # Limits are resource limits applied to an individual step in the job.\n" +
+	"                    # These are directly used in creating the Pods that execute the Job.\n" +
+	"                    limits:\n" +
+	"                        \"\": \"\"\n" +
+	"                    # Requests are resource requests applied to an individual step in the job.\n" +
+	"                    # These are directly used in creating the Pods that execute the Job.\n" +
+	"                    requests:\n" +
+	"                        \"\": \"\"\n" +
+	"              # SplitLogs additionally writes the standard output and error of the\n" +
+	"              # commands of the step to separate files in `$ARTIFACT_DIR/logs`, which\n" +
+	"              # are rotated once they reach 10MiB. The output is then copied from\n" +
+	"              # pipes: processes the commands start in the background and which keep\n" +
+	"              # them open are not waited for longer than ten seconds after the commands\n" +
+	"              # exit, and what they write afterwards is only in the container log.\n" +
+	"              split_logs: false\n" +
+	"              # StdinFrom feeds the standard input of the commands of the step from a\n" +
+	"              # parameter of the step or a file in the shared directory, e.g. to drive\n" +
+	"              # installers which prompt for a confirmation. The standard input of the\n" +
+	"              # commands is empty if not set.\n" +
+	"              stdin_from:\n" +
+	"                # Parameter is the name of a parameter of the step, the value of which is\n" +
+	"                # written to the standard input followed by a newline, unless it already\n" +
+	"                # ends with one.\n" +
+	"                parameter: ' '\n" +
+	"                # SharedFile is the name of a file in the shared directory, the contents\n" +
+	"                # of which are written to the standard input. The step fails if the file\n" +
+	"                # does not exist when it starts.\n" +
+	"                shared_file: ' '\n" +
+	"              # Sysctls are set for the pod of the step. Sysctls which the kubelet does\n" +
+	"              # not consider safe require a cluster with the `unsafe-sysctls`\n" +
+	"              # capability.\n" +
+	"              sysctls:\n" +
+	"                - # Name of the parameter, e.g. `net.ipv6.conf.all.forwarding`.\n" +
+	"                  name: ' '\n" +
+	"                  # Value of the parameter.\n" +
+	"                  value: ' '\n" +
+	"              # Timeout is how long the we will wait before aborting a job with SIGINT.\n" +
+	"              timeout: 0s\n" +
+	"              # Toolkit sources the shell function library of steps before their\n" +
+	"              # commands, which provides `log`, `retry`, `wait_for` and `collect`. The\n" +
+	"              # library is mounted in the step and its path is exposed as $CI_TOOLKIT,\n" +
+	"              # for commands run as scripts to source it themselves.\n" +
+	"              toolkit: false\n" +
+	"              # Upgrade makes this a built-in step which upgrades the cluster under\n" +
+	"              # test, instead of running `commands` in a container. The upgrade is\n" +
+	"              # driven by ci-operator through the ClusterVersion of the cluster, using\n" +
+	"              # the `kubeconfig` in the shared directory.\n" +
+	"              upgrade:\n" +
+	"                # Channel is set on the ClusterVersion before the upgrade, if not empty.\n" +
+	"                channel: ' '\n" +
+	"                # Force upgrades to the release even if its signature cannot be verified\n" +
+	"                # or the cluster is not upgradeable.\n" +
+	"                force: true\n" +
+	"                # Release is the name of the release to upgrade to, e.g. `latest`.\n" +
+	"                release: ' '\n" +
+	"                # Rollback returns the cluster to the release it was running if the\n" +
+	"                # upgrade fails. The step still fails.\n" +
+	"                rollback: true\n" +
+	"              # WaitFor lists conditions on objects of the cluster under test which\n" +
+	"              # ci-operator waits for, in order, before the step starts, instead of the\n" +
+	"              # step polling the cluster itself. The step fails if a condition is not\n" +
+	"              # met in time. The cluster is accessed with the `kubeconfig` in the\n" +
+	"              # shared directory.\n" +
+	"              wait_for:\n" +
+	"                - # APIVersion of the object, e.g. `apps/v1`.\n" +
+	"                  api_version: ' '\n" +
+	"                  # JSONPath is a template evaluated against the object, in the syntax of\n" +
+	"                  # `oc get -o jsonpath`, e.g.\n" +
+	"                  # `{.status.conditions[?(@.type==\"Available\")].status}`.\n" +
+	"                  jsonpath: ' '\n" +
+	"                  # Kind of the object, e.g. `Deployment`.\n" +
+	"                  kind: ' '\n" +
+	"                  # Name of the object.\n" +
+	"                  name: ' '\n" +
+	"                  # Namespace of the object, empty for cluster-scoped objects.\n" +
+	"                  namespace: ' '\n" +
+	"                  # Timeout is how long to wait for the condition, 10 minutes by default.\n" +
+	"                  timeout: 0s\n" +
+	"                  # Value is the output of the template which meets the condition. If\n" +
+	"                  # empty, any non-empty output meets it.\n" +
+	"                  value: ' '\n" +
+	"              # Workdir is the path at which a writable scratch directory is mounted\n" +
+	"              # for the step, which is also the working directory of its commands,\n" +
+	"              # `/scratch` by default. The path is exposed as $SCRATCH_DIR. The\n" +
+	"              # scratch directory is only added if this or `scratch_size` is set.\n" +
+	"              # References to the parameters of the step, e.g. `$(PLATFORM)`, are\n" +
+	"              # expanded.\n" +
+	"              workdir: ' '\n" +
+	"        # ReuseClusterFrom is the name of another test of the configuration whose\n" +
+	"        # cluster this test runs against instead of provisioning one. The test\n" +
+	"        # starts with a snapshot of the shared directory of the other test, taken\n" +
+	"        # once its `pre` steps have installed the cluster, and the other test only\n" +
+	"        # runs its `post` steps once this test has finished. Cluster profiles and\n" +
+	"        # claims cannot be used.\n" +
+	"        reuse_cluster_from: ' '\n" +
+	"        # Rollbacks are the steps referenced by the `rollback` of other steps,\n" +
+	"        # executed only when those steps fail.\n" +
+	"        rollbacks:\n" +
+	"            - # Args are the arguments passed to Command.\n" +
+	"              args:\n" +
+	"                - \"\"\n" +
+	"              # As is the name of the LiteralTestStep.\n" +
+	"              as: ' '\n" +
+	"              # BestEffort defines if this step should cause the job to fail when the\n" +
+	"              # step fails. This only applies when AllowBestEffortPostSteps flag is set\n" +
+	"              # to true in MultiStageTestConfiguration. This option is applicable to\n" +
+	"              # `post` steps.\n" +
+	"              best_effort: false\n" +
+	"              # Burst lets the step use resources up to its limits, which are kept as\n" +
+	"              # configured instead of being derived from its requests, so that bursty\n" +
+	"              # steps can request what they use on average. Limits are capped by the\n" +
+	"              # policy of the build farm and the CPU throttling of the step is recorded\n" +
+	"              # in its metrics.\n" +
+	"              burst: false\n" +
+	"              # Cli is the (optional) name of the release from which the `oc` binary\n" +
+	"              # will be injected into this step. If the step has a `KUBECTL_RETRY`\n" +
+	"              # parameter, the binary is wrapped to retry calls which fail with a\n" +
+	"              # refused connection or a 429 response, at most that many times.\n" +
+	"              cli: ' '\n" +
+	"              # Command is the executable run inside the image, as an alternative to\n" +
+	"              # `commands` for images without bash, e.g. distroless or scratch-based\n" +
+	"              # tool images. It is executed directly with Args, without a shell.\n" +
+	"              command:\n" +
+	"                - \"\"\n" +
+	"              # Commands is the command(s) that will be run inside the image.\n" +
+	"              commands: ' '\n" +
+	"              # ConcurrencyGroup limits the number of instances of the step which run\n" +
+	"              # concurrently across all jobs, e.g. for heavyweight steps which would\n" +
+	"              # overload a shared service. A lease is acquired from the lease server\n" +
+	"              # for the duration of the step, so the limit is the number of resources\n" +
+	"              # of type `<group>-concurrency` configured in the server.\n" +
+	"              concurrency_group: ' '\n" +
+	"              # Credentials defines the credentials we'll mount into this step.\n" +
+	"              credentials:\n" +
+	"                - # MountPath is where the secret should be mounted. References to the\n" +
+	"                  # parameters of the step, e.g. `$(PLATFORM)`, are expanded.\n" +
+	"                  mount_path: ' '\n" +
+	"                  # Names is which source secret to mount.\n" +
+	"                  name: ' '\n" +
+	"                  # Namespace is where the source secret exists.\n" +
+	"                  namespace: ' '\n" +
+	"                  # Refresh propagates updates of the source secret to its copy in the\n" +
+	"                  # test namespace while the test runs, for credentials which are rotated\n" +
+	"                  # during long tests. Mounted files are updated by the kubelet shortly\n" +
+	"                  # after, so steps must read them again instead of caching their content.\n" +
+	"                  refresh: true\n" +
+	"                  # Selector mounts a collection of secrets instead of a single one: every\n" +
+	"                  # secret in the namespace which has the labels is mounted in a directory\n" +
+	"                  # named after it under the mount path. The collection is resolved when\n" +
+	"                  # the test starts, so secrets can be added to or removed from it without\n" +
+	"                  # changing the configuration of the step.\n" +
+	"                  selector:\n" +
+	"                    # MatchLabels are the labels the secrets must have.\n" +
+	"                    matchLabels:\n" +
+	"                        \"\": \"\"\n" +
+	"              # Critical protects the pods of the step from being evicted while they\n" +
+	"              # run, e.g. when the build farm scales down, for steps which cannot be\n" +
+	"              # interrupted without leaking resources, like cluster installation and\n" +
+	"              # teardown.\n" +
+	"              critical: false\n" +
+	"              # Dependencies lists images which must be available before the test runs\n" +
+	"              # and the environment variables which are used to expose their pull specs.\n" +
+	"              dependencies:\n" +
+	"                - # Env is the environment variable that the image's pull spec is exposed with.\n" +
+	"                  # References to the parameters of the step, e.g. `$(PLATFORM)`, are expanded.\n" +
+	"                  env: ' '\n" +
+	"                  # Name is the tag or stream:tag that this dependency references\n" +
+	"                  name: ' '\n" +
+	"              # DnsConfig for step's Pod.\n" +
+	"              dnsConfig:\n" +
+	"                # Nameservers is a list of IP addresses that will be used as DNS servers for the Pod\n" +
+	"                nameservers:\n" +
+	"                    - \"\"\n" +
+	"                # Searches is a list of DNS search domains for host-name lookup\n" +
+	"                searches:\n" +
+	"                    - \"\"\n" +
+	"              # Environment lists parameters that should be set by the test.\n" +
+	"              env:\n" +
+	"                - # Default if not set, optional, makes the parameter not required if set.\n" +
+	"                  default: \"\"\n" +
+	"                  # Documentation is a textual description of the parameter.\n" +
+	"                  documentation: ' '\n" +
+	"                  # Name of the environment variable.\n" +
+	"                  name: ' '\n" +
+	"              # FailOnRestart fails the step if a container of its pod is restarted\n" +
+	"              # while it runs, e.g. by the kubelet, which violates the restart policy\n" +
+	"              # of the pod. Restarts and warning events of the pod are reported in the\n" +
+	"              # results of the step either way.\n" +
+	"              fail_on_restart: false\n" +
+	"              # From is the container image that will be used for this step.\n" +
+	"              from: ' '\n" +
+	"              # FromImage is a literal ImageStreamTag reference to use for this step.\n" +
+	"              from_image:\n" +
+	"                # As is an optional string to use as the intermediate name for this reference.\n" +
+	"                as: ' '\n" +
+	"                name: ' '\n" +
+	"                namespace: ' '\n" +
+	"                tag: ' '\n" +
+	"              # Gang is the name of a group of consecutive steps of a phase which run\n" +
+	"              # in parallel and must be scheduled together, e.g. the client and server\n" +
+	"              # of a performance test. The pods of a gang are held back from the\n" +
+	"              # scheduler until all of them were created. If they are not all created\n" +
+	"              # in time, or a pod of the gang cannot be scheduled while its peers run,\n" +
+	"              # all pods of the gang are stopped and the gang fails.\n" +
+	"              # The changes the steps of a gang make to $SHARED_DIR are merged like\n" +
+	"              # those of shards.\n" +
+	"              gang: ' '\n" +
+	"              # Gather marks a `post` step which collects information about the test.\n" +
+	"              # Gather steps run after all other `post` steps, even if the test failed\n" +
+	"              # or was cancelled, each with a timeout of its own (30 minutes unless\n" +
+	"              # `timeout` is set). Their results are reported in a dedicated suite.\n" +
+	"              gather: false\n" +
+	"              # GracePeriod is how long the we will wait after sending SIGINT to send\n" +
+	"              # SIGKILL when aborting a Step.\n" +
+	"              grace_period: 0s\n" +
+	"              # HostNetwork runs the step in the network namespace of the node, e.g.\n" +
+	"              # for dual-stack tests which need the addresses of the node. Tests with\n" +
+	"              # such steps must run on a cluster with the `host-network` capability.\n" +
+	"              host_network: false\n" +
+	"              # Job runs the step as a Kubernetes Job instead of a bare pod, for steps\n" +
+	"              # which can safely be retried. The cluster replaces the pod of the step\n" +
+	"              # when it fails or is lost, e.g. with its node, up to `backoff_limit`\n" +
+	"              # times, and the step fails once the Job does.\n" +
+	"              job:\n" +
+	"                # TTLSecondsAfterFinished is the time after which the Job and its pods\n" +
+	"                # are removed once the step finished. They are kept with the namespace\n" +
+	"                # of the test if not set.\n" +
+	"                ttl_seconds_after_finished: 0\n" +
+	"              # Leases lists resources that should be acquired for the test.\n" +
+	"              leases:\n" +
+	"                - # Env is the environment variable that will contain the resource name.\n" +
+	"                  env: ' '\n" +
+	"                  # ResourceType is the type of resource that will be leased.\n" +
+	"                  resource_type: ' '\n" +
+	"              # Manifests are Kubernetes objects, as a stream of YAML documents, which\n" +
+	"              # are created on the cluster under test before the step runs and deleted\n" +
+	"              # once it completes, e.g. the RBAC objects or CRDs the step requires.\n" +
+	"              # Objects which exist are replaced. The cluster is accessed with the\n" +
+	"              # `kubeconfig` in the shared directory. In the registry, this is the name\n" +
+	"              # of a file next to the reference, e.g. `ipi-conf-manifests.yaml`.\n" +
+	"              manifests: ' '\n" +
+	"              # Mutex is the name of a lock held for the duration of the step, so that\n" +
+	"              # no two steps with the same mutex run at the same time across all jobs,\n" +
+	"              # e.g. for steps which modify a shared DNS zone. The lock is a lease of\n" +
+	"              # type `<mutex>-mutex`, of which the lease server must hold exactly one\n" +
+	"              # resource.\n" +
+	"              mutex: ' '\n" +
+	"              # NoKubeconfig determines that no $KUBECONFIG will exist in $SHARED_DIR,\n" +
+	"              # so no local copy of it will be created for the step and if the step\n" +
+	"              # creates one, it will not be propagated.\n" +
+	"              no_kubeconfig: false\n" +
+	"              # NodeCapabilities lists the capabilities the nodes running the step\n" +
+	"              # must have, e.g. `kvm`, `sse4`, `large-disk` or `arm64`. They are\n" +
+	"              # checked against the capabilities of the build farm before the test\n" +
+	"              # runs, and the step is scheduled on the nodes which provide them.\n" +
+	"              node_capabilities:\n" +
+	"                - \"\"\n" +
+	"              # Observers are the observers that should be running\n" +
+	"              observers:\n" +
+	"                - \"\"\n" +
+	"              # OptionalOnSuccess defines if this step should be skipped as long\n" +
+	"              # as all `pre` and `test` steps were successful and AllowSkipOnSuccess\n" +
+	"              # flag is set to true in MultiStageTestConfiguration. This option is\n" +
+	"              # applicable to `post` steps.\n" +
+	"              optional_on_success: false\n" +
+	"              # PreviousJobArtifacts exposes the artifacts of the last successful run\n" +
+	"              # of the same job to the step, e.g. for performance or compatibility\n" +
+	"              # tests which compare their results with a baseline.\n" +
+	"              previous_job_artifacts:\n" +
+	"                # Files are paths relative to the artifact directory of the previous run\n" +
+	"                # which are fetched before the step starts and mounted in the directory\n" +
+	"                # at `$PREVIOUS_JOB_ARTIFACTS_DIR`. Files which do not exist in the\n" +
+	"                # previous run are skipped. Not available to steps which run on the\n" +
+	"                # ephemeral cluster.\n" +
+	"                files:\n" +
+	"                    - \"\"\n" +
+	"              # PrivilegedApproval is the name of the entry of the central allowlist\n" +
+	"              # which approves the privileged capabilities the step uses, like\n" +
+	"              # `host_network` or `sysctls`. Where the allowlist is enforced, steps\n" +
+	"              # which use such capabilities cannot be resolved without an approval\n" +
+	"              # which lists the step and its capabilities.\n" +
+	"              privileged_approval: ' '\n" +
+	"              # ProvidesSharedFiles lists the files this step writes to $SHARED_DIR.\n" +
+	"              # The step fails if any of them is missing when its commands succeed.\n" +
+	"              # Steps requiring a file must run after a step providing it.\n" +
+	"              provides_shared_files:\n" +
+	"                - \"\"\n" +
+	"              # RequiresSharedFiles lists the files which previous steps must have\n" +
+	"              # written to $SHARED_DIR for this step to run. Their presence is\n" +
+	"              # verified before the step is executed.\n" +
+	"              requires_shared_files:\n" +
+	"                - \"\"\n" +
+	"              # Resources defines the resource requirements for the step.\n" +
+	"              resources:\n" +
+	"                # Limits are resource limits applied to an individual step in the job.\n" +
+	"                # These are directly used in creating the Pods that execute the Job.\n" +
+	"                limits:\n" +
+	"                    \"\": \"\"\n" +
+	"                # Requests are resource requests applied to an individual step in the job.\n" +
+	"                # These are directly used in creating the Pods that execute the Job.\n" +
+	"                requests:\n" +
+	"                    \"\": \"\"\n" +
+	"              # Rollback is the name of a step which is executed immediately if this\n" +
+	"              # step fails, before the test proceeds, to restore external state the\n" +
+	"              # step modified, e.g. DNS records or image tags. It references a step\n" +
+	"              # in the registry, which is resolved into the `rollbacks` of the test.\n" +
+	"              rollback: ' '\n" +
+	"              # RunAsScript defines if the commands of this step should be executed\n" +
+	"              # verbatim, using their own interpreter line, instead of being prefixed\n" +
+	"              # with the default bash preamble. The commands of every step are mounted\n" +
+	"              # as an executable script in the test container.\n" +
+	"              run_as_script: false\n" +
+	"              # RunOnEphemeralCluster runs the step as a pod on the cluster under test\n" +
+	"              # instead of the build farm, e.g. for tests which must run inside the\n" +
+	"              # network of the cluster. The step is executed with the `kubeconfig` in\n" +
+	"              # the shared directory, which must be provided by a previous step. The\n" +
+	"              # shared directory is available to the step, but changes to it are not\n" +
+	"              # propagated. The artifacts of the step are copied back once it exits.\n" +
+	"              run_on_ephemeral_cluster: false\n" +
+	"              # ScratchSize is the size of the scratch directory, e.g. `20Gi`. That\n" +
+	"              # much ephemeral storage is requested for the step, so the directory is\n" +
+	"              # guaranteed to have room for it instead of relying on the image having\n" +
+	"              # a writable `/tmp` big enough.\n" +
+	"              scratch_size: ' '\n" +
+	"              # ShardCount is the number of pods the step is split into. All shards\n" +
+	"              # run in parallel and receive the $SHARD_INDEX (starting from zero) and\n" +
+	"              # $SHARD_TOTAL environment variables, which the step uses to select its\n" +
+	"              # part of the work. The step fails if any of its shards fail. The\n" +
+	"              # changes the shards make to $SHARED_DIR are merged, when several shards\n" +
+	"              # write the same file the last one to finish wins.\n" +
+	"              shard_count: 0\n" +
+	"              # Sidecars are helper containers which run alongside the commands of\n" +
+	"              # the step, e.g. local registries, tunnels or log forwarders. They are\n" +
+	"              # terminated when the commands exit.\n" +
+	"              sidecars:\n" +
+	"                - # Commands is the command(s) that will be run inside the sidecar.\n" +
+	"                  commands: ' '\n" +
+	"                  # From is the container image tag used for the sidecar, resolved like\n" +
+	"                  # the `from` field of the step.\n" +
+	"                  from: ' '\n" +
+	"                  # Name is the name of the container, unique within the step.\n" +
+	"                  name: ' '\n" +
+	"                  # Readiness is an optional command executed repeatedly in the sidecar\n" +
+	"                  # until it succeeds. The commands of the step are only started once the\n" +
+	"                  # sidecars are ready.\n" +
+	"                  readiness: ' '\n" +
+	"                  # Resources defines the resource requirements for the sidecar.\n" +
+	"                  resources:\n" +
+	"                    # Limits are resource limits applied to an individual step in the job.\n" +
+	"                    # These are directly used in creating the Pods that execute the Job.\n" +
+	"                    limits:\n" +
+	"                        \"\": \"\"\n" +
+	"                    # Requests are resource requests applied to an individual step in the job.\n" +
+	"                    # These are directly used in creating the Pods that execute the Job.\n" +
+	"                    requests:\n" +
+	"                        \"\": \"\"\n" +
+	"              # SplitLogs additionally writes the standard output and error of the\n" +
+	"              # commands of the step to separate files in `$ARTIFACT_DIR/logs`, which\n" +
+	"              # are rotated once they reach 10MiB. The output is then copied from\n" +
+	"              # pipes: processes the commands start in the background and which keep\n" +
+	"              # them open are not waited for longer than ten seconds after the commands\n" +
+	"              # exit, and what they write afterwards is only in the container log.\n" +
+	"              split_logs: false\n" +
+	"              # StdinFrom feeds the standard input of the commands of the step from a\n" +
+	"              # parameter of the step or a file in the shared directory, e.g. to drive\n" +
+	"              # installers which prompt for a confirmation. The standard input of the\n" +
+	"              # commands is empty if not set.\n" +
+	"              stdin_from:\n" +
+	"                # Parameter is the name of a parameter of the step, the value of which is\n" +
+	"                # written to the standard input followed by a newline, unless it already\n" +
+	"                # ends with one.\n" +
+	"                parameter: ' '\n" +
+	"                # SharedFile is the name of a file in the shared directory, the contents\n" +
+	"                # of which are written to the standard input. The step fails if the file\n" +
+	"                # does not exist when it starts.\n" +
+	"                shared_file: ' '\n" +
+	"              # Sysctls are set for the pod of the step. Sysctls which the kubelet does\n" +
+	"              # not consider safe require a cluster with the `unsafe-sysctls`\n" +
+	"              # capability.\n" +
+	"              sysctls:\n" +
+	"                - # Name of the parameter, e.g. `net.ipv6.conf.all.forwarding`.\n" +
+	"                  name: ' '\n" +
+	"                  # Value of the parameter.\n" +
+	"                  value: ' '\n" +
+	"              # Timeout is how long the we will wait before aborting a job with SIGINT.\n" +
+	"              timeout: 0s\n" +
+	"              # Toolkit sources the shell function library of steps before their\n" +
+	"              # commands, which provides `log`, `retry`, `wait_for` and `collect`. The\n" +
+	"              # library is mounted in the step and its path is exposed as $CI_TOOLKIT,\n" +
+	"              # for commands run as scripts to source it themselves.\n" +
+	"              toolkit: false\n" +
+	"              # Upgrade makes this a built-in step which upgrades the cluster under\n" +
+	"              # test, instead of running `commands` in a container. The upgrade is\n" +
+	"              # driven by ci-operator through the ClusterVersion of the cluster, using\n" +
+	"              # the `kubeconfig` in the shared directory.\n" +
+	"              upgrade:\n" +
+	"                # Channel is set on the ClusterVersion before the upgrade, if not empty.\n" +
+	"                channel: ' '\n" +
+	"                # Force upgrades to the release even if its signature cannot be verified\n" +
+	"                # or the cluster is not upgradeable.\n" +
+	"                force: true\n" +
+	"                # Release is the name of the release to upgrade to, e.g. `latest`.\n" +
+	"                release: ' '\n" +
+	"                # Rollback returns the cluster to the release it was running if the\n" +
+	"                # upgrade fails. The step still fails.\n" +
+	"                rollback: true\n" +
+	"              # WaitFor lists conditions on objects of the cluster under test which\n" +
+	"              # ci-operator waits for, in order, before the step starts, instead of the\n" +
+	"              # step polling the cluster itself. The step fails if a condition is not\n" +
+	"              # met in time. The cluster is accessed with the `kubeconfig` in the\n" +
+	"              # shared directory.\n" +
+	"              wait_for:\n" +
+	"                - # APIVersion of the object, e.g. `apps/v1`.\n" +
+	"                  api_version: ' '\n" +
+	"                  # JSONPath is a template evaluated against the object, in the syntax of\n" +
+	"                  # `oc get -o jsonpath`, e.g.\n" +
+	"                  # `{.status.conditions[?(@.type==\"Available\")].status}`.\n" +
+	"                  jsonpath: ' '\n" +
+	"                  # Kind of the object, e.g. `Deployment`.\n" +
+	"                  kind: ' '\n" +
+	"                  # Name of the object.\n" +
+	"                  name: ' '\n" +
+	"                  # Namespace of the object, empty for cluster-scoped objects.\n" +
+	"                  namespace: ' '\n" +
+	"                  # Timeout is how long to wait for the condition, 10 minutes by default.\n" +
+	"                  timeout: 0s\n" +
+	"                  # Value is the output of the template which meets the condition. If\n" +
+	"                  # empty, any non-empty output meets it.\n" +
+	"                  value: ' '\n" +
+	"              # Workdir is the path at which a writable scratch directory is mounted\n" +
+	"              # for the step, which is also the working directory of its commands,\n" +
+	"              # `/scratch` by default. The path is exposed as $SCRATCH_DIR. The\n" +
+	"              # scratch directory is only added if this or `scratch_size` is set.\n" +
+	"              # References to the parameters of the step, e.g. `$(PLATFORM)`, are\n" +
+	"              # expanded.\n" +
+	"              workdir: ' '\n" +
+	"        # StepReleases maps the names of steps to the release they use in place\n" +
+	"        # of `latest`: their dependencies on `release:latest` and on images of the\n" +
+	"        # `stable` stream, and the $RELEASE_IMAGE_LATEST given to them with a\n" +
+	"        # cluster profile, refer to the named release instead. This allows the\n" +
+	"        # steps of one test to use different payloads, e.g. for the management\n" +
+	"        # and the hosted clusters of a HyperShift test.\n" +
+	"        step_releases:\n" +
+	"            \"\": \"\"\n" +
+	"        # Test is the array of test steps that define the actual test.\n" +
+	"        test:\n" +
+	"            - # Args are the arguments passed to Command.\n" +
+	"              args:\n" +
+	"                - \"\"\n" +
+	"              # As is the name of the LiteralTestStep.\n" +
+	"              as: ' '\n" +
+	"              # BestEffort defines if this step should cause the job to fail when the\n" +
+	"              # step fails. This only applies when AllowBestEffortPostSteps flag is set\n" +
 	"              # to true in MultiStageTestConfiguration. This option is applicable to\n" +
 	"              # `post` steps.\n" +
 	"              best_effort: false\n" +
+	"              # Burst lets the step use resources up to its limits, which are kept as\n" +
+	"              # configured instead of being derived from its requests, so that bursty\n" +
+	"              # steps can request what they use on average. Limits are capped by the\n" +
+	"              # policy of the build farm and the CPU throttling of the step is recorded\n" +
+	"              # in its metrics.\n" +
+	"              burst: false\n" +
 	"              # Cli is the (optional) name of the release from which the `oc` binary\n" +
-	"              # will be injected into this step.\n" +
+	"              # will be injected into this step. If the step has a `KUBECTL_RETRY`\n" +
+	"              # parameter, the binary is wrapped to retry calls which fail with a\n" +
+	"              # refused connection or a 429 response, at most that many times.\n" +
 	"              cli: ' '\n" +
+	"              # Command is the executable run inside the image, as an alternative to\n" +
+	"              # `commands` for images without bash, e.g. distroless or scratch-based\n" +
+	"              # tool images. It is executed directly with Args, without a shell.\n" +
+	"              command:\n" +
+	"                - \"\"\n" +
 	"              # Commands is the command(s) that will be run inside the image.\n" +
 	"              commands: ' '\n" +
+	"              # ConcurrencyGroup limits the number of instances of the step which run\n" +
+	"              # concurrently across all jobs, e.g. for heavyweight steps which would\n" +
+	"              # overload a shared service. A lease is acquired from the lease server\n" +
+	"              # for the duration of the step, so the limit is the number of resources\n" +
+	"              # of type `<group>-concurrency` configured in the server.\n" +
+	"              concurrency_group: ' '\n" +
 	"              # Credentials defines the credentials we'll mount into this step.\n" +
 	"              credentials:\n" +
-	"                - # MountPath is where the secret should be mounted.\n" +
+	"                - # MountPath is where the secret should be mounted. References to the\n" +
+	"                  # parameters of the step, e.g. `$(PLATFORM)`, are expanded.\n" +
 	"                  mount_path: ' '\n" +
 	"                  # Names is which source secret to mount.\n" +
 	"                  name: ' '\n" +
 	"                  # Namespace is where the source secret exists.\n" +
 	"                  namespace: ' '\n" +
+	"                  # Refresh propagates updates of the source secret to its copy in the\n" +
+	"                  # test namespace while the test runs, for credentials which are rotated\n" +
+	"                  # during long tests. Mounted files are updated by the kubelet shortly\n" +
+	"                  # after, so steps must read them again instead of caching their content.\n" +
+	"                  refresh: true\n" +
+	"                  # Selector mounts a collection of secrets instead of a single one: every\n" +
+	"                  # secret in the namespace which has the labels is mounted in a directory\n" +
+	"                  # named after it under the mount path. The collection is resolved when\n" +
+	"                  # the test starts, so secrets can be added to or removed from it without\n" +
+	"                  # changing the configuration of the step.\n" +
+	"                  selector:\n" +
+	"                    # MatchLabels are the labels the secrets must have.\n" +
+	"                    matchLabels:\n" +
+	"                        \"\": \"\"\n" +
+	"              # Critical protects the pods of the step from being evicted while they\n" +
+	"              # run, e.g. when the build farm scales down, for steps which cannot be\n" +
+	"              # interrupted without leaking resources, like cluster installation and\n" +
+	"              # teardown.\n" +
+	"              critical: false\n" +
 	"              # Dependencies lists images which must be available before the test runs\n" +
 	"              # and the environment variables which are used to expose their pull specs.\n" +
 	"              dependencies:\n" +
-	"                - # Env is the environment variable that the image's pull spec is exposed with\n" +
+	"                - # Env is the environment variable that the image's pull spec is exposed with.\n" +
+	"                  # References to the parameters of the step, e.g. `$(PLATFORM)`, are expanded.\n" +
 	"                  env: ' '\n" +
 	"                  # Name is the tag or stream:tag that this dependency references\n" +
 	"                  name: ' '\n" +
@@ -1695,6 +4168,11 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"                  documentation: ' '\n" +
 	"                  # Name of the environment variable.\n" +
 	"                  name: ' '\n" +
+	"              # FailOnRestart fails the step if a container of its pod is restarted\n" +
+	"              # while it runs, e.g. by the kubelet, which violates the restart policy\n" +
+	"              # of the pod. Restarts and warning events of the pod are reported in the\n" +
+	"              # results of the step either way.\n" +
+	"              fail_on_restart: false\n" +
 	"              # From is the container image that will be used for this step.\n" +
 	"              from: ' '\n" +
 	"              # FromImage is a literal ImageStreamTag reference to use for this step.\n" +
@@ -1704,19 +4182,65 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"                name: ' '\n" +
 	"                namespace: ' '\n" +
 	"                tag: ' '\n" +
+	"              # Gang is the name of a group of consecutive steps of a phase which run\n" +
+	"              # in parallel and must be scheduled together, e.g. the client and server\n" +
+	"              # of a performance test. The pods of a gang are held back from the\n" +
+	"              # scheduler until all of them were created. If they are not all created\n" +
+	"              # in time, or a pod of the gang cannot be scheduled while its peers run,\n" +
+	"              # all pods of the gang are stopped and the gang fails.\n" +
+	"              # The changes the steps of a gang make to $SHARED_DIR are merged like\n" +
+	"              # those of shards.\n" +
+	"              gang: ' '\n" +
+	"              # Gather marks a `post` step which collects information about the test.\n" +
+	"              # Gather steps run after all other `post` steps, even if the test failed\n" +
+	"              # or was cancelled, each with a timeout of its own (30 minutes unless\n" +
+	"              # `timeout` is set). Their results are reported in a dedicated suite.\n" +
+	"              gather: false\n" +
 	"              # GracePeriod is how long the we will wait after sending SIGINT to send\n" +
 	"              # SIGKILL when aborting a Step.\n" +
 	"              grace_period: 0s\n" +
+	"              # HostNetwork runs the step in the network namespace of the node, e.g.\n" +
+	"              # for dual-stack tests which need the addresses of the node. Tests with\n" +
+	"              # such steps must run on a cluster with the `host-network` capability.\n" +
+	"              host_network: false\n" +
+	"              # Job runs the step as a Kubernetes Job instead of a bare pod, for steps\n" +
+	"              # which can safely be retried. The cluster replaces the pod of the step\n" +
+	"              # when it fails or is lost, e.g. with its node, up to `backoff_limit`\n" +
+	"              # times, and the step fails once the Job does.\n" +
+	"              job:\n" +
+	"                # TTLSecondsAfterFinished is the time after which the Job and its pods\n" +
+	"                # are removed once the step finished. They are kept with the namespace\n" +
+	"                # of the test if not set.\n" +
+	"                ttl_seconds_after_finished: 0\n" +
 	"              # Leases lists resources that should be acquired for the test.\n" +
 	"              leases:\n" +
 	"                - # Env is the environment variable that will contain the resource name.\n" +
 	"                  env: ' '\n" +
 	"                  # ResourceType is the type of resource that will be leased.\n" +
 	"                  resource_type: ' '\n" +
+	"              # Manifests are Kubernetes objects, as a stream of YAML documents, which\n" +
+	"              # are created on the cluster under test before the step runs and deleted\n" +
+	"              # once it completes, e.g. the RBAC objects or CRDs the step requires.\n" +
+	"              # Objects which exist are replaced. The cluster is accessed with the\n" +
+	"              # `kubeconfig` in the shared directory. In the registry, this is the name\n" +
+	"              # of a file next to the reference, e.g. `ipi-conf-manifests.yaml`.\n" +
+	"              manifests: ' '\n" +
+	"              # Mutex is the name of a lock held for the duration of the step, so that\n" +
+	"              # no two steps with the same mutex run at the same time across all jobs,\n" +
+	"              # e.g. for steps which modify a shared DNS zone. The lock is a lease of\n" +
+	"              # type `<mutex>-mutex`, of which the lease server must hold exactly one\n" +
+	"              # resource.\n" +
+	"              mutex: ' '\n" +
 	"              # NoKubeconfig determines that no $KUBECONFIG will exist in $SHARED_DIR,\n" +
 	"              # so no local copy of it will be created for the step and if the step\n" +
 	"              # creates one, it will not be propagated.\n" +
 	"              no_kubeconfig: false\n" +
+	"              # NodeCapabilities lists the capabilities the nodes running the step\n" +
+	"              # must have, e.g. `kvm`, `sse4`, `large-disk` or `arm64`. They are\n" +
+	"              # checked against the capabilities of the build farm before the test\n" +
+	"              # runs, and the step is scheduled on the nodes which provide them.\n" +
+	"              node_capabilities:\n" +
+	"                - \"\"\n" +
 	"              # Observers are the observers that should be running\n" +
 	"              observers:\n" +
 	"                - \"\"\n" +
@@ -1725,6 +4249,33 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"              # flag is set to true in MultiStageTestConfiguration. This option is\n" +
 	"              # applicable to `post` steps.\n" +
 	"              optional_on_success: false\n" +
+	"              # PreviousJobArtifacts exposes the artifacts of the last successful run\n" +
+	"              # of the same job to the step, e.g. for performance or compatibility\n" +
+	"              # tests which compare their results with a baseline.\n" +
+	"              previous_job_artifacts:\n" +
+	"                # Files are paths relative to the artifact directory of the previous run\n" +
+	"                # which are fetched before the step starts and mounted in the directory\n" +
+	"                # at `$PREVIOUS_JOB_ARTIFACTS_DIR`. Files which do not exist in the\n" +
+	"                # previous run are skipped. Not available to steps which run on the\n" +
+	"                # ephemeral cluster.\n" +
+	"                files:\n" +
+	"                    - \"\"\n" +
+	"              # PrivilegedApproval is the name of the entry of the central allowlist\n" +
+	"              # which approves the privileged capabilities the step uses, like\n" +
+	"              # `host_network` or `sysctls`. Where the allowlist is enforced, steps\n" +
+	"              # which use such capabilities cannot be resolved without an approval\n" +
+	"              # which lists the step and its capabilities.\n" +
+	"              privileged_approval: ' '\n" +
+	"              # ProvidesSharedFiles lists the files this step writes to $SHARED_DIR.\n" +
+	"              # The step fails if any of them is missing when its commands succeed.\n" +
+	"              # Steps requiring a file must run after a step providing it.\n" +
+	"              provides_shared_files:\n" +
+	"                - \"\"\n" +
+	"              # RequiresSharedFiles lists the files which previous steps must have\n" +
+	"              # written to $SHARED_DIR for this step to run. Their presence is\n" +
+	"              # verified before the step is executed.\n" +
+	"              requires_shared_files:\n" +
+	"                - \"\"\n" +
 	"              # Resources defines the resource requirements for the step.\n" +
 	"              resources:\n" +
 	"                # Limits are resource limits applied to an individual step in the job.\n" +
@@ -1735,11 +4286,140 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"                # These are directly used in creating the Pods that execute the Job.\n" +
 	"                requests:\n" +
 	"                    \"\": \"\"\n" +
-	"              # RunAsScript defines if this step should be executed as a script mounted\n" +
-	"              # in the test container instead of being executed directly via bash\n" +
+	"              # Rollback is the name of a step which is executed immediately if this\n" +
+	"              # step fails, before the test proceeds, to restore external state the\n" +
+	"              # step modified, e.g. DNS records or image tags. It references a step\n" +
+	"              # in the registry, which is resolved into the `rollbacks` of the test.\n" +
+	"              rollback: ' '\n" +
+	"              # RunAsScript defines if the commands of this step should be executed\n" +
+	"              # verbatim, using their own interpreter line, instead of being prefixed\n" +
+	"              # with the default bash preamble. The commands of every step are mounted\n" +
+	"              # as an executable script in the test container.\n" +
 	"              run_as_script: false\n" +
+	"              # RunOnEphemeralCluster runs the step as a pod on the cluster under test\n" +
+	"              # instead of the build farm, e.g. for tests which must run inside the\n" +
+	"              # network of the cluster. The step is executed with the `kubeconfig` in\n" +
+	"              # the shared directory, which must be provided by a previous step. The\n" +
+	"              # shared directory is available to the step, but changes to it are not\n" +
+	"              # propagated. The artifacts of the step are copied back once it exits.\n" +
+	"              run_on_ephemeral_cluster: false\n" +
+	"              # ScratchSize is the size of the scratch directory, e.g. `20Gi`. That\n" +
+	"              # much ephemeral storage is requested for the step, so the directory is\n" +
+	"              # guaranteed to have room for it instead of relying on the image having\n" +
+	"              # a writable `/tmp` big enough.\n" +
+	"              scratch_size: ' '\n" +
+	"              # ShardCount is the number of pods the step is split into. All shards\n" +
+	"              # run in parallel and receive the $SHARD_INDEX (starting from zero) and\n" +
+	"              # $SHARD_TOTAL environment variables, which the step uses to select its\n" +
+	"              # part of the work. The step fails if any of its shards fail. The\n" +
+	"              # changes the shards make to $SHARED_DIR are merged, when several shards\n" +
+	"              # write the same file the last one to finish wins.\n" +
+	"              shard_count: 0\n" +
+	"              # Sidecars are helper containers which run alongside the commands of\n" +
+	"              # the step, e.g. local registries, tunnels or log forwarders. They are\n" +
+	"              # terminated when the commands exit.\n" +
+	"              sidecars:\n" +
+	"                - # Commands is the command(s) that will be run inside the sidecar.\n" +
+	"                  commands: ' '\n" +
+	"                  # From is the container image tag used for the sidecar, resolved like\n" +
+	"                  # the `from` field of the step.\n" +
+	"                  from: ' '\n" +
+	"                  # Name is the name of the container, unique within the step.\n" +
+	"                  name: ' '\n" +
+	"                  # Readiness is an optional command executed repeatedly in the sidecar\n" +
+	"                  # until it succeeds. The commands of the step are only started once the\n" +
+	"                  # sidecars are ready.\n" +
+	"                  readiness: ' '\n" +
+	"                  # Resources defines the resource requirements for the sidecar.\n" +
+	"                  resources:\n" +
+	"                    # Limits are resource limits applied to an individual step in the job.\n" +
+	"                    # These are directly used in creating the Pods that execute the Job.\n" +
+	"                    limits:\n" +
+	"                        \"\": \"\"\n" +
+	"                    # Requests are resource requests applied to an individual step in the job.\n" +
+	"                    # These are directly used in creating the Pods that execute the Job.\n" +
+	"                    requests:\n" +
+	"                        \"\": \"\"\n" +
+	"              # SplitLogs additionally writes the standard output and error of the\n" +
+	"              # commands of the step to separate files in `$ARTIFACT_DIR/logs`, which\n" +
+	"              # are rotated once they reach 10MiB. The output is then copied from\n" +
+	"              # pipes: processes the commands start in the background and which keep\n" +
+	"              # them open are not waited for longer than ten seconds after the commands\n" +
+	"              # exit, and what they write afterwards is only in the container log.\n" +
+	"              split_logs: false\n" +
+	"              # StdinFrom feeds the standard input of the commands of the step from a\n" +
+	"              # parameter of the step or a file in the shared directory, e.g. to drive\n" +
+	"              # installers which prompt for a confirmation. The standard input of the\n" +
+	"              # commands is empty if not set.\n" +
+	"              stdin_from:\n" +
+	"                # Parameter is the name of a parameter of the step, the value of which is\n" +
+	"                # written to the standard input followed by a newline, unless it already\n" +
+	"                # ends with one.\n" +
+	"                parameter: ' '\n" +
+	"                # SharedFile is the name of a file in the shared directory, the contents\n" +
+	"                # of which are written to the standard input. The step fails if the file\n" +
+	"                # does not exist when it starts.\n" +
+	"                shared_file: ' '\n" +
+	"              # Sysctls are set for the pod of the step. Sysctls which the kubelet does\n" +
+	"              # not consider safe require a cluster with the `unsafe-sysctls`\n" +
+	"              # capability.\n" +
+	"              sysctls:\n" +
+	"                - # Name of the parameter, e.g. `net.ipv6.conf.all.forwarding`.\n" +
+	"                  name: ' '\n" +
+	"                  # Value of the parameter.\n" +
+	"                  value: ' '\n" +
 	"              # Timeout is how long the we will wait before aborting a job with SIGINT.\n" +
 	"              timeout: 0s\n" +
+	"              # Toolkit sources the shell function library of steps before their\n" +
+	"              # commands, which provides `log`, `retry`, `wait_for` and `collect`. The\n" +
+	"              # library is mounted in the step and its path is exposed as $CI_TOOLKIT,\n" +
+	"              # for commands run as scripts to source it themselves.\n" +
+	"              toolkit: false\n" +
+	"              # Upgrade makes this a built-in step which upgrades the cluster under\n" +
+	"              # test, instead of running `commands` in a container. The upgrade is\n" +
+	"              # driven by ci-operator through the ClusterVersion of the cluster, using\n" +
+	"              # the `kubeconfig` in the shared directory.\n" +
+	"              upgrade:\n" +
+	"                # Channel is set on the ClusterVersion before the upgrade, if not empty.\n" +
+	"                channel: ' '\n" +
+	"                # Force upgrades to the release even if its signature cannot be verified\n" +
+	"                # or the cluster is not upgradeable.\n" +
+	"                force: true\n" +
+	"                # Release is the name of the release to upgrade to, e.g. `latest`.\n" +
+	"                release: ' '\n" +
+	"                # Rollback returns the cluster to the release it was running if the\n" +
+	"                # upgrade fails. The step still fails.\n" +
+	"                rollback: true\n" +
+	"              # WaitFor lists conditions on objects of the cluster under test which\n" +
+	"              # ci-operator waits for, in order, before the step starts, instead of the\n" +
+	"              # step polling the cluster itself. The step fails if a condition is not\n" +
+	"              # met in time. The cluster is accessed with the `kubeconfig` in the\n" +
+	"              # shared directory.\n" +
+	"              wait_for:\n" +
+	"                - # APIVersion of the object, e.g. `apps/v1`.\n" +
+	"                  api_version: ' '\n" +
+	"                  # JSONPath is a template evaluated against the object, in the syntax of\n" +
+	"                  # `oc get -o jsonpath`, e.g.\n" +
+	"                  # `{.status.conditions[?(@.type==\"Available\")].status}`.\n" +
+	"                  jsonpath: ' '\n" +
+	"                  # Kind of the object, e.g. `Deployment`.\n" +
+	"                  kind: ' '\n" +
+	"                  # Name of the object.\n" +
+	"                  name: ' '\n" +
+	"                  # Namespace of the object, empty for cluster-scoped objects.\n" +
+	"                  namespace: ' '\n" +
+	"                  # Timeout is how long to wait for the condition, 10 minutes by default.\n" +
+	"                  timeout: 0s\n" +
+	"                  # Value is the output of the template which meets the condition. If\n" +
+	"                  # empty, any non-empty output meets it.\n" +
+	"                  value: ' '\n" +
+	"              # Workdir is the path at which a writable scratch directory is mounted\n" +
+	"              # for the step, which is also the working directory of its commands,\n" +
+	"              # `/scratch` by default. The path is exposed as $SCRATCH_DIR. The\n" +
+	"              # scratch directory is only added if this or `scratch_size` is set.\n" +
+	"              # References to the parameters of the step, e.g. `$(PLATFORM)`, are\n" +
+	"              # expanded.\n" +
+	"              workdir: ' '\n" +
 	"        # Override job timeout\n" +
 	"        timeout: 0s\n" +
 	"      # MinimumInterval to wait between two runs of the job. Consecutive\n" +
@@ -1813,8 +4493,29 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"        # all previous `pre` and `test` steps were successful. The given step must explicitly\n" +
 	"        # ask for being skipped by setting the OptionalOnSuccess flag to true.\n" +
 	"        allow_skip_on_success: false\n" +
+	"        # ClusterHealthGate configures checks of the health of the cluster under\n" +
+	"        # test which must pass after the `pre` phase before `test` steps are run.\n" +
+	"        cluster_health_gate:\n" +
+	"            # Checks are the checks which must pass. All checks are run when empty.\n" +
+	"            checks:\n" +
+	"                - \"\"\n" +
+	"            # Timeout is how long to wait for the checks to pass, defaults to 10m.\n" +
+	"            timeout: 0s\n" +
 	"        # ClusterProfile defines the profile/cloud provider for end-to-end test steps.\n" +
+	"        # Steps receive a $CLUSTER_NAME unique to the test and short enough for\n" +
+	"        # the platform and, when it is known, the $BASE_DOMAIN of the profile.\n" +
+	"        # Once the `pre` steps have completed, the following steps receive the\n" +
+	"        # $CONSOLE_URL, $API_URL and $OCP_VERSION of the cluster which was\n" +
+	"        # installed, also as the values of the step parameters of these names.\n" +
+	"        # Steps mount a copy of the profile owned by the test, in which the keys\n" +
+	"        # of the `<test>-cluster-profile-overrides` secret, if it exists, replace\n" +
+	"        # those of the profile.\n" +
 	"        cluster_profile: ' '\n" +
+	"        # ContainerOnly declares that the steps of the test do not interact with\n" +
+	"        # any cluster. Steps are not given a kubeconfig, run with reduced\n" +
+	"        # permissions in the test namespace and have a default timeout of one\n" +
+	"        # hour. Cluster profiles, claims, observers and leases cannot be used.\n" +
+	"        container_only: false\n" +
 	"        # Dependencies holds override values for dependency parameters.\n" +
 	"        dependencies:\n" +
 	"            \"\": \"\"\n" +
@@ -1822,6 +4523,25 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"        # be used with rehearsals. Otherwise, the overrides should be passed in as parameters to ci-operator.\n" +
 	"        dependency_overrides:\n" +
 	"            \"\": \"\"\n" +
+	"        # DisruptionMonitor configures an availability monitor which runs in\n" +
+	"        # ci-operator during the `test` phase.\n" +
+	"        disruption_monitor:\n" +
+	"            # Interval is the time between two requests to a target, defaults to 1s.\n" +
+	"            interval: 0s\n" +
+	"            # Targets are the endpoints which are polled.\n" +
+	"            targets:\n" +
+	"                - # InsecureSkipTLSVerify disables the verification of the certificate\n" +
+	"                  # served by the target, which is necessary when polling endpoints of\n" +
+	"                  # ephemeral clusters with self-signed certificates.\n" +
+	"                  insecure_skip_tls_verify: true\n" +
+	"                  # MaxDisruption is the total unavailability tolerated during the `test`\n" +
+	"                  # phase. When unset, disruption is only reported.\n" +
+	"                  max_disruption: 0s\n" +
+	"                  # Name identifies the target in the report.\n" +
+	"                  name: ' '\n" +
+	"                  # URL is requested with GET on every poll. The target is considered\n" +
+	"                  # available when the request succeeds with a status code below 500.\n" +
+	"                  url: ' '\n" +
 	"        # DnsConfig for step's Pod.\n" +
 	"        dnsConfig:\n" +
 	"            # Nameservers is a list of IP addresses that will be used as DNS servers for the Pod\n" +
@@ -1833,6 +4553,17 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"        # Environment has the values of parameters for the steps.\n" +
 	"        env:\n" +
 	"            \"\": \"\"\n" +
+	"        # EnvironmentFromFile has the values of parameters for the steps which are\n" +
+	"        # read from files, relative to the directory of the configuration file.\n" +
+	"        # The files are read when the configuration is loaded and their contents\n" +
+	"        # are added to Environment.\n" +
+	"        env_from_file:\n" +
+	"            \"\": \"\"\n" +
+	"        # FeatureSet is the OpenShift feature set the cluster under test is\n" +
+	"        # installed with, e.g. TechPreviewNoUpgrade. It is exposed to the steps\n" +
+	"        # as $FEATURE_SET and as the value of their parameter of that name, and\n" +
+	"        # is validated against the version of the `latest` release.\n" +
+	"        feature_set: ' '\n" +
 	"        # Leases lists resources that should be acquired for the test.\n" +
 	"        leases:\n" +
 	"            - # Env is the environment variable that will contain the resource name.\n" +
@@ -1852,19 +4583,42 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"        # execution if previous Pre and Test steps passed.\n" +
 	"        post:\n" +
 	"            # LiteralTestStep is a full test step definition.\n" +
-	"            - as: ' '\n" +
+	"            - # Alias disambiguates steps whose names would otherwise conflict, e.g.\n" +
+	"              # when two chains contribute steps with the same name. For a reference,\n" +
+	"              # it replaces the name of the step. For a chain, it is prepended to the\n" +
+	"              # names of all the steps of the chain, e.g. `upgrade-gather-must-gather`\n" +
+	"              # for the step `gather-must-gather` of a chain with the alias `upgrade`.\n" +
+	"              alias: ' '\n" +
+	"              args:\n" +
+	"                # LiteralTestStep is a full test step definition.\n" +
+	"                - \"\"\n" +
+	"              as: ' '\n" +
 	"              best_effort: false\n" +
+	"              burst: false\n" +
 	"              # Chain is the name of a step chain reference.\n" +
 	"              chain: \"\"\n" +
 	"              # Cli is the (optional) name of the release from which the `oc` binary\n" +
-	"              # will be injected into this step.\n" +
+	"              # will be injected into this step. If the step has a `KUBECTL_RETRY`\n" +
+	"              # parameter, the binary is wrapped to retry calls which fail with a\n" +
+	"              # refused connection or a 429 response, at most that many times.\n" +
 	"              cli: ' '\n" +
+	"              command:\n" +
+	"                # LiteralTestStep is a full test step definition.\n" +
+	"                - \"\"\n" +
 	"              commands: ' '\n" +
+	"              concurrency_group: ' '\n" +
 	"              credentials:\n" +
 	"                # LiteralTestStep is a full test step definition.\n" +
 	"                - mount_path: ' '\n" +
 	"                  name: ' '\n" +
 	"                  namespace: ' '\n" +
+	"                  refresh: true\n" +
+	"                  selector:\n" +
+	"                    # LiteralTestStep is a full test step definition.\n" +
+	"                    matchLabels:\n" +
+	"                        # LiteralTestStep is a full test step definition.\n" +
+	"                        \"\": \"\"\n" +
+	"              critical: false\n" +
 	"              dependencies:\n" +
 	"                # LiteralTestStep is a full test step definition.\n" +
 	"                - env: ' '\n" +
@@ -1882,6 +4636,7 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"                - default: \"\"\n" +
 	"                  documentation: ' '\n" +
 	"                  name: ' '\n" +
+	"              fail_on_restart: false\n" +
 	"              from: ' '\n" +
 	"              from_image:\n" +
 	"                # LiteralTestStep is a full test step definition.\n" +
@@ -1889,19 +4644,44 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"                name: ' '\n" +
 	"                namespace: ' '\n" +
 	"                tag: ' '\n" +
+	"              gang: ' '\n" +
+	"              gather: false\n" +
 	"              grace_period: 0s\n" +
+	"              host_network: false\n" +
+	"              job:\n" +
+	"                # LiteralTestStep is a full test step definition.\n" +
+	"                ttl_seconds_after_finished: 0\n" +
 	"              leases:\n" +
 	"                # LiteralTestStep is a full test step definition.\n" +
 	"                - env: ' '\n" +
 	"                  resource_type: ' '\n" +
+	"              manifests: ' '\n" +
+	"              mutex: ' '\n" +
 	"              no_kubeconfig: false\n" +
+	"              node_capabilities:\n" +
+	"                # LiteralTestStep is a full test step definition.\n" +
+	"                - \"\"\n" +
 	"              observers:\n" +
 	"                # LiteralTestStep is a full test step definition.\n" +
 	"                - \"\"\n" +
 	"              optional_on_success: false\n" +
+	"              previous_job_artifacts:\n" +
+	"                # LiteralTestStep is a full test step definition.\n" +
+	"                files:\n" +
+	"                    # LiteralTestStep is a full test step definition.\n" +
+	"                    - \"\"\n" +
+	"              privileged_approval: ' '\n" +
+	"              provides_shared_files:\n" +
+	"                # LiteralTestStep is a full test step definition.\n" +
+	"                - \"\"\n" +
 	"              # Reference is the name of a step reference.\n" +
 	"              ref: \"\"\n" +
-	"              # Resources defines the resource requirements for the step.\n" +
+	"              # RequiresSharedFiles lists the files which previous steps must have\n" +
+	"              # written to $SHARED_DIR for this step to run. Their presence is\n" +
+	"              # verified before the step is executed.\n" +
+	"              requires_shared_files:\n" +
+	"                # LiteralTestStep is a full test step definition.\n" +
+	"                - \"\"\n" +
 	"              resources:\n" +
 	"                # LiteralTestStep is a full test step definition.\n" +
 	"                limits:\n" +
@@ -1910,24 +4690,91 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"                requests:\n" +
 	"                    # LiteralTestStep is a full test step definition.\n" +
 	"                    \"\": \"\"\n" +
+	"              rollback: ' '\n" +
 	"              run_as_script: false\n" +
+	"              run_on_ephemeral_cluster: false\n" +
+	"              scratch_size: ' '\n" +
+	"              shard_count: 0\n" +
+	"              sidecars:\n" +
+	"                # LiteralTestStep is a full test step definition.\n" +
+	"                - commands: ' '\n" +
+	"                  from: ' '\n" +
+	"                  name: ' '\n" +
+	"                  readiness: ' '\n" +
+	"                  resources:\n" +
+	"                    # LiteralTestStep is a full test step definition.\n" +
+	"                    limits:\n" +
+	"                        # LiteralTestStep is a full test step definition.\n" +
+	"                        \"\": \"\"\n" +
+	"                    requests:\n" +
+	"                        # LiteralTestStep is a full test step definition.\n" +
+	"                        \"\": \"\"\n" +
+	"              split_logs: false\n" +
+	"              stdin_from:\n" +
+	"                # LiteralTestStep is a full test step definition.\n" +
+	"                parameter: ' '\n" +
+	"                shared_file: ' '\n" +
+	"              sysctls:\n" +
+	"                # LiteralTestStep is a full test step definition.\n" +
+	"                - name: ' '\n" +
+	"                  value: ' '\n" +
 	"              timeout: 0s\n" +
+	"              toolkit: false\n" +
+	"              upgrade:\n" +
+	"                # LiteralTestStep is a full test step definition.\n" +
+	"                channel: ' '\n" +
+	"                force: true\n" +
+	"                release: ' '\n" +
+	"                rollback: true\n" +
+	"              wait_for:\n" +
+	"                # LiteralTestStep is a full test step definition.\n" +
+	"                - api_version: ' '\n" +
+	"                  jsonpath: ' '\n" +
+	"                  kind: ' '\n" +
+	"                  name: ' '\n" +
+	"                  namespace: ' '\n" +
+	"                  timeout: 0s\n" +
+	"                  value: ' '\n" +
+	"              workdir: ' '\n" +
 	"        # Pre is the array of test steps run to set up the environment for the test.\n" +
 	"        pre:\n" +
 	"            # LiteralTestStep is a full test step definition.\n" +
-	"            - as: ' '\n" +
+	"            - # Alias disambiguates steps whose names would otherwise conflict, e.g.\n" +
+	"              # when two chains contribute steps with the same name. For a reference,\n" +
+	"              # it replaces the name of the step. For a chain, it is prepended to the\n" +
+	"              # names of all the steps of the chain, e.g. `upgrade-gather-must-gather`\n" +
+	"              # for the step `gather-must-gather` of a chain with the alias `upgrade`.\n" +
+	"              alias: ' '\n" +
+	"              args:\n" +
+	"                # LiteralTestStep is a full test step definition.\n" +
+	"                - \"\"\n" +
+	"              as: ' '\n" +
 	"              best_effort: false\n" +
+	"              burst: false\n" +
 	"              # Chain is the name of a step chain reference.\n" +
 	"              chain: \"\"\n" +
 	"              # Cli is the (optional) name of the release from which the `oc` binary\n" +
-	"              # will be injected into this step.\n" +
+	"              # will be injected into this step. If the step has a `KUBECTL_RETRY`\n" +
+	"              # parameter, the binary is wrapped to retry calls which fail with a\n" +
+	"              # refused connection or a 429 response, at most that many times.\n" +
 	"              cli: ' '\n" +
+	"              command:\n" +
+	"                # LiteralTestStep is a full test step definition.\n" +
+	"                - \"\"\n" +
 	"              commands: ' '\n" +
+	"              concurrency_group: ' '\n" +
 	"              credentials:\n" +
 	"                # LiteralTestStep is a full test step definition.\n" +
 	"                - mount_path: ' '\n" +
 	"                  name: ' '\n" +
 	"                  namespace: ' '\n" +
+	"                  refresh: true\n" +
+	"                  selector:\n" +
+	"                    # LiteralTestStep is a full test step definition.\n" +
+	"                    matchLabels:\n" +
+	"                        # LiteralTestStep is a full test step definition.\n" +
+	"                        \"\": \"\"\n" +
+	"              critical: false\n" +
 	"              dependencies:\n" +
 	"                # LiteralTestStep is a full test step definition.\n" +
 	"                - env: ' '\n" +
@@ -1945,6 +4792,7 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"                - default: \"\"\n" +
 	"                  documentation: ' '\n" +
 	"                  name: ' '\n" +
+	"              fail_on_restart: false\n" +
 	"              from: ' '\n" +
 	"              from_image:\n" +
 	"                # LiteralTestStep is a full test step definition.\n" +
@@ -1952,19 +4800,44 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"                name: ' '\n" +
 	"                namespace: ' '\n" +
 	"                tag: ' '\n" +
+	"              gang: ' '\n" +
+	"              gather: false\n" +
 	"              grace_period: 0s\n" +
+	"              host_network: false\n" +
+	"              job:\n" +
+	"                # LiteralTestStep is a full test step definition.\n" +
+	"                ttl_seconds_after_finished: 0\n" +
 	"              leases:\n" +
 	"                # LiteralTestStep is a full test step definition.\n" +
 	"                - env: ' '\n" +
 	"                  resource_type: ' '\n" +
+	"              manifests: ' '\n" +
+	"              mutex: ' '\n" +
 	"              no_kubeconfig: false\n" +
+	"              node_capabilities:\n" +
+	"                # LiteralTestStep is a full test step definition.\n" +
+	"                - \"\"\n" +
 	"              observers:\n" +
 	"                # LiteralTestStep is a full test step definition.\n" +
 	"                - \"\"\n" +
 	"              optional_on_success: false\n" +
+	"              previous_job_artifacts:\n" +
+	"                # LiteralTestStep is a full test step definition.\n" +
+	"                files:\n" +
+	"                    # LiteralTestStep is a full test step definition.\n" +
+	"                    - \"\"\n" +
+	"              privileged_approval: ' '\n" +
+	"              provides_shared_files:\n" +
+	"                # LiteralTestStep is a full test step definition.\n" +
+	"                - \"\"\n" +
 	"              # Reference is the name of a step reference.\n" +
 	"              ref: \"\"\n" +
-	"              # Resources defines the resource requirements for the step.\n" +
+	"              # RequiresSharedFiles lists the files which previous steps must have\n" +
+	"              # written to $SHARED_DIR for this step to run. Their presence is\n" +
+	"              # verified before the step is executed.\n" +
+	"              requires_shared_files:\n" +
+	"                # LiteralTestStep is a full test step definition.\n" +
+	"                - \"\"\n" +
 	"              resources:\n" +
 	"                # LiteralTestStep is a full test step definition.\n" +
 	"                limits:\n" +
@@ -1973,24 +4846,106 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"                requests:\n" +
 	"                    # LiteralTestStep is a full test step definition.\n" +
 	"                    \"\": \"\"\n" +
+	"              rollback: ' '\n" +
 	"              run_as_script: false\n" +
+	"              run_on_ephemeral_cluster: false\n" +
+	"              scratch_size: ' '\n" +
+	"              shard_count: 0\n" +
+	"              sidecars:\n" +
+	"                # LiteralTestStep is a full test step definition.\n" +
+	"                - commands: ' '\n" +
+	"                  from: ' '\n" +
+	"                  name: ' '\n" +
+	"                  readiness: ' '\n" +
+	"                  resources:\n" +
+	"                    # LiteralTestStep is a full test step definition.\n" +
+	"                    limits:\n" +
+	"                        # LiteralTestStep is a full test step definition.\n" +
+	"                        \"\": \"\"\n" +
+	"                    requests:\n" +
+	"                        # LiteralTestStep is a full test step definition.\n" +
+	"                        \"\": \"\"\n" +
+	"              split_logs: false\n" +
+	"              stdin_from:\n" +
+	"                # LiteralTestStep is a full test step definition.\n" +
+	"                parameter: ' '\n" +
+	"                shared_file: ' '\n" +
+	"              sysctls:\n" +
+	"                # LiteralTestStep is a full test step definition.\n" +
+	"                - name: ' '\n" +
+	"                  value: ' '\n" +
 	"              timeout: 0s\n" +
+	"              toolkit: false\n" +
+	"              upgrade:\n" +
+	"                # LiteralTestStep is a full test step definition.\n" +
+	"                channel: ' '\n" +
+	"                force: true\n" +
+	"                release: ' '\n" +
+	"                rollback: true\n" +
+	"              wait_for:\n" +
+	"                # LiteralTestStep is a full test step definition.\n" +
+	"                - api_version: ' '\n" +
+	"                  jsonpath: ' '\n" +
+	"                  kind: ' '\n" +
+	"                  name: ' '\n" +
+	"                  namespace: ' '\n" +
+	"                  timeout: 0s\n" +
+	"                  value: ' '\n" +
+	"              workdir: ' '\n" +
+	"        # ReuseClusterFrom is the name of another test of the configuration whose\n" +
+	"        # cluster this test runs against instead of provisioning one. The test\n" +
+	"        # starts with a snapshot of the shared directory of the other test, taken\n" +
+	"        # once its `pre` steps have installed the cluster, and the other test only\n" +
+	"        # runs its `post` steps once this test has finished. Cluster profiles and\n" +
+	"        # claims cannot be used.\n" +
+	"        reuse_cluster_from: ' '\n" +
+	"        # StepReleases maps the names of steps to the release they use in place\n" +
+	"        # of `latest`: their dependencies on `release:latest` and on images of the\n" +
+	"        # `stable` stream, and the $RELEASE_IMAGE_LATEST given to them with a\n" +
+	"        # cluster profile, refer to the named release instead. This allows the\n" +
+	"        # steps of one test to use different payloads, e.g. for the management\n" +
+	"        # and the hosted clusters of a HyperShift test.\n" +
+	"        step_releases:\n" +
+	"            \"\": \"\"\n" +
 	"        # Test is the array of test steps that define the actual test.\n" +
 	"        test:\n" +
 	"            # LiteralTestStep is a full test step definition.\n" +
-	"            - as: ' '\n" +
+	"            - # Alias disambiguates steps whose names would otherwise conflict, e.g.\n" +
+	"              # when two chains contribute steps with the same name. For a reference,\n" +
+	"              # it replaces the name of the step. For a chain, it is prepended to the\n" +
+	"              # names of all the steps of the chain, e.g. `upgrade-gather-must-gather`\n" +
+	"              # for the step `gather-must-gather` of a chain with the alias `upgrade`.\n" +
+	"              alias: ' '\n" +
+	"              args:\n" +
+	"                # LiteralTestStep is a full test step definition.\n" +
+	"                - \"\"\n" +
+	"              as: ' '\n" +
 	"              best_effort: false\n" +
+	"              burst: false\n" +
 	"              # Chain is the name of a step chain reference.\n" +
 	"              chain: \"\"\n" +
 	"              # Cli is the (optional) name of the release from which the `oc` binary\n" +
-	"              # will be injected into this step.\n" +
+	"              # will be injected into this step. If the step has a `KUBECTL_RETRY`\n" +
+	"              # parameter, the binary is wrapped to retry calls which fail with a\n" +
+	"              # refused connection or a 429 response, at most that many times.\n" +
 	"              cli: ' '\n" +
+	"              command:\n" +
+	"                # LiteralTestStep is a full test step definition.\n" +
+	"                - \"\"\n" +
 	"              commands: ' '\n" +
+	"              concurrency_group: ' '\n" +
 	"              credentials:\n" +
 	"                # LiteralTestStep is a full test step definition.\n" +
 	"                - mount_path: ' '\n" +
 	"                  name: ' '\n" +
 	"                  namespace: ' '\n" +
+	"                  refresh: true\n" +
+	"                  selector:\n" +
+	"                    # LiteralTestStep is a full test step definition.\n" +
+	"                    matchLabels:\n" +
+	"                        # LiteralTestStep is a full test step definition.\n" +
+	"                        \"\": \"\"\n" +
+	"              critical: false\n" +
 	"              dependencies:\n" +
 	"                # LiteralTestStep is a full test step definition.\n" +
 	"                - env: ' '\n" +
@@ -2008,6 +4963,7 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"                - default: \"\"\n" +
 	"                  documentation: ' '\n" +
 	"                  name: ' '\n" +
+	"              fail_on_restart: false\n" +
 	"              from: ' '\n" +
 	"              from_image:\n" +
 	"                # LiteralTestStep is a full test step definition.\n" +
@@ -2015,19 +4971,44 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"                name: ' '\n" +
 	"                namespace: ' '\n" +
 	"                tag: ' '\n" +
+	"              gang: ' '\n" +
+	"              gather: false\n" +
 	"              grace_period: 0s\n" +
+	"              host_network: false\n" +
+	"              job:\n" +
+	"                # LiteralTestStep is a full test step definition.\n" +
+	"                ttl_seconds_after_finished: 0\n" +
 	"              leases:\n" +
 	"                # LiteralTestStep is a full test step definition.\n" +
 	"                - env: ' '\n" +
 	"                  resource_type: ' '\n" +
+	"              manifests: ' '\n" +
+	"              mutex: ' '\n" +
 	"              no_kubeconfig: false\n" +
+	"              node_capabilities:\n" +
+	"                # LiteralTestStep is a full test step definition.\n" +
+	"                - \"\"\n" +
 	"              observers:\n" +
 	"                # LiteralTestStep is a full test step definition.\n" +
 	"                - \"\"\n" +
 	"              optional_on_success: false\n" +
+	"              previous_job_artifacts:\n" +
+	"                # LiteralTestStep is a full test step definition.\n" +
+	"                files:\n" +
+	"                    # LiteralTestStep is a full test step definition.\n" +
+	"                    - \"\"\n" +
+	"              privileged_approval: ' '\n" +
+	"              provides_shared_files:\n" +
+	"                # LiteralTestStep is a full test step definition.\n" +
+	"                - \"\"\n" +
 	"              # Reference is the name of a step reference.\n" +
 	"              ref: \"\"\n" +
-	"              # Resources defines the resource requirements for the step.\n" +
+	"              # RequiresSharedFiles lists the files which previous steps must have\n" +
+	"              # written to $SHARED_DIR for this step to run. Their presence is\n" +
+	"              # verified before the step is executed.\n" +
+	"              requires_shared_files:\n" +
+	"                # LiteralTestStep is a full test step definition.\n" +
+	"                - \"\"\n" +
 	"              resources:\n" +
 	"                # LiteralTestStep is a full test step definition.\n" +
 	"                limits:\n" +
@@ -2036,8 +5017,52 @@ const ciOperatorReferenceYaml = "# The list of base images describe\n" +
 	"                requests:\n" +
 	"                    # LiteralTestStep is a full test step definition.\n" +
 	"                    \"\": \"\"\n" +
+	"              rollback: ' '\n" +
 	"              run_as_script: false\n" +
+	"              run_on_ephemeral_cluster: false\n" +
+	"              scratch_size: ' '\n" +
+	"              shard_count: 0\n" +
+	"              sidecars:\n" +
+	"                # LiteralTestStep is a full test step definition.\n" +
+	"                - commands: ' '\n" +
+	"                  from: ' '\n" +
+	"                  name: ' '\n" +
+	"                  readiness: ' '\n" +
+	"                  resources:\n" +
+	"                    # LiteralTestStep is a full test step definition.\n" +
+	"                    limits:\n" +
+	"                        # LiteralTestStep is a full test step definition.\n" +
+	"                        \"\": \"\"\n" +
+	"                    requests:\n" +
+	"                        # LiteralTestStep is a full test step definition.\n" +
+	"                        \"\": \"\"\n" +
+	"              split_logs: false\n" +
+	"              stdin_from:\n" +
+	"                # LiteralTestStep is a full test step definition.\n" +
+	"                parameter: ' '\n" +
+	"                shared_file: ' '\n" +
+	"              sysctls:\n" +
+	"                # LiteralTestStep is a full test step definition.\n" +
+	"                - name: ' '\n" +
+	"                  value: ' '\n" +
 	"              timeout: 0s\n" +
+	"              toolkit: false\n" +
+	"              upgrade:\n" +
+	"                # LiteralTestStep is a full test step definition.\n" +
+	"                channel: ' '\n" +
+	"                force: true\n" +
+	"                release: ' '\n" +
+	"                rollback: true\n" +
+	"              wait_for:\n" +
+	"                # LiteralTestStep is a full test step definition.\n" +
+	"                - api_version: ' '\n" +
+	"                  jsonpath: ' '\n" +
+	"                  kind: ' '\n" +
+	"                  name: ' '\n" +
+	"                  namespace: ' '\n" +
+	"                  timeout: 0s\n" +
+	"                  value: ' '\n" +
+	"              workdir: ' '\n" +
 	"        # Workflow is the name of the workflow to be used for this configuration. For fields defined in both\n" +
 	"        # the config and the workflow, the fields from the config will override what is set in Workflow.\n" +
 	"        workflow: \"\"\n" +